@@ -19,6 +19,7 @@
 package acquire
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -29,18 +30,44 @@ import (
 //Use DefaultAcquirer for such no-op use case.
 var ErrEmptyCredentials = errors.New("Empty credentials are not valid")
 
+// ErrNilAcquirer is returned when an Acquirer that is needed is nil.
+var ErrNilAcquirer = errors.New("acquirer is undefined")
+
 // Acquirer gets an Authorization value that can be added to an http request.
 // The format of the string returned should be the key, a space, and then the
 // auth string: '[AuthType] [AuthValue]'
 type Acquirer interface {
-	Acquire() (string, error)
+	// Acquire returns the Authorization value.  The context carries the
+	// deadline and tracing of the request that needs it.
+	Acquire(context.Context) (string, error)
+}
+
+// Refresher is implemented by Acquirers that cache their Authorization
+// values, so that a caller whose value was rejected, such as with a 401 from
+// the upstream service, can get a new one.
+type Refresher interface {
+	// InvalidateAndRefresh discards the cached value and acquires a new one.
+	InvalidateAndRefresh(context.Context) (string, error)
+}
+
+// InvalidateAndRefresh discards the acquirer's cached Authorization value, if
+// it is a Refresher, and returns a new one.  Other acquirers are just asked
+// for their value again.
+func InvalidateAndRefresh(ctx context.Context, acquirer Acquirer) (string, error) {
+	if acquirer == nil {
+		return "", ErrNilAcquirer
+	}
+	if r, ok := acquirer.(Refresher); ok {
+		return r.InvalidateAndRefresh(ctx)
+	}
+	return acquirer.Acquire(ctx)
 }
 
 // DefaultAcquirer is a no-op Acquirer.
 type DefaultAcquirer struct{}
 
 //Acquire returns the zero values of the return types.
-func (d *DefaultAcquirer) Acquire() (string, error) {
+func (d *DefaultAcquirer) Acquire(context.Context) (string, error) {
 	return "", nil
 }
 
@@ -51,10 +78,10 @@ func AddAuth(r *http.Request, acquirer Acquirer) error {
 	}
 
 	if acquirer == nil {
-		return errors.New("acquirer is undefined")
+		return ErrNilAcquirer
 	}
 
-	auth, err := acquirer.Acquire()
+	auth, err := acquirer.Acquire(r.Context())
 
 	if err != nil {
 		return fmt.Errorf("failed to acquire auth for request: %w", err)
@@ -72,7 +99,7 @@ type FixedValueAcquirer struct {
 	authValue string
 }
 
-func (f *FixedValueAcquirer) Acquire() (string, error) {
+func (f *FixedValueAcquirer) Acquire(context.Context) (string, error) {
 	return f.authValue, nil
 }
 
@@ -18,7 +18,9 @@
 package acquire
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -81,7 +83,7 @@ func TestFixedAuthAcquirer(t *testing.T) {
 		assert.NotNil(acquirer)
 		assert.NoError(err)
 
-		authValue, _ := acquirer.Acquire()
+		authValue, _ := acquirer.Acquire(context.Background())
 		assert.Equal("Basic xyz==", authValue)
 	})
 
@@ -97,13 +99,59 @@ func TestFixedAuthAcquirer(t *testing.T) {
 func TestDefaultAcquirer(t *testing.T) {
 	assert := assert.New(t)
 	acquirer := &DefaultAcquirer{}
-	authValue, err := acquirer.Acquire()
+	authValue, err := acquirer.Acquire(context.Background())
 	assert.Empty(authValue)
 	assert.Empty(err)
 }
 
 type failingAcquirer struct{}
 
-func (f *failingAcquirer) Acquire() (string, error) {
+func (f *failingAcquirer) Acquire(context.Context) (string, error) {
 	return "", errors.New("always fails")
 }
+
+// refreshingAcquirer counts its calls, returning a new value from each
+// refresh.
+type refreshingAcquirer struct {
+	acquires   int
+	refreshes  int
+	err        error
+	refreshErr error
+}
+
+func (r *refreshingAcquirer) Acquire(context.Context) (string, error) {
+	r.acquires++
+	if r.err != nil {
+		return "", r.err
+	}
+	return fmt.Sprintf("Bearer token%d", r.refreshes), nil
+}
+
+func (r *refreshingAcquirer) InvalidateAndRefresh(context.Context) (string, error) {
+	r.refreshes++
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.refreshErr != nil {
+		return "", r.refreshErr
+	}
+	return fmt.Sprintf("Bearer token%d", r.refreshes), nil
+}
+
+func TestInvalidateAndRefresh(t *testing.T) {
+	assert := assert.New(t)
+	_, err := InvalidateAndRefresh(context.Background(), nil)
+	assert.ErrorIs(err, ErrNilAcquirer)
+
+	fixed, _ := NewFixedAuthAcquirer("Basic abc")
+	value, err := InvalidateAndRefresh(context.Background(), fixed)
+	assert.NoError(err)
+	assert.Equal("Basic abc", value)
+
+	r := &refreshingAcquirer{}
+	value, err = InvalidateAndRefresh(context.Background(), r)
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+	assert.Equal(1, r.refreshes)
+	assert.Zero(r.acquires)
+}
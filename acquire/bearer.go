@@ -18,6 +18,7 @@
 package acquire
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -76,7 +77,7 @@ func NewRemoteBearerTokenAcquirer(options RemoteBearerTokenAcquirerOptions) (*Re
 
 // Acquire provides the cached token or, if it's near its expiry time, contacts
 // the server for a new token to cache.
-func (acquirer *RemoteBearerTokenAcquirer) Acquire() (string, error) {
+func (acquirer *RemoteBearerTokenAcquirer) Acquire(ctx context.Context) (string, error) {
 	acquirer.lock.RLock()
 	if time.Now().Add(acquirer.options.Buffer).Before(acquirer.authValueExpiration) {
 		defer acquirer.lock.RUnlock()
@@ -86,7 +87,26 @@ func (acquirer *RemoteBearerTokenAcquirer) Acquire() (string, error) {
 	acquirer.lock.Lock()
 	defer acquirer.lock.Unlock()
 
-	req, err := http.NewRequest("GET", acquirer.options.AuthURL, nil)
+	// another caller may have refreshed the token while waiting for the lock.
+	if time.Now().Add(acquirer.options.Buffer).Before(acquirer.authValueExpiration) {
+		return acquirer.authValue, nil
+	}
+	return acquirer.refresh(ctx)
+}
+
+// InvalidateAndRefresh discards the cached token, such as one the upstream
+// service rejected, and contacts the server for a new one.
+func (acquirer *RemoteBearerTokenAcquirer) InvalidateAndRefresh(ctx context.Context) (string, error) {
+	acquirer.lock.Lock()
+	defer acquirer.lock.Unlock()
+	acquirer.authValue, acquirer.authValueExpiration = "", time.Now()
+	return acquirer.refresh(ctx)
+}
+
+// refresh gets a new token from the server and caches it.  The lock must be
+// held.
+func (acquirer *RemoteBearerTokenAcquirer) refresh(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, acquirer.options.AuthURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create new request for Bearer: %v", err)
 	}
@@ -97,29 +117,52 @@ func (acquirer *RemoteBearerTokenAcquirer) Acquire() (string, error) {
 
 	resp, errHTTP := acquirer.httpClient.Do(req)
 	if errHTTP != nil {
-		return "", fmt.Errorf("error making request to '%v' to acquire bearer token: %v",
-			acquirer.options.AuthURL, errHTTP)
+		return "", &AcquireError{
+			Reason: RequestFailedReason,
+			Err: fmt.Errorf("error making request to '%v' to acquire bearer token: %w",
+				acquirer.options.AuthURL, errHTTP),
+		}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non 200 code acquiring Bearer: code %v", resp.Status)
+		return "", &AcquireError{
+			Reason: BadStatusReason,
+			Err:    fmt.Errorf("received non 200 code acquiring Bearer: code %v", resp.Status),
+		}
 	}
 
 	respBody, errRead := io.ReadAll(resp.Body)
 	if errRead != nil {
-		return "", fmt.Errorf("error reading HTTP response body: %v", errRead)
+		return "", &AcquireError{
+			Reason: ReadFailedReason,
+			Err:    fmt.Errorf("error reading HTTP response body: %v", errRead),
+		}
 	}
 
 	token, err := acquirer.options.GetToken(respBody)
 	if err != nil {
-		return "", fmt.Errorf("error parsing bearer token from http response body: %v", err)
+		return "", &AcquireError{
+			Reason: ParseFailedReason,
+			Err:    fmt.Errorf("error parsing bearer token from http response body: %v", err),
+		}
 	}
 	expiration, err := acquirer.options.GetExpiration(respBody)
 	if err != nil {
-		return "", fmt.Errorf("error parsing bearer token expiration from http response body: %v", err)
+		return "", &AcquireError{
+			Reason: ParseFailedReason,
+			Err:    fmt.Errorf("error parsing bearer token expiration from http response body: %v", err),
+		}
 	}
 
 	acquirer.authValue, acquirer.authValueExpiration = "Bearer "+token, expiration
 	return acquirer.authValue, nil
 }
+
+// Expiration returns when the cached token expires.  Before a token has been
+// fetched, it is the time the acquirer was created.
+func (acquirer *RemoteBearerTokenAcquirer) Expiration() time.Time {
+	acquirer.lock.RLock()
+	defer acquirer.lock.RUnlock()
+	return acquirer.authValueExpiration
+}
@@ -18,6 +18,7 @@
 package acquire
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRemoteBearerTokenAcquirer(t *testing.T) {
@@ -116,7 +118,7 @@ func TestRemoteBearerTokenAcquirer(t *testing.T) {
 
 			assert.NoError(errConstructor)
 
-			token, err := auth.Acquire()
+			token, err := auth.Acquire(context.Background())
 
 			if tc.expectedErr == nil || err == nil {
 				assert.Equal(tc.expectedErr, err)
@@ -152,10 +154,10 @@ func TestRemoteBearerTokenAcquirerCaching(t *testing.T) {
 		Buffer:  time.Microsecond,
 	})
 	assert.NoError(errConstructor)
-	token, err := auth.Acquire()
+	token, err := auth.Acquire(context.Background())
 	assert.NoError(err)
 
-	cachedToken, err := auth.Acquire()
+	cachedToken, err := auth.Acquire(context.Background())
 	assert.NoError(err)
 	assert.Equal(token, cachedToken)
 	assert.Equal(1, count)
@@ -185,19 +187,62 @@ func TestRemoteBearerTokenAcquirerExiting(t *testing.T) {
 		Buffer:  time.Second,
 	})
 	assert.NoError(errConstructor)
-	token, err := auth.Acquire()
+	token, err := auth.Acquire(context.Background())
 	assert.NoError(err)
 	var wg sync.WaitGroup
 	for i := 0; i < 10; i++ {
 		wg.Add(1)
 		go func() {
-			_, err := auth.Acquire()
+			_, err := auth.Acquire(context.Background())
 			assert.NoError(err)
 			wg.Done()
 		}()
 	}
 	wg.Wait()
-	cachedToken, err := auth.Acquire()
+	cachedToken, err := auth.Acquire(context.Background())
 	assert.NoError(err)
 	assert.NotEqual(token, cachedToken)
 }
+
+func TestRemoteBearerTokenAcquirerInvalidateAndRefresh(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		count++
+		marshaledAuth, err := json.Marshal(&SimpleBearer{
+			Token:            fmt.Sprintf("gopher%v", count),
+			ExpiresInSeconds: 3600,
+		})
+		assert.NoError(err)
+		rw.Write(marshaledAuth)
+	}))
+	defer server.Close()
+
+	auth, err := NewRemoteBearerTokenAcquirer(RemoteBearerTokenAcquirerOptions{
+		AuthURL: server.URL,
+		Timeout: 5 * time.Second,
+		Buffer:  time.Second,
+	})
+	require.NoError(err)
+	token, err := auth.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal("Bearer gopher1", token)
+
+	refreshed, err := auth.InvalidateAndRefresh(context.Background())
+	require.NoError(err)
+	assert.Equal("Bearer gopher2", refreshed)
+	cached, err := auth.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal(refreshed, cached)
+	assert.Equal(2, count)
+	assert.True(auth.Expiration().After(time.Now().Add(time.Minute)))
+
+	// the context's deadline applies to the request for a token.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = auth.InvalidateAndRefresh(ctx)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(RequestFailedReason, ReasonOf(err))
+}
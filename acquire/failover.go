@@ -0,0 +1,92 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// ErrNoAcquirers is returned when a FailoverAcquirer is created without any
+// Acquirers.
+var ErrNoAcquirers = errors.New("no acquirers to fail over between")
+
+// FailoverAcquirer acquires Authorization values from the first of several
+// credential sources that works, such as a token service with a static
+// credential as a fallback.
+type FailoverAcquirer struct {
+	acquirers []Acquirer
+
+	lock    sync.Mutex
+	current int
+}
+
+// NewFailoverAcquirer creates a FailoverAcquirer trying the acquirers in the
+// order given.  Nil acquirers are skipped.
+func NewFailoverAcquirer(acquirers ...Acquirer) (*FailoverAcquirer, error) {
+	f := &FailoverAcquirer{}
+	for _, a := range acquirers {
+		if a != nil {
+			f.acquirers = append(f.acquirers, a)
+		}
+	}
+	if len(f.acquirers) == 0 {
+		return nil, ErrNoAcquirers
+	}
+	return f, nil
+}
+
+// Acquire returns the value of the source that last worked, failing over to
+// the sources after it, and then those before it, if it fails.  If every
+// source fails, the errors of all of them are returned.
+func (f *FailoverAcquirer) Acquire(ctx context.Context) (string, error) {
+	f.lock.Lock()
+	start := f.current
+	f.lock.Unlock()
+	return f.acquire(start, func(a Acquirer) (string, error) {
+		return a.Acquire(ctx)
+	})
+}
+
+// InvalidateAndRefresh refreshes the sources in order, starting with the
+// first, so that a rejected value moves the acquirer back to its preferred
+// source if that source has recovered.
+func (f *FailoverAcquirer) InvalidateAndRefresh(ctx context.Context) (string, error) {
+	return f.acquire(0, func(a Acquirer) (string, error) {
+		return InvalidateAndRefresh(ctx, a)
+	})
+}
+
+func (f *FailoverAcquirer) acquire(start int, acquire func(Acquirer) (string, error)) (string, error) {
+	var errs error
+	for i := 0; i < len(f.acquirers); i++ {
+		n := (start + i) % len(f.acquirers)
+		auth, err := acquire(f.acquirers[n])
+		if err == nil {
+			f.lock.Lock()
+			f.current = n
+			f.lock.Unlock()
+			return auth, nil
+		}
+		errs = multierr.Append(errs, err)
+	}
+	return "", errs
+}
@@ -0,0 +1,75 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFailoverAcquirer(t *testing.T) {
+	f, err := NewFailoverAcquirer(nil, nil)
+	assert.ErrorIs(t, err, ErrNoAcquirers)
+	assert.Nil(t, f)
+}
+
+func TestFailoverAcquirer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	primaryErr := errors.New("primary down")
+	primary := &refreshingAcquirer{err: primaryErr}
+	fallback, _ := NewFixedAuthAcquirer("Basic abc")
+	f, err := NewFailoverAcquirer(nil, primary, fallback)
+	require.NoError(err)
+
+	value, err := f.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal("Basic abc", value)
+	assert.Equal(1, primary.acquires)
+
+	// the source that last worked is tried first.
+	value, err = f.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal("Basic abc", value)
+	assert.Equal(1, primary.acquires)
+
+	// refreshing goes back to the primary once it has recovered.
+	primary.err = nil
+	value, err = f.InvalidateAndRefresh(context.Background())
+	require.NoError(err)
+	assert.Equal("Bearer token1", value)
+	value, err = f.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal("Bearer token1", value)
+	assert.Equal(2, primary.acquires)
+}
+
+func TestFailoverAcquirerAllFail(t *testing.T) {
+	assert := assert.New(t)
+	first, second := errors.New("first"), errors.New("second")
+	f, err := NewFailoverAcquirer(&refreshingAcquirer{err: first}, &refreshingAcquirer{err: second})
+	require.NoError(t, err)
+	value, err := f.Acquire(context.Background())
+	assert.Empty(value)
+	assert.ErrorIs(err, first)
+	assert.ErrorIs(err, second)
+}
@@ -0,0 +1,202 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/touchstone"
+	"go.uber.org/fx"
+)
+
+// Names for our metrics
+const (
+	AuthAcquireDuration = "auth_acquire_duration_seconds"
+	AuthAcquireFailures = "auth_acquire_failures"
+	AuthAcquiredTTL     = "auth_acquired_token_ttl_seconds"
+	AuthAcquireRefresh  = "auth_acquire_refreshes"
+)
+
+// labels
+const (
+	AcquirerLabel = "acquirer"
+	ReasonLabel   = "reason"
+)
+
+// label values
+const (
+	RequestFailedReason = "request_failed"
+	BadStatusReason     = "bad_status"
+	ReadFailedReason    = "read_failed"
+	ParseFailedReason   = "parse_failed"
+	UnknownReason       = "unknown"
+)
+
+const (
+	acquireDurationHelpMsg = "Histogram of the time taken to acquire an authorization value, by acquirer"
+	acquireFailuresHelpMsg = "Counter for failures to acquire an authorization value, by acquirer and reason"
+	acquiredTTLHelpMsg     = "Gauge of the seconds until the acquired token expires, as of the last acquisition, by acquirer"
+	acquireRefreshHelpMsg  = "Counter for the times an acquirer fetched a new token, by acquirer"
+)
+
+// AcquireError is an error acquiring an authorization value, with the reason
+// it failed.
+type AcquireError struct {
+	Reason string
+	Err    error
+}
+
+func (e *AcquireError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AcquireError) Unwrap() error {
+	return e.Err
+}
+
+// ReasonOf returns the reason of an AcquireError in the error's chain, or
+// UnknownReason if there isn't one.
+func ReasonOf(err error) string {
+	var ae *AcquireError
+	if errors.As(err, &ae) && len(ae.Reason) > 0 {
+		return ae.Reason
+	}
+	return UnknownReason
+}
+
+// ExpiringAcquirer is implemented by Acquirers whose authorization values
+// expire, such as the RemoteBearerTokenAcquirer.
+type ExpiringAcquirer interface {
+	Acquirer
+
+	// Expiration returns when the current authorization value expires, or
+	// the zero time if there isn't one yet.
+	Expiration() time.Time
+}
+
+// ProvideMetrics provides the metrics used by the metric Acquirer as uber/fx
+// options.
+func ProvideMetrics() fx.Option {
+	return fx.Options(
+		touchstone.HistogramVec(prometheus.HistogramOpts{
+			Name:        AuthAcquireDuration,
+			Help:        acquireDurationHelpMsg,
+			ConstLabels: nil,
+		}, AcquirerLabel),
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthAcquireFailures,
+			Help:        acquireFailuresHelpMsg,
+			ConstLabels: nil,
+		}, AcquirerLabel, ReasonLabel),
+		touchstone.GaugeVec(prometheus.GaugeOpts{
+			Name:        AuthAcquiredTTL,
+			Help:        acquiredTTLHelpMsg,
+			ConstLabels: nil,
+		}, AcquirerLabel),
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthAcquireRefresh,
+			Help:        acquireRefreshHelpMsg,
+			ConstLabels: nil,
+		}, AcquirerLabel),
+	)
+}
+
+// AcquirerMeasures describes the metrics used by the metric Acquirer.
+type AcquirerMeasures struct {
+	fx.In
+
+	Duration  prometheus.ObserverVec `name:"auth_acquire_duration_seconds"`
+	Failures  *prometheus.CounterVec `name:"auth_acquire_failures"`
+	TTL       *prometheus.GaugeVec   `name:"auth_acquired_token_ttl_seconds"`
+	Refreshes *prometheus.CounterVec `name:"auth_acquire_refreshes"`
+}
+
+type metricAcquirer struct {
+	next     Acquirer
+	name     string
+	measures AcquirerMeasures
+	now      func() time.Time
+}
+
+// NewMetricAcquirer decorates an Acquirer so that its latency and failures
+// are measured, labeled with the name given.  If the Acquirer is an
+// ExpiringAcquirer, the time to live of its token and the times it fetches a
+// new token are measured too.  Nil measures are skipped.
+func NewMetricAcquirer(next Acquirer, name string, measures AcquirerMeasures) Acquirer {
+	return &metricAcquirer{
+		next:     next,
+		name:     name,
+		measures: measures,
+		now:      time.Now,
+	}
+}
+
+func (m *metricAcquirer) Acquire(ctx context.Context) (string, error) {
+	if m.next == nil {
+		return "", ErrNilAcquirer
+	}
+	return m.observe(func() (string, error) {
+		return m.next.Acquire(ctx)
+	})
+}
+
+// InvalidateAndRefresh refreshes the decorated Acquirer, so that decorating
+// an Acquirer doesn't hide that it's a Refresher.
+func (m *metricAcquirer) InvalidateAndRefresh(ctx context.Context) (string, error) {
+	if m.next == nil {
+		return "", ErrNilAcquirer
+	}
+	return m.observe(func() (string, error) {
+		return InvalidateAndRefresh(ctx, m.next)
+	})
+}
+
+func (m *metricAcquirer) observe(acquire func() (string, error)) (string, error) {
+	expiring, isExpiring := m.next.(ExpiringAcquirer)
+	var before time.Time
+	if isExpiring {
+		before = expiring.Expiration()
+	}
+
+	start := m.now()
+	auth, err := acquire()
+	if m.measures.Duration != nil {
+		m.measures.Duration.With(prometheus.Labels{AcquirerLabel: m.name}).Observe(m.now().Sub(start).Seconds())
+	}
+	if err != nil {
+		if m.measures.Failures != nil {
+			m.measures.Failures.With(prometheus.Labels{AcquirerLabel: m.name, ReasonLabel: ReasonOf(err)}).Inc()
+		}
+		return auth, err
+	}
+	if !isExpiring {
+		return auth, nil
+	}
+
+	after := expiring.Expiration()
+	if !after.Equal(before) && m.measures.Refreshes != nil {
+		m.measures.Refreshes.With(prometheus.Labels{AcquirerLabel: m.name}).Inc()
+	}
+	if !after.IsZero() && m.measures.TTL != nil {
+		m.measures.TTL.With(prometheus.Labels{AcquirerLabel: m.name}).Set(after.Sub(m.now()).Seconds())
+	}
+	return auth, nil
+}
@@ -0,0 +1,167 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAcquirerMeasures() AcquirerMeasures {
+	return AcquirerMeasures{
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "testDuration",
+		}, []string{AcquirerLabel}),
+		Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testFailures",
+		}, []string{AcquirerLabel, ReasonLabel}),
+		TTL: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "testTTL",
+		}, []string{AcquirerLabel}),
+		Refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testRefreshes",
+		}, []string{AcquirerLabel}),
+	}
+}
+
+type acquirerFunc func() (string, error)
+
+func (f acquirerFunc) Acquire(context.Context) (string, error) {
+	return f()
+}
+
+func TestReasonOf(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(UnknownReason, ReasonOf(nil))
+	assert.Equal(UnknownReason, ReasonOf(errors.New("test")))
+	assert.Equal(UnknownReason, ReasonOf(&AcquireError{Err: errors.New("test")}))
+	err := fmt.Errorf("wrapped: %w", &AcquireError{Reason: BadStatusReason, Err: errors.New("test")})
+	assert.Equal(BadStatusReason, ReasonOf(err))
+	assert.Equal("wrapped: test", err.Error())
+}
+
+func TestMetricAcquirer(t *testing.T) {
+	assert := assert.New(t)
+	measures := newTestAcquirerMeasures()
+	calls := 0
+	a := NewMetricAcquirer(acquirerFunc(func() (string, error) {
+		calls++
+		if calls == 2 {
+			return "", &AcquireError{Reason: RequestFailedReason, Err: errors.New("test")}
+		}
+		return "Basic abc", nil
+	}), "fixed", measures)
+
+	auth, err := a.Acquire(context.Background())
+	assert.NoError(err)
+	assert.Equal("Basic abc", auth)
+	_, err = a.Acquire(context.Background())
+	assert.Error(err)
+
+	assert.Equal(1, testutil.CollectAndCount(measures.Duration.(prometheus.Collector)))
+	assert.Equal(1.0, testutil.ToFloat64(measures.Failures.With(prometheus.Labels{
+		AcquirerLabel: "fixed",
+		ReasonLabel:   RequestFailedReason,
+	})))
+	assert.Equal(0, testutil.CollectAndCount(measures.TTL))
+	assert.Equal(0, testutil.CollectAndCount(measures.Refreshes))
+}
+
+func TestMetricAcquirerExpiring(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"expires_in": 3600, "serviceAccessToken": "abc"}`))
+	}))
+	defer server.Close()
+
+	bearer, err := NewRemoteBearerTokenAcquirer(RemoteBearerTokenAcquirerOptions{
+		AuthURL: server.URL,
+		Timeout: time.Second,
+		Buffer:  time.Minute,
+	})
+	require.NoError(t, err)
+	measures := newTestAcquirerMeasures()
+	a := NewMetricAcquirer(bearer, "remote", measures)
+	labels := prometheus.Labels{AcquirerLabel: "remote"}
+
+	auth, err := a.Acquire(context.Background())
+	assert.NoError(err)
+	assert.Equal("Bearer abc", auth)
+	assert.Equal(1.0, testutil.ToFloat64(measures.Refreshes.With(labels)))
+	ttl := testutil.ToFloat64(measures.TTL.With(labels))
+	assert.InDelta(3600, ttl, 5)
+
+	// a cached token isn't a refresh.
+	_, err = a.Acquire(context.Background())
+	assert.NoError(err)
+	assert.Equal(1.0, testutil.ToFloat64(measures.Refreshes.With(labels)))
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	failing, err := NewRemoteBearerTokenAcquirer(RemoteBearerTokenAcquirerOptions{
+		AuthURL: unauthorized.URL,
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	a = NewMetricAcquirer(failing, "remote", measures)
+	_, err = a.Acquire(context.Background())
+	assert.Error(err)
+	assert.Equal(1.0, testutil.ToFloat64(measures.Failures.With(prometheus.Labels{
+		AcquirerLabel: "remote",
+		ReasonLabel:   BadStatusReason,
+	})))
+	assert.Equal(1.0, testutil.ToFloat64(measures.Refreshes.With(labels)))
+}
+
+func TestMetricAcquirerRefresh(t *testing.T) {
+	assert := assert.New(t)
+	measures := newTestAcquirerMeasures()
+	r := &refreshingAcquirer{}
+	a := NewMetricAcquirer(r, "refreshing", measures)
+	value, err := InvalidateAndRefresh(context.Background(), a)
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+	assert.Equal(1, r.refreshes)
+	assert.Equal(1, testutil.CollectAndCount(measures.Duration.(prometheus.Collector)))
+
+	_, err = NewMetricAcquirer(nil, "nil", measures).(Refresher).InvalidateAndRefresh(context.Background())
+	assert.ErrorIs(err, ErrNilAcquirer)
+}
+
+func TestMetricAcquirerNil(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewMetricAcquirer(nil, "nil", AcquirerMeasures{}).Acquire(context.Background())
+	assert.ErrorIs(err, ErrNilAcquirer)
+
+	// nil measures are skipped.
+	auth, err := NewMetricAcquirer(&DefaultAcquirer{}, "default", AcquirerMeasures{}).Acquire(context.Background())
+	assert.NoError(err)
+	assert.Empty(auth)
+}
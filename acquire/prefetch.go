@@ -0,0 +1,315 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+const (
+	defaultPrefetchInterval      = 5 * time.Minute
+	defaultPrefetchRefreshBefore = time.Minute
+	defaultPrefetchRetryInterval = 5 * time.Second
+	defaultPrefetchTimeout       = 30 * time.Second
+)
+
+var (
+	// ErrNoSources is returned when a Prefetcher is created without any
+	// audiences.
+	ErrNoSources = errors.New("no audiences to prefetch tokens for")
+
+	// ErrUnknownAudience is returned for an audience the Prefetcher wasn't
+	// configured with.
+	ErrUnknownAudience = errors.New("unknown audience")
+
+	// ErrTokenNotReady is returned when the Prefetcher doesn't have an
+	// unexpired token for an audience yet.
+	ErrTokenNotReady = errors.New("token not ready")
+)
+
+// PrefetchConfig configures how a Prefetcher keeps its tokens fresh.
+type PrefetchConfig struct {
+	// Interval is how often tokens from Acquirers that aren't
+	// ExpiringAcquirers are refreshed.  The default is 5 minutes.
+	Interval time.Duration
+
+	// RefreshBefore is how long before an ExpiringAcquirer's token expires
+	// that it is refreshed.  The default is 1 minute.
+	RefreshBefore time.Duration
+
+	// Jitter is the most that each refresh is moved earlier by, chosen at
+	// random, so that many instances don't refresh at once.
+	Jitter time.Duration
+
+	// RetryInterval is how long to wait before retrying a failed refresh.
+	// The default is 5 seconds.
+	RetryInterval time.Duration
+
+	// Timeout bounds each refresh.  The default is 30 seconds.
+	Timeout time.Duration
+
+	// OnError, if set, is called with each failed refresh.
+	OnError func(audience string, err error)
+}
+
+// prefetched is the current token of an audience.
+type prefetched struct {
+	value   string
+	expires time.Time
+	err     error
+}
+
+// Prefetcher keeps fresh tokens for a set of audiences or scopes, refreshing
+// them in the background, so that services calling many downstream APIs
+// don't wait for a token on the request path.
+type Prefetcher struct {
+	sources map[string]Acquirer
+	config  PrefetchConfig
+	now     func() time.Time
+
+	lock   sync.RWMutex
+	tokens map[string]prefetched
+
+	runLock sync.Mutex
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+}
+
+// NewPrefetcher creates a Prefetcher for the Acquirers given, keyed by the
+// audience or scope they acquire tokens for.  Nil Acquirers are skipped.
+func NewPrefetcher(sources map[string]Acquirer, config PrefetchConfig) (*Prefetcher, error) {
+	p := &Prefetcher{
+		sources: make(map[string]Acquirer, len(sources)),
+		config:  config,
+		now:     time.Now,
+		tokens:  make(map[string]prefetched, len(sources)),
+	}
+	for audience, a := range sources {
+		if a != nil {
+			p.sources[audience] = a
+		}
+	}
+	if len(p.sources) == 0 {
+		return nil, ErrNoSources
+	}
+	if p.config.Interval <= 0 {
+		p.config.Interval = defaultPrefetchInterval
+	}
+	if p.config.RefreshBefore <= 0 {
+		p.config.RefreshBefore = defaultPrefetchRefreshBefore
+	}
+	if p.config.Jitter < 0 {
+		p.config.Jitter = 0
+	}
+	if p.config.RetryInterval <= 0 {
+		p.config.RetryInterval = defaultPrefetchRetryInterval
+	}
+	if p.config.Timeout <= 0 {
+		p.config.Timeout = defaultPrefetchTimeout
+	}
+	return p, nil
+}
+
+// Audiences returns the audiences the Prefetcher keeps tokens for, sorted.
+func (p *Prefetcher) Audiences() []string {
+	audiences := make([]string, 0, len(p.sources))
+	for audience := range p.sources {
+		audiences = append(audiences, audience)
+	}
+	sort.Strings(audiences)
+	return audiences
+}
+
+// GetFor returns the current token for the audience without waiting.  If the
+// Prefetcher hasn't got an unexpired token yet, the error wraps
+// ErrTokenNotReady and the last refresh's error, if there was one.
+func (p *Prefetcher) GetFor(audience string) (string, error) {
+	if _, ok := p.sources[audience]; !ok {
+		return "", fmt.Errorf("%w: [%s]", ErrUnknownAudience, audience)
+	}
+	p.lock.RLock()
+	t := p.tokens[audience]
+	p.lock.RUnlock()
+	if len(t.value) == 0 || (!t.expires.IsZero() && !p.now().Before(t.expires)) {
+		if t.err != nil {
+			return "", fmt.Errorf("%w for [%s]: %v", ErrTokenNotReady, audience, t.err)
+		}
+		return "", fmt.Errorf("%w for [%s]", ErrTokenNotReady, audience)
+	}
+	return t.value, nil
+}
+
+// Acquirer returns an Acquirer for the audience's tokens, for use with
+// NewRoundTripper and AddAuth.  Until the Prefetcher has a token, it acquires
+// one on the caller's behalf.
+func (p *Prefetcher) Acquirer(audience string) Acquirer {
+	return &prefetchedAcquirer{p: p, audience: audience}
+}
+
+type prefetchedAcquirer struct {
+	p        *Prefetcher
+	audience string
+}
+
+func (a *prefetchedAcquirer) Acquire(ctx context.Context) (string, error) {
+	value, err := a.p.GetFor(a.audience)
+	if !errors.Is(err, ErrTokenNotReady) {
+		return value, err
+	}
+	return a.p.refresh(ctx, a.audience, false)
+}
+
+func (a *prefetchedAcquirer) InvalidateAndRefresh(ctx context.Context) (string, error) {
+	if _, ok := a.p.sources[a.audience]; !ok {
+		return "", fmt.Errorf("%w: [%s]", ErrUnknownAudience, a.audience)
+	}
+	return a.p.refresh(ctx, a.audience, true)
+}
+
+// refresh gets a token for the audience and stores it.  If force is true, the
+// source's cached token is discarded first.
+func (p *Prefetcher) refresh(ctx context.Context, audience string, force bool) (string, error) {
+	source := p.sources[audience]
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	var (
+		value string
+		err   error
+	)
+	if force {
+		value, err = InvalidateAndRefresh(ctx, source)
+	} else {
+		value, err = source.Acquire(ctx)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t := p.tokens[audience]
+	if err != nil {
+		t.err = err
+		p.tokens[audience] = t
+		if p.config.OnError != nil {
+			p.config.OnError(audience, err)
+		}
+		return "", err
+	}
+	t = prefetched{value: value}
+	if e, ok := source.(ExpiringAcquirer); ok {
+		t.expires = e.Expiration()
+	}
+	p.tokens[audience] = t
+	return value, nil
+}
+
+// next returns how long to wait before refreshing the audience's token.
+func (p *Prefetcher) next(audience string, r *rand.Rand) time.Duration {
+	p.lock.RLock()
+	t := p.tokens[audience]
+	p.lock.RUnlock()
+
+	var wait time.Duration
+	switch {
+	case t.err != nil:
+		return p.config.RetryInterval
+	case !t.expires.IsZero():
+		wait = t.expires.Sub(p.now()) - p.config.RefreshBefore
+	default:
+		wait = p.config.Interval
+	}
+	if p.config.Jitter > 0 {
+		wait -= time.Duration(r.Int63n(int64(p.config.Jitter) + 1))
+	}
+	if wait < p.config.RetryInterval {
+		wait = p.config.RetryInterval
+	}
+	return wait
+}
+
+// Start fetches the first tokens for all audiences in parallel, and then keeps
+// refreshing them in the background until Stop is called.  Calling Start
+// again while running does nothing.
+func (p *Prefetcher) Start() {
+	p.runLock.Lock()
+	defer p.runLock.Unlock()
+	if p.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	seed := time.Now().UnixNano()
+	for _, audience := range p.Audiences() {
+		seed++
+		p.done.Add(1)
+		go p.run(ctx, audience, rand.New(rand.NewSource(seed))) //nolint:gosec
+	}
+}
+
+func (p *Prefetcher) run(ctx context.Context, audience string, r *rand.Rand) {
+	defer p.done.Done()
+	// the first token may come from the source's cache, later ones are forced.
+	_, _ = p.refresh(ctx, audience, false)
+	timer := time.NewTimer(p.next(audience, r))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			_, _ = p.refresh(ctx, audience, true)
+			timer.Reset(p.next(audience, r))
+		}
+	}
+}
+
+// Stop stops refreshing tokens, waiting for refreshes in progress to be
+// canceled.  The tokens already fetched can still be used until they expire.
+func (p *Prefetcher) Stop() {
+	p.runLock.Lock()
+	cancel := p.cancel
+	p.cancel = nil
+	p.runLock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	p.done.Wait()
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops the
+// Prefetcher.
+func (p *Prefetcher) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			p.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			p.Stop()
+			return nil
+		},
+	}
+}
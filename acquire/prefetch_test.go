@@ -0,0 +1,284 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expiringAcquirer is a refreshingAcquirer whose tokens expire.
+type expiringAcquirer struct {
+	refreshingAcquirer
+	expires time.Time
+}
+
+func (e *expiringAcquirer) Expiration() time.Time {
+	return e.expires
+}
+
+// syncAcquirer is safe to use from the Prefetcher's goroutines.
+type syncAcquirer struct {
+	lock      sync.Mutex
+	refreshes int
+	refreshed chan struct{}
+}
+
+func (s *syncAcquirer) Acquire(context.Context) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return fmt.Sprintf("Bearer token%d", s.refreshes), nil
+}
+
+func (s *syncAcquirer) InvalidateAndRefresh(context.Context) (string, error) {
+	s.lock.Lock()
+	s.refreshes++
+	value := fmt.Sprintf("Bearer token%d", s.refreshes)
+	s.lock.Unlock()
+	select {
+	case s.refreshed <- struct{}{}:
+	default:
+	}
+	return value, nil
+}
+
+func TestNewPrefetcher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	p, err := NewPrefetcher(nil, PrefetchConfig{})
+	assert.ErrorIs(err, ErrNoSources)
+	assert.Nil(p)
+
+	p, err = NewPrefetcher(map[string]Acquirer{"a": nil}, PrefetchConfig{})
+	assert.ErrorIs(err, ErrNoSources)
+	assert.Nil(p)
+
+	p, err = NewPrefetcher(map[string]Acquirer{
+		"b": &refreshingAcquirer{},
+		"a": &refreshingAcquirer{},
+		"c": nil,
+	}, PrefetchConfig{Jitter: -time.Second})
+	require.NoError(err)
+	require.NotNil(p)
+	assert.Equal([]string{"a", "b"}, p.Audiences())
+	assert.Equal(PrefetchConfig{
+		Interval:      defaultPrefetchInterval,
+		RefreshBefore: defaultPrefetchRefreshBefore,
+		RetryInterval: defaultPrefetchRetryInterval,
+		Timeout:       defaultPrefetchTimeout,
+	}, p.config)
+}
+
+func TestPrefetcherGetFor(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	errFetch := errors.New("fetch failed")
+	now := time.Now()
+	failing := &refreshingAcquirer{err: errFetch}
+	expiring := &expiringAcquirer{expires: now.Add(time.Hour)}
+	p, err := NewPrefetcher(map[string]Acquirer{
+		"plain":    &refreshingAcquirer{},
+		"failing":  failing,
+		"expiring": expiring,
+	}, PrefetchConfig{})
+	require.NoError(err)
+	p.now = func() time.Time { return now }
+
+	_, err = p.GetFor("unknown")
+	assert.ErrorIs(err, ErrUnknownAudience)
+	_, err = p.GetFor("plain")
+	assert.ErrorIs(err, ErrTokenNotReady)
+
+	value, err := p.refresh(context.Background(), "plain", true)
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+	value, err = p.GetFor("plain")
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+
+	_, err = p.refresh(context.Background(), "failing", false)
+	assert.ErrorIs(err, errFetch)
+	_, err = p.GetFor("failing")
+	assert.ErrorIs(err, ErrTokenNotReady)
+	assert.ErrorContains(err, errFetch.Error())
+
+	// a failed refresh keeps the last good token.
+	failing.err = nil
+	_, err = p.refresh(context.Background(), "failing", false)
+	assert.NoError(err)
+	failing.refreshErr = errFetch
+	_, err = p.refresh(context.Background(), "failing", true)
+	assert.ErrorIs(err, errFetch)
+	value, err = p.GetFor("failing")
+	assert.NoError(err)
+	assert.Equal("Bearer token0", value)
+
+	_, err = p.refresh(context.Background(), "expiring", false)
+	assert.NoError(err)
+	value, err = p.GetFor("expiring")
+	assert.NoError(err)
+	assert.Equal("Bearer token0", value)
+	p.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = p.GetFor("expiring")
+	assert.ErrorIs(err, ErrTokenNotReady)
+}
+
+func TestPrefetcherNext(t *testing.T) {
+	now := time.Now()
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+	tests := []struct {
+		description string
+		token       prefetched
+		config      PrefetchConfig
+		min         time.Duration
+		max         time.Duration
+	}{
+		{
+			description: "Interval",
+			token:       prefetched{value: "v"},
+			config:      PrefetchConfig{Interval: time.Hour},
+			min:         time.Hour,
+			max:         time.Hour,
+		},
+		{
+			description: "Interval with jitter",
+			token:       prefetched{value: "v"},
+			config:      PrefetchConfig{Interval: time.Hour, Jitter: time.Minute},
+			min:         59 * time.Minute,
+			max:         time.Hour,
+		},
+		{
+			description: "Expiring",
+			token:       prefetched{value: "v", expires: now.Add(time.Hour)},
+			config:      PrefetchConfig{RefreshBefore: 10 * time.Minute},
+			min:         50 * time.Minute,
+			max:         50 * time.Minute,
+		},
+		{
+			description: "Expiring with jitter",
+			token:       prefetched{value: "v", expires: now.Add(time.Hour)},
+			config:      PrefetchConfig{RefreshBefore: 10 * time.Minute, Jitter: 10 * time.Minute},
+			min:         40 * time.Minute,
+			max:         50 * time.Minute,
+		},
+		{
+			description: "Expiring soon",
+			token:       prefetched{value: "v", expires: now.Add(time.Minute)},
+			config:      PrefetchConfig{RefreshBefore: 10 * time.Minute, RetryInterval: time.Second},
+			min:         time.Second,
+			max:         time.Second,
+		},
+		{
+			description: "Retry",
+			token:       prefetched{value: "v", err: errors.New("failed")},
+			config:      PrefetchConfig{Interval: time.Hour, RetryInterval: time.Second},
+			min:         time.Second,
+			max:         time.Second,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			p, err := NewPrefetcher(map[string]Acquirer{"a": &refreshingAcquirer{}}, tc.config)
+			require.NoError(err)
+			p.now = func() time.Time { return now }
+			p.tokens["a"] = tc.token
+			for i := 0; i < 20; i++ {
+				wait := p.next("a", r)
+				assert.GreaterOrEqual(wait, tc.min)
+				assert.LessOrEqual(wait, tc.max)
+			}
+		})
+	}
+}
+
+func TestPrefetcherAcquirer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	source := &refreshingAcquirer{}
+	p, err := NewPrefetcher(map[string]Acquirer{"a": source}, PrefetchConfig{})
+	require.NoError(err)
+
+	// nothing is cached yet, so the token is acquired on the caller's behalf.
+	a := p.Acquirer("a")
+	value, err := a.Acquire(context.Background())
+	assert.NoError(err)
+	assert.Equal("Bearer token0", value)
+	assert.Equal(1, source.acquires)
+
+	value, err = a.Acquire(context.Background())
+	assert.NoError(err)
+	assert.Equal("Bearer token0", value)
+	assert.Equal(1, source.acquires)
+
+	value, err = InvalidateAndRefresh(context.Background(), a)
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+	value, err = p.GetFor("a")
+	assert.NoError(err)
+	assert.Equal("Bearer token1", value)
+
+	unknown := p.Acquirer("unknown")
+	_, err = unknown.Acquire(context.Background())
+	assert.ErrorIs(err, ErrUnknownAudience)
+	_, err = InvalidateAndRefresh(context.Background(), unknown)
+	assert.ErrorIs(err, ErrUnknownAudience)
+}
+
+func TestPrefetcherStartStop(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	sources := map[string]Acquirer{
+		"a": &syncAcquirer{refreshed: make(chan struct{}, 1)},
+		"b": &syncAcquirer{refreshed: make(chan struct{}, 1)},
+	}
+	p, err := NewPrefetcher(sources, PrefetchConfig{
+		Interval:      time.Millisecond,
+		RetryInterval: time.Millisecond,
+	})
+	require.NoError(err)
+
+	hook := p.Hook()
+	require.NoError(hook.OnStart(context.Background()))
+	p.Start()
+	for _, s := range sources {
+		select {
+		case <-s.(*syncAcquirer).refreshed:
+		case <-time.After(5 * time.Second):
+			assert.Fail("token wasn't refreshed")
+		}
+	}
+	require.NoError(hook.OnStop(context.Background()))
+	p.Stop()
+
+	for _, audience := range p.Audiences() {
+		value, err := p.GetFor(audience)
+		assert.NoError(err)
+		assert.NotEmpty(value)
+	}
+}
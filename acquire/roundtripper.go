@@ -0,0 +1,93 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type roundTripper struct {
+	acquirer Acquirer
+	next     http.RoundTripper
+}
+
+// NewRoundTripper returns an http.RoundTripper that adds the Authorization
+// value from the acquirer to each request before passing it to next.  If the
+// acquirer is a Refresher and the response is a 401, the value is refreshed
+// and the request is retried once, if its body can be sent again.  If next is
+// nil, http.DefaultTransport is used.
+func NewRoundTripper(acquirer Acquirer, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{
+		acquirer: acquirer,
+		next:     next,
+	}
+}
+
+func (rt *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if rt.acquirer == nil {
+		return nil, fmt.Errorf("failed to acquire auth for request: acquirer is undefined")
+	}
+	auth, err := rt.acquirer.Acquire(r.Context())
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to acquire auth for request: %w", err)
+	}
+
+	// RoundTrippers shouldn't modify the request they're given.
+	out := r.Clone(r.Context())
+	if auth != "" {
+		out.Header.Set("Authorization", auth)
+	}
+	resp, err := rt.next.RoundTrip(out)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rt.canRetry(r) {
+		return resp, err
+	}
+
+	refreshed, rerr := rt.acquirer.(Refresher).InvalidateAndRefresh(r.Context())
+	if rerr != nil || refreshed == auth {
+		return resp, nil
+	}
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		body, berr := r.GetBody()
+		if berr != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	retry.Header.Set("Authorization", refreshed)
+	return rt.next.RoundTrip(retry)
+}
+
+// canRetry returns true if the acquirer can refresh its value and the
+// request's body can be sent again.
+func (rt *roundTripper) canRetry(r *http.Request) bool {
+	if _, ok := rt.acquirer.(Refresher); !ok {
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
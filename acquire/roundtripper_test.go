@@ -0,0 +1,184 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package acquire
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRoundTripper(t *testing.T) {
+	fixedAcquirer, _ := NewFixedAuthAcquirer("Basic abc==")
+	tests := []struct {
+		description  string
+		acquirer     Acquirer
+		expectedAuth string
+		expectedErr  bool
+	}{
+		{
+			description:  "Success",
+			acquirer:     fixedAcquirer,
+			expectedAuth: "Basic abc==",
+		},
+		{
+			description: "Empty Auth",
+			acquirer:    &DefaultAcquirer{},
+		},
+		{
+			description: "Acquirer Error",
+			acquirer:    &failingAcquirer{},
+			expectedErr: true,
+		},
+		{
+			description: "Nil Acquirer",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			called := false
+			next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				called = true
+				assert.Equal(tc.expectedAuth, r.Header.Get("Authorization"))
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			resp, err := NewRoundTripper(tc.acquirer, next).RoundTrip(req)
+			assert.Empty(req.Header.Get("Authorization"))
+			if tc.expectedErr {
+				assert.Error(err)
+				assert.Nil(resp)
+				assert.False(called)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			assert.True(called)
+		})
+	}
+}
+
+func TestRoundTripperRefresh(t *testing.T) {
+	tests := []struct {
+		description       string
+		acquirer          Acquirer
+		body              string
+		noGetBody         bool
+		statuses          []int
+		expectedAuths     []string
+		expectedStatus    int
+		expectedRefreshes int
+	}{
+		{
+			description:    "Not Unauthorized",
+			acquirer:       &refreshingAcquirer{},
+			statuses:       []int{http.StatusOK},
+			expectedAuths:  []string{"Bearer token0"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:       "Retried",
+			acquirer:          &refreshingAcquirer{},
+			statuses:          []int{http.StatusUnauthorized, http.StatusOK},
+			expectedAuths:     []string{"Bearer token0", "Bearer token1"},
+			expectedStatus:    http.StatusOK,
+			expectedRefreshes: 1,
+		},
+		{
+			description:       "Retried Once",
+			acquirer:          &refreshingAcquirer{},
+			body:              "payload",
+			statuses:          []int{http.StatusUnauthorized, http.StatusUnauthorized},
+			expectedAuths:     []string{"Bearer token0", "Bearer token1"},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedRefreshes: 1,
+		},
+		{
+			description:       "Refresh Failed",
+			acquirer:          &refreshingAcquirer{refreshErr: errors.New("refresh failed")},
+			statuses:          []int{http.StatusUnauthorized},
+			expectedAuths:     []string{"Bearer token0"},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedRefreshes: 1,
+		},
+		{
+			description:    "Not A Refresher",
+			acquirer:       &FixedValueAcquirer{authValue: "Basic abc"},
+			statuses:       []int{http.StatusUnauthorized},
+			expectedAuths:  []string{"Basic abc"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "Body Can't Be Resent",
+			acquirer:       &refreshingAcquirer{},
+			body:           "payload",
+			noGetBody:      true,
+			statuses:       []int{http.StatusUnauthorized},
+			expectedAuths:  []string{"Bearer token0"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var auths []string
+			next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				auths = append(auths, r.Header.Get("Authorization"))
+				if r.Body != nil {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(err)
+					assert.Equal(tc.body, string(body))
+				}
+				return &http.Response{StatusCode: tc.statuses[len(auths)-1], Body: io.NopCloser(strings.NewReader(""))}, nil
+			})
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader(tc.body))
+			require.NoError(err)
+			if tc.noGetBody {
+				req.GetBody = nil
+			}
+			resp, err := NewRoundTripper(tc.acquirer, next).RoundTrip(req)
+			require.NoError(err)
+			assert.Equal(tc.expectedStatus, resp.StatusCode)
+			assert.Equal(tc.expectedAuths, auths)
+			if r, ok := tc.acquirer.(*refreshingAcquirer); ok {
+				assert.Equal(tc.expectedRefreshes, r.refreshes)
+			}
+		})
+	}
+}
+
+func TestRoundTripperDefaultTransport(t *testing.T) {
+	rt := NewRoundTripper(&DefaultAcquirer{}, nil)
+	assert.Equal(t, http.DefaultTransport, rt.(*roundTripper).next)
+}
@@ -0,0 +1,208 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xmidt-org/arrange"
+)
+
+var ErrInvalidAttributePath = errors.New("invalid attribute path")
+
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// AttributePath is a compiled path into a token's Attributes.  It supports a
+// limited JSONPath syntax: an optional leading "$", dot separated keys, array
+// indexes ("[0]"), quoted keys ("['key.with.dots']"), and the "[*]" wildcard,
+// which applies the rest of the path to every element of an array.
+type AttributePath []pathSegment
+
+// ParseAttributePath compiles a path such as
+// "$.allowedResources[0].serviceIDs" into an AttributePath.
+func ParseAttributePath(path string) (AttributePath, error) {
+	p := strings.TrimPrefix(path, "$")
+	if p == "" {
+		return nil, fmt.Errorf("%w: empty path", ErrInvalidAttributePath)
+	}
+	var segments AttributePath
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+			end := i
+			for end < len(p) && p[end] != '.' && p[end] != '[' {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("%w: empty key in [%v]", ErrInvalidAttributePath, path)
+			}
+			if strings.IndexByte(p[i:end], ']') >= 0 {
+				return nil, fmt.Errorf("%w: unexpected ']' in [%v]", ErrInvalidAttributePath, path)
+			}
+			segments = append(segments, pathSegment{key: p[i:end]})
+			i = end
+		case '[':
+			if i+1 < len(p) && (p[i+1] == '\'' || p[i+1] == '"') {
+				// quoted keys may contain brackets, so they end at the
+				// closing quote followed by a bracket.
+				end := strings.Index(p[i+2:], p[i+1:i+2]+"]")
+				if end < 0 {
+					return nil, fmt.Errorf("%w: unclosed bracket in [%v]", ErrInvalidAttributePath, path)
+				}
+				if end == 0 {
+					return nil, fmt.Errorf("%w: empty quoted key in [%v]", ErrInvalidAttributePath, path)
+				}
+				segments = append(segments, pathSegment{key: p[i+2 : i+2+end]})
+				i += end + 4
+				continue
+			}
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed bracket in [%v]", ErrInvalidAttributePath, path)
+			}
+			seg, err := parseBracket(p[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v in [%v]", ErrInvalidAttributePath, err, path)
+			}
+			segments = append(segments, seg)
+			i += end + 1
+		default:
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("%w: unexpected character %q in [%v]", ErrInvalidAttributePath, p[i], path)
+			}
+			// the first key may omit the leading dot.
+			p = "." + p[i:]
+			i = 0
+		}
+	}
+	return segments, nil
+}
+
+func parseBracket(s string) (pathSegment, error) {
+	if s == "*" {
+		return pathSegment{wildcard: true}, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return pathSegment{}, fmt.Errorf("bad index %q", s)
+	}
+	return pathSegment{index: i, isIndex: true}, nil
+}
+
+// String returns the path in its canonical JSONPath form.
+func (p AttributePath) String() string {
+	var b strings.Builder
+	b.WriteRune('$')
+	for _, s := range p {
+		switch {
+		case s.wildcard:
+			b.WriteString("[*]")
+		case s.isIndex:
+			b.WriteString("[" + strconv.Itoa(s.index) + "]")
+		case strings.Contains(s.key, "']"):
+			b.WriteString(`["` + s.key + `"]`)
+		case strings.ContainsAny(s.key, ".[]"):
+			b.WriteString("['" + s.key + "']")
+		default:
+			b.WriteString("." + s.key)
+		}
+	}
+	return b.String()
+}
+
+// Get follows the path through the attributes given.  When the path contains
+// a wildcard, the values found for each array element are returned as a
+// []interface{}, and the lookup only fails if no element had a value.
+func (p AttributePath) Get(attributes Attributes) (interface{}, bool) {
+	if len(p) == 0 || attributes == nil {
+		return nil, false
+	}
+	return p.get(attributes)
+}
+
+func (p AttributePath) get(v interface{}) (interface{}, bool) {
+	for i, s := range p {
+		if v == nil {
+			return nil, false
+		}
+		switch {
+		case s.wildcard:
+			l, ok := asSlice(v)
+			if !ok {
+				return nil, false
+			}
+			results := make([]interface{}, 0, l.Len())
+			for j := 0; j < l.Len(); j++ {
+				if r, ok := p[i+1:].get(l.Index(j).Interface()); ok {
+					results = append(results, r)
+				}
+			}
+			if len(results) == 0 {
+				return nil, false
+			}
+			return results, true
+		case s.isIndex:
+			l, ok := asSlice(v)
+			if !ok || s.index >= l.Len() {
+				return nil, false
+			}
+			v = l.Index(s.index).Interface()
+		default:
+			var a Attributes
+			ok := arrange.TryConvert(v,
+				func(attr Attributes) { a = attr },
+				func(m map[string]interface{}) { a = BasicAttributes(m) },
+			)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = a.Get(s.key); !ok {
+				return nil, false
+			}
+		}
+	}
+	return v, true
+}
+
+func asSlice(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return reflect.Value{}, false
+	}
+	return rv, true
+}
+
+// GetAttribute parses the path given and uses it to get a value from the
+// attributes.  Invalid paths are treated as not found.
+func GetAttribute(attributes Attributes, path string) (interface{}, bool) {
+	p, err := ParseAttributePath(path)
+	if err != nil {
+		return nil, false
+	}
+	return p.Get(attributes)
+}
@@ -0,0 +1,196 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAttributePath(t *testing.T) {
+	tests := []struct {
+		description    string
+		path           string
+		expectedString string
+		expectedErr    bool
+	}{
+		{
+			description:    "Success",
+			path:           "$.allowedResources[0].serviceIDs",
+			expectedString: "$.allowedResources[0].serviceIDs",
+		},
+		{
+			description:    "No Root Success",
+			path:           "a.b",
+			expectedString: "$.a.b",
+		},
+		{
+			description:    "Quoted Key Success",
+			path:           "$['a.b'][*].c",
+			expectedString: "$['a.b'][*].c",
+		},
+		{
+			description:    "Quoted Bracket Key Success",
+			path:           "['a]b'].c",
+			expectedString: "$['a]b'].c",
+		},
+		{
+			description:    "Double Quoted Key Success",
+			path:           `["a']b"]`,
+			expectedString: `$["a']b"]`,
+		},
+		{
+			description:    "Leading Index Success",
+			path:           "[2]",
+			expectedString: "$[2]",
+		},
+		{
+			description: "Empty Path Error",
+			path:        "$",
+			expectedErr: true,
+		},
+		{
+			description: "Empty Key Error",
+			path:        "a..b",
+			expectedErr: true,
+		},
+		{
+			description: "Unclosed Bracket Error",
+			path:        "a[0",
+			expectedErr: true,
+		},
+		{
+			description: "Bad Index Error",
+			path:        "a[-1]",
+			expectedErr: true,
+		},
+		{
+			description: "Empty Quoted Key Error",
+			path:        "a['']",
+			expectedErr: true,
+		},
+		{
+			description: "Unclosed Quoted Key Error",
+			path:        "a['b]",
+			expectedErr: true,
+		},
+		{
+			description: "Unquoted Bracket Error",
+			path:        "a]b",
+			expectedErr: true,
+		},
+		{
+			description: "Unexpected Character Error",
+			path:        "a[0]b",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p, err := ParseAttributePath(tc.path)
+			if tc.expectedErr {
+				assert.ErrorIs(err, ErrInvalidAttributePath)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.expectedString, p.String())
+		})
+	}
+}
+
+func TestGetAttribute(t *testing.T) {
+	attributes := NewAttributes(map[string]interface{}{
+		"allowedResources": []interface{}{
+			map[string]interface{}{"serviceIDs": []string{"a", "b"}},
+			map[string]interface{}{"other": true},
+			BasicAttributes{"serviceIDs": []string{"c"}},
+		},
+		"a.b":   "dotted",
+		"names": []string{"x", "y"},
+	})
+	tests := []struct {
+		description    string
+		path           string
+		expectedResult interface{}
+		expectedOK     bool
+	}{
+		{
+			description:    "Success",
+			path:           "$.allowedResources[0].serviceIDs",
+			expectedResult: []string{"a", "b"},
+			expectedOK:     true,
+		},
+		{
+			description:    "Nested Index Success",
+			path:           "allowedResources[2].serviceIDs[0]",
+			expectedResult: "c",
+			expectedOK:     true,
+		},
+		{
+			description: "Wildcard Success",
+			path:        "allowedResources[*].serviceIDs",
+			expectedResult: []interface{}{
+				[]string{"a", "b"},
+				[]string{"c"},
+			},
+			expectedOK: true,
+		},
+		{
+			description:    "Quoted Key Success",
+			path:           "['a.b']",
+			expectedResult: "dotted",
+			expectedOK:     true,
+		},
+		{
+			description:    "String Slice Success",
+			path:           "names[1]",
+			expectedResult: "y",
+			expectedOK:     true,
+		},
+		{
+			description: "Wildcard Nothing Found Error",
+			path:        "allowedResources[*].missing",
+		},
+		{
+			description: "Index Out Of Range Error",
+			path:        "names[2]",
+		},
+		{
+			description: "Index Non Array Error",
+			path:        "['a.b'][0]",
+		},
+		{
+			description: "Key Into Array Error",
+			path:        "names.x",
+		},
+		{
+			description: "Invalid Path Error",
+			path:        "names[",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			val, ok := GetAttribute(attributes, tc.path)
+			assert.Equal(tc.expectedResult, val)
+			assert.Equal(tc.expectedOK, ok)
+		})
+	}
+}
@@ -18,6 +18,9 @@
 package bascule
 
 import (
+	"sort"
+	"strconv"
+
 	"github.com/xmidt-org/arrange"
 )
 
@@ -28,13 +31,68 @@ func (a BasicAttributes) Get(key string) (interface{}, bool) {
 	return v, ok
 }
 
+// Keys returns the attributes' keys, sorted.
+func (a BasicAttributes) Keys() ([]string, bool) {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, true
+}
+
+// KeyLister is implemented by Attributes that can list their keys, which is
+// needed to serialize them.  Attributes layered over other Attributes should
+// only list their keys if the layer beneath them can.
+type KeyLister interface {
+	Keys() ([]string, bool)
+}
+
+// AttributeKeys returns the keys of the attributes given, sorted and without
+// duplicates, if the attributes can list them.
+func AttributeKeys(a Attributes) ([]string, bool) {
+	l, ok := a.(KeyLister)
+	if !ok {
+		return nil, false
+	}
+	keys, ok := l.Keys()
+	if !ok {
+		return nil, false
+	}
+	sort.Strings(keys)
+	j := 0
+	for i, k := range keys {
+		if i == 0 || k != keys[j-1] {
+			keys[j] = k
+			j++
+		}
+	}
+	return keys[:j], true
+}
+
+// LayeredKeys returns the keys given along with the keys of the base
+// attributes, for implementing KeyLister on Attributes layered over others.
+// It returns false if the base attributes can't list their keys.
+func LayeredKeys(base Attributes, keys ...string) ([]string, bool) {
+	if base == nil {
+		return keys, true
+	}
+	baseKeys, ok := AttributeKeys(base)
+	if !ok {
+		return nil, false
+	}
+	return append(keys, baseKeys...), true
+}
+
 //NewAttributes builds an Attributes instance with
 //the given map as datasource.
 func NewAttributes(m map[string]interface{}) Attributes {
 	return BasicAttributes(m)
 }
 
-// GetNestedAttribute uses multiple keys in order to obtain an attribute.
+// GetNestedAttribute uses multiple keys in order to obtain an attribute.  When
+// the value reached is an array, the next key is treated as an index into it.
+// For more complex lookups, see AttributePath.
 func GetNestedAttribute(attributes Attributes, keys ...string) (interface{}, bool) {
 	// need at least one key.
 	if len(keys) == 0 {
@@ -51,6 +109,14 @@ func GetNestedAttribute(attributes Attributes, keys ...string) (interface{}, boo
 		if result == nil {
 			return nil, false
 		}
+		if l, isSlice := asSlice(result); isSlice {
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= l.Len() {
+				return nil, false
+			}
+			result = l.Index(i).Interface()
+			continue
+		}
 		ok = arrange.TryConvert(result,
 			func(attr Attributes) { a = attr },
 			func(m map[string]interface{}) { a = BasicAttributes(m) },
@@ -63,5 +129,5 @@ func GetNestedAttribute(attributes Attributes, keys ...string) (interface{}, boo
 			return nil, false
 		}
 	}
-	return result, ok
+	return result, true
 }
@@ -46,6 +46,9 @@ func TestGetNestedAttribute(t *testing.T) {
 		"a":         map[string]interface{}{"b": map[string]interface{}{"c": "answer"}},
 		"one level": "yay",
 		"bad":       nil,
+		"list": []interface{}{
+			map[string]interface{}{"id": "first"},
+		},
 	})
 	tests := []struct {
 		description    string
@@ -72,6 +75,20 @@ func TestGetNestedAttribute(t *testing.T) {
 			expectedResult: nil,
 			expectedOK:     true,
 		},
+		{
+			description:    "Success array index",
+			keys:           []string{"list", "0", "id"},
+			expectedResult: "first",
+			expectedOK:     true,
+		},
+		{
+			description: "Array Index Out Of Range Error",
+			keys:        []string{"list", "1", "id"},
+		},
+		{
+			description: "Array Non Numeric Index Error",
+			keys:        []string{"list", "id"},
+		},
 		{
 			description: "Nil Keys Error",
 			keys:        nil,
@@ -106,3 +123,50 @@ func TestGetNestedAttribute(t *testing.T) {
 		})
 	}
 }
+
+type unlistedAttributes struct{}
+
+func (unlistedAttributes) Get(string) (interface{}, bool) {
+	return nil, false
+}
+
+func TestAttributeKeys(t *testing.T) {
+	tests := []struct {
+		description  string
+		attributes   Attributes
+		expectedKeys []string
+		expectedOK   bool
+	}{
+		{
+			description:  "Basic",
+			attributes:   BasicAttributes{"b": 1, "a": 2},
+			expectedKeys: []string{"a", "b"},
+			expectedOK:   true,
+		},
+		{
+			description:  "Layered",
+			attributes:   mappedAttributes{canonical: BasicAttributes{"c": 1, "a": 2}, base: BasicAttributes{"b": 3, "a": 4}},
+			expectedKeys: []string{"a", "b", "c"},
+			expectedOK:   true,
+		},
+		{
+			description: "Layered Over Unlisted",
+			attributes:  mappedAttributes{canonical: BasicAttributes{"a": 1}, base: unlistedAttributes{}},
+		},
+		{
+			description: "Unlisted",
+			attributes:  unlistedAttributes{},
+		},
+		{
+			description: "Nil",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			keys, ok := AttributeKeys(tc.attributes)
+			assert.Equal(tc.expectedOK, ok)
+			assert.Equal(tc.expectedKeys, keys)
+		})
+	}
+}
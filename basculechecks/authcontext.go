@@ -0,0 +1,222 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+var (
+	ErrUnknownACRLevel = errors.New("unknown acr level")
+
+	// ErrInsufficientACR is returned when the token's authentication context
+	// class is below the minimum required.
+	ErrInsufficientACR = errWithReason{
+		err:    errors.New("authentication context class too low"),
+		reason: InsufficientACR,
+	}
+
+	// ErrMissingAMR is returned when the token wasn't authenticated with any
+	// of the methods required.
+	ErrMissingAMR = errWithReason{
+		err:    errors.New("required authentication method missing"),
+		reason: MissingAMR,
+	}
+)
+
+// AuthContextPolicy is what a request requires of how its token's user
+// authenticated.  The zero value requires nothing.
+type AuthContextPolicy struct {
+	// MinACR is the lowest acceptable acr claim value, from the config's
+	// ACRLevels.
+	MinACR string
+
+	// AMR are authentication methods, such as "mfa" or "hwk", at least one of
+	// which must be in the token's amr claim.
+	AMR []string
+}
+
+// AuthContextEndpointPolicy is the AuthContextPolicy for requests to an
+// endpoint.
+type AuthContextEndpointPolicy struct {
+	// Endpoint is a regular expression the request path must match from its
+	// start.
+	Endpoint string
+
+	AuthContextPolicy
+}
+
+// AuthContextConfig configures an AuthContextValidator.
+type AuthContextConfig struct {
+	// ACRLevels are the acr values the IdP issues, from weakest to strongest.
+	// Tokens with an acr value not in the list don't meet any minimum.
+	ACRLevels []string
+
+	// ACRKey is the claim holding the authentication context class.
+	// Defaults to "acr".
+	ACRKey string
+
+	// AMRKey is the claim holding the authentication methods.  Defaults to
+	// "amr".
+	AMRKey string
+
+	// Default is the policy for requests that don't match an endpoint.
+	Default AuthContextPolicy
+
+	// Endpoints are the policies for endpoint buckets, checked in order.
+	Endpoints []AuthContextEndpointPolicy
+}
+
+type authContextPolicy struct {
+	minLevel int
+	amr      []string
+}
+
+type authContextEndpoint struct {
+	endpoint *regexp.Regexp
+	policy   authContextPolicy
+}
+
+// AuthContextValidator requires tokens to have come from a strong enough
+// authentication, as described by their acr and amr claims, so that policies
+// can tell password only sessions from MFA ones.
+type AuthContextValidator struct {
+	levels    map[string]int
+	acrKey    string
+	amrKey    string
+	fallback  authContextPolicy
+	endpoints []authContextEndpoint
+}
+
+// NewAuthContextValidator creates an AuthContextValidator from the config.
+// Every MinACR must be one of the ACRLevels.
+func NewAuthContextValidator(config AuthContextConfig) (*AuthContextValidator, error) {
+	a := &AuthContextValidator{
+		levels: make(map[string]int, len(config.ACRLevels)),
+		acrKey: config.ACRKey,
+		amrKey: config.AMRKey,
+	}
+	if a.acrKey == "" {
+		a.acrKey = "acr"
+	}
+	if a.amrKey == "" {
+		a.amrKey = "amr"
+	}
+	for i, l := range config.ACRLevels {
+		a.levels[l] = i + 1
+	}
+
+	var err error
+	a.fallback, err = a.policy(config.Default)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range config.Endpoints {
+		r, err := regexp.Compile(e.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile endpoint [%v]: %w", e.Endpoint, err)
+		}
+		p, err := a.policy(e.AuthContextPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint [%v]: %w", e.Endpoint, err)
+		}
+		a.endpoints = append(a.endpoints, authContextEndpoint{endpoint: r, policy: p})
+	}
+	return a, nil
+}
+
+func (a *AuthContextValidator) policy(p AuthContextPolicy) (authContextPolicy, error) {
+	policy := authContextPolicy{amr: p.AMR}
+	if p.MinACR != "" {
+		level, ok := a.levels[p.MinACR]
+		if !ok {
+			return policy, fmt.Errorf("%w [%v]", ErrUnknownACRLevel, p.MinACR)
+		}
+		policy.minLevel = level
+	}
+	return policy, nil
+}
+
+// Check compares the token's acr and amr claims to the policy for the
+// request.
+func (a *AuthContextValidator) Check(ctx context.Context, token bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return ErrNoAuth
+	}
+	policy := a.policyFor(auth)
+	if policy.minLevel == 0 && len(policy.amr) == 0 {
+		return nil
+	}
+	var attributes bascule.Attributes
+	if token != nil {
+		attributes = token.Attributes()
+	}
+
+	if policy.minLevel > 0 {
+		var acr string
+		if v, ok := bascule.GetNestedAttribute(attributes, a.acrKey); ok {
+			acr = cast.ToString(v)
+		}
+		if a.levels[acr] < policy.minLevel {
+			return fmt.Errorf("%w: [%v]", ErrInsufficientACR, acr)
+		}
+	}
+
+	if len(policy.amr) > 0 {
+		var methods []string
+		if v, ok := bascule.GetNestedAttribute(attributes, a.amrKey); ok {
+			methods, _ = cast.ToStringSliceE(v)
+		}
+		if !containsAny(methods, policy.amr) {
+			return fmt.Errorf("%w: need one of %v, have %v", ErrMissingAMR, policy.amr, methods)
+		}
+	}
+	return nil
+}
+
+// policyFor returns the policy of the first endpoint the request matches, or
+// the default.
+func (a *AuthContextValidator) policyFor(auth bascule.Authentication) authContextPolicy {
+	if auth.Request.URL != nil {
+		path := auth.Request.URL.EscapedPath()
+		for _, e := range a.endpoints {
+			if idxs := e.endpoint.FindStringIndex(path); len(idxs) > 0 && idxs[0] == 0 {
+				return e.policy
+			}
+		}
+	}
+	return a.fallback
+}
+
+func containsAny(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*AuthContextValidator)(nil)
+
+func TestNewAuthContextValidator(t *testing.T) {
+	tests := []struct {
+		description string
+		config      AuthContextConfig
+		expectedErr error
+	}{
+		{
+			description: "Unknown Default Level",
+			config: AuthContextConfig{
+				Default: AuthContextPolicy{MinACR: "gold"},
+			},
+			expectedErr: ErrUnknownACRLevel,
+		},
+		{
+			description: "Unknown Endpoint Level",
+			config: AuthContextConfig{
+				ACRLevels: []string{"silver"},
+				Endpoints: []AuthContextEndpointPolicy{
+					{Endpoint: "/admin", AuthContextPolicy: AuthContextPolicy{MinACR: "gold"}},
+				},
+			},
+			expectedErr: ErrUnknownACRLevel,
+		},
+		{
+			description: "Invalid Endpoint",
+			config: AuthContextConfig{
+				Endpoints: []AuthContextEndpointPolicy{{Endpoint: "("}},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			a, err := NewAuthContextValidator(tc.config)
+			assert.Error(err)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+			assert.Nil(a)
+		})
+	}
+
+	a, err := NewAuthContextValidator(AuthContextConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "acr", a.acrKey)
+	assert.Equal(t, "amr", a.amrKey)
+}
+
+func TestAuthContextValidator(t *testing.T) {
+	config := AuthContextConfig{
+		ACRLevels: []string{"pwd", "mfa", "phr"},
+		Default:   AuthContextPolicy{MinACR: "pwd"},
+		Endpoints: []AuthContextEndpointPolicy{
+			{
+				Endpoint:          "/admin",
+				AuthContextPolicy: AuthContextPolicy{MinACR: "mfa", AMR: []string{"mfa", "hwk"}},
+			},
+			{
+				Endpoint: "/public",
+			},
+		},
+	}
+	tests := []struct {
+		description string
+		path        string
+		attributes  map[string]interface{}
+		noAuth      bool
+		expectedErr error
+	}{
+		{
+			description: "Default Met",
+			path:        "/things",
+			attributes:  map[string]interface{}{"acr": "pwd"},
+		},
+		{
+			description: "Default Missing ACR",
+			path:        "/things",
+			expectedErr: ErrInsufficientACR,
+		},
+		{
+			description: "Default Unknown ACR",
+			path:        "/things",
+			attributes:  map[string]interface{}{"acr": "none"},
+			expectedErr: ErrInsufficientACR,
+		},
+		{
+			description: "Public",
+			path:        "/public/docs",
+		},
+		{
+			description: "Admin MFA",
+			path:        "/admin/users",
+			attributes: map[string]interface{}{
+				"acr": "mfa",
+				"amr": []interface{}{"pwd", "otp", "mfa"},
+			},
+		},
+		{
+			description: "Admin Hardware Key",
+			path:        "/admin/users",
+			attributes: map[string]interface{}{
+				"acr": "phr",
+				"amr": []string{"hwk"},
+			},
+		},
+		{
+			description: "Admin Password Only",
+			path:        "/admin/users",
+			attributes: map[string]interface{}{
+				"acr": "pwd",
+				"amr": []string{"pwd"},
+			},
+			expectedErr: ErrInsufficientACR,
+		},
+		{
+			description: "Admin Missing AMR",
+			path:        "/admin/users",
+			attributes: map[string]interface{}{
+				"acr": "mfa",
+				"amr": []string{"pwd"},
+			},
+			expectedErr: ErrMissingAMR,
+		},
+		{
+			description: "Admin No AMR",
+			path:        "/admin/users",
+			attributes:  map[string]interface{}{"acr": "mfa"},
+			expectedErr: ErrMissingAMR,
+		},
+		{
+			description: "Endpoint Must Match From Start",
+			path:        "/v1/admin",
+			attributes:  map[string]interface{}{"acr": "pwd"},
+		},
+		{
+			description: "No Auth",
+			noAuth:      true,
+			expectedErr: ErrNoAuth,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			a, err := NewAuthContextValidator(config)
+			require.NoError(err)
+
+			token := bascule.NewToken("test", "alice", bascule.NewAttributes(tc.attributes))
+			ctx := context.Background()
+			if !tc.noAuth {
+				u, err := url.Parse(tc.path)
+				require.NoError(err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   token,
+					Request: bascule.Request{URL: u, Method: "GET"},
+				})
+			}
+			err = a.Check(ctx, token)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedErr != ErrNoAuth {
+				assert.Equal(bascule.CapabilityClass, bascule.ClassOf(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+// Kinds of token binding anomalies.
+const (
+	IPBinding        = "ip"
+	UserAgentBinding = "user_agent"
+)
+
+var (
+	// ErrBindingMismatch is returned by an enforcing BindingValidator when
+	// the request doesn't come from the client the token was issued to.
+	ErrBindingMismatch = errWithReason{
+		err:    errors.New("token used from a different client than it was issued to"),
+		reason: BindingMismatch,
+	}
+)
+
+// BindingConfig configures a BindingValidator.
+type BindingConfig struct {
+	// IPClaims are the claims that can hold the IP address or CIDR block the
+	// token was issued to.  The first one in the token is used.  Defaults to
+	// "cdniip" and "ip".
+	IPClaims []string
+
+	// UserAgentClaim is the claim holding the hash of the User-Agent the
+	// token was issued to.  Defaults to "ua-hash".
+	UserAgentClaim string
+
+	// UserAgentHash hashes the request's User-Agent for comparing to the
+	// claim.  Defaults to the hex encoded SHA-256 hash.
+	UserAgentHash func(string) string
+
+	// Enforce rejects requests that don't match the token.  Otherwise,
+	// mismatches are only recorded in the anomaly metric.
+	Enforce bool
+}
+
+// BindingValidator compares the client a token was issued to, as recorded in
+// its claims, with where the request came from.  A mismatch may mean the
+// token was stolen.  Tokens without the claims aren't checked, and neither
+// are requests whose source wasn't captured.
+type BindingValidator struct {
+	config   BindingConfig
+	measures *BindingMeasures
+	server   string
+	clientID ClientIDTransform
+}
+
+// NewBindingValidator creates a BindingValidator.  The measures and client ID
+// transform are optional.
+func NewBindingValidator(config BindingConfig, measures *BindingMeasures, server string, clientID ClientIDTransform) *BindingValidator {
+	if len(config.IPClaims) == 0 {
+		config.IPClaims = []string{"cdniip", "ip"}
+	}
+	if config.UserAgentClaim == "" {
+		config.UserAgentClaim = "ua-hash"
+	}
+	if config.UserAgentHash == nil {
+		config.UserAgentHash = HashUserAgent
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	return &BindingValidator{
+		config:   config,
+		measures: measures,
+		server:   server,
+		clientID: clientID,
+	}
+}
+
+// HashUserAgent returns the hex encoded SHA-256 hash of the User-Agent.
+func HashUserAgent(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// Check compares the token's binding claims to the request in the
+// bascule.Authentication in the context.
+func (b *BindingValidator) Check(ctx context.Context, token bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || token == nil || token.Attributes() == nil {
+		return nil
+	}
+	var mismatched []string
+	if !b.ipMatches(token.Attributes(), auth.Request.ClientIP) {
+		mismatched = append(mismatched, IPBinding)
+	}
+	if !b.userAgentMatches(token.Attributes(), auth.Request.UserAgent) {
+		mismatched = append(mismatched, UserAgentBinding)
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	for _, kind := range mismatched {
+		b.record(token.Principal(), kind)
+	}
+	if !b.config.Enforce {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrBindingMismatch, strings.Join(mismatched, ", "))
+}
+
+// ipMatches returns false if the token is bound to an address or block that
+// doesn't contain the client IP.
+func (b *BindingValidator) ipMatches(attributes bascule.Attributes, clientIP string) bool {
+	if clientIP == "" {
+		return true
+	}
+	var bound string
+	for _, claim := range b.config.IPClaims {
+		if v, ok := attributes.Get(claim); ok {
+			bound, _ = cast.ToStringE(v)
+			break
+		}
+	}
+	if bound == "" {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	if _, block, err := net.ParseCIDR(bound); err == nil {
+		return block.Contains(ip)
+	}
+	return ip.Equal(net.ParseIP(bound))
+}
+
+// userAgentMatches returns false if the token is bound to the hash of a
+// different User-Agent.
+func (b *BindingValidator) userAgentMatches(attributes bascule.Attributes, userAgent string) bool {
+	v, ok := attributes.Get(b.config.UserAgentClaim)
+	if !ok {
+		return true
+	}
+	bound, _ := cast.ToStringE(v)
+	if bound == "" {
+		return true
+	}
+	return strings.EqualFold(bound, b.config.UserAgentHash(userAgent))
+}
+
+func (b *BindingValidator) record(principal, kind string) {
+	if b.measures == nil || b.measures.Anomalies == nil {
+		return
+	}
+	client := principal
+	if b.clientID != nil {
+		client = b.clientID(client)
+	}
+	b.measures.Anomalies.With(prometheus.Labels{
+		ServerLabel:   b.server,
+		ClientIDLabel: client,
+		BindingLabel:  kind,
+	}).Inc()
+}
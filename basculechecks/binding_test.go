@@ -0,0 +1,157 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ bascule.Validator = (*BindingValidator)(nil)
+
+func newBindingMeasures() *BindingMeasures {
+	return &BindingMeasures{
+		Anomalies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testBindingAnomalies",
+		}, []string{ServerLabel, ClientIDLabel, BindingLabel}),
+	}
+}
+
+func TestBindingValidator(t *testing.T) {
+	const userAgent = "curl/8.0"
+	tests := []struct {
+		description        string
+		config             BindingConfig
+		claims             map[string]interface{}
+		request            bascule.Request
+		noAuth             bool
+		expectedErr        bool
+		expectedIP         float64
+		expectedUserAgent  float64
+		expectedErrContent string
+	}{
+		{
+			description: "Match",
+			claims:      map[string]interface{}{"ip": "10.0.0.1", "ua-hash": HashUserAgent(userAgent)},
+			request:     bascule.Request{ClientIP: "10.0.0.1", UserAgent: userAgent},
+		},
+		{
+			description: "Match CIDR And Uppercase Hash",
+			claims:      map[string]interface{}{"cdniip": "10.0.0.0/24", "ip": "192.168.0.1", "ua-hash": strings.ToUpper(HashUserAgent(userAgent))},
+			request:     bascule.Request{ClientIP: "10.0.0.99", UserAgent: userAgent},
+		},
+		{
+			description: "No Claims",
+			claims:      map[string]interface{}{},
+			request:     bascule.Request{ClientIP: "10.0.0.1", UserAgent: userAgent},
+		},
+		{
+			description: "No Source",
+			claims:      map[string]interface{}{"ip": "10.0.0.1"},
+		},
+		{
+			description: "No Auth",
+			claims:      map[string]interface{}{"ip": "10.0.0.1"},
+			noAuth:      true,
+		},
+		{
+			description:       "Monitored Mismatch",
+			claims:            map[string]interface{}{"ip": "10.0.0.1", "ua-hash": HashUserAgent(userAgent)},
+			request:           bascule.Request{ClientIP: "10.0.0.2", UserAgent: "other"},
+			expectedIP:        1,
+			expectedUserAgent: 1,
+		},
+		{
+			description:        "Enforced IP Mismatch",
+			config:             BindingConfig{Enforce: true},
+			claims:             map[string]interface{}{"cdniip": "10.0.0.0/24"},
+			request:            bascule.Request{ClientIP: "10.0.1.1", UserAgent: userAgent},
+			expectedErr:        true,
+			expectedIP:         1,
+			expectedErrContent: "ip",
+		},
+		{
+			description:        "Enforced Unparseable Client IP",
+			config:             BindingConfig{Enforce: true},
+			claims:             map[string]interface{}{"ip": "10.0.0.1"},
+			request:            bascule.Request{ClientIP: "not-an-ip"},
+			expectedErr:        true,
+			expectedIP:         1,
+			expectedErrContent: "ip",
+		},
+		{
+			description: "Enforced Custom Claims",
+			config: BindingConfig{
+				IPClaims:       []string{"addr"},
+				UserAgentClaim: "agent",
+				UserAgentHash:  strings.ToLower,
+				Enforce:        true,
+			},
+			claims:             map[string]interface{}{"ip": "10.0.0.1", "agent": "curl"},
+			request:            bascule.Request{ClientIP: "10.0.0.2", UserAgent: "wget"},
+			expectedErr:        true,
+			expectedUserAgent:  1,
+			expectedErrContent: "user_agent",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			measures := newBindingMeasures()
+			b := NewBindingValidator(tc.config, measures, "", HashClientID(4))
+			token := bascule.NewToken("test", "alice", bascule.NewAttributes(tc.claims))
+			ctx := context.Background()
+			if !tc.noAuth {
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{Token: token, Request: tc.request})
+			}
+			err := b.Check(ctx, token)
+			if tc.expectedErr {
+				assert.ErrorIs(err, ErrBindingMismatch)
+				assert.Contains(err.Error(), tc.expectedErrContent)
+				assert.Equal(bascule.InvalidClass, bascule.ClassOf(err))
+			} else {
+				assert.NoError(err)
+			}
+			client := HashClientID(4)("alice")
+			assert.Equal(tc.expectedIP, testutil.ToFloat64(measures.Anomalies.With(prometheus.Labels{
+				ServerLabel: defaultServer, ClientIDLabel: client, BindingLabel: IPBinding,
+			})))
+			assert.Equal(tc.expectedUserAgent, testutil.ToFloat64(measures.Anomalies.With(prometheus.Labels{
+				ServerLabel: defaultServer, ClientIDLabel: client, BindingLabel: UserAgentBinding,
+			})))
+		})
+	}
+}
+
+func TestBindingValidatorNoMeasures(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBindingValidator(BindingConfig{Enforce: true}, nil, "server", nil)
+	token := bascule.NewToken("test", "alice", bascule.NewAttributes(map[string]interface{}{"ip": "10.0.0.1"}))
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token:   token,
+		Request: bascule.Request{ClientIP: "10.0.0.2"},
+	})
+	assert.ErrorIs(b.Check(ctx, token), ErrBindingMismatch)
+	assert.NoError(b.Check(ctx, nil))
+}
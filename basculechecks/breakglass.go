@@ -0,0 +1,192 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/zap"
+)
+
+// BreakGlassRule is the rule name added to the bascule.Decision in the
+// context when a request is allowed by break-glass access.
+const BreakGlassRule = "break_glass"
+
+// BreakGlassConfig configures a BreakGlassChecker.  A token is marked for
+// break-glass access by either one of the capabilities or the claim.
+type BreakGlassConfig struct {
+	// Capabilities are the emergency capabilities that bypass the normal
+	// capability checks.
+	Capabilities []string
+
+	// ClaimKeyPath is the path to a boolean claim that, when true, bypasses
+	// the normal capability checks.
+	ClaimKeyPath []string
+
+	// ReasonKeyPath is the path to the claim holding the justification for
+	// the emergency access, such as an incident ID, which is included in the
+	// audit log.  Defaults to "incident".
+	ReasonKeyPath []string
+
+	// RequireReason only allows break-glass access to tokens with a reason.
+	// Tokens without one go through the normal capability checks.
+	RequireReason bool
+
+	// GetLogger gets the audit logger from the context.  Defaults to
+	// sallust.Get.
+	GetLogger func(context.Context) *zap.Logger
+}
+
+// BreakGlassChecker is a CapabilitiesChecker that lets tokens marked for
+// emergency access through without the normal capability checks, so that
+// incidents can be handled without turning off enforcement for everyone.
+// Every request allowed this way is logged for audit and counted.  All other
+// tokens are checked by the wrapped CapabilitiesChecker.
+type BreakGlassChecker struct {
+	checker      CapabilitiesChecker
+	capabilities map[string]bool
+	config       BreakGlassConfig
+	measures     *BreakGlassMeasures
+	server       string
+}
+
+// NewBreakGlassChecker wraps the checker given.  The checker cannot be nil;
+// the measures are optional.
+func NewBreakGlassChecker(checker CapabilitiesChecker, config BreakGlassConfig, measures *BreakGlassMeasures, server string) (*BreakGlassChecker, error) {
+	if checker == nil {
+		return nil, ErrNilChecker
+	}
+	if len(config.ReasonKeyPath) == 0 {
+		config.ReasonKeyPath = []string{"incident"}
+	}
+	if config.GetLogger == nil {
+		config.GetLogger = sallust.Get
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	b := &BreakGlassChecker{
+		checker:      checker,
+		capabilities: make(map[string]bool, len(config.Capabilities)),
+		config:       config,
+		measures:     measures,
+		server:       server,
+	}
+	for _, c := range config.Capabilities {
+		b.capabilities[c] = true
+	}
+	return b, nil
+}
+
+// CheckAuthentication allows break-glass tokens and checks all others with
+// the wrapped checker.  Use CheckAuthenticationCtx where possible so the
+// audit log uses the request's logger.
+func (b *BreakGlassChecker) CheckAuthentication(auth bascule.Authentication, vals ParsedValues) error {
+	return b.CheckAuthenticationCtx(context.Background(), auth, vals)
+}
+
+// CheckAuthenticationCtx allows break-glass tokens, recording the access in
+// the audit log, metric, and bascule.Decision in the context.  All other
+// tokens are checked by the wrapped checker.
+func (b *BreakGlassChecker) CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, vals ParsedValues) error {
+	marker, reason, ok := b.breakGlass(auth.Token)
+	if !ok {
+		return CheckWithContext(ctx, b.checker, auth, vals)
+	}
+
+	path := ""
+	if auth.Request.URL != nil {
+		path = auth.Request.URL.EscapedPath()
+	}
+	logger := b.config.GetLogger(ctx)
+	if logger == nil {
+		logger = sallust.Get(ctx)
+	}
+	logger.Warn("break-glass access granted",
+		zap.String("principal", auth.Token.Principal()),
+		zap.String("marker", marker),
+		zap.String("reason", reason),
+		zap.String("method", auth.Request.Method),
+		zap.String("path", path),
+		zap.String("endpoint", vals.Endpoint),
+		zap.String("clientIP", auth.Request.ClientIP),
+		zap.String("userAgent", auth.Request.UserAgent),
+		zap.String("requestID", auth.Request.RequestID))
+	b.record(auth.Token.Principal(), vals.Endpoint, auth.Request.Method)
+
+	recordCapability(ctx, marker)
+	if d, ok := bascule.DecisionFromContext(ctx); ok {
+		d.AddRule(BreakGlassRule)
+	}
+	return nil
+}
+
+// breakGlass returns the capability or claim marking the token for
+// break-glass access, and the reason given for it.
+func (b *BreakGlassChecker) breakGlass(token bascule.Token) (string, string, bool) {
+	if token == nil || token.Attributes() == nil {
+		return "", "", false
+	}
+	attributes := token.Attributes()
+	marker := b.marker(attributes)
+	if marker == "" {
+		return "", "", false
+	}
+	var reason string
+	if v, ok := bascule.GetNestedAttribute(attributes, b.config.ReasonKeyPath...); ok {
+		reason = cast.ToString(v)
+	}
+	if reason == "" && b.config.RequireReason {
+		return "", "", false
+	}
+	return marker, reason, true
+}
+
+func (b *BreakGlassChecker) marker(attributes bascule.Attributes) string {
+	if len(b.capabilities) > 0 {
+		capabilities, _ := getCapabilities(attributes, nil)
+		for _, c := range capabilities {
+			if b.capabilities[c] {
+				return c
+			}
+		}
+	}
+	if len(b.config.ClaimKeyPath) > 0 {
+		v, ok := bascule.GetNestedAttribute(attributes, b.config.ClaimKeyPath...)
+		if ok && cast.ToBool(v) {
+			return b.config.ClaimKeyPath[len(b.config.ClaimKeyPath)-1]
+		}
+	}
+	return ""
+}
+
+func (b *BreakGlassChecker) record(principal, endpoint, method string) {
+	if b.measures == nil || b.measures.Uses == nil {
+		return
+	}
+	b.measures.Uses.With(prometheus.Labels{
+		ServerLabel:   b.server,
+		ClientIDLabel: principal,
+		EndpointLabel: endpoint,
+		MethodLabel:   method,
+	}).Inc()
+}
@@ -0,0 +1,169 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var (
+	_ CapabilitiesChecker    = (*BreakGlassChecker)(nil)
+	_ CapabilitiesCheckerCtx = (*BreakGlassChecker)(nil)
+)
+
+func TestNewBreakGlassChecker(t *testing.T) {
+	assert := assert.New(t)
+	b, err := NewBreakGlassChecker(nil, BreakGlassConfig{}, nil, "")
+	assert.ErrorIs(err, ErrNilChecker)
+	assert.Nil(b)
+
+	b, err = NewBreakGlassChecker(CapabilitiesCheckerCtxFunc(nil), BreakGlassConfig{}, nil, "")
+	assert.NoError(err)
+	assert.Equal(defaultServer, b.server)
+	assert.Equal([]string{"incident"}, b.config.ReasonKeyPath)
+	assert.NotNil(b.config.GetLogger)
+}
+
+func TestBreakGlassChecker(t *testing.T) {
+	errDenied := errors.New("denied")
+	tests := []struct {
+		description    string
+		attributes     map[string]interface{}
+		requireReason  bool
+		expectedErr    error
+		expectedMarker string
+		expectedReason string
+	}{
+		{
+			description: "Normal Token",
+			attributes:  buildDummyAttributes(CapabilityKeys(), []string{"read"}),
+			expectedErr: errDenied,
+		},
+		{
+			description: "No Attributes",
+			expectedErr: errDenied,
+		},
+		{
+			description:    "Capability",
+			attributes:     buildDummyAttributes(CapabilityKeys(), []string{"read", "emergency:all"}),
+			expectedMarker: "emergency:all",
+		},
+		{
+			description: "Claim",
+			attributes: map[string]interface{}{
+				"breakGlass": true,
+				"incident":   "INC-42",
+			},
+			expectedMarker: "breakGlass",
+			expectedReason: "INC-42",
+		},
+		{
+			description: "False Claim",
+			attributes:  map[string]interface{}{"breakGlass": false},
+			expectedErr: errDenied,
+		},
+		{
+			description: "Reason Required",
+			attributes: map[string]interface{}{
+				"breakGlass": "true",
+				"incident":   "INC-42",
+			},
+			requireReason:  true,
+			expectedMarker: "breakGlass",
+			expectedReason: "INC-42",
+		},
+		{
+			description:   "Reason Missing",
+			attributes:    map[string]interface{}{"breakGlass": true},
+			requireReason: true,
+			expectedErr:   errDenied,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			core, logs := observer.New(zapcore.DebugLevel)
+			measures := &BreakGlassMeasures{
+				Uses: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testBreakGlassUses",
+				}, []string{ServerLabel, ClientIDLabel, EndpointLabel, MethodLabel}),
+			}
+			checker := CapabilitiesCheckerCtxFunc(func(context.Context, bascule.Authentication, ParsedValues) error {
+				return errDenied
+			})
+			b, err := NewBreakGlassChecker(checker, BreakGlassConfig{
+				Capabilities:  []string{"emergency:all"},
+				ClaimKeyPath:  []string{"breakGlass"},
+				RequireReason: tc.requireReason,
+				GetLogger: func(context.Context) *zap.Logger {
+					return zap.New(core)
+				},
+			}, measures, "")
+			require.NoError(err)
+
+			u, err := url.Parse("/things/1")
+			require.NoError(err)
+			auth := bascule.Authentication{
+				Token:   bascule.NewToken("test", "alice", bascule.NewAttributes(tc.attributes)),
+				Request: bascule.Request{URL: u, Method: "DELETE", ClientIP: "10.0.0.1"},
+			}
+			d := bascule.NewDecision()
+			ctx := bascule.WithDecision(context.Background(), d)
+			err = CheckWithContext(ctx, b, auth, ParsedValues{Endpoint: "/things/.*"})
+			uses := testutil.ToFloat64(measures.Uses.With(prometheus.Labels{
+				ServerLabel:   defaultServer,
+				ClientIDLabel: "alice",
+				EndpointLabel: "/things/.*",
+				MethodLabel:   "DELETE",
+			}))
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Zero(logs.Len())
+				assert.Zero(uses)
+				assert.False(d.Passed(BreakGlassRule))
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(1.0, uses)
+			assert.True(d.Passed(BreakGlassRule))
+			assert.Equal(tc.expectedMarker, d.Capability())
+			require.Equal(1, logs.Len())
+			entry := logs.All()[0]
+			assert.Equal(zapcore.WarnLevel, entry.Level)
+			fields := entry.ContextMap()
+			assert.Equal("alice", fields["principal"])
+			assert.Equal(tc.expectedMarker, fields["marker"])
+			assert.Equal(tc.expectedReason, fields["reason"])
+			assert.Equal("/things/1", fields["path"])
+			assert.Equal("10.0.0.1", fields["clientIP"])
+		})
+	}
+}
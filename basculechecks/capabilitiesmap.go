@@ -18,11 +18,12 @@
 package basculechecks
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
 
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
 )
 
 var (
@@ -41,6 +42,10 @@ var (
 type CapabilitiesMapConfig struct {
 	Endpoints map[string]string
 	Default   string
+
+	// PartnerKeyPaths are searched in order for the partner IDs used in the
+	// metric labels.  See WithPartnerKeyPaths.
+	PartnerKeyPaths [][]string
 }
 
 // CapabilitiesMap runs a capability check based on the value of the parsedURL,
@@ -59,21 +64,39 @@ type CapabilitiesMap struct {
 // capability is found to be authorized by the EndpointChecker, no error is
 // returned.
 func (c CapabilitiesMap) CheckAuthentication(auth bascule.Authentication, vs ParsedValues) error {
+	_, err := c.match(auth, vs)
+	return err
+}
+
+// CheckAuthenticationCtx is CheckAuthentication, also recording the
+// capability that authorized the request in the bascule.Decision in the
+// context.
+func (c CapabilitiesMap) CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, vs ParsedValues) error {
+	capability, err := c.match(auth, vs)
+	if err != nil {
+		return err
+	}
+	recordCapability(ctx, capability)
+	return nil
+}
+
+// match returns the capability that authorizes the request.
+func (c CapabilitiesMap) match(auth bascule.Authentication, vs ParsedValues) (string, error) {
 	if auth.Token == nil {
-		return ErrNoToken
+		return "", ErrNoToken
 	}
 
 	if auth.Request.URL == nil {
-		return ErrNoURL
+		return "", ErrNoURL
 	}
 
 	if vs.Endpoint == "" {
-		return ErrEmptyEndpoint
+		return "", ErrEmptyEndpoint
 	}
 
 	capabilities, err := getCapabilities(auth.Token.Attributes(), c.KeyPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// determine which EndpointChecker to use.
@@ -88,7 +111,7 @@ func (c CapabilitiesMap) CheckAuthentication(auth bascule.Authentication, vs Par
 	// false.
 	if checker == nil {
 		// ErrNoValidCapabilityFound is a Reasoner.
-		return fmt.Errorf("%w in [%v] with nil endpoint checker",
+		return "", fmt.Errorf("%w in [%v] with nil endpoint checker",
 			ErrNoValidCapabilityFound, capabilities)
 	}
 
@@ -96,11 +119,11 @@ func (c CapabilitiesMap) CheckAuthentication(auth bascule.Authentication, vs Par
 	// for this endpoint.
 	for _, capability := range capabilities {
 		if checker.Authorized(capability, reqURL, method) {
-			return nil
+			return capability, nil
 		}
 	}
 
-	return fmt.Errorf("%w in [%v] with %v endpoint checker",
+	return "", fmt.Errorf("%w in [%v] with %v endpoint checker",
 		ErrNoValidCapabilityFound, capabilities, checker.Name())
 }
 
@@ -135,8 +158,12 @@ func NewCapabilitiesMap(config CapabilitiesMapConfig) (CapabilitiesCheckerOut, e
 		DefaultChecker: defaultChecker,
 	}
 
+	os := []MetricOption{WithEndpoints(rs)}
+	if len(config.PartnerKeyPaths) > 0 {
+		os = append(os, WithPartnerKeyPaths(config.PartnerKeyPaths...))
+	}
 	return CapabilitiesCheckerOut{
 		Checker: cc,
-		Options: []MetricOption{WithEndpoints(rs)},
+		Options: os,
 	}, nil
 }
@@ -18,14 +18,15 @@
 package basculechecks
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/xmidt-org/bascule"
 )
 
 func TestCapabilitiesMapCheck(t *testing.T) {
@@ -175,6 +176,36 @@ func TestCapabilitiesMapCheck(t *testing.T) {
 	}
 }
 
+func TestCapabilitiesMapCheckCtx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	cm := CapabilitiesMap{
+		Checkers:       map[string]EndpointChecker{"a": ConstEndpointCheck("yay")},
+		DefaultChecker: ConstEndpointCheck("default checker"),
+	}
+	u, err := url.Parse("/test")
+	require.NoError(err)
+	auth := bascule.Authentication{
+		Token: bascule.NewToken("test", "princ",
+			bascule.NewAttributes(
+				buildDummyAttributes(CapabilityKeys(), []string{"test", "yay"}))),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	}
+
+	decision := bascule.NewDecision()
+	ctx := bascule.WithDecision(context.Background(), decision)
+	assert.NoError(CheckWithContext(ctx, cm, auth, ParsedValues{Endpoint: "a"}))
+	assert.Equal("yay", decision.Capability())
+
+	decision = bascule.NewDecision()
+	ctx = bascule.WithDecision(context.Background(), decision)
+	assert.ErrorIs(cm.CheckAuthenticationCtx(ctx, auth, ParsedValues{Endpoint: "b"}), ErrNoValidCapabilityFound)
+	assert.Empty(decision.Capability())
+
+	// without a Decision, the check still works.
+	assert.NoError(cm.CheckAuthenticationCtx(context.Background(), auth, ParsedValues{Endpoint: "a"}))
+}
+
 func TestNewCapabilitiesMap(t *testing.T) {
 	a := ".*"
 	b := "aaaaa+"
@@ -23,8 +23,8 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/spf13/cast"
-	"github.com/xmidt-org/bascule"
 )
 
 var (
@@ -79,6 +79,19 @@ type CapabilitiesValidatorConfig struct {
 	Prefix          string
 	AcceptAllMethod string
 	EndpointBuckets []string
+
+	// EnforcePercentage is the percentage of principals that failures are
+	// returned for when the Type is "enforce".  Zero enforces every
+	// principal.  See WithEnforcementPercentage.
+	EnforcePercentage int
+
+	// EnforceTarget limits which partners and clients failures are returned
+	// for when the Type is "enforce".  See WithEnforcementTarget.
+	EnforceTarget EnforcementTarget
+
+	// PartnerKeyPaths are searched in order for the partner IDs used in the
+	// metric labels.  See WithPartnerKeyPaths.
+	PartnerKeyPaths [][]string
 }
 
 // CapabilitiesValidator checks the capabilities provided in a
@@ -103,9 +116,9 @@ func (c CapabilitiesValidator) Check(ctx context.Context, _ bascule.Token) error
 		return nil
 	}
 
-	err := c.CheckAuthentication(auth, ParsedValues{})
+	err := c.CheckAuthenticationCtx(ctx, auth, ParsedValues{})
 	if err != nil && c.ErrorOut {
-		return fmt.Errorf("endpoint auth for %v on %v failed: %v",
+		return fmt.Errorf("endpoint auth for %v on %v failed: %w",
 			auth.Request.Method, auth.Request.URL.EscapedPath(), err)
 	}
 
@@ -118,19 +131,37 @@ func (c CapabilitiesValidator) Check(ctx context.Context, _ bascule.Token) error
 // capability authorizes the client for the given endpoint and method, it is
 // unauthorized.
 func (c CapabilitiesValidator) CheckAuthentication(auth bascule.Authentication, _ ParsedValues) error {
+	_, err := c.match(auth)
+	return err
+}
+
+// CheckAuthenticationCtx is CheckAuthentication, also recording the
+// capability that authorized the request in the bascule.Decision in the
+// context.
+func (c CapabilitiesValidator) CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, _ ParsedValues) error {
+	capability, err := c.match(auth)
+	if err != nil {
+		return err
+	}
+	recordCapability(ctx, capability)
+	return nil
+}
+
+// match returns the capability that authorizes the request.
+func (c CapabilitiesValidator) match(auth bascule.Authentication) (string, error) {
 	if auth.Token == nil {
-		return ErrNoToken
+		return "", ErrNoToken
 	}
 	if len(auth.Request.Method) == 0 {
-		return ErrNoMethod
+		return "", ErrNoMethod
 	}
 	vals, err := getCapabilities(auth.Token.Attributes(), c.KeyPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if auth.Request.URL == nil {
-		return ErrNoURL
+		return "", ErrNoURL
 	}
 	reqURL := auth.Request.URL.EscapedPath()
 	method := auth.Request.Method
@@ -138,19 +169,27 @@ func (c CapabilitiesValidator) CheckAuthentication(auth bascule.Authentication,
 }
 
 // checkCapabilities uses a EndpointChecker to check if each capability
-// provided is authorized.  If an authorized capability is found, no error is
+// provided is authorized.  If an authorized capability is found, it is
 // returned.
-func (c CapabilitiesValidator) checkCapabilities(capabilities []string, reqURL string, method string) error {
+func (c CapabilitiesValidator) checkCapabilities(capabilities []string, reqURL string, method string) (string, error) {
 	for _, val := range capabilities {
 		if c.Checker.Authorized(val, reqURL, method) {
-			return nil
+			return val, nil
 		}
 	}
-	return fmt.Errorf("%w in [%v] with %v endpoint checker",
+	return "", fmt.Errorf("%w in [%v] with %v endpoint checker",
 		ErrNoValidCapabilityFound, capabilities, c.Checker.Name())
 
 }
 
+// recordCapability sets the capability of the bascule.Decision in the
+// context, if there is one.
+func recordCapability(ctx context.Context, capability string) {
+	if d, ok := bascule.DecisionFromContext(ctx); ok {
+		d.SetCapability(capability)
+	}
+}
+
 // getCapabilities runs some error checks while getting the list of
 // capabilities from the attributes.
 func getCapabilities(attributes bascule.Attributes, keyPath []string) ([]string, error) {
@@ -207,9 +246,18 @@ func NewCapabilitiesValidator(config CapabilitiesValidatorConfig) (CapabilitiesC
 	}
 
 	os := []MetricOption{WithEndpoints(endpoints)}
+	if len(config.PartnerKeyPaths) > 0 {
+		os = append(os, WithPartnerKeyPaths(config.PartnerKeyPaths...))
+	}
 	if config.Type == "monitor" {
 		os = append(os, MonitorOnly())
 	}
+	if config.Type == "enforce" && config.EnforcePercentage > 0 {
+		os = append(os, WithEnforcementPercentage(config.EnforcePercentage))
+	}
+	if config.Type == "enforce" && (len(config.EnforceTarget.Partners) > 0 || len(config.EnforceTarget.ClientIDs) > 0) {
+		os = append(os, WithEnforcementTarget(config.EnforceTarget))
+	}
 
 	out = CapabilitiesCheckerOut{
 		Checker: CapabilitiesValidator{Checker: c},
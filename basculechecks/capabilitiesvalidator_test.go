@@ -26,9 +26,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/xmidt-org/bascule"
 )
 
 var _ CapabilitiesChecker = CapabilitiesValidator{}
@@ -97,12 +97,19 @@ func TestCapabilitiesValidatorCheck(t *testing.T) {
 				Checker:  ConstEndpointCheck("it's a match"),
 				ErrorOut: tc.errorOut,
 			}
+			decision := bascule.NewDecision()
+			ctx = bascule.WithDecision(ctx, decision)
 			err := c.Check(ctx, bascule.NewToken("", "", nil))
 			if tc.errExpected {
 				assert.NotNil(err)
 				return
 			}
 			assert.Nil(err)
+			if tc.includeToken {
+				assert.Equal("it's a match", decision.Capability())
+			} else {
+				assert.Empty(decision.Capability())
+			}
 		})
 	}
 }
@@ -232,7 +239,8 @@ func TestCheckCapabilities(t *testing.T) {
 			c := CapabilitiesValidator{
 				Checker: ConstEndpointCheck(tc.goodCapability),
 			}
-			err := c.checkCapabilities(capabilities, "", "")
+			capability, err := c.checkCapabilities(capabilities, "", "")
+			assert.Equal(tc.goodCapability, capability)
 			if tc.expectedErr == nil {
 				assert.NoError(err)
 				return
@@ -389,6 +397,51 @@ func TestNewCapabilitiesValidator(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "Enforce percentage success",
+			config: CapabilitiesValidatorConfig{
+				Type:              "enforce",
+				EndpointBuckets:   es,
+				EnforcePercentage: 25,
+			},
+			expectedOut: CapabilitiesCheckerOut{
+				Checker: CapabilitiesValidator{Checker: goodCheck},
+				Options: []MetricOption{
+					WithEndpoints(goodEndpoints),
+					WithEnforcementPercentage(25),
+				},
+			},
+		},
+		{
+			description: "Enforce target success",
+			config: CapabilitiesValidatorConfig{
+				Type:            "enforce",
+				EndpointBuckets: es,
+				EnforceTarget:   EnforcementTarget{Partners: []string{"comcast"}},
+			},
+			expectedOut: CapabilitiesCheckerOut{
+				Checker: CapabilitiesValidator{Checker: goodCheck},
+				Options: []MetricOption{
+					WithEndpoints(goodEndpoints),
+					WithEnforcementTarget(EnforcementTarget{Partners: []string{"comcast"}}),
+				},
+			},
+		},
+		{
+			description: "Monitor ignores enforce target",
+			config: CapabilitiesValidatorConfig{
+				Type:            "monitor",
+				EndpointBuckets: es,
+				EnforceTarget:   EnforcementTarget{Partners: []string{"comcast"}},
+			},
+			expectedOut: CapabilitiesCheckerOut{
+				Checker: CapabilitiesValidator{Checker: goodCheck},
+				Options: []MetricOption{
+					WithEndpoints(goodEndpoints),
+					MonitorOnly(),
+				},
+			},
+		},
 		{
 			description: "Disabled success",
 		},
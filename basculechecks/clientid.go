@@ -0,0 +1,93 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+const (
+	// OtherClientID is the client ID label value used for principals that
+	// aren't tracked individually.
+	OtherClientID = "other"
+
+	defaultHashLength = 8
+)
+
+// ClientIDTransform changes a principal before it is used as the client ID
+// metric label, so that PII-bearing principals don't leak into metrics.
+type ClientIDTransform func(string) string
+
+// HashClientID returns a ClientIDTransform that replaces the principal with
+// the first n hex characters of its SHA-256 hash.  If n isn't positive, 8
+// characters are used.  Empty principals are left empty.
+func HashClientID(n int) ClientIDTransform {
+	if n <= 0 {
+		n = defaultHashLength
+	}
+	return func(client string) string {
+		if client == "" {
+			return client
+		}
+		sum := sha256.Sum256([]byte(client))
+		h := hex.EncodeToString(sum[:])
+		if n < len(h) {
+			h = h[:n]
+		}
+		return h
+	}
+}
+
+// TruncateClientID returns a ClientIDTransform that keeps at most n
+// characters of the principal.
+func TruncateClientID(n int) ClientIDTransform {
+	return func(client string) string {
+		if n >= 0 && len(client) > n {
+			return client[:n]
+		}
+		return client
+	}
+}
+
+// FirstNClientIDs returns a ClientIDTransform that keeps the first n distinct
+// principals seen and buckets every other principal into OtherClientID,
+// bounding the cardinality of the client ID label.  If n isn't positive,
+// every principal is bucketed into OtherClientID.
+func FirstNClientIDs(n int) ClientIDTransform {
+	if n < 0 {
+		n = 0
+	}
+	var (
+		lock sync.Mutex
+		seen = make(map[string]struct{}, n)
+	)
+	return func(client string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		if _, ok := seen[client]; ok {
+			return client
+		}
+		if len(seen) < n {
+			seen[client] = struct{}{}
+			return client
+		}
+		return OtherClientID
+	}
+}
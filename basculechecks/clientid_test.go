@@ -0,0 +1,78 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashClientID(t *testing.T) {
+	assert := assert.New(t)
+	h := HashClientID(0)
+	assert.Equal("9f86d081", h("test"))
+	assert.Equal("", h(""))
+	assert.Len(HashClientID(100)("test"), 64)
+	assert.Equal("9f86", HashClientID(4)("test"))
+}
+
+func TestTruncateClientID(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("abc", TruncateClientID(3)("abcdef"))
+	assert.Equal("ab", TruncateClientID(3)("ab"))
+	assert.Equal("abcdef", TruncateClientID(-1)("abcdef"))
+}
+
+func TestFirstNClientIDs(t *testing.T) {
+	assert := assert.New(t)
+	first := FirstNClientIDs(2)
+	assert.Equal("a", first("a"))
+	assert.Equal("b", first("b"))
+	assert.Equal(OtherClientID, first("c"))
+	assert.Equal("a", first("a"))
+	assert.Equal(OtherClientID, first("d"))
+
+	none := FirstNClientIDs(-1)
+	assert.NotPanics(func() {
+		assert.Equal(OtherClientID, none("a"))
+	})
+}
+
+func TestPrepMetricsClientIDTransform(t *testing.T) {
+	u, err := url.ParseRequestURI("/test")
+	require.NoError(t, err)
+	m, err := NewMetricValidator(CapabilitiesValidator{}, &AuthCapabilityCheckMeasures{},
+		WithClientIDTransform(HashClientID(8)),
+		WithClientIDTransform(nil),
+	)
+	require.NoError(t, err)
+	v, err := m.prepMetrics(bascule.Authentication{
+		Token: bascule.NewToken("jwt", "test", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"p"},
+			},
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "9f86d081", v.client)
+}
@@ -0,0 +1,211 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+)
+
+const defaultConcurrencyLimit = 10
+
+var (
+	ErrTooManyInFlight      = bascule.NewClassError(bascule.RateLimitedClass, "too many requests in flight for principal")
+	ErrConcurrencyUntracked = errors.New("concurrency limiter is missing its Track middleware")
+)
+
+// ConcurrencyLimitConfig configures a ConcurrencyLimiter.
+type ConcurrencyLimitConfig struct {
+	// Limit is the most requests a principal can have in flight.  Defaults
+	// to 10.
+	Limit int
+
+	// Limits overrides the Limit for specific principals.
+	Limits map[string]int
+}
+
+// ConcurrencyLimitError is returned when a principal already has as many
+// requests in flight as it's allowed.  It results in a 429 response.
+type ConcurrencyLimitError struct {
+	Principal string
+	Limit     int
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	return fmt.Sprintf("%v: limit of %d reached", ErrTooManyInFlight, e.Limit)
+}
+
+func (e *ConcurrencyLimitError) Unwrap() error {
+	return ErrTooManyInFlight
+}
+
+// Reason returns the metric label for the failure.
+func (e *ConcurrencyLimitError) Reason() string {
+	return TooManyInFlight
+}
+
+// StatusCode returns 429, Too Many Requests.
+func (e *ConcurrencyLimitError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// ErrorClass returns bascule.RateLimitedClass.
+func (e *ConcurrencyLimitError) ErrorClass() bascule.ErrorClass {
+	return bascule.RateLimitedClass
+}
+
+// ConcurrencyLimiter is a Validator that caps the requests each principal can
+// have in flight, so a single credential can't monopolize shared backends.  A
+// slot is taken when the token is checked and released when the request
+// finishes, which is why the limiter's Track middleware must wrap the
+// enforcer using it.
+type ConcurrencyLimiter struct {
+	config   ConcurrencyLimitConfig
+	measures *ConcurrencyLimitMeasures
+	server   string
+	clientID ClientIDTransform
+
+	lock     sync.Mutex
+	inFlight map[string]int
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter.  The measures and client
+// ID transform are optional.
+func NewConcurrencyLimiter(config ConcurrencyLimitConfig, measures *ConcurrencyLimitMeasures, server string, clientID ClientIDTransform) *ConcurrencyLimiter {
+	if config.Limit <= 0 {
+		config.Limit = defaultConcurrencyLimit
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	return &ConcurrencyLimiter{
+		config:   config,
+		measures: measures,
+		server:   server,
+		clientID: clientID,
+		inFlight: make(map[string]int),
+	}
+}
+
+type concurrencySlotsKey struct{}
+
+// concurrencySlots holds the slots taken for a request, so they can be
+// released once it finishes.
+type concurrencySlots struct {
+	lock     sync.Mutex
+	releases []func()
+}
+
+func (s *concurrencySlots) add(release func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.releases = append(s.releases, release)
+}
+
+func (s *concurrencySlots) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, r := range s.releases {
+		r()
+	}
+	s.releases = nil
+}
+
+// Track is an Alice-style decorator that releases the slots taken by the
+// limiter once the request finishes.  It must wrap the enforcer that runs
+// the limiter.
+func (l *ConcurrencyLimiter) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(concurrencySlotsKey{}).(*concurrencySlots); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		slots := new(concurrencySlots)
+		defer slots.release()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), concurrencySlotsKey{}, slots)))
+	})
+}
+
+// Check takes one of the principal's slots, failing with a
+// *ConcurrencyLimitError if there are none left.
+func (l *ConcurrencyLimiter) Check(ctx context.Context, token bascule.Token) error {
+	if token == nil {
+		return ErrNoToken
+	}
+	slots, ok := ctx.Value(concurrencySlotsKey{}).(*concurrencySlots)
+	if !ok {
+		return ErrConcurrencyUntracked
+	}
+
+	principal := token.Principal()
+	limit := l.config.Limit
+	if n, ok := l.config.Limits[principal]; ok {
+		limit = n
+	}
+
+	l.lock.Lock()
+	if l.inFlight[principal] >= limit {
+		l.lock.Unlock()
+		l.record(principal)
+		return &ConcurrencyLimitError{Principal: principal, Limit: limit}
+	}
+	l.inFlight[principal]++
+	l.lock.Unlock()
+
+	var once sync.Once
+	slots.add(func() {
+		once.Do(func() { l.release(principal) })
+	})
+	return nil
+}
+
+// InFlight returns the number of requests the principal has in flight.
+func (l *ConcurrencyLimiter) InFlight(principal string) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.inFlight[principal]
+}
+
+func (l *ConcurrencyLimiter) release(principal string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.inFlight[principal] <= 1 {
+		delete(l.inFlight, principal)
+		return
+	}
+	l.inFlight[principal]--
+}
+
+func (l *ConcurrencyLimiter) record(principal string) {
+	if l.measures == nil || l.measures.Rejections == nil {
+		return
+	}
+	if l.clientID != nil {
+		principal = l.clientID(principal)
+	}
+	l.measures.Rejections.With(prometheus.Labels{
+		ServerLabel:   l.server,
+		ClientIDLabel: principal,
+	}).Add(1)
+}
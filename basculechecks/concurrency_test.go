@@ -0,0 +1,147 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*ConcurrencyLimiter)(nil)
+
+func newConcurrencyMeasures() *ConcurrencyLimitMeasures {
+	return &ConcurrencyLimitMeasures{
+		Rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testConcurrencyRejections",
+		}, []string{ServerLabel, ClientIDLabel}),
+	}
+}
+
+// trackedContext returns a context as the Track middleware gives it, along
+// with the function that releases its slots.
+func trackedContext(l *ConcurrencyLimiter) (context.Context, func()) {
+	ctx := make(chan context.Context)
+	finish := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Track(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			ctx <- r.Context()
+			<-finish
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	return <-ctx, func() {
+		close(finish)
+		<-done
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	measures := newConcurrencyMeasures()
+	l := NewConcurrencyLimiter(ConcurrencyLimitConfig{
+		Limit:  2,
+		Limits: map[string]int{"vip": 3},
+	}, measures, "", TruncateClientID(3))
+
+	alice := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	ctx1, done1 := trackedContext(l)
+	require.NoError(l.Check(ctx1, alice))
+	ctx2, done2 := trackedContext(l)
+	require.NoError(l.Check(ctx2, alice))
+	assert.Equal(2, l.InFlight("alice"))
+
+	ctx3, done3 := trackedContext(l)
+	err := l.Check(ctx3, alice)
+	assert.ErrorIs(err, ErrTooManyInFlight)
+	var limitErr *ConcurrencyLimitError
+	require.True(errors.As(err, &limitErr))
+	assert.Equal("alice", limitErr.Principal)
+	assert.Equal(2, limitErr.Limit)
+	assert.Equal(http.StatusTooManyRequests, limitErr.StatusCode())
+	assert.Equal(bascule.RateLimitedClass, bascule.ClassOf(err))
+	var r Reasoner
+	require.True(errors.As(err, &r))
+	assert.Equal(TooManyInFlight, r.Reason())
+	assert.Equal(1.0, testutil.ToFloat64(measures.Rejections.With(prometheus.Labels{
+		ServerLabel:   defaultServer,
+		ClientIDLabel: "ali",
+	})))
+
+	// other principals have their own limits.
+	vip := bascule.NewToken("test", "vip", bascule.NewAttributes(nil))
+	for i := 0; i < 3; i++ {
+		assert.NoError(l.Check(ctx3, vip))
+	}
+	assert.Error(l.Check(ctx3, vip))
+	done3()
+	assert.Zero(l.InFlight("vip"))
+
+	// finishing a request frees its slot.
+	done1()
+	assert.Equal(1, l.InFlight("alice"))
+	ctx4, done4 := trackedContext(l)
+	assert.NoError(l.Check(ctx4, alice))
+	done2()
+	done4()
+	assert.Zero(l.InFlight("alice"))
+}
+
+func TestConcurrencyLimiterErrors(t *testing.T) {
+	assert := assert.New(t)
+	l := NewConcurrencyLimiter(ConcurrencyLimitConfig{}, nil, "", nil)
+	assert.Equal(defaultConcurrencyLimit, l.config.Limit)
+
+	ctx, done := trackedContext(l)
+	defer done()
+	assert.ErrorIs(l.Check(ctx, nil), ErrNoToken)
+
+	token := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	assert.ErrorIs(l.Check(context.Background(), token), ErrConcurrencyUntracked)
+
+	// rejections without measures don't panic.
+	l = NewConcurrencyLimiter(ConcurrencyLimitConfig{Limit: 1}, nil, "", nil)
+	ctx, done = trackedContext(l)
+	defer done()
+	assert.NoError(l.Check(ctx, token))
+	assert.ErrorIs(l.Check(ctx, token), ErrTooManyInFlight)
+}
+
+func TestConcurrencyLimiterNestedTrack(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyLimitConfig{Limit: 1}, nil, "", nil)
+	token := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	called := false
+	handler := l.Track(l.Track(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		called = true
+		assert.NoError(t, l.Check(r.Context(), token))
+		assert.Equal(t, 1, l.InFlight("alice"))
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, called)
+	assert.Zero(t, l.InFlight("alice"))
+}
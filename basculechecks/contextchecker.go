@@ -0,0 +1,57 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// CapabilitiesCheckerCtx is an optional interface for CapabilitiesCheckers
+// that do I/O, such as calling a policy engine or a database, and need to
+// honor the request's cancellation and deadlines.  The MetricValidator uses
+// it in place of CheckAuthentication when it is implemented.
+type CapabilitiesCheckerCtx interface {
+	CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, vals ParsedValues) error
+}
+
+// CapabilitiesCheckerCtxFunc makes it so any function with the same signature
+// as CheckAuthenticationCtx implements both CapabilitiesChecker and
+// CapabilitiesCheckerCtx.
+type CapabilitiesCheckerCtxFunc func(context.Context, bascule.Authentication, ParsedValues) error
+
+// CheckAuthentication calls the function with a background context.
+func (f CapabilitiesCheckerCtxFunc) CheckAuthentication(auth bascule.Authentication, vals ParsedValues) error {
+	return f(context.Background(), auth, vals)
+}
+
+// CheckAuthenticationCtx calls the function.
+func (f CapabilitiesCheckerCtxFunc) CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, vals ParsedValues) error {
+	return f(ctx, auth, vals)
+}
+
+// CheckWithContext runs the checker given, passing it the context if it
+// implements CapabilitiesCheckerCtx.  Otherwise, CheckAuthentication is
+// called.
+func CheckWithContext(ctx context.Context, c CapabilitiesChecker, auth bascule.Authentication, vals ParsedValues) error {
+	if cc, ok := c.(CapabilitiesCheckerCtx); ok {
+		return cc.CheckAuthenticationCtx(ctx, auth, vals)
+	}
+	return c.CheckAuthentication(auth, vals)
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+func TestCheckWithContext(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	testErr := errors.New("test")
+
+	var got context.Context
+	f := CapabilitiesCheckerCtxFunc(func(ctx context.Context, _ bascule.Authentication, _ ParsedValues) error {
+		got = ctx
+		return testErr
+	})
+	assert.ErrorIs(CheckWithContext(ctx, f, bascule.Authentication{}, ParsedValues{}), testErr)
+	assert.Equal("value", got.Value(ctxKey{}))
+
+	// the context free method still works.
+	assert.ErrorIs(f.CheckAuthentication(bascule.Authentication{}, ParsedValues{}), testErr)
+	assert.Nil(got.Value(ctxKey{}))
+
+	checker := new(mockCapabilitiesChecker)
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(testErr).Once()
+	assert.ErrorIs(CheckWithContext(ctx, checker, bascule.Authentication{}, ParsedValues{}), testErr)
+	checker.AssertExpectations(t)
+}
+
+func TestMetricValidatorContextChecker(t *testing.T) {
+	u, err := url.ParseRequestURI("/test")
+	require.NoError(t, err)
+	auth := bascule.Authentication{
+		Token: bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"meh"},
+			},
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	}
+	checker := CapabilitiesCheckerCtxFunc(func(ctx context.Context, _ bascule.Authentication, _ ParsedValues) error {
+		return ctx.Err()
+	})
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "testCounter",
+		Help: "testCounter",
+	}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+		PartnerIDLabel, EndpointLabel, MethodLabel})
+	m, err := NewMetricValidator(checker, &AuthCapabilityCheckMeasures{CapabilityCheckOutcome: counter})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(bascule.WithAuthentication(context.Background(), auth))
+	assert.NoError(t, m.Check(ctx, nil))
+	cancel()
+	assert.ErrorContains(t, m.Check(ctx, nil), context.Canceled.Error())
+}
+
+func TestDecisionCacheContext(t *testing.T) {
+	assert := assert.New(t)
+	u, err := url.ParseRequestURI("/test")
+	require.NoError(t, err)
+	auth := bascule.Authentication{
+		Token: bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+			"capabilities": []string{"cap"},
+			"jti":          "id",
+			"exp":          time.Now().Add(time.Hour).Unix(),
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	}
+	calls := 0
+	checker := CapabilitiesCheckerCtxFunc(func(ctx context.Context, _ bascule.Authentication, _ ParsedValues) error {
+		calls++
+		return ctx.Err()
+	})
+	d, err := NewDecisionCache(checker, DecisionCacheConfig{}, nil, "")
+	require.NoError(t, err)
+
+	// a canceled check isn't cached.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(d.CheckAuthenticationCtx(ctx, auth, ParsedValues{}), context.Canceled)
+	assert.NoError(d.CheckAuthentication(auth, ParsedValues{}))
+	assert.NoError(d.CheckAuthentication(auth, ParsedValues{}))
+	assert.Equal(2, calls)
+}
@@ -0,0 +1,210 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+	"github.com/spf13/cast"
+)
+
+const (
+	defaultCacheTTL        = time.Minute
+	defaultCacheMaxEntries = 10000
+	expClaimKey            = "exp"
+	jtiClaimKey            = "jti"
+)
+
+// DecisionCacheConfig configures a DecisionCache.
+type DecisionCacheConfig struct {
+	// TTL is the longest a decision is cached.  Decisions are never cached
+	// past the token's expiration.  Defaults to one minute.
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached decisions.  Defaults to 10000.
+	MaxEntries int
+
+	// KeyPath is the location of the capabilities in the token's attributes,
+	// which are part of the cache key.  Defaults to CapabilityKeys().
+	KeyPath []string
+
+	// IgnorePath leaves the request's URL path out of the cache key, so that
+	// all requests in the same endpoint bucket share a decision.  Only set
+	// this when the wrapped checker decides using the endpoint bucket alone,
+	// like the CapabilitiesMap does.
+	IgnorePath bool
+}
+
+// DecisionCache wraps a CapabilitiesChecker, remembering the allow or deny
+// decision made for a token, method, and endpoint until the token expires or
+// the TTL passes, whichever comes first.  Tokens are identified by their jti
+// and exp claims; tokens missing either claim are never cached, since two
+// different tokens could otherwise share a decision.
+type DecisionCache struct {
+	checker  CapabilitiesChecker
+	config   DecisionCacheConfig
+	measures *DecisionCacheMeasures
+	server   string
+	now      func() time.Time
+
+	decisions *ttlcache.Cache
+}
+
+// NewDecisionCache creates a DecisionCache around the checker given.  The
+// measures are optional.
+func NewDecisionCache(checker CapabilitiesChecker, config DecisionCacheConfig, measures *DecisionCacheMeasures, server string) (*DecisionCache, error) {
+	if checker == nil {
+		return nil, ErrNilChecker
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultCacheTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+	if len(config.KeyPath) == 0 {
+		config.KeyPath = CapabilityKeys()
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	return &DecisionCache{
+		checker:   checker,
+		config:    config,
+		measures:  measures,
+		server:    server,
+		now:       time.Now,
+		decisions: ttlcache.New(config.MaxEntries),
+	}, nil
+}
+
+// CheckAuthentication returns the cached decision for the request if there is
+// one, otherwise it calls the wrapped checker and caches the result.
+func (d *DecisionCache) CheckAuthentication(auth bascule.Authentication, vs ParsedValues) error {
+	return d.CheckAuthenticationCtx(context.Background(), auth, vs)
+}
+
+// CheckAuthenticationCtx is CheckAuthentication with a context, which is
+// passed on to the wrapped checker.  Failures caused by the context ending
+// aren't cached.
+func (d *DecisionCache) CheckAuthenticationCtx(ctx context.Context, auth bascule.Authentication, vs ParsedValues) error {
+	key, ok := d.key(auth, vs)
+	if !ok {
+		// there's not enough information to safely cache the decision.
+		return CheckWithContext(ctx, d.checker, auth, vs)
+	}
+
+	now := d.now()
+	if cached, found := d.decisions.Get(key, now); found {
+		d.record(CacheHit)
+		err, _ := cached.(error)
+		return err
+	}
+	d.record(CacheMiss)
+
+	err := CheckWithContext(ctx, d.checker, auth, vs)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	expires := now.Add(d.config.TTL)
+	if exp, ok := tokenExpiration(auth.Token); ok && exp.Before(expires) {
+		expires = exp
+	}
+	if !now.Before(expires) {
+		return err
+	}
+
+	d.decisions.Set(key, err, expires, now)
+	return err
+}
+
+func (d *DecisionCache) key(auth bascule.Authentication, vs ParsedValues) (string, bool) {
+	if auth.Token == nil || auth.Token.Attributes() == nil || auth.Request.URL == nil {
+		return "", false
+	}
+	id, ok := tokenID(auth.Token)
+	if !ok {
+		return "", false
+	}
+	exp, ok := tokenExpiration(auth.Token)
+	if !ok {
+		return "", false
+	}
+	capabilities, err := getCapabilities(auth.Token.Attributes(), d.config.KeyPath)
+	if err != nil {
+		return "", false
+	}
+	parts := []string{
+		auth.Token.Type(),
+		id,
+		strconv.FormatInt(exp.Unix(), 10),
+		auth.Token.Principal(),
+		strings.Join(capabilities, ","),
+		auth.Request.Method,
+		vs.String(),
+	}
+	if !d.config.IgnorePath {
+		parts = append(parts, auth.Request.URL.EscapedPath())
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:]), true
+}
+
+func (d *DecisionCache) record(result string) {
+	if d.measures == nil || d.measures.CacheOutcome == nil {
+		return
+	}
+	d.measures.CacheOutcome.With(prometheus.Labels{
+		ServerLabel:      d.server,
+		CacheResultLabel: result,
+	}).Add(1)
+}
+
+// tokenID gets the jti claim from the token, if there is one.
+func tokenID(t bascule.Token) (string, bool) {
+	v, ok := t.Attributes().Get(jtiClaimKey)
+	if !ok {
+		return "", false
+	}
+	id, err := cast.ToStringE(v)
+	if err != nil || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// tokenExpiration gets the exp claim from the token, if there is one.
+func tokenExpiration(t bascule.Token) (time.Time, bool) {
+	v, ok := t.Attributes().Get(expClaimKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	exp, err := cast.ToInt64E(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(exp, 0), true
+}
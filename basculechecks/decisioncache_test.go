@@ -0,0 +1,171 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDecisionCache(t *testing.T) {
+	assert := assert.New(t)
+	d, err := NewDecisionCache(nil, DecisionCacheConfig{}, nil, "")
+	assert.Nil(d)
+	assert.ErrorIs(err, ErrNilChecker)
+
+	d, err = NewDecisionCache(new(mockCapabilitiesChecker), DecisionCacheConfig{}, nil, "")
+	assert.NoError(err)
+	assert.Equal(defaultCacheTTL, d.config.TTL)
+	assert.Equal(defaultCacheMaxEntries, d.config.MaxEntries)
+	assert.Equal(CapabilityKeys(), d.config.KeyPath)
+	assert.Equal(defaultServer, d.server)
+}
+
+func TestDecisionCacheCheckAuthentication(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	now := time.Unix(1000, 0)
+	u, err := url.ParseRequestURI("/a/b")
+	require.NoError(err)
+
+	newAuth := func(principal string, exp int64) bascule.Authentication {
+		return bascule.Authentication{
+			Token: bascule.NewToken("jwt", principal, bascule.NewAttributes(map[string]interface{}{
+				"capabilities": []string{"cap"},
+				"exp":          exp,
+				"jti":          principal,
+			})),
+			Request: bascule.Request{URL: u, Method: "GET"},
+		}
+	}
+	denied := errors.New("denied")
+
+	checker := new(mockCapabilitiesChecker)
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(nil).Once()
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(denied).Once()
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(nil).Once()
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(nil).Once()
+
+	measures := &DecisionCacheMeasures{
+		CacheOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testCounter",
+			Help: "testCounter",
+		}, []string{ServerLabel, CacheResultLabel}),
+	}
+	d, err := NewDecisionCache(checker, DecisionCacheConfig{TTL: time.Minute, MaxEntries: 2}, measures, "test")
+	require.NoError(err)
+	d.now = func() time.Time { return now }
+
+	vs := ParsedValues{Endpoint: "/a"}
+	// first call is a miss, second a hit.
+	assert.NoError(d.CheckAuthentication(newAuth("allowed", 2000), vs))
+	assert.NoError(d.CheckAuthentication(newAuth("allowed", 2000), vs))
+
+	// denials are cached as well.
+	assert.ErrorIs(d.CheckAuthentication(newAuth("denied", 2000), vs), denied)
+	assert.ErrorIs(d.CheckAuthentication(newAuth("denied", 2000), vs), denied)
+
+	// the decision doesn't outlive the token.
+	assert.NoError(d.CheckAuthentication(newAuth("short", 1010), vs))
+	now = now.Add(20 * time.Second)
+	assert.NoError(d.CheckAuthentication(newAuth("short", 1010), vs))
+
+	// tokens without capabilities bypass the cache.
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(denied).Once()
+	assert.ErrorIs(d.CheckAuthentication(bascule.Authentication{}, vs), denied)
+
+	// so do tokens without a jti, even if they share a principal and
+	// capabilities with a cached token.
+	noID := bascule.Authentication{
+		Token: bascule.NewToken("jwt", "allowed", bascule.NewAttributes(map[string]interface{}{
+			"capabilities": []string{"cap"},
+			"exp":          int64(2000),
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	}
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(denied).Twice()
+	assert.ErrorIs(d.CheckAuthentication(noID, vs), denied)
+	assert.ErrorIs(d.CheckAuthentication(noID, vs), denied)
+
+	checker.AssertExpectations(t)
+	assert.Equal(2.0, testutil.ToFloat64(measures.CacheOutcome.With(prometheus.Labels{
+		ServerLabel: "test", CacheResultLabel: CacheHit,
+	})))
+	assert.Equal(4.0, testutil.ToFloat64(measures.CacheOutcome.With(prometheus.Labels{
+		ServerLabel: "test", CacheResultLabel: CacheMiss,
+	})))
+	assert.LessOrEqual(d.decisions.Len(), 2)
+}
+
+func TestDecisionCacheKey(t *testing.T) {
+	assert := assert.New(t)
+	u1, _ := url.ParseRequestURI("/a/1")
+	u2, _ := url.ParseRequestURI("/a/2")
+	token := bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{"cap"},
+		"jti":          "id",
+		"exp":          int64(2000),
+	}))
+	vs := ParsedValues{Endpoint: "/a"}
+
+	d, _ := NewDecisionCache(new(mockCapabilitiesChecker), DecisionCacheConfig{}, nil, "")
+	k1, ok := d.key(bascule.Authentication{Token: token, Request: bascule.Request{URL: u1}}, vs)
+	assert.True(ok)
+	k2, _ := d.key(bascule.Authentication{Token: token, Request: bascule.Request{URL: u2}}, vs)
+	assert.NotEqual(k1, k2)
+
+	d.config.IgnorePath = true
+	k1, _ = d.key(bascule.Authentication{Token: token, Request: bascule.Request{URL: u1}}, vs)
+	k2, _ = d.key(bascule.Authentication{Token: token, Request: bascule.Request{URL: u2}}, vs)
+	assert.Equal(k1, k2)
+
+	// enriched values are part of the key.
+	k2, _ = d.key(bascule.Authentication{Token: token, Request: bascule.Request{URL: u1}}, vs.With("region", "east"))
+	assert.NotEqual(k1, k2)
+
+	// tokens with the same principal and capabilities but a different jti
+	// don't share a key.
+	other := bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{"cap"},
+		"jti":          "other",
+		"exp":          int64(2000),
+	}))
+	k2, _ = d.key(bascule.Authentication{Token: other, Request: bascule.Request{URL: u1}}, vs)
+	assert.NotEqual(k1, k2)
+
+	// tokens without a jti or exp can't be keyed.
+	for _, attrs := range []map[string]interface{}{
+		{"capabilities": []string{"cap"}, "exp": int64(2000)},
+		{"capabilities": []string{"cap"}, "jti": "id"},
+	} {
+		_, ok = d.key(bascule.Authentication{
+			Token:   bascule.NewToken("jwt", "p", bascule.NewAttributes(attrs)),
+			Request: bascule.Request{URL: u1},
+		}, vs)
+		assert.False(ok)
+	}
+}
@@ -0,0 +1,312 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSummaryInterval = time.Minute
+	defaultSummaryTopN     = 10
+)
+
+// The dimensions of a DecisionSummary, used as the dimension metric label.
+const (
+	PrincipalDimension = "principal"
+	EndpointDimension  = "endpoint"
+	ReasonDimension    = "reason"
+)
+
+// DecisionSummaryConfig configures a DecisionSummary.
+type DecisionSummaryConfig struct {
+	// Interval is how often a summary is emitted.  Defaults to a minute.
+	Interval time.Duration
+
+	// TopN is the number of principals, endpoints, and reasons included in
+	// each summary.  Defaults to 10.
+	TopN int
+
+	// SampleRate is the fraction of failures recorded, between 0 and 1, for
+	// servers where counting every failure costs too much.  Counts are scaled
+	// back up by the rate.  Zero or anything above 1 records every failure.
+	SampleRate float64
+
+	// Logger is where summaries are logged.  Defaults to sallust.Default().
+	Logger *zap.Logger
+}
+
+// SummaryCount is how many failures a principal, endpoint, or reason had.
+type SummaryCount struct {
+	Key   string
+	Count int
+}
+
+// DecisionSummaryReport holds the failures of one interval.
+type DecisionSummaryReport struct {
+	Start time.Time
+	End   time.Time
+
+	// Failures is every failure, including ones only recorded in monitor
+	// mode.  Rejected is the failures that were enforced.
+	Failures int
+	Rejected int
+
+	// Principals, Endpoints, and Reasons are the ones with the most
+	// failures, most first.
+	Principals []SummaryCount
+	Endpoints  []SummaryCount
+	Reasons    []SummaryCount
+}
+
+// DecisionSummary aggregates the failures seen by a MetricValidator and
+// periodically emits the principals, endpoints, and reasons with the most
+// failures as a log line and, optionally, a gauge.  Failures recorded in
+// monitor mode are included, so the effect of enforcing can be judged without
+// a metrics query stack.  Principals are the client ID metric label, so a
+// ClientIDTransform applies to them too.  Add it to a MetricValidator with
+// WithDecisionSummary.
+type DecisionSummary struct {
+	config   DecisionSummaryConfig
+	measures *DecisionSummaryMeasures
+	server   string
+	now      func() time.Time
+	sample   func() float64
+
+	lock       sync.Mutex
+	start      time.Time
+	failures   float64
+	rejected   float64
+	principals map[string]float64
+	endpoints  map[string]float64
+	reasons    map[string]float64
+
+	runLock sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewDecisionSummary creates a DecisionSummary.  The measures are optional.
+// Start must be called for summaries to be emitted periodically.
+func NewDecisionSummary(config DecisionSummaryConfig, measures *DecisionSummaryMeasures, server string) *DecisionSummary {
+	if config.Interval <= 0 {
+		config.Interval = defaultSummaryInterval
+	}
+	if config.TopN <= 0 {
+		config.TopN = defaultSummaryTopN
+	}
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	if config.Logger == nil {
+		config.Logger = sallust.Default()
+	}
+	if len(server) == 0 {
+		server = defaultServer
+	}
+	s := &DecisionSummary{
+		config:   config,
+		measures: measures,
+		server:   server,
+		now:      time.Now,
+		sample:   rand.Float64,
+	}
+	s.reset(s.now())
+	return s
+}
+
+// Record counts a failure for the principal, endpoint, and reason given.
+// Rejected is true if the failure was enforced rather than only monitored.
+func (s *DecisionSummary) Record(principal, endpoint, reason string, rejected bool) {
+	if s.config.SampleRate < 1 && s.sample() >= s.config.SampleRate {
+		return
+	}
+	weight := 1 / s.config.SampleRate
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures += weight
+	if rejected {
+		s.rejected += weight
+	}
+	s.principals[principal] += weight
+	s.endpoints[endpoint] += weight
+	s.reasons[reason] += weight
+}
+
+// RecordOutcome counts the outcome of a MetricValidator's check if it
+// failed, so a DecisionSummary is an OutcomeSink.  Successes have no reason
+// and aren't counted.
+func (s *DecisionSummary) RecordOutcome(o Outcome) {
+	if len(o.Reason) == 0 {
+		return
+	}
+	s.Record(o.ClientID, o.Endpoint, o.Reason, o.Outcome == RejectedOutcome)
+}
+
+// Summarize returns the report for the failures recorded since the last
+// one, starting a new interval.  It doesn't emit the report.
+func (s *DecisionSummary) Summarize() DecisionSummaryReport {
+	now := s.now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := DecisionSummaryReport{
+		Start:      s.start,
+		End:        now,
+		Failures:   int(s.failures + 0.5),
+		Rejected:   int(s.rejected + 0.5),
+		Principals: topCounts(s.principals, s.config.TopN),
+		Endpoints:  topCounts(s.endpoints, s.config.TopN),
+		Reasons:    topCounts(s.reasons, s.config.TopN),
+	}
+	s.reset(now)
+	return r
+}
+
+// Emit summarizes the last interval, logs the report, and sets the gauge to
+// it.  Intervals without failures are logged too, so a quiet log isn't
+// mistaken for a stopped summary.
+func (s *DecisionSummary) Emit() DecisionSummaryReport {
+	r := s.Summarize()
+	s.config.Logger.Info("authorization decision summary",
+		zap.String("server", s.server),
+		zap.Time("start", r.Start),
+		zap.Time("end", r.End),
+		zap.Int("failures", r.Failures),
+		zap.Int("rejected", r.Rejected),
+		zap.Any("principals", r.Principals),
+		zap.Any("endpoints", r.Endpoints),
+		zap.Any("reasons", r.Reasons),
+	)
+	s.observe(r)
+	return r
+}
+
+// reset starts a new interval.  It must be called with the lock held.
+func (s *DecisionSummary) reset(now time.Time) {
+	s.start = now
+	s.failures, s.rejected = 0, 0
+	s.principals = make(map[string]float64)
+	s.endpoints = make(map[string]float64)
+	s.reasons = make(map[string]float64)
+}
+
+// observe replaces the gauge's values with the report's, so keys that fell
+// out of the top N don't linger.
+func (s *DecisionSummary) observe(r DecisionSummaryReport) {
+	if s.measures == nil || s.measures.Summary == nil {
+		return
+	}
+	s.measures.Summary.Reset()
+	set := func(dimension string, counts []SummaryCount) {
+		for _, c := range counts {
+			s.measures.Summary.With(prometheus.Labels{
+				ServerLabel:    s.server,
+				DimensionLabel: dimension,
+				KeyLabel:       c.Key,
+			}).Set(float64(c.Count))
+		}
+	}
+	set(PrincipalDimension, r.Principals)
+	set(EndpointDimension, r.Endpoints)
+	set(ReasonDimension, r.Reasons)
+}
+
+// topCounts returns the n keys with the highest counts, most first, breaking
+// ties by key so reports are stable.
+func topCounts(counts map[string]float64, n int) []SummaryCount {
+	top := make([]SummaryCount, 0, len(counts))
+	for k, v := range counts {
+		top = append(top, SummaryCount{Key: k, Count: int(v + 0.5)})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Key < top[j].Key
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// Start begins emitting summaries in the background.  Calling Start more
+// than once has no effect.
+func (s *DecisionSummary) Start() {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+}
+
+func (s *DecisionSummary) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Emit()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Stop ends the background summaries and emits one for the failures since
+// the last, so they aren't lost on shutdown.
+func (s *DecisionSummary) Stop() {
+	s.runLock.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.runLock.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	s.Emit()
+}
+
+// Hook returns an uber fx lifecycle hook that starts the DecisionSummary with
+// the application and stops it on shutdown.
+func (s *DecisionSummary) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			s.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			s.Stop()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,179 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDecisionSummarySummarize(t *testing.T) {
+	assert := assert.New(t)
+	s := NewDecisionSummary(DecisionSummaryConfig{TopN: 2, Logger: zap.NewNop()}, nil, "")
+	start := time.Unix(1000, 0)
+	now := start
+	s.now = func() time.Time { return now }
+	s.reset(now)
+
+	s.Record("alice", "/a", NoCapabilitiesMatch, true)
+	s.Record("alice", "/b", NoCapabilitiesMatch, false)
+	s.Record("bob", "/a", PartnerNotAllowed, false)
+	s.Record("carol", "/c", NoCapabilitiesMatch, false)
+	now = start.Add(time.Minute)
+
+	r := s.Summarize()
+	assert.Equal(DecisionSummaryReport{
+		Start:      start,
+		End:        now,
+		Failures:   4,
+		Rejected:   1,
+		Principals: []SummaryCount{{Key: "alice", Count: 2}, {Key: "bob", Count: 1}},
+		Endpoints:  []SummaryCount{{Key: "/a", Count: 2}, {Key: "/b", Count: 1}},
+		Reasons:    []SummaryCount{{Key: NoCapabilitiesMatch, Count: 3}, {Key: PartnerNotAllowed, Count: 1}},
+	}, r)
+
+	r = s.Summarize()
+	assert.Equal(now, r.Start)
+	assert.Zero(r.Failures)
+	assert.Empty(r.Principals)
+}
+
+func TestDecisionSummarySampling(t *testing.T) {
+	assert := assert.New(t)
+	s := NewDecisionSummary(DecisionSummaryConfig{SampleRate: 0.5, Logger: zap.NewNop()}, nil, "")
+	samples := []float64{0.1, 0.9, 0.4, 0.6}
+	s.sample = func() float64 {
+		v := samples[0]
+		samples = samples[1:]
+		return v
+	}
+	for i := 0; i < 4; i++ {
+		s.Record("alice", "/a", NoCapabilitiesMatch, true)
+	}
+	r := s.Summarize()
+	assert.Equal(4, r.Failures)
+	assert.Equal(4, r.Rejected)
+	assert.Equal([]SummaryCount{{Key: "alice", Count: 4}}, r.Principals)
+
+	assert.Equal(float64(1), NewDecisionSummary(DecisionSummaryConfig{SampleRate: 2}, nil, "").config.SampleRate)
+}
+
+func TestDecisionSummaryEmit(t *testing.T) {
+	assert := assert.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	measures := &DecisionSummaryMeasures{
+		Summary: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "testSummary",
+		}, []string{ServerLabel, DimensionLabel, KeyLabel}),
+	}
+	s := NewDecisionSummary(DecisionSummaryConfig{Logger: zap.New(core)}, measures, "api")
+
+	s.Record("alice", "/a", NoCapabilitiesMatch, true)
+	s.Record("alice", "/a", NoCapabilitiesMatch, true)
+	s.Emit()
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal("authorization decision summary", entry.Message)
+	assert.Equal(int64(2), entry.ContextMap()["failures"])
+	assert.Equal(float64(2), testutil.ToFloat64(measures.Summary.With(prometheus.Labels{
+		ServerLabel:    "api",
+		DimensionLabel: PrincipalDimension,
+		KeyLabel:       "alice",
+	})))
+	assert.Equal(3, testutil.CollectAndCount(measures.Summary))
+
+	s.Record("bob", "/b", PartnerNotAllowed, false)
+	s.Emit()
+	assert.Equal(2, logs.Len())
+	assert.Equal(3, testutil.CollectAndCount(measures.Summary))
+	assert.Equal(float64(1), testutil.ToFloat64(measures.Summary.With(prometheus.Labels{
+		ServerLabel:    "api",
+		DimensionLabel: ReasonDimension,
+		KeyLabel:       PartnerNotAllowed,
+	})))
+}
+
+func TestDecisionSummaryLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	s := NewDecisionSummary(DecisionSummaryConfig{
+		Interval: 10 * time.Millisecond,
+		Logger:   zap.New(core),
+	}, nil, "")
+	hook := s.Hook()
+	require.NoError(t, hook.OnStart(context.Background()))
+	s.Start()
+	assert.Eventually(func() bool { return logs.Len() > 0 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, hook.OnStop(context.Background()))
+	n := logs.Len()
+	s.Stop()
+	assert.Equal(n, logs.Len())
+}
+
+func TestMetricValidatorDecisionSummary(t *testing.T) {
+	assert := assert.New(t)
+	checker := new(mockCapabilitiesChecker)
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(errWithReason{
+		err:    errors.New("check test error"),
+		reason: NoCapabilitiesMatch,
+	}).Once()
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(nil).Once()
+	s := NewDecisionSummary(DecisionSummaryConfig{Logger: zap.NewNop()}, nil, "")
+	m, err := NewMetricValidator(checker, &AuthCapabilityCheckMeasures{
+		CapabilityCheckOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testCounter",
+		}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+			PartnerIDLabel, EndpointLabel, MethodLabel}),
+	}, MonitorOnly(), WithDecisionSummary(s), WithDecisionSummary(nil))
+	require.NoError(t, err)
+
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token: bascule.NewToken("test", "alice", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"comcast"},
+			},
+		})),
+		Request: bascule.Request{
+			URL:    &url.URL{Path: "/test"},
+			Method: "GET",
+		},
+	})
+	assert.NoError(m.Check(ctx, nil))
+	assert.NoError(m.Check(ctx, nil))
+
+	r := s.Summarize()
+	assert.Equal(1, r.Failures)
+	assert.Zero(r.Rejected)
+	assert.Equal([]SummaryCount{{Key: "alice", Count: 1}}, r.Principals)
+	assert.Equal([]SummaryCount{{Key: NoneEndpoint, Count: 1}}, r.Endpoints)
+	assert.Equal([]SummaryCount{{Key: NoCapabilitiesMatch, Count: 1}}, r.Reasons)
+}
@@ -0,0 +1,230 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDeprecationLogInterval = time.Hour
+	defaultDeprecationMaxEntries  = 10000
+)
+
+// LegacyCapabilityFormat is a deprecated capability format, such as an old
+// prefix.
+type LegacyCapabilityFormat struct {
+	// Name identifies the format in the metric label and log.
+	Name string
+
+	// Pattern is a regular expression matching capabilities in the format.
+	Pattern string
+}
+
+// LegacyClaimLocation is a deprecated place to find capabilities in a token.
+type LegacyClaimLocation struct {
+	// Name identifies the location in the metric label and log.
+	Name string
+
+	// KeyPath is the path to the claim.
+	KeyPath []string
+}
+
+// DeprecationConfig configures a DeprecationDetector.
+type DeprecationConfig struct {
+	// Formats are the deprecated capability formats to look for in the
+	// capabilities found at the KeyPath.
+	Formats []LegacyCapabilityFormat
+
+	// Locations are the deprecated claims to look for.
+	Locations []LegacyClaimLocation
+
+	// KeyPath is where the current capabilities are.  Defaults to
+	// CapabilityKeys().
+	KeyPath []string
+
+	// LogInterval is how often each principal's use of a deprecation is
+	// logged.  The metric counts every use.  Defaults to an hour.  A
+	// negative interval logs every use.
+	LogInterval time.Duration
+
+	// MaxEntries bounds the number of principals and deprecations whose last
+	// log time is kept.  Defaults to 10000.
+	MaxEntries int
+
+	// GetLogger gets the logger from the context.  Defaults to sallust.Get.
+	GetLogger func(context.Context) *zap.Logger
+}
+
+type legacyFormat struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// DeprecationDetector is a Validator that finds tokens using deprecated
+// capability formats or claim locations, counting them in a metric and
+// logging a warning for each principal, so that operators can drive
+// migrations with data.  It never rejects a request.
+type DeprecationDetector struct {
+	formats  []legacyFormat
+	config   DeprecationConfig
+	measures *DeprecationMeasures
+	server   string
+	clientID ClientIDTransform
+	now      func() time.Time
+
+	lock   sync.Mutex
+	logged map[string]time.Time
+}
+
+// NewDeprecationDetector creates a DeprecationDetector.  The measures and
+// client ID transform are optional.
+func NewDeprecationDetector(config DeprecationConfig, measures *DeprecationMeasures, server string, clientID ClientIDTransform) (*DeprecationDetector, error) {
+	if len(config.KeyPath) == 0 {
+		config.KeyPath = CapabilityKeys()
+	}
+	if config.LogInterval == 0 {
+		config.LogInterval = defaultDeprecationLogInterval
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultDeprecationMaxEntries
+	}
+	if config.GetLogger == nil {
+		config.GetLogger = sallust.Get
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	d := &DeprecationDetector{
+		config:   config,
+		measures: measures,
+		server:   server,
+		clientID: clientID,
+		now:      time.Now,
+		logged:   make(map[string]time.Time),
+	}
+	for _, f := range config.Formats {
+		r, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile legacy format [%v]: %w", f.Name, err)
+		}
+		d.formats = append(d.formats, legacyFormat{name: f.Name, pattern: r})
+	}
+	return d, nil
+}
+
+// Check records the deprecations the token uses.  It always returns nil.
+func (d *DeprecationDetector) Check(ctx context.Context, token bascule.Token) error {
+	if token == nil || token.Attributes() == nil {
+		return nil
+	}
+	attributes := token.Attributes()
+	principal := token.Principal()
+
+	for _, l := range d.config.Locations {
+		if _, ok := bascule.GetNestedAttribute(attributes, l.KeyPath...); ok {
+			d.found(ctx, principal, l.Name, zap.Strings("keyPath", l.KeyPath))
+		}
+	}
+	if len(d.formats) == 0 {
+		return nil
+	}
+	capabilities, _ := getCapabilities(attributes, d.config.KeyPath)
+	for _, f := range d.formats {
+		for _, c := range capabilities {
+			if f.pattern.MatchString(c) {
+				d.found(ctx, principal, f.name, zap.String("capability", c))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// found counts a use of the deprecation and logs it, unless the principal's
+// use was logged within the LogInterval.
+func (d *DeprecationDetector) found(ctx context.Context, principal, name string, detail zap.Field) {
+	d.record(principal, name)
+	if !d.shouldLog(principal, name) {
+		return
+	}
+	logger := d.config.GetLogger(ctx)
+	if logger == nil {
+		logger = sallust.Get(ctx)
+	}
+	fields := []zap.Field{
+		zap.String("principal", principal),
+		zap.String("deprecation", name),
+		detail,
+	}
+	if auth, ok := bascule.FromContext(ctx); ok && auth.Request.URL != nil {
+		fields = append(fields,
+			zap.String("method", auth.Request.Method),
+			zap.String("path", auth.Request.URL.EscapedPath()))
+	}
+	logger.Warn("deprecated capability usage", fields...)
+}
+
+func (d *DeprecationDetector) shouldLog(principal, name string) bool {
+	if d.config.LogInterval < 0 {
+		return true
+	}
+	key := principal + "\x00" + name
+	now := d.now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if last, ok := d.logged[key]; ok && now.Sub(last) < d.config.LogInterval {
+		return false
+	}
+	if len(d.logged) >= d.config.MaxEntries {
+		for k, last := range d.logged {
+			if now.Sub(last) >= d.config.LogInterval {
+				delete(d.logged, k)
+			}
+		}
+		if len(d.logged) >= d.config.MaxEntries {
+			d.logged = make(map[string]time.Time)
+		}
+	}
+	d.logged[key] = now
+	return true
+}
+
+func (d *DeprecationDetector) record(principal, name string) {
+	if d.measures == nil || d.measures.Usage == nil {
+		return
+	}
+	client := principal
+	if d.clientID != nil {
+		client = d.clientID(client)
+	}
+	d.measures.Usage.With(prometheus.Labels{
+		ServerLabel:      d.server,
+		ClientIDLabel:    client,
+		DeprecationLabel: name,
+	}).Inc()
+}
@@ -0,0 +1,193 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var _ bascule.Validator = (*DeprecationDetector)(nil)
+
+func TestNewDeprecationDetector(t *testing.T) {
+	assert := assert.New(t)
+	d, err := NewDeprecationDetector(DeprecationConfig{
+		Formats: []LegacyCapabilityFormat{{Name: "bad", Pattern: "("}},
+	}, nil, "", nil)
+	assert.Error(err)
+	assert.Nil(d)
+
+	d, err = NewDeprecationDetector(DeprecationConfig{}, nil, "", nil)
+	require.NoError(t, err)
+	assert.Equal(CapabilityKeys(), d.config.KeyPath)
+	assert.Equal(defaultDeprecationLogInterval, d.config.LogInterval)
+	assert.Equal(defaultDeprecationMaxEntries, d.config.MaxEntries)
+	assert.Equal(defaultServer, d.server)
+	assert.NotNil(d.config.GetLogger)
+}
+
+func TestDeprecationDetector(t *testing.T) {
+	tests := []struct {
+		description  string
+		attributes   map[string]interface{}
+		expectedUses map[string]float64
+		expectedLogs int
+	}{
+		{
+			description: "Current Format",
+			attributes: map[string]interface{}{
+				"capabilities": []interface{}{"x1:webpa:api:.*:all"},
+			},
+		},
+		{
+			description: "Legacy Format",
+			attributes: map[string]interface{}{
+				"capabilities": []interface{}{"x1:webpa:api:.*:all", "webpa:api:.*:get", "webpa:api:/a:get"},
+			},
+			expectedUses: map[string]float64{"v0_prefix": 1},
+			expectedLogs: 1,
+		},
+		{
+			description: "Legacy Location",
+			attributes: map[string]interface{}{
+				"allowedResources": map[string]interface{}{
+					"capabilities": []interface{}{"x1:webpa:api:.*:all"},
+				},
+			},
+			expectedUses: map[string]float64{"nested_capabilities": 1},
+			expectedLogs: 1,
+		},
+		{
+			description: "Both",
+			attributes: map[string]interface{}{
+				"capabilities": []interface{}{"webpa:api:.*:get"},
+				"allowedResources": map[string]interface{}{
+					"capabilities": []interface{}{"webpa:api:.*:get"},
+				},
+			},
+			expectedUses: map[string]float64{"v0_prefix": 1, "nested_capabilities": 1},
+			expectedLogs: 2,
+		},
+		{
+			description: "No Attributes",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			core, logs := observer.New(zapcore.DebugLevel)
+			measures := &DeprecationMeasures{
+				Usage: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testDeprecatedUsage",
+				}, []string{ServerLabel, ClientIDLabel, DeprecationLabel}),
+			}
+			d, err := NewDeprecationDetector(DeprecationConfig{
+				Formats: []LegacyCapabilityFormat{{Name: "v0_prefix", Pattern: "^webpa:"}},
+				Locations: []LegacyClaimLocation{
+					{Name: "nested_capabilities", KeyPath: []string{"allowedResources", "capabilities"}},
+				},
+				GetLogger: func(context.Context) *zap.Logger { return zap.New(core) },
+			}, measures, "", nil)
+			require.NoError(err)
+
+			var attributes bascule.Attributes
+			if tc.attributes != nil {
+				attributes = bascule.NewAttributes(tc.attributes)
+			}
+			token := bascule.NewToken("jwt", "alice", attributes)
+			u, err := url.Parse("/things")
+			require.NoError(err)
+			ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Token:   token,
+				Request: bascule.Request{URL: u, Method: "GET"},
+			})
+			assert.NoError(d.Check(ctx, token))
+
+			for _, name := range []string{"v0_prefix", "nested_capabilities"} {
+				assert.Equal(tc.expectedUses[name], testutil.ToFloat64(measures.Usage.With(prometheus.Labels{
+					ServerLabel:      defaultServer,
+					ClientIDLabel:    "alice",
+					DeprecationLabel: name,
+				})), name)
+			}
+			require.Equal(tc.expectedLogs, logs.Len())
+			for _, entry := range logs.All() {
+				assert.Equal(zapcore.WarnLevel, entry.Level)
+				fields := entry.ContextMap()
+				assert.Equal("alice", fields["principal"])
+				assert.Equal("/things", fields["path"])
+			}
+		})
+	}
+}
+
+func TestDeprecationDetectorLogInterval(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	measures := &DeprecationMeasures{
+		Usage: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testDeprecatedUsage",
+		}, []string{ServerLabel, ClientIDLabel, DeprecationLabel}),
+	}
+	d, err := NewDeprecationDetector(DeprecationConfig{
+		Formats:    []LegacyCapabilityFormat{{Name: "v0_prefix", Pattern: "^webpa:"}},
+		MaxEntries: 1,
+		GetLogger:  func(context.Context) *zap.Logger { return zap.New(core) },
+	}, measures, "", HashClientID(0))
+	require.NoError(err)
+	now := time.Unix(1700000000, 0)
+	d.now = func() time.Time { return now }
+
+	token := func(principal string) bascule.Token {
+		return bascule.NewToken("jwt", principal, bascule.NewAttributes(map[string]interface{}{
+			"capabilities": []interface{}{"webpa:api:.*:get"},
+		}))
+	}
+	ctx := context.Background()
+
+	// each principal is logged once per interval, but every use is counted.
+	assert.NoError(d.Check(ctx, token("alice")))
+	assert.NoError(d.Check(ctx, token("alice")))
+	assert.Equal(1, logs.Len())
+	assert.Equal(2.0, testutil.ToFloat64(measures.Usage.With(prometheus.Labels{
+		ServerLabel:      defaultServer,
+		ClientIDLabel:    HashClientID(0)("alice"),
+		DeprecationLabel: "v0_prefix",
+	})))
+
+	assert.NoError(d.Check(ctx, token("bob")))
+	assert.Equal(2, logs.Len())
+
+	now = now.Add(time.Hour)
+	assert.NoError(d.Check(ctx, token("alice")))
+	assert.Equal(3, logs.Len())
+	assert.LessOrEqual(len(d.logged), 1)
+}
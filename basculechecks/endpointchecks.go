@@ -120,7 +120,7 @@ func (e RegexEndpointCheck) Name() string {
 // urlPathNormalization returns an url path with a leading `/` if missing,
 // otherwise the same unmodified url path is returned.
 func urlPathNormalization(url string) string {
-	if url[0] == '/' {
+	if strings.HasPrefix(url, "/") {
 		return url
 	}
 
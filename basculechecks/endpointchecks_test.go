@@ -98,6 +98,14 @@ func TestRegexEndpointCheck(t *testing.T) {
 			method:          "get",
 			okExpected:      true,
 		},
+		{
+			description:     "Empty URL Success",
+			prefix:          "a:b:c:",
+			acceptAllMethod: "all",
+			capability:      "a:b:c:/:all",
+			method:          "get",
+			okExpected:      true,
+		},
 		{
 			description: "No Match Error",
 			prefix:      "a:b:c:",
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"github.com/s-srakshe/bascule"
+)
+
+// EnforcementTarget chooses the requests a MetricValidator enforces, by the
+// token's partners and client ID, so enforcement can be rolled out one
+// customer at a time.  Requests that aren't enforced are only recorded, like
+// with MonitorOnly.
+type EnforcementTarget struct {
+	// Partners are the partner IDs to target.  A token is targeted if any of
+	// its partners is listed.
+	Partners []string
+
+	// ClientIDs are the principals to target.
+	ClientIDs []string
+
+	// Exclude enforces every request except the targeted ones, rather than
+	// only the targeted ones.
+	Exclude bool
+
+	// PartnerKeyPath is the location of the partner IDs in the token's
+	// attributes.  Defaults to PartnerKeys().
+	PartnerKeyPath []string
+
+	// FallbackPartnerKeyPaths are searched in order for the partner IDs
+	// when they aren't at the PartnerKeyPath.
+	FallbackPartnerKeyPaths [][]string
+}
+
+type enforcementTarget struct {
+	partners partnerSet
+	clients  map[string]struct{}
+	exclude  bool
+	keyPaths [][]string
+}
+
+func newEnforcementTarget(t EnforcementTarget) *enforcementTarget {
+	e := enforcementTarget{
+		partners: newPartnerSet(t.Partners),
+		clients:  make(map[string]struct{}, len(t.ClientIDs)),
+		exclude:  t.Exclude,
+		keyPaths: partnerKeyPaths(t.PartnerKeyPath, t.FallbackPartnerKeyPaths),
+	}
+	for _, c := range t.ClientIDs {
+		e.clients[c] = struct{}{}
+	}
+	return &e
+}
+
+// matches returns true if the request with the principal and token given
+// should be enforced.
+func (e *enforcementTarget) matches(principal string, token bascule.Token) bool {
+	return e.targets(principal, token) != e.exclude
+}
+
+// targets returns true if the principal or one of the token's partners is
+// listed.  Partners that can't be read from the token aren't targeted.
+func (e *enforcementTarget) targets(principal string, token bascule.Token) bool {
+	if _, ok := e.clients[principal]; ok && len(principal) > 0 {
+		return true
+	}
+	if len(e.partners) == 0 || token == nil {
+		return false
+	}
+	partners, err := FindPartnerIDs(token.Attributes(), e.keyPaths...)
+	if err != nil {
+		return false
+	}
+	for _, p := range partners {
+		if _, ok := e.partners[p]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforcementTarget(t *testing.T) {
+	partnerAttributes := func(partners ...string) bascule.Attributes {
+		return bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": partners,
+			},
+		})
+	}
+	tests := []struct {
+		description string
+		target      EnforcementTarget
+		principal   string
+		attributes  bascule.Attributes
+		nilToken    bool
+		expected    bool
+	}{
+		{
+			description: "Client targeted",
+			target:      EnforcementTarget{ClientIDs: []string{"alice"}},
+			principal:   "alice",
+			expected:    true,
+		},
+		{
+			description: "Client not targeted",
+			target:      EnforcementTarget{ClientIDs: []string{"alice"}},
+			principal:   "bob",
+			attributes:  partnerAttributes("comcast"),
+		},
+		{
+			description: "Partner targeted",
+			target:      EnforcementTarget{Partners: []string{"comcast"}},
+			principal:   "bob",
+			attributes:  partnerAttributes("other", "comcast"),
+			expected:    true,
+		},
+		{
+			description: "Partner not targeted",
+			target:      EnforcementTarget{Partners: []string{"comcast"}},
+			principal:   "bob",
+			attributes:  partnerAttributes("other"),
+		},
+		{
+			description: "Custom key path",
+			target: EnforcementTarget{
+				Partners:       []string{"comcast"},
+				PartnerKeyPath: []string{"partner"},
+			},
+			principal:  "bob",
+			attributes: bascule.NewAttributes(map[string]interface{}{"partner": "comcast"}),
+			expected:   true,
+		},
+		{
+			description: "Unreadable partners",
+			target:      EnforcementTarget{Partners: []string{"comcast"}},
+			principal:   "bob",
+			attributes: bascule.NewAttributes(map[string]interface{}{
+				"allowedResources": map[string]interface{}{
+					"allowedPartners": map[string]int{"comcast": 1},
+				},
+			}),
+		},
+		{
+			description: "Nil token",
+			target:      EnforcementTarget{Partners: []string{"comcast"}, ClientIDs: []string{""}},
+			nilToken:    true,
+		},
+		{
+			description: "Excluded client",
+			target:      EnforcementTarget{ClientIDs: []string{"alice"}, Exclude: true},
+			principal:   "alice",
+		},
+		{
+			description: "Not excluded partner",
+			target:      EnforcementTarget{Partners: []string{"comcast"}, Exclude: true},
+			principal:   "bob",
+			attributes:  partnerAttributes("other"),
+			expected:    true,
+		},
+		{
+			description: "Not excluded nil token",
+			target:      EnforcementTarget{Partners: []string{"comcast"}, Exclude: true},
+			nilToken:    true,
+			expected:    true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var token bascule.Token
+			if !tc.nilToken {
+				token = bascule.NewToken("test", tc.principal, tc.attributes)
+			}
+			e := newEnforcementTarget(tc.target)
+			assert.Equal(t, tc.expected, e.matches(tc.principal, token))
+		})
+	}
+}
+
+func TestMetricValidatorEnforcementTarget(t *testing.T) {
+	checkErr := errWithReason{
+		err:    errors.New("check test error"),
+		reason: NoCapabilitiesMatch,
+	}
+	attributes := bascule.NewAttributes(map[string]interface{}{
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []string{"comcast"},
+		},
+	})
+	checker := new(mockCapabilitiesChecker)
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(checkErr)
+	measures := AuthCapabilityCheckMeasures{
+		CapabilityCheckOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testCounter",
+		}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+			PartnerIDLabel, EndpointLabel, MethodLabel}),
+	}
+	check := func(m *MetricValidator, principal string) error {
+		return m.Check(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+			Token: bascule.NewToken("test", principal, attributes),
+			Request: bascule.Request{
+				URL:    &url.URL{Path: "/test"},
+				Method: "GET",
+			},
+		}), nil)
+	}
+
+	m, err := NewMetricValidator(checker, &measures,
+		WithEnforcementTarget(EnforcementTarget{ClientIDs: []string{"alice"}}))
+	require.NoError(t, err)
+	assert.ErrorIs(t, check(m, "alice"), checkErr)
+	assert.NoError(t, check(m, "bob"))
+	assert.NoError(t, m.Check(context.Background(), nil))
+
+	m, err = NewMetricValidator(checker, &measures,
+		WithEnforcementTarget(EnforcementTarget{Partners: []string{"comcast"}, Exclude: true}))
+	require.NoError(t, err)
+	assert.NoError(t, check(m, "alice"))
+	assert.ErrorIs(t, m.Check(context.Background(), nil), ErrNoAuth)
+
+	m, err = NewMetricValidator(checker, &measures,
+		WithEnforcementTarget(EnforcementTarget{Exclude: true}))
+	require.NoError(t, err)
+	assert.Nil(t, m.target)
+	assert.ErrorIs(t, check(m, "alice"), checkErr)
+
+	m, err = NewMetricValidator(checker, &measures, MonitorOnly(),
+		WithEnforcementTarget(EnforcementTarget{ClientIDs: []string{"alice"}}))
+	require.NoError(t, err)
+	assert.NoError(t, check(m, "alice"))
+}
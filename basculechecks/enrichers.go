@@ -0,0 +1,89 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// ParsedValuesEnricher adds information to the ParsedValues before they are
+// given to the CapabilitiesChecker, so that custom checkers get more context
+// without parsing the request again.  Returning an error fails the check.
+type ParsedValuesEnricher func(context.Context, bascule.Authentication, ParsedValues) (ParsedValues, error)
+
+// With returns a copy of the ParsedValues with the key set to the value
+// given.  The original ParsedValues aren't modified.
+func (vs ParsedValues) With(key string, value interface{}) ParsedValues {
+	values := make(map[string]interface{}, len(vs.Values)+1)
+	for k, v := range vs.Values {
+		values[k] = v
+	}
+	values[key] = value
+	vs.Values = values
+	return vs
+}
+
+// Get returns the value added for the key given, if there is one.
+func (vs ParsedValues) Get(key string) (interface{}, bool) {
+	v, ok := vs.Values[key]
+	return v, ok
+}
+
+// String returns a stable representation of the ParsedValues, sorting the
+// added values by key.
+func (vs ParsedValues) String() string {
+	keys := make([]string, 0, len(vs.Values))
+	for k := range vs.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(vs.Endpoint)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\x00%s=%v", k, vs.Values[k])
+	}
+	return b.String()
+}
+
+// WithParsedValuesEnrichers adds enrichers that are run in order on the
+// ParsedValues before the CapabilitiesChecker is called.
+func WithParsedValuesEnrichers(enrichers ...ParsedValuesEnricher) MetricOption {
+	return func(m *MetricValidator) {
+		for _, e := range enrichers {
+			if e != nil {
+				m.enrichers = append(m.enrichers, e)
+			}
+		}
+	}
+}
+
+func (m MetricValidator) enrich(ctx context.Context, auth bascule.Authentication, vs ParsedValues) (ParsedValues, error) {
+	var err error
+	for _, e := range m.enrichers {
+		vs, err = e(ctx, auth, vs)
+		if err != nil {
+			return vs, fmt.Errorf("failed to enrich parsed values: %w", err)
+		}
+	}
+	return vs, nil
+}
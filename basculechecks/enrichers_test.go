@@ -0,0 +1,123 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsedValues(t *testing.T) {
+	assert := assert.New(t)
+	vs := ParsedValues{Endpoint: "/a"}
+	vs2 := vs.With("region", "east")
+	vs3 := vs2.With("account", 5)
+
+	_, ok := vs.Get("region")
+	assert.False(ok)
+	_, ok = vs2.Get("account")
+	assert.False(ok)
+	v, ok := vs3.Get("region")
+	assert.True(ok)
+	assert.Equal("east", v)
+
+	assert.Equal("/a", vs.String())
+	assert.Equal("/a\x00account=5\x00region=east", vs3.String())
+}
+
+func TestMetricValidatorEnrichers(t *testing.T) {
+	u, err := url.ParseRequestURI("/test")
+	require.NoError(t, err)
+	auth := bascule.Authentication{
+		Token: bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"meh"},
+			},
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	}
+	region := func(_ context.Context, _ bascule.Authentication, vs ParsedValues) (ParsedValues, error) {
+		return vs.With("region", "east"), nil
+	}
+	failErr := errWithReason{err: errors.New("test"), reason: "enrich_failure"}
+	fail := func(_ context.Context, _ bascule.Authentication, vs ParsedValues) (ParsedValues, error) {
+		return vs, failErr
+	}
+
+	tests := []struct {
+		description    string
+		enrichers      []ParsedValuesEnricher
+		expectedValues ParsedValues
+		expectedErr    error
+		expectedReason string
+	}{
+		{
+			description:    "Success",
+			enrichers:      []ParsedValuesEnricher{region, nil},
+			expectedValues: ParsedValues{Endpoint: NoneEndpoint, Values: map[string]interface{}{"region": "east"}},
+		},
+		{
+			description:    "Enricher Error",
+			enrichers:      []ParsedValuesEnricher{region, fail},
+			expectedErr:    failErr,
+			expectedReason: "enrich_failure",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			checker := new(mockCapabilitiesChecker)
+			if tc.expectedErr == nil {
+				checker.On("CheckAuthentication", auth, tc.expectedValues).Return(nil).Once()
+			}
+			counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "testCounter",
+				Help: "testCounter",
+			}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+				PartnerIDLabel, EndpointLabel, MethodLabel})
+			m, err := NewMetricValidator(checker, &AuthCapabilityCheckMeasures{CapabilityCheckOutcome: counter},
+				WithParsedValuesEnrichers(tc.enrichers...))
+			require.NoError(t, err)
+
+			err = m.Check(bascule.WithAuthentication(context.Background(), auth), nil)
+			checker.AssertExpectations(t)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Equal(1.0, testutil.ToFloat64(counter.With(prometheus.Labels{
+				ServerLabel:    defaultServer,
+				OutcomeLabel:   RejectedOutcome,
+				ReasonLabel:    tc.expectedReason,
+				ClientIDLabel:  "p",
+				PartnerIDLabel: "meh",
+				EndpointLabel:  NoneEndpoint,
+				MethodLabel:    "GET",
+			})))
+		})
+	}
+}
@@ -17,6 +17,8 @@
 
 package basculechecks
 
+import "github.com/s-srakshe/bascule"
+
 // Reasoner is an error that provides a failure reason to use as a value for a
 // metric label.
 type Reasoner interface {
@@ -43,3 +45,16 @@ func (e errWithReason) Reason() string {
 func (e errWithReason) Unwrap() error {
 	return e.err
 }
+
+// ErrorClass returns the class of the error based on its reason.
+func (e errWithReason) ErrorClass() bascule.ErrorClass {
+	switch e.reason {
+	case PartnerNotAllowed, UndeterminedPartnerID:
+		return bascule.PartnerClass
+	case TooManyInFlight, QuotaExceeded:
+		return bascule.RateLimitedClass
+	case BindingMismatch:
+		return bascule.InvalidClass
+	}
+	return bascule.CapabilityClass
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,3 +40,21 @@ func TestErrorWithReason(t *testing.T) {
 
 	assert.Equal(testErr, e.Unwrap())
 }
+
+func TestErrorWithReasonClass(t *testing.T) {
+	tests := []struct {
+		reason        string
+		expectedClass bascule.ErrorClass
+	}{
+		{reason: UndeterminedCapabilities, expectedClass: bascule.CapabilityClass},
+		{reason: NoCapabilitiesMatch, expectedClass: bascule.CapabilityClass},
+		{reason: TooManyInFlight, expectedClass: bascule.RateLimitedClass},
+		{reason: QuotaExceeded, expectedClass: bascule.RateLimitedClass},
+	}
+	for _, tc := range tests {
+		t.Run(tc.reason, func(t *testing.T) {
+			err := errWithReason{err: errors.New("test"), reason: tc.reason}
+			assert.Equal(t, tc.expectedClass, bascule.ClassOf(err))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// The attribute names of a FlagContext.
+const (
+	FlagCapabilitiesKey  = "capabilities"
+	FlagPartnerIDsKey    = "partnerIds"
+	FlagTokenTypeKey     = "tokenType"
+	FlagAuthorizationKey = "authorization"
+)
+
+// FlagContext is a feature flag evaluation context built from a token.  It
+// is shaped like an OpenFeature evaluation context, a targeting key and
+// attributes, so it can be passed to a provider without custom plumbing:
+//
+//	fc, _ := basculechecks.FlagContextFromContext(ctx)
+//	evalCtx := openfeature.NewEvaluationContext(fc.TargetingKey, fc.Attributes)
+type FlagContext struct {
+	// TargetingKey is the token's principal.
+	TargetingKey string
+
+	// Attributes holds the token's capabilities and partner IDs as string
+	// slices, its type and Authorization value, and any attributes added by
+	// the FlagContextConfig.
+	Attributes map[string]interface{}
+}
+
+// HasCapability returns true if the token has the capability given.
+func (f FlagContext) HasCapability(capability string) bool {
+	capabilities, _ := f.Attributes[FlagCapabilitiesKey].([]string)
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// FlagContextConfig determines how a FlagContext is built.
+type FlagContextConfig struct {
+	// CapabilityKeyPath is where the capabilities are in the token's
+	// attributes.  CapabilityKeys is used if it's empty.
+	CapabilityKeyPath []string
+
+	// PartnerKeyPath is where the partner IDs are in the token's attributes.
+	// PartnerKeys is used if it's empty.
+	PartnerKeyPath []string
+
+	// FallbackPartnerKeyPaths are searched in order for the partner IDs
+	// when they aren't at the PartnerKeyPath.
+	FallbackPartnerKeyPaths [][]string
+
+	// Attributes adds the token attributes at each key path to the
+	// FlagContext under the name given.  Missing attributes are left out.
+	Attributes map[string][]string
+}
+
+// Build creates the FlagContext for the Authentication given.  Capabilities
+// and partner IDs that are missing or can't be read are left empty, so flags
+// fall back to their defaults rather than failing.
+func (c FlagContextConfig) Build(auth bascule.Authentication) FlagContext {
+	fc := FlagContext{
+		Attributes: map[string]interface{}{
+			FlagCapabilitiesKey:  []string{},
+			FlagPartnerIDsKey:    []string{},
+			FlagAuthorizationKey: string(auth.Authorization),
+		},
+	}
+	if auth.Token == nil {
+		return fc
+	}
+	fc.TargetingKey = auth.Token.Principal()
+	fc.Attributes[FlagTokenTypeKey] = auth.Token.Type()
+	attributes := auth.Token.Attributes()
+	if attributes == nil {
+		return fc
+	}
+
+	if capabilities, err := getCapabilities(attributes, c.CapabilityKeyPath); err == nil {
+		fc.Attributes[FlagCapabilitiesKey] = capabilities
+	}
+	if partnerIDs, err := FindPartnerIDs(attributes, partnerKeyPaths(c.PartnerKeyPath, c.FallbackPartnerKeyPaths)...); err == nil {
+		fc.Attributes[FlagPartnerIDsKey] = partnerIDs
+	}
+	for name, keyPath := range c.Attributes {
+		if val, ok := bascule.GetNestedAttribute(attributes, keyPath...); ok {
+			fc.Attributes[name] = val
+		}
+	}
+	return fc
+}
+
+// FromContext creates the FlagContext for the Authentication in the context.
+// It returns false if there isn't one.
+func (c FlagContextConfig) FromContext(ctx context.Context) (FlagContext, bool) {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return FlagContext{}, false
+	}
+	return c.Build(auth), true
+}
+
+// FlagContextFromContext creates the FlagContext for the Authentication in
+// the context using the default key paths.
+func FlagContextFromContext(ctx context.Context) (FlagContext, bool) {
+	return FlagContextConfig{}.FromContext(ctx)
+}
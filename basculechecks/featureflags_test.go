@@ -0,0 +1,152 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagContextConfigBuild(t *testing.T) {
+	attributes := bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []interface{}{"x:a:all", "x:b:get"},
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []string{"comcast"},
+		},
+		"scopes": []string{"read"},
+		"custom": map[string]interface{}{
+			"caps":     []string{"y:c:all"},
+			"partners": "sky",
+			"tier":     "gold",
+		},
+	})
+	tests := []struct {
+		description        string
+		config             FlagContextConfig
+		auth               bascule.Authentication
+		expectedKey        string
+		expectedAttributes map[string]interface{}
+	}{
+		{
+			description: "Defaults",
+			auth: bascule.Authentication{
+				Authorization: "Bearer",
+				Token:         bascule.NewToken("jwt", "alice", attributes),
+			},
+			expectedKey: "alice",
+			expectedAttributes: map[string]interface{}{
+				FlagCapabilitiesKey:  []string{"x:a:all", "x:b:get"},
+				FlagPartnerIDsKey:    []string{"comcast"},
+				FlagTokenTypeKey:     "jwt",
+				FlagAuthorizationKey: "Bearer",
+			},
+		},
+		{
+			description: "Custom Paths",
+			config: FlagContextConfig{
+				CapabilityKeyPath: []string{"custom", "caps"},
+				PartnerKeyPath:    []string{"custom", "partners"},
+				Attributes: map[string][]string{
+					"tier":    {"custom", "tier"},
+					"missing": {"nope"},
+				},
+			},
+			auth: bascule.Authentication{
+				Authorization: "Bearer",
+				Token:         bascule.NewToken("jwt", "alice", attributes),
+			},
+			expectedKey: "alice",
+			expectedAttributes: map[string]interface{}{
+				FlagCapabilitiesKey:  []string{"y:c:all"},
+				FlagPartnerIDsKey:    []string{"sky"},
+				FlagTokenTypeKey:     "jwt",
+				FlagAuthorizationKey: "Bearer",
+				"tier":               "gold",
+			},
+		},
+		{
+			description: "Unreadable Values",
+			config: FlagContextConfig{
+				CapabilityKeyPath: []string{"custom"},
+				PartnerKeyPath:    []string{"custom"},
+			},
+			auth: bascule.Authentication{
+				Authorization: "Basic",
+				Token:         bascule.NewToken("basic", "bob", attributes),
+			},
+			expectedKey: "bob",
+			expectedAttributes: map[string]interface{}{
+				FlagCapabilitiesKey:  []string{},
+				FlagPartnerIDsKey:    []string{},
+				FlagTokenTypeKey:     "basic",
+				FlagAuthorizationKey: "Basic",
+			},
+		},
+		{
+			description: "Nil Attributes",
+			auth: bascule.Authentication{
+				Token: bascule.NewToken("basic", "bob", nil),
+			},
+			expectedKey: "bob",
+			expectedAttributes: map[string]interface{}{
+				FlagCapabilitiesKey:  []string{},
+				FlagPartnerIDsKey:    []string{},
+				FlagTokenTypeKey:     "basic",
+				FlagAuthorizationKey: "",
+			},
+		},
+		{
+			description: "No Token",
+			expectedAttributes: map[string]interface{}{
+				FlagCapabilitiesKey:  []string{},
+				FlagPartnerIDsKey:    []string{},
+				FlagAuthorizationKey: "",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			fc := tc.config.Build(tc.auth)
+			assert.Equal(tc.expectedKey, fc.TargetingKey)
+			assert.Equal(tc.expectedAttributes, fc.Attributes)
+		})
+	}
+}
+
+func TestFlagContextFromContext(t *testing.T) {
+	assert := assert.New(t)
+	_, ok := FlagContextFromContext(context.Background())
+	assert.False(ok)
+
+	attributes := bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{"x:a:all"},
+	})
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token: bascule.NewToken("jwt", "alice", attributes),
+	})
+	fc, ok := FlagContextFromContext(ctx)
+	assert.True(ok)
+	assert.Equal("alice", fc.TargetingKey)
+	assert.True(fc.HasCapability("x:a:all"))
+	assert.False(fc.HasCapability("x:b:all"))
+	assert.False(FlagContext{}.HasCapability("x:a:all"))
+}
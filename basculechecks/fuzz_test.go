@@ -0,0 +1,75 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+)
+
+func FuzzRegexEndpointCheck(f *testing.F) {
+	f.Add("x1:webpa:api:.*:all", "/things/1", "GET")
+	f.Add("x1:webpa:api:/things:get", "/things/1", "get")
+	f.Add("x1:webpa:api:things:get", "things", "GET")
+	f.Add("x1:webpa:api:.*:all", "", "GET")
+	f.Add("x1:webpa:api:[:get", "/", "GET")
+	f.Add("x1:webpa:api:", "/", "")
+	f.Add("", "", "")
+
+	r, err := NewRegexEndpointCheck("x1:webpa:api:", "all")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, capability string, path string, method string) {
+		r.Authorized(capability, path, method)
+
+		c := CapabilitiesValidator{Checker: r}
+		auth := bascule.Authentication{
+			Token: bascule.NewToken("jwt", "test", bascule.NewAttributes(map[string]interface{}{
+				"capabilities": []interface{}{capability},
+			})),
+			Request: bascule.Request{
+				URL:    &url.URL{Path: path},
+				Method: method,
+			},
+		}
+		err := c.CheckAuthentication(auth, ParsedValues{})
+		if err == nil && method == "" {
+			t.Errorf("capability [%q] authorized a request without a method", capability)
+		}
+	})
+}
+
+func FuzzGetCapabilities(f *testing.F) {
+	f.Add("x1:webpa:api:.*:all", "capabilities")
+	f.Add("", "")
+	f.Add("a", "missing")
+	f.Fuzz(func(t *testing.T, capability string, key string) {
+		attrs := bascule.NewAttributes(map[string]interface{}{
+			"capabilities": []interface{}{capability},
+			"nested":       map[string]interface{}{key: capability},
+		})
+		vals, err := getCapabilities(attrs, []string{key})
+		if err == nil && len(vals) == 0 {
+			t.Errorf("key [%q] returned no capabilities without an error", key)
+		}
+		getCapabilities(attrs, []string{"nested", key})
+	})
+}
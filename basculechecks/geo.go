@@ -0,0 +1,249 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+)
+
+// UnknownCountry is the country label used when the client's country
+// couldn't be determined.
+const UnknownCountry = "unknown"
+
+var (
+	ErrNilGeoIPResolver = errors.New("geoip resolver cannot be nil")
+
+	// ErrGeoBlocked is returned when the request comes from a country the
+	// policy doesn't allow.
+	ErrGeoBlocked = errWithReason{
+		err:    errors.New("requests from this country are not allowed"),
+		reason: GeoBlocked,
+	}
+
+	// ErrGeoLookupFailed is returned when the client's country couldn't be
+	// determined and the validator doesn't fail open.
+	ErrGeoLookupFailed = errWithReason{
+		err:    errors.New("couldn't determine the client's country"),
+		reason: GeoLookupFailed,
+	}
+)
+
+// GeoIPResolver finds the ISO 3166-1 alpha-2 country code of an IP address.
+// A MaxMind reader can be adapted with a GeoIPResolverFunc:
+//
+//	basculechecks.GeoIPResolverFunc(func(ip net.IP) (string, error) {
+//		record, err := reader.Country(ip)
+//		if err != nil {
+//			return "", err
+//		}
+//		return record.Country.IsoCode, nil
+//	})
+type GeoIPResolver interface {
+	Country(net.IP) (string, error)
+}
+
+// GeoIPResolverFunc makes it so any function with the same signature as
+// Country implements GeoIPResolver.
+type GeoIPResolverFunc func(net.IP) (string, error)
+
+// Country runs the function.
+func (f GeoIPResolverFunc) Country(ip net.IP) (string, error) {
+	return f(ip)
+}
+
+// GeoPolicy lists the countries allowed or denied, by ISO 3166-1 alpha-2
+// code.  Denied countries are always rejected.  If Allow isn't empty, only the
+// countries in it are allowed.
+type GeoPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// GeoEndpointPolicy is the GeoPolicy for the endpoints whose path matches the
+// regular expression from its start.
+type GeoEndpointPolicy struct {
+	Endpoint string
+	GeoPolicy
+}
+
+// GeoConfig configures a GeoValidator.  The most specific policy for a
+// request is used: the first endpoint policy matching its path, then the
+// policy for its token kind, then the default.
+type GeoConfig struct {
+	Default   GeoPolicy
+	Endpoints []GeoEndpointPolicy
+	Kinds     map[bascule.TokenKind]GeoPolicy
+
+	// FailOpen allows requests whose country can't be determined.  By
+	// default, they are rejected unless the policy has neither allowed nor
+	// denied countries.
+	FailOpen bool
+}
+
+type geoPolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+func newGeoPolicy(p GeoPolicy) geoPolicy {
+	return geoPolicy{allow: countrySet(p.Allow), deny: countrySet(p.Deny)}
+}
+
+func countrySet(countries []string) map[string]bool {
+	set := make(map[string]bool, len(countries))
+	for _, c := range countries {
+		set[strings.ToUpper(c)] = true
+	}
+	return set
+}
+
+func (p geoPolicy) empty() bool {
+	return len(p.allow) == 0 && len(p.deny) == 0
+}
+
+func (p geoPolicy) allows(country string) bool {
+	if p.deny[country] {
+		return false
+	}
+	return len(p.allow) == 0 || p.allow[country]
+}
+
+type geoEndpointPolicy struct {
+	endpoint *regexp.Regexp
+	policy   geoPolicy
+}
+
+// GeoValidator restricts where requests can come from using the client IP
+// captured in the bascule.Request and a GeoIPResolver.
+type GeoValidator struct {
+	resolver  GeoIPResolver
+	fallback  geoPolicy
+	endpoints []geoEndpointPolicy
+	kinds     map[bascule.TokenKind]geoPolicy
+	failOpen  bool
+	measures  *GeoMeasures
+	server    string
+}
+
+// NewGeoValidator creates a GeoValidator, compiling the endpoint expressions
+// in the config.  The measures are optional.
+func NewGeoValidator(resolver GeoIPResolver, config GeoConfig, measures *GeoMeasures, server string) (*GeoValidator, error) {
+	if resolver == nil {
+		return nil, ErrNilGeoIPResolver
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	g := &GeoValidator{
+		resolver: resolver,
+		fallback: newGeoPolicy(config.Default),
+		kinds:    make(map[bascule.TokenKind]geoPolicy, len(config.Kinds)),
+		failOpen: config.FailOpen,
+		measures: measures,
+		server:   server,
+	}
+	for _, e := range config.Endpoints {
+		r, err := regexp.Compile(e.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile endpoint [%v]: %w", e.Endpoint, err)
+		}
+		g.endpoints = append(g.endpoints, geoEndpointPolicy{endpoint: r, policy: newGeoPolicy(e.GeoPolicy)})
+	}
+	for kind, p := range config.Kinds {
+		g.kinds[kind] = newGeoPolicy(p)
+	}
+	return g, nil
+}
+
+// Check rejects the request if its country isn't allowed by the policy for
+// the request.
+func (g *GeoValidator) Check(ctx context.Context, token bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return ErrNoAuth
+	}
+	policy := g.policyFor(auth, token)
+	if policy.empty() {
+		return nil
+	}
+
+	country, err := g.country(auth.Request.ClientIP)
+	if err != nil {
+		if g.failOpen {
+			return nil
+		}
+		g.record(UnknownCountry)
+		return fmt.Errorf("%w: %v", ErrGeoLookupFailed, err)
+	}
+	if !policy.allows(country) {
+		g.record(country)
+		return fmt.Errorf("%w: [%v]", ErrGeoBlocked, country)
+	}
+	return nil
+}
+
+// policyFor returns the most specific policy for the request.
+func (g *GeoValidator) policyFor(auth bascule.Authentication, token bascule.Token) geoPolicy {
+	if auth.Request.URL != nil {
+		path := auth.Request.URL.EscapedPath()
+		for _, e := range g.endpoints {
+			if idxs := e.endpoint.FindStringIndex(path); len(idxs) > 0 && idxs[0] == 0 {
+				return e.policy
+			}
+		}
+	}
+	if token != nil {
+		if p, ok := g.kinds[bascule.KindOf(token)]; ok {
+			return p
+		}
+	}
+	return g.fallback
+}
+
+func (g *GeoValidator) country(clientIP string) (string, error) {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return "", fmt.Errorf("invalid client IP [%v]", clientIP)
+	}
+	country, err := g.resolver.Country(ip)
+	if err != nil {
+		return "", err
+	}
+	if country == "" {
+		return "", errors.New("no country found")
+	}
+	return strings.ToUpper(country), nil
+}
+
+func (g *GeoValidator) record(country string) {
+	if g.measures == nil || g.measures.Blocked == nil {
+		return
+	}
+	g.measures.Blocked.With(prometheus.Labels{
+		ServerLabel:  g.server,
+		CountryLabel: country,
+	}).Inc()
+}
@@ -0,0 +1,222 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*GeoValidator)(nil)
+
+var testCountries = GeoIPResolverFunc(func(ip net.IP) (string, error) {
+	switch ip.String() {
+	case "10.0.0.1":
+		return "us", nil
+	case "10.0.0.2":
+		return "CA", nil
+	case "10.0.0.3":
+		return "KP", nil
+	case "10.0.0.4":
+		return "", nil
+	}
+	return "", errors.New("not found")
+})
+
+func TestNewGeoValidator(t *testing.T) {
+	assert := assert.New(t)
+	g, err := NewGeoValidator(nil, GeoConfig{}, nil, "")
+	assert.ErrorIs(err, ErrNilGeoIPResolver)
+	assert.Nil(g)
+
+	g, err = NewGeoValidator(testCountries, GeoConfig{
+		Endpoints: []GeoEndpointPolicy{{Endpoint: "("}},
+	}, nil, "")
+	assert.Error(err)
+	assert.Nil(g)
+
+	g, err = NewGeoValidator(testCountries, GeoConfig{}, nil, "")
+	assert.NoError(err)
+	assert.Equal(defaultServer, g.server)
+}
+
+func TestGeoValidator(t *testing.T) {
+	config := GeoConfig{
+		Default: GeoPolicy{Deny: []string{"kp"}},
+		Endpoints: []GeoEndpointPolicy{
+			{Endpoint: "/us-only", GeoPolicy: GeoPolicy{Allow: []string{"US"}}},
+			{Endpoint: "/open"},
+		},
+		Kinds: map[bascule.TokenKind]GeoPolicy{
+			bascule.DeviceKind: {Allow: []string{"US", "CA"}},
+		},
+	}
+	tests := []struct {
+		description     string
+		config          *GeoConfig
+		path            string
+		clientIP        string
+		kind            bascule.TokenKind
+		noAuth          bool
+		expectedErr     error
+		expectedCountry string
+	}{
+		{
+			description: "Default Allowed",
+			path:        "/things",
+			clientIP:    "10.0.0.2",
+		},
+		{
+			description:     "Default Denied",
+			path:            "/things",
+			clientIP:        "10.0.0.3",
+			expectedErr:     ErrGeoBlocked,
+			expectedCountry: "KP",
+		},
+		{
+			description: "Endpoint Allowed",
+			path:        "/us-only/1",
+			clientIP:    "10.0.0.1",
+		},
+		{
+			description:     "Endpoint Not Allowed",
+			path:            "/us-only/1",
+			clientIP:        "10.0.0.2",
+			kind:            bascule.DeviceKind,
+			expectedErr:     ErrGeoBlocked,
+			expectedCountry: "CA",
+		},
+		{
+			description: "Endpoint Must Match From Start",
+			path:        "/v1/us-only",
+			clientIP:    "10.0.0.2",
+		},
+		{
+			description: "Open Endpoint",
+			path:        "/open",
+			clientIP:    "10.0.0.3",
+		},
+		{
+			description: "Kind Allowed",
+			path:        "/things",
+			clientIP:    "10.0.0.2",
+			kind:        bascule.DeviceKind,
+		},
+		{
+			description:     "Kind Not Allowed",
+			path:            "/things",
+			clientIP:        "10.0.0.3",
+			kind:            bascule.DeviceKind,
+			expectedErr:     ErrGeoBlocked,
+			expectedCountry: "KP",
+		},
+		{
+			description:     "Lookup Failed",
+			path:            "/things",
+			clientIP:        "10.0.0.9",
+			expectedErr:     ErrGeoLookupFailed,
+			expectedCountry: UnknownCountry,
+		},
+		{
+			description:     "No Country",
+			path:            "/things",
+			clientIP:        "10.0.0.4",
+			expectedErr:     ErrGeoLookupFailed,
+			expectedCountry: UnknownCountry,
+		},
+		{
+			description:     "No Client IP",
+			path:            "/things",
+			expectedErr:     ErrGeoLookupFailed,
+			expectedCountry: UnknownCountry,
+		},
+		{
+			description: "Fail Open",
+			config: &GeoConfig{
+				Default:  GeoPolicy{Allow: []string{"US"}},
+				FailOpen: true,
+			},
+			path:     "/things",
+			clientIP: "10.0.0.9",
+		},
+		{
+			description: "No Policy",
+			config:      &GeoConfig{},
+			path:        "/things",
+		},
+		{
+			description: "No Auth",
+			noAuth:      true,
+			expectedErr: ErrNoAuth,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			c := config
+			if tc.config != nil {
+				c = *tc.config
+			}
+			measures := &GeoMeasures{
+				Blocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testGeoBlocked",
+				}, []string{ServerLabel, CountryLabel}),
+			}
+			g, err := NewGeoValidator(testCountries, c, measures, "")
+			require.NoError(err)
+
+			attributes := map[string]interface{}{}
+			if tc.kind != "" {
+				attributes[bascule.TokenKindKey] = string(tc.kind)
+			}
+			token := bascule.NewToken("test", "alice", bascule.NewAttributes(attributes))
+			ctx := context.Background()
+			if !tc.noAuth {
+				u, err := url.Parse(tc.path)
+				require.NoError(err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   token,
+					Request: bascule.Request{URL: u, Method: "GET", ClientIP: tc.clientIP},
+				})
+			}
+			err = g.Check(ctx, token)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedCountry != "" {
+				assert.Equal(bascule.CapabilityClass, bascule.ClassOf(err))
+				assert.Equal(1.0, testutil.ToFloat64(measures.Blocked.With(prometheus.Labels{
+					ServerLabel:  defaultServer,
+					CountryLabel: tc.expectedCountry,
+				})))
+			}
+		})
+	}
+}
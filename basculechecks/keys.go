@@ -17,6 +17,13 @@
 
 package basculechecks
 
+import (
+	"fmt"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
 var (
 	capabilityKeys = []string{"capabilities"}
 	partnerKeys    = []string{"allowedResources", "allowedPartners"}
@@ -33,3 +40,44 @@ func CapabilityKeys() []string {
 func PartnerKeys() []string {
 	return partnerKeys
 }
+
+// FindPartnerIDs returns the partner IDs at the first of the key paths given
+// that is in the attributes, so tokens from issuers that put partners in
+// different claims, like allowedResources.allowedPartners, partner-ids, or
+// org, can all be read.  PartnerKeys() is used if no paths are given.  A
+// string claim holds partner IDs separated by whitespace.
+func FindPartnerIDs(attributes bascule.Attributes, keyPaths ...[]string) ([]string, error) {
+	if len(keyPaths) == 0 {
+		keyPaths = [][]string{PartnerKeys()}
+	}
+	if attributes != nil {
+		for _, keys := range keyPaths {
+			if len(keys) == 0 {
+				continue
+			}
+			val, ok := bascule.GetNestedAttribute(attributes, keys...)
+			if !ok {
+				continue
+			}
+			partners, err := cast.ToStringSliceE(val)
+			if err != nil {
+				return nil, fmt.Errorf("%w for partner IDs \"%v\" at %v: %v",
+					ErrPartnerIDsNotStringSlice, val, keys, err)
+			}
+			return partners, nil
+		}
+	}
+	if len(keyPaths) == 1 {
+		return nil, fmt.Errorf("%w using keys %v", ErrGettingPartnerIDs, keyPaths[0])
+	}
+	return nil, fmt.Errorf("%w using any of keys %v", ErrGettingPartnerIDs, keyPaths)
+}
+
+// partnerKeyPaths returns the primary key path followed by the fallbacks,
+// defaulting the primary to PartnerKeys().
+func partnerKeyPaths(primary []string, fallbacks [][]string) [][]string {
+	if len(primary) == 0 {
+		primary = PartnerKeys()
+	}
+	return append([][]string{primary}, fallbacks...)
+}
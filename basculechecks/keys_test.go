@@ -0,0 +1,101 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPartnerIDs(t *testing.T) {
+	attributes := bascule.NewAttributes(map[string]interface{}{
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []interface{}{"comcast", "sky"},
+		},
+		"partner-ids": []string{"other"},
+		"org":         "acme",
+		"bad":         map[string]interface{}{},
+	})
+	tests := []struct {
+		description      string
+		attributes       bascule.Attributes
+		keyPaths         [][]string
+		expectedPartners []string
+		expectedErr      error
+		expectedMsg      string
+	}{
+		{
+			description:      "Default",
+			attributes:       attributes,
+			expectedPartners: []string{"comcast", "sky"},
+		},
+		{
+			description:      "First found",
+			attributes:       attributes,
+			keyPaths:         [][]string{{"missing"}, {}, {"partner-ids"}, {"org"}},
+			expectedPartners: []string{"other"},
+		},
+		{
+			description:      "String claim",
+			attributes:       attributes,
+			keyPaths:         [][]string{{"org"}},
+			expectedPartners: []string{"acme"},
+		},
+		{
+			description: "Not a list",
+			attributes:  attributes,
+			keyPaths:    [][]string{{"bad"}, {"org"}},
+			expectedErr: ErrPartnerIDsNotStringSlice,
+			expectedMsg: "at [bad]",
+		},
+		{
+			description: "Missing",
+			attributes:  attributes,
+			keyPaths:    [][]string{{"missing"}},
+			expectedErr: ErrGettingPartnerIDs,
+			expectedMsg: "using keys [missing]",
+		},
+		{
+			description: "Missing from all",
+			attributes:  attributes,
+			keyPaths:    [][]string{{"missing"}, {"gone"}},
+			expectedErr: ErrGettingPartnerIDs,
+			expectedMsg: "using any of keys [[missing] [gone]]",
+		},
+		{
+			description: "Nil attributes",
+			expectedErr: ErrGettingPartnerIDs,
+			expectedMsg: "using keys [allowedResources allowedPartners]",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			partners, err := FindPartnerIDs(tc.attributes, tc.keyPaths...)
+			assert.Equal(tc.expectedPartners, partners)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Contains(err.Error(), tc.expectedMsg)
+		})
+	}
+}
@@ -17,7 +17,12 @@
 
 package basculechecks
 
-import "regexp"
+import (
+	"regexp"
+
+	"github.com/s-srakshe/bascule/basculemetrics"
+	"go.uber.org/zap"
+)
 
 const (
 	defaultServer = "primary"
@@ -34,6 +39,56 @@ func MonitorOnly() MetricOption {
 	}
 }
 
+// WithPartnerKeyPaths sets where the partner IDs are in the token's
+// attributes.  The key paths are searched in order, and the first one found
+// is used.  Defaults to PartnerKeys().
+func WithPartnerKeyPaths(keyPaths ...[]string) MetricOption {
+	return func(m *MetricValidator) {
+		paths := make([][]string, 0, len(keyPaths))
+		for _, k := range keyPaths {
+			if len(k) > 0 {
+				paths = append(paths, k)
+			}
+		}
+		if len(paths) > 0 {
+			m.partnerKeys = paths
+		}
+	}
+}
+
+// WithEnforcementPercentage returns the errors of only the percentage of
+// principals given, so enforcement can be ramped up gradually.  The failures
+// of the other principals are recorded as accepted, like with MonitorOnly.
+// Principals are chosen by hashing, so the same principals are enforced on
+// every request.  Percentages are clamped to between 0 and 100, and 100
+// enforces every request.
+func WithEnforcementPercentage(percent int) MetricOption {
+	return func(m *MetricValidator) {
+		switch {
+		case percent >= 100:
+			m.partial = false
+		case percent <= 0:
+			m.partial, m.percent = true, 0
+		default:
+			m.partial, m.percent = true, uint32(percent)
+		}
+	}
+}
+
+// WithEnforcementTarget returns the errors of only the requests chosen by
+// the target given, or of every request but those with an excluding target.
+// The failures of the other requests are recorded as accepted, like with
+// MonitorOnly.  With WithEnforcementPercentage, only that percentage of the
+// targeted principals is enforced.  A target listing nothing is ignored.
+func WithEnforcementTarget(t EnforcementTarget) MetricOption {
+	return func(m *MetricValidator) {
+		if len(t.Partners) == 0 && len(t.ClientIDs) == 0 {
+			return
+		}
+		m.target = newEnforcementTarget(t)
+	}
+}
+
 // WithServer provides the server name to be used in the metric label.
 func WithServer(s string) MetricOption {
 	return func(m *MetricValidator) {
@@ -54,18 +109,93 @@ func WithEndpoints(e []*regexp.Regexp) MetricOption {
 	}
 }
 
+// WithClientIDTransform sets a function that changes the principal before it
+// is used as the client ID metric label, such as HashClientID or
+// FirstNClientIDs.
+func WithClientIDTransform(t ClientIDTransform) MetricOption {
+	return func(m *MetricValidator) {
+		if t != nil {
+			m.clientID = t
+		}
+	}
+}
+
+// WithPartnerClassifier sets how the partner ID metric label is determined.
+// By default, DeterminePartnerMetric is used.
+func WithPartnerClassifier(pc PartnerClassifier) MetricOption {
+	return func(m *MetricValidator) {
+		if pc != nil {
+			m.partners = pc
+		}
+	}
+}
+
+// WithBufferedOutcomes sends outcome counter increments through the buffer
+// given rather than directly to the measures' counter.  The buffer should wrap
+// the same CounterVec as the measures, and its lifecycle is the caller's
+// responsibility.
+func WithBufferedOutcomes(b *basculemetrics.BufferedCounterVec) MetricOption {
+	return func(m *MetricValidator) {
+		if b != nil {
+			m.buffer = b
+		}
+	}
+}
+
+// WithDecisionSummary adds the failures the MetricValidator sees, including
+// ones only monitored, to the DecisionSummary given.  Its lifecycle is the
+// caller's responsibility.
+func WithDecisionSummary(s *DecisionSummary) MetricOption {
+	return func(m *MetricValidator) {
+		if s != nil {
+			m.sinks = append(m.sinks, s)
+		}
+	}
+}
+
+// WithOutcomeSink sends every outcome to the sink given as well as to the
+// Prometheus counter, such as to count them in StatsD or publish them to an
+// event bus.
+func WithOutcomeSink(s OutcomeSink) MetricOption {
+	return func(m *MetricValidator) {
+		if s != nil {
+			m.sinks = append(m.sinks, s)
+		}
+	}
+}
+
+// WithDefaultOutcomeSink sends outcomes to the sink given instead of the
+// Prometheus counter, which is then not needed.  Sinks added with
+// WithOutcomeSink still get every outcome.
+func WithDefaultOutcomeSink(s OutcomeSink) MetricOption {
+	return func(m *MetricValidator) {
+		if s != nil {
+			m.sink = s
+		}
+	}
+}
+
+// WithLogger sets the logger used to warn that the measures can't be used
+// and, in that case, to log failures.  Defaults to sallust.Default().
+func WithLogger(l *zap.Logger) MetricOption {
+	return func(m *MetricValidator) {
+		if l != nil {
+			m.logger = l
+		}
+	}
+}
+
 // NewMetricValidator creates a MetricValidator given a CapabilitiesChecker,
-// measures, and options to configure it.  The checker and measures cannot be
-// nil.
+// measures, and options to configure it.  The checker cannot be nil.  If the
+// measures are nil, or their counter is missing or doesn't have the expected
+// labels, a warning is logged and the MetricValidator logs its failures
+// rather than counting them, instead of panicking on the first request.  The
+// measures aren't used with WithDefaultOutcomeSink.
 func NewMetricValidator(checker CapabilitiesChecker, measures *AuthCapabilityCheckMeasures, options ...MetricOption) (*MetricValidator, error) {
 	if checker == nil {
 		return nil, ErrNilChecker
 	}
 
-	if measures == nil {
-		return nil, ErrNilMeasures
-	}
-
 	m := MetricValidator{
 		c:        checker,
 		measures: measures,
@@ -78,5 +208,16 @@ func NewMetricValidator(checker CapabilitiesChecker, measures *AuthCapabilityChe
 			o(&m)
 		}
 	}
+
+	if m.sink != nil {
+		return &m, nil
+	}
+	if err := validateMeasures(measures); err != nil {
+		m.log().Warn("capability check metrics disabled, logging failures instead", zap.Error(err))
+		m.measures = NopAuthCapabilityCheckMeasures()
+		m.buffer = nil
+		m.sink = LoggingOutcomeSink(m.log())
+		m.degraded = true
+	}
 	return &m, nil
 }
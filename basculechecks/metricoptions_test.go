@@ -18,17 +18,28 @@
 package basculechecks
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculemetrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestNewMetricValidator(t *testing.T) {
 	c := &CapabilitiesValidator{}
-	m := &AuthCapabilityCheckMeasures{}
+	m := NopAuthCapabilityCheckMeasures()
 	e := []*regexp.Regexp{regexp.MustCompile(".*")}
 	s := "testserverrr"
 	tests := []struct {
@@ -74,11 +85,6 @@ func TestNewMetricValidator(t *testing.T) {
 			measures:    m,
 			expectedErr: ErrNilChecker,
 		},
-		{
-			description: "Nil Measures Error",
-			checker:     c,
-			expectedErr: ErrNilMeasures,
-		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
@@ -92,3 +98,134 @@ func TestNewMetricValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestWithBufferedOutcomes(t *testing.T) {
+	b, err := basculemetrics.NewBufferedCounterVec(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "testCounter",
+		Help: "testCounter",
+	}, []string{ServerLabel}))
+	require.NoError(t, err)
+	m, err := NewMetricValidator(&CapabilitiesValidator{}, NopAuthCapabilityCheckMeasures(),
+		WithBufferedOutcomes(b), WithBufferedOutcomes(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, b, m.buffer)
+}
+
+func TestWithEnforcementPercentage(t *testing.T) {
+	tests := []struct {
+		description     string
+		percent         int
+		expectedPartial bool
+		expectedPercent uint32
+	}{
+		{description: "Partial", percent: 40, expectedPartial: true, expectedPercent: 40},
+		{description: "Zero", percent: 0, expectedPartial: true},
+		{description: "Negative", percent: -5, expectedPartial: true},
+		{description: "Full", percent: 100},
+		{description: "Over", percent: 150},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := NewMetricValidator(&CapabilitiesValidator{}, &AuthCapabilityCheckMeasures{},
+				WithEnforcementPercentage(tc.percent))
+			assert.NoError(err)
+			assert.Equal(tc.expectedPartial, m.partial)
+			assert.Equal(tc.expectedPercent, m.percent)
+		})
+	}
+}
+
+func TestWithPartnerKeyPaths(t *testing.T) {
+	assert := assert.New(t)
+	m, err := NewMetricValidator(&CapabilitiesValidator{}, &AuthCapabilityCheckMeasures{},
+		WithPartnerKeyPaths(), WithPartnerKeyPaths(nil, []string{"partner-ids"}, []string{"org"}))
+	assert.NoError(err)
+	assert.Equal([][]string{{"partner-ids"}, {"org"}}, m.partnerKeys)
+
+	v, err := m.prepMetrics(bascule.Authentication{
+		Token: bascule.NewToken("test", "alice", bascule.NewAttributes(map[string]interface{}{
+			"org": "comcast",
+		})),
+		Request: bascule.Request{
+			URL:    &url.URL{Path: "/"},
+			Method: "GET",
+		},
+	})
+	assert.NoError(err)
+	assert.Equal("comcast", v.partnerID)
+}
+
+func TestNewMetricValidatorDegraded(t *testing.T) {
+	tests := []struct {
+		description string
+		measures    *AuthCapabilityCheckMeasures
+		expectedErr error
+	}{
+		{
+			description: "Nil measures",
+			expectedErr: ErrNilMeasures,
+		},
+		{
+			description: "Nil counter",
+			measures:    &AuthCapabilityCheckMeasures{},
+			expectedErr: ErrNilMeasures,
+		},
+		{
+			description: "Label mismatch",
+			measures: &AuthCapabilityCheckMeasures{
+				CapabilityCheckOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testCounter",
+				}, []string{ServerLabel, OutcomeLabel}),
+			},
+			expectedErr: ErrMeasuresMismatch,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			core, logs := observer.New(zapcore.InfoLevel)
+			b, err := basculemetrics.NewBufferedCounterVec(NopAuthCapabilityCheckMeasures().CapabilityCheckOutcome)
+			require.NoError(err)
+			checker := new(mockCapabilitiesChecker)
+			checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(errWithReason{
+				err:    errors.New("check test error"),
+				reason: NoCapabilitiesMatch,
+			})
+			m, err := NewMetricValidator(checker, tc.measures, WithLogger(zap.New(core)), WithBufferedOutcomes(b))
+			require.NoError(err)
+			assert.True(m.degraded)
+			assert.Nil(m.buffer)
+			require.Equal(1, logs.Len())
+			assert.Equal(zapcore.WarnLevel, logs.All()[0].Level)
+			assert.Contains(logs.All()[0].ContextMap()["error"], tc.expectedErr.Error())
+
+			err = m.Check(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Token: bascule.NewToken("test", "alice", bascule.NewAttributes(map[string]interface{}{
+					"allowedResources": map[string]interface{}{
+						"allowedPartners": []string{"comcast"},
+					},
+				})),
+				Request: bascule.Request{
+					URL:    &url.URL{Path: "/"},
+					Method: "GET",
+				},
+			}), nil)
+			assert.Equal(bascule.CapabilityClass, bascule.ClassOf(err))
+			require.Equal(2, logs.Len())
+			entry := logs.All()[1]
+			assert.Equal("capability check failed", entry.Message)
+			assert.Equal(RejectedOutcome, entry.ContextMap()["outcome"])
+			assert.Equal(NoCapabilitiesMatch, entry.ContextMap()["reason"])
+			assert.Equal("alice", entry.ContextMap()["clientID"])
+		})
+	}
+}
+
+func TestValidateMeasures(t *testing.T) {
+	assert := assert.New(t)
+	measures := NopAuthCapabilityCheckMeasures()
+	assert.NoError(validateMeasures(measures))
+	assert.Zero(testutil.CollectAndCount(measures.CapabilityCheckOutcome))
+}
@@ -18,6 +18,8 @@
 package basculechecks
 
 import (
+	"fmt"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/xmidt-org/touchstone"
 	"go.uber.org/fx"
@@ -26,6 +28,14 @@ import (
 // Names for our metrics
 const (
 	AuthCapabilityCheckOutcome = "auth_capability_check"
+	AuthCapabilityCacheOutcome = "auth_capability_cache"
+	AuthConcurrencyRejections  = "auth_concurrency_limit_rejections"
+	AuthBindingAnomalies       = "auth_token_binding_anomalies"
+	AuthGeoBlocked             = "auth_geo_blocked"
+	AuthBreakGlassUses         = "auth_break_glass_uses"
+	AuthDeprecatedUsage        = "auth_deprecated_capability_usage"
+	AuthIPReputation           = "auth_ip_reputation"
+	AuthDecisionSummary        = "auth_decision_summary"
 )
 
 // labels
@@ -37,6 +47,14 @@ const (
 	MethodLabel    = "method"
 	PartnerIDLabel = "partnerid"
 	ServerLabel    = "server"
+
+	CacheResultLabel = "result"
+	BindingLabel     = "binding"
+	CountryLabel     = "country"
+	DeprecationLabel = "deprecation"
+	VerdictLabel     = "verdict"
+	DimensionLabel   = "dimension"
+	KeyLabel         = "key"
 )
 
 // label values
@@ -54,6 +72,18 @@ const (
 	NoEndpointChecker        = "no_capability_checker"
 	NoCapabilitiesMatch      = "no_capabilities_match"
 	EmptyParsedURL           = "empty_parsed_URL"
+	PartnerNotAllowed        = "partner_not_allowed"
+	TooManyInFlight          = "too_many_in_flight"
+	QuotaExceeded            = "quota_exceeded"
+	BindingMismatch          = "binding_mismatch"
+	GeoBlocked               = "geo_blocked"
+	GeoLookupFailed          = "geo_lookup_failed"
+	ScheduleDenied           = "schedule_denied"
+	InsufficientACR          = "insufficient_acr"
+	MissingAMR               = "missing_amr"
+	ScopeNotApproved         = "scope_not_approved"
+	BadIPReputation          = "bad_ip_reputation"
+	IPReputationFailed       = "ip_reputation_failed"
 	// partners
 	NonePartner     = "none"
 	WildcardPartner = "wildcard"
@@ -61,11 +91,22 @@ const (
 	// endpoints
 	NoneEndpoint          = "no_endpoints"
 	NotRecognizedEndpoint = "not_recognized"
+	// cache results
+	CacheHit  = "hit"
+	CacheMiss = "miss"
 )
 
 // help messages
 const (
 	capabilityCheckHelpMsg = "Counter for the capability checker, providing outcome information by client, partner, and endpoint"
+	capabilityCacheHelpMsg = "Counter for the capability decision cache, providing hit and miss information"
+	concurrencyHelpMsg     = "Counter for requests rejected by the concurrency limiter, by client"
+	bindingHelpMsg         = "Counter for tokens used from a different client than they were issued to, by client and binding"
+	geoBlockedHelpMsg      = "Counter for requests rejected by the geo validator, by country"
+	breakGlassHelpMsg      = "Counter for requests allowed by break-glass emergency access, by client and endpoint"
+	deprecationHelpMsg     = "Counter for tokens using deprecated capability formats or claim locations, by client and deprecation"
+	ipReputationHelpMsg    = "Counter for requests from flagged or blocked addresses, by verdict"
+	decisionSummaryHelpMsg = "Gauge of the failures of the top principals, endpoints, and reasons over the last summary interval"
 )
 
 // ProvideMetrics provides the metrics relevant to this package as uber/fx
@@ -82,9 +123,200 @@ func ProvideMetrics() fx.Option {
 }
 
 // AuthCapabilityCheckMeasures describes the defined metrics that will be used
-// by clients.
+// by clients.  The counter is optional so a partially wired application still
+// starts, with the MetricValidator logging its failures instead.
 type AuthCapabilityCheckMeasures struct {
 	fx.In
 
-	CapabilityCheckOutcome *prometheus.CounterVec `name:"auth_capability_check"`
+	CapabilityCheckOutcome *prometheus.CounterVec `name:"auth_capability_check" optional:"true"`
+}
+
+// NopAuthCapabilityCheckMeasures returns measures whose counter isn't
+// registered anywhere, for uses of the MetricValidator that don't need its
+// metric.
+func NopAuthCapabilityCheckMeasures() *AuthCapabilityCheckMeasures {
+	return &AuthCapabilityCheckMeasures{
+		CapabilityCheckOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: AuthCapabilityCheckOutcome,
+			Help: capabilityCheckHelpMsg,
+		}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+			PartnerIDLabel, EndpointLabel, MethodLabel}),
+	}
+}
+
+// validateMeasures checks that the measures have a counter with the labels
+// the MetricValidator uses, so a mismatch is found at startup instead of
+// panicking on a request.  The series created to check is deleted.
+func validateMeasures(measures *AuthCapabilityCheckMeasures) error {
+	if measures == nil || measures.CapabilityCheckOutcome == nil {
+		return ErrNilMeasures
+	}
+	labels := prometheus.Labels{
+		ServerLabel:    "\x00validate",
+		OutcomeLabel:   "",
+		ReasonLabel:    "",
+		ClientIDLabel:  "",
+		PartnerIDLabel: "",
+		EndpointLabel:  "",
+		MethodLabel:    "",
+	}
+	if _, err := measures.CapabilityCheckOutcome.GetMetricWith(labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrMeasuresMismatch, err)
+	}
+	measures.CapabilityCheckOutcome.Delete(labels)
+	return nil
+}
+
+// ProvideDecisionCacheMetrics provides the metrics used by the DecisionCache
+// as uber/fx options.
+func ProvideDecisionCacheMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthCapabilityCacheOutcome,
+			Help:        capabilityCacheHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, CacheResultLabel),
+	)
+}
+
+// DecisionCacheMeasures describes the metrics used by the DecisionCache.
+type DecisionCacheMeasures struct {
+	fx.In
+
+	CacheOutcome *prometheus.CounterVec `name:"auth_capability_cache"`
+}
+
+// ProvideConcurrencyLimitMetrics provides the metrics used by the
+// ConcurrencyLimiter as uber/fx options.
+func ProvideConcurrencyLimitMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthConcurrencyRejections,
+			Help:        concurrencyHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, ClientIDLabel),
+	)
+}
+
+// ConcurrencyLimitMeasures describes the metrics used by the
+// ConcurrencyLimiter.
+type ConcurrencyLimitMeasures struct {
+	fx.In
+
+	Rejections *prometheus.CounterVec `name:"auth_concurrency_limit_rejections"`
+}
+
+// ProvideBindingMetrics provides the metrics used by the BindingValidator as
+// uber/fx options.
+func ProvideBindingMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthBindingAnomalies,
+			Help:        bindingHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, ClientIDLabel, BindingLabel),
+	)
+}
+
+// BindingMeasures describes the metrics used by the BindingValidator.
+type BindingMeasures struct {
+	fx.In
+
+	Anomalies *prometheus.CounterVec `name:"auth_token_binding_anomalies"`
+}
+
+// ProvideGeoMetrics provides the metrics used by the GeoValidator as uber/fx
+// options.
+func ProvideGeoMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthGeoBlocked,
+			Help:        geoBlockedHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, CountryLabel),
+	)
+}
+
+// GeoMeasures describes the metrics used by the GeoValidator.
+type GeoMeasures struct {
+	fx.In
+
+	Blocked *prometheus.CounterVec `name:"auth_geo_blocked"`
+}
+
+// ProvideBreakGlassMetrics provides the metrics used by the BreakGlassChecker
+// as uber/fx options.
+func ProvideBreakGlassMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthBreakGlassUses,
+			Help:        breakGlassHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, ClientIDLabel, EndpointLabel, MethodLabel),
+	)
+}
+
+// BreakGlassMeasures describes the metrics used by the BreakGlassChecker.
+type BreakGlassMeasures struct {
+	fx.In
+
+	Uses *prometheus.CounterVec `name:"auth_break_glass_uses"`
+}
+
+// ProvideDeprecationMetrics provides the metrics used by the
+// DeprecationDetector as uber/fx options.
+func ProvideDeprecationMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthDeprecatedUsage,
+			Help:        deprecationHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, ClientIDLabel, DeprecationLabel),
+	)
+}
+
+// DeprecationMeasures describes the metrics used by the DeprecationDetector.
+type DeprecationMeasures struct {
+	fx.In
+
+	Usage *prometheus.CounterVec `name:"auth_deprecated_capability_usage"`
+}
+
+// ProvideIPReputationMetrics provides the metrics used by the
+// IPReputationValidator as uber/fx options.
+func ProvideIPReputationMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthIPReputation,
+			Help:        ipReputationHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, VerdictLabel),
+	)
+}
+
+// IPReputationMeasures describes the metrics used by the
+// IPReputationValidator.
+type IPReputationMeasures struct {
+	fx.In
+
+	Verdicts *prometheus.CounterVec `name:"auth_ip_reputation"`
+}
+
+// ProvideDecisionSummaryMetrics provides the metrics used by the
+// DecisionSummary as uber/fx options.
+func ProvideDecisionSummaryMetrics() fx.Option {
+	return fx.Options(
+		touchstone.GaugeVec(prometheus.GaugeOpts{
+			Name:        AuthDecisionSummary,
+			Help:        decisionSummaryHelpMsg,
+			ConstLabels: nil,
+		}, ServerLabel, DimensionLabel, KeyLabel),
+	)
+}
+
+// DecisionSummaryMeasures describes the metrics used by the DecisionSummary.
+type DecisionSummaryMeasures struct {
+	fx.In
+
+	Summary *prometheus.GaugeVec `name:"auth_decision_summary"`
 }
@@ -21,17 +21,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"regexp"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/spf13/cast"
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculemetrics"
+	"github.com/xmidt-org/sallust"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
 var (
 	ErrNilChecker        = errors.New("capabilities checker cannot be nil")
 	ErrNilMeasures       = errors.New("measures cannot be nil")
+	ErrMeasuresMismatch  = errors.New("measures don't have the expected labels")
 	ErrGettingPartnerIDs = errWithReason{
 		err:    errors.New("couldn't get partner IDs from attributes"),
 		reason: UndeterminedPartnerID,
@@ -44,7 +47,8 @@ var (
 
 // CapabilitiesChecker is an object that can determine if a request is
 // authorized given a bascule.Authentication object.  If it's not authorized, an
-//  error is given for logging and metrics.
+//
+//	error is given for logging and metrics.
 type CapabilitiesChecker interface {
 	CheckAuthentication(auth bascule.Authentication, vals ParsedValues) error
 }
@@ -65,6 +69,10 @@ type ParsedValues struct {
 	// most likely won't include values that change from one request to the next
 	// (ie, device ID).
 	Endpoint string
+
+	// Values holds anything added by ParsedValuesEnrichers, such as a resolved
+	// account ID or the request's region.
+	Values map[string]interface{}
 }
 
 type metricValues struct {
@@ -81,16 +89,29 @@ type MetricValidatorIn struct {
 	Checker  CapabilitiesChecker
 	Measures AuthCapabilityCheckMeasures
 	Options  []MetricOption `group:"bascule_capability_options"`
+	Logger   *zap.Logger    `optional:"true"`
 }
 
 // MetricValidator determines if a request is authorized and then updates a
 // metric to show those results.
 type MetricValidator struct {
-	c         CapabilitiesChecker
-	measures  *AuthCapabilityCheckMeasures
-	endpoints []*regexp.Regexp
-	errorOut  bool
-	server    string
+	c           CapabilitiesChecker
+	measures    *AuthCapabilityCheckMeasures
+	endpoints   []*regexp.Regexp
+	errorOut    bool
+	partial     bool
+	percent     uint32
+	target      *enforcementTarget
+	server      string
+	clientID    ClientIDTransform
+	partners    PartnerClassifier
+	partnerKeys [][]string
+	buffer      *basculemetrics.BufferedCounterVec
+	sink        OutcomeSink
+	sinks       []OutcomeSink
+	logger      *zap.Logger
+	degraded    bool
+	enrichers   []ParsedValuesEnricher
 }
 
 // Check is a function for authorization middleware.  The function parses the
@@ -98,61 +119,74 @@ type MetricValidator struct {
 // request is authorized, and maintains the results in a metric.  The function
 // can mark the request as unauthorized or only update the metric and allow the
 // request, depending on configuration.  This allows for monitoring before being
-// more strict with authorization.
+// more strict with authorization.  With an enforcement target or percentage,
+// only the failures of the principals and partners chosen are returned.
 func (m MetricValidator) Check(ctx context.Context, _ bascule.Token) error {
 	auth, ok := bascule.FromContext(ctx)
 	if !ok {
-		m.measures.CapabilityCheckOutcome.With(prometheus.Labels{
-			ServerLabel:    m.server,
-			OutcomeLabel:   m.failureOutcome(),
-			ReasonLabel:    TokenMissing,
-			ClientIDLabel:  "",
-			PartnerIDLabel: "",
-			EndpointLabel:  "",
-			MethodLabel:    "",
-		}).Add(1)
-		return m.errReturn(ErrNoAuth)
+		enforced := m.enforced(bascule.Authentication{})
+		m.record(Outcome{
+			Server:  m.server,
+			Outcome: m.failureOutcome(enforced),
+			Reason:  TokenMissing,
+		})
+		return m.errReturn(ErrNoAuth, enforced)
 	}
 
+	enforced := m.enforced(auth)
 	l, err := m.prepMetrics(auth)
-	labels := prometheus.Labels{
-		ServerLabel:    m.server,
-		ClientIDLabel:  l.client,
-		PartnerIDLabel: l.partnerID,
-		EndpointLabel:  l.endpoint,
-		MethodLabel:    l.method,
-		OutcomeLabel:   AcceptedOutcome,
-		ReasonLabel:    "",
+	o := Outcome{
+		Server:    m.server,
+		Outcome:   AcceptedOutcome,
+		ClientID:  l.client,
+		PartnerID: l.partnerID,
+		Endpoint:  l.endpoint,
+		Method:    l.method,
 	}
 	if err != nil {
-		labels[OutcomeLabel] = m.failureOutcome()
-		labels[ReasonLabel] = UnknownReason
+		o.Outcome = m.failureOutcome(enforced)
+		o.Reason = UnknownReason
 		var r Reasoner
 		if errors.As(err, &r) {
-			labels[ReasonLabel] = r.Reason()
+			o.Reason = r.Reason()
 		}
-		m.measures.CapabilityCheckOutcome.With(labels).Add(1)
-		return m.errReturn(err)
+		m.record(o)
+		return m.errReturn(err, enforced)
+	}
+
+	if d, ok := bascule.DecisionFromContext(ctx); ok {
+		d.SetBuckets(l.partnerID, l.endpoint)
 	}
 
 	v := ParsedValues{
 		Endpoint: l.endpoint,
 	}
+	v, err = m.enrich(ctx, auth, v)
+	if err != nil {
+		o.Outcome = m.failureOutcome(enforced)
+		o.Reason = UnknownReason
+		var r Reasoner
+		if errors.As(err, &r) {
+			o.Reason = r.Reason()
+		}
+		m.record(o)
+		return m.errReturn(err, enforced)
+	}
 
-	err = m.c.CheckAuthentication(auth, v)
+	err = CheckWithContext(ctx, m.c, auth, v)
 	if err != nil {
-		labels[OutcomeLabel] = m.failureOutcome()
-		labels[ReasonLabel] = UnknownReason
+		o.Outcome = m.failureOutcome(enforced)
+		o.Reason = UnknownReason
 		var r Reasoner
 		if errors.As(err, &r) {
-			labels[ReasonLabel] = r.Reason()
+			o.Reason = r.Reason()
 		}
-		m.measures.CapabilityCheckOutcome.With(labels).Add(1)
-		return m.errReturn(fmt.Errorf("endpoint auth for %v on %v failed: %v",
-			auth.Request.Method, auth.Request.URL.EscapedPath(), err))
+		m.record(o)
+		return m.errReturn(fmt.Errorf("endpoint auth for %v on %v failed: %w",
+			auth.Request.Method, auth.Request.URL.EscapedPath(), err), enforced)
 	}
 
-	m.measures.CapabilityCheckOutcome.With(labels).Add(1)
+	m.record(o)
 	return nil
 }
 
@@ -160,52 +194,90 @@ func (m MetricValidator) Check(ctx context.Context, _ bascule.Token) error {
 // gathers the client ID, partnerID, and endpoint (bucketed) for more information
 // on the metric when a request is unauthorized.
 func (m MetricValidator) prepMetrics(auth bascule.Authentication) (metricValues, error) {
-	v := metricValues{}
-	if auth.Token == nil {
-		return v, ErrNoToken
+	info, err := m.requestParser().Parse(auth)
+	return metricValues{
+		method:    info.Method,
+		endpoint:  info.Endpoint,
+		partnerID: info.Partner,
+		client:    info.ClientID,
+	}, err
+}
+
+// requestParser returns the RequestParser for the MetricValidator's
+// configuration.
+func (m MetricValidator) requestParser() RequestParser {
+	p := RequestParser{
+		Endpoints: RegexEndpointBucketer(m.endpoints),
+		ClientID:  m.clientID,
+		Partners:  m.partners,
 	}
-	v.client = auth.Token.Principal()
-	if len(auth.Request.Method) == 0 {
-		return v, ErrNoMethod
+	if len(m.partnerKeys) > 0 {
+		p.PartnerKeyPath = m.partnerKeys[0]
+		p.FallbackPartnerKeyPaths = m.partnerKeys[1:]
 	}
-	v.method = auth.Request.Method
-	if auth.Token.Attributes() == nil {
-		return v, ErrNilAttributes
+	return p
+}
+
+// record sends the outcome to the MetricValidator's sinks.  The default is
+// the Prometheus counter, going through the buffer if one is configured.
+func (m MetricValidator) record(o Outcome) {
+	switch {
+	case m.sink != nil:
+		m.sink.RecordOutcome(o)
+	case m.buffer != nil:
+		m.buffer.Inc(o.Labels())
+	default:
+		m.measures.CapabilityCheckOutcome.With(o.Labels()).Inc()
+	}
+	for _, s := range m.sinks {
+		s.RecordOutcome(o)
 	}
+}
 
-	partnerVal, ok := bascule.GetNestedAttribute(auth.Token.Attributes(), PartnerKeys()...)
-	if !ok {
-		err := fmt.Errorf("%w using keys %v", ErrGettingPartnerIDs, PartnerKeys())
-		return v, err
+// enforced returns true if failures for the Authentication given are
+// returned rather than only recorded.  It must match the enforcement target,
+// if there is one, and then the enforcement percentage.  Principals are
+// hashed into one of 100 buckets, so the same principals stay enforced as the
+// percentage is raised.  Without a principal, failures are only enforced at
+// 100 percent.
+func (m MetricValidator) enforced(auth bascule.Authentication) bool {
+	var principal string
+	if auth.Token != nil {
+		principal = auth.Token.Principal()
 	}
-	partnerIDs, err := cast.ToStringSliceE(partnerVal)
-	if err != nil {
-		err = fmt.Errorf("%w for partner IDs \"%v\": %v",
-			ErrPartnerIDsNotStringSlice, partnerVal, err)
-		return v, err
+	if m.target != nil && !m.target.matches(principal, auth.Token) {
+		return false
+	}
+	if !m.partial {
+		return true
 	}
-	v.partnerID = DeterminePartnerMetric(partnerIDs)
+	if len(principal) == 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(principal))
+	return h.Sum32()%100 < m.percent
+}
 
-	if auth.Request.URL == nil {
-		return v, ErrNoURL
+func (m MetricValidator) log() *zap.Logger {
+	if m.logger != nil {
+		return m.logger
 	}
-	escapedURL := auth.Request.URL.EscapedPath()
-	v.endpoint = determineEndpointMetric(m.endpoints, escapedURL)
-	return v, nil
+	return sallust.Default()
 }
 
-func (m MetricValidator) failureOutcome() string {
+func (m MetricValidator) failureOutcome(enforced bool) string {
 	// if we actually error out, the outcome is the request being rejected
-	if m.errorOut {
+	if m.errorOut && enforced {
 		return RejectedOutcome
 	}
 	// if we're not supposed to error out, the outcome should be accepted on failure
 	return AcceptedOutcome
 }
 
-func (m MetricValidator) errReturn(err error) error {
+func (m MetricValidator) errReturn(err error, enforced bool) error {
 	// if we actually error out, the error should be returned.
-	if m.errorOut {
+	if m.errorOut && enforced {
 		return err
 	}
 	// if we're not supposed to error out, the error is suppressed.
@@ -26,10 +26,11 @@ import (
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
-	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/touchstone/touchtest"
 )
 
@@ -218,10 +219,19 @@ func TestMetricValidatorCheck(t *testing.T) {
 				errorOut: tc.errorOut,
 				server:   "testserver",
 			}
-			err := m.Check(ctx, nil)
+			decision := bascule.NewDecision()
+			err := m.Check(bascule.WithDecision(ctx, decision), nil)
 			mockCapabilitiesChecker.AssertExpectations(t)
+			if tc.checkCallExpected {
+				assert.Equal(tc.expectedLabels[PartnerIDLabel], decision.Partner())
+				assert.Equal(NoneEndpoint, decision.Endpoint())
+			}
 			if tc.errExpected {
 				assert.NotNil(err)
+				if tc.checkErr != nil {
+					assert.ErrorIs(err, tc.checkErr)
+					assert.Equal(bascule.CapabilityClass, bascule.ClassOf(err))
+				}
 				return
 			}
 			assert.Nil(err)
@@ -426,3 +436,77 @@ func TestPrepMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricValidatorEnforcementPercentage(t *testing.T) {
+	goodMap := buildDummyAttributes(CapabilityKeys(), []string{"a"})
+	goodMap["allowedResources"] = map[string]interface{}{
+		"allowedPartners": []string{"meh"},
+	}
+	checkErr := errWithReason{
+		err:    errors.New("check test error"),
+		reason: NoCapabilitiesMatch,
+	}
+	check := func(m *MetricValidator, principal string) error {
+		ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+			Token: bascule.NewToken("test", principal, bascule.NewAttributes(goodMap)),
+			Request: bascule.Request{
+				URL:    &url.URL{Path: "/test"},
+				Method: "GET",
+			},
+		})
+		return m.Check(ctx, nil)
+	}
+	newValidator := func(percent int) (*MetricValidator, *prometheus.CounterVec) {
+		checker := new(mockCapabilitiesChecker)
+		checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(checkErr)
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testCounter",
+		}, []string{ServerLabel, OutcomeLabel, ReasonLabel, ClientIDLabel,
+			PartnerIDLabel, EndpointLabel, MethodLabel})
+		m, err := NewMetricValidator(checker, &AuthCapabilityCheckMeasures{CapabilityCheckOutcome: counter},
+			WithEnforcementPercentage(percent))
+		require.NoError(t, err)
+		return m, counter
+	}
+
+	assert := assert.New(t)
+	principals := make([]string, 1000)
+	for i := range principals {
+		principals[i] = fmt.Sprintf("client-%d", i)
+	}
+	enforcedAt := func(percent int) map[string]bool {
+		m, counter := newValidator(percent)
+		enforced := make(map[string]bool)
+		for _, p := range principals {
+			err := check(m, p)
+			assert.Equal(err, check(m, p), "enforcement should be deterministic")
+			if err != nil {
+				assert.ErrorIs(err, checkErr)
+				enforced[p] = true
+				continue
+			}
+			assert.Equal(float64(2), testutil.ToFloat64(counter.With(prometheus.Labels{
+				ServerLabel:    defaultServer,
+				OutcomeLabel:   AcceptedOutcome,
+				ReasonLabel:    NoCapabilitiesMatch,
+				ClientIDLabel:  p,
+				PartnerIDLabel: "meh",
+				EndpointLabel:  NoneEndpoint,
+				MethodLabel:    "GET",
+			})))
+		}
+		return enforced
+	}
+
+	assert.Empty(enforcedAt(0))
+	assert.Len(enforcedAt(100), len(principals))
+	low, high := enforcedAt(10), enforcedAt(50)
+	assert.InDelta(100, len(low), 40)
+	assert.InDelta(500, len(high), 80)
+	for p := range low {
+		assert.True(high[p], "principals enforced at a lower percentage stay enforced")
+	}
+
+	m, _ := newValidator(50)
+	assert.NoError(m.Check(context.Background(), nil))
+}
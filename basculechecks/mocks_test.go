@@ -18,8 +18,8 @@
 package basculechecks
 
 import (
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/mock"
-	"github.com/xmidt-org/bascule"
 )
 
 type mockCapabilitiesChecker struct {
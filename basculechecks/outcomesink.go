@@ -0,0 +1,107 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule/basculemetrics"
+	"go.uber.org/zap"
+)
+
+// Outcome is the result of a MetricValidator's check of a request.  Its fields
+// hold the values of the auth_capability_check metric's labels.
+type Outcome struct {
+	Server string
+
+	// Outcome is AcceptedOutcome or RejectedOutcome.  Failures only
+	// monitored are accepted.
+	Outcome string
+
+	// Reason is why the check failed, and is empty for successes.
+	Reason string
+
+	ClientID  string
+	PartnerID string
+	Endpoint  string
+	Method    string
+}
+
+// Labels returns the outcome as the labels of the auth_capability_check
+// metric.
+func (o Outcome) Labels() prometheus.Labels {
+	return prometheus.Labels{
+		ServerLabel:    o.Server,
+		OutcomeLabel:   o.Outcome,
+		ReasonLabel:    o.Reason,
+		ClientIDLabel:  o.ClientID,
+		PartnerIDLabel: o.PartnerID,
+		EndpointLabel:  o.Endpoint,
+		MethodLabel:    o.Method,
+	}
+}
+
+// OutcomeSink records the outcomes of a MetricValidator, such as by counting
+// them in Prometheus or StatsD or by publishing them to an event bus.  It's
+// called on the request's goroutine, so sinks that do I/O should hand off
+// their work.
+type OutcomeSink interface {
+	RecordOutcome(Outcome)
+}
+
+// OutcomeSinkFunc makes it so any function with the same signature as
+// OutcomeSink's RecordOutcome function implements OutcomeSink.
+type OutcomeSinkFunc func(Outcome)
+
+func (osf OutcomeSinkFunc) RecordOutcome(o Outcome) {
+	osf(o)
+}
+
+// PrometheusOutcomeSink returns the OutcomeSink the MetricValidator uses by
+// default, which increments the CounterVec given.  The CounterVec must have
+// the auth_capability_check metric's labels.
+func PrometheusOutcomeSink(vec *prometheus.CounterVec) OutcomeSink {
+	return OutcomeSinkFunc(func(o Outcome) {
+		vec.With(o.Labels()).Inc()
+	})
+}
+
+// BufferedOutcomeSink returns an OutcomeSink that increments the
+// BufferedCounterVec given.
+func BufferedOutcomeSink(b *basculemetrics.BufferedCounterVec) OutcomeSink {
+	return OutcomeSinkFunc(func(o Outcome) {
+		b.Inc(o.Labels())
+	})
+}
+
+// LoggingOutcomeSink returns an OutcomeSink that logs failures at the info
+// level.  Successes aren't logged.
+func LoggingOutcomeSink(logger *zap.Logger) OutcomeSink {
+	return OutcomeSinkFunc(func(o Outcome) {
+		if len(o.Reason) == 0 {
+			return
+		}
+		logger.Info("capability check failed",
+			zap.String("outcome", o.Outcome),
+			zap.String("reason", o.Reason),
+			zap.String("clientID", o.ClientID),
+			zap.String("partnerID", o.PartnerID),
+			zap.String("endpoint", o.Endpoint),
+			zap.String("method", o.Method),
+		)
+	})
+}
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculemetrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type outcomeRecorder struct {
+	outcomes []Outcome
+}
+
+func (r *outcomeRecorder) RecordOutcome(o Outcome) {
+	r.outcomes = append(r.outcomes, o)
+}
+
+func TestOutcomeSinks(t *testing.T) {
+	assert := assert.New(t)
+	failure := Outcome{
+		Server:    "api",
+		Outcome:   RejectedOutcome,
+		Reason:    NoCapabilitiesMatch,
+		ClientID:  "alice",
+		PartnerID: "comcast",
+		Endpoint:  NoneEndpoint,
+		Method:    "GET",
+	}
+	success := failure
+	success.Outcome, success.Reason = AcceptedOutcome, ""
+
+	vec := NopAuthCapabilityCheckMeasures().CapabilityCheckOutcome
+	PrometheusOutcomeSink(vec).RecordOutcome(failure)
+	assert.Equal(float64(1), testutil.ToFloat64(vec.With(failure.Labels())))
+
+	b, err := basculemetrics.NewBufferedCounterVec(vec)
+	require.NoError(t, err)
+	BufferedOutcomeSink(b).RecordOutcome(failure)
+	b.Flush()
+	assert.Equal(float64(2), testutil.ToFloat64(vec.With(failure.Labels())))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	s := LoggingOutcomeSink(zap.New(core))
+	s.RecordOutcome(success)
+	s.RecordOutcome(failure)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(map[string]interface{}{
+		"outcome":   RejectedOutcome,
+		"reason":    NoCapabilitiesMatch,
+		"clientID":  "alice",
+		"partnerID": "comcast",
+		"endpoint":  NoneEndpoint,
+		"method":    "GET",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestMetricValidatorOutcomeSinks(t *testing.T) {
+	checker := new(mockCapabilitiesChecker)
+	checker.On("CheckAuthentication", mock.Anything, mock.Anything).Return(nil)
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token: bascule.NewToken("test", "alice", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"comcast"},
+			},
+		})),
+		Request: bascule.Request{
+			URL:    &url.URL{Path: "/"},
+			Method: "GET",
+		},
+	})
+	expected := Outcome{
+		Server:    defaultServer,
+		Outcome:   AcceptedOutcome,
+		ClientID:  "alice",
+		PartnerID: "comcast",
+		Endpoint:  NoneEndpoint,
+		Method:    "GET",
+	}
+
+	t.Run("Additional", func(t *testing.T) {
+		assert := assert.New(t)
+		measures := NopAuthCapabilityCheckMeasures()
+		r := new(outcomeRecorder)
+		m, err := NewMetricValidator(checker, measures, WithOutcomeSink(r), WithOutcomeSink(nil))
+		require.NoError(t, err)
+		assert.NoError(m.Check(ctx, nil))
+		assert.Equal([]Outcome{expected}, r.outcomes)
+		assert.Equal(float64(1), testutil.ToFloat64(measures.CapabilityCheckOutcome.With(expected.Labels())))
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		assert := assert.New(t)
+		core, logs := observer.New(zapcore.InfoLevel)
+		r, extra := new(outcomeRecorder), new(outcomeRecorder)
+		m, err := NewMetricValidator(checker, nil, WithLogger(zap.New(core)),
+			WithDefaultOutcomeSink(r), WithDefaultOutcomeSink(nil), WithOutcomeSink(extra))
+		require.NoError(t, err)
+		assert.False(m.degraded)
+		assert.Zero(logs.Len())
+		assert.NoError(m.Check(ctx, nil))
+		assert.ErrorIs(m.Check(context.Background(), nil), ErrNoAuth)
+		assert.Equal([]Outcome{expected, {
+			Server:  defaultServer,
+			Outcome: RejectedOutcome,
+			Reason:  TokenMissing,
+		}}, r.outcomes)
+		assert.Equal(r.outcomes, extra.outcomes)
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		assert.Equal(t, prometheus.Labels{
+			ServerLabel:    defaultServer,
+			OutcomeLabel:   AcceptedOutcome,
+			ReasonLabel:    "",
+			ClientIDLabel:  "alice",
+			PartnerIDLabel: "comcast",
+			EndpointLabel:  NoneEndpoint,
+			MethodLabel:    "GET",
+		}, expected.Labels())
+	})
+}
@@ -0,0 +1,105 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+const defaultPartnerSeparator = ","
+
+// PartnerClassifier decides what the partner ID metric label should be for a
+// token's list of partner IDs.
+type PartnerClassifier interface {
+	Classify(partners []string) string
+}
+
+// PartnerClassifierFunc makes it so any function with the same signature as
+// PartnerClassifier's Classify function implements PartnerClassifier.
+type PartnerClassifierFunc func([]string) string
+
+func (pcf PartnerClassifierFunc) Classify(partners []string) string {
+	return pcf(partners)
+}
+
+// DefaultPartnerClassifier returns the PartnerClassifier used when none is
+// configured, which buckets partners using DeterminePartnerMetric.
+func DefaultPartnerClassifier() PartnerClassifier {
+	return PartnerClassifierFunc(DeterminePartnerMetric)
+}
+
+// JoinedPartnerClassifier returns a PartnerClassifier that preserves the true
+// partner list by sorting the partners and joining them with the separator
+// given, which defaults to a comma.  An empty list is labeled NonePartner.
+func JoinedPartnerClassifier(sep string) PartnerClassifier {
+	if sep == "" {
+		sep = defaultPartnerSeparator
+	}
+	return PartnerClassifierFunc(func(partners []string) string {
+		if len(partners) == 0 {
+			return NonePartner
+		}
+		return strings.Join(sortedPartners(partners), sep)
+	})
+}
+
+// FirstPartnerClassifier returns a PartnerClassifier that labels a token with
+// its first partner.  The wildcard partner is still labeled WildcardPartner
+// and an empty list is labeled NonePartner.
+func FirstPartnerClassifier() PartnerClassifier {
+	return PartnerClassifierFunc(func(partners []string) string {
+		if len(partners) == 0 {
+			return NonePartner
+		}
+		if partners[0] == Wildcard {
+			return WildcardPartner
+		}
+		return partners[0]
+	})
+}
+
+// HashedPartnerClassifier returns a PartnerClassifier that labels a token with
+// the first n hex characters of the SHA-256 hash of its sorted partner list,
+// keeping distinct partner lists apart without exposing them.  If n isn't
+// positive, 8 characters are used.  An empty list is labeled NonePartner.
+func HashedPartnerClassifier(n int) PartnerClassifier {
+	if n <= 0 {
+		n = defaultHashLength
+	}
+	return PartnerClassifierFunc(func(partners []string) string {
+		if len(partners) == 0 {
+			return NonePartner
+		}
+		sum := sha256.Sum256([]byte(strings.Join(sortedPartners(partners), "\x00")))
+		h := hex.EncodeToString(sum[:])
+		if n < len(h) {
+			h = h[:n]
+		}
+		return h
+	})
+}
+
+func sortedPartners(partners []string) []string {
+	sorted := make([]string, len(partners))
+	copy(sorted, partners)
+	sort.Strings(sorted)
+	return sorted
+}
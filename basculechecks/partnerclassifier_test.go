@@ -0,0 +1,127 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartnerClassifiers(t *testing.T) {
+	tests := []struct {
+		description string
+		classifier  PartnerClassifier
+		partners    []string
+		expected    string
+	}{
+		{
+			description: "Default Many",
+			classifier:  DefaultPartnerClassifier(),
+			partners:    []string{"b", "a"},
+			expected:    ManyPartner,
+		},
+		{
+			description: "Joined",
+			classifier:  JoinedPartnerClassifier(""),
+			partners:    []string{"b", "*", "a"},
+			expected:    "*,a,b",
+		},
+		{
+			description: "Joined Separator",
+			classifier:  JoinedPartnerClassifier("|"),
+			partners:    []string{"b", "a"},
+			expected:    "a|b",
+		},
+		{
+			description: "Joined None",
+			classifier:  JoinedPartnerClassifier(""),
+			expected:    NonePartner,
+		},
+		{
+			description: "First",
+			classifier:  FirstPartnerClassifier(),
+			partners:    []string{"b", "a"},
+			expected:    "b",
+		},
+		{
+			description: "First Wildcard",
+			classifier:  FirstPartnerClassifier(),
+			partners:    []string{"*", "a"},
+			expected:    WildcardPartner,
+		},
+		{
+			description: "First None",
+			classifier:  FirstPartnerClassifier(),
+			expected:    NonePartner,
+		},
+		{
+			description: "Hashed",
+			classifier:  HashedPartnerClassifier(0),
+			partners:    []string{"test"},
+			expected:    "9f86d081",
+		},
+		{
+			description: "Hashed Length",
+			classifier:  HashedPartnerClassifier(100),
+			partners:    []string{"test"},
+			expected:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		},
+		{
+			description: "Hashed None",
+			classifier:  HashedPartnerClassifier(4),
+			partners:    []string{},
+			expected:    NonePartner,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.classifier.Classify(tc.partners))
+		})
+	}
+
+	// the order of the partners doesn't change the hash, and the input isn't
+	// modified.
+	partners := []string{"b", "a"}
+	h := HashedPartnerClassifier(8)
+	assert.Equal(t, h.Classify([]string{"a", "b"}), h.Classify(partners))
+	assert.Equal(t, []string{"b", "a"}, partners)
+}
+
+func TestPrepMetricsPartnerClassifier(t *testing.T) {
+	u, err := url.ParseRequestURI("/test")
+	require.NoError(t, err)
+	m, err := NewMetricValidator(CapabilitiesValidator{}, &AuthCapabilityCheckMeasures{},
+		WithPartnerClassifier(JoinedPartnerClassifier("")),
+		WithPartnerClassifier(nil),
+	)
+	require.NoError(t, err)
+	v, err := m.prepMetrics(bascule.Authentication{
+		Token: bascule.NewToken("jwt", "test", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"p2", "p1"},
+			},
+		})),
+		Request: bascule.Request{URL: u, Method: "GET"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "p1,p2", v.partnerID)
+}
@@ -0,0 +1,135 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var ErrPartnerNotAllowed = errWithReason{
+	err:    errors.New("partner not allowed"),
+	reason: PartnerNotAllowed,
+}
+
+// PartnerAllowListConfig configures a partner allow-list validator.
+type PartnerAllowListConfig struct {
+	// Allowed is the list of partners allowed for every endpoint.  Including
+	// the Wildcard allows all partners, including tokens with a wildcard
+	// partner.
+	Allowed []string
+
+	// Endpoints maps a regular expression for a URL path to the partners
+	// allowed for that path, overriding Allowed.  The expressions must match
+	// at the start of the path and are tried in sorted order.
+	Endpoints map[string][]string
+
+	// KeyPath is the location of the partner IDs in the token's attributes.
+	// Defaults to PartnerKeys().
+	KeyPath []string
+
+	// FallbackKeyPaths are searched in order for the partner IDs when they
+	// aren't at the KeyPath.
+	FallbackKeyPaths [][]string
+}
+
+type partnerSet map[string]struct{}
+
+func newPartnerSet(partners []string) partnerSet {
+	s := make(partnerSet, len(partners))
+	for _, p := range partners {
+		s[p] = struct{}{}
+	}
+	return s
+}
+
+// allows returns the first partner that isn't allowed, if there is one.  A
+// token with the wildcard partner is treated as asking for every partner, the
+// same way DeterminePartnerMetric labels it, so only a wildcard allow-list
+// allows it.
+func (s partnerSet) allows(partners []string) (string, bool) {
+	if _, ok := s[Wildcard]; ok {
+		return "", true
+	}
+	for _, p := range partners {
+		if _, ok := s[p]; !ok {
+			return p, false
+		}
+	}
+	return "", true
+}
+
+type partnerEndpoint struct {
+	regex    *regexp.Regexp
+	partners partnerSet
+}
+
+// NewPartnerValidator creates a Validator that rejects tokens whose partner
+// IDs aren't all in the allow-list for the request's endpoint.  Tokens with no
+// partner IDs are rejected.
+func NewPartnerValidator(config PartnerAllowListConfig) (bascule.ValidatorFunc, error) {
+	keyPaths := partnerKeyPaths(config.KeyPath, config.FallbackKeyPaths)
+	allowed := newPartnerSet(config.Allowed)
+
+	patterns := make([]string, 0, len(config.Endpoints))
+	for r := range config.Endpoints {
+		patterns = append(patterns, r)
+	}
+	sort.Strings(patterns)
+	endpoints := make([]partnerEndpoint, 0, len(patterns))
+	for _, r := range patterns {
+		regex, err := regexp.Compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w [%v]: %v", errRegexCompileFail, r, err)
+		}
+		endpoints = append(endpoints, partnerEndpoint{
+			regex:    regex,
+			partners: newPartnerSet(config.Endpoints[r]),
+		})
+	}
+
+	return func(ctx context.Context, token bascule.Token) error {
+		partners, err := FindPartnerIDs(token.Attributes(), keyPaths...)
+		if err != nil {
+			return err
+		}
+		if len(partners) == 0 {
+			return fmt.Errorf("%w: no partner IDs found", ErrPartnerNotAllowed)
+		}
+
+		set := allowed
+		if auth, ok := bascule.FromContext(ctx); ok && auth.Request.URL != nil {
+			path := auth.Request.URL.EscapedPath()
+			for _, e := range endpoints {
+				if idxs := e.regex.FindStringIndex(path); len(idxs) > 0 && idxs[0] == 0 {
+					set = e.partners
+					break
+				}
+			}
+		}
+		if p, ok := set.allows(partners); !ok {
+			return fmt.Errorf("%w: [%v]", ErrPartnerNotAllowed, p)
+		}
+		return nil
+	}, nil
+}
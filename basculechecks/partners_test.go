@@ -0,0 +1,161 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartnerValidator(t *testing.T) {
+	config := PartnerAllowListConfig{
+		Allowed: []string{"comcast", "sky"},
+		Endpoints: map[string][]string{
+			`/admin`:  {"comcast"},
+			`/public`: {Wildcard},
+		},
+	}
+	tests := []struct {
+		description string
+		attributes  map[string]interface{}
+		path        string
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []interface{}{"comcast", "sky"}}},
+			path:        "/api",
+		},
+		{
+			description: "No Request Success",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"sky"}}},
+		},
+		{
+			description: "Endpoint Success",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"comcast"}}},
+			path:        "/admin/users",
+		},
+		{
+			description: "Endpoint Wildcard Success",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"*"}}},
+			path:        "/public",
+		},
+		{
+			description: "Endpoint Not Allowed",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"sky"}}},
+			path:        "/admin",
+			expectedErr: ErrPartnerNotAllowed,
+		},
+		{
+			description: "Not At Start Of Path",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"sky"}}},
+			path:        "/api/admin",
+		},
+		{
+			description: "One Partner Not Allowed",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"sky", "other"}}},
+			path:        "/api",
+			expectedErr: ErrPartnerNotAllowed,
+		},
+		{
+			description: "Wildcard Partner Not Allowed",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{"*"}}},
+			path:        "/api",
+			expectedErr: ErrPartnerNotAllowed,
+		},
+		{
+			description: "Empty Partners",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": []string{}}},
+			path:        "/api",
+			expectedErr: ErrPartnerNotAllowed,
+		},
+		{
+			description: "Missing Partners",
+			attributes:  map[string]interface{}{},
+			path:        "/api",
+			expectedErr: ErrGettingPartnerIDs,
+		},
+		{
+			description: "Partners Not A List",
+			attributes:  map[string]interface{}{"allowedResources": map[string]interface{}{"allowedPartners": map[string]interface{}{}}},
+			path:        "/api",
+			expectedErr: ErrPartnerIDsNotStringSlice,
+		},
+	}
+	v, err := NewPartnerValidator(config)
+	require.NoError(t, err)
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			token := bascule.NewToken("jwt", "p", bascule.NewAttributes(tc.attributes))
+			ctx := context.Background()
+			if len(tc.path) > 0 {
+				u, err := url.ParseRequestURI(tc.path)
+				require.NoError(t, err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   token,
+					Request: bascule.Request{URL: u, Method: "GET"},
+				})
+			}
+			err := v(ctx, token)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			var r Reasoner
+			assert.ErrorAs(err, &r)
+		})
+	}
+}
+
+func TestNewPartnerValidatorError(t *testing.T) {
+	assert := assert.New(t)
+	v, err := NewPartnerValidator(PartnerAllowListConfig{
+		Endpoints: map[string][]string{`\M`: {"comcast"}},
+	})
+	assert.Nil(v)
+	assert.ErrorIs(err, errRegexCompileFail)
+
+	v, err = NewPartnerValidator(PartnerAllowListConfig{
+		Allowed: []string{"comcast"},
+		KeyPath: []string{"partners"},
+	})
+	assert.NoError(err)
+	assert.NoError(v(context.Background(), bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+		"partners": []string{"comcast"},
+	}))))
+
+	v, err = NewPartnerValidator(PartnerAllowListConfig{
+		Allowed:          []string{"comcast"},
+		FallbackKeyPaths: [][]string{{"partner-ids"}, {"org"}},
+	})
+	assert.NoError(err)
+	assert.NoError(v(context.Background(), bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+		"org": "comcast",
+	}))))
+	assert.ErrorIs(v(context.Background(), bascule.NewToken("jwt", "p", bascule.NewAttributes(map[string]interface{}{
+		"partner-ids": []string{"sky"},
+		"org":         "comcast",
+	}))), ErrPartnerNotAllowed)
+}
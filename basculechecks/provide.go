@@ -18,8 +18,8 @@
 package basculechecks
 
 import (
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule"
 	"go.uber.org/fx"
 )
 
@@ -33,7 +33,8 @@ func ProvideMetricValidator(optional bool) fx.Option {
 				if optional && in.Checker == nil {
 					return nil, nil
 				}
-				return NewMetricValidator(in.Checker, &in.Measures, in.Options...)
+				options := append([]MetricOption{WithLogger(in.Logger)}, in.Options...)
+				return NewMetricValidator(in.Checker, &in.Measures, options...)
 			},
 		},
 	)
@@ -64,3 +65,18 @@ func ProvideRegexCapabilitiesValidator(key string) fx.Option {
 		ProvideMetricValidator(true),
 	)
 }
+
+// ProvidePartnerValidator is an uber fx Provide() function that builds a
+// partner allow-list Validator using the configuration found at the key
+// provided, adding it to the bearer validators.
+func ProvidePartnerValidator(key string) fx.Option {
+	return fx.Provide(
+		arrange.UnmarshalKey(key, PartnerAllowListConfig{}),
+		fx.Annotated{
+			Group: "bascule_bearer_validators",
+			Target: func(config PartnerAllowListConfig) (bascule.Validator, error) {
+				return NewPartnerValidator(config)
+			},
+		},
+	)
+}
@@ -23,9 +23,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/touchstone"
 	"go.uber.org/fx"
 )
@@ -87,3 +87,28 @@ func TestProvideMetricValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestProvideMetricValidatorWithoutMetrics(t *testing.T) {
+	type In struct {
+		fx.In
+		V bascule.Validator `name:"bascule_validator_capabilities"`
+	}
+	var result bascule.Validator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(
+			func() CapabilitiesChecker {
+				return &CapabilitiesValidator{}
+			},
+		),
+		ProvideMetricValidator(false),
+		fx.Invoke(
+			func(in In) {
+				result = in.V
+			},
+		),
+	)
+	require.NoError(t, app.Err())
+	require.NotNil(t, result)
+	assert.True(t, result.(*MetricValidator).degraded)
+}
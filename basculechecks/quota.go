@@ -0,0 +1,265 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// QuotaPeriod is the calendar window a quota's count is kept over.
+type QuotaPeriod string
+
+const (
+	Hourly  QuotaPeriod = "hourly"
+	Daily   QuotaPeriod = "daily"
+	Monthly QuotaPeriod = "monthly"
+)
+
+// Quota headers added to a rejection.
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	retryAfterHeader         = "Retry-After"
+
+	defaultQuotaKeyPrefix = "quota"
+)
+
+var (
+	ErrQuotaExceeded       = bascule.NewClassError(bascule.RateLimitedClass, "quota exceeded")
+	ErrInvalidQuotaPeriod  = errors.New("invalid quota period")
+	ErrInvalidQuotaLimit   = errors.New("quota limit must be positive")
+	ErrQuotaStorageFailure = bascule.NewClassError(bascule.UnavailableClass, "failed to count request against quota")
+)
+
+// QuotaWindow is a limit on the requests a principal can make per period.
+type QuotaWindow struct {
+	// Period is Hourly, Daily, or Monthly.  Periods start on the calendar
+	// boundary in UTC.
+	Period QuotaPeriod
+
+	// Limit is the number of requests allowed per period.
+	Limit int64
+
+	// Limits overrides the Limit for specific principals.
+	Limits map[string]int64
+}
+
+// QuotaConfig configures a QuotaValidator.
+type QuotaConfig struct {
+	// Windows are the quotas enforced.  A request has to be within all of
+	// them.
+	Windows []QuotaWindow
+
+	// KeyPrefix is the start of the Storage keys used.  Defaults to "quota".
+	KeyPrefix string
+
+	// FailOpen allows requests when the Storage can't be reached.  By
+	// default they're rejected.
+	FailOpen bool
+
+	// LimitSource, if set, is asked for each principal's limits before the
+	// windows' Limits, such as to use the limits in a client registry.
+	LimitSource QuotaLimitSource
+}
+
+// QuotaLimitSource looks up a principal's limit for a period.  The bool
+// returned is false if the principal has no limit of its own.
+type QuotaLimitSource interface {
+	QuotaLimit(ctx context.Context, principal string, period QuotaPeriod) (int64, bool)
+}
+
+// QuotaLimitSourceFunc makes it so any function with the same signature as
+// QuotaLimit implements QuotaLimitSource.
+type QuotaLimitSourceFunc func(context.Context, string, QuotaPeriod) (int64, bool)
+
+// QuotaLimit runs the function.
+func (f QuotaLimitSourceFunc) QuotaLimit(ctx context.Context, principal string, period QuotaPeriod) (int64, bool) {
+	return f(ctx, principal, period)
+}
+
+// QuotaExceededError is returned when a principal has used up a quota.  It
+// results in a 429 response with the rate limit headers.
+type QuotaExceededError struct {
+	Principal string
+	Period    QuotaPeriod
+	Limit     int64
+	Reset     time.Time
+	now       time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v: %s limit of %d reached", ErrQuotaExceeded, e.Period, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// Reason returns the metric label for the failure.
+func (e *QuotaExceededError) Reason() string {
+	return QuotaExceeded
+}
+
+// StatusCode returns 429, Too Many Requests.
+func (e *QuotaExceededError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// ErrorClass returns bascule.RateLimitedClass.
+func (e *QuotaExceededError) ErrorClass() bascule.ErrorClass {
+	return bascule.RateLimitedClass
+}
+
+// RetryAfter returns how long until the window resets.
+func (e *QuotaExceededError) RetryAfter() time.Duration {
+	return e.Reset.Sub(e.now)
+}
+
+// RateLimit returns the window's limit, no remaining requests, and how long
+// until the window resets.
+func (e *QuotaExceededError) RateLimit() (limit, remaining int64, reset time.Duration) {
+	return e.Limit, 0, e.RetryAfter()
+}
+
+// Headers returns the rate limit headers and Retry-After.
+func (e *QuotaExceededError) Headers() http.Header {
+	retryAfter := int64(math.Ceil(e.Reset.Sub(e.now).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return http.Header{
+		RateLimitLimitHeader:     {strconv.FormatInt(e.Limit, 10)},
+		RateLimitRemainingHeader: {"0"},
+		RateLimitResetHeader:     {strconv.FormatInt(e.Reset.Unix(), 10)},
+		retryAfterHeader:         {strconv.FormatInt(retryAfter, 10)},
+	}
+}
+
+// QuotaValidator is a Validator that counts each principal's requests over
+// calendar windows in a bascule.Storage, rejecting requests once a window's
+// limit is reached.  Rejected requests count against the quota too.
+type QuotaValidator struct {
+	storage bascule.Storage
+	config  QuotaConfig
+	now     func() time.Time
+}
+
+// NewQuotaValidator creates a QuotaValidator that keeps its counts in the
+// storage given, which should be shared by every instance of a clustered
+// service.
+func NewQuotaValidator(storage bascule.Storage, config QuotaConfig) (*QuotaValidator, error) {
+	if storage == nil {
+		return nil, bascule.ErrNilStorage
+	}
+	for _, w := range config.Windows {
+		if _, err := periodStart(w.Period, time.Time{}); err != nil {
+			return nil, err
+		}
+		if w.Limit <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidQuotaLimit, w.Period)
+		}
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultQuotaKeyPrefix
+	}
+	return &QuotaValidator{
+		storage: storage,
+		config:  config,
+		now:     time.Now,
+	}, nil
+}
+
+// Check counts the request against each of the principal's quotas, failing
+// with a *QuotaExceededError for the first one that's used up.
+func (q *QuotaValidator) Check(ctx context.Context, token bascule.Token) error {
+	if token == nil {
+		return ErrNoToken
+	}
+	principal := token.Principal()
+	now := q.now().UTC()
+	for _, w := range q.config.Windows {
+		limit := q.limit(ctx, w, principal)
+		start, _ := periodStart(w.Period, now)
+		reset := periodEnd(w.Period, start)
+		key := fmt.Sprintf("%s:%s:%d:%s", q.config.KeyPrefix, w.Period, start.Unix(), principal)
+		count, err := q.storage.Incr(ctx, key, reset.Sub(now))
+		if err != nil {
+			if q.config.FailOpen {
+				continue
+			}
+			return fmt.Errorf("%w: %v", ErrQuotaStorageFailure, err)
+		}
+		if count > limit {
+			return &QuotaExceededError{
+				Principal: principal,
+				Period:    w.Period,
+				Limit:     limit,
+				Reset:     reset,
+				now:       now,
+			}
+		}
+	}
+	return nil
+}
+
+// limit returns the principal's limit for the window, from the LimitSource,
+// the window's Limits, or the window's default, in that order.
+func (q *QuotaValidator) limit(ctx context.Context, w QuotaWindow, principal string) int64 {
+	if q.config.LimitSource != nil {
+		if n, ok := q.config.LimitSource.QuotaLimit(ctx, principal, w.Period); ok && n > 0 {
+			return n
+		}
+	}
+	if n, ok := w.Limits[principal]; ok {
+		return n
+	}
+	return w.Limit
+}
+
+// periodStart returns the start of the period containing the time given.
+func periodStart(p QuotaPeriod, t time.Time) (time.Time, error) {
+	switch p {
+	case Hourly:
+		return t.Truncate(time.Hour), nil
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case Monthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidQuotaPeriod, p)
+}
+
+func periodEnd(p QuotaPeriod, start time.Time) time.Time {
+	switch p {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
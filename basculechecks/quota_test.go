@@ -0,0 +1,196 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*QuotaValidator)(nil)
+
+type failingStorage struct {
+	bascule.Storage
+}
+
+func (failingStorage) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("storage down")
+}
+
+func TestNewQuotaValidator(t *testing.T) {
+	tests := []struct {
+		description string
+		storage     bascule.Storage
+		config      QuotaConfig
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			storage:     bascule.NewMemoryStorage(),
+			config:      QuotaConfig{Windows: []QuotaWindow{{Period: Daily, Limit: 5}}},
+		},
+		{
+			description: "Nil Storage",
+			expectedErr: bascule.ErrNilStorage,
+		},
+		{
+			description: "Bad Period",
+			storage:     bascule.NewMemoryStorage(),
+			config:      QuotaConfig{Windows: []QuotaWindow{{Period: "weekly", Limit: 5}}},
+			expectedErr: ErrInvalidQuotaPeriod,
+		},
+		{
+			description: "Bad Limit",
+			storage:     bascule.NewMemoryStorage(),
+			config:      QuotaConfig{Windows: []QuotaWindow{{Period: Monthly}}},
+			expectedErr: ErrInvalidQuotaLimit,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			q, err := NewQuotaValidator(tc.storage, tc.config)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(q)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(defaultQuotaKeyPrefix, q.config.KeyPrefix)
+		})
+	}
+}
+
+func TestQuotaValidator(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	q, err := NewQuotaValidator(bascule.NewMemoryStorage(), QuotaConfig{
+		Windows: []QuotaWindow{
+			{Period: Daily, Limit: 2, Limits: map[string]int64{"vip": 3}},
+			{Period: Monthly, Limit: 3},
+		},
+	})
+	require.NoError(err)
+	now := time.Date(2026, time.October, 17, 22, 30, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	alice := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	assert.NoError(q.Check(context.Background(), alice))
+	assert.NoError(q.Check(context.Background(), alice))
+
+	err = q.Check(context.Background(), alice)
+	assert.ErrorIs(err, ErrQuotaExceeded)
+	var quotaErr *QuotaExceededError
+	require.True(errors.As(err, &quotaErr))
+	assert.Equal(Daily, quotaErr.Period)
+	assert.Equal(int64(2), quotaErr.Limit)
+	assert.Equal(time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), quotaErr.Reset)
+	assert.Equal(http.StatusTooManyRequests, quotaErr.StatusCode())
+	assert.Equal(bascule.RateLimitedClass, bascule.ClassOf(err))
+	assert.Equal(QuotaExceeded, quotaErr.Reason())
+	assert.Equal(http.Header{
+		RateLimitLimitHeader:     {"2"},
+		RateLimitRemainingHeader: {"0"},
+		RateLimitResetHeader:     {"1792281600"},
+		retryAfterHeader:         {"5400"},
+	}, quotaErr.Headers())
+	assert.Equal(90*time.Minute, quotaErr.RetryAfter())
+	limit, remaining, reset := quotaErr.RateLimit()
+	assert.Equal(int64(2), limit)
+	assert.Zero(remaining)
+	assert.Equal(90*time.Minute, reset)
+
+	// the next day has a new daily count, but the month's is used up.
+	now = now.Add(2 * time.Hour)
+	assert.NoError(q.Check(context.Background(), alice))
+	err = q.Check(context.Background(), alice)
+	require.True(errors.As(err, &quotaErr))
+	assert.Equal(Monthly, quotaErr.Period)
+	assert.Equal(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), quotaErr.Reset)
+
+	// principals are counted separately, with their own limits.
+	vip := bascule.NewToken("test", "vip", bascule.NewAttributes(nil))
+	for i := 0; i < 3; i++ {
+		assert.NoError(q.Check(context.Background(), vip))
+	}
+	assert.ErrorIs(q.Check(context.Background(), vip), ErrQuotaExceeded)
+
+	assert.ErrorIs(q.Check(context.Background(), nil), ErrNoToken)
+}
+
+func TestQuotaValidatorHourly(t *testing.T) {
+	q, err := NewQuotaValidator(bascule.NewMemoryStorage(), QuotaConfig{
+		Windows: []QuotaWindow{{Period: Hourly, Limit: 1}},
+	})
+	require.NoError(t, err)
+	now := time.Date(2026, time.October, 17, 22, 59, 59, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	token := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	assert.NoError(t, q.Check(context.Background(), token))
+	err = q.Check(context.Background(), token)
+	var quotaErr *QuotaExceededError
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, "1", quotaErr.Headers().Get(retryAfterHeader))
+
+	now = now.Add(time.Second)
+	assert.NoError(t, q.Check(context.Background(), token))
+}
+
+func TestQuotaValidatorStorageFailure(t *testing.T) {
+	token := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	config := QuotaConfig{Windows: []QuotaWindow{{Period: Daily, Limit: 1}}}
+	q, err := NewQuotaValidator(failingStorage{}, config)
+	require.NoError(t, err)
+	assert.ErrorIs(t, q.Check(context.Background(), token), ErrQuotaStorageFailure)
+
+	config.FailOpen = true
+	q, err = NewQuotaValidator(failingStorage{}, config)
+	require.NoError(t, err)
+	assert.NoError(t, q.Check(context.Background(), token))
+}
+
+func TestQuotaValidatorLimitSource(t *testing.T) {
+	q, err := NewQuotaValidator(bascule.NewMemoryStorage(), QuotaConfig{
+		Windows: []QuotaWindow{{Period: Daily, Limit: 1, Limits: map[string]int64{"partner": 3}}},
+		LimitSource: QuotaLimitSourceFunc(func(_ context.Context, principal string, p QuotaPeriod) (int64, bool) {
+			if principal == "partner" && p == Daily {
+				return 2, true
+			}
+			return 0, false
+		}),
+	})
+	require.NoError(t, err)
+
+	// the source's limit wins over the window's Limits.
+	partner := bascule.NewToken("test", "partner", bascule.NewAttributes(nil))
+	assert.NoError(t, q.Check(context.Background(), partner))
+	assert.NoError(t, q.Check(context.Background(), partner))
+	assert.ErrorIs(t, q.Check(context.Background(), partner), ErrQuotaExceeded)
+
+	// principals the source doesn't know get the window's limit.
+	alice := bascule.NewToken("test", "alice", bascule.NewAttributes(nil))
+	assert.NoError(t, q.Check(context.Background(), alice))
+	assert.ErrorIs(t, q.Check(context.Background(), alice), ErrQuotaExceeded)
+}
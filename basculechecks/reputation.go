@@ -0,0 +1,433 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+)
+
+// IPVerdict is what an IPReputation says about an address.
+type IPVerdict int
+
+const (
+	// IPNeutral addresses have no bad reputation.
+	IPNeutral IPVerdict = iota
+
+	// IPFlagged addresses are suspicious.  Requests from them are counted,
+	// and only rejected if the validator is configured to.
+	IPFlagged
+
+	// IPBlocked addresses are known to be bad, and requests from them are
+	// rejected.
+	IPBlocked
+)
+
+// String returns the verdict's metric label.
+func (v IPVerdict) String() string {
+	switch v {
+	case IPFlagged:
+		return "flagged"
+	case IPBlocked:
+		return "blocked"
+	default:
+		return "neutral"
+	}
+}
+
+const (
+	defaultReputationTTL        = 10 * time.Minute
+	defaultReputationMaxEntries = 10000
+)
+
+var (
+	ErrNilIPReputation = errors.New("ip reputation cannot be nil")
+	ErrNoDNSBLZones    = errors.New("no dnsbl zones configured")
+
+	// ErrIPBlocked is returned when the request comes from an address with
+	// a bad reputation.
+	ErrIPBlocked = errWithReason{
+		err:    errors.New("requests from this address are not allowed"),
+		reason: BadIPReputation,
+	}
+
+	// ErrIPReputationFailed is returned when the client's reputation
+	// couldn't be looked up and the validator fails closed.
+	ErrIPReputationFailed = errWithReason{
+		err:    errors.New("couldn't determine the client's reputation"),
+		reason: IPReputationFailed,
+	}
+)
+
+// IPReputation looks up the reputation of an IP address.
+type IPReputation interface {
+	Lookup(context.Context, net.IP) (IPVerdict, error)
+}
+
+// IPReputationFunc makes it so any function with the same signature as
+// Lookup implements IPReputation.
+type IPReputationFunc func(context.Context, net.IP) (IPVerdict, error)
+
+// Lookup runs the function.
+func (f IPReputationFunc) Lookup(ctx context.Context, ip net.IP) (IPVerdict, error) {
+	return f(ctx, ip)
+}
+
+// IPReputations combines reputations, returning the worst verdict of them.
+// A lookup error is only returned if no reputation blocked the address.
+type IPReputations []IPReputation
+
+// Lookup asks each reputation in turn, stopping once one blocks the address.
+func (rs IPReputations) Lookup(ctx context.Context, ip net.IP) (IPVerdict, error) {
+	var (
+		verdict IPVerdict
+		lastErr error
+	)
+	for _, r := range rs {
+		v, err := r.Lookup(ctx, ip)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if v > verdict {
+			verdict = v
+		}
+		if verdict == IPBlocked {
+			return verdict, nil
+		}
+	}
+	return verdict, lastErr
+}
+
+// CIDRReputation is an IPReputation using static lists of addresses and
+// CIDR blocks.
+type CIDRReputation struct {
+	blocked []*net.IPNet
+	flagged []*net.IPNet
+}
+
+// NewCIDRReputation creates a CIDRReputation from lists of addresses and
+// CIDR blocks.
+func NewCIDRReputation(blocked, flagged []string) (*CIDRReputation, error) {
+	b, err := parseCIDRs(blocked)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseCIDRs(flagged)
+	if err != nil {
+		return nil, err
+	}
+	return &CIDRReputation{blocked: b, flagged: f}, nil
+}
+
+func parseCIDRs(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address [%v]", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr block [%v]: %w", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup blocks addresses in the blocked list, then flags the ones in the
+// flagged list.
+func (c *CIDRReputation) Lookup(_ context.Context, ip net.IP) (IPVerdict, error) {
+	switch {
+	case containsIP(c.blocked, ip):
+		return IPBlocked, nil
+	case containsIP(c.flagged, ip):
+		return IPFlagged, nil
+	default:
+		return IPNeutral, nil
+	}
+}
+
+// HostResolver resolves host names, like a *net.Resolver.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DNSBLConfig configures a DNSBLReputation.
+type DNSBLConfig struct {
+	// Zones are the DNS blocklist zones to query, such as
+	// "zen.spamhaus.org".
+	Zones []string
+
+	// Verdict is the verdict for listed addresses.  Defaults to IPBlocked.
+	Verdict IPVerdict
+
+	// Resolver resolves the queries.  Defaults to net.DefaultResolver.
+	Resolver HostResolver
+}
+
+// DNSBLReputation is an IPReputation using DNS blocklists: an address is
+// listed when its reversed address under a zone resolves to an address in
+// 127.0.0.0/8.
+type DNSBLReputation struct {
+	zones    []string
+	verdict  IPVerdict
+	resolver HostResolver
+}
+
+// NewDNSBLReputation creates a DNSBLReputation.
+func NewDNSBLReputation(config DNSBLConfig) (*DNSBLReputation, error) {
+	var zones []string
+	for _, z := range config.Zones {
+		if z = strings.Trim(strings.TrimSpace(z), "."); z != "" {
+			zones = append(zones, z)
+		}
+	}
+	if len(zones) == 0 {
+		return nil, ErrNoDNSBLZones
+	}
+	if config.Verdict == IPNeutral {
+		config.Verdict = IPBlocked
+	}
+	if config.Resolver == nil {
+		config.Resolver = net.DefaultResolver
+	}
+	return &DNSBLReputation{
+		zones:    zones,
+		verdict:  config.Verdict,
+		resolver: config.Resolver,
+	}, nil
+}
+
+// Lookup queries each zone, returning the configured verdict if the address
+// is listed in any of them.  A zone that can't be queried is an error only
+// if no zone lists the address.
+func (d *DNSBLReputation) Lookup(ctx context.Context, ip net.IP) (IPVerdict, error) {
+	reversed := reverseIP(ip)
+	if reversed == "" {
+		return IPNeutral, fmt.Errorf("invalid address [%v]", ip)
+	}
+	var lastErr error
+	for _, z := range d.zones {
+		addrs, err := d.resolver.LookupHost(ctx, reversed+"."+z)
+		if err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				continue
+			}
+			lastErr = err
+			continue
+		}
+		for _, a := range addrs {
+			if listed := net.ParseIP(a); listed != nil && listed.To4() != nil && listed.To4()[0] == 127 {
+				return d.verdict, nil
+			}
+		}
+	}
+	return IPNeutral, lastErr
+}
+
+// reverseIP returns the address in DNSBL query form: reversed octets for
+// IPv4, and reversed nibbles for IPv6.
+func reverseIP(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return ""
+	}
+	parts := make([]string, 0, 2*net.IPv6len)
+	for i := net.IPv6len - 1; i >= 0; i-- {
+		parts = append(parts,
+			strconv.FormatUint(uint64(ip16[i]&0x0f), 16),
+			strconv.FormatUint(uint64(ip16[i]>>4), 16))
+	}
+	return strings.Join(parts, ".")
+}
+
+// CachingIPReputationConfig configures a CachingIPReputation.
+type CachingIPReputationConfig struct {
+	// TTL is how long a verdict is cached.  Defaults to 10 minutes.
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached verdicts.  Defaults to 10000.
+	MaxEntries int
+}
+
+// CachingIPReputation wraps an IPReputation, such as one calling an external
+// API, remembering its verdicts so that each address is only looked up once
+// per TTL.  Failed lookups aren't cached.
+type CachingIPReputation struct {
+	reputation IPReputation
+	config     CachingIPReputationConfig
+	now        func() time.Time
+	verdicts   *ttlcache.Cache
+}
+
+// NewCachingIPReputation creates a CachingIPReputation around the reputation
+// given.
+func NewCachingIPReputation(r IPReputation, config CachingIPReputationConfig) (*CachingIPReputation, error) {
+	if r == nil {
+		return nil, ErrNilIPReputation
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultReputationTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultReputationMaxEntries
+	}
+	return &CachingIPReputation{
+		reputation: r,
+		config:     config,
+		now:        time.Now,
+		verdicts:   ttlcache.New(config.MaxEntries),
+	}, nil
+}
+
+// Lookup returns the cached verdict for the address, otherwise it asks the
+// wrapped reputation and caches the answer.
+func (c *CachingIPReputation) Lookup(ctx context.Context, ip net.IP) (IPVerdict, error) {
+	key := ip.String()
+	now := c.now()
+	if cached, found := c.verdicts.Get(key, now); found {
+		return cached.(IPVerdict), nil
+	}
+
+	verdict, err := c.reputation.Lookup(ctx, ip)
+	if err != nil {
+		return verdict, err
+	}
+
+	c.verdicts.Set(key, verdict, now.Add(c.config.TTL), now)
+	return verdict, nil
+}
+
+// IPReputationConfig configures an IPReputationValidator.
+type IPReputationConfig struct {
+	// RejectFlagged rejects requests from flagged addresses as well as
+	// blocked ones.
+	RejectFlagged bool
+
+	// FailClosed rejects requests whose reputation can't be looked up.  By
+	// default, they are allowed so that an outage of the reputation source
+	// doesn't reject everyone.
+	FailClosed bool
+}
+
+// IPReputationValidator rejects requests from addresses with a bad
+// reputation, using the client IP captured in the bascule.Request.  As a
+// Validator it runs after the token is parsed; to reject bad sources before
+// any token validation work is spent, also give it to the basculehttp
+// constructor, which calls CheckIP first.
+type IPReputationValidator struct {
+	reputation IPReputation
+	config     IPReputationConfig
+	measures   *IPReputationMeasures
+	server     string
+}
+
+// NewIPReputationValidator creates an IPReputationValidator.  The measures
+// are optional.
+func NewIPReputationValidator(r IPReputation, config IPReputationConfig, measures *IPReputationMeasures, server string) (*IPReputationValidator, error) {
+	if r == nil {
+		return nil, ErrNilIPReputation
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	return &IPReputationValidator{
+		reputation: r,
+		config:     config,
+		measures:   measures,
+		server:     server,
+	}, nil
+}
+
+// Check rejects the request if its client IP has a bad reputation.
+func (v *IPReputationValidator) Check(ctx context.Context, _ bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return ErrNoAuth
+	}
+	return v.CheckIP(ctx, auth.Request.ClientIP)
+}
+
+// CheckIP rejects the client IP given if it has a bad reputation.
+func (v *IPReputationValidator) CheckIP(ctx context.Context, clientIP string) error {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		if v.config.FailClosed {
+			return fmt.Errorf("%w: invalid client IP [%v]", ErrIPReputationFailed, clientIP)
+		}
+		return nil
+	}
+	verdict, err := v.reputation.Lookup(ctx, ip)
+	if err != nil && verdict != IPBlocked {
+		if v.config.FailClosed {
+			return fmt.Errorf("%w: %v", ErrIPReputationFailed, err)
+		}
+		return nil
+	}
+	if verdict == IPNeutral {
+		return nil
+	}
+	v.record(verdict)
+	if verdict == IPBlocked || v.config.RejectFlagged {
+		return fmt.Errorf("%w: [%v] is %v", ErrIPBlocked, ip, verdict)
+	}
+	return nil
+}
+
+func (v *IPReputationValidator) record(verdict IPVerdict) {
+	if v.measures == nil || v.measures.Verdicts == nil {
+		return
+	}
+	v.measures.Verdicts.With(prometheus.Labels{
+		ServerLabel:  v.server,
+		VerdictLabel: verdict.String(),
+	}).Inc()
+}
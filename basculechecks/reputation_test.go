@@ -0,0 +1,347 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ bascule.Validator = (*IPReputationValidator)(nil)
+	_ IPReputation      = (*CIDRReputation)(nil)
+	_ IPReputation      = (*DNSBLReputation)(nil)
+	_ IPReputation      = (*CachingIPReputation)(nil)
+	_ IPReputation      = IPReputations{}
+)
+
+type hostResolverFunc func(context.Context, string) ([]string, error)
+
+func (f hostResolverFunc) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return f(ctx, host)
+}
+
+func TestIPVerdictString(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("neutral", IPNeutral.String())
+	assert.Equal("flagged", IPFlagged.String())
+	assert.Equal("blocked", IPBlocked.String())
+	assert.Equal("neutral", IPVerdict(9).String())
+}
+
+func TestCIDRReputation(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewCIDRReputation([]string{"nope"}, nil)
+	assert.Error(err)
+	_, err = NewCIDRReputation(nil, []string{"10.0.0.0/33"})
+	assert.Error(err)
+
+	r, err := NewCIDRReputation([]string{"10.0.0.0/8", " 192.168.1.1 ", "2001:db8::1"}, []string{"10.1.0.0/16", "172.16.0.0/12"})
+	require.NoError(t, err)
+	tests := []struct {
+		ip       string
+		expected IPVerdict
+	}{
+		{ip: "10.1.2.3", expected: IPBlocked},
+		{ip: "192.168.1.1", expected: IPBlocked},
+		{ip: "192.168.1.2", expected: IPNeutral},
+		{ip: "2001:db8::1", expected: IPBlocked},
+		{ip: "2001:db8::2", expected: IPNeutral},
+		{ip: "172.20.0.1", expected: IPFlagged},
+		{ip: "8.8.8.8", expected: IPNeutral},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			v, err := r.Lookup(context.Background(), net.ParseIP(tc.ip))
+			assert.NoError(err)
+			assert.Equal(tc.expected, v)
+		})
+	}
+}
+
+func TestReverseIP(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("4.3.2.1", reverseIP(net.ParseIP("1.2.3.4")))
+	assert.Equal("1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", reverseIP(net.ParseIP("2001:db8::1")))
+	assert.Empty(reverseIP(net.IP{1, 2}))
+}
+
+func TestDNSBLReputation(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", IsNotFound: true}
+	unavailable := errors.New("dns unavailable")
+	resolver := hostResolverFunc(func(_ context.Context, host string) ([]string, error) {
+		switch host {
+		case "4.3.2.1.bl.example.com":
+			return []string{"127.0.0.2"}, nil
+		case "5.3.2.1.bl.example.com":
+			return []string{"10.0.0.1"}, nil
+		case "6.3.2.1.bl.example.com", "6.3.2.1.other.example.com":
+			return nil, unavailable
+		case "7.3.2.1.bl.example.com":
+			return nil, unavailable
+		case "7.3.2.1.other.example.com":
+			return []string{"127.0.0.4"}, nil
+		}
+		return nil, notFound
+	})
+
+	_, err := NewDNSBLReputation(DNSBLConfig{Zones: []string{" ", "."}})
+	assert.ErrorIs(t, err, ErrNoDNSBLZones)
+
+	d, err := NewDNSBLReputation(DNSBLConfig{
+		Zones:    []string{"bl.example.com.", "other.example.com"},
+		Resolver: resolver,
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		ip          string
+		expected    IPVerdict
+		expectedErr error
+	}{
+		{ip: "1.2.3.4", expected: IPBlocked},
+		{ip: "1.2.3.5", expected: IPNeutral},
+		{ip: "1.2.3.6", expected: IPNeutral, expectedErr: unavailable},
+		{ip: "1.2.3.7", expected: IPBlocked},
+		{ip: "1.2.3.8", expected: IPNeutral},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			v, err := d.Lookup(context.Background(), net.ParseIP(tc.ip))
+			assert.Equal(t, tc.expected, v)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	_, err = d.Lookup(context.Background(), net.IP{1})
+	assert.Error(t, err)
+
+	flagging, err := NewDNSBLReputation(DNSBLConfig{Zones: []string{"bl.example.com"}, Verdict: IPFlagged, Resolver: resolver})
+	require.NoError(t, err)
+	v, err := flagging.Lookup(context.Background(), net.ParseIP("1.2.3.4"))
+	assert.NoError(t, err)
+	assert.Equal(t, IPFlagged, v)
+}
+
+func TestIPReputations(t *testing.T) {
+	assert := assert.New(t)
+	failing := IPReputationFunc(func(context.Context, net.IP) (IPVerdict, error) {
+		return IPNeutral, errors.New("down")
+	})
+	fixed := func(v IPVerdict) IPReputation {
+		return IPReputationFunc(func(context.Context, net.IP) (IPVerdict, error) { return v, nil })
+	}
+
+	v, err := IPReputations{fixed(IPFlagged), fixed(IPNeutral)}.Lookup(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal(IPFlagged, v)
+
+	v, err = IPReputations{failing, fixed(IPBlocked)}.Lookup(context.Background(), nil)
+	assert.NoError(err)
+	assert.Equal(IPBlocked, v)
+
+	v, err = IPReputations{fixed(IPFlagged), failing}.Lookup(context.Background(), nil)
+	assert.Error(err)
+	assert.Equal(IPFlagged, v)
+}
+
+func TestCachingIPReputation(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewCachingIPReputation(nil, CachingIPReputationConfig{})
+	assert.ErrorIs(err, ErrNilIPReputation)
+
+	var (
+		calls int
+		fail  bool
+	)
+	r := IPReputationFunc(func(_ context.Context, ip net.IP) (IPVerdict, error) {
+		calls++
+		if fail {
+			return IPNeutral, errors.New("down")
+		}
+		if ip.String() == "10.0.0.1" {
+			return IPBlocked, nil
+		}
+		return IPNeutral, nil
+	})
+	c, err := NewCachingIPReputation(r, CachingIPReputationConfig{TTL: time.Minute, MaxEntries: 2})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	v, err := c.Lookup(ctx, net.ParseIP("10.0.0.1"))
+	assert.NoError(err)
+	assert.Equal(IPBlocked, v)
+	v, err = c.Lookup(ctx, net.ParseIP("10.0.0.1"))
+	assert.NoError(err)
+	assert.Equal(IPBlocked, v)
+	assert.Equal(1, calls)
+
+	// failures aren't cached.
+	fail = true
+	_, err = c.Lookup(ctx, net.ParseIP("10.0.0.2"))
+	assert.Error(err)
+	fail = false
+	v, err = c.Lookup(ctx, net.ParseIP("10.0.0.2"))
+	assert.NoError(err)
+	assert.Equal(IPNeutral, v)
+	assert.Equal(3, calls)
+
+	// the cache is bounded.
+	_, _ = c.Lookup(ctx, net.ParseIP("10.0.0.3"))
+	assert.LessOrEqual(c.verdicts.Len(), 2)
+
+	// verdicts expire.
+	now = now.Add(2 * time.Minute)
+	calls = 0
+	_, _ = c.Lookup(ctx, net.ParseIP("10.0.0.3"))
+	assert.Equal(1, calls)
+
+	c, err = NewCachingIPReputation(r, CachingIPReputationConfig{})
+	require.NoError(t, err)
+	assert.Equal(defaultReputationTTL, c.config.TTL)
+	assert.Equal(defaultReputationMaxEntries, c.config.MaxEntries)
+}
+
+func TestNewIPReputationValidator(t *testing.T) {
+	assert := assert.New(t)
+	v, err := NewIPReputationValidator(nil, IPReputationConfig{}, nil, "")
+	assert.ErrorIs(err, ErrNilIPReputation)
+	assert.Nil(v)
+
+	r, err := NewCIDRReputation(nil, nil)
+	require.NoError(t, err)
+	v, err = NewIPReputationValidator(r, IPReputationConfig{}, nil, "")
+	require.NoError(t, err)
+	assert.Equal(defaultServer, v.server)
+}
+
+func TestIPReputationValidator(t *testing.T) {
+	cidrs, err := NewCIDRReputation([]string{"10.0.0.1"}, []string{"10.0.0.2"})
+	require.NoError(t, err)
+	reputation := IPReputations{
+		cidrs,
+		IPReputationFunc(func(_ context.Context, ip net.IP) (IPVerdict, error) {
+			if ip.String() == "10.0.0.9" {
+				return IPNeutral, errors.New("down")
+			}
+			return IPNeutral, nil
+		}),
+	}
+	tests := []struct {
+		description     string
+		config          IPReputationConfig
+		clientIP        string
+		noAuth          bool
+		expectedErr     error
+		expectedVerdict string
+	}{
+		{
+			description: "Neutral",
+			clientIP:    "10.0.0.3",
+		},
+		{
+			description:     "Blocked",
+			clientIP:        "10.0.0.1",
+			expectedErr:     ErrIPBlocked,
+			expectedVerdict: "blocked",
+		},
+		{
+			description:     "Flagged",
+			clientIP:        "10.0.0.2",
+			expectedVerdict: "flagged",
+		},
+		{
+			description:     "Flagged Rejected",
+			config:          IPReputationConfig{RejectFlagged: true},
+			clientIP:        "10.0.0.2",
+			expectedErr:     ErrIPBlocked,
+			expectedVerdict: "flagged",
+		},
+		{
+			description: "Lookup Failed",
+			clientIP:    "10.0.0.9",
+		},
+		{
+			description: "Lookup Failed Closed",
+			config:      IPReputationConfig{FailClosed: true},
+			clientIP:    "10.0.0.9",
+			expectedErr: ErrIPReputationFailed,
+		},
+		{
+			description: "Invalid IP",
+			clientIP:    "nope",
+		},
+		{
+			description: "Invalid IP Closed",
+			config:      IPReputationConfig{FailClosed: true},
+			clientIP:    "nope",
+			expectedErr: ErrIPReputationFailed,
+		},
+		{
+			description: "No Auth",
+			noAuth:      true,
+			expectedErr: ErrNoAuth,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			measures := &IPReputationMeasures{
+				Verdicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testIPReputation",
+					Help: "testIPReputation",
+				}, []string{ServerLabel, VerdictLabel}),
+			}
+			v, err := NewIPReputationValidator(reputation, tc.config, measures, "test")
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			if !tc.noAuth {
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Request: bascule.Request{ClientIP: tc.clientIP},
+				})
+			}
+			err = v.Check(ctx, nil)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.expectedVerdict != "" {
+				assert.Equal(1.0, testutil.ToFloat64(measures.Verdicts.With(prometheus.Labels{
+					ServerLabel:  "test",
+					VerdictLabel: tc.expectedVerdict,
+				})))
+			}
+		})
+	}
+}
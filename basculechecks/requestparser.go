@@ -0,0 +1,135 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"regexp"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// EndpointBucketer maps a request's escaped URL path to an endpoint bucket,
+// so values that change from one request to the next, like device IDs, don't
+// end up in metric labels or audit records.
+type EndpointBucketer interface {
+	Bucket(escapedPath string) string
+}
+
+// EndpointBucketerFunc makes it so any function with the same signature as
+// EndpointBucketer's Bucket function implements EndpointBucketer.
+type EndpointBucketerFunc func(string) string
+
+func (ebf EndpointBucketerFunc) Bucket(escapedPath string) string {
+	return ebf(escapedPath)
+}
+
+// RegexEndpointBucketer returns an EndpointBucketer that uses the first
+// regular expression matching the start of the path as its bucket, with
+// spaces replaced by underscores.  Paths that match none are bucketed as
+// NotRecognizedEndpoint, and every path is NoneEndpoint if there are no
+// expressions.
+func RegexEndpointBucketer(endpoints []*regexp.Regexp) EndpointBucketer {
+	return EndpointBucketerFunc(func(escapedPath string) string {
+		return determineEndpointMetric(endpoints, escapedPath)
+	})
+}
+
+// RequestInfo is the information about a request's client that a
+// RequestParser gets from its Authentication.
+type RequestInfo struct {
+	// ClientID is the token's principal, after the ClientIDTransform.
+	ClientID string
+
+	// Method is the request's HTTP method.
+	Method string
+
+	// PartnerIDs are the token's partner IDs, and Partner is what the
+	// PartnerClassifier makes of them.
+	PartnerIDs []string
+	Partner    string
+
+	// Endpoint is the bucket of the request's URL.
+	Endpoint string
+}
+
+// RequestParser gets the client ID, partners, and endpoint bucket of a
+// request from its Authentication, the way the MetricValidator does for its
+// metric labels, so validators, audit sinks, and middleware can describe a
+// request the same way.  The zero value uses the defaults.
+type RequestParser struct {
+	// PartnerKeyPath is the location of the partner IDs in the token's
+	// attributes.  Defaults to PartnerKeys().
+	PartnerKeyPath []string
+
+	// FallbackPartnerKeyPaths are searched in order for the partner IDs
+	// when they aren't at the PartnerKeyPath.
+	FallbackPartnerKeyPaths [][]string
+
+	// Endpoints buckets the request's URL.  If it's nil, every request is
+	// NoneEndpoint.
+	Endpoints EndpointBucketer
+
+	// ClientID changes the principal before it's used as the ClientID.
+	ClientID ClientIDTransform
+
+	// Partners classifies the partner IDs.  Defaults to
+	// DefaultPartnerClassifier().
+	Partners PartnerClassifier
+}
+
+// Parse gets the RequestInfo from the Authentication given.  If something
+// needed is missing, the RequestInfo gathered so far is returned with an
+// error, in the order ClientID, Method, partners, then Endpoint.
+func (p RequestParser) Parse(auth bascule.Authentication) (RequestInfo, error) {
+	var info RequestInfo
+	if auth.Token == nil {
+		return info, ErrNoToken
+	}
+	info.ClientID = auth.Token.Principal()
+	if p.ClientID != nil {
+		info.ClientID = p.ClientID(info.ClientID)
+	}
+	if len(auth.Request.Method) == 0 {
+		return info, ErrNoMethod
+	}
+	info.Method = auth.Request.Method
+	if auth.Token.Attributes() == nil {
+		return info, ErrNilAttributes
+	}
+
+	partnerIDs, err := FindPartnerIDs(auth.Token.Attributes(),
+		partnerKeyPaths(p.PartnerKeyPath, p.FallbackPartnerKeyPaths)...)
+	if err != nil {
+		return info, err
+	}
+	info.PartnerIDs = partnerIDs
+	if p.Partners != nil {
+		info.Partner = p.Partners.Classify(partnerIDs)
+	} else {
+		info.Partner = DeterminePartnerMetric(partnerIDs)
+	}
+
+	if auth.Request.URL == nil {
+		return info, ErrNoURL
+	}
+	info.Endpoint = NoneEndpoint
+	if p.Endpoints != nil {
+		info.Endpoint = p.Endpoints.Bucket(auth.Request.URL.EscapedPath())
+	}
+	return info, nil
+}
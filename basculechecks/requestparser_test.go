@@ -0,0 +1,119 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexEndpointBucketer(t *testing.T) {
+	assert := assert.New(t)
+	b := RegexEndpointBucketer([]*regexp.Regexp{
+		regexp.MustCompile(`/device/[^/]+/stat`),
+		regexp.MustCompile(`/device/[^/]+ config`),
+	})
+	assert.Equal(`/device/[^/]+/stat`, b.Bucket("/device/mac:112233445566/stat"))
+	assert.Equal(`/device/[^/]+_config`, b.Bucket("/device/mac:112233445566 config"))
+	assert.Equal(NotRecognizedEndpoint, b.Bucket("/api/device/mac:112233445566/stat"))
+	assert.Equal(NoneEndpoint, RegexEndpointBucketer(nil).Bucket("/device"))
+}
+
+func TestRequestParser(t *testing.T) {
+	goodURL := &url.URL{Path: "/device/mac:112233445566/stat"}
+	tests := []struct {
+		description  string
+		parser       RequestParser
+		attributes   map[string]interface{}
+		expectedInfo RequestInfo
+		expectedErr  error
+	}{
+		{
+			description: "Defaults",
+			attributes: map[string]interface{}{
+				"allowedResources": map[string]interface{}{
+					"allowedPartners": []string{"comcast"},
+				},
+			},
+			expectedInfo: RequestInfo{
+				ClientID:   "alice",
+				Method:     "GET",
+				PartnerIDs: []string{"comcast"},
+				Partner:    "comcast",
+				Endpoint:   NoneEndpoint,
+			},
+		},
+		{
+			description: "Configured",
+			parser: RequestParser{
+				PartnerKeyPath: []string{"ext", "partners"},
+				Endpoints: EndpointBucketerFunc(func(p string) string {
+					return strings.Split(p, "/")[1]
+				}),
+				ClientID: strings.ToUpper,
+				Partners: JoinedPartnerClassifier("|"),
+			},
+			attributes: map[string]interface{}{
+				"ext": map[string]interface{}{
+					"partners": []string{"sky", "comcast"},
+				},
+			},
+			expectedInfo: RequestInfo{
+				ClientID:   "ALICE",
+				Method:     "GET",
+				PartnerIDs: []string{"sky", "comcast"},
+				Partner:    "comcast|sky",
+				Endpoint:   "device",
+			},
+		},
+		{
+			description: "Missing partners",
+			parser:      RequestParser{PartnerKeyPath: []string{"ext", "partners"}},
+			attributes:  map[string]interface{}{},
+			expectedInfo: RequestInfo{
+				ClientID: "alice",
+				Method:   "GET",
+			},
+			expectedErr: ErrGettingPartnerIDs,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			info, err := tc.parser.Parse(bascule.Authentication{
+				Token: bascule.NewToken("test", "alice", bascule.NewAttributes(tc.attributes)),
+				Request: bascule.Request{
+					URL:    goodURL,
+					Method: "GET",
+				},
+			})
+			assert.Equal(tc.expectedInfo, info)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Contains(err.Error(), "[ext partners]")
+		})
+	}
+}
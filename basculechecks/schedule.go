@@ -0,0 +1,319 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+
+	// ErrOutsideSchedule is returned when a schedule rule doesn't allow the
+	// request at the current time.
+	ErrOutsideSchedule = errWithReason{
+		err:    errors.New("request not allowed at this time"),
+		reason: ScheduleDenied,
+	}
+)
+
+// Schedule is a cron-like expression of the minutes during which a rule is in
+// effect.  It has the five standard fields, minute (0-59), hour (0-23), day of
+// the month (1-31), month (1-12), and day of the week (0-6, with 0 or 7 for
+// Sunday), each of which can be "*", a value, a range like "1-5", a list like
+// "1,3,5", or any of those with a step like "*/15".  As with cron, if both
+// day fields are restricted, either matching is enough.  For example,
+// "* 9-17 * * 1-5" is business hours and "* * 20-31 12 *" is a year end
+// change freeze.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = []scheduleField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// ParseSchedule parses a five field cron-like expression.
+func ParseSchedule(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(scheduleFields) {
+		return Schedule{}, fmt.Errorf("%w [%v]: expected %d fields", ErrInvalidSchedule, expr, len(scheduleFields))
+	}
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseScheduleField(f, scheduleFields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("%w [%v]: %v", ErrInvalidSchedule, expr, err)
+		}
+		sets[i] = set
+	}
+	// 7 is another way of saying Sunday.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return Schedule{
+		minute:        sets[0],
+		hour:          sets[1],
+		dom:           sets[2],
+		month:         sets[3],
+		dow:           sets[4],
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}, nil
+}
+
+// parseScheduleField returns the set of values the field matches as a bit
+// set.
+func parseScheduleField(field string, f scheduleField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step [%v] for %v", stepPart, f.name)
+			}
+		}
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value [%v] for %v", lowPart, f.name)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid value [%v] for %v", highPart, f.name)
+				}
+			} else if hasStep {
+				high = f.max
+			}
+		}
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("[%v] out of range %d-%d for %v", rangePart, f.min, f.max, f.name)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches returns true if the minute of the time given is in the schedule.
+func (s Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 ||
+		s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// ScheduleAction is what a ScheduleRule does with the requests it applies to.
+type ScheduleAction string
+
+const (
+	// DenyDuring rejects requests while the schedule matches, such as during
+	// a change freeze.
+	DenyDuring ScheduleAction = "deny"
+
+	// AllowDuring rejects requests while the schedule doesn't match, such as
+	// outside of business hours.
+	AllowDuring ScheduleAction = "allow"
+)
+
+// ScheduleRule restricts when the requests it applies to are allowed.  Empty
+// filters apply to every request.
+type ScheduleRule struct {
+	// Schedule is the cron-like expression of when the rule is in effect.
+	// See Schedule for the syntax.
+	Schedule string
+
+	// Action is whether requests are denied or only allowed during the
+	// schedule.  Defaults to DenyDuring.
+	Action ScheduleAction
+
+	// Endpoint is a regular expression the request path must match from its
+	// start.
+	Endpoint string
+
+	// Methods are the HTTP methods the rule applies to, such as the writes
+	// during a change freeze.
+	Methods []string
+
+	// Principals are the token principals the rule applies to.
+	Principals []string
+
+	// BypassCapabilities are capabilities, such as a break-glass capability,
+	// whose holders the rule doesn't apply to.
+	BypassCapabilities []string
+}
+
+// ScheduleConfig configures a ScheduleValidator.
+type ScheduleConfig struct {
+	Rules []ScheduleRule
+
+	// Location is the time zone schedules are in.  Defaults to UTC.
+	Location *time.Location
+}
+
+type scheduleRule struct {
+	schedule   Schedule
+	action     ScheduleAction
+	endpoint   *regexp.Regexp
+	methods    map[string]bool
+	principals map[string]bool
+	bypass     map[string]bool
+}
+
+// ScheduleValidator rejects requests that schedule rules don't allow at the
+// current time.  Every rule that applies to a request must allow it.
+type ScheduleValidator struct {
+	rules    []scheduleRule
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewScheduleValidator parses the rules in the config into a
+// ScheduleValidator.
+func NewScheduleValidator(config ScheduleConfig) (*ScheduleValidator, error) {
+	s := &ScheduleValidator{
+		location: config.Location,
+		now:      time.Now,
+	}
+	if s.location == nil {
+		s.location = time.UTC
+	}
+	for i, r := range config.Rules {
+		schedule, err := ParseSchedule(r.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rule := scheduleRule{
+			schedule:   schedule,
+			action:     r.Action,
+			methods:    make(map[string]bool, len(r.Methods)),
+			principals: make(map[string]bool, len(r.Principals)),
+			bypass:     make(map[string]bool, len(r.BypassCapabilities)),
+		}
+		switch rule.action {
+		case "":
+			rule.action = DenyDuring
+		case DenyDuring, AllowDuring:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action [%v]", i, r.Action)
+		}
+		if r.Endpoint != "" {
+			rule.endpoint, err = regexp.Compile(r.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: failed to compile endpoint [%v]: %w", i, r.Endpoint, err)
+			}
+		}
+		for _, m := range r.Methods {
+			rule.methods[strings.ToUpper(m)] = true
+		}
+		for _, p := range r.Principals {
+			rule.principals[p] = true
+		}
+		for _, c := range r.BypassCapabilities {
+			rule.bypass[c] = true
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s, nil
+}
+
+// Check rejects the request if a rule that applies to it doesn't allow it at
+// the current time.
+func (s *ScheduleValidator) Check(ctx context.Context, token bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return ErrNoAuth
+	}
+	now := s.now().In(s.location)
+	var capabilities []string
+	for i, r := range s.rules {
+		if !r.appliesTo(auth, token) {
+			continue
+		}
+		if len(r.bypass) > 0 && token != nil && capabilities == nil {
+			capabilities, _ = getCapabilities(token.Attributes(), nil)
+		}
+		if r.bypassed(capabilities) {
+			continue
+		}
+		if r.schedule.Matches(now) != (r.action == AllowDuring) {
+			return fmt.Errorf("%w: rule %d", ErrOutsideSchedule, i)
+		}
+	}
+	return nil
+}
+
+func (r scheduleRule) appliesTo(auth bascule.Authentication, token bascule.Token) bool {
+	if len(r.methods) > 0 && !r.methods[strings.ToUpper(auth.Request.Method)] {
+		return false
+	}
+	if len(r.principals) > 0 && (token == nil || !r.principals[token.Principal()]) {
+		return false
+	}
+	if r.endpoint != nil {
+		if auth.Request.URL == nil {
+			return false
+		}
+		idxs := r.endpoint.FindStringIndex(auth.Request.URL.EscapedPath())
+		if len(idxs) == 0 || idxs[0] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r scheduleRule) bypassed(capabilities []string) bool {
+	for _, c := range capabilities {
+		if r.bypass[c] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*ScheduleValidator)(nil)
+
+func TestParseSchedule(t *testing.T) {
+	// a Monday
+	monday := time.Date(2022, time.December, 19, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		description string
+		expr        string
+		matches     []time.Time
+		misses      []time.Time
+		expectedErr bool
+	}{
+		{
+			description: "Always",
+			expr:        "* * * * *",
+			matches:     []time.Time{monday, monday.Add(13 * time.Hour)},
+		},
+		{
+			description: "Business Hours",
+			expr:        "* 9-17 * * 1-5",
+			matches:     []time.Time{monday},
+			misses:      []time.Time{monday.Add(8 * time.Hour), monday.AddDate(0, 0, 5)},
+		},
+		{
+			description: "Steps And Lists",
+			expr:        "*/15,31 10 * * *",
+			matches:     []time.Time{monday, monday.Add(time.Minute), monday.Add(-15 * time.Minute)},
+			misses:      []time.Time{monday.Add(2 * time.Minute)},
+		},
+		{
+			description: "Step From Value",
+			expr:        "0 20/2 * * *",
+			matches:     []time.Time{monday.Add(9*time.Hour + 30*time.Minute)},
+			misses:      []time.Time{monday.Add(10*time.Hour + 30*time.Minute)},
+		},
+		{
+			description: "Sunday As Seven",
+			expr:        "* * * * 7",
+			matches:     []time.Time{monday.AddDate(0, 0, -1)},
+			misses:      []time.Time{monday},
+		},
+		{
+			description: "Either Day Field",
+			expr:        "* * 1 * 1",
+			matches:     []time.Time{monday, time.Date(2022, time.December, 1, 0, 0, 0, 0, time.UTC)},
+			misses:      []time.Time{monday.AddDate(0, 0, 1)},
+		},
+		{
+			description: "Change Freeze",
+			expr:        "* * 20-31 12 *",
+			matches:     []time.Time{monday.AddDate(0, 0, 1)},
+			misses:      []time.Time{monday, monday.AddDate(0, 0, 13)},
+		},
+		{
+			description: "Too Few Fields",
+			expr:        "* * * *",
+			expectedErr: true,
+		},
+		{
+			description: "Out Of Range",
+			expr:        "60 * * * *",
+			expectedErr: true,
+		},
+		{
+			description: "Backwards Range",
+			expr:        "* 5-1 * * *",
+			expectedErr: true,
+		},
+		{
+			description: "Bad Step",
+			expr:        "*/0 * * * *",
+			expectedErr: true,
+		},
+		{
+			description: "Bad Value",
+			expr:        "* * * jan *",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, err := ParseSchedule(tc.expr)
+			if tc.expectedErr {
+				assert.ErrorIs(err, ErrInvalidSchedule)
+				return
+			}
+			require.NoError(t, err)
+			for _, m := range tc.matches {
+				assert.True(s.Matches(m), m.String())
+			}
+			for _, m := range tc.misses {
+				assert.False(s.Matches(m), m.String())
+			}
+		})
+	}
+}
+
+func TestNewScheduleValidator(t *testing.T) {
+	tests := []struct {
+		description string
+		rule        ScheduleRule
+	}{
+		{
+			description: "Invalid Schedule",
+			rule:        ScheduleRule{Schedule: "*"},
+		},
+		{
+			description: "Unknown Action",
+			rule:        ScheduleRule{Schedule: "* * * * *", Action: "maybe"},
+		},
+		{
+			description: "Invalid Endpoint",
+			rule:        ScheduleRule{Schedule: "* * * * *", Endpoint: "("},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			s, err := NewScheduleValidator(ScheduleConfig{Rules: []ScheduleRule{tc.rule}})
+			assert.Error(t, err)
+			assert.Nil(t, s)
+		})
+	}
+
+	s, err := NewScheduleValidator(ScheduleConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, s.location)
+}
+
+func TestScheduleValidator(t *testing.T) {
+	config := ScheduleConfig{
+		Rules: []ScheduleRule{
+			{
+				// change freeze
+				Schedule:           "* * 20-31 12 *",
+				Methods:            []string{"put", "POST", "DELETE"},
+				BypassCapabilities: []string{"break-glass"},
+			},
+			{
+				Schedule:   "* 9-17 * * 1-5",
+				Action:     AllowDuring,
+				Endpoint:   "/reports",
+				Principals: []string{"analyst"},
+			},
+		},
+	}
+	monday := time.Date(2022, time.December, 19, 10, 30, 0, 0, time.UTC)
+	freeze := monday.AddDate(0, 0, 1)
+	tests := []struct {
+		description  string
+		now          time.Time
+		method       string
+		path         string
+		principal    string
+		capabilities []string
+		noAuth       bool
+		expectedErr  error
+	}{
+		{
+			description: "Write Before Freeze",
+			now:         monday,
+			method:      "POST",
+		},
+		{
+			description: "Read During Freeze",
+			now:         freeze,
+			method:      "GET",
+		},
+		{
+			description: "Write During Freeze",
+			now:         freeze,
+			method:      "PUT",
+			expectedErr: ErrOutsideSchedule,
+		},
+		{
+			description:  "Break Glass During Freeze",
+			now:          freeze,
+			method:       "DELETE",
+			capabilities: []string{"read", "break-glass"},
+		},
+		{
+			description:  "Other Capabilities During Freeze",
+			now:          freeze,
+			method:       "DELETE",
+			capabilities: []string{"read"},
+			expectedErr:  ErrOutsideSchedule,
+		},
+		{
+			description: "Reports In Hours",
+			now:         monday,
+			method:      "GET",
+			path:        "/reports/daily",
+			principal:   "analyst",
+		},
+		{
+			description: "Reports After Hours",
+			now:         monday.Add(8 * time.Hour),
+			method:      "GET",
+			path:        "/reports/daily",
+			principal:   "analyst",
+			expectedErr: ErrOutsideSchedule,
+		},
+		{
+			description: "Other Principal After Hours",
+			now:         monday.Add(8 * time.Hour),
+			method:      "GET",
+			path:        "/reports/daily",
+			principal:   "alice",
+		},
+		{
+			description: "Endpoint Must Match From Start",
+			now:         monday.Add(8 * time.Hour),
+			method:      "GET",
+			path:        "/v1/reports",
+			principal:   "analyst",
+		},
+		{
+			description: "No Auth",
+			noAuth:      true,
+			expectedErr: ErrNoAuth,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			s, err := NewScheduleValidator(config)
+			require.NoError(err)
+			s.now = func() time.Time { return tc.now }
+
+			principal := tc.principal
+			if principal == "" {
+				principal = "alice"
+			}
+			token := bascule.NewToken("test", principal,
+				bascule.NewAttributes(buildDummyAttributes(CapabilityKeys(), tc.capabilities)))
+			ctx := context.Background()
+			if !tc.noAuth {
+				path := tc.path
+				if path == "" {
+					path = "/things"
+				}
+				u, err := url.Parse(path)
+				require.NoError(err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   token,
+					Request: bascule.Request{URL: u, Method: tc.method},
+				})
+			}
+			err = s.Check(ctx, token)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedErr == ErrOutsideSchedule {
+				assert.Equal(bascule.CapabilityClass, bascule.ClassOf(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+var (
+	ErrNilScopeRegistry = errors.New("scope registry cannot be nil")
+
+	// ErrScopeNotApproved is returned when a token grants a scope its client
+	// was never approved for.
+	ErrScopeNotApproved = errWithReason{
+		err:    errors.New("scope not approved for client"),
+		reason: ScopeNotApproved,
+	}
+
+	// ErrScopeRegistryUnavailable is returned when the registry can't be
+	// reached.
+	ErrScopeRegistryUnavailable = bascule.NewClassError(bascule.UnavailableClass, "scope registry unavailable")
+)
+
+// ScopeRegistry looks up the scopes a client has been approved for.  The
+// bool returned is false if the client isn't registered.
+type ScopeRegistry interface {
+	AllowedScopes(ctx context.Context, clientID string) ([]string, bool, error)
+}
+
+// ScopeRegistryFunc makes it so any function with the same signature as
+// AllowedScopes implements ScopeRegistry.
+type ScopeRegistryFunc func(context.Context, string) ([]string, bool, error)
+
+// AllowedScopes runs the function.
+func (f ScopeRegistryFunc) AllowedScopes(ctx context.Context, clientID string) ([]string, bool, error) {
+	return f(ctx, clientID)
+}
+
+// ScopeMap is a ScopeRegistry kept in memory, from client ID to the scopes
+// approved for it.
+type ScopeMap map[string][]string
+
+// AllowedScopes returns the scopes in the map for the client.
+func (m ScopeMap) AllowedScopes(_ context.Context, clientID string) ([]string, bool, error) {
+	scopes, ok := m[clientID]
+	return scopes, ok, nil
+}
+
+// ScopeConfig configures a ScopeValidator.
+type ScopeConfig struct {
+	// ClientIDClaims are the claims that can hold the client the token was
+	// issued to.  The first one in the token is used.  Defaults to
+	// "client_id" and "azp".
+	ClientIDClaims []string
+
+	// ScopeClaims are the claims that can hold the scopes granted, either as
+	// a space separated string or a list.  The first one in the token is
+	// used.  Defaults to "scope" and "scp".
+	ScopeClaims []string
+}
+
+// ScopeValidator rejects tokens granting scopes that their client was never
+// approved for in the registry, protecting APIs from an identity provider
+// that is more permissive than intended.  Tokens without a client ID or
+// scopes aren't checked.
+type ScopeValidator struct {
+	registry ScopeRegistry
+	config   ScopeConfig
+}
+
+// NewScopeValidator creates a ScopeValidator.  The registry cannot be nil.
+func NewScopeValidator(registry ScopeRegistry, config ScopeConfig) (*ScopeValidator, error) {
+	if registry == nil {
+		return nil, ErrNilScopeRegistry
+	}
+	if len(config.ClientIDClaims) == 0 {
+		config.ClientIDClaims = []string{"client_id", "azp"}
+	}
+	if len(config.ScopeClaims) == 0 {
+		config.ScopeClaims = []string{"scope", "scp"}
+	}
+	return &ScopeValidator{
+		registry: registry,
+		config:   config,
+	}, nil
+}
+
+// Check compares the scopes granted by the token to the ones approved for
+// its client.
+func (s *ScopeValidator) Check(ctx context.Context, token bascule.Token) error {
+	if token == nil || token.Attributes() == nil {
+		return nil
+	}
+	clientID := firstClaim(token.Attributes(), s.config.ClientIDClaims)
+	if clientID == nil {
+		return nil
+	}
+	granted := scopesOf(firstClaim(token.Attributes(), s.config.ScopeClaims))
+	if len(granted) == 0 {
+		return nil
+	}
+
+	client := cast.ToString(clientID)
+	allowed, ok, err := s.registry.AllowedScopes(ctx, client)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrScopeRegistryUnavailable, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: client [%v] not registered", ErrScopeNotApproved, client)
+	}
+	approved := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		approved[a] = true
+	}
+	var unapproved []string
+	for _, g := range granted {
+		if !approved[g] {
+			unapproved = append(unapproved, g)
+		}
+	}
+	if len(unapproved) > 0 {
+		return fmt.Errorf("%w: client [%v] granted %v", ErrScopeNotApproved, client, unapproved)
+	}
+	return nil
+}
+
+// firstClaim returns the value of the first claim in the attributes.
+func firstClaim(attributes bascule.Attributes, claims []string) interface{} {
+	for _, c := range claims {
+		if v, ok := attributes.Get(c); ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// scopesOf returns the scopes in a space separated string or a list.
+func scopesOf(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return strings.Fields(s)
+	}
+	scopes, _ := cast.ToStringSliceE(v)
+	return scopes
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechecks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ bascule.Validator = (*ScopeValidator)(nil)
+	_ ScopeRegistry     = ScopeMap(nil)
+)
+
+func TestNewScopeValidator(t *testing.T) {
+	assert := assert.New(t)
+	s, err := NewScopeValidator(nil, ScopeConfig{})
+	assert.ErrorIs(err, ErrNilScopeRegistry)
+	assert.Nil(s)
+
+	s, err = NewScopeValidator(ScopeMap{}, ScopeConfig{})
+	assert.NoError(err)
+	assert.Equal([]string{"client_id", "azp"}, s.config.ClientIDClaims)
+	assert.Equal([]string{"scope", "scp"}, s.config.ScopeClaims)
+}
+
+func TestScopeValidator(t *testing.T) {
+	errRegistry := errors.New("registry down")
+	registry := ScopeMap{
+		"reporting": {"read:reports", "read:things"},
+		"admin-ui":  {"read:things", "write:things"},
+	}
+	tests := []struct {
+		description   string
+		attributes    map[string]interface{}
+		registry      ScopeRegistry
+		expectedErr   error
+		expectedClass bascule.ErrorClass
+	}{
+		{
+			description: "Approved String Scopes",
+			attributes: map[string]interface{}{
+				"client_id": "reporting",
+				"scope":     "read:reports read:things",
+			},
+		},
+		{
+			description: "Approved List Scopes",
+			attributes: map[string]interface{}{
+				"azp": "admin-ui",
+				"scp": []interface{}{"write:things"},
+			},
+		},
+		{
+			description: "Unapproved Scope",
+			attributes: map[string]interface{}{
+				"client_id": "reporting",
+				"scope":     "read:reports write:things",
+			},
+			expectedErr:   ErrScopeNotApproved,
+			expectedClass: bascule.CapabilityClass,
+		},
+		{
+			description: "Unregistered Client",
+			attributes: map[string]interface{}{
+				"client_id": "unknown",
+				"scope":     "read:things",
+			},
+			expectedErr:   ErrScopeNotApproved,
+			expectedClass: bascule.CapabilityClass,
+		},
+		{
+			description: "Registry Error",
+			attributes: map[string]interface{}{
+				"client_id": "reporting",
+				"scope":     "read:things",
+			},
+			registry: ScopeRegistryFunc(func(context.Context, string) ([]string, bool, error) {
+				return nil, false, errRegistry
+			}),
+			expectedErr:   ErrScopeRegistryUnavailable,
+			expectedClass: bascule.UnavailableClass,
+		},
+		{
+			description: "No Client ID",
+			attributes:  map[string]interface{}{"scope": "write:everything"},
+		},
+		{
+			description: "No Scopes",
+			attributes:  map[string]interface{}{"client_id": "unknown"},
+		},
+		{
+			description: "No Attributes",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r := tc.registry
+			if r == nil {
+				r = registry
+			}
+			s, err := NewScopeValidator(r, ScopeConfig{})
+			require.NoError(t, err)
+
+			var attributes bascule.Attributes
+			if tc.attributes != nil {
+				attributes = bascule.NewAttributes(tc.attributes)
+			}
+			err = s.Check(context.Background(), bascule.NewToken("test", "alice", attributes))
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Equal(tc.expectedClass, bascule.ClassOf(err))
+		})
+	}
+}
@@ -21,8 +21,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
 )
 
 // AllowAll returns a Validator that never returns an error.
@@ -68,6 +70,20 @@ func NonEmptyPrincipal() bascule.ValidatorFunc {
 	}
 }
 
+// ValidKind returns a Validator that checks that the token's kind is one of
+// the given kinds.
+func ValidKind(kinds ...bascule.TokenKind) bascule.ValidatorFunc {
+	return func(_ context.Context, token bascule.Token) error {
+		k := bascule.KindOf(token)
+		for _, vk := range kinds {
+			if k == vk {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid token kind [%v]", k)
+	}
+}
+
 // AttributeList returns a Validator that runs checks against the content found
 // in the key given.  It runs every check and returns all errors it finds.
 func AttributeList(keys []string, checks ...func(context.Context, []interface{}) error) bascule.ValidatorFunc {
@@ -93,3 +109,65 @@ func AttributeList(keys []string, checks ...func(context.Context, []interface{})
 		return fmt.Errorf("attribute checks of keys %v failed: %v", keys, errs)
 	}
 }
+
+// AttributeContains returns a Validator that checks that the list found in the
+// key given contains the value given, such as a specific role or capability.
+func AttributeContains(keys []string, value string) bascule.ValidatorFunc {
+	return func(_ context.Context, token bascule.Token) error {
+		val, ok := bascule.GetNestedAttribute(token.Attributes(), keys...)
+		if !ok {
+			return fmt.Errorf("couldn't find attribute with keys %v", keys)
+		}
+		vals, err := cast.ToStringSliceE(val)
+		if err != nil {
+			return fmt.Errorf("unexpected attribute value, expected a string slice but received: %T", val)
+		}
+		for _, v := range vals {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("attribute with keys %v doesn't contain [%v]", keys, value)
+	}
+}
+
+// DeviceIDGroup is the name of the regular expression group that DeviceEndpoints
+// compares against the device token's principal.
+const DeviceIDGroup = "deviceID"
+
+// DeviceEndpoints returns a Validator that restricts device tokens to the
+// endpoints given, matched against the request's URL path.  If an endpoint
+// has a group named DeviceIDGroup, the device id captured must match the
+// token's principal, so a device can only reach its own resources.  Tokens
+// that aren't device tokens are not checked.
+func DeviceEndpoints(endpoints ...*regexp.Regexp) bascule.ValidatorFunc {
+	return func(ctx context.Context, token bascule.Token) error {
+		if bascule.KindOf(token) != bascule.DeviceKind {
+			return nil
+		}
+		auth, ok := bascule.FromContext(ctx)
+		if !ok || auth.Request.URL == nil {
+			return errors.New("no request found for device token")
+		}
+		path := auth.Request.URL.EscapedPath()
+		for _, e := range endpoints {
+			if e == nil {
+				continue
+			}
+			m := e.FindStringSubmatch(path)
+			if m == nil {
+				continue
+			}
+			i := e.SubexpIndex(DeviceIDGroup)
+			if i < 0 {
+				return nil
+			}
+			id, err := bascule.NormalizeDeviceID(m[i])
+			if err == nil && id == token.Principal() {
+				return nil
+			}
+			return fmt.Errorf("device [%v] cannot access resources of device [%v]", token.Principal(), m[i])
+		}
+		return fmt.Errorf("endpoint [%v] not allowed for device tokens", path)
+	}
+}
@@ -20,10 +20,12 @@ package basculechecks
 import (
 	"context"
 	"errors"
+	"net/url"
+	"regexp"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
-	"github.com/xmidt-org/bascule"
 )
 
 func TestAllowAll(t *testing.T) {
@@ -63,6 +65,16 @@ func TestNonEmptyPrincipal(t *testing.T) {
 	assert.NotNil(err)
 }
 
+func TestValidKind(t *testing.T) {
+	assert := assert.New(t)
+	f := ValidKind(bascule.ServiceKind, bascule.DeviceKind)
+	err := f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{
+		bascule.TokenKindKey: bascule.ServiceKind})))
+	assert.NoError(err)
+	err = f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{})))
+	assert.Error(err)
+}
+
 func TestAttributeList(t *testing.T) {
 	testErr := errors.New("test err")
 	failFunc := func(_ context.Context, _ []interface{}) error {
@@ -94,3 +106,104 @@ func TestAttributeList(t *testing.T) {
 		"subkey": []interface{}{}}})))
 	assert.Error(err)
 }
+
+func TestAttributeContains(t *testing.T) {
+	assert := assert.New(t)
+	f := AttributeContains([]string{"roles"}, "admin")
+
+	err := f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{
+		"roles": []interface{}{"user", "admin"}})))
+	assert.NoError(err)
+
+	err = f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{
+		"roles": []string{"user"}})))
+	assert.Error(err)
+
+	err = f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{
+		"roles": map[string]interface{}{}})))
+	assert.Error(err)
+
+	err = f(context.Background(), bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{})))
+	assert.Error(err)
+}
+
+func TestDeviceEndpoints(t *testing.T) {
+	f := DeviceEndpoints(
+		regexp.MustCompile(`^/api/v2/device/(?P<deviceID>[^/]+)/stat$`),
+		nil,
+		regexp.MustCompile(`^/api/v2/hook$`),
+	)
+	device := bascule.NewToken("device", "mac:112233445566", bascule.NewAttributes(map[string]interface{}{
+		bascule.TokenKindKey: bascule.DeviceKind,
+	}))
+	user := bascule.NewToken("jwt", "user", bascule.NewAttributes(map[string]interface{}{
+		bascule.TokenKindKey: bascule.UserKind,
+	}))
+	tests := []struct {
+		description string
+		token       bascule.Token
+		path        string
+		noRequest   bool
+		expectedErr bool
+	}{
+		{
+			description: "Own Device",
+			token:       device,
+			path:        "/api/v2/device/MAC:11-22-33-44-55-66/stat",
+		},
+		{
+			description: "No Device ID Group",
+			token:       device,
+			path:        "/api/v2/hook",
+		},
+		{
+			description: "Other Device",
+			token:       device,
+			path:        "/api/v2/device/mac:665544332211/stat",
+			expectedErr: true,
+		},
+		{
+			description: "Invalid Device ID",
+			token:       device,
+			path:        "/api/v2/device/bad/stat",
+			expectedErr: true,
+		},
+		{
+			description: "Endpoint Not Allowed",
+			token:       device,
+			path:        "/api/v2/device/mac:112233445566/config",
+			expectedErr: true,
+		},
+		{
+			description: "No Request",
+			token:       device,
+			noRequest:   true,
+			expectedErr: true,
+		},
+		{
+			description: "Not A Device",
+			token:       user,
+			path:        "/api/v2/device/mac:112233445566/config",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := context.Background()
+			if !tc.noRequest {
+				u, err := url.ParseRequestURI(tc.path)
+				assert.NoError(err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   tc.token,
+					Request: bascule.Request{URL: u, Method: "GET"},
+				})
+			}
+			err := f(ctx, tc.token)
+			if tc.expectedErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
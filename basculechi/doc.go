@@ -0,0 +1,29 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+/*
+Package basculechi adapts the basculehttp middleware to chi routers.  chi
+middleware is net/http middleware, so the constructor, enforcer, and listener
+decorator can be given to a router's Use as they are; this package builds the
+whole chain for it, and the Authentication is in the request's context as
+usual.
+
+	mw, err := basculechi.New(basculehttp.WithConstructorOptions(...), ...)
+	r := chi.NewRouter()
+	r.Use(mw)
+*/
+
+package basculechi
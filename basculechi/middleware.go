@@ -0,0 +1,48 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechi
+
+import (
+	"net/http"
+
+	"github.com/s-srakshe/bascule/basculehttp"
+)
+
+// Middlewares is the type of a chi router's middleware stack.
+type Middlewares []func(http.Handler) http.Handler
+
+// New builds the chain of basculehttp middleware, checked as NewAuthChain
+// checks it, as a single chi middleware.
+func New(options ...basculehttp.AuthOption) (func(http.Handler) http.Handler, error) {
+	chain, err := basculehttp.NewAuthChain(options...)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Then, nil
+}
+
+// Stack returns the constructor, enforcer, and listener decorator as
+// separate chi middleware, for routers that need to add their own
+// middleware between them, e.g. r.Use(stack...).
+func Stack(c []basculehttp.COption, e []basculehttp.EOption, l []basculehttp.LOption) Middlewares {
+	return Middlewares{
+		basculehttp.NewConstructor(c...),
+		basculehttp.NewEnforcer(e...),
+		basculehttp.NewListenerDecoratorWithOptions(l...),
+	}
+}
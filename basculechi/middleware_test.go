@@ -0,0 +1,96 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculechi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func principalHandler(w http.ResponseWriter, r *http.Request) {
+	auth, ok := bascule.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write([]byte(auth.Token.Principal()))
+}
+
+func testCOptions() []basculehttp.COption {
+	return []basculehttp.COption{
+		basculehttp.WithTokenFactory(basculehttp.BasicAuthorization,
+			basculehttp.BasicTokenFactory{"alice": "secret"}),
+	}
+}
+
+func testEOptions() []basculehttp.EOption {
+	return []basculehttp.EOption{
+		basculehttp.WithRules(basculehttp.BasicAuthorization, basculechecks.AllowAll()),
+	}
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mw, err := New()
+	assert.ErrorIs(err, basculehttp.ErrNoTokenFactories)
+	assert.Nil(mw)
+
+	mw, err = New(
+		basculehttp.WithConstructorOptions(testCOptions()...),
+		basculehttp.WithEnforcerOptions(testEOptions()...),
+	)
+	require.NoError(err)
+	h := mw(http.HandlerFunc(principalHandler))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.SetBasicAuth("alice", "secret")
+	h.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("alice", w.Body.String())
+}
+
+func TestStack(t *testing.T) {
+	assert := assert.New(t)
+	stack := Stack(testCOptions(), testEOptions(), nil)
+	assert.Len(stack, 3)
+
+	var h http.Handler = http.HandlerFunc(principalHandler)
+	for i := len(stack) - 1; i >= 0; i-- {
+		h = stack[i](h)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("alice", "secret")
+	h.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("alice", w.Body.String())
+}
@@ -0,0 +1,67 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package basculeecho adapts the basculehttp middleware to echo.  It's a
+// separate module so that users of bascule who don't use echo don't depend
+// on it.
+package basculeecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+)
+
+// New builds the chain of basculehttp middleware, checked as NewAuthChain
+// checks it, as echo middleware.
+func New(options ...basculehttp.AuthOption) (echo.MiddlewareFunc, error) {
+	g, err := basculehttp.NewAuthGate(options...)
+	if err != nil {
+		return nil, err
+	}
+	return adapt(g), nil
+}
+
+// Adapt returns echo middleware that runs the net/http middleware given,
+// such as the basculehttp constructor, enforcer, or listener decorator.
+// Requests the middleware rejects end with the response it wrote.
+// Otherwise the request the middleware passed on, whose context has the
+// Authentication, replaces the echo context's request, and the next handler
+// runs inside the middleware.
+func Adapt(middleware func(http.Handler) http.Handler) echo.MiddlewareFunc {
+	return adapt(basculehttp.NewGate(middleware))
+}
+
+func adapt(g *basculehttp.Gate) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var err error
+			g.Serve(c.Response(), c.Request(), func(_ http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				err = next(c)
+			})
+			return err
+		}
+	}
+}
+
+// FromContext gets the Authentication added by the middleware.
+func FromContext(c echo.Context) (bascule.Authentication, bool) {
+	return bascule.FromContext(c.Request().Context())
+}
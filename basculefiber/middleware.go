@@ -0,0 +1,159 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package basculefiber adapts the basculehttp middleware to fiber.  It's a
+// separate module so that users of bascule who don't use fiber don't depend
+// on it.
+//
+// fiber isn't built on net/http, so each request is converted to an
+// http.Request for the middleware.  The Authentication is carried in the
+// fiber context's user context, and changes the middleware makes to the
+// request's headers, such as projected attributes and identity assertions,
+// are copied back to the fiber request.
+package basculefiber
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// New builds the chain of basculehttp middleware, checked as NewAuthChain
+// checks it, as a fiber handler.
+func New(options ...basculehttp.AuthOption) (fiber.Handler, error) {
+	g, err := basculehttp.NewAuthGate(options...)
+	if err != nil {
+		return nil, err
+	}
+	return adapt(g), nil
+}
+
+// Adapt returns a fiber handler that runs the net/http middleware given,
+// such as the basculehttp constructor, enforcer, or listener decorator.
+// Requests the middleware rejects end with the response it wrote.
+// Otherwise the next handler is called inside the middleware, with the
+// Authentication in the user context.
+func Adapt(middleware func(http.Handler) http.Handler) fiber.Handler {
+	return adapt(basculehttp.NewGate(middleware))
+}
+
+func adapt(g *basculehttp.Gate) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var r http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &r, true); err != nil {
+			return err
+		}
+		before := r.Header.Clone()
+		w := &responseWriter{header: make(http.Header)}
+		var err error
+		ok := g.Serve(w, r.WithContext(c.UserContext()), func(_ http.ResponseWriter, passed *http.Request) {
+			w.writeHeaders(c)
+			c.SetUserContext(passed.Context())
+			copyRequestHeaders(c, before, passed.Header)
+			err = c.Next()
+		})
+		if !ok {
+			w.writeHeaders(c)
+			w.writeResponse(c)
+		}
+		return err
+	}
+}
+
+// FromContext gets the Authentication added by the middleware.
+func FromContext(c *fiber.Ctx) (bascule.Authentication, bool) {
+	return bascule.FromContext(c.UserContext())
+}
+
+// copyRequestHeaders applies the changes between the headers before and
+// after the middleware ran to the fiber request.
+func copyRequestHeaders(c *fiber.Ctx, before, after http.Header) {
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			c.Request().Header.Del(k)
+		}
+	}
+	for k, values := range after {
+		if equal(before[k], values) {
+			continue
+		}
+		c.Request().Header.Del(k)
+		for _, v := range values {
+			c.Request().Header.Add(k, v)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// responseWriter records the response the middleware writes, so that it can
+// be written to the fiber response.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// writeHeaders writes the response headers the middleware set, which it
+// may set on requests it passes on too, e.g. to warn of a token expiring.
+func (w *responseWriter) writeHeaders(c *fiber.Ctx) {
+	for k, values := range w.header {
+		c.Response().Header.Del(k)
+		for _, v := range values {
+			c.Response().Header.Add(k, v)
+		}
+	}
+}
+
+// writeResponse writes the status and body of a rejection.
+func (w *responseWriter) writeResponse(c *fiber.Ctx) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+	c.Response().SetBody(w.body.Bytes())
+}
@@ -0,0 +1,122 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculefiber
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() []basculehttp.AuthOption {
+	return []basculehttp.AuthOption{
+		basculehttp.WithConstructorOptions(basculehttp.WithTokenFactory(basculehttp.BasicAuthorization,
+			basculehttp.BasicTokenFactory{"alice": "secret"})),
+		basculehttp.WithEnforcerOptions(basculehttp.WithRules(basculehttp.BasicAuthorization, basculechecks.AllowAll())),
+	}
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mw, err := New()
+	assert.ErrorIs(err, basculehttp.ErrNoTokenFactories)
+	assert.Nil(mw)
+
+	mw, err = New(testOptions()...)
+	require.NoError(err)
+	app := fiber.New()
+	app.Use(mw)
+	app.Get("/", func(c *fiber.Ctx) error {
+		auth, ok := FromContext(c)
+		if !ok {
+			return c.SendStatus(http.StatusInternalServerError)
+		}
+		return c.SendString(auth.Token.Principal())
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(err)
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("alice", "secret")
+	resp, err = app.Test(r)
+	require.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	assert.Equal("alice", string(body))
+}
+
+func TestAdaptRequestHeaders(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	app := fiber.New()
+	app.Use(Adapt(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del("X-Remove")
+			r.Header.Set("X-Added", "added")
+			next.ServeHTTP(w, r)
+		})
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		assert.Empty(c.Get("X-Remove"))
+		assert.Equal("added", c.Get("X-Added"))
+		assert.Equal("kept", c.Get("X-Kept"))
+		return c.SendStatus(http.StatusOK)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Remove", "removed")
+	r.Header.Set("X-Kept", "kept")
+	resp, err := app.Test(r)
+	require.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func TestAdaptContext(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	app := fiber.New()
+	app.Use(Adapt(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		// the handler runs inside the middleware, before it cancels.
+		assert.NoError(c.UserContext().Err())
+		return c.SendStatus(http.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+}
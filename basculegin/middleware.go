@@ -0,0 +1,66 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package basculegin adapts the basculehttp middleware to gin.  It's a
+// separate module so that users of bascule who don't use gin don't depend on
+// it.
+package basculegin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+)
+
+// New builds the chain of basculehttp middleware, checked as NewAuthChain
+// checks it, as gin middleware.
+func New(options ...basculehttp.AuthOption) (gin.HandlerFunc, error) {
+	g, err := basculehttp.NewAuthGate(options...)
+	if err != nil {
+		return nil, err
+	}
+	return adapt(g), nil
+}
+
+// Adapt returns gin middleware that runs the net/http middleware given, such
+// as the basculehttp constructor, enforcer, or listener decorator.  Requests
+// the middleware rejects are aborted after it writes its response.
+// Otherwise the request the middleware passed on, whose context has the
+// Authentication, replaces the gin context's request, and the rest of the
+// gin handlers run inside the middleware.
+func Adapt(middleware func(http.Handler) http.Handler) gin.HandlerFunc {
+	return adapt(basculehttp.NewGate(middleware))
+}
+
+func adapt(g *basculehttp.Gate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok := g.Serve(c.Writer, c.Request, func(_ http.ResponseWriter, r *http.Request) {
+			c.Request = r
+			c.Next()
+		})
+		if !ok {
+			c.Abort()
+		}
+	}
+}
+
+// FromContext gets the Authentication added by the middleware.
+func FromContext(c *gin.Context) (bascule.Authentication, bool) {
+	return bascule.FromContext(c.Request.Context())
+}
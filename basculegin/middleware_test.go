@@ -0,0 +1,113 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculegin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() []basculehttp.AuthOption {
+	return []basculehttp.AuthOption{
+		basculehttp.WithConstructorOptions(basculehttp.WithTokenFactory(basculehttp.BasicAuthorization,
+			basculehttp.BasicTokenFactory{"alice": "secret"})),
+		basculehttp.WithEnforcerOptions(basculehttp.WithRules(basculehttp.BasicAuthorization, basculechecks.AllowAll())),
+	}
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	gin.SetMode(gin.TestMode)
+
+	mw, err := New()
+	assert.ErrorIs(err, basculehttp.ErrNoTokenFactories)
+	assert.Nil(mw)
+
+	mw, err = New(testOptions()...)
+	require.NoError(err)
+	router := gin.New()
+	router.Use(mw)
+	router.GET("/", func(c *gin.Context) {
+		auth, ok := FromContext(c)
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, auth.Token.Principal())
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.SetBasicAuth("alice", "secret")
+	router.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("alice", w.Body.String())
+}
+
+func TestAdapt(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Adapt(func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(http.StatusForbidden, w.Code)
+}
+
+func TestAdaptContext(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Adapt(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}))
+	router.GET("/", func(c *gin.Context) {
+		// the handler runs inside the middleware, before it cancels.
+		assert.NoError(c.Request.Context().Err())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(http.StatusOK, w.Code)
+}
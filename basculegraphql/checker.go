@@ -0,0 +1,140 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculegraphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/spf13/cast"
+)
+
+var (
+	ErrNoAuthentication  = bascule.NewClassError(bascule.MissingCredentialsClass, "no authentication found in context")
+	ErrMissingCapability = bascule.NewClassError(bascule.CapabilityClass, "missing required capability")
+	ErrMissingRole       = bascule.NewClassError(bascule.CapabilityClass, "missing required role")
+)
+
+// Resolver resolves a GraphQL field.  It's the type of gqlgen's
+// graphql.Resolver, so a graphql.Resolver can be passed as one.
+type Resolver = func(ctx context.Context) (interface{}, error)
+
+// Config configures a Checker.
+type Config struct {
+	// CapabilityKeys is the location of the token's capabilities in its
+	// attributes.  Defaults to basculechecks.CapabilityKeys().
+	CapabilityKeys []string
+
+	// RoleKeys is the location of the token's roles in its attributes.
+	// Defaults to bascule.RolesKey, where a ClaimsMapper or GroupExpander
+	// puts them.
+	RoleKeys []string
+}
+
+// Checker checks the capabilities and roles of the token in the context
+// before a GraphQL field is resolved.
+type Checker struct {
+	capabilityKeys []string
+	roleKeys       []string
+}
+
+// NewChecker creates a Checker.
+func NewChecker(config Config) *Checker {
+	if len(config.CapabilityKeys) == 0 {
+		config.CapabilityKeys = basculechecks.CapabilityKeys()
+	}
+	if len(config.RoleKeys) == 0 {
+		config.RoleKeys = []string{bascule.RolesKey}
+	}
+	return &Checker{
+		capabilityKeys: config.CapabilityKeys,
+		roleKeys:       config.RoleKeys,
+	}
+}
+
+// CheckCapabilities returns nil if the token in the context has at least one
+// of the capabilities given.  Capabilities are compared as strings, not as the
+// patterns the CapabilitiesValidator matches against URLs.  With no
+// capabilities given, any token passes.
+func (c *Checker) CheckCapabilities(ctx context.Context, capabilities ...string) error {
+	return c.checkAny(ctx, c.capabilityKeys, capabilities, ErrMissingCapability)
+}
+
+// CheckRoles returns nil if the token in the context has at least one of the
+// roles given.
+func (c *Checker) CheckRoles(ctx context.Context, roles ...string) error {
+	return c.checkAny(ctx, c.roleKeys, roles, ErrMissingRole)
+}
+
+// HasCapabilities resolves the field if the token in the context has at
+// least one of the capabilities given.
+func (c *Checker) HasCapabilities(ctx context.Context, next Resolver, capabilities ...string) (interface{}, error) {
+	if err := c.CheckCapabilities(ctx, capabilities...); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// HasRoles resolves the field if the token in the context has at least one
+// of the roles given.
+func (c *Checker) HasRoles(ctx context.Context, next Resolver, roles ...string) (interface{}, error) {
+	if err := c.CheckRoles(ctx, roles...); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// Validate resolves the field if the token in the context passes the
+// validator given, so that fields can be guarded by the same validators as
+// the REST endpoints.
+func (c *Checker) Validate(ctx context.Context, next Resolver, v bascule.Validator) (interface{}, error) {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil {
+		return nil, ErrNoAuthentication
+	}
+	if err := v.Check(ctx, auth.Token); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+func (c *Checker) checkAny(ctx context.Context, keys []string, required []string, missing error) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil {
+		return ErrNoAuthentication
+	}
+	if len(required) == 0 {
+		return nil
+	}
+	var have []string
+	if attributes := auth.Token.Attributes(); attributes != nil {
+		if v, ok := bascule.GetNestedAttribute(attributes, keys...); ok {
+			have, _ = cast.ToStringSliceE(v)
+		}
+	}
+	for _, h := range have {
+		for _, r := range required {
+			if h == r {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: need one of %v", missing, required)
+}
@@ -0,0 +1,196 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculegraphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContext(attributes map[string]interface{}) context.Context {
+	return bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "Bearer",
+		Token:         bascule.NewToken("jwt", "alice", bascule.NewAttributes(attributes)),
+	})
+}
+
+func TestNewChecker(t *testing.T) {
+	assert := assert.New(t)
+	c := NewChecker(Config{})
+	assert.Equal(basculechecks.CapabilityKeys(), c.capabilityKeys)
+	assert.Equal([]string{bascule.RolesKey}, c.roleKeys)
+
+	c = NewChecker(Config{CapabilityKeys: []string{"caps"}, RoleKeys: []string{"realm", "roles"}})
+	assert.Equal([]string{"caps"}, c.capabilityKeys)
+	assert.Equal([]string{"realm", "roles"}, c.roleKeys)
+}
+
+func TestHasCapabilities(t *testing.T) {
+	tests := []struct {
+		description  string
+		ctx          context.Context
+		capabilities []string
+		expectedErr  error
+	}{
+		{
+			description:  "Success",
+			ctx:          testContext(map[string]interface{}{"capabilities": []interface{}{"a", "b"}}),
+			capabilities: []string{"c", "b"},
+		},
+		{
+			description: "No Capabilities Required",
+			ctx:         testContext(nil),
+		},
+		{
+			description:  "Missing Capability",
+			ctx:          testContext(map[string]interface{}{"capabilities": []string{"a"}}),
+			capabilities: []string{"b"},
+			expectedErr:  ErrMissingCapability,
+		},
+		{
+			description:  "No Capabilities",
+			ctx:          testContext(map[string]interface{}{}),
+			capabilities: []string{"b"},
+			expectedErr:  ErrMissingCapability,
+		},
+		{
+			description:  "No Authentication",
+			ctx:          context.Background(),
+			capabilities: []string{"b"},
+			expectedErr:  ErrNoAuthentication,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			called := false
+			res, err := NewChecker(Config{}).HasCapabilities(tc.ctx, func(context.Context) (interface{}, error) {
+				called = true
+				return "field", nil
+			}, tc.capabilities...)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.False(called)
+				assert.Nil(res)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("field", res)
+		})
+	}
+}
+
+func TestHasRoles(t *testing.T) {
+	tests := []struct {
+		description string
+		config      Config
+		ctx         context.Context
+		roles       []string
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			ctx:         testContext(map[string]interface{}{bascule.RolesKey: []string{"admin"}}),
+			roles:       []string{"admin", "operator"},
+		},
+		{
+			description: "Custom Keys",
+			config:      Config{RoleKeys: []string{"realm_access", "roles"}},
+			ctx: testContext(map[string]interface{}{
+				"realm_access": map[string]interface{}{"roles": []interface{}{"operator"}},
+			}),
+			roles: []string{"admin", "operator"},
+		},
+		{
+			description: "Missing Role",
+			ctx:         testContext(map[string]interface{}{bascule.RolesKey: []string{"viewer"}}),
+			roles:       []string{"admin"},
+			expectedErr: ErrMissingRole,
+		},
+		{
+			description: "No Authentication",
+			ctx:         context.Background(),
+			roles:       []string{"admin"},
+			expectedErr: ErrNoAuthentication,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			res, err := NewChecker(tc.config).HasRoles(tc.ctx, func(context.Context) (interface{}, error) {
+				return "field", nil
+			}, tc.roles...)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(res)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("field", res)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validatorErr := errors.New("validation failed")
+	tests := []struct {
+		description string
+		ctx         context.Context
+		validator   bascule.Validator
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			ctx:         testContext(nil),
+			validator:   basculechecks.AllowAll(),
+		},
+		{
+			description: "Validator Error",
+			ctx:         testContext(nil),
+			validator: bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+				return validatorErr
+			}),
+			expectedErr: validatorErr,
+		},
+		{
+			description: "No Authentication",
+			ctx:         context.Background(),
+			validator:   basculechecks.AllowAll(),
+			expectedErr: ErrNoAuthentication,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			res, err := NewChecker(Config{}).Validate(tc.ctx, func(context.Context) (interface{}, error) {
+				return "field", nil
+			}, tc.validator)
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				assert.Nil(res)
+				return
+			}
+			assert.Equal("field", res)
+		})
+	}
+}
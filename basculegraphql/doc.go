@@ -0,0 +1,40 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+/*
+Package basculegraphql authorizes GraphQL fields with the Authentication the
+basculehttp constructor adds to the request's context, for services serving
+GraphQL alongside REST.  A Checker's methods have the shape of gqlgen
+directive implementations, so they can back directives like these:
+
+	directive @hasCapability(capabilities: [String!]!) on FIELD_DEFINITION
+	directive @hasRole(roles: [String!]!) on FIELD_DEFINITION
+
+with a one line adapter each, without this package depending on gqlgen:
+
+	checker := basculegraphql.NewChecker(basculegraphql.Config{})
+	c := generated.Config{Resolvers: resolvers}
+	c.Directives.HasCapability = func(ctx context.Context, _ interface{}, next graphql.Resolver, capabilities []string) (interface{}, error) {
+		return checker.HasCapabilities(ctx, next, capabilities...)
+	}
+	c.Directives.HasRole = func(ctx context.Context, _ interface{}, next graphql.Resolver, roles []string) (interface{}, error) {
+		return checker.HasRoles(ctx, next, roles...)
+	}
+
+Resolvers can also call CheckCapabilities and CheckRoles directly.
+*/
+
+package basculegraphql
@@ -14,9 +14,9 @@ import (
 	"github.com/SermoDigital/jose/jwt"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/provider"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
 	"github.com/spf13/cast"
-	"github.com/xmidt-org/bascule"
-	"github.com/xmidt-org/bascule/basculehttp"
 
 	//nolint:staticcheck
 	"github.com/xmidt-org/webpa-common/v2/xmetrics"
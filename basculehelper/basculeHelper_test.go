@@ -8,10 +8,10 @@ import (
 	"testing"
 
 	"github.com/go-kit/kit/metrics/generic"
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
-	"github.com/xmidt-org/bascule"
 )
 
 // MetricValidatorTests
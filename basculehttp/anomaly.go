@@ -0,0 +1,252 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+)
+
+const defaultAnomalyWindow = 5 * time.Minute
+
+// AnomalyKind is what an anomaly's failures were counted for.
+type AnomalyKind string
+
+const (
+	// PrincipalAnomaly is reported when a principal fails to authenticate
+	// too often, such as when its password is being guessed.
+	PrincipalAnomaly AnomalyKind = "principal"
+
+	// IPAnomaly is reported when a client IP fails too often, such as when
+	// it's trying a list of stolen credentials.
+	IPAnomaly AnomalyKind = "ip"
+)
+
+// Anomaly describes a principal or client IP whose failure rate crossed its
+// threshold.
+type Anomaly struct {
+	Kind AnomalyKind
+
+	// Key is the principal or client IP.
+	Key string
+
+	// Failures is the number of failures within the Window.
+	Failures int
+	Window   time.Duration
+
+	// RequestID is the correlation ID of the request that crossed the
+	// threshold.
+	RequestID string
+}
+
+// AnomalyConfig configures an AnomalyDetector.
+type AnomalyConfig struct {
+	// PrincipalThreshold is the number of failures for a principal within
+	// the window that is reported.  If it isn't positive, principals aren't
+	// tracked.
+	PrincipalThreshold int
+
+	// IPThreshold is the number of failures from a client IP within the
+	// window that is reported.  If it isn't positive, client IPs aren't
+	// tracked.
+	IPThreshold int
+
+	// Window is the length of the sliding window failures are counted over.
+	// Defaults to 5 minutes.
+	Window time.Duration
+}
+
+// AnomalyDetector tracks authentication failures per principal and per client
+// IP over a sliding window, and reports an Anomaly to its callbacks and
+// metrics when one crosses its threshold, so operators can block or alert on
+// credential stuffing.  Each key is reported once when it crosses the
+// threshold, and again only after its failure rate has dropped below it.
+// Add its DenyHook to the constructor and enforcer as an AfterDeny hook.
+type AnomalyDetector struct {
+	config    AnomalyConfig
+	measures  *AnomalyMeasures
+	server    string
+	callbacks []func(context.Context, Anomaly)
+	now       func() time.Time
+
+	lock      sync.Mutex
+	windows   map[string]*failureWindow
+	lastSweep time.Time
+}
+
+// failureWindow holds the most recent failure times for a key, oldest
+// first.  Only the last threshold failures are needed to tell whether the
+// threshold was crossed, so no more than that are kept.
+type failureWindow struct {
+	times     []time.Time
+	triggered bool
+}
+
+// NewAnomalyDetector creates an AnomalyDetector.  The measures are optional.
+// The callbacks are run on the request's goroutine, so ones that block or
+// alert should hand off their work rather than slow the response.
+func NewAnomalyDetector(config AnomalyConfig, measures *AnomalyMeasures, server string, callbacks ...func(context.Context, Anomaly)) *AnomalyDetector {
+	if config.Window <= 0 {
+		config.Window = defaultAnomalyWindow
+	}
+	d := &AnomalyDetector{
+		config:   config,
+		measures: measures,
+		server:   server,
+		now:      time.Now,
+		windows:  make(map[string]*failureWindow),
+	}
+	for _, c := range callbacks {
+		if c != nil {
+			d.callbacks = append(d.callbacks, c)
+		}
+	}
+	return d
+}
+
+// DenyHook returns an AfterDeny Hook that records the denials.  The principal
+// is taken from the request's token, or from its basic auth header when
+// the token couldn't be parsed.
+func (d *AnomalyDetector) DenyHook() Hook {
+	return func(_ http.ResponseWriter, r *http.Request, event HookEvent) *http.Request {
+		if event.Stage != AfterDeny {
+			return nil
+		}
+		principal := ""
+		if event.Auth.Token != nil {
+			principal = event.Auth.Token.Principal()
+		} else if scheme, value, ok := strings.Cut(r.Header.Get(DefaultHeaderName), " "); ok &&
+			strings.EqualFold(scheme, string(BasicAuthorization)) {
+			principal, _ = basicPrincipal(value)
+		}
+		d.RecordFailure(r.Context(), principal, denialClientIP(r, event.Auth), event.RequestID)
+		return nil
+	}
+}
+
+// RecordFailure records a failure for the principal and client IP, either
+// of which can be empty, and reports any anomaly.
+func (d *AnomalyDetector) RecordFailure(ctx context.Context, principal, clientIP, requestID string) {
+	now := d.now()
+	var anomalies []Anomaly
+	d.lock.Lock()
+	d.sweep(now)
+	if a, ok := d.record(PrincipalAnomaly, principal, d.config.PrincipalThreshold, now); ok {
+		anomalies = append(anomalies, a)
+	}
+	if a, ok := d.record(IPAnomaly, clientIP, d.config.IPThreshold, now); ok {
+		anomalies = append(anomalies, a)
+	}
+	d.lock.Unlock()
+
+	for _, a := range anomalies {
+		a.RequestID = requestID
+		d.observe(a)
+		for _, c := range d.callbacks {
+			c(ctx, a)
+		}
+	}
+}
+
+// record adds a failure to the key's window, returning the anomaly if this
+// failure crossed the threshold.  It must be called with the lock held.
+func (d *AnomalyDetector) record(kind AnomalyKind, key string, threshold int, now time.Time) (Anomaly, bool) {
+	if key == "" || threshold <= 0 {
+		return Anomaly{}, false
+	}
+	id := string(kind) + ":" + key
+	w := d.windows[id]
+	if w == nil {
+		w = &failureWindow{times: make([]time.Time, 0, threshold)}
+		d.windows[id] = w
+	}
+	w.prune(now.Add(-d.config.Window))
+	if len(w.times) < threshold {
+		w.triggered = false
+	}
+	if len(w.times) == threshold {
+		w.times = append(w.times[:0], w.times[1:]...)
+	}
+	w.times = append(w.times, now)
+	if len(w.times) < threshold || w.triggered {
+		return Anomaly{}, false
+	}
+	w.triggered = true
+	return Anomaly{
+		Kind:     kind,
+		Key:      key,
+		Failures: len(w.times),
+		Window:   d.config.Window,
+	}, true
+}
+
+// prune drops the failures at or before the cutoff.
+func (w *failureWindow) prune(cutoff time.Time) {
+	i := 0
+	for i < len(w.times) && !w.times[i].After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		w.times = append(w.times[:0], w.times[i:]...)
+	}
+}
+
+// sweep forgets the keys with no failures in the window, at most once per
+// window.  It must be called with the lock held.
+func (d *AnomalyDetector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.config.Window {
+		return
+	}
+	d.lastSweep = now
+	cutoff := now.Add(-d.config.Window)
+	for id, w := range d.windows {
+		if len(w.times) == 0 || !w.times[len(w.times)-1].After(cutoff) {
+			delete(d.windows, id)
+		}
+	}
+}
+
+func (d *AnomalyDetector) observe(a Anomaly) {
+	if d.measures == nil || d.measures.Anomalies == nil {
+		return
+	}
+	d.measures.Anomalies.With(prometheus.Labels{
+		ServerLabel:      d.server,
+		AnomalyKindLabel: string(a.Kind),
+	}).Inc()
+}
+
+// denialClientIP returns the client IP of a denied request: the one the
+// constructor captured, or the host of the request's RemoteAddr.
+func denialClientIP(r *http.Request, auth bascule.Authentication) string {
+	if auth.Request.ClientIP != "" {
+		return auth.Request.ClientIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,216 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type anomalyRecorder struct {
+	anomalies []Anomaly
+}
+
+func (a *anomalyRecorder) record(_ context.Context, anomaly Anomaly) {
+	a.anomalies = append(a.anomalies, anomaly)
+}
+
+func newTestAnomalyDetector(config AnomalyConfig) (*AnomalyDetector, *anomalyRecorder, *AnomalyMeasures, *time.Time) {
+	recorder := &anomalyRecorder{}
+	measures := &AnomalyMeasures{
+		Anomalies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testAnomalies",
+			Help: "testAnomalies",
+		}, []string{ServerLabel, AnomalyKindLabel}),
+	}
+	d := NewAnomalyDetector(config, measures, "test", recorder.record, nil)
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+	return d, recorder, measures, &now
+}
+
+func TestNewAnomalyDetector(t *testing.T) {
+	assert := assert.New(t)
+	d := NewAnomalyDetector(AnomalyConfig{}, nil, "")
+	assert.Equal(defaultAnomalyWindow, d.config.Window)
+	assert.Empty(d.callbacks)
+
+	d = NewAnomalyDetector(AnomalyConfig{Window: time.Second}, nil, "", nil)
+	assert.Equal(time.Second, d.config.Window)
+	assert.Empty(d.callbacks)
+}
+
+func TestAnomalyDetectorRecordFailure(t *testing.T) {
+	assert := assert.New(t)
+	d, recorder, measures, now := newTestAnomalyDetector(AnomalyConfig{
+		PrincipalThreshold: 3,
+		IPThreshold:        2,
+		Window:             time.Minute,
+	})
+	ctx := context.Background()
+
+	d.RecordFailure(ctx, "alice", "10.0.0.1", "r1")
+	assert.Empty(recorder.anomalies)
+
+	*now = now.Add(10 * time.Second)
+	d.RecordFailure(ctx, "bob", "10.0.0.1", "r2")
+	assert.Equal([]Anomaly{
+		{Kind: IPAnomaly, Key: "10.0.0.1", Failures: 2, Window: time.Minute, RequestID: "r2"},
+	}, recorder.anomalies)
+
+	// already reported, so it isn't reported again while over the threshold.
+	*now = now.Add(10 * time.Second)
+	d.RecordFailure(ctx, "alice", "10.0.0.1", "r3")
+	d.RecordFailure(ctx, "alice", "", "r4")
+	assert.Equal([]Anomaly{
+		{Kind: IPAnomaly, Key: "10.0.0.1", Failures: 2, Window: time.Minute, RequestID: "r2"},
+		{Kind: PrincipalAnomaly, Key: "alice", Failures: 3, Window: time.Minute, RequestID: "r4"},
+	}, recorder.anomalies)
+
+	// once the failures slide out of the window, the next crossing is
+	// reported again.
+	*now = now.Add(2 * time.Minute)
+	recorder.anomalies = nil
+	d.RecordFailure(ctx, "", "10.0.0.1", "r5")
+	assert.Empty(recorder.anomalies)
+	d.RecordFailure(ctx, "", "10.0.0.1", "r6")
+	assert.Equal([]Anomaly{
+		{Kind: IPAnomaly, Key: "10.0.0.1", Failures: 2, Window: time.Minute, RequestID: "r6"},
+	}, recorder.anomalies)
+
+	assert.Equal(2.0, testutil.ToFloat64(measures.Anomalies.With(prometheus.Labels{
+		ServerLabel:      "test",
+		AnomalyKindLabel: string(IPAnomaly),
+	})))
+	assert.Equal(1.0, testutil.ToFloat64(measures.Anomalies.With(prometheus.Labels{
+		ServerLabel:      "test",
+		AnomalyKindLabel: string(PrincipalAnomaly),
+	})))
+}
+
+func TestAnomalyDetectorSlidingWindow(t *testing.T) {
+	assert := assert.New(t)
+	d, recorder, _, now := newTestAnomalyDetector(AnomalyConfig{
+		PrincipalThreshold: 3,
+		Window:             time.Minute,
+	})
+	ctx := context.Background()
+
+	// failures spread out so no three fall within a minute aren't reported.
+	for i := 0; i < 5; i++ {
+		d.RecordFailure(ctx, "alice", "", "")
+		*now = now.Add(40 * time.Second)
+	}
+	assert.Empty(recorder.anomalies)
+	assert.LessOrEqual(len(d.windows[string(PrincipalAnomaly)+":alice"].times), 3)
+
+	d.RecordFailure(ctx, "alice", "", "")
+	*now = now.Add(time.Second)
+	d.RecordFailure(ctx, "alice", "", "")
+	assert.Len(recorder.anomalies, 1)
+}
+
+func TestAnomalyDetectorSweep(t *testing.T) {
+	assert := assert.New(t)
+	d, _, _, now := newTestAnomalyDetector(AnomalyConfig{
+		PrincipalThreshold: 3,
+		IPThreshold:        3,
+		Window:             time.Minute,
+	})
+	ctx := context.Background()
+	d.RecordFailure(ctx, "alice", "10.0.0.1", "")
+	assert.Len(d.windows, 2)
+
+	*now = now.Add(2 * time.Minute)
+	d.RecordFailure(ctx, "bob", "", "")
+	assert.Len(d.windows, 1)
+	assert.Contains(d.windows, string(PrincipalAnomaly)+":bob")
+}
+
+func TestAnomalyDetectorDenyHook(t *testing.T) {
+	tests := []struct {
+		description       string
+		header            string
+		event             HookEvent
+		expectedAnomalies []Anomaly
+	}{
+		{
+			description: "Token principal",
+			event: HookEvent{
+				Stage: AfterDeny,
+				Auth: bascule.Authentication{
+					Token:   bascule.NewToken("jwt", "alice", nil),
+					Request: bascule.Request{ClientIP: "10.0.0.1"},
+				},
+				RequestID: "r1",
+			},
+			expectedAnomalies: []Anomaly{
+				{Kind: PrincipalAnomaly, Key: "alice", Failures: 1, Window: time.Minute, RequestID: "r1"},
+				{Kind: IPAnomaly, Key: "10.0.0.1", Failures: 1, Window: time.Minute, RequestID: "r1"},
+			},
+		},
+		{
+			description: "Basic auth principal",
+			header:      "Basic " + base64.StdEncoding.EncodeToString([]byte("bob:wrong")),
+			event:       HookEvent{Stage: AfterDeny},
+			expectedAnomalies: []Anomaly{
+				{Kind: PrincipalAnomaly, Key: "bob", Failures: 1, Window: time.Minute},
+				{Kind: IPAnomaly, Key: "192.168.1.2", Failures: 1, Window: time.Minute},
+			},
+		},
+		{
+			description: "Bearer without token",
+			header:      "Bearer abc",
+			event:       HookEvent{Stage: AfterDeny},
+			expectedAnomalies: []Anomaly{
+				{Kind: IPAnomaly, Key: "192.168.1.2", Failures: 1, Window: time.Minute},
+			},
+		},
+		{
+			description: "Not a denial",
+			header:      "Basic " + base64.StdEncoding.EncodeToString([]byte("bob:right")),
+			event:       HookEvent{Stage: AfterAllow},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			d, recorder, _, _ := newTestAnomalyDetector(AnomalyConfig{
+				PrincipalThreshold: 1,
+				IPThreshold:        1,
+				Window:             time.Minute,
+			})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.168.1.2:1234"
+			if tc.header != "" {
+				r.Header.Set(DefaultHeaderName, tc.header)
+			}
+			require.Nil(t, d.DenyHook()(nil, r, tc.event))
+			assert.Equal(t, tc.expectedAnomalies, recorder.anomalies)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// AuthenticatedEvent describes a request the constructor authenticated.
+type AuthenticatedEvent struct {
+	// Auth is the Authentication added to the request's context, including
+	// the token and the snapshot of the request.
+	Auth bascule.Authentication
+
+	// Factory is the scheme of the TokenFactory that parsed the token.  It
+	// can differ from the request's scheme when schemes are aliased or
+	// matched case-insensitively.
+	Factory bascule.Authorization
+
+	// ParseDuration is how long the constructor took to parse, validate, and
+	// enrich the token.
+	ParseDuration time.Duration
+}
+
+// OnAuthenticated is called with each request the constructor authenticates,
+// before the AfterAllow hooks and the next handler.  The request's context
+// has the Authentication.  It is useful for caching, billing, or tracking
+// sessions without another middleware.
+type OnAuthenticated func(*http.Request, AuthenticatedEvent)
+
+// WithCOnAuthenticated adds a function called with each authenticated request.
+// Functions are called in the order they were added.
+func WithCOnAuthenticated(f OnAuthenticated) COption {
+	return func(c *constructor) {
+		if f != nil {
+			c.onAuthenticated = append(c.onAuthenticated, f)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructorOnAuthenticated(t *testing.T) {
+	tests := []struct {
+		description     string
+		header          string
+		expectedCalls   []string
+		expectedFactory bascule.Authorization
+	}{
+		{
+			description:     "Authenticated",
+			header:          "Bearer good",
+			expectedCalls:   []string{"first", "second", "hook"},
+			expectedFactory: BearerAuthorization,
+		},
+		{
+			description:     "Aliased",
+			header:          "JWT good",
+			expectedCalls:   []string{"first", "second", "hook"},
+			expectedFactory: BearerAuthorization,
+		},
+		{
+			description: "Denied",
+			header:      "Bearer bad",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				calls []string
+				event AuthenticatedEvent
+			)
+			record := func(name string) OnAuthenticated {
+				return func(r *http.Request, e AuthenticatedEvent) {
+					calls = append(calls, name)
+					event = e
+					auth, ok := bascule.FromContext(r.Context())
+					assert.True(ok)
+					assert.Equal(e.Auth, auth)
+				}
+			}
+			handler := NewConstructor(
+				WithCOnAuthenticated(nil),
+				WithCOnAuthenticated(record("first")),
+				WithCOnAuthenticated(record("second")),
+				WithCHook(AfterAllow, func(_ http.ResponseWriter, _ *http.Request, _ HookEvent) *http.Request {
+					calls = append(calls, "hook")
+					return nil
+				}),
+				WithCSchemeAlias("JWT", BearerAuthorization),
+				WithTokenFactory(BearerAuthorization, TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+					if v != "good" {
+						return nil, errors.New("bad token")
+					}
+					return bascule.NewToken("jwt", "alice", nil), nil
+				})),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.header)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(tc.expectedCalls, calls)
+			assert.Equal(tc.expectedFactory, event.Factory)
+			if len(tc.expectedCalls) == 0 {
+				return
+			}
+			assert.Equal("alice", event.Auth.Token.Principal())
+			assert.Equal(tc.expectedFactory, event.Auth.Authorization)
+			assert.Equal("/", event.Auth.Request.URL.Path)
+			assert.Positive(event.ParseDuration)
+		})
+	}
+}
@@ -21,19 +21,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule"
 	"go.uber.org/fx"
 )
 
 var (
-	ErrorMalformedValue    = errors.New("expected <user>:<password> in decoded value")
-	ErrorPrincipalNotFound = errors.New("principal not found")
-	ErrorInvalidPassword   = errors.New("invalid password")
+	ErrorMalformedValue    = bascule.NewClassError(bascule.MalformedClass, "expected <user>:<password> in decoded value")
+	ErrorPrincipalNotFound = bascule.NewClassError(bascule.InvalidClass, "principal not found")
+	ErrorInvalidPassword   = bascule.NewClassError(bascule.InvalidClass, "invalid password")
+	ErrorInvalidBase64     = bascule.NewClassError(bascule.MalformedClass, "credentials aren't valid base64")
 )
 
 type EncodedBasicKeys struct {
@@ -62,59 +62,124 @@ type BasicTokenFactory map[string]string
 // the username followed by a colon and then the password.  The function checks
 // that the username password pair is in the map and returns a Token if it is.
 func (btf BasicTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
-	decoded, err := base64.StdEncoding.DecodeString(value)
+	dv, err := decodeBase64(value)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode string: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidBase64, err)
 	}
+	defer dv.release()
+	decoded := dv.Decoded
 
 	i := bytes.IndexByte(decoded, ':')
 	if i <= 0 {
 		return nil, ErrorMalformedValue
 	}
-	principal := string(decoded[:i])
-	val, ok := btf[principal]
+	// indexing the map with the converted bytes doesn't allocate.
+	val, ok := btf[string(decoded[:i])]
 	if !ok {
 		return nil, ErrorPrincipalNotFound
 	}
-	if val != string(decoded[i+1:]) {
+	if !bascule.SecretEqual([]byte(val), decoded[i+1:]) {
 		// failed authentication
 		return nil, ErrorInvalidPassword
 	}
+	principal := string(decoded[:i])
 	// "basic" is a placeholder here ... token types won't always map to the
 	// Authorization header.  For example, a JWT should have a type of "jwt" or some such, not "bearer"
 	return bascule.NewToken("basic", principal, bascule.NewAttributes(map[string]interface{}{})), nil
 }
 
+// ZeroizingBasicTokenFactory is a BasicTokenFactory that holds its passwords
+// in ZeroizingStrings, so they can be zeroed when they're no longer needed.
+type ZeroizingBasicTokenFactory map[string]*bascule.ZeroizingString
+
+// ParseAndValidate behaves like BasicTokenFactory's ParseAndValidate.
+func (zbtf ZeroizingBasicTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	dv, err := decodeBase64(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode string: %v", err)
+	}
+	defer dv.release()
+	decoded := dv.Decoded
+
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return nil, ErrorMalformedValue
+	}
+	val, ok := zbtf[string(decoded[:i])]
+	if !ok {
+		return nil, ErrorPrincipalNotFound
+	}
+	if !val.Equal(decoded[i+1:]) {
+		return nil, ErrorInvalidPassword
+	}
+	principal := string(decoded[:i])
+	return bascule.NewToken("basic", principal, bascule.NewAttributes(map[string]interface{}{})), nil
+}
+
+// Zero zeroes every password in the ZeroizingBasicTokenFactory.  Afterwards,
+// no basic auth is valid.
+func (zbtf ZeroizingBasicTokenFactory) Zero() {
+	for _, v := range zbtf {
+		v.Zero()
+	}
+}
+
 // NewBasicTokenFactoryFromList takes a list of base64 encoded basic auth keys,
 // decodes them, and supplies that list in map form of username to password. If
 // a username is encoded in two different auth keys, it will be overwritten by
 // the last occurrence of that username with a password.  If anoth
 func NewBasicTokenFactoryFromList(encodedBasicAuthKeys []string) (BasicTokenFactory, error) {
 	btf := make(BasicTokenFactory)
+	err := decodeBasicAuthKeys(encodedBasicAuthKeys, func(principal string, password []byte) {
+		btf[principal] = string(password)
+		bascule.Zero(password)
+	})
+	return btf, err
+}
+
+// NewZeroizingBasicTokenFactoryFromList is NewBasicTokenFactoryFromList for a
+// ZeroizingBasicTokenFactory.  The decoded keys are zeroed once they've been
+// copied.
+func NewZeroizingBasicTokenFactoryFromList(encodedBasicAuthKeys []string) (ZeroizingBasicTokenFactory, error) {
+	zbtf := make(ZeroizingBasicTokenFactory)
+	err := decodeBasicAuthKeys(encodedBasicAuthKeys, func(principal string, password []byte) {
+		if old, ok := zbtf[principal]; ok {
+			old.Zero()
+		}
+		zbtf[principal] = bascule.NewZeroizingBytes(password)
+	})
+	return zbtf, err
+}
+
+// decodeBasicAuthKeys decodes each base64 encoded basic auth key and calls add
+// with its username and password.  Keys are identified by their index in
+// errors, since they contain the password.
+func decodeBasicAuthKeys(encodedBasicAuthKeys []string, add func(string, []byte)) error {
 	errs := bascule.Errors{}
 
-	for _, encodedKey := range encodedBasicAuthKeys {
+	for n, encodedKey := range encodedBasicAuthKeys {
 		decoded, err := base64.StdEncoding.DecodeString(encodedKey)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to base64-decode basic auth key [%v]: %v", encodedKey, err))
+			errs = append(errs, fmt.Errorf("failed to base64-decode basic auth key %d: %v", n, err))
 			continue
 		}
 
 		i := bytes.IndexByte(decoded, ':')
 		if i <= 0 {
-			errs = append(errs, fmt.Errorf("basic auth key [%v] is malformed", encodedKey))
+			bascule.Zero(decoded)
+			errs = append(errs, fmt.Errorf("basic auth key %d is malformed", n))
 			continue
 		}
 
-		btf[string(decoded[:i])] = string(decoded[i+1:])
+		add(string(decoded[:i]), decoded[i+1:])
 	}
 
 	if len(errs) != 0 {
-		return btf, errs
+		return errs
 	}
 
 	// explicitly return nil so we don't have any empty error lists being returned.
-	return btf, nil
+	return nil
 }
 
 // ProvideBasicTokenFactory uses configuration at the key given to build a basic
@@ -26,11 +26,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule"
 	"go.uber.org/fx"
 )
 
@@ -39,6 +39,10 @@ func TestBasicTokenFactory(t *testing.T) {
 		"user": "pass",
 		"test": "valid",
 	})
+	zbtf := ZeroizingBasicTokenFactory{
+		"user": bascule.NewZeroizingString("pass"),
+		"test": bascule.NewZeroizingString("valid"),
+	}
 	tests := []struct {
 		description   string
 		value         string
@@ -70,23 +74,44 @@ func TestBasicTokenFactory(t *testing.T) {
 			value:       base64.StdEncoding.EncodeToString([]byte("user:p")),
 			expectedErr: ErrorInvalidPassword,
 		},
+		{
+			description: "Password Prefix Error",
+			value:       base64.StdEncoding.EncodeToString([]byte("user:pas")),
+			expectedErr: ErrorInvalidPassword,
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
 			assert := assert.New(t)
 			req := httptest.NewRequest("get", "/", nil)
-			token, err := btf.ParseAndValidate(context.Background(), req, "", tc.value)
-			assert.Equal(tc.expectedToken, token)
-			if tc.expectedErr == nil || err == nil {
-				assert.Equal(tc.expectedErr, err)
-			} else {
-				assert.Contains(err.Error(), tc.expectedErr.Error())
+			for _, tf := range []TokenFactory{btf, zbtf} {
+				token, err := tf.ParseAndValidate(context.Background(), req, "", tc.value)
+				assert.Equal(tc.expectedToken, token)
+				if tc.expectedErr == nil || err == nil {
+					assert.Equal(tc.expectedErr, err)
+				} else {
+					assert.Contains(err.Error(), tc.expectedErr.Error())
+				}
 			}
 		})
 	}
 }
 
+func TestZeroizingBasicTokenFactoryZero(t *testing.T) {
+	assert := assert.New(t)
+	zbtf := ZeroizingBasicTokenFactory{"user": bascule.NewZeroizingString("pass")}
+	value := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	_, err := zbtf.ParseAndValidate(context.Background(), nil, "", value)
+	assert.NoError(err)
+
+	zbtf.Zero()
+	assert.True(zbtf["user"].Zeroed())
+	_, err = zbtf.ParseAndValidate(context.Background(), nil, "", value)
+	assert.ErrorIs(err, ErrorInvalidPassword)
+}
+
 func TestNewBasicTokenFactoryFromList(t *testing.T) {
 	goodKey := `dXNlcjpwYXNz`
 	badKeyDecode := `dXNlcjpwYXN\\\`
@@ -134,6 +159,14 @@ func TestNewBasicTokenFactoryFromList(t *testing.T) {
 				assert.Equal(tc.expectedErr, err)
 			} else {
 				assert.Contains(err.Error(), tc.expectedErr.Error())
+				assert.NotContains(err.Error(), "dXNlcjpwYXN")
+			}
+
+			z, zerr := NewZeroizingBasicTokenFactoryFromList(tc.keyList)
+			assert.Equal(err, zerr)
+			assert.Len(z, len(tc.expectedDecodedMap))
+			for k, v := range tc.expectedDecodedMap {
+				assert.True(z[k].EqualString(v))
 			}
 		})
 	}
@@ -211,3 +244,17 @@ bad:
 		})
 	}
 }
+
+func BenchmarkBasicTokenFactory(b *testing.B) {
+	btf := BasicTokenFactory{"user": "pass"}
+	value := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := btf.ParseAndValidate(ctx, nil, "", value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -24,8 +24,8 @@ import (
 	"net/http"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/clortho"
 	"github.com/xmidt-org/clortho/clorthofx"
 	"go.uber.org/fx"
@@ -36,10 +36,10 @@ const (
 )
 
 var (
-	ErrEmptyValue       = errors.New("empty value")
-	ErrInvalidPrincipal = errors.New("invalid principal")
-	ErrInvalidToken     = errors.New("token isn't valid")
-	ErrUnexpectedClaims = errors.New("claims wasn't MapClaims as expected")
+	ErrEmptyValue       = bascule.NewClassError(bascule.MissingCredentialsClass, "empty value")
+	ErrInvalidPrincipal = bascule.NewClassError(bascule.InvalidClass, "invalid principal")
+	ErrInvalidToken     = bascule.NewClassError(bascule.InvalidClass, "token isn't valid")
+	ErrUnexpectedClaims = bascule.NewClassError(bascule.MalformedClass, "claims wasn't MapClaims as expected")
 
 	ErrNilResolver = errors.New("resolver cannot be nil")
 )
@@ -50,20 +50,40 @@ type BearerTokenFactory struct {
 	fx.In
 	DefaultKeyID string `name:"default_key_id"`
 	Resolver     clortho.Resolver
-	Parser       bascule.JWTParser `optional:"true"`
-	Leeway       bascule.Leeway    `name:"jwt_leeway" optional:"true"`
+	Parser       bascule.JWTParser    `optional:"true"`
+	Leeway       bascule.Leeway       `name:"jwt_leeway" optional:"true"`
+	ClaimsMapper bascule.ClaimsMapper `optional:"true"`
+	HeaderRules  JWTHeaderRules       `name:"jwt_header_rules" optional:"true"`
+	Policy       bascule.ClaimsPolicy `name:"jwt_claims_policy" optional:"true"`
+
+	// VerificationCache, when either field is set, wraps the Parser in a
+	// bascule.VerificationCache so that JWTs seen again skip signature
+	// verification.  It is only used by ProvideBearerTokenFactory.
+	VerificationCache bascule.VerificationCacheConfig `name:"jwt_verification_cache" optional:"true"`
 }
 
 // ParseAndValidate expects the given value to be a JWT with a kid header.  The
 // kid should be resolvable by the Resolver and the JWT should be Parseable and
-// pass any basic validation checks done by the Parser.  If everything goes
+// pass any basic validation checks done by the Parser.  The JOSE header is
+// checked against the HeaderRules before the key is resolved, and failures are
+// returned as a *JWTHeaderError.  The alg and claims must also follow the
+// Policy, whose ClockSkew replaces the Leeway when set.  If a ClaimsMapper is
+// set, the claims are normalized before the Token is built.  If everything goes
 // well, a Token of type "jwt" is returned.
 func (btf BearerTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
 	if len(value) == 0 {
 		return nil, ErrEmptyValue
 	}
 
+	var headerErr, keyErr error
 	keyfunc := func(token *jwt.Token) (interface{}, error) {
+		if headerErr = btf.HeaderRules.validate(token.Header); headerErr != nil {
+			return nil, headerErr
+		}
+		alg, _ := token.Header["alg"].(string)
+		if headerErr = btf.Policy.ValidateAlgorithm(alg); headerErr != nil {
+			return nil, headerErr
+		}
 		keyID, ok := token.Header["kid"].(string)
 		if !ok {
 			keyID = btf.DefaultKeyID
@@ -71,19 +91,30 @@ func (btf BearerTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Requ
 
 		key, err := btf.Resolver.Resolve(ctx, keyID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve key: %v", err)
+			keyErr = fmt.Errorf("failed to resolve key: %v", err)
+			return nil, keyErr
 		}
 		return key.Public(), nil
 	}
 
+	leeway := btf.Leeway
+	if btf.Policy.ClockSkew > 0 {
+		leeway = btf.Policy.Leeway()
+	}
 	leewayclaims := bascule.ClaimsWithLeeway{
-		MapClaims: make(jwt.MapClaims),
-		Leeway:    btf.Leeway,
+		MapClaims: make(jwt.MapClaims, defaultClaimsCapacity),
+		Leeway:    leeway,
 	}
 
 	jwtToken, err := btf.Parser.ParseJWT(value, &leewayclaims, keyfunc)
+	if headerErr != nil {
+		return nil, headerErr
+	}
+	if keyErr != nil {
+		return nil, fmt.Errorf("failed to parse JWS: %w: %v", bascule.ErrKeyResolution, keyErr)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse JWS: %v", err)
+		return nil, fmt.Errorf("failed to parse JWS: %w: %v", parseErrorClass(err), err)
 	}
 	if !jwtToken.Valid {
 		return nil, ErrInvalidToken
@@ -97,7 +128,16 @@ func (btf BearerTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Requ
 	if err != nil {
 		return nil, fmt.Errorf("failed to get map of claims with object [%v]: %v", claims, err)
 	}
+	if err = btf.Policy.ValidateClaims(claimsMap, jwt.TimeFunc()); err != nil {
+		return nil, fmt.Errorf("claims failed policy: %w", err)
+	}
 	jwtClaims := bascule.NewAttributes(claimsMap)
+	if btf.ClaimsMapper != nil {
+		jwtClaims, err = btf.ClaimsMapper.MapClaims(jwtClaims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map claims: %v", err)
+		}
+	}
 	principalVal, ok := jwtClaims.Get(jwtPrincipalKey)
 	if !ok {
 		return nil, fmt.Errorf("%w: principal value not found at key %v", ErrInvalidPrincipal, jwtPrincipalKey)
@@ -110,6 +150,21 @@ func (btf BearerTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Requ
 	return bascule.NewToken("jwt", principal, jwtClaims), nil
 }
 
+// parseErrorClass returns the class sentinel for an error from the Parser.
+func parseErrorClass(err error) error {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return bascule.ErrInvalid
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return bascule.ErrMalformed
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		return bascule.ErrExpired
+	}
+	return bascule.ErrInvalid
+}
+
 // ProvideBearerTokenFactory uses the key given to unmarshal configuration
 // needed to build a bearer token factory.  It provides a constructor option
 // with the bearer token factory.
@@ -122,12 +177,34 @@ func ProvideBearerTokenFactory(configKey string, optional bool) fx.Option {
 				Target: arrange.UnmarshalKey(fmt.Sprintf("%s.leeway", configKey),
 					bascule.Leeway{}),
 			},
+			fx.Annotated{
+				Name: "jwt_header_rules",
+				Target: arrange.UnmarshalKey(fmt.Sprintf("%s.header", configKey),
+					JWTHeaderRules{}),
+			},
+			fx.Annotated{
+				Name: "jwt_claims_policy",
+				Target: arrange.UnmarshalKey(fmt.Sprintf("%s.policy", configKey),
+					bascule.ClaimsPolicy{}),
+			},
+			fx.Annotated{
+				Name: "jwt_verification_cache",
+				Target: arrange.UnmarshalKey(fmt.Sprintf("%s.verificationCache", configKey),
+					bascule.VerificationCacheConfig{}),
+			},
 			fx.Annotated{
 				Group: "bascule_constructor_options",
 				Target: func(f BearerTokenFactory) (COption, error) {
 					if f.Parser == nil {
 						f.Parser = bascule.DefaultJWTParser
 					}
+					if f.VerificationCache.TTL > 0 || f.VerificationCache.MaxEntries > 0 {
+						cache, err := bascule.NewVerificationCache(f.Parser, f.VerificationCache)
+						if err != nil {
+							return nil, err
+						}
+						f.Parser = cache
+					}
 					return WithTokenFactory(BearerAuthorization, f), nil
 				},
 			},
@@ -19,19 +19,24 @@ package basculehttp
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule"
 	"go.uber.org/fx"
 )
 
@@ -46,6 +51,7 @@ func TestBearerTokenFactory(t *testing.T) {
 		resolveCalled bool
 		resolveErr    error
 		claims        jwt.Claims
+		mapper        bascule.ClaimsMapper
 		validToken    bool
 		expectedToken bascule.Token
 		expectedErr   error
@@ -118,6 +124,20 @@ func TestBearerTokenFactory(t *testing.T) {
 			},
 			expectedErr: ErrInvalidPrincipal,
 		},
+		{
+			description:   "Map Claims Error",
+			value:         "abcd",
+			parseCalled:   true,
+			resolveCalled: true,
+			validToken:    true,
+			claims: &bascule.ClaimsWithLeeway{
+				MapClaims: jwt.MapClaims{jwtPrincipalKey: "test"},
+			},
+			mapper: bascule.ClaimsMapperFunc(func(bascule.Attributes) (bascule.Attributes, error) {
+				return nil, errors.New("map claims test error")
+			}),
+			expectedErr: errors.New("map claims test error"),
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
@@ -140,6 +160,7 @@ func TestBearerTokenFactory(t *testing.T) {
 				DefaultKeyID: "default key id",
 				Resolver:     r,
 				Parser:       p,
+				ClaimsMapper: tc.mapper,
 			}
 			req := httptest.NewRequest("get", "/", nil)
 			token, err := btf.ParseAndValidate(context.Background(), req, "", tc.value)
@@ -154,6 +175,98 @@ func TestBearerTokenFactory(t *testing.T) {
 	}
 }
 
+func TestBearerTokenFactoryPolicy(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	now := time.Now().Unix()
+	policy := bascule.ClaimsPolicy{
+		Audiences:  []string{"svc"},
+		Issuers:    []string{"issuer"},
+		Algorithms: []string{"ES256"},
+		MaxTTL:     time.Hour,
+		ClockSkew:  time.Minute,
+	}
+	tests := []struct {
+		description   string
+		method        jwt.SigningMethod
+		signingKey    interface{}
+		claims        jwt.MapClaims
+		resolveCalled bool
+		expectedErr   error
+	}{
+		{
+			description:   "Success",
+			claims:        jwt.MapClaims{jwtPrincipalKey: "test", "iss": "issuer", "aud": "svc", "iat": now, "exp": now + 60},
+			resolveCalled: true,
+		},
+		{
+			description:   "Expired Within Skew",
+			claims:        jwt.MapClaims{jwtPrincipalKey: "test", "iss": "issuer", "aud": "svc", "iat": now - 90, "exp": now - 30},
+			resolveCalled: true,
+		},
+		{
+			description: "Algorithm Not Allowed",
+			method:      jwt.SigningMethodHS256,
+			signingKey:  []byte("secret"),
+			claims:      jwt.MapClaims{jwtPrincipalKey: "test", "iss": "issuer", "aud": "svc", "exp": now + 60},
+			expectedErr: bascule.ErrAlgorithmNotAllowed,
+		},
+		{
+			description:   "Wrong Audience",
+			claims:        jwt.MapClaims{jwtPrincipalKey: "test", "iss": "issuer", "aud": "other", "exp": now + 60},
+			resolveCalled: true,
+			expectedErr:   bascule.ErrAudienceNotAllowed,
+		},
+		{
+			description:   "Wrong Issuer",
+			claims:        jwt.MapClaims{jwtPrincipalKey: "test", "iss": "other", "aud": "svc", "exp": now + 60},
+			resolveCalled: true,
+			expectedErr:   bascule.ErrIssuerNotAllowed,
+		},
+		{
+			description:   "TTL Too Long",
+			claims:        jwt.MapClaims{jwtPrincipalKey: "test", "iss": "issuer", "aud": "svc", "iat": now, "exp": now + 7200},
+			resolveCalled: true,
+			expectedErr:   bascule.ErrTTLTooLong,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			method, signingKey := tc.method, tc.signingKey
+			if method == nil {
+				method, signingKey = jwt.SigningMethodES256, key
+			}
+			value, err := jwt.NewWithClaims(method, tc.claims).SignedString(signingKey)
+			require.NoError(t, err)
+
+			r := new(MockResolver)
+			k := new(mockKey)
+			if tc.resolveCalled {
+				r.On("Resolve", mock.Anything, "default").Return(k, nil).Once()
+				k.On("Public").Return(&key.PublicKey).Once()
+			}
+			btf := BearerTokenFactory{
+				DefaultKeyID: "default",
+				Resolver:     r,
+				Parser:       bascule.DefaultJWTParser,
+				Policy:       policy,
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			token, err := btf.ParseAndValidate(context.Background(), req, BearerAuthorization, value)
+			r.AssertExpectations(t)
+			k.AssertExpectations(t)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(token)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("test", token.Principal())
+		})
+	}
+}
+
 func TestProvideBearerTokenFactory(t *testing.T) {
 	type In struct {
 		fx.In
@@ -167,6 +280,15 @@ good:
       uri: "http://test:1111/keys/{keyId}"
     purpose: 0
     updateInterval: 604800000000000
+cached:
+  key:
+    factory:
+      uri: "http://test:1111/keys/{keyId}"
+    purpose: 0
+    updateInterval: 604800000000000
+  verificationCache:
+    ttl: 30s
+    maxEntries: 100
 `
 	v := viper.New()
 	v.SetConfigType("yaml")
@@ -185,6 +307,12 @@ good:
 			optional:       false,
 			optionExpected: true,
 		},
+		{
+			description:    "Success with verification cache",
+			key:            "cached",
+			optional:       false,
+			optionExpected: true,
+		},
 		{
 			description: "Silent failure",
 			key:         "bad",
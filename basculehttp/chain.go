@@ -18,10 +18,145 @@
 package basculehttp
 
 import (
+	"context"
+	"errors"
+	"net/http"
+
 	"github.com/justinas/alice"
+	"github.com/s-srakshe/bascule"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNoTokenFactories       = errors.New("no token factories configured")
+	ErrNoEnforcerRules        = errors.New("no enforcer rules configured, so every request would be forbidden")
+	ErrMissingNotFoundHandler = errors.New("not found behavior needs a handler or enforcer that isn't configured")
 )
 
+// AuthOption is any function that modifies an auth chain - used to configure
+// the middleware built by NewAuthChain and NewAuthHandler.
+type AuthOption func(*authChain)
+
+type authChain struct {
+	cOptions  []COption
+	eOptions  []EOption
+	lOptions  []LOption
+	listeners []Listener
+	redactor  *bascule.Redactor
+}
+
+// WithConstructorOptions adds options for the constructor middleware.
+func WithConstructorOptions(options ...COption) AuthOption {
+	return func(a *authChain) {
+		a.cOptions = append(a.cOptions, options...)
+	}
+}
+
+// WithEnforcerOptions adds options for the enforcer middleware.
+func WithEnforcerOptions(options ...EOption) AuthOption {
+	return func(a *authChain) {
+		a.eOptions = append(a.eOptions, options...)
+	}
+}
+
+// WithListenerOptions adds options for the listener middleware.  Listeners
+// should be added with WithListeners, so that the chain's redactor applies to
+// them.
+func WithListenerOptions(options ...LOption) AuthOption {
+	return func(a *authChain) {
+		a.lOptions = append(a.lOptions, options...)
+	}
+}
+
+// WithListeners adds Listeners called after a request passes the enforcer.
+func WithListeners(listeners ...Listener) AuthOption {
+	return func(a *authChain) {
+		a.listeners = append(a.listeners, listeners...)
+	}
+}
+
+// WithAuthLogger sets the function used by the constructor, the enforcer,
+// and the listener middleware to get the logger from the context.
+func WithAuthLogger(getLogger func(context.Context) *zap.Logger) AuthOption {
+	return func(a *authChain) {
+		a.cOptions = append(a.cOptions, WithCLogger(getLogger))
+		a.eOptions = append(a.eOptions, WithELogger(getLogger))
+		a.lOptions = append(a.lOptions, WithLLogger(getLogger))
+	}
+}
+
+// WithAuthErrorResponseFunc sets the function both the constructor and the
+// enforcer call when an error occurs.
+func WithAuthErrorResponseFunc(f OnErrorResponse) AuthOption {
+	return func(a *authChain) {
+		a.cOptions = append(a.cOptions, WithCErrorResponseFunc(f))
+		a.eOptions = append(a.eOptions, WithEErrorResponseFunc(f))
+	}
+}
+
+// WithAuthSkip adds a SkipFunc for requests that bypass the whole chain.
+func WithAuthSkip(f SkipFunc) AuthOption {
+	return func(a *authChain) {
+		a.cOptions = append(a.cOptions, WithCSkip(f))
+	}
+}
+
+// WithAuthRedactor sets the redactor used by both the constructor and the
+// enforcer, and masks the tokens given to the Listeners.
+func WithAuthRedactor(r *bascule.Redactor) AuthOption {
+	return func(a *authChain) {
+		a.cOptions = append(a.cOptions, WithCRedactor(r))
+		a.eOptions = append(a.eOptions, WithERedactor(r))
+		if r != nil {
+			a.redactor = r
+		}
+	}
+}
+
+// NewAuthChain builds the constructor, enforcer, and listener middleware and
+// chains them in that order.  The configuration is checked so that mistakes
+// that would reject every request are returned as errors rather than found in
+// production.
+func NewAuthChain(options ...AuthOption) (alice.Chain, error) {
+	a := &authChain{}
+	for _, o := range options {
+		if o != nil {
+			o(a)
+		}
+	}
+
+	c := newConstructor(a.cOptions...)
+	if len(c.authorizations) == 0 {
+		return alice.Chain{}, ErrNoTokenFactories
+	}
+	e := newEnforcer(a.eOptions...)
+	if len(e.rules) == 0 && e.defaultRules == nil &&
+		(e.notFoundBehavior == Forbid || e.notFoundBehavior == Challenge) {
+		return alice.Chain{}, ErrNoEnforcerRules
+	}
+	if (e.notFoundBehavior == Handle && e.notFoundHandler == nil) ||
+		(e.notFoundBehavior == Defer && e.notFoundEnforcer == nil) {
+		return alice.Chain{}, ErrMissingNotFoundHandler
+	}
+	listeners := make([]Listener, len(a.listeners))
+	for i, l := range a.listeners {
+		listeners[i] = NewRedactingListener(a.redactor, l)
+	}
+	l := NewListenerDecoratorWithOptions(append([]LOption{WithLListeners(listeners...)}, a.lOptions...)...)
+	return alice.New(c.decorate, e.decorate, l), nil
+}
+
+// NewAuthHandler wraps the handler given with the middleware built by
+// NewAuthChain.
+func NewAuthHandler(next http.Handler, options ...AuthOption) (http.Handler, error) {
+	chain, err := NewAuthChain(options...)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Then(next), nil
+}
+
 // MetricListenerIn is used for uber fx wiring.
 type MetricListenerIn struct {
 	fx.In
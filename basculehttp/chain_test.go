@@ -0,0 +1,127 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuthChain(t *testing.T) {
+	basic := WithConstructorOptions(WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"}))
+	tests := []struct {
+		description string
+		options     []AuthOption
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			options: []AuthOption{
+				basic,
+				WithEnforcerOptions(WithRules(BasicAuthorization, basculechecks.AllowAll())),
+			},
+		},
+		{
+			description: "Allow Without Rules Success",
+			options:     []AuthOption{basic, WithEnforcerOptions(WithNotFoundBehavior(Allow)), nil},
+		},
+		{
+			description: "No Token Factories Error",
+			options:     []AuthOption{WithEnforcerOptions(WithNotFoundBehavior(Allow))},
+			expectedErr: ErrNoTokenFactories,
+		},
+		{
+			description: "No Rules Error",
+			options:     []AuthOption{basic},
+			expectedErr: ErrNoEnforcerRules,
+		},
+		{
+			description: "Missing Handler Error",
+			options:     []AuthOption{basic, WithEnforcerOptions(WithNotFoundBehavior(Handle))},
+			expectedErr: ErrMissingNotFoundHandler,
+		},
+		{
+			description: "Missing Enforcer Error",
+			options:     []AuthOption{basic, WithEnforcerOptions(WithNotFoundBehavior(Defer))},
+			expectedErr: ErrMissingNotFoundHandler,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h, err := NewAuthHandler(next, tc.options...)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(h)
+				return
+			}
+			assert.NoError(err)
+			assert.NotNil(h)
+		})
+	}
+}
+
+func TestNewAuthHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	listener := new(mockListener)
+	listener.On("OnAuthenticated", mock.MatchedBy(func(a bascule.Authentication) bool {
+		return a.Token.Principal() == bascule.DefaultRedactionMask
+	})).Once()
+	var reasons []ErrorResponseReason
+	h, err := NewAuthHandler(next,
+		WithConstructorOptions(WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass", "other": "pass"})),
+		WithEnforcerOptions(WithRules(BasicAuthorization, bascule.ValidatorFunc(func(_ context.Context, t bascule.Token) error {
+			if t.Principal() != "user" {
+				return errors.New("not allowed")
+			}
+			return nil
+		}))),
+		WithAuthErrorResponseFunc(func(r ErrorResponseReason, _ error) {
+			reasons = append(reasons, r)
+		}),
+		WithListeners(listener),
+		WithAuthRedactor(bascule.NewRedactor(bascule.WithSensitivePrincipal())),
+	)
+	require.NoError(err)
+
+	serve := func(creds string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if creds != "" {
+			req.Header.Set(DefaultHeaderName, "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(http.StatusOK, serve("user:pass"))
+	assert.Equal(http.StatusUnauthorized, serve(""))
+	assert.Equal(http.StatusForbidden, serve("other:pass"))
+	assert.Equal([]ErrorResponseReason{MissingHeader, ChecksFailed}, reasons)
+	listener.AssertExpectations(t)
+}
@@ -19,13 +19,16 @@ package basculehttp
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/justinas/alice"
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/sallust"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -50,9 +53,13 @@ const (
 )
 
 var (
-	errNoAuthHeader    = errors.New("no authorization header")
-	errBadAuthHeader   = errors.New("unexpected authorization header value")
-	errKeyNotSupported = errors.New("key not supported")
+	errNoAuthHeader    = bascule.NewClassError(bascule.MissingCredentialsClass, "no authorization header")
+	errBadAuthHeader   = bascule.NewClassError(bascule.MalformedClass, "unexpected authorization header value")
+	errKeyNotSupported = bascule.NewClassError(bascule.MalformedClass, "key not supported")
+
+	// ErrMultipleCredentials is returned when a request carries more than
+	// one set of credentials and the constructor rejects them.
+	ErrMultipleCredentials = bascule.NewClassError(bascule.MalformedClass, "multiple authorization credentials")
 )
 
 // TokenFactory is a strategy interface responsible for creating and validating
@@ -73,51 +80,242 @@ type COptionsIn struct {
 	Options []COption `group:"bascule_constructor_options"`
 }
 
+// SchemeMatching is how the scheme of an authorization header is matched
+// against the schemes of the token factories.
+type SchemeMatching int
+
+const (
+	// DefaultSchemeMatching matches schemes case-insensitively, as RFC 7235
+	// requires, when the default header delimiter is used.  Otherwise,
+	// schemes must match exactly.
+	DefaultSchemeMatching SchemeMatching = iota
+
+	// CaseSensitiveSchemes requires schemes to match exactly.
+	CaseSensitiveSchemes
+
+	// CaseInsensitiveSchemes matches schemes case-insensitively with any
+	// header delimiter.
+	CaseInsensitiveSchemes
+)
+
+// MultipleCredentials is what the constructor does with a request carrying
+// more than one set of credentials, either as multiple authorization headers
+// or as a header whose values a proxy joined with commas.
+type MultipleCredentials int
+
+const (
+	// FirstCredentials authenticates the request with the first credentials.
+	FirstCredentials MultipleCredentials = iota
+
+	// LastCredentials authenticates the request with the last credentials.
+	LastCredentials
+
+	// TryAllCredentials tries the credentials in order until one
+	// authenticates the request.  If none do, the request is denied with the
+	// first credentials' error.  Each failure counts toward lockouts.
+	TryAllCredentials
+
+	// RejectMultipleCredentials denies the request with ErrMultipleCredentials.
+	RejectMultipleCredentials
+)
+
 type constructor struct {
 	headerName          string
 	headerDelimiter     string
 	authorizations      map[bascule.Authorization]TokenFactory
+	schemeAliases       map[string]bascule.Authorization
+	decorators          []TokenFactoryDecorator
+	schemeDecorators    map[bascule.Authorization][]TokenFactoryDecorator
+	schemeMatching      SchemeMatching
+	multipleCredentials MultipleCredentials
+	proxyMode           ProxyMode
+	propagate           bool
 	getLogger           func(context.Context) *zap.Logger
 	parseURL            ParseURL
 	onErrorResponse     OnErrorResponse
 	onErrorHTTPResponse OnErrorHTTPResponse
+	onAuthenticated     []OnAuthenticated
+	errorBodies         *errorBodies
+	redactor            *bascule.Redactor
+	secondaries         []secondaryCredential
+	enrichers           []bascule.TokenEnricher
+	clientIPHeader      string
+	trustedProxies      int
+	challengers         []Challenger
+	hooks               hooks
+	skips               skipFuncs
+	preflight           *PreflightConfig
+	headersOnly         bool
+	sessions            bool
+	captureTime         bool
+	digester            *bodyDigester
+	statusMap           StatusMap
+	reasonStatuses      ReasonStatusMap
+	stageStatuses       bool
+	expiryCancel        bool
+	expiryGrace         time.Duration
+	requestIDHeaders    []string
+	ipChecker           IPChecker
+}
+
+// Challenger is implemented by token factories that want to advertise their
+// scheme in the WWW-Authenticate header when a request couldn't be
+// authenticated.
+type Challenger interface {
+	// Challenges returns the WWW-Authenticate values to add to the response.
+	// The error is why authentication failed, if a token factory was tried.
+	Challenges(*http.Request, error) []string
 }
 
 func (c *constructor) authenticationOutput(logger *zap.Logger, request *http.Request) (bascule.Authentication, ErrorResponseReason, error) {
+	auth, _, reason, err := c.authenticateRequest(request)
+	return auth, reason, err
+}
+
+// authenticateRequest builds the request's Authentication, also returning
+// the authorization value it was built from.
+func (c *constructor) authenticateRequest(request *http.Request) (bascule.Authentication, string, ErrorResponseReason, error) {
+	if c.ipChecker != nil {
+		if err := c.ipChecker.CheckIP(request.Context(), c.clientIP(request)); err != nil {
+			return bascule.Authentication{}, "", IPBlocked, err
+		}
+	}
 	urlVal := *request.URL // copy the URL before modifying it
 	u, err := c.parseURL(&urlVal)
 	if err != nil {
-		return bascule.Authentication{}, GetURLFailed, fmt.Errorf("failed to parse url '%v': %v", request.URL, err)
+		return bascule.Authentication{}, "", GetURLFailed, fmt.Errorf("failed to parse url '%v': %v", request.URL, err)
 	}
-	authorization := request.Header.Get(c.headerName)
-	if len(authorization) == 0 {
-		return bascule.Authentication{}, MissingHeader, errNoAuthHeader
+	credentials := c.credentials(request.Header.Values(c.authorizationHeader(request.Header)))
+	if len(credentials) == 0 {
+		return bascule.Authentication{}, "", MissingHeader, errNoAuthHeader
 	}
-	i := strings.Index(authorization, c.headerDelimiter)
-	if i < 1 {
-		return bascule.Authentication{}, InvalidHeader, errBadAuthHeader
-	}
-
-	key := bascule.Authorization(authorization[:i])
-	tf, supported := c.authorizations[key]
-	if !supported {
-		return bascule.Authentication{}, KeyNotSupported, fmt.Errorf("%w: [%v]", errKeyNotSupported, key)
+	if len(credentials) > 1 {
+		switch c.multipleCredentials {
+		case LastCredentials:
+			credentials = credentials[len(credentials)-1:]
+		case TryAllCredentials:
+		case RejectMultipleCredentials:
+			return bascule.Authentication{}, "", InvalidHeader, fmt.Errorf("%w: got %d", ErrMultipleCredentials, len(credentials))
+		default:
+			credentials = credentials[:1]
+		}
 	}
 
 	ctx := request.Context()
-	token, err := tf.ParseAndValidate(ctx, request, key, authorization[i+len(c.headerDelimiter):])
+	var (
+		key        bascule.Authorization
+		token      bascule.Token
+		reason     ErrorResponseReason
+		credential string
+	)
+	for i, authorization := range credentials {
+		k, t, r, e := c.authenticate(ctx, request, authorization)
+		if e == nil {
+			key, token, credential, err = k, t, authorization, nil
+			break
+		}
+		if i == 0 {
+			reason, err = r, e
+		}
+	}
+	if err != nil {
+		return bascule.Authentication{}, "", reason, err
+	}
+	token, err = c.parseSecondaries(ctx, request, token)
 	if err != nil {
-		return bascule.Authentication{}, ParseFailed, fmt.Errorf("failed to parse and validate token: %v", err)
+		return bascule.Authentication{}, "", ParseFailed, err
+	}
+	for _, e := range c.enrichers {
+		token, err = e.Enrich(ctx, token)
+		if err != nil {
+			return bascule.Authentication{}, "", ParseFailed, fmt.Errorf("failed to enrich token: %w", err)
+		}
 	}
 
 	return bascule.Authentication{
 		Authorization: key,
 		Token:         token,
 		Request: bascule.Request{
-			URL:    u,
-			Method: request.Method,
+			URL:       u,
+			Method:    request.Method,
+			ClientIP:  c.clientIP(request),
+			UserAgent: request.UserAgent(),
 		},
-	}, -1, nil
+	}, credential, -1, nil
+}
+
+// authenticate parses and validates the token of one authorization value.
+func (c *constructor) authenticate(ctx context.Context, request *http.Request, authorization string) (bascule.Authorization, bascule.Token, ErrorResponseReason, error) {
+	key, value, tf, supported, err := c.parseHeader(authorization)
+	if err != nil {
+		return "", nil, InvalidHeader, err
+	}
+	if !supported {
+		return "", nil, KeyNotSupported, fmt.Errorf("%w: [%v]", errKeyNotSupported, key)
+	}
+
+	token, err := tf.ParseAndValidate(ctx, request, key, value)
+	if err != nil {
+		reason := ParseFailed
+		var locked *LockedOutError
+		var header *JWTHeaderError
+		if errors.As(err, &locked) {
+			reason = LockedOut
+		} else if errors.As(err, &header) {
+			reason = header.ResponseReason()
+		}
+		return "", nil, reason, fmt.Errorf("failed to parse and validate token: %w", err)
+	}
+	return key, token, -1, nil
+}
+
+// credentials returns the credentials of the authorization header fields.
+// With the default delimiter, values that a proxy joined with commas are
+// split back into separate credentials.
+func (c *constructor) credentials(fields []string) []string {
+	if len(fields) == 1 && len(fields[0]) > 0 &&
+		(c.headerDelimiter != DefaultHeaderDelimiter || strings.IndexByte(fields[0], ',') < 0) {
+		return fields
+	}
+	var list []string
+	for _, f := range fields {
+		if c.headerDelimiter != DefaultHeaderDelimiter {
+			if len(f) > 0 {
+				list = append(list, f)
+			}
+			continue
+		}
+		list = SplitCredentials(list, f)
+	}
+	return list
+}
+
+// clientIP returns the address the nearest trusted proxy saw in the client IP
+// header, if one is configured and present, otherwise the host of the
+// request's RemoteAddr.
+func (c *constructor) clientIP(r *http.Request) string {
+	if c.clientIPHeader != "" {
+		if values := r.Header.Values(c.clientIPHeader); len(values) > 0 {
+			// proxies append to the header, so only the addresses at the end
+			// were added by trusted proxies.  Those before them are whatever
+			// the client sent.
+			addrs := strings.Split(strings.Join(values, ","), ",")
+			proxies := c.trustedProxies
+			if proxies <= 0 {
+				proxies = 1
+			}
+			if i := len(addrs) - proxies; i >= 0 {
+				if addr := strings.TrimSpace(addrs[i]); addr != "" {
+					return addr
+				}
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
 func (c *constructor) decorate(next http.Handler) http.Handler {
@@ -126,22 +324,199 @@ func (c *constructor) decorate(next http.Handler) http.Handler {
 		if logger == nil {
 			logger = sallust.Get(r.Context())
 		}
-		auth, errReason, err := c.authenticationOutput(logger, r)
+		if c.preflight != nil && isPreflight(r) {
+			c.preflight.respond(w, r)
+			return
+		}
+		if c.skips.skip(r) {
+			next.ServeHTTP(w, withSkipped(r))
+			return
+		}
+		if id, ok := requestID(r, c.requestIDHeaders); ok {
+			r = r.WithContext(bascule.WithRequestID(r.Context(), id))
+			w.Header().Set(RequestIDHeader, id)
+			logger = withRequestIDField(r.Context(), logger)
+		}
+		r = c.hooks.run(w, r, HookEvent{Stage: BeforeDecision})
+		received := time.Now()
+		ar := r
+		if c.headersOnly {
+			ar = headersOnly(r)
+		}
+		auth, credential, errReason, err := c.authenticateRequest(ar)
+		parseDuration := time.Since(received)
 		if err != nil {
-			logger.Error(err.Error(), zap.String("auth", r.Header.Get(c.headerName)))
+			logger.Error(err.Error(), append(outcomeFields(OutcomeUnauthenticated, errReason),
+				zap.String("auth", c.loggableAuth(r)))...)
 			c.onErrorResponse(errReason, err)
+			setBackoffHeaders(w.Header(), err)
+			status, ok := c.failureStatus(errReason, err)
+			if !c.stageStatuses || status == http.StatusUnauthorized {
+				c.setChallenges(w, r, errReason, err)
+			}
+			if c.stageStatuses && status == http.StatusUnauthorized && len(w.Header().Values(AuthTypeHeaderKey)) == 0 {
+				w.Header().Add(AuthTypeHeaderKey, string(BearerAuthorization))
+			}
+			c.hooks.run(w, r, HookEvent{Stage: AfterDeny, Reason: errReason, Err: err})
+			w = c.proxyWriter(c.errorBodies.writer(w, r, errReason))
+			if ok {
+				w.WriteHeader(status)
+				return
+			}
 			c.onErrorHTTPResponse(w, errReason)
 			return
 		}
+		auth.Request.RequestID, _ = bascule.RequestIDFromContext(r.Context())
+		if err := c.captureRequest(r, &auth, received); err != nil {
+			logger.Error(err.Error(), outcomeFields(OutcomeError, ParseFailed)...)
+			c.onErrorResponse(ParseFailed, err)
+			c.hooks.run(w, r, HookEvent{Stage: AfterDeny, Auth: auth, Reason: ParseFailed, Err: err})
+			WriteResponse(c.errorBodies.writer(w, r, ParseFailed), http.StatusBadRequest, err)
+			return
+		}
+		// checking the level first saves allocating the fields when debug
+		// logging is off, as it usually is.
+		if ce := logger.Check(zap.DebugLevel, "request authenticated"); ce != nil {
+			ce.Write(zap.String(OutcomeLogKey, string(OutcomeAuthenticated)),
+				zap.String("authorization", string(auth.Authorization)))
+		}
 		ctx := bascule.WithAuthentication(r.Context(), auth)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		if c.propagate {
+			ctx = withPropagatedCredential(ctx, credential)
+		}
+		r = r.WithContext(ctx)
+		if len(c.onAuthenticated) > 0 {
+			event := AuthenticatedEvent{Auth: auth, Factory: auth.Authorization, ParseDuration: parseDuration}
+			for _, f := range c.onAuthenticated {
+				f(r, event)
+			}
+		}
+		r = c.hooks.run(w, r, HookEvent{Stage: AfterAllow, Auth: auth})
+		if c.sessions || c.expiryCancel {
+			var s *Session
+			r, s = c.newSession(r, auth)
+			defer s.close()
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
+// parseHeader splits the authorization header into its scheme and value, and
+// finds the TokenFactory for the scheme.  With the default delimiter, the
+// header is parsed strictly by ParseCredentials.  A custom delimiter splits the
+// header at its first occurrence.
+func (c *constructor) parseHeader(authorization string) (bascule.Authorization, string, TokenFactory, bool, error) {
+	var scheme, value string
+	if c.headerDelimiter != DefaultHeaderDelimiter {
+		var ok bool
+		scheme, value, ok = splitAuthorization(authorization, c.headerDelimiter)
+		if !ok {
+			return "", "", nil, false, errBadAuthHeader
+		}
+	} else {
+		creds, err := ParseCredentials(authorization)
+		if err != nil {
+			return "", "", nil, false, err
+		}
+		scheme, value = creds.Scheme, creds.Value
+	}
+	key, tf, supported := c.lookupScheme(scheme)
+	return key, value, tf, supported, nil
+}
+
+// lookupScheme finds the TokenFactory for a scheme, trying an exact match
+// before the aliases and, if enabled, a case-insensitive match.  The key
+// returned is the scheme the TokenFactory was registered with.
+func (c *constructor) lookupScheme(scheme string) (bascule.Authorization, TokenFactory, bool) {
+	key := bascule.Authorization(scheme)
+	if tf, ok := c.authorizations[key]; ok {
+		return key, tf, true
+	}
+	if target, ok := c.schemeAliases[scheme]; ok {
+		if tf, ok := c.authorizations[target]; ok {
+			return target, tf, true
+		}
+	}
+	if !c.foldSchemes() {
+		return key, nil, false
+	}
+	for k, tf := range c.authorizations {
+		if strings.EqualFold(string(k), scheme) {
+			return k, tf, true
+		}
+	}
+	for alias, target := range c.schemeAliases {
+		if strings.EqualFold(alias, scheme) {
+			if tf, ok := c.authorizations[target]; ok {
+				return target, tf, true
+			}
+		}
+	}
+	return key, nil, false
+}
+
+// foldSchemes returns true if schemes should be matched case-insensitively.
+func (c *constructor) foldSchemes() bool {
+	switch c.schemeMatching {
+	case CaseSensitiveSchemes:
+		return false
+	case CaseInsensitiveSchemes:
+		return true
+	default:
+		return c.headerDelimiter == DefaultHeaderDelimiter
+	}
+}
+
+// failureStatus returns the status configured for a failed authentication:
+// the reason's status, then the status for the failure's stage if stage
+// statuses are on, then the StatusMap's.  It returns false if the
+// OnErrorHTTPResponse should decide.
+func (c *constructor) failureStatus(reason ErrorResponseReason, err error) (int, bool) {
+	if status, ok := c.reasonStatuses.Status(reason); ok {
+		return status, true
+	}
+	if c.stageStatuses {
+		if status, ok := stageStatus(reason, err); ok {
+			return status, true
+		}
+	}
+	return c.statusMap.Status(err)
+}
+
+// setChallenges adds the WWW-Authenticate challenges of the configured
+// challengers, unless the failure wasn't about the client's credentials.
+func (c *constructor) setChallenges(w http.ResponseWriter, r *http.Request, reason ErrorResponseReason, err error) {
+	if reason == LockedOut || reason == GetURLFailed || reason == IPBlocked {
+		return
+	}
+	for _, ch := range c.challengers {
+		for _, v := range ch.Challenges(r, err) {
+			w.Header().Add(AuthTypeHeaderKey, v)
+		}
+	}
+}
+
+// loggableAuth returns the authorization header value to be logged.  If a
+// redactor is configured, only the authorization type is kept.
+func (c *constructor) loggableAuth(r *http.Request) string {
+	authorization := r.Header.Get(c.authorizationHeader(r.Header))
+	if c.redactor == nil || len(authorization) == 0 {
+		return authorization
+	}
+	if i := strings.Index(authorization, c.headerDelimiter); i > 0 {
+		return authorization[:i+len(c.headerDelimiter)] + c.redactor.Mask()
+	}
+	return c.redactor.Mask()
+}
+
 // NewConstructor creates an Alice-style decorator function that acts as
 // middleware: parsing the http request to get a Token, which is added to the
 // context.
 func NewConstructor(options ...COption) func(http.Handler) http.Handler {
+	return newConstructor(options...).decorate
+}
+
+func newConstructor(options ...COption) *constructor {
 	c := &constructor{
 		headerName:          DefaultHeaderName,
 		headerDelimiter:     DefaultHeaderDelimiter,
@@ -158,8 +533,9 @@ func NewConstructor(options ...COption) func(http.Handler) http.Handler {
 		}
 		o(c)
 	}
+	c.decorateTokenFactories()
 
-	return c.decorate
+	return c
 }
 
 // WithHeaderName sets the headername and verifies it's valid.  The headername
@@ -181,11 +557,27 @@ func WithHeaderDelimiter(delimiter string) COption {
 	}
 }
 
-// WithTokenFactory sets the TokenFactory for the constructor to use.
+// WithTokenFactory sets the TokenFactory for the constructor to use.  If the
+// TokenFactory is also a Challenger, its challenges are added to responses
+// for requests that couldn't be authenticated.
 func WithTokenFactory(key bascule.Authorization, tf TokenFactory) COption {
 	return func(c *constructor) {
 		if tf != nil {
 			c.authorizations[key] = tf
+			if ch, ok := tf.(Challenger); ok {
+				c.challengers = append(c.challengers, ch)
+			}
+		}
+	}
+}
+
+// WithChallenger adds a Challenger whose challenges are added to responses
+// for requests that couldn't be authenticated.  This is useful when a
+// Challenger's TokenFactory is wrapped by another.
+func WithChallenger(ch Challenger) COption {
+	return func(c *constructor) {
+		if ch != nil {
+			c.challengers = append(c.challengers, ch)
 		}
 	}
 }
@@ -229,6 +621,231 @@ func WithCErrorHTTPResponseFunc(f OnErrorHTTPResponse) COption {
 	}
 }
 
+// WithCSkip adds a SkipFunc for requests that bypass authentication.  The
+// enforcer and listeners let requests skipped by the constructor through.
+func WithCSkip(f SkipFunc) COption {
+	return func(c *constructor) {
+		c.skips = c.skips.add(f)
+	}
+}
+
+// WithCPreflightPassThrough lets CORS preflight requests through to the next
+// handler without authentication, since browsers never send credentials with
+// them.
+func WithCPreflightPassThrough() COption {
+	return WithCSkip(SkipPreflight())
+}
+
+// WithCPreflightResponse makes the constructor answer CORS preflight requests
+// itself with the configuration given, rather than responding with a 401.
+func WithCPreflightResponse(config PreflightConfig) COption {
+	return func(c *constructor) {
+		c.preflight = &config
+	}
+}
+
+// WithCHook adds a Hook run by the constructor at the stage given.
+func WithCHook(stage HookStage, hook Hook) COption {
+	return func(c *constructor) {
+		c.hooks = c.hooks.add(stage, hook)
+	}
+}
+
+// WithCHeadersOnly makes authentication use only the request's headers.  Token
+// factories get a copy of the request whose body fails with
+// ErrBodyReadDuringAuth, so streaming and chunked bodies reach the handler
+// untouched.
+func WithCHeadersOnly() COption {
+	return func(c *constructor) {
+		c.headersOnly = true
+	}
+}
+
+// WithCExpiryCancel cancels an authenticated request's context when its token
+// expires, plus the grace period, so long-lived requests such as streams end
+// once their credentials are no longer valid.  Handlers should stop when the
+// context is done; TokenExpired tells them why.  Renewing the request's Session
+// pushes the cancellation back.  Tokens without an exp attribute aren't
+// affected.
+func WithCExpiryCancel(grace time.Duration) COption {
+	return func(c *constructor) {
+		c.expiryCancel = true
+		if grace > 0 {
+			c.expiryGrace = grace
+		}
+	}
+}
+
+// WithCSessions adds a Session to the context of authenticated requests, so
+// long-lived requests can check their token's expiry and renew it.
+func WithCSessions() COption {
+	return func(c *constructor) {
+		c.sessions = true
+	}
+}
+
+// WithCRequestTime records when each request was received in its
+// Authentication, for validators with freshness rules.
+func WithCRequestTime() COption {
+	return func(c *constructor) {
+		c.captureTime = true
+	}
+}
+
+// WithCBodyDigest adds a digest of each authenticated request's body to its
+// Authentication, for validators checking signatures.  The body is buffered so
+// the handler can still read it.  Bodies larger than maxBytes aren't hashed
+// and get no digest.  No digest is taken when authentication only uses
+// headers.  The option is ignored if the hash isn't available.
+func WithCBodyDigest(h crypto.Hash, maxBytes int64) COption {
+	return func(c *constructor) {
+		if d, err := newBodyDigester(h, maxBytes); err == nil {
+			c.digester = d
+		}
+	}
+}
+
+// WithCStatusMap sets the statuses written when authentication fails by the
+// class of the error, such as DefaultStatusMap.  Errors whose class isn't
+// mapped are handled by the OnErrorHTTPResponse.  By default, only the
+// OnErrorHTTPResponse is used.
+func WithCStatusMap(m StatusMap) COption {
+	return func(c *constructor) {
+		c.statusMap = m
+	}
+}
+
+// WithCReasonStatusMap sets the statuses written when authentication fails by
+// the reason it failed, like writing a 401 instead of a 403 for an
+// unsupported key.  The reason's status is used before the StatusMap's and
+// the OnErrorHTTPResponse's.
+func WithCReasonStatusMap(m ReasonStatusMap) COption {
+	return func(c *constructor) {
+		c.reasonStatuses = m
+	}
+}
+
+// WithCStageStatuses writes a 401 with a WWW-Authenticate challenge for
+// authentication failures and a 403, without a challenge, for the rest, as
+// decided by StageOf.  Throttling and outages get a 429 and 503.  The
+// challenges come from the configured Challengers, falling back to Bearer.
+// A ReasonStatusMap is still used first.  Use it with WithEStageStatuses for
+// the same behavior in the enforcer.
+func WithCStageStatuses() COption {
+	return func(c *constructor) {
+		c.stageStatuses = true
+	}
+}
+
+// WithCErrorBodies writes a body with each failed response, in the media type
+// the request's Accept header prefers.  An OnErrorHTTPResponse that writes its
+// own body must set the Content-Type first.  A config whose default media type
+// has no encoder is ignored.
+func WithCErrorBodies(config ErrorBodyConfig) COption {
+	return func(c *constructor) {
+		if eb := newErrorBodies(config); eb != nil {
+			c.errorBodies = eb
+		}
+	}
+}
+
+// WithCTokenEnricher adds a TokenEnricher, such as a bascule.GroupExpander,
+// that is run on each token after it is parsed and validated.  Enrichers run
+// in the order they are added, and an error fails authentication.
+func WithCTokenEnricher(e bascule.TokenEnricher) COption {
+	return func(c *constructor) {
+		if e != nil {
+			c.enrichers = append(c.enrichers, e)
+		}
+	}
+}
+
+// WithCClientIPHeader sets the header, such as X-Forwarded-For, that the
+// request's client IP is taken from when the service is behind a proxy or
+// CDN.  Since clients can put any addresses in the header themselves, the
+// last address, which the proxy nearest the service added, is used.  See
+// WithCTrustedProxies for chains of proxies.  By default, the client IP is
+// the request's RemoteAddr.
+func WithCClientIPHeader(header string) COption {
+	return func(c *constructor) {
+		c.clientIPHeader = header
+	}
+}
+
+// WithCTrustedProxies sets the number of proxies in front of the service that
+// append to the client IP header.  The client IP is the nth address from the
+// end of the header, skipping the addresses of the proxies themselves.  If the
+// header has fewer addresses, the request's RemoteAddr is used.  Defaults to
+// one.
+func WithCTrustedProxies(n int) COption {
+	return func(c *constructor) {
+		c.trustedProxies = n
+	}
+}
+
+// IPChecker rejects client IPs, such as ones with a bad reputation.  The
+// basculechecks.IPReputationValidator is an IPChecker.
+type IPChecker interface {
+	CheckIP(ctx context.Context, clientIP string) error
+}
+
+// WithCIPChecker sets the IPChecker that the client IP of each request is
+// checked with before the token is parsed, so no token validation work is
+// spent on requests from bad sources.  Rejected requests are denied with the
+// IPBlocked reason.
+func WithCIPChecker(checker IPChecker) COption {
+	return func(c *constructor) {
+		if checker != nil {
+			c.ipChecker = checker
+		}
+	}
+}
+
+// WithCSchemeMatching sets how the scheme of the authorization header is
+// matched against the schemes of the token factories and aliases.
+func WithCSchemeMatching(m SchemeMatching) COption {
+	return func(c *constructor) {
+		if m >= DefaultSchemeMatching && m <= CaseInsensitiveSchemes {
+			c.schemeMatching = m
+		}
+	}
+}
+
+// WithCMultipleCredentials sets what is done with requests carrying more
+// than one set of credentials.  The default is FirstCredentials.
+func WithCMultipleCredentials(m MultipleCredentials) COption {
+	return func(c *constructor) {
+		if m >= FirstCredentials && m <= RejectMultipleCredentials {
+			c.multipleCredentials = m
+		}
+	}
+}
+
+// WithCSchemeAlias has requests using the alias scheme handled by the
+// TokenFactory of another scheme, like treating "JWT" as "Bearer".  The
+// Authentication's Authorization is the scheme aliased, not the alias.
+func WithCSchemeAlias(alias string, scheme bascule.Authorization) COption {
+	return func(c *constructor) {
+		if len(alias) == 0 || len(scheme) == 0 {
+			return
+		}
+		if c.schemeAliases == nil {
+			c.schemeAliases = make(map[string]bascule.Authorization)
+		}
+		c.schemeAliases[alias] = scheme
+	}
+}
+
+// WithCRedactor sets the redactor used to mask credentials before they are
+// logged.
+func WithCRedactor(r *bascule.Redactor) COption {
+	return func(c *constructor) {
+		if r != nil {
+			c.redactor = r
+		}
+	}
+}
+
 // ProvideConstructor is a helper function for wiring up a basculehttp
 // constructor with uber fx.  Any options or optional values added with uber fx
 // will be used to create the constructor.
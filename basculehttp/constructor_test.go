@@ -18,12 +18,18 @@
 package basculehttp
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/xmidt-org/sallust"
+	"go.uber.org/zap"
 )
 
 func TestConstructor(t *testing.T) {
@@ -125,3 +131,572 @@ func TestConstructor(t *testing.T) {
 		})
 	}
 }
+
+func TestConstructorLoggableAuth(t *testing.T) {
+	tests := []struct {
+		description string
+		redactor    *bascule.Redactor
+		header      string
+		expected    string
+	}{
+		{
+			description: "No Redactor",
+			header:      "Basic Y29kZXg6Y29kZXg=",
+			expected:    "Basic Y29kZXg6Y29kZXg=",
+		},
+		{
+			description: "Redacted",
+			redactor:    bascule.NewRedactor(),
+			header:      "Basic Y29kZXg6Y29kZXg=",
+			expected:    "Basic " + bascule.DefaultRedactionMask,
+		},
+		{
+			description: "Redacted No Delimiter",
+			redactor:    bascule.NewRedactor(),
+			header:      "Y29kZXg6Y29kZXg=",
+			expected:    bascule.DefaultRedactionMask,
+		},
+		{
+			description: "Empty Header",
+			redactor:    bascule.NewRedactor(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			c := constructor{
+				headerName:      DefaultHeaderName,
+				headerDelimiter: DefaultHeaderDelimiter,
+			}
+			WithCRedactor(tc.redactor)(&c)
+			req := httptest.NewRequest("get", "/", nil)
+			if tc.header != "" {
+				req.Header.Set(DefaultHeaderName, tc.header)
+			}
+			assert.Equal(t, tc.expected, c.loggableAuth(req))
+		})
+	}
+}
+
+func TestConstructorTokenEnricher(t *testing.T) {
+	testErr := errors.New("test error")
+	tests := []struct {
+		description       string
+		enrichers         []bascule.TokenEnricher
+		expectedStatus    int
+		expectedPrincipal string
+	}{
+		{
+			description: "Enriched",
+			enrichers: []bascule.TokenEnricher{
+				nil,
+				bascule.TokenEnricherFunc(func(_ context.Context, t bascule.Token) (bascule.Token, error) {
+					return bascule.NewToken(t.Type(), t.Principal()+"-1", t.Attributes()), nil
+				}),
+				bascule.TokenEnricherFunc(func(_ context.Context, t bascule.Token) (bascule.Token, error) {
+					return bascule.NewToken(t.Type(), t.Principal()+"-2", t.Attributes()), nil
+				}),
+			},
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "alice-1-2",
+		},
+		{
+			description: "Error",
+			enrichers: []bascule.TokenEnricher{
+				bascule.TokenEnricherFunc(func(context.Context, bascule.Token) (bascule.Token, error) {
+					return nil, testErr
+				}),
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			options := []COption{
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+					return bascule.NewToken("basic", "alice", bascule.NewAttributes(nil)), nil
+				})),
+			}
+			for _, e := range tc.enrichers {
+				options = append(options, WithCTokenEnricher(e))
+			}
+			var principal string
+			handler := NewConstructor(options...)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				auth, _ := bascule.FromContext(r.Context())
+				principal = auth.Token.Principal()
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(tc.expectedStatus, w.Code)
+			assert.Equal(tc.expectedPrincipal, principal)
+		})
+	}
+}
+
+func TestConstructorClientIP(t *testing.T) {
+	tests := []struct {
+		description string
+		header      string
+		proxies     int
+		remoteAddr  string
+		forwarded   string
+		expected    string
+	}{
+		{
+			description: "Remote Addr",
+			remoteAddr:  "10.0.0.1:1234",
+			expected:    "10.0.0.1",
+		},
+		{
+			description: "Remote Addr Without Port",
+			remoteAddr:  "10.0.0.1",
+			expected:    "10.0.0.1",
+		},
+		{
+			description: "Header Not Configured",
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   "192.168.0.1",
+			expected:    "10.0.0.1",
+		},
+		{
+			description: "Header",
+			header:      "X-Forwarded-For",
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   "192.168.0.1",
+			expected:    "192.168.0.1",
+		},
+		{
+			description: "Spoofed Header",
+			header:      "X-Forwarded-For",
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   "1.2.3.4, 192.168.0.1 ",
+			expected:    "192.168.0.1",
+		},
+		{
+			description: "Trusted Proxies",
+			header:      "X-Forwarded-For",
+			proxies:     2,
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   "1.2.3.4, 192.168.0.1, 10.0.0.5",
+			expected:    "192.168.0.1",
+		},
+		{
+			description: "Too Few Addresses",
+			header:      "X-Forwarded-For",
+			proxies:     3,
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   "192.168.0.1, 10.0.0.5",
+			expected:    "10.0.0.1",
+		},
+		{
+			description: "Header Missing",
+			header:      "X-Forwarded-For",
+			remoteAddr:  "10.0.0.1:1234",
+			expected:    "10.0.0.1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var request bascule.Request
+			handler := NewConstructor(
+				WithCClientIPHeader(tc.header),
+				WithCTrustedProxies(tc.proxies),
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+					return bascule.NewToken("basic", "alice", nil), nil
+				})),
+			)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				auth, _ := bascule.FromContext(r.Context())
+				request = auth.Request
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			req.Header.Set("User-Agent", "test-agent")
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(tc.expected, request.ClientIP)
+			assert.Equal("test-agent", request.UserAgent)
+		})
+	}
+}
+
+type ipCheckerFunc func(context.Context, string) error
+
+func (f ipCheckerFunc) CheckIP(ctx context.Context, clientIP string) error {
+	return f(ctx, clientIP)
+}
+
+func TestConstructorIPChecker(t *testing.T) {
+	blocked := errors.New("blocked")
+	tests := []struct {
+		description     string
+		remoteAddr      string
+		expectedCode    int
+		expectedReason  ErrorResponseReason
+		expectedParsing bool
+	}{
+		{
+			description:     "Allowed",
+			remoteAddr:      "10.0.0.1:1234",
+			expectedCode:    http.StatusOK,
+			expectedParsing: true,
+		},
+		{
+			description:    "Blocked",
+			remoteAddr:     "192.168.0.1:1234",
+			expectedCode:   http.StatusForbidden,
+			expectedReason: IPBlocked,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				parsed bool
+				reason ErrorResponseReason
+			)
+			handler := NewConstructor(
+				WithCIPChecker(nil),
+				WithCIPChecker(ipCheckerFunc(func(_ context.Context, clientIP string) error {
+					if clientIP == "192.168.0.1" {
+						return blocked
+					}
+					return nil
+				})),
+				WithCErrorResponseFunc(func(r ErrorResponseReason, _ error) {
+					reason = r
+				}),
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+					parsed = true
+					return bascule.NewToken("basic", "alice", nil), nil
+				})),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(tc.expectedCode, recorder.Code)
+			assert.Equal(tc.expectedParsing, parsed)
+			assert.Equal(tc.expectedReason, reason)
+			assert.Empty(recorder.Header().Get(AuthTypeHeaderKey))
+		})
+	}
+}
+
+func TestConstructorCredentials(t *testing.T) {
+	tests := []struct {
+		description    string
+		header         string
+		expectedCode   int
+		expectedValue  string
+		expectedReason ErrorResponseReason
+		expectedErr    error
+	}{
+		{
+			description:   "Exact Scheme",
+			header:        "Basic abc",
+			expectedCode:  http.StatusOK,
+			expectedValue: "abc",
+		},
+		{
+			description:   "Case Insensitive Scheme",
+			header:        "bASIC   abc  ",
+			expectedCode:  http.StatusOK,
+			expectedValue: "abc",
+		},
+		{
+			description:    "Missing Scheme",
+			header:         " abc",
+			expectedCode:   http.StatusUnauthorized,
+			expectedReason: InvalidHeader,
+			expectedErr:    ErrMissingScheme,
+		},
+		{
+			description:    "Invalid Credentials",
+			header:         "Basic a b",
+			expectedCode:   http.StatusUnauthorized,
+			expectedReason: InvalidHeader,
+			expectedErr:    ErrInvalidCredentials,
+		},
+		{
+			description:    "Unsupported Scheme",
+			header:         "Bearer abc",
+			expectedCode:   http.StatusUnauthorized,
+			expectedReason: KeyNotSupported,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				value  string
+				reason ErrorResponseReason
+				err    error
+			)
+			handler := NewConstructor(
+				WithCErrorResponseFunc(func(r ErrorResponseReason, e error) {
+					reason, err = r, e
+				}),
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(_ context.Context, _ *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+					assert.Equal(BasicAuthorization, a)
+					value = v
+					return bascule.NewToken("basic", "alice", nil), nil
+				})),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.header)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(tc.expectedCode, recorder.Code)
+			assert.Equal(tc.expectedValue, value)
+			assert.Equal(tc.expectedReason, reason)
+			if tc.expectedErr != nil {
+				assert.True(errors.Is(err, tc.expectedErr))
+			}
+		})
+	}
+}
+
+func TestConstructorSchemeMatching(t *testing.T) {
+	tests := []struct {
+		description   string
+		options       []COption
+		header        string
+		expectedCode  int
+		expectedKey   bascule.Authorization
+		expectedValue string
+	}{
+		{
+			description:   "Default Delimiter Folds",
+			header:        "bAsIc abc",
+			expectedCode:  http.StatusOK,
+			expectedKey:   BasicAuthorization,
+			expectedValue: "abc",
+		},
+		{
+			description:  "Case Sensitive",
+			options:      []COption{WithCSchemeMatching(CaseSensitiveSchemes)},
+			header:       "bAsIc abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Custom Delimiter Exact",
+			options:      []COption{WithHeaderDelimiter("=")},
+			header:       "bAsIc=abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description: "Custom Delimiter Folds",
+			options: []COption{
+				WithHeaderDelimiter("="),
+				WithCSchemeMatching(SchemeMatching(-1)),
+				WithCSchemeMatching(CaseInsensitiveSchemes),
+			},
+			header:        "bAsIc=abc",
+			expectedCode:  http.StatusOK,
+			expectedKey:   BasicAuthorization,
+			expectedValue: "abc",
+		},
+		{
+			description: "Alias",
+			options: []COption{
+				WithCSchemeAlias("", BearerAuthorization),
+				WithCSchemeAlias("JWT", ""),
+				WithCSchemeAlias("JWT", BearerAuthorization),
+			},
+			header:        "JWT abc",
+			expectedCode:  http.StatusOK,
+			expectedKey:   BearerAuthorization,
+			expectedValue: "abc",
+		},
+		{
+			description:   "Folded Alias",
+			options:       []COption{WithCSchemeAlias("JWT", BearerAuthorization)},
+			header:        "jwt abc",
+			expectedCode:  http.StatusOK,
+			expectedKey:   BearerAuthorization,
+			expectedValue: "abc",
+		},
+		{
+			description: "Case Sensitive Alias",
+			options: []COption{
+				WithCSchemeAlias("JWT", BearerAuthorization),
+				WithCSchemeMatching(CaseSensitiveSchemes),
+			},
+			header:       "jwt abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Alias Without Factory",
+			options:      []COption{WithCSchemeAlias("Token", "Missing")},
+			header:       "Token abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				key   bascule.Authorization
+				value string
+			)
+			tf := TokenFactoryFunc(func(_ context.Context, _ *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+				key, value = a, v
+				return bascule.NewToken("test", "alice", nil), nil
+			})
+			options := append([]COption{
+				WithTokenFactory(BasicAuthorization, tf),
+				WithTokenFactory(BearerAuthorization, tf),
+			}, tc.options...)
+			handler := NewConstructor(options...)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.header)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(tc.expectedCode, recorder.Code)
+			assert.Equal(tc.expectedKey, key)
+			assert.Equal(tc.expectedValue, value)
+		})
+	}
+}
+
+func TestConstructorMultipleCredentials(t *testing.T) {
+	tests := []struct {
+		description    string
+		policy         MultipleCredentials
+		delimiter      string
+		headers        []string
+		expectedCode   int
+		expectedTried  []string
+		expectedReason ErrorResponseReason
+		expectedErr    error
+	}{
+		{
+			description:   "First Of Headers",
+			headers:       []string{"Basic good", "Basic bad"},
+			expectedCode:  http.StatusOK,
+			expectedTried: []string{"good"},
+		},
+		{
+			description:    "First Of Joined",
+			policy:         FirstCredentials,
+			headers:        []string{"Basic bad, Basic good"},
+			expectedCode:   http.StatusUnauthorized,
+			expectedTried:  []string{"bad"},
+			expectedReason: ParseFailed,
+		},
+		{
+			description:   "Last",
+			policy:        LastCredentials,
+			headers:       []string{"Basic bad", "Bearer x, Basic good"},
+			expectedCode:  http.StatusOK,
+			expectedTried: []string{"good"},
+		},
+		{
+			description:   "Try All",
+			policy:        TryAllCredentials,
+			headers:       []string{"Basic bad, Basic good", "Basic other"},
+			expectedCode:  http.StatusOK,
+			expectedTried: []string{"bad", "good"},
+		},
+		{
+			description:    "Try All Fail",
+			policy:         TryAllCredentials,
+			headers:        []string{"Basic bad", "Unknown x"},
+			expectedCode:   http.StatusUnauthorized,
+			expectedTried:  []string{"bad"},
+			expectedReason: ParseFailed,
+		},
+		{
+			description:    "Reject",
+			policy:         RejectMultipleCredentials,
+			headers:        []string{"Basic good, Basic good"},
+			expectedCode:   http.StatusUnauthorized,
+			expectedReason: InvalidHeader,
+			expectedErr:    ErrMultipleCredentials,
+		},
+		{
+			description:   "Reject Single",
+			policy:        RejectMultipleCredentials,
+			headers:       []string{`Digest good=x, realm="r"`},
+			expectedCode:  http.StatusOK,
+			expectedTried: []string{`good=x, realm="r"`},
+		},
+		{
+			description:   "Custom Delimiter Doesn't Split",
+			policy:        RejectMultipleCredentials,
+			delimiter:     "=",
+			headers:       []string{"Basic=good, Basic=bad"},
+			expectedCode:  http.StatusOK,
+			expectedTried: []string{"good, Basic=bad"},
+		},
+		{
+			description:    "Empty",
+			headers:        []string{"", " , "},
+			expectedCode:   http.StatusUnauthorized,
+			expectedReason: MissingHeader,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				tried  []string
+				reason ErrorResponseReason
+				err    error
+			)
+			tf := TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+				tried = append(tried, v)
+				if strings.HasPrefix(v, "good") {
+					return bascule.NewToken("basic", "alice", nil), nil
+				}
+				return nil, errors.New("bad credentials")
+			})
+			handler := NewConstructor(
+				WithCMultipleCredentials(MultipleCredentials(-1)),
+				WithCMultipleCredentials(tc.policy),
+				WithHeaderDelimiter(tc.delimiter),
+				WithTokenFactory(BasicAuthorization, tf),
+				WithTokenFactory("Digest", tf),
+				WithCErrorResponseFunc(func(r ErrorResponseReason, e error) {
+					reason, err = r, e
+				}),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, h := range tc.headers {
+				req.Header.Add(DefaultHeaderName, h)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(tc.expectedCode, recorder.Code)
+			assert.Equal(tc.expectedTried, tried)
+			assert.Equal(tc.expectedReason, reason)
+			if tc.expectedErr != nil {
+				assert.True(errors.Is(err, tc.expectedErr))
+			}
+		})
+	}
+}
+
+func BenchmarkConstructor(b *testing.B) {
+	logger := zap.NewNop()
+	handler := NewConstructor(
+		WithCLogger(func(context.Context) *zap.Logger { return logger }),
+		WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"}),
+	)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/things", nil)
+	req.Header.Set(DefaultHeaderName, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")))
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(w, req)
+	}
+}
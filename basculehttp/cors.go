@@ -0,0 +1,112 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PreflightConfig configures how the constructor answers CORS preflight
+// requests itself.
+type PreflightConfig struct {
+	// AllowedOrigins are the origins allowed to make requests.  "*" allows
+	// any origin, but without credentials: origins only allowed by "*" are
+	// answered with a literal "*" and no Access-Control-Allow-Credentials.
+	// Preflights from other origins are answered without CORS headers, so
+	// the browser blocks the request.
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed.  If empty, the requested
+	// method is allowed.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed.  If empty, the
+	// requested headers are allowed.
+	AllowedHeaders []string
+
+	// AllowCredentials allows requests from the origins listed explicitly in
+	// AllowedOrigins to include credentials, such as the Authorization header
+	// or cookies.
+	AllowCredentials bool
+
+	// MaxAge is how long the browser can cache the preflight response.
+	MaxAge time.Duration
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// originAllowed reports whether the origin is allowed, and whether it is
+// listed explicitly rather than only matching "*".
+func (pc PreflightConfig) originAllowed(origin string) (allowed bool, listed bool) {
+	for _, o := range pc.AllowedOrigins {
+		if strings.EqualFold(o, origin) {
+			return true, true
+		}
+		if o == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// respond answers a preflight request with a 204.
+func (pc PreflightConfig) respond(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	allowed, listed := pc.originAllowed(origin)
+	if !allowed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if listed {
+		h.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		// never pair a wildcard origin with credentials.
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+	if len(pc.AllowedMethods) > 0 {
+		h.Set("Access-Control-Allow-Methods", strings.Join(pc.AllowedMethods, ", "))
+	} else {
+		h.Set("Access-Control-Allow-Methods", r.Header.Get("Access-Control-Request-Method"))
+	}
+	if len(pc.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(pc.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if pc.AllowCredentials && listed {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if pc.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(pc.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructorPreflight(t *testing.T) {
+	tests := []struct {
+		description     string
+		option          COption
+		method          string
+		origin          string
+		requestHeaders  string
+		expectedCode    int
+		expectedHeaders map[string]string
+	}{
+		{
+			description:  "Default Unauthorized",
+			method:       http.MethodOptions,
+			origin:       "https://a.example.com",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Pass Through",
+			option:       WithCPreflightPassThrough(),
+			method:       http.MethodOptions,
+			origin:       "https://a.example.com",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description: "Response Configured",
+			option: WithCPreflightResponse(PreflightConfig{
+				AllowedOrigins:   []string{"https://a.example.com"},
+				AllowedMethods:   []string{"GET", "POST"},
+				AllowedHeaders:   []string{"Authorization"},
+				AllowCredentials: true,
+				MaxAge:           time.Hour,
+			}),
+			method:       http.MethodOptions,
+			origin:       "https://a.example.com",
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://a.example.com",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "3600",
+			},
+		},
+		{
+			description:    "Response Echoed",
+			option:         WithCPreflightResponse(PreflightConfig{AllowedOrigins: []string{"*"}}),
+			method:         http.MethodOptions,
+			origin:         "https://b.example.com",
+			requestHeaders: "Authorization, X-Custom",
+			expectedCode:   http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Methods":     "PUT",
+				"Access-Control-Allow-Headers":     "Authorization, X-Custom",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Max-Age":           "",
+			},
+		},
+		{
+			description: "Wildcard Never Allows Credentials",
+			option: WithCPreflightResponse(PreflightConfig{
+				AllowedOrigins:   []string{"https://a.example.com", "*"},
+				AllowCredentials: true,
+			}),
+			method:       http.MethodOptions,
+			origin:       "https://evil.example.com",
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			description: "Listed Origin Allows Credentials With Wildcard",
+			option: WithCPreflightResponse(PreflightConfig{
+				AllowedOrigins:   []string{"*", "https://a.example.com"},
+				AllowCredentials: true,
+			}),
+			method:       http.MethodOptions,
+			origin:       "https://a.example.com",
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://a.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			description:  "Origin Not Allowed",
+			option:       WithCPreflightResponse(PreflightConfig{AllowedOrigins: []string{"https://a.example.com"}}),
+			method:       http.MethodOptions,
+			origin:       "https://evil.example.com",
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			description:  "Not Preflight",
+			option:       WithCPreflightResponse(PreflightConfig{AllowedOrigins: []string{"*"}}),
+			method:       http.MethodGet,
+			origin:       "https://a.example.com",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			c := NewConstructor(tc.option)
+			req := httptest.NewRequest(tc.method, "/", nil)
+			req.Header.Set("Origin", tc.origin)
+			if tc.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			}
+			if tc.requestHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tc.requestHeaders)
+			}
+			w := httptest.NewRecorder()
+			c(next).ServeHTTP(w, req)
+			assert.Equal(tc.expectedCode, w.Code)
+			for k, v := range tc.expectedHeaders {
+				assert.Equal(v, w.Header().Get(k), k)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var (
+	// ErrMissingScheme is returned when an authorization header doesn't
+	// start with a scheme.
+	ErrMissingScheme = bascule.NewClassError(bascule.MalformedClass, "authorization header has no scheme")
+
+	// ErrInvalidScheme is returned when an authorization header's scheme
+	// isn't a token, or isn't followed by a space.
+	ErrInvalidScheme = bascule.NewClassError(bascule.MalformedClass, "authorization scheme is invalid")
+
+	// ErrInvalidCredentials is returned when the credentials after the
+	// scheme are neither a token68 nor a list of auth-params.
+	ErrInvalidCredentials = bascule.NewClassError(bascule.MalformedClass, "authorization credentials are neither token68 nor auth-params")
+)
+
+// Credentials are the parts of an authorization header, as described by
+// RFC 7235:
+//
+//	credentials = auth-scheme [ 1*SP ( token68 / #auth-param ) ]
+//
+// The fields are substrings of the header, so parsing doesn't copy it.
+type Credentials struct {
+	// Scheme is the authentication scheme, such as "Basic".  Schemes are
+	// case-insensitive.
+	Scheme string
+
+	// Value is everything after the scheme and the spaces following it,
+	// which is what a TokenFactory parses.  It's empty if the header is just
+	// a scheme.
+	Value string
+
+	// Token68 is the Value if it is in the token68 form, like the Basic and
+	// Bearer schemes' credentials.
+	Token68 string
+}
+
+// AuthParam is a name and value from the auth-param form of credentials,
+// like the Digest scheme's.
+type AuthParam struct {
+	Name  string
+	Value string
+}
+
+// ParseCredentials strictly parses an authorization header, returning an
+// error wrapping ErrMissingScheme, ErrInvalidScheme, or ErrInvalidCredentials
+// that says where the header went wrong.  It doesn't allocate unless the
+// header is invalid.
+func ParseCredentials(header string) (Credentials, error) {
+	i := 0
+	for i < len(header) && isTChar(header[i]) {
+		i++
+	}
+	if i == 0 {
+		if len(header) == 0 || header[0] == ' ' || header[0] == '\t' {
+			return Credentials{}, ErrMissingScheme
+		}
+		return Credentials{}, fmt.Errorf("%w: unexpected %q at position 0", ErrInvalidScheme, header[0])
+	}
+	c := Credentials{Scheme: header[:i]}
+	if i == len(header) {
+		return c, nil
+	}
+	if header[i] != ' ' {
+		return Credentials{}, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidScheme, header[i], i)
+	}
+	start := i
+	for start < len(header) && header[start] == ' ' {
+		start++
+	}
+	end := len(header)
+	for end > start && (header[end-1] == ' ' || header[end-1] == '\t') {
+		end--
+	}
+	c.Value = header[start:end]
+	if len(c.Value) == 0 {
+		return c, nil
+	}
+	if isToken68(c.Value) {
+		c.Token68 = c.Value
+		return c, nil
+	}
+	if pos, ok := scanAuthParams(c.Value, nil); !ok {
+		return Credentials{}, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidCredentials, c.Value[pos], start+pos)
+	}
+	return c, nil
+}
+
+// Params returns the credentials' auth-params, or nil if they are in the
+// token68 form.  Quoted values are unquoted.
+func (c Credentials) Params() []AuthParam {
+	if len(c.Token68) > 0 || len(c.Value) == 0 {
+		return nil
+	}
+	var params []AuthParam
+	scanAuthParams(c.Value, func(name, value string) {
+		if len(value) > 0 && value[0] == '"' {
+			value = unquote(value)
+		}
+		params = append(params, AuthParam{Name: name, Value: value})
+	})
+	return params
+}
+
+// SplitCredentials appends the credentials in an authorization value to the
+// list.  A value normally holds one set of credentials, but proxies may join
+// multiple header fields with commas.  A comma starts new credentials when it's
+// followed by a scheme rather than an auth-param, so the commas of
+// auth-param credentials don't split them.  Commas in quoted strings never
+// split credentials.  Empty values are skipped.
+func SplitCredentials(list []string, value string) []string {
+	start := 0
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case quoted && value[i] == '\\':
+			// skip the character of the quoted-pair.
+			i++
+		case value[i] == '"':
+			quoted = !quoted
+		case !quoted && value[i] == ',' && startsCredentials(value[i+1:]):
+			list = appendTrimmed(list, value[start:i])
+			start = i + 1
+		}
+	}
+	return appendTrimmed(list, value[start:])
+}
+
+func appendTrimmed(list []string, v string) []string {
+	v = strings.Trim(v, " \t,")
+	if len(v) == 0 {
+		return list
+	}
+	return append(list, v)
+}
+
+// startsCredentials returns true if the value, after optional whitespace,
+// starts with a scheme that isn't the name of an auth-param.
+func startsCredentials(v string) bool {
+	v = strings.TrimLeft(v, " \t")
+	i := 0
+	for i < len(v) && isTChar(v[i]) {
+		i++
+	}
+	if i == 0 {
+		return false
+	}
+	if i == len(v) || v[i] == ',' {
+		return true
+	}
+	if v[i] != ' ' {
+		return false
+	}
+	v = strings.TrimLeft(v[i:], " ")
+	return len(v) == 0 || v[0] != '='
+}
+
+// isTChar returns true if the byte can be part of a token.
+func isTChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", b) >= 0
+}
+
+// isToken68 returns true if the value is a token68:
+//
+//	token68 = 1*( ALPHA / DIGIT / "-" / "." / "_" / "~" / "+" / "/" ) *"="
+func isToken68(v string) bool {
+	i := 0
+	for i < len(v) && isToken68Char(v[i]) {
+		i++
+	}
+	if i == 0 {
+		return false
+	}
+	for ; i < len(v); i++ {
+		if v[i] != '=' {
+			return false
+		}
+	}
+	return true
+}
+
+func isToken68Char(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return b == '-' || b == '.' || b == '_' || b == '~' || b == '+' || b == '/'
+}
+
+// scanAuthParams checks that the value is a comma separated list of
+// auth-params, calling fn, if it isn't nil, with each name and raw value.
+// If the value is invalid, it returns the position of the first unexpected
+// byte and false.
+//
+//	auth-param = token BWS "=" BWS ( token / quoted-string )
+func scanAuthParams(v string, fn func(name, value string)) (int, bool) {
+	i := 0
+	skipOWS := func() {
+		for i < len(v) && (v[i] == ' ' || v[i] == '\t') {
+			i++
+		}
+	}
+	for i < len(v) {
+		// empty list elements are allowed.
+		if v[i] == ',' {
+			i++
+			skipOWS()
+			continue
+		}
+
+		nameStart := i
+		for i < len(v) && isTChar(v[i]) {
+			i++
+		}
+		if i == nameStart {
+			return i, false
+		}
+		name := v[nameStart:i]
+		skipOWS()
+		if i >= len(v) || v[i] != '=' {
+			return lastByte(v, i), false
+		}
+		i++
+		skipOWS()
+
+		valueStart := i
+		if i < len(v) && v[i] == '"' {
+			i++
+			for i < len(v) && v[i] != '"' {
+				if v[i] == '\\' {
+					i++
+					if i >= len(v) || !isQuotedPairChar(v[i]) {
+						return lastByte(v, i), false
+					}
+				} else if !isQDText(v[i]) {
+					return i, false
+				}
+				i++
+			}
+			if i >= len(v) {
+				return lastByte(v, i), false
+			}
+			i++
+		} else {
+			for i < len(v) && isTChar(v[i]) {
+				i++
+			}
+			if i == valueStart {
+				return lastByte(v, i), false
+			}
+		}
+		if fn != nil {
+			fn(name, v[valueStart:i])
+		}
+
+		skipOWS()
+		if i < len(v) {
+			if v[i] != ',' {
+				return i, false
+			}
+			i++
+			skipOWS()
+		}
+	}
+	return 0, true
+}
+
+// lastByte returns the position given, or the position of the last byte if it
+// is past the end of the value.
+func lastByte(v string, i int) int {
+	if i >= len(v) {
+		return len(v) - 1
+	}
+	return i
+}
+
+func isQDText(b byte) bool {
+	return b == '\t' || b == ' ' || b == 0x21 || (0x23 <= b && b <= 0x5b) || (0x5d <= b && b <= 0x7e) || b >= 0x80
+}
+
+func isQuotedPairChar(b byte) bool {
+	return b == '\t' || (0x20 <= b && b <= 0x7e) || b >= 0x80
+}
+
+// unquote returns the contents of a valid quoted-string, with its
+// quoted-pairs unescaped.
+func unquote(q string) string {
+	q = q[1 : len(q)-1]
+	if strings.IndexByte(q, '\\') < 0 {
+		return q
+	}
+	var b strings.Builder
+	b.Grow(len(q))
+	for i := 0; i < len(q); i++ {
+		if q[i] == '\\' {
+			i++
+		}
+		b.WriteByte(q[i])
+	}
+	return b.String()
+}
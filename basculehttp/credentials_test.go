@@ -0,0 +1,177 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCredentials(t *testing.T) {
+	tests := []struct {
+		description   string
+		header        string
+		expected      Credentials
+		expectedErr   error
+		expectedPos   string
+		expectedParam []AuthParam
+	}{
+		{
+			description: "Basic",
+			header:      "Basic dXNlcjpwYXNz",
+			expected:    Credentials{Scheme: "Basic", Value: "dXNlcjpwYXNz", Token68: "dXNlcjpwYXNz"},
+		},
+		{
+			description: "Token68 Padding",
+			header:      "Bearer  abc.def_ghi~+/==  ",
+			expected:    Credentials{Scheme: "Bearer", Value: "abc.def_ghi~+/==", Token68: "abc.def_ghi~+/=="},
+		},
+		{
+			description: "Scheme Only",
+			header:      "Negotiate",
+			expected:    Credentials{Scheme: "Negotiate"},
+		},
+		{
+			description: "Auth Params",
+			header:      `Digest username="a\"b", realm = "r" ,, nc=00000001`,
+			expected: Credentials{
+				Scheme: "Digest",
+				Value:  `username="a\"b", realm = "r" ,, nc=00000001`,
+			},
+			expectedParam: []AuthParam{
+				{Name: "username", Value: `a"b`},
+				{Name: "realm", Value: "r"},
+				{Name: "nc", Value: "00000001"},
+			},
+		},
+		{
+			description: "Empty",
+			header:      "",
+			expectedErr: ErrMissingScheme,
+		},
+		{
+			description: "Leading Space",
+			header:      " Basic abc",
+			expectedErr: ErrMissingScheme,
+		},
+		{
+			description: "Invalid Scheme Character",
+			header:      "Ba@sic abc",
+			expectedErr: ErrInvalidScheme,
+			expectedPos: `unexpected '@' at position 2`,
+		},
+		{
+			description: "Tab Delimiter",
+			header:      "Basic\tabc",
+			expectedErr: ErrInvalidScheme,
+		},
+		{
+			description: "Space In Token68",
+			header:      "Basic abc def",
+			expectedErr: ErrInvalidCredentials,
+			expectedPos: `unexpected 'd' at position 10`,
+		},
+		{
+			description: "Padding Not At End",
+			header:      "Bearer ab=c=",
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			description: "Unterminated Quote",
+			header:      `Digest username="alice`,
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			description: "Missing Param Value",
+			header:      "Digest username=, realm=r",
+			expectedErr: ErrInvalidCredentials,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			c, err := ParseCredentials(tc.header)
+			assert.True(errors.Is(err, tc.expectedErr),
+				"ParseCredentials() error = %v, expected %v", err, tc.expectedErr)
+			if tc.expectedPos != "" {
+				assert.Contains(err.Error(), tc.expectedPos)
+			}
+			assert.Equal(tc.expected, c)
+			assert.Equal(tc.expectedParam, c.Params())
+		})
+	}
+}
+
+func TestSplitCredentials(t *testing.T) {
+	tests := []struct {
+		description string
+		value       string
+		expected    []string
+	}{
+		{
+			description: "Empty",
+			value:       " ",
+		},
+		{
+			description: "Single",
+			value:       "Basic abc",
+			expected:    []string{"Basic abc"},
+		},
+		{
+			description: "Joined",
+			value:       "Basic abc, Bearer def==",
+			expected:    []string{"Basic abc", "Bearer def=="},
+		},
+		{
+			description: "Auth Params",
+			value:       `Digest username="a, b", realm = r,, Basic abc`,
+			expected:    []string{`Digest username="a, b", realm = r`, "Basic abc"},
+		},
+		{
+			description: "Quoted Comma",
+			value:       `Digest realm="Acme, Digest auth", nonce="n", Basic abc`,
+			expected:    []string{`Digest realm="Acme, Digest auth", nonce="n"`, "Basic abc"},
+		},
+		{
+			description: "Quoted Pair",
+			value:       `Digest realm="a \", Basic abc", nonce="n"`,
+			expected:    []string{`Digest realm="a \", Basic abc", nonce="n"`},
+		},
+		{
+			description: "Empty Elements",
+			value:       ", Basic abc, , Negotiate,",
+			expected:    []string{"Basic abc", "Negotiate"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, SplitCredentials(nil, tc.value))
+		})
+	}
+}
+
+func BenchmarkParseCredentials(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCredentials("Basic dXNlcjpwYXNz"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// EventEncoding is how a DecisionPublisher encodes decision events.
+type EventEncoding int
+
+const (
+	// JSONEvents encodes events as JSON objects.
+	JSONEvents EventEncoding = iota
+
+	// ProtobufEvents encodes events as the DecisionEvent message in
+	// decisionEvent.proto.
+	ProtobufEvents
+)
+
+// The decisions of a DecisionEvent.
+const (
+	AllowDecision = "allow"
+	DenyDecision  = "deny"
+)
+
+const (
+	defaultPublisherQueueSize = 1000
+	defaultPublisherTimeout   = 5 * time.Second
+)
+
+var (
+	ErrNilProducer = errors.New("producer cannot be nil")
+	ErrEmptyTopic  = errors.New("topic cannot be empty")
+)
+
+// Producer publishes messages to an event bus topic, like a Kafka or NATS
+// topic.  Implementations wrap the client library in use.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// ProducerFunc makes it so any function with the same signature as
+// Producer's Produce function implements Producer.
+type ProducerFunc func(ctx context.Context, topic string, key, value []byte) error
+
+func (pf ProducerFunc) Produce(ctx context.Context, topic string, key, value []byte) error {
+	return pf(ctx, topic, key, value)
+}
+
+// DecisionEvent is an authorization decision published to an event bus.
+type DecisionEvent struct {
+	Time          time.Time           `json:"time"`
+	Server        string              `json:"server,omitempty"`
+	Decision      string              `json:"decision"`
+	RequestID     string              `json:"requestId,omitempty"`
+	Method        string              `json:"method"`
+	Path          string              `json:"path"`
+	ClientIP      string              `json:"clientIp,omitempty"`
+	Principal     string              `json:"principal,omitempty"`
+	TokenType     string              `json:"tokenType,omitempty"`
+	Authorization string              `json:"authorization,omitempty"`
+	PartnerIDs    []string            `json:"partnerIds,omitempty"`
+	Reason        ErrorResponseReason `json:"-"`
+	Error         string              `json:"error,omitempty"`
+}
+
+// MarshalJSON writes the event with its reason as a string.
+func (d DecisionEvent) MarshalJSON() ([]byte, error) {
+	type event DecisionEvent
+	var reason string
+	if d.Decision == DenyDecision {
+		reason = d.Reason.String()
+	}
+	return json.Marshal(struct {
+		event
+		Reason string `json:"reason,omitempty"`
+	}{event: event(d), Reason: reason})
+}
+
+// MarshalProto writes the event as the DecisionEvent message in
+// decisionEvent.proto.
+func (d DecisionEvent) MarshalProto() []byte {
+	var b []byte
+	appendString := func(num protowire.Number, v string) {
+		if len(v) > 0 {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		}
+	}
+	if !d.Time.IsZero() {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(d.Time.UnixNano()))
+	}
+	appendString(2, d.Server)
+	appendString(3, d.Decision)
+	appendString(4, d.RequestID)
+	appendString(5, d.Method)
+	appendString(6, d.Path)
+	appendString(7, d.ClientIP)
+	appendString(8, d.Principal)
+	appendString(9, d.TokenType)
+	appendString(10, d.Authorization)
+	for _, p := range d.PartnerIDs {
+		b = protowire.AppendTag(b, 11, protowire.BytesType)
+		b = protowire.AppendString(b, p)
+	}
+	if d.Decision == DenyDecision {
+		appendString(12, d.Reason.String())
+	}
+	appendString(13, d.Error)
+	return b
+}
+
+// DecisionPublisherConfig configures a DecisionPublisher.
+type DecisionPublisherConfig struct {
+	// Topic is the topic events are published to.  It is required.
+	Topic string
+
+	// Encoding is how events are encoded.  Defaults to JSONEvents.
+	Encoding EventEncoding
+
+	// Server is added to each event, to tell servers sharing a topic apart.
+	Server string
+
+	// PartnerKeys is the location of the partner IDs in the token's
+	// attributes.  Defaults to allowedResources, allowedPartners.
+	PartnerKeys []string
+
+	// QueueSize is the number of events buffered while the producer is
+	// busy.  Events are dropped when it is full.  Defaults to 1000.
+	QueueSize int
+
+	// Timeout limits how long each event can take to publish.  Defaults to
+	// 5 seconds.
+	Timeout time.Duration
+}
+
+// DecisionPublisher publishes allow and deny decisions to an event bus for
+// entitlement analytics, without anyone having to scrape logs.  Events are
+// queued and published in the background, keyed by principal, so a slow
+// event bus never holds up requests.  Add its AllowHook and DenyHook to the
+// constructor and enforcer, and its Hook to the fx lifecycle.
+type DecisionPublisher struct {
+	producer  Producer
+	config    DecisionPublisherConfig
+	getLogger func(context.Context) *zap.Logger
+	now       func() time.Time
+	queue     chan DecisionEvent
+
+	runLock sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewDecisionPublisher creates a DecisionPublisher.  Events aren't published
+// until it is started.
+func NewDecisionPublisher(producer Producer, config DecisionPublisherConfig, getLogger func(context.Context) *zap.Logger) (*DecisionPublisher, error) {
+	if producer == nil {
+		return nil, ErrNilProducer
+	}
+	if config.Topic == "" {
+		return nil, ErrEmptyTopic
+	}
+	if config.Encoding != JSONEvents && config.Encoding != ProtobufEvents {
+		return nil, fmt.Errorf("unsupported event encoding %d", config.Encoding)
+	}
+	if len(config.PartnerKeys) == 0 {
+		config.PartnerKeys = defaultPartnerKeys
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultPublisherQueueSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultPublisherTimeout
+	}
+	if getLogger == nil {
+		getLogger = sallust.Get
+	}
+	return &DecisionPublisher{
+		producer:  producer,
+		config:    config,
+		getLogger: getLogger,
+		now:       time.Now,
+		queue:     make(chan DecisionEvent, config.QueueSize),
+	}, nil
+}
+
+// AllowHook returns an AfterAllow Hook that publishes the allowed requests.
+func (p *DecisionPublisher) AllowHook() Hook {
+	return p.hook(AfterAllow, AllowDecision)
+}
+
+// DenyHook returns an AfterDeny Hook that publishes the denied requests.
+func (p *DecisionPublisher) DenyHook() Hook {
+	return p.hook(AfterDeny, DenyDecision)
+}
+
+func (p *DecisionPublisher) hook(stage HookStage, decision string) Hook {
+	return func(_ http.ResponseWriter, r *http.Request, event HookEvent) *http.Request {
+		if event.Stage != stage {
+			return nil
+		}
+		p.Publish(p.event(r, event, decision))
+		return nil
+	}
+}
+
+// event builds the DecisionEvent for a hook's request.
+func (p *DecisionPublisher) event(r *http.Request, event HookEvent, decision string) DecisionEvent {
+	d := DecisionEvent{
+		Time:          p.now(),
+		Server:        p.config.Server,
+		Decision:      decision,
+		RequestID:     event.RequestID,
+		Method:        r.Method,
+		Path:          r.URL.EscapedPath(),
+		ClientIP:      denialClientIP(r, event.Auth),
+		Authorization: string(event.Auth.Authorization),
+	}
+	if decision == DenyDecision {
+		d.Reason = event.Reason
+		if event.Err != nil {
+			d.Error = event.Err.Error()
+		}
+	}
+	if token := event.Auth.Token; token != nil {
+		d.Principal = token.Principal()
+		d.TokenType = token.Type()
+		if token.Attributes() != nil {
+			if v, ok := bascule.GetNestedAttribute(token.Attributes(), p.config.PartnerKeys...); ok {
+				d.PartnerIDs, _ = cast.ToStringSliceE(v)
+			}
+		}
+	}
+	return d
+}
+
+// Publish queues the event to be published, returning false if the queue is
+// full and the event was dropped.
+func (p *DecisionPublisher) Publish(d DecisionEvent) bool {
+	select {
+	case p.queue <- d:
+		return true
+	default:
+		p.getLogger(context.Background()).Warn("decision event queue full, dropping event",
+			zap.String(RequestIDLogKey, d.RequestID))
+		return false
+	}
+}
+
+// Encode returns the event in the configured encoding.
+func (p *DecisionPublisher) Encode(d DecisionEvent) ([]byte, error) {
+	if p.config.Encoding == ProtobufEvents {
+		return d.MarshalProto(), nil
+	}
+	return json.Marshal(d)
+}
+
+// Start starts publishing events.
+func (p *DecisionPublisher) Start() {
+	p.runLock.Lock()
+	defer p.runLock.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(p.stop, p.done)
+}
+
+// Stop stops publishing events, first publishing the events already queued.
+func (p *DecisionPublisher) Stop() {
+	p.runLock.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.runLock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops the
+// publisher.
+func (p *DecisionPublisher) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			p.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			p.Stop()
+			return nil
+		},
+	}
+}
+
+func (p *DecisionPublisher) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case d := <-p.queue:
+			p.send(d)
+		case <-stop:
+			for {
+				select {
+				case d := <-p.queue:
+					p.send(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send publishes one event, logging rather than retrying failures since
+// event bus clients retry on their own.
+func (p *DecisionPublisher) send(d DecisionEvent) {
+	value, err := p.Encode(d)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+		err = p.producer.Produce(ctx, p.config.Topic, []byte(d.Principal), value)
+		cancel()
+	}
+	if err != nil {
+		p.getLogger(context.Background()).Error("failed to publish decision event",
+			zap.String("topic", p.config.Topic), zap.String(RequestIDLogKey, d.RequestID), zap.Error(err))
+	}
+}
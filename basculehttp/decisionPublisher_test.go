@@ -0,0 +1,372 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type producedMessage struct {
+	topic string
+	key   string
+	value []byte
+}
+
+type recordingProducer struct {
+	lock     sync.Mutex
+	messages []producedMessage
+	err      error
+}
+
+func (r *recordingProducer) Produce(_ context.Context, topic string, key, value []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.messages = append(r.messages, producedMessage{topic: topic, key: string(key), value: value})
+	return r.err
+}
+
+func (r *recordingProducer) produced() []producedMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]producedMessage(nil), r.messages...)
+}
+
+func testDecision() DecisionEvent {
+	return DecisionEvent{
+		Time:       time.Date(2023, time.March, 4, 5, 6, 7, 8000000, time.UTC),
+		Server:     "server",
+		Decision:   DenyDecision,
+		RequestID:  "abc123",
+		Method:     http.MethodGet,
+		Path:       "/api/v1/a",
+		ClientIP:   "10.0.0.1",
+		Principal:  "alice",
+		TokenType:  "jwt",
+		PartnerIDs: []string{"comcast", "sky"},
+		Reason:     ChecksFailed,
+		Error:      "no capabilities match",
+	}
+}
+
+func TestNewDecisionPublisher(t *testing.T) {
+	producer := &recordingProducer{}
+	tests := []struct {
+		description    string
+		producer       Producer
+		config         DecisionPublisherConfig
+		expectedConfig DecisionPublisherConfig
+		expectedErr    error
+	}{
+		{
+			description: "Defaults",
+			producer:    producer,
+			config:      DecisionPublisherConfig{Topic: "decisions"},
+			expectedConfig: DecisionPublisherConfig{
+				Topic:       "decisions",
+				PartnerKeys: defaultPartnerKeys,
+				QueueSize:   defaultPublisherQueueSize,
+				Timeout:     defaultPublisherTimeout,
+			},
+		},
+		{
+			description: "Custom",
+			producer:    producer,
+			config: DecisionPublisherConfig{
+				Topic:       "decisions",
+				Encoding:    ProtobufEvents,
+				Server:      "server",
+				PartnerKeys: []string{"partners"},
+				QueueSize:   5,
+				Timeout:     time.Second,
+			},
+			expectedConfig: DecisionPublisherConfig{
+				Topic:       "decisions",
+				Encoding:    ProtobufEvents,
+				Server:      "server",
+				PartnerKeys: []string{"partners"},
+				QueueSize:   5,
+				Timeout:     time.Second,
+			},
+		},
+		{
+			description: "Nil Producer Error",
+			config:      DecisionPublisherConfig{Topic: "decisions"},
+			expectedErr: ErrNilProducer,
+		},
+		{
+			description: "Empty Topic Error",
+			producer:    producer,
+			expectedErr: ErrEmptyTopic,
+		},
+		{
+			description: "Unsupported Encoding Error",
+			producer:    producer,
+			config:      DecisionPublisherConfig{Topic: "decisions", Encoding: 7},
+			expectedErr: errors.New("unsupported event encoding 7"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p, err := NewDecisionPublisher(tc.producer, tc.config, nil)
+			if tc.expectedErr != nil {
+				assert.Nil(p)
+				assert.EqualError(err, tc.expectedErr.Error())
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.expectedConfig, p.config)
+		})
+	}
+}
+
+func TestDecisionEventMarshalJSON(t *testing.T) {
+	tests := []struct {
+		description    string
+		decision       string
+		expectedReason interface{}
+	}{
+		{
+			description:    "Deny",
+			decision:       DenyDecision,
+			expectedReason: "checks_failed",
+		},
+		{
+			description: "Allow",
+			decision:    AllowDecision,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			d := testDecision()
+			d.Decision = tc.decision
+			b, err := json.Marshal(d)
+			require.NoError(err)
+			var m map[string]interface{}
+			require.NoError(json.Unmarshal(b, &m))
+			assert.Equal(tc.decision, m["decision"])
+			assert.Equal("alice", m["principal"])
+			assert.Equal("abc123", m["requestId"])
+			assert.Equal([]interface{}{"comcast", "sky"}, m["partnerIds"])
+			assert.Equal("2023-03-04T05:06:07.008Z", m["time"])
+			assert.Equal(tc.expectedReason, m["reason"])
+			assert.NotContains(m, "authorization")
+		})
+	}
+}
+
+func TestDecisionEventMarshalProto(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	d := testDecision()
+	b := d.MarshalProto()
+
+	strs := map[protowire.Number][]string{}
+	var nanos uint64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.Greater(n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.Greater(n, 0)
+			assert.Equal(protowire.Number(1), num)
+			nanos = v
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			require.Greater(n, 0)
+			strs[num] = append(strs[num], v)
+			b = b[n:]
+		default:
+			require.Fail("unexpected wire type")
+		}
+	}
+	assert.Equal(d.Time.UnixNano(), int64(nanos))
+	assert.Equal(map[protowire.Number][]string{
+		2:  {"server"},
+		3:  {DenyDecision},
+		4:  {"abc123"},
+		5:  {http.MethodGet},
+		6:  {"/api/v1/a"},
+		7:  {"10.0.0.1"},
+		8:  {"alice"},
+		9:  {"jwt"},
+		11: {"comcast", "sky"},
+		12: {"checks_failed"},
+		13: {"no capabilities match"},
+	}, strs)
+}
+
+func TestDecisionPublisherHooks(t *testing.T) {
+	auth := bascule.Authentication{
+		Authorization: "Bearer",
+		Token: bascule.NewToken("jwt", "alice", bascule.NewAttributes(map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": []string{"comcast"},
+			},
+		})),
+	}
+	tests := []struct {
+		description string
+		allow       bool
+		event       HookEvent
+		expected    *DecisionEvent
+	}{
+		{
+			description: "Allow",
+			allow:       true,
+			event:       HookEvent{Stage: AfterAllow, Auth: auth, RequestID: "abc123"},
+			expected: &DecisionEvent{
+				Decision:      AllowDecision,
+				RequestID:     "abc123",
+				Authorization: "Bearer",
+				Principal:     "alice",
+				TokenType:     "jwt",
+				PartnerIDs:    []string{"comcast"},
+			},
+		},
+		{
+			description: "Deny",
+			event:       HookEvent{Stage: AfterDeny, Auth: auth, Reason: ChecksFailed, Err: errors.New("nope")},
+			expected: &DecisionEvent{
+				Decision:      DenyDecision,
+				Authorization: "Bearer",
+				Principal:     "alice",
+				TokenType:     "jwt",
+				PartnerIDs:    []string{"comcast"},
+				Reason:        ChecksFailed,
+				Error:         "nope",
+			},
+		},
+		{
+			description: "Deny Without Token",
+			event:       HookEvent{Stage: AfterDeny, Reason: MissingHeader},
+			expected: &DecisionEvent{
+				Decision: DenyDecision,
+				Reason:   MissingHeader,
+			},
+		},
+		{
+			description: "Wrong Stage",
+			event:       HookEvent{Stage: BeforeDecision},
+		},
+	}
+	now := time.Date(2023, time.March, 4, 5, 6, 7, 0, time.UTC)
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			p, err := NewDecisionPublisher(&recordingProducer{}, DecisionPublisherConfig{Topic: "decisions", Server: "server"}, nil)
+			require.NoError(err)
+			p.now = func() time.Time { return now }
+			hook := p.DenyHook()
+			if tc.allow {
+				hook = p.AllowHook()
+			}
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/a", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			assert.Nil(hook(httptest.NewRecorder(), r, tc.event))
+			if tc.expected == nil {
+				assert.Empty(p.queue)
+				return
+			}
+			require.Len(p.queue, 1)
+			expected := *tc.expected
+			expected.Time = now
+			expected.Server = "server"
+			expected.Method = http.MethodPost
+			expected.Path = "/api/v1/a"
+			expected.ClientIP = "10.0.0.1"
+			assert.Equal(expected, <-p.queue)
+		})
+	}
+}
+
+func TestDecisionPublisherQueueFull(t *testing.T) {
+	assert := assert.New(t)
+	p, err := NewDecisionPublisher(&recordingProducer{}, DecisionPublisherConfig{Topic: "decisions", QueueSize: 1}, nil)
+	assert.NoError(err)
+	assert.True(p.Publish(testDecision()))
+	assert.False(p.Publish(testDecision()))
+}
+
+func TestDecisionPublisherLifecycle(t *testing.T) {
+	tests := []struct {
+		description string
+		encoding    EventEncoding
+		produceErr  error
+	}{
+		{
+			description: "JSON",
+			encoding:    JSONEvents,
+		},
+		{
+			description: "Protobuf",
+			encoding:    ProtobufEvents,
+		},
+		{
+			description: "Producer Error",
+			produceErr:  errors.New("broker unavailable"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			producer := &recordingProducer{err: tc.produceErr}
+			p, err := NewDecisionPublisher(producer, DecisionPublisherConfig{Topic: "decisions", Encoding: tc.encoding}, nil)
+			require.NoError(err)
+
+			// queued events are published when the publisher stops.
+			d := testDecision()
+			assert.True(p.Publish(d))
+			assert.True(p.Publish(d))
+			hook := p.Hook()
+			require.NoError(hook.OnStart(context.Background()))
+			p.Start()
+			require.NoError(hook.OnStop(context.Background()))
+			p.Stop()
+
+			expected, err := p.Encode(d)
+			require.NoError(err)
+			messages := producer.produced()
+			require.Len(messages, 2)
+			for _, m := range messages {
+				assert.Equal("decisions", m.topic)
+				assert.Equal("alice", m.key)
+				assert.Equal(expected, m.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// TokenFactoryDecorator wraps a TokenFactory, so that features like caching,
+// enrichment, and metrics can be added to any TokenFactory rather than to each
+// implementation.
+type TokenFactoryDecorator func(TokenFactory) TokenFactory
+
+// DecorateTokenFactory wraps the TokenFactory with the decorators.  The first
+// decorator is the outermost, so it runs first, like an alice chain.  Nil
+// decorators, and decorators returning nil, are skipped.
+func DecorateTokenFactory(tf TokenFactory, decorators ...TokenFactoryDecorator) TokenFactory {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		if decorators[i] == nil {
+			continue
+		}
+		if decorated := decorators[i](tf); decorated != nil {
+			tf = decorated
+		}
+	}
+	return tf
+}
+
+// WithCTokenFactoryDecorators adds decorators that wrap every TokenFactory of
+// the constructor.  They wrap the decorators added for a scheme with
+// WithCSchemeDecorators, whatever order the options are in.
+func WithCTokenFactoryDecorators(decorators ...TokenFactoryDecorator) COption {
+	return func(c *constructor) {
+		c.decorators = append(c.decorators, decorators...)
+	}
+}
+
+// WithCSchemeDecorators adds decorators that wrap the TokenFactory of one
+// scheme.
+func WithCSchemeDecorators(key bascule.Authorization, decorators ...TokenFactoryDecorator) COption {
+	return func(c *constructor) {
+		if len(decorators) == 0 {
+			return
+		}
+		if c.schemeDecorators == nil {
+			c.schemeDecorators = make(map[bascule.Authorization][]TokenFactoryDecorator)
+		}
+		c.schemeDecorators[key] = append(c.schemeDecorators[key], decorators...)
+	}
+}
+
+// decorateTokenFactories wraps the constructor's token factories with their
+// decorators, once all the options have been applied.
+func (c *constructor) decorateTokenFactories() {
+	if len(c.decorators) == 0 && len(c.schemeDecorators) == 0 {
+		return
+	}
+	for key, tf := range c.authorizations {
+		tf = DecorateTokenFactory(tf, c.schemeDecorators[key]...)
+		c.authorizations[key] = DecorateTokenFactory(tf, c.decorators...)
+	}
+}
+
+// EnrichTokens decorates a TokenFactory so that the tokens it returns are
+// passed through the enrichers, in order.
+func EnrichTokens(enrichers ...bascule.TokenEnricher) TokenFactoryDecorator {
+	return func(next TokenFactory) TokenFactory {
+		return TokenFactoryFunc(func(ctx context.Context, r *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+			token, err := next.ParseAndValidate(ctx, r, a, v)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range enrichers {
+				if e == nil {
+					continue
+				}
+				token, err = e.Enrich(ctx, token)
+				if err != nil {
+					return nil, fmt.Errorf("failed to enrich token: %w", err)
+				}
+			}
+			return token, nil
+		})
+	}
+}
+
+// TokenFactoryObserver is given the outcome of each call to a TokenFactory,
+// for recording metrics.
+type TokenFactoryObserver func(ctx context.Context, key bascule.Authorization, d time.Duration, err error)
+
+// ObserveTokenFactory decorates a TokenFactory so that the observer is
+// called with how long each call took and the error it returned.  A nil
+// observer leaves the TokenFactory undecorated.
+func ObserveTokenFactory(observe TokenFactoryObserver) TokenFactoryDecorator {
+	return func(next TokenFactory) TokenFactory {
+		if observe == nil {
+			return next
+		}
+		return TokenFactoryFunc(func(ctx context.Context, r *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+			start := time.Now()
+			token, err := next.ParseAndValidate(ctx, r, a, v)
+			observe(ctx, a, time.Since(start), err)
+			return token, err
+		})
+	}
+}
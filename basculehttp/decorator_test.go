@@ -0,0 +1,187 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDecorator appends its name to calls each time the factory it
+// wraps is called.
+func recordingDecorator(calls *[]string, name string) TokenFactoryDecorator {
+	return func(next TokenFactory) TokenFactory {
+		return TokenFactoryFunc(func(ctx context.Context, r *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+			*calls = append(*calls, name)
+			return next.ParseAndValidate(ctx, r, a, v)
+		})
+	}
+}
+
+func TestDecorateTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	var calls []string
+	tf := DecorateTokenFactory(
+		TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+			calls = append(calls, "factory")
+			return bascule.NewToken("test", "alice", nil), nil
+		}),
+		recordingDecorator(&calls, "outer"),
+		nil,
+		func(TokenFactory) TokenFactory { return nil },
+		recordingDecorator(&calls, "inner"),
+	)
+	token, err := tf.ParseAndValidate(context.Background(), nil, BasicAuthorization, "abc")
+	assert.NoError(err)
+	assert.Equal("alice", token.Principal())
+	assert.Equal([]string{"outer", "inner", "factory"}, calls)
+}
+
+func TestConstructorDecorators(t *testing.T) {
+	tests := []struct {
+		description   string
+		header        string
+		expectedCalls []string
+	}{
+		{
+			description:   "Basic",
+			header:        "Basic abc",
+			expectedCalls: []string{"all1", "all2", "basic", "factory"},
+		},
+		{
+			description:   "Bearer",
+			header:        "Bearer abc",
+			expectedCalls: []string{"all1", "all2", "factory"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var calls []string
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				calls = append(calls, "factory")
+				return bascule.NewToken("test", "alice", nil), nil
+			})
+			handler := NewConstructor(
+				WithCSchemeDecorators(BasicAuthorization, recordingDecorator(&calls, "basic")),
+				WithCSchemeDecorators(BearerAuthorization),
+				WithCTokenFactoryDecorators(recordingDecorator(&calls, "all1"), recordingDecorator(&calls, "all2")),
+				WithTokenFactory(BasicAuthorization, tf),
+				WithTokenFactory(BearerAuthorization, tf),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.header)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestEnrichTokens(t *testing.T) {
+	parseErr := errors.New("parse failed")
+	enrichErr := errors.New("enrich failed")
+	tests := []struct {
+		description       string
+		parseErr          error
+		enrichers         []bascule.TokenEnricher
+		expectedPrincipal string
+		expectedErr       error
+	}{
+		{
+			description: "Enriched",
+			enrichers: []bascule.TokenEnricher{
+				nil,
+				bascule.TokenEnricherFunc(func(_ context.Context, t bascule.Token) (bascule.Token, error) {
+					return bascule.NewToken(t.Type(), t.Principal()+"-enriched", t.Attributes()), nil
+				}),
+			},
+			expectedPrincipal: "alice-enriched",
+		},
+		{
+			description: "Parse Error",
+			parseErr:    parseErr,
+			enrichers: []bascule.TokenEnricher{
+				bascule.TokenEnricherFunc(func(context.Context, bascule.Token) (bascule.Token, error) {
+					panic("enricher called after the factory failed")
+				}),
+			},
+			expectedErr: parseErr,
+		},
+		{
+			description: "Enrich Error",
+			enrichers: []bascule.TokenEnricher{
+				bascule.TokenEnricherFunc(func(context.Context, bascule.Token) (bascule.Token, error) {
+					return nil, enrichErr
+				}),
+			},
+			expectedErr: enrichErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			tf := EnrichTokens(tc.enrichers...)(TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				if tc.parseErr != nil {
+					return nil, tc.parseErr
+				}
+				return bascule.NewToken("test", "alice", nil), nil
+			}))
+			token, err := tf.ParseAndValidate(context.Background(), nil, BasicAuthorization, "abc")
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				assert.Nil(token)
+				return
+			}
+			require.NotNil(t, token)
+			assert.Equal(tc.expectedPrincipal, token.Principal())
+		})
+	}
+}
+
+func TestObserveTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	parseErr := errors.New("parse failed")
+	tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+		time.Sleep(time.Millisecond)
+		return nil, parseErr
+	})
+	assert.NotNil(ObserveTokenFactory(nil)(tf))
+
+	var (
+		key      bascule.Authorization
+		duration time.Duration
+		observed error
+	)
+	decorated := ObserveTokenFactory(func(_ context.Context, k bascule.Authorization, d time.Duration, err error) {
+		key, duration, observed = k, d, err
+	})(tf)
+	_, err := decorated.ParseAndValidate(context.Background(), nil, BearerAuthorization, "abc")
+	assert.ErrorIs(err, parseErr)
+	assert.ErrorIs(observed, parseErr)
+	assert.Equal(BearerAuthorization, key)
+	assert.GreaterOrEqual(duration, time.Millisecond)
+}
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// DefaultConveyHeader is the header Xmidt devices send their convey
+	// information in, as base64 encoded JSON.
+	DefaultConveyHeader = "X-Webpa-Convey"
+
+	// DeviceTokenType is the type of the Tokens created by the
+	// DeviceTokenFactory.
+	DeviceTokenType = "device"
+
+	// ConveyKey is the attribute key the decoded convey information is stored
+	// under.
+	ConveyKey = "convey"
+
+	conveyMacKey    = "hw-mac"
+	conveySerialKey = "hw-serial-number"
+)
+
+var ErrNoDeviceID = bascule.NewClassError(bascule.MissingCredentialsClass, "no device id found")
+
+// DeviceTokenFactory authenticates devices, creating a Token whose principal
+// is the normalized device id and whose kind is bascule.DeviceKind.
+//
+// If a JWT TokenFactory is set, the value must be a device JWT and the device
+// id is read from the token's principal; the convey header is never used in
+// that case.  Otherwise, if AllowConvey is set, the device id is built from
+// the MAC address or serial number found in the convey header.  The convey
+// header isn't signed, so it should only be allowed behind something that has
+// already authenticated the device, such as a TLS terminator that requires
+// client certificates.
+type DeviceTokenFactory struct {
+	// JWT parses device JWTs.  If it is set, convey headers are ignored.
+	JWT TokenFactory
+
+	// ConveyHeader is the header to read convey information from.  Defaults
+	// to DefaultConveyHeader.
+	ConveyHeader string
+
+	// AllowConvey turns on authentication using the unsigned convey header
+	// when no JWT TokenFactory is set.
+	AllowConvey bool
+}
+
+// ParseAndValidate parses the device credential found in the request and
+// returns a device Token.
+func (dtf DeviceTokenFactory) ParseAndValidate(ctx context.Context, r *http.Request, a bascule.Authorization, value string) (bascule.Token, error) {
+	if dtf.JWT != nil {
+		if len(value) == 0 {
+			return nil, ErrEmptyValue
+		}
+		return dtf.parseJWT(ctx, r, a, value)
+	}
+	if !dtf.AllowConvey {
+		return nil, fmt.Errorf("%w: convey header not allowed", ErrNoCredential)
+	}
+	return dtf.parseConvey(r)
+}
+
+func (dtf DeviceTokenFactory) parseJWT(ctx context.Context, r *http.Request, a bascule.Authorization, value string) (bascule.Token, error) {
+	t, err := dtf.JWT.ParseAndValidate(ctx, r, a, value)
+	if err != nil {
+		return nil, err
+	}
+	id, err := bascule.NormalizeDeviceID(t.Principal())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrincipal, err)
+	}
+	return newDeviceToken(id, t.Attributes()), nil
+}
+
+func (dtf DeviceTokenFactory) parseConvey(r *http.Request) (bascule.Token, error) {
+	header := dtf.ConveyHeader
+	if header == "" {
+		header = DefaultConveyHeader
+	}
+	raw := r.Header.Get(header)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: header %v", ErrNoCredential, header)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode convey header: %v", err)
+	}
+	var convey map[string]interface{}
+	if err := json.Unmarshal(decoded, &convey); err != nil {
+		return nil, fmt.Errorf("could not unmarshal convey header: %v", err)
+	}
+
+	var id string
+	if mac, ok := convey[conveyMacKey].(string); ok && len(mac) > 0 {
+		id = bascule.MacScheme + ":" + mac
+	} else if serial, ok := convey[conveySerialKey].(string); ok && len(serial) > 0 {
+		id = bascule.SerialScheme + ":" + serial
+	} else {
+		return nil, ErrNoDeviceID
+	}
+	id, err = bascule.NormalizeDeviceID(id)
+	if err != nil {
+		return nil, err
+	}
+	return newDeviceToken(id, bascule.NewAttributes(map[string]interface{}{
+		ConveyKey: convey,
+	})), nil
+}
+
+func newDeviceToken(id string, attributes bascule.Attributes) bascule.Token {
+	return bascule.NewToken(DeviceTokenType, id, deviceAttributes{base: attributes})
+}
+
+// deviceAttributes marks the token as a device token.
+type deviceAttributes struct {
+	base bascule.Attributes
+}
+
+func (d deviceAttributes) Get(key string) (interface{}, bool) {
+	if key == bascule.TokenKindKey {
+		return bascule.DeviceKind, true
+	}
+	if d.base == nil {
+		return nil, false
+	}
+	return d.base.Get(key)
+}
+
+func (d deviceAttributes) Keys() ([]string, bool) {
+	return bascule.LayeredKeys(d.base, bascule.TokenKindKey)
+}
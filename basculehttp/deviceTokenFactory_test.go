@@ -0,0 +1,174 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceTokenFactory(t *testing.T) {
+	jwtFactory := TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+		switch v {
+		case "good":
+			return bascule.NewToken("jwt", "MAC:11:22:33:44:55:66", bascule.NewAttributes(map[string]interface{}{"iss": "test"})), nil
+		case "bad-principal":
+			return bascule.NewToken("jwt", "user", nil), nil
+		}
+		return nil, errors.New("bad jwt")
+	})
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		description       string
+		factory           DeviceTokenFactory
+		value             string
+		header            string
+		convey            string
+		expectedPrincipal string
+		expectedAttribute string
+		expectedErr       error
+		expectErr         bool
+	}{
+		{
+			description:       "JWT Success",
+			factory:           DeviceTokenFactory{JWT: jwtFactory},
+			value:             "good",
+			expectedPrincipal: "mac:112233445566",
+			expectedAttribute: "iss",
+		},
+		{
+			description: "JWT Error",
+			factory:     DeviceTokenFactory{JWT: jwtFactory},
+			value:       "bad",
+			expectErr:   true,
+		},
+		{
+			description: "JWT Principal Error",
+			factory:     DeviceTokenFactory{JWT: jwtFactory},
+			value:       "bad-principal",
+			expectedErr: ErrInvalidPrincipal,
+		},
+		{
+			description: "JWT Empty Value Ignores Convey",
+			factory:     DeviceTokenFactory{JWT: jwtFactory, AllowConvey: true},
+			convey:      encode(`{"hw-mac":"AA-BB-CC-DD-EE-FF"}`),
+			expectedErr: ErrEmptyValue,
+		},
+		{
+			description:       "Convey Mac Success",
+			factory:           DeviceTokenFactory{AllowConvey: true},
+			convey:            encode(`{"hw-mac":"AA-BB-CC-DD-EE-FF","hw-serial-number":"123"}`),
+			expectedPrincipal: "mac:aabbccddeeff",
+			expectedAttribute: ConveyKey,
+		},
+		{
+			description:       "Convey Serial Success",
+			factory:           DeviceTokenFactory{AllowConvey: true},
+			convey:            encode(`{"hw-serial-number":"ABC123"}`),
+			expectedPrincipal: "serial:ABC123",
+			expectedAttribute: ConveyKey,
+		},
+		{
+			description:       "Custom Header Success",
+			factory:           DeviceTokenFactory{ConveyHeader: "X-Convey", AllowConvey: true},
+			header:            "X-Convey",
+			convey:            encode(`{"hw-serial-number":"ABC123"}`),
+			expectedPrincipal: "serial:ABC123",
+		},
+		{
+			description: "Convey Not Allowed",
+			convey:      encode(`{"hw-serial-number":"ABC123"}`),
+			expectedErr: ErrNoCredential,
+		},
+		{
+			description: "No Convey Header",
+			factory:     DeviceTokenFactory{AllowConvey: true},
+			expectedErr: ErrNoCredential,
+		},
+		{
+			description: "Decode Error",
+			factory:     DeviceTokenFactory{AllowConvey: true},
+			convey:      "!!!",
+			expectErr:   true,
+		},
+		{
+			description: "Unmarshal Error",
+			factory:     DeviceTokenFactory{AllowConvey: true},
+			convey:      encode(`[]`),
+			expectErr:   true,
+		},
+		{
+			description: "No Device ID",
+			factory:     DeviceTokenFactory{AllowConvey: true},
+			convey:      encode(`{"fw-name":"test"}`),
+			expectedErr: ErrNoDeviceID,
+		},
+		{
+			description: "Invalid Mac",
+			factory:     DeviceTokenFactory{AllowConvey: true},
+			convey:      encode(`{"hw-mac":"1122"}`),
+			expectedErr: bascule.ErrInvalidDeviceID,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if len(tc.convey) > 0 {
+				header := tc.header
+				if header == "" {
+					header = DefaultConveyHeader
+				}
+				req.Header.Set(header, tc.convey)
+			}
+			token, err := tc.factory.ParseAndValidate(context.Background(), req, "Device", tc.value)
+			if tc.expectErr || tc.expectedErr != nil {
+				assert.Nil(token)
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(DeviceTokenType, token.Type())
+			assert.Equal(tc.expectedPrincipal, token.Principal())
+			assert.Equal(bascule.DeviceKind, bascule.KindOf(token))
+			if len(tc.expectedAttribute) > 0 {
+				_, ok := token.Attributes().Get(tc.expectedAttribute)
+				assert.True(ok)
+			}
+		})
+	}
+}
+
+func TestDeviceAttributesKeys(t *testing.T) {
+	assert := assert.New(t)
+	keys, ok := bascule.AttributeKeys(deviceAttributes{})
+	assert.True(ok)
+	assert.Equal([]string{bascule.TokenKindKey}, keys)
+}
@@ -0,0 +1,369 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5" //nolint:gosec // required by RFC 7616 for legacy clients
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// DigestAuthorization is the authorization scheme for HTTP Digest
+	// authentication, as described in RFC 7616.
+	DigestAuthorization bascule.Authorization = "Digest"
+
+	// DigestTokenType is the type of tokens created by the
+	// DigestTokenFactory.
+	DigestTokenType = "digest"
+
+	// The algorithms supported by the DigestTokenFactory.
+	DigestMD5       = "MD5"
+	DigestSHA256    = "SHA-256"
+	DigestSHA512256 = "SHA-512-256"
+
+	defaultDigestNonceTTL = 5 * time.Minute
+	digestNonceRandomLen  = 16
+	digestNonceMACLen     = 16
+)
+
+var (
+	ErrNilDigestSecrets      = errors.New("digest secrets cannot be nil")
+	ErrEmptyDigestRealm      = errors.New("digest realm cannot be empty")
+	ErrMalformedDigest       = bascule.NewClassError(bascule.MalformedClass, "malformed digest authorization")
+	ErrUnsupportedDigest     = bascule.NewClassError(bascule.MalformedClass, "unsupported digest algorithm or qop")
+	ErrDigestRealmMismatch   = bascule.NewClassError(bascule.InvalidClass, "digest realm does not match")
+	ErrDigestURIMismatch     = bascule.NewClassError(bascule.InvalidClass, "digest uri does not match the request")
+	ErrInvalidDigestNonce    = bascule.NewClassError(bascule.InvalidClass, "invalid digest nonce")
+	ErrStaleDigestNonce      = bascule.NewClassError(bascule.ExpiredClass, "stale digest nonce")
+	ErrDigestNonceCountReuse = bascule.NewClassError(bascule.InvalidClass, "digest nonce count reused")
+)
+
+// DigestSecrets looks up the H(username:realm:password) value for a user,
+// hex encoded and hashed with the algorithm given.  This allows passwords to
+// be stored pre-hashed.  ErrorPrincipalNotFound should be returned for
+// unknown users.
+type DigestSecrets interface {
+	HA1(ctx context.Context, username, realm, algorithm string) (string, error)
+}
+
+// DigestPasswords is a DigestSecrets backed by a map of usernames to
+// plaintext passwords.
+type DigestPasswords map[string]string
+
+// HA1 hashes the user's password with their username and the realm.
+func (dp DigestPasswords) HA1(_ context.Context, username, realm, algorithm string) (string, error) {
+	password, ok := dp[username]
+	if !ok {
+		return "", ErrorPrincipalNotFound
+	}
+	h, ok := digestHash(algorithm)
+	if !ok {
+		return "", ErrUnsupportedDigest
+	}
+	return digestHex(h, username, realm, password), nil
+}
+
+// DigestConfig configures a DigestTokenFactory.
+type DigestConfig struct {
+	// Realm is the protection space the credentials are valid for.  It's
+	// required.
+	Realm string
+
+	// Algorithms are the hash algorithms offered to clients, most preferred
+	// first.  Defaults to SHA-256 and MD5.
+	Algorithms []string
+
+	// NonceTTL is how long a nonce can be used for before clients must
+	// retry with a new one.  Defaults to 5 minutes.
+	NonceTTL time.Duration
+
+	// NonceKey is the key used to sign nonces.  If empty, a random key is
+	// generated.  Instances behind a load balancer need to share a key so
+	// that a nonce issued by one can be used with another.
+	NonceKey []byte
+
+	// Storage records the nonce counts used, to prevent replays.  Defaults
+	// to in-memory storage.  Instances sharing a NonceKey should share
+	// Storage too, otherwise a request can be replayed to another instance.
+	Storage bascule.Storage
+}
+
+// DigestTokenFactory implements HTTP Digest authentication with qop=auth,
+// for clients that can't use bearer tokens.  Nonces are signed rather than
+// stored, and each nonce count is only accepted once to prevent replays.  It
+// implements Challenger so the constructor can advertise it in the
+// WWW-Authenticate header.
+type DigestTokenFactory struct {
+	secrets DigestSecrets
+	config  DigestConfig
+	now     func() time.Time
+}
+
+// NewDigestTokenFactory creates a DigestTokenFactory that checks credentials
+// with the secrets given.
+func NewDigestTokenFactory(secrets DigestSecrets, config DigestConfig) (*DigestTokenFactory, error) {
+	if secrets == nil {
+		return nil, ErrNilDigestSecrets
+	}
+	if config.Realm == "" {
+		return nil, ErrEmptyDigestRealm
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{DigestSHA256, DigestMD5}
+	}
+	for _, a := range config.Algorithms {
+		if _, ok := digestHash(a); !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedDigest, a)
+		}
+	}
+	if config.NonceTTL <= 0 {
+		config.NonceTTL = defaultDigestNonceTTL
+	}
+	if len(config.NonceKey) == 0 {
+		config.NonceKey = make([]byte, 32)
+		if _, err := rand.Read(config.NonceKey); err != nil {
+			return nil, fmt.Errorf("failed to generate digest nonce key: %w", err)
+		}
+	}
+	if config.Storage == nil {
+		config.Storage = bascule.NewMemoryStorage()
+	}
+	return &DigestTokenFactory{
+		secrets: secrets,
+		config:  config,
+		now:     time.Now,
+	}, nil
+}
+
+// ParseAndValidate checks the digest response in the authorization value
+// against the request and the user's secret.
+func (d *DigestTokenFactory) ParseAndValidate(ctx context.Context, r *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	params, err := parseDigestParams(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"username", "realm", "nonce", "uri", "response", "qop", "nc", "cnonce"} {
+		if params[k] == "" {
+			return nil, fmt.Errorf("%w: missing %s", ErrMalformedDigest, k)
+		}
+	}
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = DigestMD5
+	}
+	h, ok := d.algorithm(algorithm)
+	if !ok || params["qop"] != "auth" {
+		return nil, ErrUnsupportedDigest
+	}
+	if params["realm"] != d.config.Realm {
+		return nil, ErrDigestRealmMismatch
+	}
+	if r != nil && params["uri"] != r.URL.RequestURI() && params["uri"] != r.RequestURI {
+		return nil, ErrDigestURIMismatch
+	}
+	nc, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nc", ErrMalformedDigest)
+	}
+	expires, err := d.checkNonce(params["nonce"])
+	if err != nil {
+		return nil, err
+	}
+
+	username := params["username"]
+	ha1, err := d.secrets.HA1(ctx, username, d.config.Realm, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	method := http.MethodGet
+	if r != nil {
+		method = r.Method
+	}
+	ha2 := digestHex(h, method, params["uri"])
+	expected := digestHex(h, ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(params["response"]))) != 1 {
+		return nil, ErrorInvalidPassword
+	}
+
+	// only count the nonce as used once the response is valid, so that
+	// unauthenticated clients can't burn other clients' nonce counts.
+	if err := d.useNonce(ctx, params["nonce"], nc, expires); err != nil {
+		return nil, err
+	}
+	return bascule.NewToken(DigestTokenType, username, bascule.NewAttributes(map[string]interface{}{})), nil
+}
+
+// Challenges returns a WWW-Authenticate challenge for each algorithm, each
+// with a fresh nonce.  If the error given is ErrStaleDigestNonce, the
+// challenges tell the client it can retry without asking the user again.
+func (d *DigestTokenFactory) Challenges(_ *http.Request, err error) []string {
+	nonce := d.newNonce()
+	stale := ""
+	if errors.Is(err, ErrStaleDigestNonce) {
+		stale = ", stale=true"
+	}
+	challenges := make([]string, 0, len(d.config.Algorithms))
+	for _, a := range d.config.Algorithms {
+		challenges = append(challenges, fmt.Sprintf(`%s realm=%s, qop="auth", algorithm=%s, nonce="%s"%s`,
+			DigestAuthorization, strconv.Quote(d.config.Realm), a, nonce, stale))
+	}
+	return challenges
+}
+
+func (d *DigestTokenFactory) algorithm(name string) (func() hash.Hash, bool) {
+	for _, a := range d.config.Algorithms {
+		if strings.EqualFold(a, name) {
+			return digestHash(a)
+		}
+	}
+	return nil, false
+}
+
+// newNonce creates a nonce holding the time it was issued, some random bytes,
+// and a signature over both.
+func (d *DigestTokenFactory) newNonce() string {
+	raw := make([]byte, 8+digestNonceRandomLen, 8+digestNonceRandomLen+digestNonceMACLen)
+	binary.BigEndian.PutUint64(raw, uint64(d.now().Unix()))
+	_, _ = rand.Read(raw[8:])
+	return base64.RawURLEncoding.EncodeToString(append(raw, d.nonceMAC(raw)...))
+}
+
+func (d *DigestTokenFactory) nonceMAC(raw []byte) []byte {
+	mac := hmac.New(sha256.New, d.config.NonceKey)
+	mac.Write(raw)
+	return mac.Sum(nil)[:digestNonceMACLen]
+}
+
+// checkNonce verifies the nonce's signature and age, returning when it
+// expires.
+func (d *DigestTokenFactory) checkNonce(nonce string) (time.Time, error) {
+	b, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(b) != 8+digestNonceRandomLen+digestNonceMACLen {
+		return time.Time{}, ErrInvalidDigestNonce
+	}
+	raw, mac := b[:8+digestNonceRandomLen], b[8+digestNonceRandomLen:]
+	if !hmac.Equal(mac, d.nonceMAC(raw)) {
+		return time.Time{}, ErrInvalidDigestNonce
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(raw)), 0).Add(d.config.NonceTTL)
+	if !d.now().Before(expires) {
+		return time.Time{}, ErrStaleDigestNonce
+	}
+	return expires, nil
+}
+
+// useNonce records the nonce count, which can only be used once with the
+// nonce.  Counts can arrive out of order, since clients send requests
+// concurrently.
+func (d *DigestTokenFactory) useNonce(ctx context.Context, nonce string, nc uint64, expires time.Time) error {
+	key := "digest:" + nonce + ":" + strconv.FormatUint(nc, 16)
+	n, err := d.config.Storage.Incr(ctx, key, expires.Sub(d.now()))
+	if err != nil {
+		return fmt.Errorf("failed to record digest nonce count: %w", err)
+	}
+	if n > 1 {
+		return ErrDigestNonceCountReuse
+	}
+	return nil
+}
+
+func digestHash(algorithm string) (func() hash.Hash, bool) {
+	switch strings.ToUpper(algorithm) {
+	case DigestMD5:
+		return md5.New, true
+	case DigestSHA256:
+		return sha256.New, true
+	case DigestSHA512256:
+		return sha512.New512_256, true
+	}
+	return nil, false
+}
+
+// digestHex hashes the values joined by colons and hex encodes the result.
+func digestHex(h func() hash.Hash, values ...string) string {
+	hh := h()
+	hh.Write([]byte(strings.Join(values, ":")))
+	return hex.EncodeToString(hh.Sum(nil))
+}
+
+// parseDigestParams parses the comma separated key=value pairs of a digest
+// authorization, where values may be quoted strings.
+func parseDigestParams(value string) (map[string]string, error) {
+	params := make(map[string]string)
+	s := strings.TrimSpace(value)
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, ErrMalformedDigest
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, ErrMalformedDigest
+			}
+			val, s = b.String(), s[i+1:]
+		} else {
+			end := strings.IndexAny(s, ", \t")
+			if end < 0 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+		}
+		if _, dup := params[key]; dup {
+			return nil, fmt.Errorf("%w: duplicate %s", ErrMalformedDigest, key)
+		}
+		params[key] = val
+
+		s = strings.TrimLeft(s, " \t")
+		if len(s) > 0 {
+			if s[0] != ',' {
+				return nil, ErrMalformedDigest
+			}
+			s = strings.TrimLeft(s[1:], " \t")
+		}
+	}
+	return params, nil
+}
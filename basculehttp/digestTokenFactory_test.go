@@ -0,0 +1,232 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var digestNonceRegex = regexp.MustCompile(`nonce="([^"]+)"`)
+
+func digestAuthorization(h func() hash.Hash, algorithm, username, password, realm, method, uri, nonce, nc string) string {
+	ha1 := digestHex(h, username, realm, password)
+	ha2 := digestHex(h, method, uri)
+	response := digestHex(h, ha1, nonce, nc, "abc", "auth", ha2)
+	return fmt.Sprintf(`username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, qop=auth, nc=%s, cnonce="abc", response="%s"`,
+		username, realm, nonce, uri, algorithm, nc, response)
+}
+
+func TestDigestTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	d, err := NewDigestTokenFactory(DigestPasswords{"user": "pass"}, DigestConfig{Realm: "test@example.com"})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+
+	challenges := d.Challenges(nil, nil)
+	require.Len(challenges, 2)
+	assert.True(strings.HasPrefix(challenges[0], `Digest realm="test@example.com", qop="auth", algorithm=SHA-256, nonce="`))
+	assert.Contains(challenges[1], "algorithm=MD5")
+	assert.NotContains(challenges[0], "stale")
+	nonce := digestNonceRegex.FindStringSubmatch(challenges[0])[1]
+	forged := []byte(nonce)
+	forged[20] ^= 1
+
+	req := httptest.NewRequest(http.MethodGet, "/a/b?c=d", nil)
+	try := func(value string) error {
+		_, err := d.ParseAndValidate(context.Background(), req, DigestAuthorization, value)
+		return err
+	}
+
+	tests := []struct {
+		description string
+		value       string
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000001"),
+		},
+		{
+			description: "MD5 Success",
+			value:       digestAuthorization(md5.New, "MD5", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000002"),
+		},
+		{
+			description: "Nonce Count Reused Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000002"),
+			expectedErr: ErrDigestNonceCountReuse,
+		},
+		{
+			description: "Wrong Password Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "nope", "test@example.com", "GET", "/a/b?c=d", nonce, "00000003"),
+			expectedErr: ErrorInvalidPassword,
+		},
+		{
+			description: "Unknown User Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "bob", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000003"),
+			expectedErr: ErrorPrincipalNotFound,
+		},
+		{
+			description: "Wrong Method Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "POST", "/a/b?c=d", nonce, "00000003"),
+			expectedErr: ErrorInvalidPassword,
+		},
+		{
+			description: "Wrong URI Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a", nonce, "00000003"),
+			expectedErr: ErrDigestURIMismatch,
+		},
+		{
+			description: "Wrong Realm Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "other", "GET", "/a/b?c=d", nonce, "00000003"),
+			expectedErr: ErrDigestRealmMismatch,
+		},
+		{
+			description: "Unsupported Algorithm Error",
+			value:       digestAuthorization(sha256.New, "SHA-1", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000003"),
+			expectedErr: ErrUnsupportedDigest,
+		},
+		{
+			description: "Forged Nonce Error",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", string(forged), "00000003"),
+			expectedErr: ErrInvalidDigestNonce,
+		},
+		{
+			description: "Later Count Success",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "0000000a"),
+		},
+		{
+			description: "Out Of Order Count Success",
+			value:       digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000004"),
+		},
+		{
+			description: "Missing Parameter Error",
+			value:       `username="user", realm="test@example.com"`,
+			expectedErr: ErrMalformedDigest,
+		},
+		{
+			description: "Unparseable Error",
+			value:       `username="user`,
+			expectedErr: ErrMalformedDigest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := try(tc.value)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+		})
+	}
+
+	now = now.Add(defaultDigestNonceTTL)
+	err = try(digestAuthorization(sha256.New, "SHA-256", "user", "pass", "test@example.com", "GET", "/a/b?c=d", nonce, "00000009"))
+	assert.ErrorIs(err, ErrStaleDigestNonce)
+	assert.Contains(d.Challenges(nil, err)[0], "stale=true")
+}
+
+func TestNewDigestTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewDigestTokenFactory(nil, DigestConfig{Realm: "r"})
+	assert.ErrorIs(err, ErrNilDigestSecrets)
+	_, err = NewDigestTokenFactory(DigestPasswords{}, DigestConfig{})
+	assert.ErrorIs(err, ErrEmptyDigestRealm)
+	_, err = NewDigestTokenFactory(DigestPasswords{}, DigestConfig{Realm: "r", Algorithms: []string{"SHA-1"}})
+	assert.ErrorIs(err, ErrUnsupportedDigest)
+
+	// instances sharing a key accept each other's nonces.
+	key := []byte("key")
+	a, err := NewDigestTokenFactory(DigestPasswords{"user": "pass"}, DigestConfig{Realm: "r", NonceKey: key})
+	assert.NoError(err)
+	b, err := NewDigestTokenFactory(DigestPasswords{"user": "pass"}, DigestConfig{Realm: "r", NonceKey: key})
+	assert.NoError(err)
+	nonce := digestNonceRegex.FindStringSubmatch(a.Challenges(nil, nil)[0])[1]
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	value := digestAuthorization(sha256.New, "SHA-256", "user", "pass", "r", "GET", "/", nonce, "1")
+	token, err := b.ParseAndValidate(context.Background(), req, DigestAuthorization, value)
+	assert.NoError(err)
+	assert.Equal(DigestTokenType, token.Type())
+	assert.Equal("user", token.Principal())
+
+	// replay protection fails closed when the storage is unavailable.
+	c, err := NewDigestTokenFactory(DigestPasswords{"user": "pass"}, DigestConfig{Realm: "r", NonceKey: key, Storage: errStorage{}})
+	assert.NoError(err)
+	_, err = c.ParseAndValidate(context.Background(), req, DigestAuthorization, value)
+	assert.Error(err)
+}
+
+func TestParseDigestParams(t *testing.T) {
+	assert := assert.New(t)
+	params, err := parseDigestParams(` Username="a \"b\", c" ,nc=01,  qop=auth `)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"username": `a "b", c`, "nc": "01", "qop": "auth"}, params)
+
+	for _, v := range []string{"nc", "nc=1 qop=auth", "nc=1, nc=2", `a="b`} {
+		_, err = parseDigestParams(v)
+		assert.ErrorIs(err, ErrMalformedDigest, v)
+	}
+}
+
+func TestConstructorChallenges(t *testing.T) {
+	assert := assert.New(t)
+	d, err := NewDigestTokenFactory(DigestPasswords{}, DigestConfig{Realm: "r", Algorithms: []string{DigestSHA256}})
+	assert.NoError(err)
+	c := NewConstructor(
+		WithTokenFactory(DigestAuthorization, d),
+		WithTokenFactory(BasicAuthorization, BasicTokenFactory{}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	c(next).ServeHTTP(w, req)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	values := w.Header().Values(AuthTypeHeaderKey)
+	if assert.Len(values, 2) {
+		assert.True(strings.HasPrefix(values[0], "Digest "))
+		assert.Equal(string(BearerAuthorization), values[1])
+	}
+
+	c = NewConstructor(
+		WithChallenger(d),
+		WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+			return nil, &LockedOutError{Key: "ip:1"}
+		})),
+	)
+	req.Header.Set(DefaultHeaderName, "Basic abc")
+	w = httptest.NewRecorder()
+	c(next).ServeHTTP(w, req)
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Empty(w.Header().Values(AuthTypeHeaderKey))
+}
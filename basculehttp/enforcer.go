@@ -21,9 +21,11 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/justinas/alice"
-	"github.com/xmidt-org/bascule"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/sallust"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -36,8 +38,24 @@ import (
 type NotFoundBehavior int
 
 const (
+	// Forbid responds with a 403.
 	Forbid NotFoundBehavior = iota
+
+	// Allow runs the next handler without checking the token.
 	Allow
+
+	// Challenge responds with a 401 and the challenges set with
+	// WithNotFoundChallenges, asking the client to use an Authorization
+	// value that has rules.
+	Challenge
+
+	// Handle runs the handler set with WithNotFoundHandler instead of the
+	// next handler.
+	Handle
+
+	// Defer hands the request to the enforcer set with WithNotFoundEnforcer,
+	// which is useful when rolling out rules for a new token type.
+	Defer
 )
 
 // EOption is any function that modifies the enforcer - used to configure
@@ -53,53 +71,214 @@ type EOptionsIn struct {
 
 type enforcer struct {
 	notFoundBehavior NotFoundBehavior
+	challenges       []string
+	notFoundHandler  http.Handler
+	notFoundEnforcer func(http.Handler) http.Handler
+	hooks            hooks
+	skips            skipFuncs
 	rules            map[bascule.Authorization]bascule.Validator
+	defaultRules     bascule.Validator
+	overrides        map[bascule.Authorization]bool
+	kindRules        map[bascule.TokenKind]bascule.Validator
 	getLogger        func(context.Context) *zap.Logger
 	onErrorResponse  OnErrorResponse
+	redactor         *bascule.Redactor
+	budget           int
+	evalTimeout      time.Duration
+	costOrder        bool
+	shortCircuitCost int
+	statusMap        StatusMap
+	errorBodies      *errorBodies
+	ruleMeasures     *RuleMeasures
+	stageStatuses    bool
+	server           string
+}
+
+// rulesFor returns the rules for the Authorization value given: the default
+// rules followed by the value's own rules, unless its rules override the
+// defaults.  It returns false if there are neither.
+func (e *enforcer) rulesFor(key bascule.Authorization) (bascule.Validator, bool) {
+	rules, ok := e.rules[key]
+	switch {
+	case ok && (e.defaultRules == nil || e.overrides[key]):
+		return rules, true
+	case ok:
+		return bascule.Validators{e.defaultRules, rules}, true
+	case e.defaultRules != nil:
+		return e.defaultRules, true
+	}
+	return nil, false
+}
+
+// evaluate runs the rules for the token's Authorization value and then its
+// kind within the request's evaluation budget and timeout.  Rules that go over
+// either limit are logged, even if they pass.
+func (e *enforcer) evaluate(ctx context.Context, logger *zap.Logger, auth bascule.Authentication, rules bascule.Validator) (ErrorResponseReason, error) {
+	budget := bascule.NewBudget(e.budget)
+	ctx = bascule.WithBudget(ctx, budget)
+	if e.evalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.evalTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		if budget.Exceeded() || (e.evalTimeout > 0 && elapsed > e.evalTimeout) {
+			logger.Warn("policy evaluation exceeded its budget",
+				zap.Int("cost", budget.Spent()), zap.Int("budget", budget.Limit()),
+				zap.Duration("elapsed", elapsed), zap.Duration("timeout", e.evalTimeout))
+		}
+	}()
+
+	checks := []bascule.Validator{rules}
+	if kindRules, ok := e.kindRules[bascule.KindOf(auth.Token)]; ok && auth.Token != nil {
+		checks = append(checks, kindRules)
+	}
+	if e.costOrder {
+		checks = []bascule.Validator{bascule.ShortCircuit(e.shortCircuitCost, checks...)}
+	}
+	for _, c := range checks {
+		if c == nil {
+			continue
+		}
+		if err := c.Check(ctx, auth.Token); err != nil {
+			if budget.Exceeded() || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return EvaluationBudgetExceeded, err
+			}
+			return ChecksFailed, err
+		}
+	}
+	return Unknown, nil
+}
+
+// recordRuleFailures counts the named rules that failed, or UnnamedRule if
+// none of them were named.
+func (e *enforcer) recordRuleFailures(ctx context.Context) {
+	if e.ruleMeasures == nil || e.ruleMeasures.RuleFailures == nil {
+		return
+	}
+	var failed []string
+	if d, ok := bascule.DecisionFromContext(ctx); ok {
+		failed = d.Failed()
+	}
+	if len(failed) == 0 {
+		failed = []string{UnnamedRule}
+	}
+	for _, rule := range failed {
+		e.ruleMeasures.RuleFailures.With(prometheus.Labels{
+			ServerLabel: e.server,
+			RuleLabel:   rule,
+		}).Inc()
+	}
+}
+
+// writeDenial writes the status for a request denied by the enforcer.  With
+// stage statuses, authentication failures get a 401 with challenges.
+// Otherwise the request's rules failing gets the StatusMap's status, and a
+// missing Authentication gets a 403.
+func (e *enforcer) writeDenial(response http.ResponseWriter, reason ErrorResponseReason, err error) {
+	if e.stageStatuses {
+		if status, ok := stageStatus(reason, err); ok {
+			if status == http.StatusUnauthorized {
+				e.addChallenges(response)
+			}
+			WriteResponse(response, status, err)
+			return
+		}
+	}
+	if reason == MissingAuthentication {
+		response.WriteHeader(http.StatusForbidden)
+		return
+	}
+	WriteResponse(response, e.statusMap.StatusOr(err, http.StatusForbidden), err)
+}
+
+// addChallenges adds the challenges set with WithNotFoundChallenges, or
+// Bearer, to the response.
+func (e *enforcer) addChallenges(response http.ResponseWriter) {
+	challenges := e.challenges
+	if len(challenges) == 0 {
+		challenges = []string{string(BearerAuthorization)}
+	}
+	for _, c := range challenges {
+		response.Header().Add(AuthTypeHeaderKey, c)
+	}
+}
+
+// notFound follows the NotFoundBehavior for a request whose Authorization
+// value has no rules.  It returns true if the request should continue to the
+// next handler.
+func (e *enforcer) notFound(response http.ResponseWriter, request *http.Request, next http.Handler, auth bascule.Authentication, err error) bool {
+	switch {
+	case e.notFoundBehavior == Allow:
+		return true
+	case e.notFoundBehavior == Challenge:
+		response = e.deny(response, request, auth, ChecksNotFound, err)
+		e.addChallenges(response)
+		response.WriteHeader(http.StatusUnauthorized)
+	case e.notFoundBehavior == Handle && e.notFoundHandler != nil:
+		e.deny(response, request, auth, ChecksNotFound, err)
+		e.notFoundHandler.ServeHTTP(response, request)
+	case e.notFoundBehavior == Defer && e.notFoundEnforcer != nil:
+		e.notFoundEnforcer(next).ServeHTTP(response, request)
+	default:
+		response = e.deny(response, request, auth, ChecksNotFound, err)
+		response.WriteHeader(http.StatusForbidden)
+	}
+	return false
+}
+
+// deny reports why a request was denied and runs the AfterDeny hooks.  It
+// returns the response to write the denial to.
+func (e *enforcer) deny(response http.ResponseWriter, request *http.Request, auth bascule.Authentication, reason ErrorResponseReason, err error) http.ResponseWriter {
+	e.onErrorResponse(reason, err)
+	e.hooks.run(response, request, HookEvent{Stage: AfterDeny, Auth: auth, Reason: reason, Err: err})
+	return e.errorBodies.writer(response, request, reason)
 }
 
 func (e *enforcer) decorate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if e.skips.skip(request) {
+			next.ServeHTTP(response, withSkipped(request))
+			return
+		}
+		auth, _ := bascule.FromContext(request.Context())
+		request = e.hooks.run(response, request, HookEvent{Stage: BeforeDecision, Auth: auth})
 		ctx := request.Context()
 		logger := e.getLogger(ctx)
 		if logger == nil {
 			logger = sallust.Get(ctx)
 		}
+		logger = withRequestIDField(ctx, logger)
 		auth, ok := bascule.FromContext(ctx)
 		if !ok {
 			err := errors.New("no authentication found")
-			logger.Error(err.Error())
-			e.onErrorResponse(MissingAuthentication, err)
-			response.WriteHeader(http.StatusForbidden)
+			logger.Error(err.Error(), outcomeFields(OutcomeError, MissingAuthentication)...)
+			e.writeDenial(e.deny(response, request, auth, MissingAuthentication, err), MissingAuthentication, err)
 			return
 		}
-		rules, ok := e.rules[auth.Authorization]
+		rules, ok := e.rulesFor(auth.Authorization)
 		if !ok {
 			err := errors.New("no rules found for authorization")
-			logger.Error(err.Error(), zap.Any("rules", rules),
-				zap.String("authorization", string(auth.Authorization)), zap.Int("behavior", int(e.notFoundBehavior)))
-			switch e.notFoundBehavior {
-			case Forbid:
-				e.onErrorResponse(ChecksNotFound, err)
-				response.WriteHeader(http.StatusForbidden)
-				return
-			case Allow:
-				// continue
-			default:
-				e.onErrorResponse(ChecksNotFound, err)
-				response.WriteHeader(http.StatusForbidden)
-				return
-			}
-		} else {
-			err := rules.Check(ctx, auth.Token)
-			if err != nil {
-				logger.Error(err.Error())
-				e.onErrorResponse(ChecksFailed, err)
-				WriteResponse(response, http.StatusForbidden, err)
+			logger.Error(err.Error(), append(outcomeFields(OutcomeNoRules, ChecksNotFound), zap.Any("rules", rules),
+				zap.String("authorization", string(auth.Authorization)), zap.Int("behavior", int(e.notFoundBehavior)))...)
+			if !e.notFound(response, request, next, auth, err) {
 				return
 			}
 		}
-		logger.Debug("authentication accepted by enforcer")
+
+		ctx = bascule.WithDecision(ctx, bascule.NewDecision())
+		if reason, err := e.evaluate(ctx, logger, auth, rules); err != nil {
+			redacted := e.redactor.Error(err, auth.Token)
+			logger.Error(redacted.Error(), outcomeFields(OutcomeDenied, reason)...)
+			e.recordRuleFailures(ctx)
+			e.writeDenial(e.deny(response, request, auth, reason, redacted), reason, err)
+			return
+		}
+		logger.Debug("authentication accepted by enforcer", zap.String(OutcomeLogKey, string(OutcomeAccepted)))
+		request = e.hooks.run(response, request.WithContext(ctx), HookEvent{Stage: AfterAllow, Auth: auth})
 		next.ServeHTTP(response, request)
 	})
 }
@@ -108,10 +287,17 @@ func (e *enforcer) decorate(next http.Handler) http.Handler {
 // middleware, allowing for Listeners to be called after a token has been
 // authenticated.
 func NewEnforcer(options ...EOption) func(http.Handler) http.Handler {
+	return newEnforcer(options...).decorate
+}
+
+func newEnforcer(options ...EOption) *enforcer {
 	e := &enforcer{
 		rules:           make(map[bascule.Authorization]bascule.Validator),
+		overrides:       make(map[bascule.Authorization]bool),
+		kindRules:       make(map[bascule.TokenKind]bascule.Validator),
 		getLogger:       sallust.Get,
 		onErrorResponse: DefaultOnErrorResponse,
+		server:          defaultServer,
 	}
 
 	for _, o := range options {
@@ -121,11 +307,13 @@ func NewEnforcer(options ...EOption) func(http.Handler) http.Handler {
 		o(e)
 	}
 
-	return e.decorate
+	return e
 }
 
 // WithNotFoundBehavior sets the behavior upon not finding the Authorization
-// value in the rules map.
+// value in the rules map.  It only applies when there are no default rules.
+// The Handle and Defer behaviors forbid the request if their handler or
+// enforcer isn't set.
 func WithNotFoundBehavior(behavior NotFoundBehavior) EOption {
 	return func(e *enforcer) {
 		if behavior > 0 {
@@ -134,11 +322,125 @@ func WithNotFoundBehavior(behavior NotFoundBehavior) EOption {
 	}
 }
 
+// WithNotFoundChallenges sets the WWW-Authenticate challenges sent with the
+// Challenge NotFoundBehavior and the 401s written with WithEStageStatuses.
+// Defaults to Bearer.
+func WithNotFoundChallenges(challenges ...string) EOption {
+	return func(e *enforcer) {
+		e.challenges = append(e.challenges, challenges...)
+	}
+}
+
+// WithNotFoundHandler sets the handler run with the Handle NotFoundBehavior.
+func WithNotFoundHandler(h http.Handler) EOption {
+	return func(e *enforcer) {
+		if h != nil {
+			e.notFoundHandler = h
+		}
+	}
+}
+
+// WithNotFoundEnforcer sets the enforcer the request is handed to with the
+// Defer NotFoundBehavior.  It is given the next handler, so the request
+// continues if it passes the secondary enforcer's rules.
+func WithNotFoundEnforcer(secondary func(http.Handler) http.Handler) EOption {
+	return func(e *enforcer) {
+		if secondary != nil {
+			e.notFoundEnforcer = secondary
+		}
+	}
+}
+
 // WithRules sets the validator to be used for a given Authorization value.
+// If there are default rules, they run first and both must pass.
 func WithRules(key bascule.Authorization, v bascule.Validator) EOption {
 	return func(e *enforcer) {
 		if v != nil {
 			e.rules[key] = v
+			delete(e.overrides, key)
+		}
+	}
+}
+
+// WithOverrideRules sets the validator to be used for a given Authorization
+// value in place of the default rules.
+func WithOverrideRules(key bascule.Authorization, v bascule.Validator) EOption {
+	return func(e *enforcer) {
+		if v != nil {
+			e.rules[key] = v
+			e.overrides[key] = true
+		}
+	}
+}
+
+// WithDefaultRules sets the validator run for every Authorization value,
+// before the value's own rules.  Authorization values without their own rules
+// only run the default rules, rather than following the NotFoundBehavior.
+func WithDefaultRules(v bascule.Validator) EOption {
+	return func(e *enforcer) {
+		if v != nil {
+			e.defaultRules = v
+		}
+	}
+}
+
+// WithKindRules sets a validator to be run against tokens of the given kind.
+// Kind rules run after the rules for the token's Authorization value pass.
+func WithKindRules(kind bascule.TokenKind, v bascule.Validator) EOption {
+	return func(e *enforcer) {
+		if v != nil {
+			e.kindRules[kind] = v
+		}
+	}
+}
+
+// WithEvaluationBudget limits the work done evaluating the rules for a single
+// request.  The cost of each validator wrapped with bascule.WithCost is spent
+// from the budget, and the rules are given a context that ends after the
+// timeout.  A request whose rules fail because of either limit is rejected
+// with the EvaluationBudgetExceeded reason.  Rules that go over either limit
+// are logged.  A budget or timeout that isn't positive isn't enforced.
+func WithEvaluationBudget(budget int, timeout time.Duration) EOption {
+	return func(e *enforcer) {
+		e.budget = budget
+		e.evalTimeout = timeout
+	}
+}
+
+// WithCostOrder runs all of a request's rules, including its kind rules, in
+// order of the cost they were given with bascule.WithCost, cheapest first.
+// Once a rule fails, the remaining rules costing more than shortCircuitCost
+// are skipped, so that requests already failing cheap checks, such as a bad
+// issuer, don't spend the latency and dependency load of expensive ones, such
+// as remote introspection or policy engines.  See bascule.ShortCircuit.
+func WithCostOrder(shortCircuitCost int) EOption {
+	return func(e *enforcer) {
+		e.costOrder = true
+		e.shortCircuitCost = shortCircuitCost
+	}
+}
+
+// WithEStageStatuses writes a 401, with the challenges set with
+// WithNotFoundChallenges, for requests denied because of their credential,
+// and a 403 for requests whose valid credential isn't allowed, as decided by
+// StageOf.  Throttling and outages get a 429 and 503.  Statuses for failures
+// in the UnknownStage still come from the StatusMap.  Use it with
+// WithCStageStatuses for the same behavior in the constructor.
+func WithEStageStatuses() EOption {
+	return func(e *enforcer) {
+		e.stageStatuses = true
+	}
+}
+
+// WithERuleMetrics counts the failures of each rule named with bascule.Named,
+// labeled with the rule name and the server given, so operators can see which
+// rule is rejecting traffic.  Denials by rules without names are counted
+// under UnnamedRule.  Rules skipped by WithCostOrder aren't counted.
+func WithERuleMetrics(measures *RuleMeasures, server string) EOption {
+	return func(e *enforcer) {
+		e.ruleMeasures = measures
+		if server != "" {
+			e.server = server
 		}
 	}
 }
@@ -162,6 +464,54 @@ func WithEErrorResponseFunc(f OnErrorResponse) EOption {
 	}
 }
 
+// WithEStatusMap sets the statuses written for failed checks by the class of
+// their error, such as DefaultStatusMap.  Errors whose class isn't mapped get
+// a 403.  By default, every failed check gets a 403.
+func WithEStatusMap(m StatusMap) EOption {
+	return func(e *enforcer) {
+		if m != nil {
+			e.statusMap = m
+		}
+	}
+}
+
+// WithEErrorBodies writes a body with each denied response, in the media type
+// the request's Accept header prefers.  A config whose default media type has
+// no encoder is ignored.
+func WithEErrorBodies(config ErrorBodyConfig) EOption {
+	return func(e *enforcer) {
+		if eb := newErrorBodies(config); eb != nil {
+			e.errorBodies = eb
+		}
+	}
+}
+
+// WithESkip adds a SkipFunc for requests that bypass the enforcer's rules.
+// Requests skipped by the constructor are always skipped.
+func WithESkip(f SkipFunc) EOption {
+	return func(e *enforcer) {
+		e.skips = e.skips.add(f)
+	}
+}
+
+// WithEHook adds a Hook run by the enforcer at the stage given.  Requests
+// deferred to another enforcer are left to its hooks.
+func WithEHook(stage HookStage, hook Hook) EOption {
+	return func(e *enforcer) {
+		e.hooks = e.hooks.add(stage, hook)
+	}
+}
+
+// WithERedactor sets the redactor used to mask sensitive token values in the
+// errors that are logged and passed to the OnErrorResponse function.
+func WithERedactor(r *bascule.Redactor) EOption {
+	return func(e *enforcer) {
+		if r != nil {
+			e.redactor = r
+		}
+	}
+}
+
 // ProvideEnforcer is a helper function for wiring up an enforcer with uber fx.
 // Any options added with uber fx will be used to create the enforcer.
 func ProvideEnforcer() fx.Option {
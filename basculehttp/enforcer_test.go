@@ -19,13 +19,19 @@ package basculehttp
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
 	"github.com/stretchr/testify/assert"
-	"github.com/xmidt-org/bascule"
-	"github.com/xmidt-org/bascule/basculechecks"
+	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/sallust"
 )
 
@@ -99,3 +105,494 @@ func TestEnforcer(t *testing.T) {
 		})
 	}
 }
+
+func TestEnforcerDecision(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	u, err := url.Parse("/things/1")
+	require.NoError(err)
+	attributes := bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{"x:things:all", "x:things:get"},
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []string{"comcast"},
+		},
+	})
+	e := NewEnforcer(WithRules("jwt", bascule.Validators{
+		bascule.Named("type", basculechecks.NonEmptyType()),
+		bascule.Named("capabilities", basculechecks.CapabilitiesValidator{
+			Checker:  basculechecks.ConstEndpointCheck("x:things:get"),
+			ErrorOut: true,
+		}),
+		bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+			return nil
+		}),
+	}))
+
+	var decision *bascule.Decision
+	handler := e(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var ok bool
+		decision, ok = bascule.DecisionFromContext(r.Context())
+		assert.True(ok)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/things/1", nil)
+	req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("test", "alice", attributes),
+		Request:       bascule.Request{URL: u, Method: http.MethodGet},
+	}))
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(http.StatusOK, writer.Code)
+	require.NotNil(decision)
+	assert.Equal([]string{"type", "capabilities"}, decision.Rules())
+	assert.Equal("x:things:get", decision.Capability())
+}
+
+func TestEnforcerRuleMetrics(t *testing.T) {
+	errRule := errors.New("rule failed")
+	pass := bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return nil })
+	fail := bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return errRule })
+	tests := []struct {
+		description  string
+		rules        bascule.Validator
+		server       string
+		expectedCode int
+		expected     map[string]float64
+	}{
+		{
+			description:  "Pass",
+			rules:        bascule.Validators{bascule.Named("a", pass), pass},
+			expectedCode: http.StatusOK,
+			expected:     map[string]float64{},
+		},
+		{
+			description:  "Named failures",
+			rules:        bascule.Validators{bascule.Named("a", fail), bascule.Named("b", pass), bascule.Named("c", fail), fail},
+			server:       "api",
+			expectedCode: http.StatusForbidden,
+			expected:     map[string]float64{"a": 1, "c": 1},
+		},
+		{
+			description:  "Unnamed failure",
+			rules:        bascule.Validators{bascule.Named("a", pass), fail},
+			expectedCode: http.StatusForbidden,
+			expected:     map[string]float64{UnnamedRule: 1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			measures := &RuleMeasures{
+				RuleFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testRuleFailures",
+				}, []string{ServerLabel, RuleLabel}),
+			}
+			handler := NewEnforcer(
+				WithRules("jwt", tc.rules),
+				WithERuleMetrics(measures, tc.server),
+			)(next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token:         bascule.NewToken("jwt", "alice", nil),
+			}))
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, req)
+			assert.Equal(tc.expectedCode, writer.Code)
+
+			server := tc.server
+			if server == "" {
+				server = defaultServer
+			}
+			assert.Equal(len(tc.expected), testutil.CollectAndCount(measures.RuleFailures))
+			for rule, count := range tc.expected {
+				assert.Equal(count, testutil.ToFloat64(measures.RuleFailures.With(prometheus.Labels{
+					ServerLabel: server,
+					RuleLabel:   rule,
+				})), rule)
+			}
+		})
+	}
+
+	// without measures, nothing is recorded.
+	handler := NewEnforcer(WithRules("jwt", fail), WithERuleMetrics(nil, ""))(next)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("jwt", "alice", nil),
+	}))
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, req)
+	assert.Equal(t, http.StatusForbidden, writer.Code)
+}
+
+func TestEnforcerRedactor(t *testing.T) {
+	assert := assert.New(t)
+	var reported error
+	e := NewEnforcer(
+		WithRules("jwt", bascule.ValidatorFunc(func(_ context.Context, t bascule.Token) error {
+			return errors.New("bad principal " + t.Principal())
+		})),
+		WithEErrorResponseFunc(func(_ ErrorResponseReason, err error) {
+			reported = err
+		}),
+		WithERedactor(bascule.NewRedactor(bascule.WithSensitivePrincipal())),
+		WithERedactor(nil),
+	)
+	handler := e(next)
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest("get", "/", nil)
+	req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("jwt", "user@example.com", nil),
+	}))
+	handler.ServeHTTP(writer, req)
+	assert.Equal(http.StatusForbidden, writer.Code)
+	assert.Equal("bad principal "+bascule.DefaultRedactionMask, reported.Error())
+}
+
+func TestEnforcerConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+	l := basculechecks.NewConcurrencyLimiter(basculechecks.ConcurrencyLimitConfig{Limit: 1}, nil, "", nil)
+	auth := bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("jwt", "alice", nil),
+	}
+	e := NewEnforcer(WithRules("jwt", bascule.Validators{l}))
+
+	var nested *httptest.ResponseRecorder
+	handler := l.Track(e(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a second request while the first is in flight is rejected.
+		nested = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(bascule.WithAuthentication(context.Background(), auth))
+		l.Track(e(next)).ServeHTTP(nested, req)
+		w.WriteHeader(http.StatusOK)
+	})))
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(bascule.WithAuthentication(context.Background(), auth))
+	handler.ServeHTTP(writer, req)
+	assert.Equal(http.StatusOK, writer.Code)
+	assert.Equal(http.StatusTooManyRequests, nested.Code)
+	assert.Zero(l.InFlight("alice"))
+}
+
+func TestEnforcerDefaultRules(t *testing.T) {
+	e := NewEnforcer(
+		WithDefaultRules(basculechecks.NonEmptyPrincipal()),
+		WithDefaultRules(nil),
+		WithRules("jwt", basculechecks.NonEmptyType()),
+		WithOverrideRules("basic", basculechecks.AllowAll()),
+		WithOverrideRules("digest", basculechecks.AllowAll()),
+		WithRules("digest", basculechecks.NonEmptyType()),
+	)
+	tests := []struct {
+		description        string
+		authorization      bascule.Authorization
+		tokenType          string
+		principal          string
+		expectedStatusCode int
+	}{
+		{
+			description:        "Merged Success",
+			authorization:      "jwt",
+			tokenType:          "jwt",
+			principal:          "user",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Merged Default Rule Error",
+			authorization:      "jwt",
+			tokenType:          "jwt",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			description:        "Merged Type Rule Error",
+			authorization:      "jwt",
+			principal:          "user",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			description:        "Override Success",
+			authorization:      "basic",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Rules After Override Merged Error",
+			authorization:      "digest",
+			tokenType:          "digest",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			description:        "Default Only Success",
+			authorization:      "other",
+			principal:          "user",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Default Only Error",
+			authorization:      "other",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			writer := httptest.NewRecorder()
+			req := httptest.NewRequest("get", "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: tc.authorization,
+				Token:         bascule.NewToken(tc.tokenType, tc.principal, bascule.NewAttributes(map[string]interface{}{})),
+			}))
+			e(next).ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatusCode, writer.Code)
+		})
+	}
+}
+
+func TestEnforcerNotFoundBehaviors(t *testing.T) {
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	secondary := NewEnforcer(WithRules("new", basculechecks.NonEmptyPrincipal()))
+	tests := []struct {
+		description        string
+		options            []EOption
+		principal          string
+		expectedStatusCode int
+		expectedChallenges []string
+		expectedReported   bool
+	}{
+		{
+			description:        "Default Challenge",
+			options:            []EOption{WithNotFoundBehavior(Challenge)},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenges: []string{"Bearer"},
+			expectedReported:   true,
+		},
+		{
+			description: "Custom Challenges",
+			options: []EOption{
+				WithNotFoundBehavior(Challenge),
+				WithNotFoundChallenges(`Digest realm="r"`, "Negotiate"),
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenges: []string{`Digest realm="r"`, "Negotiate"},
+			expectedReported:   true,
+		},
+		{
+			description:        "Handle",
+			options:            []EOption{WithNotFoundBehavior(Handle), WithNotFoundHandler(fallback)},
+			expectedStatusCode: http.StatusTeapot,
+			expectedReported:   true,
+		},
+		{
+			description:        "Handle Without Handler",
+			options:            []EOption{WithNotFoundBehavior(Handle), WithNotFoundHandler(nil)},
+			expectedStatusCode: http.StatusForbidden,
+			expectedReported:   true,
+		},
+		{
+			description:        "Defer Success",
+			options:            []EOption{WithNotFoundBehavior(Defer), WithNotFoundEnforcer(secondary)},
+			principal:          "user",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Defer Error",
+			options:            []EOption{WithNotFoundBehavior(Defer), WithNotFoundEnforcer(secondary)},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			description:        "Defer Without Enforcer",
+			options:            []EOption{WithNotFoundBehavior(Defer), WithNotFoundEnforcer(nil)},
+			principal:          "user",
+			expectedStatusCode: http.StatusForbidden,
+			expectedReported:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			reported := false
+			options := append([]EOption{
+				WithRules("old", basculechecks.AllowAll()),
+				WithEErrorResponseFunc(func(reason ErrorResponseReason, _ error) {
+					reported = reason == ChecksNotFound
+				}),
+			}, tc.options...)
+			writer := httptest.NewRecorder()
+			req := httptest.NewRequest("get", "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "new",
+				Token:         bascule.NewToken("new", tc.principal, bascule.NewAttributes(map[string]interface{}{})),
+			}))
+			NewEnforcer(options...)(next).ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatusCode, writer.Code)
+			assert.Equal(tc.expectedChallenges, writer.Header().Values(AuthTypeHeaderKey))
+			assert.Equal(tc.expectedReported, reported)
+		})
+	}
+}
+
+func TestEnforcerKindRules(t *testing.T) {
+	e := NewEnforcer(
+		WithRules("jwt", basculechecks.AllowAll()),
+		WithKindRules(bascule.DeviceKind, basculechecks.NonEmptyPrincipal()),
+		WithKindRules(bascule.UserKind, nil),
+	)
+	tests := []struct {
+		description        string
+		kind               bascule.TokenKind
+		principal          string
+		expectedStatusCode int
+	}{
+		{
+			description:        "Success",
+			kind:               bascule.DeviceKind,
+			principal:          "mac:112233445566",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "No Kind Rules Success",
+			kind:               bascule.UserKind,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Kind Rule Error",
+			kind:               bascule.DeviceKind,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			handler := e(next)
+			writer := httptest.NewRecorder()
+			req := httptest.NewRequest("get", "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token: bascule.NewToken("jwt", tc.principal, bascule.NewAttributes(map[string]interface{}{
+					bascule.TokenKindKey: tc.kind,
+				})),
+			}))
+			handler.ServeHTTP(writer, req)
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+		})
+	}
+}
+
+func TestEnforcerEvaluationBudget(t *testing.T) {
+	cheap := bascule.WithCost(basculechecks.AllowAll(), 1)
+	expensive := bascule.WithCost(basculechecks.AllowAll(), 10)
+	slow := bascule.ValidatorFunc(func(ctx context.Context, _ bascule.Token) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tests := []struct {
+		description        string
+		rules              bascule.Validator
+		timeout            time.Duration
+		expectedStatusCode int
+		expectedReason     ErrorResponseReason
+	}{
+		{
+			description:        "Success",
+			rules:              bascule.Validators{cheap, cheap},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "Budget Exceeded",
+			rules:              bascule.Validators{cheap, expensive},
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     EvaluationBudgetExceeded,
+		},
+		{
+			description:        "Timeout",
+			rules:              slow,
+			timeout:            time.Millisecond,
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     EvaluationBudgetExceeded,
+		},
+		{
+			description:        "Checks Failed",
+			rules:              basculechecks.NonEmptyPrincipal(),
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     ChecksFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var reason ErrorResponseReason
+			e := NewEnforcer(
+				WithRules("jwt", tc.rules),
+				WithEvaluationBudget(5, tc.timeout),
+				WithEErrorResponseFunc(func(r ErrorResponseReason, _ error) {
+					reason = r
+				}),
+			)
+			writer := httptest.NewRecorder()
+			req := httptest.NewRequest("get", "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token:         bascule.NewToken("jwt", "", nil),
+			}))
+			e(next).ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatusCode, writer.Code)
+			assert.Equal(tc.expectedReason, reason)
+		})
+	}
+}
+
+func TestEnforcerCostOrder(t *testing.T) {
+	var ran []string
+	rule := func(name string, cost int, fail bool) bascule.Validator {
+		return bascule.WithCost(bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+			ran = append(ran, name)
+			if fail {
+				return errors.New(name + " failed")
+			}
+			return nil
+		}), cost)
+	}
+	tests := []struct {
+		description        string
+		badIssuer          bool
+		expectedStatusCode int
+		expectedRan        []string
+	}{
+		{
+			description:        "Success",
+			expectedStatusCode: http.StatusOK,
+			expectedRan:        []string{"issuer", "device", "introspect"},
+		},
+		{
+			description:        "Cheap Failure Skips Introspection",
+			badIssuer:          true,
+			expectedStatusCode: http.StatusForbidden,
+			expectedRan:        []string{"issuer", "device"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			ran = nil
+			e := NewEnforcer(
+				WithRules("jwt", bascule.Validators{rule("introspect", 100, false), rule("issuer", 1, tc.badIssuer)}),
+				WithKindRules(bascule.DeviceKind, rule("device", 5, false)),
+				WithCostOrder(10),
+			)
+			writer := httptest.NewRecorder()
+			req := httptest.NewRequest("get", "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token: bascule.NewToken("jwt", "mac:112233445566", bascule.NewAttributes(map[string]interface{}{
+					bascule.TokenKindKey: bascule.DeviceKind,
+				})),
+			}))
+			e(next).ServeHTTP(writer, req)
+			assert.Equal(t, tc.expectedStatusCode, writer.Code)
+			assert.Equal(t, tc.expectedRan, ran)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// JSONMediaType is the media type of JSON error bodies, and the default
+	// media type when the request doesn't say what it accepts.
+	JSONMediaType = "application/json"
+
+	// XMLMediaType is the media type of XML error bodies.
+	XMLMediaType = "application/xml"
+
+	// TextXMLMediaType is the legacy media type of XML error bodies.
+	TextXMLMediaType = "text/xml"
+
+	// TextMediaType is the media type of plain text error bodies.
+	TextMediaType = "text/plain"
+
+	acceptHeader      = "Accept"
+	contentTypeHeader = "Content-Type"
+)
+
+// ErrorBody is the body written when a request fails authentication or
+// authorization.  Message is the status text, or the localized message from
+// the MessageCatalog, rather than the error itself, so that nothing about the
+// credentials is leaked.
+type ErrorBody struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	Status  int      `json:"status" xml:"status"`
+	Reason  string   `json:"reason" xml:"reason"`
+	Message string   `json:"message" xml:"message"`
+
+	// RequestID is the request's correlation ID, if the constructor
+	// extracted one, so clients can quote it when reporting problems.
+	RequestID string `json:"requestId,omitempty" xml:"requestId,omitempty"`
+}
+
+// ErrorEncoder writes an ErrorBody in a particular media type.
+type ErrorEncoder func(io.Writer, ErrorBody) error
+
+// JSONErrorEncoder writes the body as a JSON object.
+func JSONErrorEncoder(w io.Writer, body ErrorBody) error {
+	return json.NewEncoder(w).Encode(body)
+}
+
+// XMLErrorEncoder writes the body as an <error> element.
+func XMLErrorEncoder(w io.Writer, body ErrorBody) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(body)
+}
+
+// TextErrorEncoder writes the body as a single line of text.
+func TextErrorEncoder(w io.Writer, body ErrorBody) error {
+	_, err := fmt.Fprintf(w, "%d %s: %s\n", body.Status, body.Message, body.Reason)
+	return err
+}
+
+// DefaultErrorEncoders returns the encoders used when none are configured:
+// JSON, XML and plain text.
+func DefaultErrorEncoders() map[string]ErrorEncoder {
+	return map[string]ErrorEncoder{
+		JSONMediaType:    JSONErrorEncoder,
+		XMLMediaType:     XMLErrorEncoder,
+		TextXMLMediaType: XMLErrorEncoder,
+		TextMediaType:    TextErrorEncoder,
+	}
+}
+
+// ErrorBodyConfig configures the bodies written for failed requests.  The
+// media type is chosen using the request's Accept header.
+type ErrorBodyConfig struct {
+	// Encoders maps media types to their encoders.  If empty,
+	// DefaultErrorEncoders is used.
+	Encoders map[string]ErrorEncoder
+
+	// Default is the media type used when the request doesn't have an Accept
+	// header or accepts none of the encoders' media types.  It defaults to
+	// JSONMediaType, and must be one of the encoders' media types.
+	Default string
+
+	// Catalog translates messages into the languages of the request's
+	// Accept-Language header.  If nil, or it has no message for any of the
+	// languages, the status text is used.
+	Catalog MessageCatalog
+}
+
+// errorBodies picks the encoder for each failed request.
+type errorBodies struct {
+	encoders    map[string]ErrorEncoder
+	mediaTypes  []string
+	defaultType string
+	catalog     MessageCatalog
+}
+
+// newErrorBodies returns nil if the config's default media type has no
+// encoder.
+func newErrorBodies(config ErrorBodyConfig) *errorBodies {
+	encoders := config.Encoders
+	if len(encoders) == 0 {
+		encoders = DefaultErrorEncoders()
+	}
+	eb := &errorBodies{
+		encoders:    make(map[string]ErrorEncoder, len(encoders)),
+		defaultType: strings.ToLower(config.Default),
+		catalog:     config.Catalog,
+	}
+	if eb.defaultType == "" {
+		eb.defaultType = JSONMediaType
+	}
+	for mediaType, encoder := range encoders {
+		if encoder == nil {
+			continue
+		}
+		mediaType = strings.ToLower(mediaType)
+		eb.encoders[mediaType] = encoder
+		eb.mediaTypes = append(eb.mediaTypes, mediaType)
+	}
+	if eb.encoders[eb.defaultType] == nil {
+		return nil
+	}
+	sort.Strings(eb.mediaTypes)
+	return eb
+}
+
+// acceptRange is a media range from an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept returns the media ranges of the header, most preferred first.
+// Ranges that can't be parsed or aren't acceptable are dropped.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// negotiate returns the media type and encoder to use for the Accept header
+// given.
+func (eb *errorBodies) negotiate(accept string) (string, ErrorEncoder) {
+	for _, r := range parseAccept(accept) {
+		if mediaType, ok := eb.match(r.mediaType); ok {
+			return mediaType, eb.encoders[mediaType]
+		}
+	}
+	return eb.defaultType, eb.encoders[eb.defaultType]
+}
+
+// match finds the media type for a media range, preferring the default for
+// wildcards.
+func (eb *errorBodies) match(mediaRange string) (string, bool) {
+	if _, ok := eb.encoders[mediaRange]; ok {
+		return mediaRange, true
+	}
+	if mediaRange == "*/*" {
+		return eb.defaultType, true
+	}
+	prefix := strings.TrimSuffix(mediaRange, "*")
+	if len(prefix) == len(mediaRange) {
+		return "", false
+	}
+	if strings.HasPrefix(eb.defaultType, prefix) {
+		return eb.defaultType, true
+	}
+	for _, mediaType := range eb.mediaTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return mediaType, true
+		}
+	}
+	return "", false
+}
+
+// writer wraps the response so that a body is written along with an error
+// status.  A nil errorBodies doesn't wrap the response.
+func (eb *errorBodies) writer(response http.ResponseWriter, request *http.Request, reason ErrorResponseReason) http.ResponseWriter {
+	if eb == nil {
+		return response
+	}
+	mediaType, encode := eb.negotiate(request.Header.Get(acceptHeader))
+	w := &errorBodyWriter{
+		ResponseWriter: response,
+		mediaType:      mediaType,
+		encode:         encode,
+		reason:         reason,
+		catalog:        eb.catalog,
+	}
+	w.requestID, _ = bascule.RequestIDFromContext(request.Context())
+	if eb.catalog != nil {
+		w.languages = parseAcceptLanguage(request.Header.Get(acceptLanguageHeader))
+	}
+	return w
+}
+
+// errorBodyWriter writes the error body when the status is written.  If the
+// Content-Type is already set, whoever set it is trusted to write the body.
+type errorBodyWriter struct {
+	http.ResponseWriter
+	mediaType   string
+	encode      ErrorEncoder
+	reason      ErrorResponseReason
+	catalog     MessageCatalog
+	languages   []string
+	requestID   string
+	wroteHeader bool
+}
+
+func (w *errorBodyWriter) WriteHeader(status int) {
+	if w.wroteHeader || status < http.StatusBadRequest || w.Header().Get(contentTypeHeader) != "" {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	body := ErrorBody{
+		Status:    status,
+		Reason:    w.reason.String(),
+		Message:   http.StatusText(status),
+		RequestID: w.requestID,
+	}
+	if msg, language, ok := localize(w.catalog, w.languages, w.reason, status); ok {
+		body.Message = msg
+		w.Header().Set(contentLanguageHeader, language)
+	}
+	w.Header().Set(contentTypeHeader, w.mediaType+"; charset=utf-8")
+	w.ResponseWriter.WriteHeader(status)
+	_ = w.encode(w.ResponseWriter, body)
+}
+
+func (w *errorBodyWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,226 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewErrorBodies(t *testing.T) {
+	assert := assert.New(t)
+	eb := newErrorBodies(ErrorBodyConfig{})
+	assert.NotNil(eb)
+	assert.Equal(JSONMediaType, eb.defaultType)
+	assert.Equal([]string{JSONMediaType, XMLMediaType, TextMediaType, TextXMLMediaType}, eb.mediaTypes)
+
+	eb = newErrorBodies(ErrorBodyConfig{
+		Encoders: map[string]ErrorEncoder{"Application/Problem+JSON": JSONErrorEncoder, "text/html": nil},
+		Default:  "application/problem+json",
+	})
+	assert.NotNil(eb)
+	assert.Equal([]string{"application/problem+json"}, eb.mediaTypes)
+
+	assert.Nil(newErrorBodies(ErrorBodyConfig{Default: "text/html"}))
+}
+
+func TestErrorBodiesNegotiate(t *testing.T) {
+	tests := []struct {
+		description       string
+		config            ErrorBodyConfig
+		accept            string
+		expectedMediaType string
+	}{
+		{
+			description:       "No Accept",
+			expectedMediaType: JSONMediaType,
+		},
+		{
+			description:       "Exact",
+			accept:            "application/xml",
+			expectedMediaType: XMLMediaType,
+		},
+		{
+			description:       "Preference",
+			accept:            "text/html, application/json;q=0.5, text/plain;q=0.9",
+			expectedMediaType: TextMediaType,
+		},
+		{
+			description:       "Not Acceptable",
+			accept:            "text/plain;q=0, application/json;q=0",
+			expectedMediaType: JSONMediaType,
+		},
+		{
+			description:       "Any",
+			accept:            "*/*",
+			config:            ErrorBodyConfig{Default: XMLMediaType},
+			expectedMediaType: XMLMediaType,
+		},
+		{
+			description:       "Wildcard Subtype",
+			accept:            "text/*",
+			expectedMediaType: TextMediaType,
+		},
+		{
+			description:       "Wildcard Subtype Prefers Default",
+			accept:            "text/*",
+			config:            ErrorBodyConfig{Default: TextXMLMediaType},
+			expectedMediaType: TextXMLMediaType,
+		},
+		{
+			description:       "Unparseable",
+			accept:            "text/plain;q=high, ;;, application/xml",
+			expectedMediaType: XMLMediaType,
+		},
+		{
+			description:       "Unsupported",
+			accept:            "image/png",
+			expectedMediaType: JSONMediaType,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			eb := newErrorBodies(tc.config)
+			require.NotNil(t, eb)
+			mediaType, encoder := eb.negotiate(tc.accept)
+			assert.Equal(tc.expectedMediaType, mediaType)
+			assert.NotNil(encoder)
+		})
+	}
+}
+
+func TestErrorBodiesWriter(t *testing.T) {
+	tests := []struct {
+		description         string
+		accept              string
+		write               func(http.ResponseWriter)
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			description: "JSON",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus:      http.StatusUnauthorized,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"status":401,"reason":"missing_header","message":"Unauthorized"}` + "\n",
+		},
+		{
+			description: "XML",
+			accept:      "application/xml",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			expectedStatus:      http.StatusForbidden,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<error><status>403</status><reason>missing_header</reason><message>Forbidden</message></error>`,
+		},
+		{
+			description: "Text",
+			accept:      "text/plain",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			expectedStatus:      http.StatusTooManyRequests,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "429 Too Many Requests: missing_header\n",
+		},
+		{
+			description: "Own Body",
+			write: func(w http.ResponseWriter) {
+				w.Header().Set(contentTypeHeader, "text/html")
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = io.WriteString(w, "<p>no</p>")
+			},
+			expectedStatus:      http.StatusUnauthorized,
+			expectedContentType: "text/html",
+			expectedBody:        "<p>no</p>",
+		},
+		{
+			description: "Not An Error",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			description: "Implicit Status",
+			write: func(w http.ResponseWriter) {
+				_, _ = io.WriteString(w, "ok")
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "ok",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set(acceptHeader, tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			tc.write(newErrorBodies(ErrorBodyConfig{}).writer(recorder, req, MissingHeader))
+			assert.Equal(tc.expectedStatus, recorder.Code)
+			assert.Equal(tc.expectedContentType, recorder.Header().Get(contentTypeHeader))
+			assert.Equal(tc.expectedBody, recorder.Body.String())
+		})
+	}
+
+	var eb *errorBodies
+	recorder := httptest.NewRecorder()
+	assert.Equal(t, recorder, eb.writer(recorder, httptest.NewRequest(http.MethodGet, "/", nil), MissingHeader))
+}
+
+func TestErrorBodiesMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	config := ErrorBodyConfig{}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(acceptHeader, "text/plain")
+	recorder := httptest.NewRecorder()
+	NewConstructor(WithCErrorBodies(config), WithCErrorBodies(ErrorBodyConfig{Default: "text/html"}))(next).ServeHTTP(recorder, req)
+	assert.Equal(http.StatusUnauthorized, recorder.Code)
+	assert.Equal("401 Unauthorized: missing_header\n", recorder.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("jwt", "alice", nil),
+	}))
+	recorder = httptest.NewRecorder()
+	NewEnforcer(WithEErrorBodies(config), WithRules("jwt", bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+		return errors.New("no")
+	})))(next).ServeHTTP(recorder, req)
+	assert.Equal(http.StatusForbidden, recorder.Code)
+	assert.Equal("application/json; charset=utf-8", recorder.Header().Get(contentTypeHeader))
+	assert.Equal(`{"status":403,"reason":"checks_failed","message":"Forbidden"}`+"\n", recorder.Body.String())
+}
@@ -49,14 +49,17 @@ type OnErrorHTTPResponseIn struct {
 
 // DefaultOnErrorHTTPResponse will write a 401 status code along the
 // 'WWW-Authenticate: Bearer' header for all error cases related to building
-// the security token. For error checks that happen once a valid token has been
-// created will result in a 403.
+// the security token.  The header is added after any challenges already set.
+// For error checks that happen once a valid token has been created will result
+// in a 403.
 func DefaultOnErrorHTTPResponse(w http.ResponseWriter, reason ErrorResponseReason) {
 	switch reason {
-	case ChecksNotFound, ChecksFailed:
+	case ChecksNotFound, ChecksFailed, ImpersonationDenied, EvaluationBudgetExceeded, IPBlocked:
 		w.WriteHeader(http.StatusForbidden)
+	case LockedOut:
+		w.WriteHeader(http.StatusTooManyRequests)
 	default:
-		w.Header().Set(AuthTypeHeaderKey, string(BearerAuthorization))
+		w.Header().Add(AuthTypeHeaderKey, string(BearerAuthorization))
 		w.WriteHeader(http.StatusUnauthorized)
 	}
 }
@@ -31,6 +31,14 @@ const (
 	MissingAuthentication
 	ChecksNotFound
 	ChecksFailed
+	ImpersonationDenied
+	EvaluationBudgetExceeded
+	LockedOut
+	InvalidTokenType
+	InvalidContentType
+	UnsupportedCritical
+	MissingKeyID
+	IPBlocked
 )
 
 const (
@@ -38,14 +46,22 @@ const (
 )
 
 var responseReasonMarshal = map[ErrorResponseReason]string{
-	MissingHeader:         "missing_header",
-	InvalidHeader:         "invalid_header",
-	KeyNotSupported:       "key_not_supported",
-	ParseFailed:           "parse_failed",
-	GetURLFailed:          "get_url_failed",
-	MissingAuthentication: "missing_authentication",
-	ChecksNotFound:        "checks_not_found",
-	ChecksFailed:          "checks_failed",
+	MissingHeader:            "missing_header",
+	InvalidHeader:            "invalid_header",
+	KeyNotSupported:          "key_not_supported",
+	ParseFailed:              "parse_failed",
+	GetURLFailed:             "get_url_failed",
+	MissingAuthentication:    "missing_authentication",
+	ChecksNotFound:           "checks_not_found",
+	ChecksFailed:             "checks_failed",
+	ImpersonationDenied:      "impersonation_denied",
+	EvaluationBudgetExceeded: "evaluation_budget_exceeded",
+	LockedOut:                "locked_out",
+	InvalidTokenType:         "invalid_token_type",
+	InvalidContentType:       "invalid_content_type",
+	UnsupportedCritical:      "unsupported_critical_header",
+	MissingKeyID:             "missing_key_id",
+	IPBlocked:                "ip_blocked",
 }
 
 // String provides a metric label safe string of the response reason.
@@ -56,3 +72,13 @@ func (e ErrorResponseReason) String() string {
 	}
 	return reason
 }
+
+// parseErrorResponseReason returns the reason whose String is the name given.
+func parseErrorResponseReason(name string) (ErrorResponseReason, bool) {
+	for reason, s := range responseReasonMarshal {
+		if s == name {
+			return reason, true
+		}
+	}
+	return Unknown, false
+}
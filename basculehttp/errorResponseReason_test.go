@@ -61,6 +61,38 @@ func TestErrorResponseReasonStr(t *testing.T) {
 			reason:         ChecksFailed,
 			expectedString: "checks_failed",
 		},
+		{
+			reason:         ImpersonationDenied,
+			expectedString: "impersonation_denied",
+		},
+		{
+			reason:         EvaluationBudgetExceeded,
+			expectedString: "evaluation_budget_exceeded",
+		},
+		{
+			reason:         LockedOut,
+			expectedString: "locked_out",
+		},
+		{
+			reason:         InvalidTokenType,
+			expectedString: "invalid_token_type",
+		},
+		{
+			reason:         InvalidContentType,
+			expectedString: "invalid_content_type",
+		},
+		{
+			reason:         UnsupportedCritical,
+			expectedString: "unsupported_critical_header",
+		},
+		{
+			reason:         MissingKeyID,
+			expectedString: "missing_key_id",
+		},
+		{
+			reason:         IPBlocked,
+			expectedString: "ip_blocked",
+		},
 		{
 			reason:         -1,
 			expectedString: UnknownReason,
@@ -79,6 +79,12 @@ func TestDefaultOnErrorHTTPResponse(t *testing.T) {
 			ExpectedCode:         403,
 			ExpectAuthTypeHeader: false,
 		},
+		{
+			Description:          "IPBlocked",
+			Reason:               IPBlocked,
+			ExpectedCode:         403,
+			ExpectAuthTypeHeader: false,
+		},
 	}
 
 	for _, tc := range tcs {
@@ -0,0 +1,149 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func FuzzAuthorizationHeader(f *testing.F) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	jws, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		jwtPrincipalKey: "test",
+		"exp":           float64(time.Now().Add(time.Hour).Unix()),
+	}).SignedString(key)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	k := new(mockKey)
+	k.On("Public").Return(&key.PublicKey).Maybe()
+	r := new(MockResolver)
+	r.On("Resolve", mock.Anything, mock.Anything).Return(k, nil).Maybe()
+
+	c := newConstructor(
+		WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"}),
+		WithTokenFactory(BearerAuthorization, BearerTokenFactory{
+			DefaultKeyID: "default",
+			Resolver:     r,
+			Parser:       bascule.DefaultJWTParser,
+		}),
+	)
+
+	for _, seed := range []string{
+		"",
+		" ",
+		"Basic",
+		"Basic ",
+		"Basic dXNlcjpwYXNz",
+		"Basic Og==",
+		"Basic dXNlcg==",
+		"Bearer ",
+		"Bearer " + jws,
+		"Bearer " + jws[:len(jws)/2],
+		"Bearer a.b.c",
+		"Digest username=\"user\"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, header string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DefaultHeaderName, header)
+		auth, reason, err := c.authenticationOutput(zap.NewNop(), req)
+		if err != nil {
+			if reason < 0 {
+				t.Errorf("error [%v] without a response reason", err)
+			}
+			return
+		}
+		if auth.Token == nil {
+			t.Errorf("header [%q] authenticated without a token", header)
+		}
+		prefix := string(auth.Authorization) + DefaultHeaderDelimiter
+		if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+			t.Errorf("header [%q] authenticated with scheme [%v]", header, auth.Authorization)
+		}
+	})
+}
+
+func FuzzParseDigestParams(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		`username="user", realm="test", nonce="abc", uri="/", response="def"`,
+		`username="us\"er"`,
+		`a=b,c=d`,
+		`a="unterminated`,
+		`a="trailing\`,
+		`=b`,
+		`a=b c=d`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		params, err := parseDigestParams(value)
+		if err != nil {
+			return
+		}
+		for k := range params {
+			if k != strings.ToLower(k) {
+				t.Errorf("key [%v] wasn't lower cased", k)
+			}
+		}
+	})
+}
+
+func FuzzParseCredentials(f *testing.F) {
+	for _, seed := range []string{
+		"Basic dXNlcjpwYXNz",
+		"Bearer abc.def.ghi==",
+		"Digest username=\"user\", realm=\"a\\\"b\"",
+		"Basic",
+		" Basic abc",
+		"Basic a b",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, header string) {
+		c, err := ParseCredentials(header)
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(header, c.Scheme) {
+			t.Errorf("header [%q] parsed with scheme [%q]", header, c.Scheme)
+		}
+		if !strings.Contains(header, c.Value) {
+			t.Errorf("header [%q] parsed with value [%q]", header, c.Value)
+		}
+		c.Params()
+	})
+}
@@ -0,0 +1,71 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// Gate runs middleware, like the constructor, the enforcer, the listener
+// decorator, or the chain built by NewAuthChain, outside of a net/http
+// handler chain.  It's for adapting the middleware to frameworks whose
+// handlers aren't http.Handlers, such as gin, echo, and fiber.
+type Gate struct {
+	handler http.Handler
+}
+
+type gateKey struct{}
+
+// NewGate creates a Gate that runs the middleware given.
+func NewGate(middleware func(http.Handler) http.Handler) *Gate {
+	return &Gate{
+		handler: middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if next, ok := r.Context().Value(gateKey{}).(http.HandlerFunc); ok {
+				next(w, r)
+			}
+		})),
+	}
+}
+
+// NewAuthGate creates a Gate that runs the chain built by NewAuthChain.
+func NewAuthGate(options ...AuthOption) (*Gate, error) {
+	chain, err := NewAuthChain(options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewGate(chain.Then), nil
+}
+
+// Serve runs the middleware on the request.  If the middleware passes the
+// request on, next is called with the request as it was passed on, whose
+// context has the Authentication, and Serve returns true.  Otherwise the
+// middleware has already written the response, and Serve returns false.
+//
+// next runs inside the middleware, so the framework's handlers must be run
+// from it: the request's context is only live, sessions are only open, and
+// concurrency limits are only held until next returns.
+func (g *Gate) Serve(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) bool {
+	called := false
+	wrapped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		next(w, r)
+	})
+	g.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), gateKey{}, wrapped)))
+	return called
+}
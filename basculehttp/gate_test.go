@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+)
+
+type gateTestKey struct{}
+
+func TestGate(t *testing.T) {
+	auth := bascule.Authentication{Token: bascule.NewToken("test", "alice", nil)}
+	tests := []struct {
+		description    string
+		middleware     func(http.Handler) http.Handler
+		expectedPass   bool
+		expectedStatus int
+	}{
+		{
+			description: "Pass",
+			middleware: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(bascule.WithAuthentication(r.Context(), auth)))
+				})
+			},
+			expectedPass:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description: "Reject",
+			middleware: func(http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				})
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			g := NewGate(tc.middleware)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(context.WithValue(r.Context(), gateTestKey{}, "kept"))
+
+			var passed *http.Request
+			ok := g.Serve(w, r, func(w http.ResponseWriter, r *http.Request) {
+				passed = r
+				w.WriteHeader(http.StatusOK)
+			})
+			assert.Equal(tc.expectedPass, ok)
+			assert.Equal(tc.expectedStatus, w.Code)
+			if !tc.expectedPass {
+				assert.Nil(passed)
+				return
+			}
+			actual, ok := bascule.FromContext(passed.Context())
+			assert.True(ok)
+			assert.Equal(auth, actual)
+			assert.Equal("kept", passed.Context().Value(gateTestKey{}))
+		})
+	}
+}
+
+func TestGateServeInsideMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	done := false
+	g := NewGate(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+			done = true
+		})
+	})
+
+	ok := g.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), func(_ http.ResponseWriter, r *http.Request) {
+		// the middleware hasn't finished, so its context is still live.
+		assert.False(done)
+		assert.NoError(r.Context().Err())
+	})
+	assert.True(ok)
+	assert.True(done)
+}
+
+func TestNewAuthGate(t *testing.T) {
+	assert := assert.New(t)
+	g, err := NewAuthGate()
+	assert.ErrorIs(err, ErrNoTokenFactories)
+	assert.Nil(g)
+
+	g, err = NewAuthGate(
+		WithConstructorOptions(WithTokenFactory(BasicAuthorization, BasicTokenFactory{"alice": "secret"})),
+		WithEnforcerOptions(WithRules(BasicAuthorization, basculechecks.AllowAll())),
+	)
+	assert.NoError(err)
+
+	var principal string
+	next := func(_ http.ResponseWriter, r *http.Request) {
+		if auth, ok := bascule.FromContext(r.Context()); ok {
+			principal = auth.Token.Principal()
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(g.Serve(w, r, next))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.SetBasicAuth("alice", "secret")
+	assert.True(g.Serve(w, r, next))
+	assert.Equal("alice", principal)
+}
@@ -0,0 +1,262 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultReloadInterval = time.Minute
+
+var ErrNoCredentialsFile = errors.New("no credentials file to reload")
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// HashedBasicTokenFactory verifies basic auths against a map of usernames to
+// bcrypt or argon2id password hashes, so plaintext passwords never need to be
+// stored.  The map can be loaded from a file and reloaded while running.
+type HashedBasicTokenFactory struct {
+	path     string
+	interval time.Duration
+
+	lock    sync.RWMutex
+	hashes  map[string]string
+	modTime time.Time
+
+	runLock sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewHashedBasicTokenFactory creates a HashedBasicTokenFactory from a map of
+// usernames to password hashes.  Every hash must be in a supported format.
+func NewHashedBasicTokenFactory(hashes map[string]string) (*HashedBasicTokenFactory, error) {
+	if err := validateHashes(hashes); err != nil {
+		return nil, err
+	}
+	return &HashedBasicTokenFactory{
+		hashes:   hashes,
+		interval: defaultReloadInterval,
+	}, nil
+}
+
+// NewHashedBasicTokenFactoryFromFile creates a HashedBasicTokenFactory from a
+// file with a username:hash pair on each line, like an htpasswd file.  Blank
+// lines and lines starting with # are ignored.  If the interval given is
+// positive, Start polls the file that often and reloads it when it changes.
+func NewHashedBasicTokenFactoryFromFile(path string, interval time.Duration) (*HashedBasicTokenFactory, error) {
+	h := &HashedBasicTokenFactory{
+		path:     path,
+		interval: interval,
+	}
+	if h.interval <= 0 {
+		h.interval = defaultReloadInterval
+	}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ParseAndValidate expects the given value to be a base64 encoded string with
+// the username followed by a colon and then the password.  The password is
+// verified against the stored hash in constant time.
+func (h *HashedBasicTokenFactory) ParseAndValidate(_ context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode string: %v", err)
+	}
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return nil, ErrorMalformedValue
+	}
+	principal := string(decoded[:i])
+	password := string(decoded[i+1:])
+
+	h.lock.RLock()
+	hash, ok := h.hashes[principal]
+	h.lock.RUnlock()
+	if !ok {
+		// spend the same time as a real check so usernames can't be found
+		// by timing the response.
+		_ = VerifyPasswordHash(getDummyHash(), password)
+		return nil, ErrorPrincipalNotFound
+	}
+	if err := VerifyPasswordHash(hash, password); err != nil {
+		return nil, err
+	}
+	return bascule.NewToken("basic", principal, bascule.NewAttributes(map[string]interface{}{})), nil
+}
+
+// Reload reads the credentials file again, replacing the current credentials
+// only if the whole file is valid.
+func (h *HashedBasicTokenFactory) Reload() error {
+	if h.path == "" {
+		return ErrNoCredentialsFile
+	}
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat credentials file: %v", err)
+	}
+	hashes, err := loadHashes(h.path)
+	if err != nil {
+		return err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.hashes = hashes
+	h.modTime = info.ModTime()
+	return nil
+}
+
+func (h *HashedBasicTokenFactory) reloadIfChanged() {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return
+	}
+	h.lock.RLock()
+	changed := !info.ModTime().Equal(h.modTime)
+	h.lock.RUnlock()
+	if changed {
+		// a bad file keeps the current credentials in place.
+		_ = h.Reload()
+	}
+}
+
+// Start begins polling the credentials file for changes.  It does nothing if
+// the factory wasn't loaded from a file or is already started.
+func (h *HashedBasicTokenFactory) Start() {
+	h.runLock.Lock()
+	defer h.runLock.Unlock()
+	if h.path == "" || h.stop != nil {
+		return
+	}
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+	go h.run(h.stop, h.done)
+}
+
+func (h *HashedBasicTokenFactory) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.reloadIfChanged()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Stop ends polling of the credentials file.
+func (h *HashedBasicTokenFactory) Stop() {
+	h.runLock.Lock()
+	stop, done := h.stop, h.done
+	h.stop, h.done = nil, nil
+	h.runLock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops polling of the
+// credentials file.
+func (h *HashedBasicTokenFactory) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			h.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			h.Stop()
+			return nil
+		},
+	}
+}
+
+func loadHashes(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials file: %v", err)
+	}
+	defer f.Close()
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.IndexByte(text, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("credentials file line %d is malformed", line)
+		}
+		hashes[text[:i]] = text[i+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %v", err)
+	}
+	if err := validateHashes(hashes); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func validateHashes(hashes map[string]string) error {
+	errs := bascule.Errors{}
+	for principal, hash := range hashes {
+		if err := ValidatePasswordHash(hash); err != nil {
+			errs = append(errs, fmt.Errorf("invalid hash for [%v]: %w", principal, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func getDummyHash() string {
+	dummyHashOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		if err == nil {
+			dummyHash = string(hash)
+		}
+	})
+	return dummyHash
+}
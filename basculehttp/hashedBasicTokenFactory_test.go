@@ -0,0 +1,155 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashedBasicTokenFactory(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	argonHash, err := HashArgon2id("secret", testArgon2idParams)
+	require.NoError(t, err)
+
+	f, err := NewHashedBasicTokenFactory(map[string]string{
+		"user":    string(bcryptHash),
+		"service": argonHash,
+	})
+	require.NoError(t, err)
+
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		description string
+		value       string
+		expectedErr error
+		expectErr   bool
+	}{
+		{
+			description: "Bcrypt Success",
+			value:       encode("user:pass"),
+		},
+		{
+			description: "Argon2id Success",
+			value:       encode("service:secret"),
+		},
+		{
+			description: "Decode Error",
+			value:       "!!!",
+			expectErr:   true,
+		},
+		{
+			description: "Malformed",
+			value:       encode("userpass"),
+			expectedErr: ErrorMalformedValue,
+		},
+		{
+			description: "Unknown User",
+			value:       encode("nobody:pass"),
+			expectedErr: ErrorPrincipalNotFound,
+		},
+		{
+			description: "Wrong Password",
+			value:       encode("service:pass"),
+			expectedErr: ErrorInvalidPassword,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			token, err := f.ParseAndValidate(context.Background(), httptest.NewRequest("GET", "/", nil), BasicAuthorization, tc.value)
+			if tc.expectErr || tc.expectedErr != nil {
+				assert.Nil(token)
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("basic", token.Type())
+		})
+	}
+
+	_, err = NewHashedBasicTokenFactory(map[string]string{"user": "plaintext"})
+	assert.ErrorContains(t, err, ErrUnsupportedHash.Error())
+	assert.ErrorIs(t, f.Reload(), ErrNoCredentialsFile)
+}
+
+func TestHashedBasicTokenFactoryFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	userHash, err := HashArgon2id("pass", testArgon2idParams)
+	require.NoError(err)
+	otherHash, err := HashArgon2id("other", testArgon2idParams)
+	require.NoError(err)
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	require.NoError(os.WriteFile(path, []byte("# comment\n\nuser:"+userHash+"\n"), 0600))
+
+	_, err = NewHashedBasicTokenFactoryFromFile(filepath.Join(t.TempDir(), "missing"), 0)
+	assert.Error(err)
+
+	f, err := NewHashedBasicTokenFactoryFromFile(path, time.Millisecond)
+	require.NoError(err)
+	value := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	_, err = f.ParseAndValidate(context.Background(), nil, BasicAuthorization, value)
+	assert.NoError(err)
+
+	f.Start()
+	f.Start()
+	defer f.Stop()
+
+	// a bad file keeps the current credentials.
+	require.NoError(os.WriteFile(path, []byte("user\n"), 0600))
+	require.NoError(os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	assert.Error(f.Reload())
+	_, err = f.ParseAndValidate(context.Background(), nil, BasicAuthorization, value)
+	assert.NoError(err)
+
+	require.NoError(os.WriteFile(path, []byte("user:"+otherHash+"\n"), 0600))
+	require.NoError(os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+	assert.Eventually(func() bool {
+		_, err := f.ParseAndValidate(context.Background(), nil, BasicAuthorization, value)
+		return err != nil
+	}, time.Second, time.Millisecond)
+
+	hook := f.Hook()
+	assert.NoError(hook.OnStop(context.Background()))
+	assert.NoError(hook.OnStart(context.Background()))
+}
+
+func TestLoadHashesErrors(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(os.WriteFile(path, []byte("user:plaintext\n"), 0600))
+	_, err := loadHashes(path)
+	assert.ErrorContains(err, ErrUnsupportedHash.Error())
+}
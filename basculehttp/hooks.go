@@ -0,0 +1,90 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// HookStage is when a Hook is run by the constructor or enforcer.
+type HookStage int
+
+const (
+	// BeforeDecision hooks run before the constructor parses the token, or
+	// before the enforcer checks it.
+	BeforeDecision HookStage = iota
+
+	// AfterAllow hooks run after a request is allowed, before the next
+	// handler.
+	AfterAllow
+
+	// AfterDeny hooks run after a request is denied, before the response
+	// is written.
+	AfterDeny
+)
+
+// HookEvent describes the decision a Hook is being run for.
+type HookEvent struct {
+	Stage HookStage
+
+	// Auth is the request's Authentication, if it has been built.
+	Auth bascule.Authentication
+
+	// Reason and Err are why the request was denied, for AfterDeny hooks.
+	Reason ErrorResponseReason
+	Err    error
+
+	// RequestID is the request's correlation ID, if the constructor
+	// extracted one.
+	RequestID string
+}
+
+// Hook is a function run around the constructor's and enforcer's decisions,
+// for enriching the request, stamping headers on the response, or sending
+// notifications.  It can return a new request, such as one with values added
+// to its context, to use for the rest of the middleware, or nil to keep the
+// request as is.  The request returned by AfterDeny hooks is ignored.
+type Hook func(http.ResponseWriter, *http.Request, HookEvent) *http.Request
+
+type hooks map[HookStage][]Hook
+
+func (h hooks) add(stage HookStage, hook Hook) hooks {
+	if hook == nil {
+		return h
+	}
+	if h == nil {
+		h = make(hooks)
+	}
+	h[stage] = append(h[stage], hook)
+	return h
+}
+
+// run runs the hooks for the event's stage in the order they were added.
+func (h hooks) run(w http.ResponseWriter, r *http.Request, event HookEvent) *http.Request {
+	if len(h[event.Stage]) > 0 && event.RequestID == "" {
+		event.RequestID, _ = bascule.RequestIDFromContext(r.Context())
+	}
+	for _, hook := range h[event.Stage] {
+		if next := hook(w, r, event); next != nil {
+			r = next
+		}
+	}
+	return r
+}
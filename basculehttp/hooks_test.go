@@ -0,0 +1,186 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+)
+
+type hookKey struct{}
+
+// recordHook records the stages it's run for and adds the stage to the
+// request's context.
+func recordHook(stages *[]HookStage) Hook {
+	return func(w http.ResponseWriter, r *http.Request, e HookEvent) *http.Request {
+		*stages = append(*stages, e.Stage)
+		w.Header().Add("X-Hook", r.Method)
+		return r.WithContext(context.WithValue(r.Context(), hookKey{}, e.Stage))
+	}
+}
+
+func TestConstructorHooks(t *testing.T) {
+	var stages []HookStage
+	var denied HookEvent
+	c := NewConstructor(
+		WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"}),
+		WithCHook(BeforeDecision, recordHook(&stages)),
+		WithCHook(AfterAllow, recordHook(&stages)),
+		WithCHook(AfterDeny, recordHook(&stages)),
+		WithCHook(AfterDeny, func(_ http.ResponseWriter, _ *http.Request, e HookEvent) *http.Request {
+			denied = e
+			return nil
+		}),
+		WithCHook(AfterDeny, nil),
+	)
+	var seen interface{}
+	handler := c(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Context().Value(hookKey{})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		description    string
+		creds          string
+		expectedStages []HookStage
+		expectedCode   int
+	}{
+		{
+			description:    "Allow",
+			creds:          "user:pass",
+			expectedStages: []HookStage{BeforeDecision, AfterAllow},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			description:    "Deny",
+			creds:          "user:nope",
+			expectedStages: []HookStage{BeforeDecision, AfterDeny},
+			expectedCode:   http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			stages, seen, denied = nil, nil, HookEvent{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, "Basic "+base64.StdEncoding.EncodeToString([]byte(tc.creds)))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(tc.expectedCode, w.Code)
+			assert.Equal(tc.expectedStages, stages)
+			assert.Len(w.Header().Values("X-Hook"), len(tc.expectedStages))
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(AfterAllow, seen)
+				return
+			}
+			assert.Equal(ParseFailed, denied.Reason)
+			assert.ErrorIs(denied.Err, ErrorInvalidPassword)
+		})
+	}
+}
+
+func TestEnforcerHooks(t *testing.T) {
+	var stages []HookStage
+	var denied HookEvent
+	e := NewEnforcer(
+		WithRules("jwt", basculechecks.NonEmptyPrincipal()),
+		WithEHook(BeforeDecision, recordHook(&stages)),
+		WithEHook(AfterAllow, recordHook(&stages)),
+		WithEHook(AfterDeny, recordHook(&stages)),
+		WithEHook(AfterDeny, func(_ http.ResponseWriter, _ *http.Request, e HookEvent) *http.Request {
+			denied = e
+			return nil
+		}),
+	)
+	var seen interface{}
+	handler := e(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Context().Value(hookKey{})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		description    string
+		noAuth         bool
+		authorization  bascule.Authorization
+		principal      string
+		expectedStages []HookStage
+		expectedReason ErrorResponseReason
+		expectedCode   int
+	}{
+		{
+			description:    "Allow",
+			authorization:  "jwt",
+			principal:      "user",
+			expectedStages: []HookStage{BeforeDecision, AfterAllow},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			description:    "Checks Failed",
+			authorization:  "jwt",
+			expectedStages: []HookStage{BeforeDecision, AfterDeny},
+			expectedReason: ChecksFailed,
+			expectedCode:   http.StatusForbidden,
+		},
+		{
+			description:    "Checks Not Found",
+			authorization:  "basic",
+			principal:      "user",
+			expectedStages: []HookStage{BeforeDecision, AfterDeny},
+			expectedReason: ChecksNotFound,
+			expectedCode:   http.StatusForbidden,
+		},
+		{
+			description:    "Missing Authentication",
+			noAuth:         true,
+			expectedStages: []HookStage{BeforeDecision, AfterDeny},
+			expectedReason: MissingAuthentication,
+			expectedCode:   http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			stages, seen, denied = nil, nil, HookEvent{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tc.noAuth {
+				req = req.WithContext(bascule.WithAuthentication(req.Context(), bascule.Authentication{
+					Authorization: tc.authorization,
+					Token:         bascule.NewToken("jwt", tc.principal, bascule.NewAttributes(map[string]interface{}{})),
+				}))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(tc.expectedCode, w.Code)
+			assert.Equal(tc.expectedStages, stages)
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(AfterAllow, seen)
+				return
+			}
+			assert.Equal(tc.expectedReason, denied.Reason)
+			assert.Error(denied.Err)
+			assert.Equal(tc.authorization, denied.Auth.Authorization)
+		})
+	}
+}
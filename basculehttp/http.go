@@ -17,7 +17,15 @@
 
 package basculehttp
 
-import "net/http"
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
 
 // statusCode follows the go-kit convention.  Errors and other objects that implement
 // this interface are allowed to supply an HTTP response status code.
@@ -31,6 +39,61 @@ type headerer interface {
 	Headers() http.Header
 }
 
+// retryAfterer allows errors to say how long a client should wait before
+// trying again.  It's used to write the Retry-After header.
+type retryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// rateLimiter allows errors to describe the limit a client ran into.  It's
+// used to write the RateLimit-* headers.  reset is how long until the limit
+// resets.
+type rateLimiter interface {
+	RateLimit() (limit, remaining int64, reset time.Duration)
+}
+
+const (
+	// RateLimitLimitHeader is the header with the request quota of the
+	// limit a client ran into.
+	RateLimitLimitHeader = "RateLimit-Limit"
+
+	// RateLimitRemainingHeader is the header with how many requests the
+	// client has left.
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+
+	// RateLimitResetHeader is the header with how many seconds until the
+	// limit resets.
+	RateLimitResetHeader = "RateLimit-Reset"
+)
+
+// RetryAfterError wraps an error with how long a client should wait before
+// trying again.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+// Error returns the wrapped error's string.
+func (e RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns how long the client should wait before trying again.
+func (e RetryAfterError) RetryAfter() time.Duration {
+	return e.After
+}
+
+// NewRetryAfterError creates a RetryAfterError with the error and duration
+// provided.
+func NewRetryAfterError(err error, after time.Duration) error {
+	return RetryAfterError{Err: err, After: after}
+}
+
 // ErrorHeaderer implements headerer, allowing an error to supply http headers
 // in an error response.
 type ErrorHeaderer struct {
@@ -55,20 +118,116 @@ func NewErrorHeaderer(err error, headers map[string][]string) error {
 }
 
 // WriteResponse performs some basic reflection on v to allow it to modify responses written
-// to an HTTP response.  Useful mainly for errors.
+// to an HTTP response.  Useful mainly for errors.  Wrapped errors and the errors in a
+// bascule.MultiError are searched too, with the first headers and status code found
+// being used.  Backoff hints are written as the Retry-After and RateLimit-*
+// headers, unless the headers supplied already include them.
 func WriteResponse(response http.ResponseWriter, defaultStatusCode int, v interface{}) {
-	if h, ok := v.(headerer); ok {
+	if h, ok := findHeaderer(v); ok {
 		for name, values := range h.Headers() {
 			for _, value := range values {
 				response.Header().Add(name, value)
 			}
 		}
 	}
+	setBackoffHeaders(response.Header(), v)
 
 	status := defaultStatusCode
-	if s, ok := v.(statusCoder); ok {
+	if s, ok := findStatusCoder(v); ok {
 		status = s.StatusCode()
 	}
 
 	response.WriteHeader(status)
 }
+
+// setBackoffHeaders adds the Retry-After and RateLimit-* headers when v
+// carries backoff hints.  Headers that are already set are left alone.
+func setBackoffHeaders(header http.Header, v interface{}) {
+	var ra retryAfterer
+	if findAs(v, &ra) && header.Get(RetryAfterHeader) == "" {
+		header.Set(RetryAfterHeader, formatSeconds(ra.RetryAfter()))
+	}
+	var rl rateLimiter
+	if findAs(v, &rl) && header.Get(RateLimitLimitHeader) == "" {
+		limit, remaining, reset := rl.RateLimit()
+		header.Set(RateLimitLimitHeader, strconv.FormatInt(limit, 10))
+		header.Set(RateLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+		header.Set(RateLimitResetHeader, formatSeconds(reset))
+	}
+}
+
+// formatSeconds rounds d up to whole seconds, never going below one.
+func formatSeconds(d time.Duration) string {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// findAs is errors.As for v, also searching the errors in a
+// bascule.MultiError.
+func findAs(v interface{}, target interface{}) bool {
+	err, ok := v.(error)
+	if !ok {
+		return false
+	}
+	if errors.As(err, target) {
+		return true
+	}
+	var me bascule.MultiError
+	if errors.As(err, &me) {
+		for _, e := range me.Errors() {
+			if findAs(e, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findHeaderer(v interface{}) (headerer, bool) {
+	if h, ok := v.(headerer); ok {
+		return h, true
+	}
+	err, ok := v.(error)
+	if !ok {
+		return nil, false
+	}
+	var h headerer
+	if errors.As(err, &h) {
+		return h, true
+	}
+	var me bascule.MultiError
+	if errors.As(err, &me) {
+		for _, e := range me.Errors() {
+			if h, ok := findHeaderer(e); ok {
+				return h, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func findStatusCoder(v interface{}) (statusCoder, bool) {
+	if s, ok := v.(statusCoder); ok {
+		return s, true
+	}
+	err, ok := v.(error)
+	if !ok {
+		return nil, false
+	}
+	var s statusCoder
+	if errors.As(err, &s) {
+		return s, true
+	}
+	var me bascule.MultiError
+	if errors.As(err, &me) {
+		for _, e := range me.Errors() {
+			if s, ok := findStatusCoder(e); ok {
+				return s, true
+			}
+		}
+	}
+	return nil, false
+}
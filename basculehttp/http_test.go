@@ -19,10 +19,13 @@ package basculehttp
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,3 +62,132 @@ func TestWriteResponse(t *testing.T) {
 	assert.Equal(http.StatusForbidden, recorder.Code)
 	assert.Equal(http.Header{}, recorder.Header())
 }
+
+type coderErr int
+
+func (c coderErr) Error() string {
+	return "coder error"
+}
+
+func (c coderErr) StatusCode() int {
+	return int(c)
+}
+
+func TestWriteResponseNestedStatus(t *testing.T) {
+	tests := []struct {
+		description    string
+		v              interface{}
+		expectedStatus int
+	}{
+		{
+			description:    "Wrapped",
+			v:              fmt.Errorf("wrapped: %w", coderErr(http.StatusTooManyRequests)),
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			description:    "In Errors",
+			v:              bascule.Errors{errors.New("other"), coderErr(http.StatusTooManyRequests)},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			description:    "Wrapped Errors",
+			v:              fmt.Errorf("wrapped: %w", bascule.Errors{fmt.Errorf("inner: %w", coderErr(http.StatusConflict))}),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			description:    "No Status",
+			v:              bascule.Errors{errors.New("other")},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Not An Error",
+			v:              "string",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			WriteResponse(recorder, http.StatusForbidden, tc.v)
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestWriteResponseNestedHeaders(t *testing.T) {
+	assert := assert.New(t)
+	headers := map[string][]string{"Retry-After": {"5"}}
+	recorder := httptest.NewRecorder()
+	err := bascule.Errors{errors.New("other"), fmt.Errorf("wrapped: %w", NewErrorHeaderer(errors.New("limited"), headers))}
+	WriteResponse(recorder, http.StatusForbidden, err)
+	assert.Equal(http.StatusForbidden, recorder.Code)
+	assert.Equal(http.Header(headers), recorder.Header())
+
+	recorder = httptest.NewRecorder()
+	WriteResponse(recorder, http.StatusForbidden, bascule.Errors{errors.New("other")})
+	assert.Equal(http.Header{}, recorder.Header())
+}
+
+type limitErr struct{}
+
+func (limitErr) Error() string {
+	return "limited"
+}
+
+func (limitErr) RateLimit() (int64, int64, time.Duration) {
+	return 10, 0, 1500 * time.Millisecond
+}
+
+func TestWriteResponseBackoff(t *testing.T) {
+	tests := []struct {
+		description     string
+		v               interface{}
+		expectedHeaders map[string]string
+	}{
+		{
+			description:     "Retry After",
+			v:               NewRetryAfterError(errors.New("limited"), 90*time.Second),
+			expectedHeaders: map[string]string{RetryAfterHeader: "90"},
+		},
+		{
+			description:     "Retry After Rounded Up",
+			v:               fmt.Errorf("wrapped: %w", NewRetryAfterError(errors.New("limited"), time.Millisecond)),
+			expectedHeaders: map[string]string{RetryAfterHeader: "1"},
+		},
+		{
+			description: "Rate Limit In Errors",
+			v:           bascule.Errors{errors.New("other"), limitErr{}},
+			expectedHeaders: map[string]string{
+				RateLimitLimitHeader:     "10",
+				RateLimitRemainingHeader: "0",
+				RateLimitResetHeader:     "2",
+			},
+		},
+		{
+			description:     "Headers Take Precedence",
+			v:               NewErrorHeaderer(NewRetryAfterError(errors.New("limited"), time.Minute), map[string][]string{RetryAfterHeader: {"5"}}),
+			expectedHeaders: map[string]string{RetryAfterHeader: "5"},
+		},
+		{
+			description:     "No Hints",
+			v:               errors.New("other"),
+			expectedHeaders: map[string]string{},
+		},
+		{
+			description:     "Not An Error",
+			v:               "string",
+			expectedHeaders: map[string]string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			WriteResponse(recorder, http.StatusTooManyRequests, tc.v)
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Len(t, recorder.Header(), len(tc.expectedHeaders))
+			for name, value := range tc.expectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(name))
+			}
+		})
+	}
+}
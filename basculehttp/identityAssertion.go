@@ -0,0 +1,182 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+const (
+	// DefaultIdentityAssertionHeader is the header WithLIdentityAssertion
+	// sets by default.
+	DefaultIdentityAssertionHeader = "X-Bascule-Identity"
+
+	defaultIdentityAssertionTTL = 30 * time.Second
+)
+
+var defaultPartnerKeys = []string{"allowedResources", "allowedPartners"}
+
+// AssertionSigner signs the JWS signing input of identity assertions.
+// tokenmint.Signer and basculekms.Signer are AssertionSigners.
+type AssertionSigner interface {
+	// Alg returns the JWS algorithm used.
+	Alg() string
+
+	// KeyID returns the kid of the signing key.  It can be empty.
+	KeyID() string
+
+	// Sign returns the signature of the signing input.
+	Sign(ctx context.Context, signingInput []byte) ([]byte, error)
+}
+
+// IdentityAssertionConfig configures the identity assertions added by
+// WithLIdentityAssertion.
+type IdentityAssertionConfig struct {
+	// Header is the request header the assertion is sent upstream in.
+	// Defaults to DefaultIdentityAssertionHeader.
+	Header string
+
+	// Issuer and Audience are the iss and aud claims.  They are left out if
+	// empty.
+	Issuer   string
+	Audience []string
+
+	// TTL is how long assertions are valid for.  Assertions never outlive the
+	// token they summarize.  Defaults to 30 seconds.
+	TTL time.Duration
+
+	// PartnerKeys is the location of the partner ids in the token's
+	// attributes.  Defaults to allowedResources, allowedPartners.
+	PartnerKeys []string
+}
+
+type identityAssertion struct {
+	signer AssertionSigner
+	config IdentityAssertionConfig
+}
+
+// WithLIdentityAssertion has the listener decorator add a short-lived JWT,
+// signed by the signer given, to the request before it is passed on.  The JWT
+// summarizes the validated identity with the sub, partners, tokenType, and
+// exp claims, so that internal services behind this one can trust the
+// identity without validating the original token again.  Any assertion header
+// sent by the client is removed first, including on skipped requests.  If
+// signing fails, the request goes on without an assertion.
+func WithLIdentityAssertion(signer AssertionSigner, config IdentityAssertionConfig) LOption {
+	return func(l *listenerDecorator) {
+		if signer == nil {
+			return
+		}
+		if config.Header == "" {
+			config.Header = DefaultIdentityAssertionHeader
+		}
+		if config.TTL <= 0 {
+			config.TTL = defaultIdentityAssertionTTL
+		}
+		if len(config.PartnerKeys) == 0 {
+			config.PartnerKeys = defaultPartnerKeys
+		}
+		l.assertion = &identityAssertion{signer: signer, config: config}
+	}
+}
+
+// claims returns the claims of the assertion for the token, or false if the
+// token has already expired.
+func (a *identityAssertion) claims(token bascule.Token, now time.Time) (map[string]interface{}, bool) {
+	expires := now.Add(a.config.TTL)
+	if exp, ok := tokenExpiration(token); ok && exp.Before(expires) {
+		expires = exp
+	}
+	if !now.Before(expires) {
+		return nil, false
+	}
+	claims := map[string]interface{}{
+		"sub":       token.Principal(),
+		"tokenType": token.Type(),
+		"iat":       now.Unix(),
+		"exp":       expires.Unix(),
+	}
+	if a.config.Issuer != "" {
+		claims["iss"] = a.config.Issuer
+	}
+	if len(a.config.Audience) == 1 {
+		claims["aud"] = a.config.Audience[0]
+	} else if len(a.config.Audience) > 1 {
+		claims["aud"] = a.config.Audience
+	}
+	if token.Attributes() != nil {
+		if v, ok := bascule.GetNestedAttribute(token.Attributes(), a.config.PartnerKeys...); ok {
+			if partners, err := cast.ToStringSliceE(v); err == nil && len(partners) > 0 {
+				claims["partners"] = partners
+			}
+		}
+	}
+	return claims, true
+}
+
+// sign creates the compact JWS of the claims.
+func (a *identityAssertion) sign(ctx context.Context, claims map[string]interface{}) (string, error) {
+	header := map[string]interface{}{
+		"alg": a.signer.Alg(),
+		"typ": "JWT",
+	}
+	if kid := a.signer.KeyID(); kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := a.signer.Sign(ctx, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign identity assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// assert replaces the assertion header of the request with a new assertion
+// for the token, returning the error if one couldn't be signed.
+func (a *identityAssertion) assert(request *http.Request, token bascule.Token, now time.Time) error {
+	request.Header.Del(a.config.Header)
+	if token == nil {
+		return nil
+	}
+	claims, ok := a.claims(token, now)
+	if !ok {
+		return nil
+	}
+	value, err := a.sign(request.Context(), claims)
+	if err != nil {
+		return err
+	}
+	request.Header.Set(a.config.Header, value)
+	return nil
+}
@@ -0,0 +1,200 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/tokenmint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ AssertionSigner = tokenmint.Signer(nil)
+
+// testAssertionSigner signs with an in-memory ES256 key.
+type testAssertionSigner struct {
+	key *ecdsa.PrivateKey
+	err error
+}
+
+func newTestAssertionSigner(t *testing.T) *testAssertionSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &testAssertionSigner{key: key}
+}
+
+func (s *testAssertionSigner) Alg() string   { return jwt.SigningMethodES256.Alg() }
+func (s *testAssertionSigner) KeyID() string { return "assertion-key" }
+
+func (s *testAssertionSigner) Sign(_ context.Context, signingInput []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	sig, err := jwt.SigningMethodES256.Sign(string(signingInput), s.key)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.DecodeSegment(sig)
+}
+
+func TestListenerDecoratorIdentityAssertion(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	errSign := errors.New("signing failed")
+	tests := []struct {
+		description    string
+		config         IdentityAssertionConfig
+		signErr        error
+		attributes     map[string]interface{}
+		skipped        bool
+		noAuth         bool
+		expectedHeader string
+		expectedClaims jwt.MapClaims
+	}{
+		{
+			description: "Defaults",
+			attributes: map[string]interface{}{
+				"allowedResources": map[string]interface{}{
+					"allowedPartners": []interface{}{"comcast", "sky"},
+				},
+			},
+			expectedHeader: DefaultIdentityAssertionHeader,
+			expectedClaims: jwt.MapClaims{
+				"sub":       "alice",
+				"tokenType": "jwt",
+				"partners":  []interface{}{"comcast", "sky"},
+				"iat":       float64(now.Unix()),
+				"exp":       float64(now.Add(defaultIdentityAssertionTTL).Unix()),
+			},
+		},
+		{
+			description: "Configured",
+			config: IdentityAssertionConfig{
+				Header:      "X-Identity",
+				Issuer:      "gateway",
+				Audience:    []string{"internal"},
+				TTL:         time.Minute,
+				PartnerKeys: []string{"partner"},
+			},
+			attributes: map[string]interface{}{
+				"partner": "comcast",
+				"exp":     now.Add(10 * time.Second).Unix(),
+			},
+			expectedHeader: "X-Identity",
+			expectedClaims: jwt.MapClaims{
+				"sub":       "alice",
+				"tokenType": "jwt",
+				"partners":  []interface{}{"comcast"},
+				"iss":       "gateway",
+				"aud":       "internal",
+				"iat":       float64(now.Unix()),
+				"exp":       float64(now.Add(10 * time.Second).Unix()),
+			},
+		},
+		{
+			description: "Multiple audiences",
+			config: IdentityAssertionConfig{
+				Audience: []string{"a", "b"},
+			},
+			expectedHeader: DefaultIdentityAssertionHeader,
+			expectedClaims: jwt.MapClaims{
+				"sub":       "alice",
+				"tokenType": "jwt",
+				"aud":       []interface{}{"a", "b"},
+				"iat":       float64(now.Unix()),
+				"exp":       float64(now.Add(defaultIdentityAssertionTTL).Unix()),
+			},
+		},
+		{
+			description:    "Expired token",
+			attributes:     map[string]interface{}{"exp": now.Add(-time.Second).Unix()},
+			expectedHeader: DefaultIdentityAssertionHeader,
+		},
+		{
+			description:    "Signing failure",
+			signErr:        errSign,
+			expectedHeader: DefaultIdentityAssertionHeader,
+		},
+		{
+			description:    "Skipped",
+			skipped:        true,
+			expectedHeader: DefaultIdentityAssertionHeader,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			signer := newTestAssertionSigner(t)
+			signer.err = tc.signErr
+
+			var forwarded http.Header
+			handler := NewListenerDecoratorWithOptions(
+				WithLIdentityAssertion(signer, tc.config),
+				func(l *listenerDecorator) { l.now = func() time.Time { return now } },
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r.Header.Clone()
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(tc.expectedHeader, "spoofed")
+			ctx := bascule.WithAuthentication(req.Context(), bascule.Authentication{
+				Token: bascule.NewToken("jwt", "alice", bascule.NewAttributes(tc.attributes)),
+			})
+			req = req.WithContext(ctx)
+			if tc.skipped {
+				req = withSkipped(req)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(http.StatusOK, w.Code)
+
+			value := forwarded.Get(tc.expectedHeader)
+			if tc.expectedClaims == nil {
+				assert.Empty(value)
+				return
+			}
+			claims := jwt.MapClaims{}
+			parser := jwt.Parser{SkipClaimsValidation: true}
+			token, err := parser.ParseWithClaims(value, claims, func(token *jwt.Token) (interface{}, error) {
+				assert.Equal("assertion-key", token.Header["kid"])
+				return signer.key.Public(), nil
+			})
+			require.NoError(err)
+			assert.True(token.Valid)
+			assert.Equal(tc.expectedClaims, claims)
+		})
+	}
+}
+
+func TestWithLIdentityAssertionNilSigner(t *testing.T) {
+	l := &listenerDecorator{}
+	WithLIdentityAssertion(nil, IdentityAssertionConfig{})(l)
+	assert.Nil(t, l.assertion)
+}
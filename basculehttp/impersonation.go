@@ -0,0 +1,257 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultImpersonationHeader is the header a caller uses to name the
+	// principal it wants to act as.
+	DefaultImpersonationHeader = "X-Impersonate-User"
+
+	// DefaultImpersonationCapability is the capability a principal must hold
+	// in order to impersonate another principal.
+	DefaultImpersonationCapability = "impersonate"
+
+	// ImpersonatorKey is the attribute key holding the original principal on
+	// an impersonated token.
+	ImpersonatorKey = "impersonator"
+
+	// ImpersonatedUserKey is the attribute key holding the requested principal
+	// when the impersonator is configured to only annotate the token.
+	ImpersonatedUserKey = "impersonatedUser"
+)
+
+var errImpersonationDenied = bascule.NewClassError(bascule.CapabilityClass, "impersonation denied")
+
+type impersonationTargetKey struct{}
+
+// ImpersonationAttributesFunc looks up the attributes of the principal being
+// impersonated, which become the attributes of the swapped token.
+type ImpersonationAttributesFunc func(ctx context.Context, target string) (bascule.Attributes, error)
+
+// IOption is any function that modifies the impersonator - used to configure
+// the impersonator.
+type IOption func(*impersonator)
+
+type impersonator struct {
+	headerName      string
+	policy          bascule.Validator
+	attributes      ImpersonationAttributesFunc
+	annotateOnly    bool
+	getLogger       func(context.Context) *zap.Logger
+	onErrorResponse OnErrorResponse
+}
+
+func (i *impersonator) decorate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		target := request.Header.Get(i.headerName)
+		if len(target) == 0 {
+			next.ServeHTTP(response, request)
+			return
+		}
+
+		ctx := request.Context()
+		logger := i.getLogger(ctx)
+		if logger == nil {
+			logger = sallust.Get(ctx)
+		}
+		auth, ok := bascule.FromContext(ctx)
+		if !ok || auth.Token == nil {
+			err := fmt.Errorf("%w: no authentication found", errImpersonationDenied)
+			logger.Error(err.Error())
+			i.onErrorResponse(MissingAuthentication, err)
+			response.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if err := i.policy.Check(context.WithValue(ctx, impersonationTargetKey{}, target), auth.Token); err != nil {
+			err = fmt.Errorf("%w: %v", errImpersonationDenied, err)
+			logger.Error(err.Error(), zap.String("impersonator", auth.Token.Principal()),
+				zap.String("impersonated", target))
+			i.onErrorResponse(ImpersonationDenied, err)
+			WriteResponse(response, http.StatusForbidden, err)
+			return
+		}
+
+		token, err := i.impersonate(ctx, auth.Token, target)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", errImpersonationDenied, err)
+			logger.Error(err.Error(), zap.String("impersonator", auth.Token.Principal()),
+				zap.String("impersonated", target))
+			i.onErrorResponse(ImpersonationDenied, err)
+			WriteResponse(response, http.StatusForbidden, err)
+			return
+		}
+
+		logger.Info("impersonation accepted", zap.String("impersonator", auth.Token.Principal()),
+			zap.String("impersonated", target))
+		auth.Token = token
+		ctx = bascule.WithAuthentication(ctx, auth)
+		next.ServeHTTP(response, request.WithContext(ctx))
+	})
+}
+
+// impersonate builds the token used for the rest of the request.  A swapped
+// token never carries the impersonator's attributes: it gets the target's
+// attributes if they can be looked up, and otherwise only ImpersonatorKey.
+func (i *impersonator) impersonate(ctx context.Context, t bascule.Token, target string) (bascule.Token, error) {
+	if i.annotateOnly {
+		return bascule.NewToken(t.Type(), t.Principal(), impersonationAttributes{
+			key:   ImpersonatedUserKey,
+			value: target,
+			base:  t.Attributes(),
+		}), nil
+	}
+	var base bascule.Attributes
+	if i.attributes != nil {
+		attributes, err := i.attributes(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		base = attributes
+	}
+	return bascule.NewToken(t.Type(), target, impersonationAttributes{
+		key:   ImpersonatorKey,
+		value: t.Principal(),
+		base:  base,
+	}), nil
+}
+
+// ImpersonationTarget returns the principal being impersonated, so that an
+// impersonation policy can decide whether the token may act as that
+// principal.  It is only set in the context passed to the policy.
+func ImpersonationTarget(ctx context.Context) (string, bool) {
+	target, ok := ctx.Value(impersonationTargetKey{}).(string)
+	return target, ok && target != ""
+}
+
+// NewImpersonator creates an Alice-style decorator function that acts as
+// middleware, letting a sufficiently privileged principal act as another
+// principal by supplying the impersonation header.  It should be placed after
+// the enforcer, so that only authorized tokens can impersonate.  Requests
+// without the header pass through untouched.
+func NewImpersonator(options ...IOption) func(http.Handler) http.Handler {
+	i := &impersonator{
+		headerName: DefaultImpersonationHeader,
+		policy: basculechecks.AttributeContains(basculechecks.CapabilityKeys(),
+			DefaultImpersonationCapability),
+		getLogger:       sallust.Get,
+		onErrorResponse: DefaultOnErrorResponse,
+	}
+
+	for _, o := range options {
+		if o == nil {
+			continue
+		}
+		o(i)
+	}
+
+	return i.decorate
+}
+
+// WithImpersonationHeader sets the header used to request impersonation.
+func WithImpersonationHeader(headerName string) IOption {
+	return func(i *impersonator) {
+		if len(headerName) > 0 {
+			i.headerName = headerName
+		}
+	}
+}
+
+// WithImpersonationPolicy sets the validator run against the original token
+// to decide if it may impersonate.  The principal being impersonated is
+// available to the validator through ImpersonationTarget.  By default the
+// token needs the "impersonate" capability.
+func WithImpersonationPolicy(v bascule.Validator) IOption {
+	return func(i *impersonator) {
+		if v != nil {
+			i.policy = v
+		}
+	}
+}
+
+// WithImpersonationAttributes sets the function used to look up the target
+// principal's attributes for the swapped token.  Without it, the swapped token
+// only has the ImpersonatorKey attribute.  If the function returns an error,
+// the impersonation is denied.
+func WithImpersonationAttributes(f ImpersonationAttributesFunc) IOption {
+	return func(i *impersonator) {
+		if f != nil {
+			i.attributes = f
+		}
+	}
+}
+
+// WithAnnotateOnly keeps the original principal and records the requested
+// principal in the token's attributes under ImpersonatedUserKey, rather than
+// swapping the principal.
+func WithAnnotateOnly() IOption {
+	return func(i *impersonator) {
+		i.annotateOnly = true
+	}
+}
+
+// WithILogger sets the function to use to get the logger from the context.
+func WithILogger(getLogger func(context.Context) *zap.Logger) IOption {
+	return func(i *impersonator) {
+		if getLogger != nil {
+			i.getLogger = getLogger
+		}
+	}
+}
+
+// WithIErrorResponseFunc sets the function that is called when an error
+// occurs.
+func WithIErrorResponseFunc(f OnErrorResponse) IOption {
+	return func(i *impersonator) {
+		if f != nil {
+			i.onErrorResponse = f
+		}
+	}
+}
+
+// impersonationAttributes adds a single impersonation attribute to a token's
+// attributes.
+type impersonationAttributes struct {
+	key   string
+	value string
+	base  bascule.Attributes
+}
+
+func (a impersonationAttributes) Get(key string) (interface{}, bool) {
+	if key == a.key {
+		return a.value, true
+	}
+	if a.base == nil {
+		return nil, false
+	}
+	return a.base.Get(key)
+}
+
+func (a impersonationAttributes) Keys() ([]string, bool) {
+	return bascule.LayeredKeys(a.base, a.key)
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/sallust"
+)
+
+func TestImpersonator(t *testing.T) {
+	admin := bascule.NewToken("jwt", "admin", bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{DefaultImpersonationCapability},
+		"roles":        []string{"support"},
+	}))
+	user := bascule.NewToken("jwt", "user", bascule.NewAttributes(map[string]interface{}{
+		"capabilities": []string{"read"},
+	}))
+	tests := []struct {
+		description        string
+		options            []IOption
+		header             string
+		target             string
+		token              bascule.Token
+		noAuth             bool
+		expectedStatusCode int
+		expectedPrincipal  string
+		expectedAttrKey    string
+		expectedAttrValue  string
+		expectedCaps       interface{}
+		expectedReason     ErrorResponseReason
+	}{
+		{
+			description:        "Swap Principal Success",
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			token:              admin,
+			expectedStatusCode: http.StatusOK,
+			expectedPrincipal:  "customer",
+			expectedAttrKey:    ImpersonatorKey,
+			expectedAttrValue:  "admin",
+		},
+		{
+			description: "Swap Principal Target Attributes Success",
+			options: []IOption{
+				WithImpersonationAttributes(nil),
+				WithImpersonationAttributes(func(_ context.Context, target string) (bascule.Attributes, error) {
+					return bascule.NewAttributes(map[string]interface{}{
+						"capabilities": []string{target + ":read"},
+					}), nil
+				}),
+			},
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			token:              admin,
+			expectedStatusCode: http.StatusOK,
+			expectedPrincipal:  "customer",
+			expectedAttrKey:    ImpersonatorKey,
+			expectedAttrValue:  "admin",
+			expectedCaps:       []string{"customer:read"},
+		},
+		{
+			description: "Target Attributes Error",
+			options: []IOption{
+				WithImpersonationAttributes(func(context.Context, string) (bascule.Attributes, error) {
+					return nil, errors.New("unknown principal")
+				}),
+			},
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			token:              admin,
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     ImpersonationDenied,
+		},
+		{
+			description: "Target Policy Denied Error",
+			options: []IOption{
+				WithImpersonationPolicy(bascule.ValidatorFunc(func(ctx context.Context, _ bascule.Token) error {
+					if target, ok := ImpersonationTarget(ctx); !ok || target == "root" {
+						return errors.New("target not allowed")
+					}
+					return nil
+				})),
+			},
+			header:             DefaultImpersonationHeader,
+			target:             "root",
+			token:              admin,
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     ImpersonationDenied,
+		},
+		{
+			description:        "Annotate Only Success",
+			options:            []IOption{WithAnnotateOnly()},
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			token:              admin,
+			expectedStatusCode: http.StatusOK,
+			expectedPrincipal:  "admin",
+			expectedAttrKey:    ImpersonatedUserKey,
+			expectedAttrValue:  "customer",
+			expectedCaps:       []string{DefaultImpersonationCapability},
+		},
+		{
+			description: "Custom Header and Policy Success",
+			options: []IOption{
+				WithImpersonationHeader("X-Sudo"),
+				WithImpersonationPolicy(basculechecks.AttributeContains([]string{"roles"}, "support")),
+			},
+			header:             "X-Sudo",
+			target:             "customer",
+			token:              admin,
+			expectedStatusCode: http.StatusOK,
+			expectedPrincipal:  "customer",
+			expectedAttrKey:    ImpersonatorKey,
+			expectedAttrValue:  "admin",
+		},
+		{
+			description:        "No Header Passthrough",
+			token:              user,
+			expectedStatusCode: http.StatusOK,
+			expectedPrincipal:  "user",
+		},
+		{
+			description:        "Policy Denied Error",
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			token:              user,
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     ImpersonationDenied,
+		},
+		{
+			description:        "No Auth Error",
+			header:             DefaultImpersonationHeader,
+			target:             "customer",
+			noAuth:             true,
+			expectedStatusCode: http.StatusForbidden,
+			expectedReason:     MissingAuthentication,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var reason ErrorResponseReason
+			options := append([]IOption{
+				nil,
+				WithILogger(sallust.Get),
+				WithIErrorResponseFunc(func(r ErrorResponseReason, _ error) { reason = r }),
+			}, tc.options...)
+			var auth bascule.Authentication
+			handler := NewImpersonator(options...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth, _ = bascule.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("get", "/", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.target)
+			}
+			if !tc.noAuth {
+				req = req.WithContext(bascule.WithAuthentication(context.Background(),
+					bascule.Authentication{Authorization: "Bearer", Token: tc.token}))
+			}
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatusCode, writer.Code)
+			if tc.expectedStatusCode != http.StatusOK {
+				assert.Equal(tc.expectedReason, reason)
+				return
+			}
+			assert.Equal(tc.expectedPrincipal, auth.Token.Principal())
+			if tc.expectedAttrKey != "" {
+				v, ok := auth.Token.Attributes().Get(tc.expectedAttrKey)
+				assert.True(ok)
+				assert.Equal(tc.expectedAttrValue, v)
+				// a swapped token never keeps the impersonator's attributes.
+				caps, ok := auth.Token.Attributes().Get("capabilities")
+				assert.Equal(tc.expectedCaps != nil, ok)
+				assert.Equal(tc.expectedCaps, caps)
+			}
+		})
+	}
+}
+
+func TestImpersonationAttributesKeys(t *testing.T) {
+	assert := assert.New(t)
+	keys, ok := bascule.AttributeKeys(impersonationAttributes{
+		key:   "onBehalfOf",
+		value: "bob",
+		base:  bascule.NewAttributes(map[string]interface{}{"sub": "alice"}),
+	})
+	assert.True(ok)
+	assert.Equal([]string{"onBehalfOf", "sub"}, keys)
+}
@@ -0,0 +1,173 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// AccessTokenJWTType is the typ of RFC 9068 JWT access tokens.
+const AccessTokenJWTType = "at+jwt"
+
+const applicationMediaPrefix = "application/"
+
+var (
+	ErrInvalidTokenType    = bascule.NewClassError(bascule.InvalidClass, "invalid token type")
+	ErrInvalidContentType  = bascule.NewClassError(bascule.InvalidClass, "invalid content type")
+	ErrUnsupportedCritical = bascule.NewClassError(bascule.InvalidClass, "unsupported critical header")
+	ErrMissingKeyID        = bascule.NewClassError(bascule.MalformedClass, "missing key id")
+)
+
+// registeredJOSEHeaders are the headers defined by RFC 7515, which can't be
+// listed in crit.
+var registeredJOSEHeaders = map[string]bool{
+	"alg": true, "jku": true, "jwk": true, "kid": true, "x5u": true, "x5c": true,
+	"x5t": true, "x5t#S256": true, "typ": true, "cty": true, "crit": true,
+}
+
+// JWTHeaderRules configures validation of a JWT's JOSE header, done before
+// the key is resolved.  The zero value only rejects critical headers that
+// aren't understood, as RFC 7515 requires.
+type JWTHeaderRules struct {
+	// Types lists the allowed typ values, such as AccessTokenJWTType for RFC
+	// 9068 access tokens.  Values are compared without case and with or without
+	// the "application/" prefix.  If empty, typ isn't checked.
+	Types []string
+
+	// ContentTypes lists the allowed cty values, compared like Types.  If
+	// empty, cty isn't checked.
+	ContentTypes []string
+
+	// Critical lists the header extensions that are understood when they're
+	// listed in crit.
+	Critical []string
+
+	// RequireKeyID rejects tokens without a kid header instead of using the
+	// default key id.
+	RequireKeyID bool
+}
+
+// JWTHeaderError is returned when a JWT's header fails validation.  Its
+// Reason is used as the metric label for the failure.
+type JWTHeaderError struct {
+	// Header is the name of the header that failed validation.
+	Header string
+
+	// Err is one of ErrInvalidTokenType, ErrInvalidContentType,
+	// ErrUnsupportedCritical, or ErrMissingKeyID.
+	Err error
+
+	reason ErrorResponseReason
+}
+
+func (e *JWTHeaderError) Error() string {
+	return fmt.Sprintf("jwt header %q failed validation: %v", e.Header, e.Err)
+}
+
+func (e *JWTHeaderError) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the metric label safe reason for the failure.
+func (e *JWTHeaderError) Reason() string {
+	return e.reason.String()
+}
+
+// ResponseReason returns the ErrorResponseReason for the failure.
+func (e *JWTHeaderError) ResponseReason() ErrorResponseReason {
+	return e.reason
+}
+
+// validate checks the header against the rules, returning a *JWTHeaderError
+// for the first failure.
+func (r JWTHeaderRules) validate(header map[string]interface{}) error {
+	if len(r.Types) > 0 && !mediaTypeIn(header["typ"], r.Types) {
+		return &JWTHeaderError{Header: "typ", Err: fmt.Errorf("%w: %v", ErrInvalidTokenType, header["typ"]), reason: InvalidTokenType}
+	}
+	if len(r.ContentTypes) > 0 && !mediaTypeIn(header["cty"], r.ContentTypes) {
+		return &JWTHeaderError{Header: "cty", Err: fmt.Errorf("%w: %v", ErrInvalidContentType, header["cty"]), reason: InvalidContentType}
+	}
+	if err := r.validateCritical(header); err != nil {
+		return &JWTHeaderError{Header: "crit", Err: err, reason: UnsupportedCritical}
+	}
+	if r.RequireKeyID {
+		if kid, _ := header["kid"].(string); kid == "" {
+			return &JWTHeaderError{Header: "kid", Err: ErrMissingKeyID, reason: MissingKeyID}
+		}
+	}
+	return nil
+}
+
+// validateCritical follows RFC 7515 section 4.1.11: crit must be a non-empty
+// list of extension names that are understood and present in the header.
+func (r JWTHeaderRules) validateCritical(header map[string]interface{}) error {
+	v, ok := header["crit"]
+	if !ok {
+		return nil
+	}
+	names, ok := v.([]interface{})
+	if !ok || len(names) == 0 {
+		return fmt.Errorf("%w: crit must be a non-empty list", ErrUnsupportedCritical)
+	}
+	for _, n := range names {
+		name, ok := n.(string)
+		if !ok || registeredJOSEHeaders[name] {
+			return fmt.Errorf("%w: %v", ErrUnsupportedCritical, n)
+		}
+		if !contains(r.Critical, name) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCritical, name)
+		}
+		if _, ok := header[name]; !ok {
+			return fmt.Errorf("%w: %s not in header", ErrUnsupportedCritical, name)
+		}
+	}
+	return nil
+}
+
+func mediaTypeIn(v interface{}, allowed []string) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	s = normalizeMediaType(s)
+	for _, a := range allowed {
+		if normalizeMediaType(a) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMediaType drops the "application/" prefix, which RFC 7515 says
+// should be left out of typ and cty.
+func normalizeMediaType(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.TrimPrefix(s, applicationMediaPrefix)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var _ basculechecks.Reasoner = (*JWTHeaderError)(nil)
+
+func TestJWTHeaderRules(t *testing.T) {
+	tests := []struct {
+		description    string
+		rules          JWTHeaderRules
+		header         map[string]interface{}
+		expectedErr    error
+		expectedHeader string
+		expectedReason ErrorResponseReason
+	}{
+		{
+			description: "Zero Rules",
+			header:      map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		},
+		{
+			description: "Type Success",
+			rules:       JWTHeaderRules{Types: []string{AccessTokenJWTType}},
+			header:      map[string]interface{}{"typ": "at+jwt"},
+		},
+		{
+			description: "Type With Prefix Success",
+			rules:       JWTHeaderRules{Types: []string{AccessTokenJWTType}},
+			header:      map[string]interface{}{"typ": "Application/AT+JWT"},
+		},
+		{
+			description:    "Wrong Type",
+			rules:          JWTHeaderRules{Types: []string{AccessTokenJWTType}},
+			header:         map[string]interface{}{"typ": "JWT"},
+			expectedErr:    ErrInvalidTokenType,
+			expectedHeader: "typ",
+			expectedReason: InvalidTokenType,
+		},
+		{
+			description:    "Missing Type",
+			rules:          JWTHeaderRules{Types: []string{AccessTokenJWTType}},
+			header:         map[string]interface{}{},
+			expectedErr:    ErrInvalidTokenType,
+			expectedHeader: "typ",
+			expectedReason: InvalidTokenType,
+		},
+		{
+			description: "Content Type Success",
+			rules:       JWTHeaderRules{ContentTypes: []string{"application/json"}},
+			header:      map[string]interface{}{"cty": "json"},
+		},
+		{
+			description:    "Wrong Content Type",
+			rules:          JWTHeaderRules{ContentTypes: []string{"json"}},
+			header:         map[string]interface{}{"cty": "JWT"},
+			expectedErr:    ErrInvalidContentType,
+			expectedHeader: "cty",
+			expectedReason: InvalidContentType,
+		},
+		{
+			description: "Critical Success",
+			rules:       JWTHeaderRules{Critical: []string{"exp"}},
+			header:      map[string]interface{}{"crit": []interface{}{"exp"}, "exp": 5.0},
+		},
+		{
+			description:    "Unknown Critical",
+			header:         map[string]interface{}{"crit": []interface{}{"exp"}, "exp": 5.0},
+			expectedErr:    ErrUnsupportedCritical,
+			expectedHeader: "crit",
+			expectedReason: UnsupportedCritical,
+		},
+		{
+			description:    "Critical Not In Header",
+			rules:          JWTHeaderRules{Critical: []string{"exp"}},
+			header:         map[string]interface{}{"crit": []interface{}{"exp"}},
+			expectedErr:    ErrUnsupportedCritical,
+			expectedHeader: "crit",
+			expectedReason: UnsupportedCritical,
+		},
+		{
+			description:    "Registered Critical",
+			rules:          JWTHeaderRules{Critical: []string{"kid"}},
+			header:         map[string]interface{}{"crit": []interface{}{"kid"}, "kid": "a"},
+			expectedErr:    ErrUnsupportedCritical,
+			expectedHeader: "crit",
+			expectedReason: UnsupportedCritical,
+		},
+		{
+			description:    "Empty Critical",
+			header:         map[string]interface{}{"crit": []interface{}{}},
+			expectedErr:    ErrUnsupportedCritical,
+			expectedHeader: "crit",
+			expectedReason: UnsupportedCritical,
+		},
+		{
+			description:    "Critical Not A List",
+			header:         map[string]interface{}{"crit": "exp"},
+			expectedErr:    ErrUnsupportedCritical,
+			expectedHeader: "crit",
+			expectedReason: UnsupportedCritical,
+		},
+		{
+			description: "Key ID Success",
+			rules:       JWTHeaderRules{RequireKeyID: true},
+			header:      map[string]interface{}{"kid": "a"},
+		},
+		{
+			description:    "Missing Key ID",
+			rules:          JWTHeaderRules{RequireKeyID: true},
+			header:         map[string]interface{}{"kid": ""},
+			expectedErr:    ErrMissingKeyID,
+			expectedHeader: "kid",
+			expectedReason: MissingKeyID,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			err := tc.rules.validate(tc.header)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			var headerErr *JWTHeaderError
+			require.True(t, errors.As(err, &headerErr))
+			assert.Equal(tc.expectedHeader, headerErr.Header)
+			assert.Equal(tc.expectedReason, headerErr.ResponseReason())
+			assert.Equal(tc.expectedReason.String(), headerErr.Reason())
+		})
+	}
+}
+
+func TestBearerTokenFactoryHeaderRules(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sign := func(header map[string]interface{}) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{jwtPrincipalKey: "test"})
+		for k, v := range header {
+			token.Header[k] = v
+		}
+		s, err := token.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	tests := []struct {
+		description   string
+		header        map[string]interface{}
+		resolveCalled bool
+		expectedErr   error
+	}{
+		{
+			description:   "Success",
+			header:        map[string]interface{}{"typ": "at+jwt", "kid": "a"},
+			resolveCalled: true,
+		},
+		{
+			description: "Wrong Type",
+			header:      map[string]interface{}{"kid": "a"},
+			expectedErr: ErrInvalidTokenType,
+		},
+		{
+			description: "Missing Key ID",
+			header:      map[string]interface{}{"typ": "at+jwt"},
+			expectedErr: ErrMissingKeyID,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r := new(MockResolver)
+			k := new(mockKey)
+			if tc.resolveCalled {
+				r.On("Resolve", mock.Anything, "a").Return(k, nil).Once()
+				k.On("Public").Return(&key.PublicKey).Once()
+			}
+			btf := BearerTokenFactory{
+				DefaultKeyID: "default",
+				Resolver:     r,
+				Parser:       bascule.DefaultJWTParser,
+				HeaderRules: JWTHeaderRules{
+					Types:        []string{AccessTokenJWTType},
+					RequireKeyID: true,
+				},
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			token, err := btf.ParseAndValidate(context.Background(), req, BearerAuthorization, sign(tc.header))
+			r.AssertExpectations(t)
+			k.AssertExpectations(t)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(token)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("test", token.Principal())
+		})
+	}
+}
+
+func TestConstructorJWTHeaderReason(t *testing.T) {
+	assert := assert.New(t)
+	tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+		return nil, &JWTHeaderError{Header: "crit", Err: ErrUnsupportedCritical, reason: UnsupportedCritical}
+	})
+	var reason ErrorResponseReason
+	c := NewConstructor(
+		WithTokenFactory(BearerAuthorization, tf),
+		WithCErrorResponseFunc(func(r ErrorResponseReason, _ error) {
+			reason = r
+		}),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultHeaderName, "Bearer abc")
+	w := httptest.NewRecorder()
+	c(next).ServeHTTP(w, req)
+	assert.Equal(UnsupportedCritical, reason)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,233 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// LDAPGroupsKey is the attribute key the user's group memberships are
+	// stored under.
+	LDAPGroupsKey = "groups"
+
+	// LDAPUserDNKey is the attribute key the user's distinguished name is
+	// stored under.
+	LDAPUserDNKey = "dn"
+
+	defaultLDAPPoolSize = 4
+	defaultLDAPTimeout  = 5 * time.Second
+)
+
+var (
+	// ErrLDAPInvalidCredentials should be returned by an LDAPConn's Bind
+	// when the directory rejects the username or password, as opposed to
+	// the directory being unavailable.
+	ErrLDAPInvalidCredentials = bascule.NewClassError(bascule.InvalidClass, "invalid ldap credentials")
+
+	ErrNilLDAPDialer = errors.New("ldap dialer cannot be nil")
+	ErrEmptyUserDN   = errors.New("ldap user dn template cannot be empty")
+)
+
+// LDAPConn is the part of an LDAP connection the LDAPTokenFactory needs.  It
+// is small so that any LDAP client can be adapted to it.
+type LDAPConn interface {
+	// Bind authenticates the connection as the dn given.
+	Bind(ctx context.Context, dn, password string) error
+
+	// Groups returns the groups the dn given is a member of.
+	Groups(ctx context.Context, dn string) ([]string, error)
+
+	// Close closes the connection.
+	Close() error
+}
+
+// LDAPDialer opens a connection to the LDAP server at the address given.  If
+// the tls.Config isn't nil, the connection must use TLS.
+type LDAPDialer func(ctx context.Context, addr string, tlsConfig *tls.Config) (LDAPConn, error)
+
+// LDAPConfig configures an LDAPTokenFactory.
+type LDAPConfig struct {
+	// Addr is the address of the LDAP server.
+	Addr string
+
+	// TLS is the TLS configuration used to connect.  If it is nil, the
+	// connection isn't encrypted, which should only be done for testing.
+	TLS *tls.Config
+
+	// UserDN is the template used to build a user's distinguished name, with
+	// %s in place of the username.  For example,
+	// "uid=%s,ou=people,dc=example,dc=com", or "%s@corp.example.com" for
+	// Active Directory.  The username is escaped before it is added.
+	UserDN string
+
+	// PoolSize is the number of idle connections kept open.  Defaults to 4.
+	PoolSize int
+
+	// Timeout bounds dialing, binding, and fetching groups for a request.
+	// Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// LDAPTokenFactory verifies basic auth credentials by binding to an LDAP
+// directory as the user, then adds the user's groups to the token's
+// attributes.
+type LDAPTokenFactory struct {
+	config LDAPConfig
+	dial   LDAPDialer
+	pool   chan LDAPConn
+}
+
+// NewLDAPTokenFactory creates an LDAPTokenFactory that uses the dialer given
+// to connect to the directory.
+func NewLDAPTokenFactory(config LDAPConfig, dial LDAPDialer) (*LDAPTokenFactory, error) {
+	if dial == nil {
+		return nil, ErrNilLDAPDialer
+	}
+	if config.UserDN == "" {
+		return nil, ErrEmptyUserDN
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultLDAPPoolSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultLDAPTimeout
+	}
+	return &LDAPTokenFactory{
+		config: config,
+		dial:   dial,
+		pool:   make(chan LDAPConn, config.PoolSize),
+	}, nil
+}
+
+// ParseAndValidate expects the given value to be a base64 encoded string with
+// the username followed by a colon and then the password.  The credentials are
+// valid if the directory accepts a bind with them.
+func (ltf *LDAPTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode string: %v", err)
+	}
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return nil, ErrorMalformedValue
+	}
+	username := string(decoded[:i])
+	password := string(decoded[i+1:])
+	if len(password) == 0 {
+		// most directories treat a bind with an empty password as an
+		// anonymous bind, which succeeds.
+		return nil, ErrorInvalidPassword
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ltf.config.Timeout)
+	defer cancel()
+	conn, err := ltf.get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %v", err)
+	}
+
+	dn := fmt.Sprintf(ltf.config.UserDN, EscapeDN(username))
+	if err := conn.Bind(ctx, dn, password); err != nil {
+		if errors.Is(err, ErrLDAPInvalidCredentials) {
+			ltf.put(conn)
+			return nil, ErrorInvalidPassword
+		}
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind to ldap: %v", err)
+	}
+	groups, err := conn.Groups(ctx, dn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to get ldap groups: %v", err)
+	}
+	ltf.put(conn)
+
+	return bascule.NewToken("basic", username, bascule.NewAttributes(map[string]interface{}{
+		LDAPUserDNKey: dn,
+		LDAPGroupsKey: groups,
+	})), nil
+}
+
+// Close closes the idle connections.
+func (ltf *LDAPTokenFactory) Close() error {
+	var errs bascule.Errors
+	for {
+		select {
+		case conn := <-ltf.pool:
+			if err := conn.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			if len(errs) > 0 {
+				return errs
+			}
+			return nil
+		}
+	}
+}
+
+func (ltf *LDAPTokenFactory) get(ctx context.Context) (LDAPConn, error) {
+	select {
+	case conn := <-ltf.pool:
+		return conn, nil
+	default:
+		return ltf.dial(ctx, ltf.config.Addr, ltf.config.TLS)
+	}
+}
+
+func (ltf *LDAPTokenFactory) put(conn LDAPConn) {
+	select {
+	case ltf.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// EscapeDN escapes the characters that are special in a distinguished name
+// attribute value, following RFC 4514, so user input can't change the
+// structure of the dn.
+func EscapeDN(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';' || c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == 0:
+			b.WriteString("\\00")
+		case (c == ' ' || c == '#') && i == 0, c == ' ' && i == len(value)-1:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
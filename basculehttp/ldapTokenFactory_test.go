@@ -0,0 +1,213 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLDAPConn struct {
+	lock      *sync.Mutex
+	closed    *int
+	bindErr   error
+	groupsErr error
+}
+
+func (c testLDAPConn) Bind(_ context.Context, dn, password string) error {
+	if c.bindErr != nil {
+		return c.bindErr
+	}
+	if dn != "uid=user,ou=people" || password != "pass" {
+		return ErrLDAPInvalidCredentials
+	}
+	return nil
+}
+
+func (c testLDAPConn) Groups(context.Context, string) ([]string, error) {
+	return []string{"admins"}, c.groupsErr
+}
+
+func (c testLDAPConn) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	*c.closed++
+	return nil
+}
+
+func TestLDAPTokenFactory(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		description    string
+		value          string
+		dialErr        error
+		bindErr        error
+		groupsErr      error
+		expectedErr    error
+		expectErr      bool
+		expectedDials  int
+		expectedClosed int
+	}{
+		{
+			description:   "Success",
+			value:         encode("user:pass"),
+			expectedDials: 1,
+		},
+		{
+			description: "Decode Error",
+			value:       "!!!",
+			expectErr:   true,
+		},
+		{
+			description: "Malformed",
+			value:       encode("userpass"),
+			expectedErr: ErrorMalformedValue,
+		},
+		{
+			description: "Empty Password",
+			value:       encode("user:"),
+			expectedErr: ErrorInvalidPassword,
+		},
+		{
+			description:   "Invalid Credentials",
+			value:         encode("user:wrong"),
+			expectedErr:   ErrorInvalidPassword,
+			expectedDials: 1,
+		},
+		{
+			description:   "Dial Error",
+			value:         encode("user:pass"),
+			dialErr:       errors.New("test"),
+			expectErr:     true,
+			expectedDials: 1,
+		},
+		{
+			description:    "Bind Error",
+			value:          encode("user:pass"),
+			bindErr:        errors.New("connection reset"),
+			expectErr:      true,
+			expectedDials:  1,
+			expectedClosed: 1,
+		},
+		{
+			description:    "Groups Error",
+			value:          encode("user:pass"),
+			groupsErr:      errors.New("test"),
+			expectErr:      true,
+			expectedDials:  1,
+			expectedClosed: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				lock   sync.Mutex
+				closed int
+				dials  int
+			)
+			tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+			f, err := NewLDAPTokenFactory(LDAPConfig{
+				Addr:   "ldap.example.com:636",
+				TLS:    tlsConfig,
+				UserDN: "uid=%s,ou=people",
+			}, func(_ context.Context, addr string, c *tls.Config) (LDAPConn, error) {
+				dials++
+				assert.Equal("ldap.example.com:636", addr)
+				assert.Equal(tlsConfig, c)
+				if tc.dialErr != nil {
+					return nil, tc.dialErr
+				}
+				return testLDAPConn{lock: &lock, closed: &closed, bindErr: tc.bindErr, groupsErr: tc.groupsErr}, nil
+			})
+			require.NoError(t, err)
+
+			token, err := f.ParseAndValidate(context.Background(), httptest.NewRequest("GET", "/", nil), "Basic", tc.value)
+			assert.Equal(tc.expectedDials, dials)
+			assert.Equal(tc.expectedClosed, closed)
+			if tc.expectErr || tc.expectedErr != nil {
+				assert.Nil(token)
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("user", token.Principal())
+			groups, ok := token.Attributes().Get(LDAPGroupsKey)
+			assert.True(ok)
+			assert.Equal([]string{"admins"}, groups)
+			dn, _ := token.Attributes().Get(LDAPUserDNKey)
+			assert.Equal("uid=user,ou=people", dn)
+
+			// the connection is reused.
+			_, err = f.ParseAndValidate(context.Background(), httptest.NewRequest("GET", "/", nil), "Basic", tc.value)
+			assert.NoError(err)
+			assert.Equal(1, dials)
+			assert.NoError(f.Close())
+			assert.Equal(1, closed)
+		})
+	}
+}
+
+func TestNewLDAPTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	dial := func(context.Context, string, *tls.Config) (LDAPConn, error) {
+		return nil, nil
+	}
+	_, err := NewLDAPTokenFactory(LDAPConfig{UserDN: "%s"}, nil)
+	assert.ErrorIs(err, ErrNilLDAPDialer)
+	_, err = NewLDAPTokenFactory(LDAPConfig{}, dial)
+	assert.ErrorIs(err, ErrEmptyUserDN)
+
+	f, err := NewLDAPTokenFactory(LDAPConfig{UserDN: "%s"}, dial)
+	assert.NoError(err)
+	assert.Equal(defaultLDAPPoolSize, cap(f.pool))
+	assert.Equal(defaultLDAPTimeout, f.config.Timeout)
+}
+
+func TestEscapeDN(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "user", expected: "user"},
+		{value: "a,ou=admins", expected: `a\,ou\=admins`},
+		{value: `a+b"c\d<e>f;g`, expected: `a\+b\"c\\d\<e\>f\;g`},
+		{value: " #lead", expected: `\ #lead`},
+		{value: "#lead", expected: `\#lead`},
+		{value: "trail ", expected: `trail\ `},
+		{value: "nul\x00", expected: `nul\00`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			assert.Equal(t, tc.expected, EscapeDN(tc.value))
+		})
+	}
+}
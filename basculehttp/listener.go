@@ -18,43 +18,198 @@
 package basculehttp
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/xmidt-org/bascule"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/zap"
 )
 
+// DefaultExpiresInHeader is the header WithLExpiringSoon sets by default.
+const DefaultExpiresInHeader = "X-Token-Expires-In"
+
 // Listener is anything that takes the Authentication information of an
 // authenticated Token.
 type Listener interface {
 	OnAuthenticated(bascule.Authentication)
 }
 
+// LOption is any function that modifies the listener decorator - used to
+// configure it.
+type LOption func(*listenerDecorator)
+
 type listenerDecorator struct {
 	listeners []Listener
+
+	expiringWindow   time.Duration
+	expiresInHeader  string
+	expiringMeasures *ExpiringSoonMeasures
+	assertion        *identityAssertion
+	projector        *Projector
+	server           string
+	now              func() time.Time
+	getLogger        func(context.Context) *zap.Logger
 }
 
 func (l *listenerDecorator) decorate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
 		ctx := request.Context()
+		if Skipped(ctx) {
+			if l.assertion != nil {
+				request.Header.Del(l.assertion.config.Header)
+			}
+			if l.projector != nil {
+				_ = l.projector.Apply(request.Header, nil)
+			}
+			next.ServeHTTP(response, request)
+			return
+		}
+		logger := l.getLogger(ctx)
+		if logger == nil {
+			logger = sallust.Get(ctx)
+		}
+		logger = withRequestIDField(ctx, logger)
 		auth, ok := bascule.FromContext(ctx)
 		if !ok {
+			logger.Error("no authentication found", outcomeFields(OutcomeError, MissingAuthentication)...)
 			response.WriteHeader(http.StatusForbidden)
 			return
 		}
 		for _, listener := range l.listeners {
 			listener.OnAuthenticated(auth)
 		}
+		l.warnExpiringSoon(logger, response, auth.Token)
+		if l.projector != nil {
+			if err := l.projector.Apply(request.Header, auth.Token); err != nil {
+				logger.Error("failed to project token attributes", zap.Error(err))
+				response.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		if l.assertion != nil {
+			if err := l.assertion.assert(request, auth.Token, l.now()); err != nil {
+				logger.Error("failed to add identity assertion", zap.Error(err))
+			}
+		}
 		next.ServeHTTP(response, request)
 
 	})
 }
 
+// warnExpiringSoon tells the client how many seconds its token has left, if
+// it expires within the window, so that it can refresh the token in time.
+func (l *listenerDecorator) warnExpiringSoon(logger *zap.Logger, response http.ResponseWriter, token bascule.Token) {
+	if l.expiringWindow <= 0 {
+		return
+	}
+	exp, ok := tokenExpiration(token)
+	if !ok {
+		return
+	}
+	left := exp.Sub(l.now())
+	if left > l.expiringWindow {
+		return
+	}
+	if left < 0 {
+		left = 0
+	}
+	response.Header().Set(l.expiresInHeader, strconv.FormatInt(int64(left/time.Second), 10))
+	logger.Debug("token expiring soon", zap.String(OutcomeLogKey, string(OutcomeExpiringSoon)),
+		zap.Duration("expiresIn", left))
+	if l.expiringMeasures != nil && l.expiringMeasures.ExpiringSoon != nil {
+		l.expiringMeasures.ExpiringSoon.With(prometheus.Labels{ServerLabel: l.server}).Inc()
+	}
+}
+
 // NewListenerDecorator creates an Alice-style decorator function that acts as
 // middleware, allowing for Listeners to be called after a token has been
 // authenticated.
 func NewListenerDecorator(listeners ...Listener) func(http.Handler) http.Handler {
-	l := &listenerDecorator{}
+	return NewListenerDecoratorWithOptions(WithLListeners(listeners...))
+}
 
-	l.listeners = append(l.listeners, listeners...)
+// NewListenerDecoratorWithOptions creates the same decorator as
+// NewListenerDecorator, configured with the options given.
+func NewListenerDecoratorWithOptions(options ...LOption) func(http.Handler) http.Handler {
+	l := &listenerDecorator{
+		server:    defaultServer,
+		now:       time.Now,
+		getLogger: sallust.Get,
+	}
+	for _, o := range options {
+		if o != nil {
+			o(l)
+		}
+	}
 	return l.decorate
 }
+
+// WithLListeners adds Listeners to be called after a token has been
+// authenticated.
+func WithLListeners(listeners ...Listener) LOption {
+	return func(l *listenerDecorator) {
+		l.listeners = append(l.listeners, listeners...)
+	}
+}
+
+// WithLExpiringSoon sets a header on responses to requests whose token
+// expires within the window given, holding the whole seconds the token has
+// left, so clients can refresh their tokens before they expire.  The header
+// defaults to DefaultExpiresInHeader.  If the measures aren't nil, their
+// counter is incremented for each of these requests.  Tokens without an exp
+// attribute aren't affected.
+func WithLExpiringSoon(window time.Duration, header string, measures *ExpiringSoonMeasures) LOption {
+	return func(l *listenerDecorator) {
+		if window <= 0 {
+			return
+		}
+		if header == "" {
+			header = DefaultExpiresInHeader
+		}
+		l.expiringWindow = window
+		l.expiresInHeader = header
+		l.expiringMeasures = measures
+	}
+}
+
+// WithLServer provides the server label value used by the listener
+// decorator's metrics.
+func WithLServer(s string) LOption {
+	return func(l *listenerDecorator) {
+		if s != "" {
+			l.server = s
+		}
+	}
+}
+
+// WithLLogger sets the function to use to get the logger from the context.
+func WithLLogger(getLogger func(context.Context) *zap.Logger) LOption {
+	return func(l *listenerDecorator) {
+		if getLogger != nil {
+			l.getLogger = getLogger
+		}
+	}
+}
+
+type redactingListener struct {
+	r *bascule.Redactor
+	l Listener
+}
+
+func (rl redactingListener) OnAuthenticated(auth bascule.Authentication) {
+	auth.Token = rl.r.Token(auth.Token)
+	rl.l.OnAuthenticated(auth)
+}
+
+// NewRedactingListener wraps the Listener given so that it only ever sees
+// Tokens with their sensitive values masked by the Redactor.
+func NewRedactingListener(r *bascule.Redactor, l Listener) Listener {
+	if r == nil {
+		return l
+	}
+	return redactingListener{r: r, l: l}
+}
@@ -23,10 +23,16 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"github.com/xmidt-org/bascule"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 var (
@@ -64,3 +70,142 @@ func TestListenerDecorator(t *testing.T) {
 	assert.Equal(http.StatusOK, writer.Code)
 
 }
+
+func TestListenerDecoratorExpiringSoon(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		description    string
+		options        []LOption
+		exp            interface{}
+		expectedHeader string
+		expectedValue  string
+	}{
+		{
+			description:    "Expiring Soon",
+			options:        []LOption{WithLExpiringSoon(5*time.Minute, "", nil)},
+			exp:            float64(now.Add(90 * time.Second).Unix()),
+			expectedHeader: DefaultExpiresInHeader,
+			expectedValue:  "90",
+		},
+		{
+			description:    "Custom Header",
+			options:        []LOption{WithLExpiringSoon(5*time.Minute, "Token-TTL", nil)},
+			exp:            float64(now.Add(5 * time.Minute).Unix()),
+			expectedHeader: "Token-TTL",
+			expectedValue:  "300",
+		},
+		{
+			description:    "Already Expired",
+			options:        []LOption{WithLExpiringSoon(5*time.Minute, "", nil)},
+			exp:            float64(now.Add(-time.Minute).Unix()),
+			expectedHeader: DefaultExpiresInHeader,
+			expectedValue:  "0",
+		},
+		{
+			description:    "Not Expiring Soon",
+			options:        []LOption{WithLExpiringSoon(5*time.Minute, "", nil)},
+			exp:            float64(now.Add(time.Hour).Unix()),
+			expectedHeader: DefaultExpiresInHeader,
+		},
+		{
+			description:    "No Exp",
+			options:        []LOption{WithLExpiringSoon(5*time.Minute, "", nil)},
+			expectedHeader: DefaultExpiresInHeader,
+		},
+		{
+			description:    "Disabled",
+			options:        []LOption{WithLExpiringSoon(0, "", nil)},
+			exp:            float64(now.Add(time.Second).Unix()),
+			expectedHeader: DefaultExpiresInHeader,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			measures := &ExpiringSoonMeasures{
+				ExpiringSoon: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "testExpiringSoon",
+				}, []string{ServerLabel}),
+			}
+			options := append(tc.options,
+				WithLServer("test"),
+				func(l *listenerDecorator) {
+					l.now = func() time.Time { return now }
+					if l.expiringWindow > 0 {
+						l.expiringMeasures = measures
+					}
+				},
+			)
+			handler := NewListenerDecoratorWithOptions(options...)(next)
+
+			attributes := map[string]interface{}{}
+			if tc.exp != nil {
+				attributes[expClaimKey] = tc.exp
+			}
+			u, err := url.ParseRequestURI("/")
+			assert.NoError(err)
+			ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token:         bascule.NewToken("jwt", "alice", bascule.NewAttributes(attributes)),
+				Request:       bascule.Request{URL: u, Method: "get"},
+			})
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, httptest.NewRequest("get", "/", nil).WithContext(ctx))
+			assert.Equal(http.StatusOK, writer.Code)
+			assert.Equal(tc.expectedValue, writer.Header().Get(tc.expectedHeader))
+
+			expectedCount := 0.0
+			if tc.expectedValue != "" {
+				expectedCount = 1.0
+			}
+			assert.Equal(expectedCount, testutil.ToFloat64(measures.ExpiringSoon.With(prometheus.Labels{ServerLabel: "test"})))
+		})
+	}
+}
+
+func TestListenerDecoratorLogger(t *testing.T) {
+	assert := assert.New(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+	now := time.Unix(1700000000, 0)
+	handler := NewListenerDecoratorWithOptions(
+		WithLLogger(func(context.Context) *zap.Logger { return logger }),
+		WithLLogger(nil),
+		WithLExpiringSoon(time.Minute, "", nil),
+		func(l *listenerDecorator) { l.now = func() time.Time { return now } },
+	)(next)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("get", "/", nil))
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token: bascule.NewToken("jwt", "alice", bascule.NewAttributes(map[string]interface{}{
+			expClaimKey: float64(now.Add(30 * time.Second).Unix()),
+		})),
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("get", "/", nil).WithContext(ctx))
+
+	entries := logs.AllUntimed()
+	if assert.Len(entries, 2) {
+		assert.Equal(zapcore.ErrorLevel, entries[0].Level)
+		assert.Equal(string(OutcomeError), entries[0].ContextMap()[OutcomeLogKey])
+		assert.Equal(zapcore.DebugLevel, entries[1].Level)
+		assert.Equal(string(OutcomeExpiringSoon), entries[1].ContextMap()[OutcomeLogKey])
+	}
+}
+
+func TestRedactingListener(t *testing.T) {
+	assert := assert.New(t)
+	r := bascule.NewRedactor(bascule.WithSensitivePrincipal())
+	mockListener := new(mockListener)
+	mockListener.On("OnAuthenticated", mock.MatchedBy(func(a bascule.Authentication) bool {
+		return a.Token.Principal() == bascule.DefaultRedactionMask
+	})).Once()
+
+	l := NewRedactingListener(r, mockListener)
+	l.OnAuthenticated(bascule.Authentication{
+		Authorization: "jwt",
+		Token:         bascule.NewToken("jwt", "secret principal", nil),
+	})
+	mockListener.AssertExpectations(t)
+
+	assert.Equal(mockListener, NewRedactingListener(nil, mockListener))
+}
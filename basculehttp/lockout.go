@@ -0,0 +1,319 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// RetryAfterHeader is the header telling a client how many seconds to
+	// wait before trying again.
+	RetryAfterHeader = "Retry-After"
+
+	defaultLockoutThreshold = 5
+	defaultLockoutWindow    = 15 * time.Minute
+	defaultLockoutDuration  = time.Minute
+)
+
+var ErrNilTokenFactory = errors.New("token factory cannot be nil")
+
+// LockedOutError is returned when a principal or client has failed to
+// authenticate too many times and must wait before trying again.
+type LockedOutError struct {
+	Key        string
+	retryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("too many failed attempts for [%v], retry after %v", e.Key, e.retryAfter)
+}
+
+// RetryAfter returns how long the client should wait before trying again.
+func (e *LockedOutError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// ErrorClass returns bascule.RateLimitedClass.
+func (e *LockedOutError) ErrorClass() bascule.ErrorClass {
+	return bascule.RateLimitedClass
+}
+
+// LockoutRecord is the failure history stored for a principal or client.
+type LockoutRecord struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// LockoutStore stores failed authentication attempts.  Clustered deployments
+// should use a shared implementation so that a lockout applies to every
+// instance.
+type LockoutStore interface {
+	// Get returns the record for the key, or an empty record if there isn't
+	// one.
+	Get(ctx context.Context, key string) (LockoutRecord, error)
+
+	// AddFailure records a failure at the time given.  Failures older than
+	// the window are forgotten.
+	AddFailure(ctx context.Context, key string, at time.Time, window time.Duration) (LockoutRecord, error)
+
+	// Reset forgets the failures for the key.
+	Reset(ctx context.Context, key string) error
+}
+
+// LockoutConfig configures a LockoutTokenFactory.
+type LockoutConfig struct {
+	// PrincipalThreshold is the number of failures for a principal before it
+	// is locked out.  Defaults to 5.  A negative value disables principal
+	// lockouts.
+	PrincipalThreshold int
+
+	// IPThreshold is the number of failures from a client IP before it is
+	// locked out.  If it isn't positive, client IPs aren't locked out.
+	IPThreshold int
+
+	// Window is how long failures are remembered after the last one.
+	// Defaults to 15 minutes.
+	Window time.Duration
+
+	// Duration is how long the first lockout lasts.  Each further failure
+	// doubles it, up to MaxDuration.  Defaults to one minute.
+	Duration time.Duration
+
+	// MaxDuration is the longest a lockout can last.  It can't be longer than
+	// the Window, since the failures causing the lockout are forgotten after
+	// it.  Defaults to the Window.
+	MaxDuration time.Duration
+}
+
+// LockoutTokenFactory protects a password based TokenFactory from brute-force
+// attacks by tracking failures per principal and per client IP.  Once a
+// threshold is reached, requests are rejected with a LockedOutError without
+// calling the wrapped TokenFactory.  Only failures classified as
+// bascule.InvalidClass, like a wrong password or an unknown principal, count
+// towards a lockout; backend outages and timeouts don't, since they say
+// nothing about the credentials.  The store is assumed to be available;
+// if it returns errors, requests are let through so an outage of the store
+// doesn't lock everyone out.
+type LockoutTokenFactory struct {
+	factory TokenFactory
+	config  LockoutConfig
+	store   LockoutStore
+	now     func() time.Time
+}
+
+// NewLockoutTokenFactory wraps the TokenFactory given.  If the store is nil,
+// a StorageLockoutStore with in-memory storage is used.
+func NewLockoutTokenFactory(tf TokenFactory, config LockoutConfig, store LockoutStore) (*LockoutTokenFactory, error) {
+	if tf == nil {
+		return nil, ErrNilTokenFactory
+	}
+	if config.PrincipalThreshold == 0 {
+		config.PrincipalThreshold = defaultLockoutThreshold
+	}
+	if config.Window <= 0 {
+		config.Window = defaultLockoutWindow
+	}
+	if config.Duration <= 0 {
+		config.Duration = defaultLockoutDuration
+	}
+	if config.MaxDuration <= 0 || config.MaxDuration > config.Window {
+		config.MaxDuration = config.Window
+	}
+	if store == nil {
+		store, _ = NewStorageLockoutStore(bascule.NewMemoryStorage())
+	}
+	return &LockoutTokenFactory{
+		factory: tf,
+		config:  config,
+		store:   store,
+		now:     time.Now,
+	}, nil
+}
+
+// ParseAndValidate rejects the request if the principal or client IP is
+// locked out, otherwise it calls the wrapped TokenFactory and records the
+// outcome.  Only invalid credentials are recorded as failures.
+func (l *LockoutTokenFactory) ParseAndValidate(ctx context.Context, r *http.Request, a bascule.Authorization, value string) (bascule.Token, error) {
+	keys := l.keys(r, value)
+	now := l.now()
+	for _, k := range keys {
+		record, err := l.store.Get(ctx, k.key)
+		if err != nil {
+			continue
+		}
+		if until := l.lockedUntil(record, k.threshold); now.Before(until) {
+			return nil, &LockedOutError{Key: k.key, retryAfter: until.Sub(now)}
+		}
+	}
+
+	token, err := l.factory.ParseAndValidate(ctx, r, a, value)
+	if err != nil {
+		if bascule.ClassOf(err) != bascule.InvalidClass {
+			return nil, err
+		}
+		for _, k := range keys {
+			_, _ = l.store.AddFailure(ctx, k.key, now, l.config.Window)
+		}
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.principal {
+			_ = l.store.Reset(ctx, k.key)
+		}
+	}
+	return token, nil
+}
+
+// lockedUntil returns the time the lockout for the record ends, which is in
+// the past if there isn't one.
+func (l *LockoutTokenFactory) lockedUntil(record LockoutRecord, threshold int) time.Time {
+	if record.Failures < threshold {
+		return time.Time{}
+	}
+	d := l.config.Duration
+	for i := threshold; i < record.Failures && d < l.config.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > l.config.MaxDuration {
+		d = l.config.MaxDuration
+	}
+	return record.LastFailure.Add(d)
+}
+
+type lockoutKey struct {
+	key       string
+	threshold int
+	principal bool
+}
+
+func (l *LockoutTokenFactory) keys(r *http.Request, value string) []lockoutKey {
+	var keys []lockoutKey
+	if l.config.PrincipalThreshold > 0 {
+		if principal, ok := basicPrincipal(value); ok {
+			keys = append(keys, lockoutKey{key: "principal:" + principal, threshold: l.config.PrincipalThreshold, principal: true})
+		}
+	}
+	if l.config.IPThreshold > 0 && r != nil {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if host != "" {
+			keys = append(keys, lockoutKey{key: "ip:" + host, threshold: l.config.IPThreshold})
+		}
+	}
+	return keys
+}
+
+// basicPrincipal gets the username from a basic auth value.
+func basicPrincipal(value string) (string, bool) {
+	dv, err := decodeBase64(value)
+	if err != nil {
+		return "", false
+	}
+	defer dv.release()
+	decoded := dv.Decoded
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return "", false
+	}
+	return string(decoded[:i]), true
+}
+
+// StorageLockoutStore is a LockoutStore that keeps its records in a
+// bascule.Storage, so that a shared Storage shares lockouts between instances.
+type StorageLockoutStore struct {
+	storage bascule.Storage
+}
+
+// NewStorageLockoutStore creates a StorageLockoutStore using the storage
+// given.
+func NewStorageLockoutStore(storage bascule.Storage) (*StorageLockoutStore, error) {
+	if storage == nil {
+		return nil, bascule.ErrNilStorage
+	}
+	return &StorageLockoutStore{storage: storage}, nil
+}
+
+func lockoutFailuresKey(key string) string {
+	return "lockout:" + key + ":failures"
+}
+
+func lockoutLastKey(key string) string {
+	return "lockout:" + key + ":last"
+}
+
+// Get returns the record for the key.
+func (s *StorageLockoutStore) Get(ctx context.Context, key string) (LockoutRecord, error) {
+	failures, err := s.getInt(ctx, lockoutFailuresKey(key))
+	if err != nil {
+		return LockoutRecord{}, err
+	}
+	last, err := s.getInt(ctx, lockoutLastKey(key))
+	if err != nil {
+		return LockoutRecord{}, err
+	}
+	if failures == 0 {
+		return LockoutRecord{}, nil
+	}
+	return LockoutRecord{Failures: int(failures), LastFailure: time.Unix(0, last)}, nil
+}
+
+// AddFailure records a failure for the key.  The record expires once the
+// window passes without another failure.
+func (s *StorageLockoutStore) AddFailure(ctx context.Context, key string, at time.Time, window time.Duration) (LockoutRecord, error) {
+	failures, err := s.storage.Incr(ctx, lockoutFailuresKey(key), window)
+	if err != nil {
+		return LockoutRecord{}, err
+	}
+	err = s.storage.Set(ctx, lockoutLastKey(key), []byte(strconv.FormatInt(at.UnixNano(), 10)), window)
+	if err != nil {
+		return LockoutRecord{}, err
+	}
+	return LockoutRecord{Failures: int(failures), LastFailure: at}, nil
+}
+
+// Reset forgets the failures for the key.
+func (s *StorageLockoutStore) Reset(ctx context.Context, key string) error {
+	if err := s.storage.Delete(ctx, lockoutFailuresKey(key)); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, lockoutLastKey(key))
+}
+
+// getInt gets an integer from the storage, which is zero if it isn't there.
+func (s *StorageLockoutStore) getInt(ctx context.Context, key string) (int64, error) {
+	b, err := s.storage.Get(ctx, key)
+	if errors.Is(err, bascule.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(b), 10, 64)
+}
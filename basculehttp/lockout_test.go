@@ -0,0 +1,250 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errStorage struct{}
+
+func (errStorage) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("test")
+}
+
+func (errStorage) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("test")
+}
+
+func (errStorage) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("test")
+}
+
+func (errStorage) Delete(context.Context, string) error {
+	return errors.New("test")
+}
+
+type errLockoutStore struct{}
+
+func (errLockoutStore) Get(context.Context, string) (LockoutRecord, error) {
+	return LockoutRecord{}, errors.New("test")
+}
+
+func (errLockoutStore) AddFailure(context.Context, string, time.Time, time.Duration) (LockoutRecord, error) {
+	return LockoutRecord{}, errors.New("test")
+}
+
+func (errLockoutStore) Reset(context.Context, string) error {
+	return errors.New("test")
+}
+
+func TestLockoutTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	now := time.Unix(1000, 0)
+	f, err := NewLockoutTokenFactory(BasicTokenFactory{"user": "pass"}, LockoutConfig{
+		PrincipalThreshold: 2,
+		IPThreshold:        3,
+		Duration:           time.Minute,
+		MaxDuration:        3 * time.Minute,
+	}, nil)
+	require.NoError(err)
+	f.now = func() time.Time { return now }
+
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	try := func(creds, ip string) error {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		_, err := f.ParseAndValidate(context.Background(), req, BasicAuthorization, encode(creds))
+		return err
+	}
+	retryAfter := func(err error) time.Duration {
+		var locked *LockedOutError
+		require.ErrorAs(err, &locked)
+		return locked.RetryAfter()
+	}
+
+	// success resets the principal's failures.
+	assert.ErrorIs(try("user:wrong", "10.0.0.1"), ErrorInvalidPassword)
+	assert.NoError(try("user:pass", "10.0.0.1"))
+	assert.ErrorIs(try("user:wrong", "10.0.0.1"), ErrorInvalidPassword)
+
+	// the second failure locks out the principal, even with the right
+	// password and from a different client.
+	assert.ErrorIs(try("user:wrong", "10.0.0.2"), ErrorInvalidPassword)
+	err = try("user:pass", "10.0.0.3")
+	assert.Equal(time.Minute, retryAfter(err))
+	assert.Contains(err.Error(), "principal:user")
+
+	// the lockout doubles with each failure, up to the max.
+	now = now.Add(time.Minute)
+	assert.ErrorIs(try("user:wrong", "10.0.0.4"), ErrorInvalidPassword)
+	assert.Equal(2*time.Minute, retryAfter(try("user:pass", "10.0.0.5")))
+	now = now.Add(2 * time.Minute)
+	assert.ErrorIs(try("user:wrong", "10.0.0.4"), ErrorInvalidPassword)
+	assert.Equal(3*time.Minute, retryAfter(try("user:pass", "10.0.0.5")))
+	now = now.Add(3 * time.Minute)
+	assert.NoError(try("user:pass", "10.0.0.5"))
+
+	// client IPs are locked out across principals.
+	assert.ErrorIs(try("a:wrong", "10.0.0.9"), ErrorPrincipalNotFound)
+	assert.ErrorIs(try("b:wrong", "10.0.0.9"), ErrorPrincipalNotFound)
+	assert.Error(try("c:wrong", "10.0.0.9"))
+	err = try("user:pass", "10.0.0.9")
+	assert.Contains(err.Error(), "ip:10.0.0.9")
+	assert.NoError(try("user:pass", "10.0.0.8"))
+
+	// failures that aren't about the credentials aren't counted.
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(try("user", "10.0.0.7"), ErrorMalformedValue)
+	}
+	assert.NoError(try("user:pass", "10.0.0.7"))
+
+	// values that aren't basic auth are only tracked by IP.
+	assert.Len(f.keys(httptest.NewRequest(http.MethodGet, "/", nil), "!!!"), 1)
+}
+
+func TestLockoutTokenFactoryBackendErrors(t *testing.T) {
+	assert := assert.New(t)
+	outage := bascule.NewClassError(bascule.UnavailableClass, "backend unavailable")
+	calls := 0
+	tf := TokenFactoryFunc(func(ctx context.Context, _ *http.Request, _ bascule.Authorization, _ string) (bascule.Token, error) {
+		calls++
+		if calls <= 3 {
+			return nil, outage
+		}
+		if calls == 4 {
+			return nil, context.DeadlineExceeded
+		}
+		return bascule.NewToken("basic", "user", nil), nil
+	})
+	f, err := NewLockoutTokenFactory(tf, LockoutConfig{PrincipalThreshold: 1, IPThreshold: 1}, nil)
+	assert.NoError(err)
+	value := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 3; i++ {
+		_, err = f.ParseAndValidate(context.Background(), req, BasicAuthorization, value)
+		assert.ErrorIs(err, outage)
+	}
+	_, err = f.ParseAndValidate(context.Background(), req, BasicAuthorization, value)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	_, err = f.ParseAndValidate(context.Background(), req, BasicAuthorization, value)
+	assert.NoError(err)
+}
+
+func TestLockoutTokenFactoryStoreErrors(t *testing.T) {
+	assert := assert.New(t)
+	f, err := NewLockoutTokenFactory(BasicTokenFactory{"user": "pass"}, LockoutConfig{PrincipalThreshold: 1}, errLockoutStore{})
+	assert.NoError(err)
+	value := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	for i := 0; i < 3; i++ {
+		_, err = f.ParseAndValidate(context.Background(), nil, BasicAuthorization, value)
+		assert.NoError(err)
+	}
+
+	_, err = NewLockoutTokenFactory(nil, LockoutConfig{}, nil)
+	assert.ErrorIs(err, ErrNilTokenFactory)
+
+	f, err = NewLockoutTokenFactory(BasicTokenFactory{}, LockoutConfig{}, nil)
+	assert.NoError(err)
+	assert.Equal(defaultLockoutThreshold, f.config.PrincipalThreshold)
+	assert.Equal(defaultLockoutWindow, f.config.Window)
+	assert.Equal(defaultLockoutDuration, f.config.Duration)
+	assert.Equal(defaultLockoutWindow, f.config.MaxDuration)
+}
+
+func TestStorageLockoutStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	_, err := NewStorageLockoutStore(nil)
+	assert.ErrorIs(err, bascule.ErrNilStorage)
+
+	storage := bascule.NewMemoryStorage()
+	s, err := NewStorageLockoutStore(storage)
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+
+	r, err := s.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal(LockoutRecord{}, r)
+
+	r, err = s.AddFailure(ctx, "a", now, time.Minute)
+	assert.NoError(err)
+	assert.Equal(LockoutRecord{Failures: 1, LastFailure: now}, r)
+	_, err = s.AddFailure(ctx, "a", now.Add(time.Second), time.Minute)
+	assert.NoError(err)
+	r, err = s.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal(2, r.Failures)
+	assert.True(now.Add(time.Second).Equal(r.LastFailure))
+
+	assert.NoError(s.Reset(ctx, "a"))
+	r, err = s.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal(LockoutRecord{}, r)
+
+	// records expire with the storage.
+	_, err = s.AddFailure(ctx, "b", now, time.Nanosecond)
+	assert.NoError(err)
+	time.Sleep(time.Millisecond)
+	r, err = s.Get(ctx, "b")
+	assert.NoError(err)
+	assert.Equal(LockoutRecord{}, r)
+
+	// storage errors are returned.
+	e, _ := NewStorageLockoutStore(errStorage{})
+	_, err = e.Get(ctx, "a")
+	assert.Error(err)
+	_, err = e.AddFailure(ctx, "a", now, time.Minute)
+	assert.Error(err)
+	assert.Error(e.Reset(ctx, "a"))
+}
+
+func TestConstructorLockedOut(t *testing.T) {
+	assert := assert.New(t)
+	tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+		return nil, &LockedOutError{Key: "principal:user", retryAfter: 1500 * time.Millisecond}
+	})
+	var reason ErrorResponseReason
+	c := NewConstructor(
+		WithTokenFactory(BasicAuthorization, tf),
+		WithCErrorResponseFunc(func(r ErrorResponseReason, _ error) {
+			reason = r
+		}),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultHeaderName, "Basic abc")
+	w := httptest.NewRecorder()
+	c(next).ServeHTTP(w, req)
+	assert.Equal(LockedOut, reason)
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("2", w.Header().Get(RetryAfterHeader))
+}
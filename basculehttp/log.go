@@ -24,7 +24,7 @@ import (
 	"strings"
 
 	"github.com/justinas/alice"
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
 	"github.com/xmidt-org/candlelight"
 	"github.com/xmidt-org/sallust"
 	"go.uber.org/fx"
@@ -0,0 +1,212 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Level is the severity of a message passed to a Logger.
+type Level int8
+
+const (
+	DebugLevel Level = iota - 1
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String returns the lower case name of the level.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", l)
+}
+
+// Logger is the minimal structured logger bascule needs, for consumers that
+// don't log with zap.  Keyvals alternate between string keys and values, as
+// with slog.  Adapters are provided for zap and log/slog; other libraries can
+// be adapted with a LoggerFunc.  For example, with logrus:
+//
+//	basculehttp.LoggerFunc(func(level basculehttp.Level, msg string, keyvals ...interface{}) {
+//		fields := logrus.Fields{}
+//		for i := 0; i+1 < len(keyvals); i += 2 {
+//			fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+//		}
+//		lvl, _ := logrus.ParseLevel(level.String())
+//		logrus.WithFields(fields).Log(lvl, msg)
+//	})
+type Logger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// LevelEnabler may be implemented by a Logger to skip building messages for
+// levels it would drop.  Loggers that don't implement it get every level.
+type LevelEnabler interface {
+	Enabled(Level) bool
+}
+
+// LoggerFunc makes it so any function that has the same signature as
+// Logger's Log function implements Logger.
+type LoggerFunc func(Level, string, ...interface{})
+
+// Log runs the LoggerFunc, making a LoggerFunc also a Logger.
+func (lf LoggerFunc) Log(level Level, msg string, keyvals ...interface{}) {
+	lf(level, msg, keyvals...)
+}
+
+// NewZapLogger returns a zap.Logger that writes to the Logger given, so it
+// can be used wherever bascule accepts a zap.Logger.  Fields are passed to
+// the Logger in the order they were added, after any added with With.
+func NewZapLogger(l Logger) *zap.Logger {
+	if l == nil {
+		return zap.NewNop()
+	}
+	return zap.New(loggerCore{logger: l})
+}
+
+// GetLoggerFunc returns a function that gets a zap.Logger writing to the
+// Logger given, for use with WithCLogger, WithELogger, WithILogger,
+// WithAuthLogger, and the GetLogger fields of the basculechecks configs.
+func GetLoggerFunc(l Logger) func(context.Context) *zap.Logger {
+	logger := NewZapLogger(l)
+	return func(context.Context) *zap.Logger {
+		return logger
+	}
+}
+
+// loggerCore is a zapcore.Core that writes to a Logger.
+type loggerCore struct {
+	logger Logger
+	fields []zapcore.Field
+}
+
+func (c loggerCore) Enabled(lvl zapcore.Level) bool {
+	if e, ok := c.logger.(LevelEnabler); ok {
+		return e.Enabled(fromZapLevel(lvl))
+	}
+	return true
+}
+
+func (c loggerCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	return loggerCore{logger: c.logger, fields: append(all, fields...)}
+}
+
+func (c loggerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c loggerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	keys := make([]string, 0, len(c.fields)+len(fields))
+	seen := make(map[string]bool, cap(keys))
+	for _, group := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range group {
+			f.AddTo(enc)
+			if !seen[f.Key] {
+				seen[f.Key] = true
+				keys = append(keys, f.Key)
+			}
+		}
+	}
+	keyvals := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		if v, ok := enc.Fields[k]; ok {
+			keyvals = append(keyvals, k, v)
+		}
+	}
+	c.logger.Log(fromZapLevel(ent.Level), ent.Message, keyvals...)
+	return nil
+}
+
+func (c loggerCore) Sync() error {
+	return nil
+}
+
+func fromZapLevel(lvl zapcore.Level) Level {
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return DebugLevel
+	case lvl == zapcore.InfoLevel:
+		return InfoLevel
+	case lvl == zapcore.WarnLevel:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+func toZapLevel(l Level) zapcore.Level {
+	switch {
+	case l <= DebugLevel:
+		return zapcore.DebugLevel
+	case l == InfoLevel:
+		return zapcore.InfoLevel
+	case l == WarnLevel:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+type zapAdapter struct {
+	logger *zap.Logger
+}
+
+// NewZapAdapter returns a Logger that writes to the zap.Logger given.
+func NewZapAdapter(l *zap.Logger) Logger {
+	if l == nil {
+		l = zap.NewNop()
+	}
+	return zapAdapter{logger: l}
+}
+
+func (z zapAdapter) Enabled(l Level) bool {
+	return z.logger.Core().Enabled(toZapLevel(l))
+}
+
+func (z zapAdapter) Log(level Level, msg string, keyvals ...interface{}) {
+	ce := z.logger.Check(toZapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	fields := make([]zap.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			fields = append(fields, zap.Any("!BADKEY", keyvals[i]))
+			break
+		}
+		fields = append(fields, zap.Any(fmt.Sprint(keyvals[i]), keyvals[i+1]))
+	}
+	ce.Write(fields...)
+}
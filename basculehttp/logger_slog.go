@@ -0,0 +1,211 @@
+//go:build go1.21
+
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type slogAdapter struct {
+	logger *slog.Logger
+}
+
+// NewSlogAdapter returns a Logger that writes to the slog.Logger given.  A nil
+// slog.Logger writes to slog.Default.
+func NewSlogAdapter(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogAdapter{logger: l}
+}
+
+func (s slogAdapter) Enabled(l Level) bool {
+	return s.logger.Enabled(context.Background(), toSlogLevel(l))
+}
+
+func (s slogAdapter) Log(level Level, msg string, keyvals ...interface{}) {
+	s.logger.Log(context.Background(), toSlogLevel(level), msg, keyvals...)
+}
+
+func toSlogLevel(l Level) slog.Level {
+	switch {
+	case l <= DebugLevel:
+		return slog.LevelDebug
+	case l == InfoLevel:
+		return slog.LevelInfo
+	case l == WarnLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// SLogConfig configures the slog logging of the decorators.
+type SLogConfig struct {
+	// Levels overrides the level messages with an Outcome are logged at.
+	// Outcomes that aren't listed keep the level the decorator logs them at.
+	Levels map[Outcome]slog.Level
+
+	// SampleInterval enables sampling of denials, so that floods of bad
+	// requests don't flood the logs.  Denials with the same outcome and
+	// reason are logged at most SampleBurst times per interval.  The next
+	// denial logged includes how many were dropped.  Sampling is disabled if
+	// the interval isn't positive.
+	SampleInterval time.Duration
+
+	// SampleBurst is the number of denials logged per key per interval.
+	// Defaults to 1.
+	SampleBurst int
+}
+
+// SampledLogKey holds the number of similar denials that were dropped by
+// sampling since the last one was logged.
+const SampledLogKey = "sampled"
+
+type outcomeSLogger struct {
+	logger   *slog.Logger
+	levels   map[Outcome]slog.Level
+	interval time.Duration
+	burst    int
+	now      func() time.Time
+
+	lock    sync.Mutex
+	samples map[string]*sample
+}
+
+type sample struct {
+	start   time.Time
+	logged  int
+	dropped int
+}
+
+// NewSLogger returns a Logger that writes to the slog.Logger given, applying
+// the configured levels by outcome and sampling of denials.
+func NewSLogger(l *slog.Logger, config SLogConfig) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	o := &outcomeSLogger{
+		logger:   l,
+		levels:   config.Levels,
+		interval: config.SampleInterval,
+		burst:    config.SampleBurst,
+		now:      time.Now,
+		samples:  make(map[string]*sample),
+	}
+	if o.burst < 1 {
+		o.burst = 1
+	}
+	return o
+}
+
+// Enabled always reports true, since the level of a message can depend on
+// its outcome.
+func (o *outcomeSLogger) Enabled(Level) bool {
+	return true
+}
+
+func (o *outcomeSLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	outcome, reason := outcomeOf(keyvals)
+	lvl := toSlogLevel(level)
+	if l, ok := o.levels[outcome]; ok {
+		lvl = l
+	}
+	ctx := context.Background()
+	if !o.logger.Enabled(ctx, lvl) {
+		return
+	}
+	if o.interval > 0 && outcome.Denial() {
+		dropped, ok := o.sample(string(outcome) + ":" + reason)
+		if !ok {
+			return
+		}
+		if dropped > 0 {
+			keyvals = append(keyvals, SampledLogKey, dropped)
+		}
+	}
+	o.logger.Log(ctx, lvl, msg, keyvals...)
+}
+
+// sample reports whether a denial with the key given should be logged and,
+// if so, how many were dropped since the last one logged.
+func (o *outcomeSLogger) sample(key string) (int, bool) {
+	now := o.now()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	s, ok := o.samples[key]
+	if !ok {
+		s = &sample{start: now}
+		o.samples[key] = s
+	}
+	if now.Sub(s.start) >= o.interval {
+		s.start = now
+		s.logged = 0
+	}
+	if s.logged >= o.burst {
+		s.dropped++
+		return 0, false
+	}
+	s.logged++
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped, true
+}
+
+func outcomeOf(keyvals []interface{}) (Outcome, string) {
+	var (
+		outcome Outcome
+		reason  string
+	)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case OutcomeLogKey:
+			if v, ok := keyvals[i+1].(string); ok {
+				outcome = Outcome(v)
+			}
+		case ReasonLogKey:
+			reason, _ = keyvals[i+1].(string)
+		}
+	}
+	return outcome, reason
+}
+
+// WithCSLogger sets the constructor to log to the slog.Logger given.
+func WithCSLogger(l *slog.Logger, config SLogConfig) COption {
+	return WithCLogger(GetLoggerFunc(NewSLogger(l, config)))
+}
+
+// WithESLogger sets the enforcer to log to the slog.Logger given.
+func WithESLogger(l *slog.Logger, config SLogConfig) EOption {
+	return WithELogger(GetLoggerFunc(NewSLogger(l, config)))
+}
+
+// WithLSLogger sets the listener decorator to log to the slog.Logger given.
+func WithLSLogger(l *slog.Logger, config SLogConfig) LOption {
+	return WithLLogger(GetLoggerFunc(NewSLogger(l, config)))
+}
+
+// WithSLogger sets the constructor, enforcer, and listener middleware to log
+// to the slog.Logger given.  Sampling is shared between them.
+func WithSLogger(l *slog.Logger, config SLogConfig) AuthOption {
+	return WithAuthLogger(GetLoggerFunc(NewSLogger(l, config)))
+}
@@ -0,0 +1,168 @@
+//go:build go1.21
+
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSlogAdapter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var buf bytes.Buffer
+	l := NewSlogAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger := NewZapLogger(l).With(zap.String("component", "enforcer"))
+	logger.Debug("dropped")
+	logger.Warn("policy evaluation exceeded its budget", zap.Int("cost", 3))
+
+	var entry map[string]interface{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal("WARN", entry["level"])
+	assert.Equal("policy evaluation exceeded its budget", entry["msg"])
+	assert.Equal("enforcer", entry["component"])
+	assert.Equal(float64(3), entry["cost"])
+
+	assert.NotNil(NewSlogAdapter(nil))
+	for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+		assert.Equal(level >= InfoLevel, l.(LevelEnabler).Enabled(level))
+	}
+}
+
+func readSlogEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(s.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSLoggerLevels(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	l := NewSLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})), SLogConfig{
+		Levels: map[Outcome]slog.Level{
+			OutcomeAccepted: slog.LevelInfo,
+			OutcomeDenied:   slog.LevelWarn,
+		},
+	})
+	logger := NewZapLogger(l)
+	logger.Debug("accepted", zap.String(OutcomeLogKey, string(OutcomeAccepted)))
+	logger.Error("denied", outcomeFields(OutcomeDenied, ChecksFailed)...)
+	logger.Debug("authenticated", zap.String(OutcomeLogKey, string(OutcomeAuthenticated)))
+	logger.Error("unauthenticated", outcomeFields(OutcomeUnauthenticated, KeyNotSupported)...)
+
+	entries := readSlogEntries(t, &buf)
+	if assert.Len(entries, 3) {
+		assert.Equal("accepted", entries[0]["msg"])
+		assert.Equal("INFO", entries[0]["level"])
+		assert.Equal("denied", entries[1]["msg"])
+		assert.Equal("WARN", entries[1]["level"])
+		assert.Equal(ChecksFailed.String(), entries[1][ReasonLogKey])
+		assert.Equal("unauthenticated", entries[2]["msg"])
+		assert.Equal("ERROR", entries[2]["level"])
+	}
+}
+
+func TestSLoggerSampling(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	now := time.Unix(1700000000, 0)
+	l := NewSLogger(slog.New(slog.NewJSONHandler(&buf, nil)), SLogConfig{
+		SampleInterval: time.Minute,
+		SampleBurst:    2,
+	})
+	l.(*outcomeSLogger).now = func() time.Time { return now }
+	logger := NewZapLogger(l)
+
+	for i := 0; i < 5; i++ {
+		logger.Error("denied", outcomeFields(OutcomeDenied, ChecksFailed)...)
+	}
+	logger.Error("other reason", outcomeFields(OutcomeDenied, ChecksNotFound)...)
+	logger.Error("not a denial", outcomeFields(OutcomeError, MissingAuthentication)...)
+	logger.Error("not a denial", outcomeFields(OutcomeError, MissingAuthentication)...)
+	logger.Error("not a denial", outcomeFields(OutcomeError, MissingAuthentication)...)
+	now = now.Add(time.Minute)
+	logger.Error("denied", outcomeFields(OutcomeDenied, ChecksFailed)...)
+
+	entries := readSlogEntries(t, &buf)
+	if assert.Len(entries, 7) {
+		assert.Equal("denied", entries[0]["msg"])
+		assert.Nil(entries[0][SampledLogKey])
+		assert.Equal("denied", entries[1]["msg"])
+		assert.Equal("other reason", entries[2]["msg"])
+		assert.Equal("not a denial", entries[5]["msg"])
+		assert.Equal("denied", entries[6]["msg"])
+		assert.Equal(float64(3), entries[6][SampledLogKey])
+	}
+}
+
+func TestWithSLogger(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	config := SLogConfig{SampleInterval: time.Hour}
+	assert.NotNil(WithCSLogger(l, config))
+	assert.NotNil(WithESLogger(l, config))
+	assert.NotNil(WithLSLogger(l, config))
+
+	handler, err := NewAuthHandler(next,
+		WithSLogger(l, config),
+		WithConstructorOptions(WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"})),
+		WithEnforcerOptions(WithRules(BasicAuthorization, bascule.Validators{})),
+	)
+	require.NoError(err)
+
+	for _, value := range []string{"dXNlcjpwYXNz", "bad", "bad", "bad"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DefaultHeaderName, "Basic "+value)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultHeaderName, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:wrong")))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var outcomes []interface{}
+	for _, entry := range readSlogEntries(t, &buf) {
+		outcomes = append(outcomes, entry[OutcomeLogKey])
+	}
+	assert.Equal([]interface{}{
+		string(OutcomeAuthenticated),
+		string(OutcomeAccepted),
+		string(OutcomeUnauthenticated),
+	}, outcomes)
+}
@@ -0,0 +1,115 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type logEntry struct {
+	level   Level
+	msg     string
+	keyvals []interface{}
+}
+
+type recordingLogger struct {
+	entries []logEntry
+	min     Level
+}
+
+func (r *recordingLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	r.entries = append(r.entries, logEntry{level: level, msg: msg, keyvals: keyvals})
+}
+
+func (r *recordingLogger) Enabled(l Level) bool {
+	return l >= r.min
+}
+
+func TestNewZapLogger(t *testing.T) {
+	assert := assert.New(t)
+	r := &recordingLogger{min: DebugLevel}
+	logger := NewZapLogger(r).With(zap.String("component", "constructor"))
+
+	logger.Debug("debug")
+	logger.Info("info", zap.Int("count", 2))
+	logger.Warn("warn", zap.String("component", "enforcer"))
+	logger.Error("error", zap.Error(errors.New("test")), zap.Strings("rules", []string{"a"}))
+
+	assert.Equal([]logEntry{
+		{level: DebugLevel, msg: "debug", keyvals: []interface{}{"component", "constructor"}},
+		{level: InfoLevel, msg: "info", keyvals: []interface{}{"component", "constructor", "count", int64(2)}},
+		{level: WarnLevel, msg: "warn", keyvals: []interface{}{"component", "enforcer"}},
+		{level: ErrorLevel, msg: "error", keyvals: []interface{}{"component", "constructor", "error", "test", "rules", []interface{}{"a"}}},
+	}, r.entries)
+
+	r = &recordingLogger{min: WarnLevel}
+	GetLoggerFunc(r)(context.Background()).Info("dropped")
+	assert.Empty(r.entries)
+
+	assert.NotPanics(func() { NewZapLogger(nil).Error("nowhere") })
+	assert.NoError(NewZapLogger(r).Sync())
+}
+
+func TestLoggerFunc(t *testing.T) {
+	assert := assert.New(t)
+	var got logEntry
+	l := LoggerFunc(func(level Level, msg string, keyvals ...interface{}) {
+		got = logEntry{level: level, msg: msg, keyvals: keyvals}
+	})
+	NewZapLogger(l).Warn("warn", zap.Bool("ok", true))
+	assert.Equal(logEntry{level: WarnLevel, msg: "warn", keyvals: []interface{}{"ok", true}}, got)
+}
+
+func TestZapAdapter(t *testing.T) {
+	assert := assert.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := NewZapAdapter(zap.New(core))
+
+	l.Log(DebugLevel, "dropped")
+	l.Log(WarnLevel, "warn", "principal", "alice", "dangling")
+	l.Log(ErrorLevel, "error", "count", 1)
+
+	entries := logs.AllUntimed()
+	if assert.Len(entries, 2) {
+		assert.Equal(zapcore.WarnLevel, entries[0].Level)
+		assert.Equal(map[string]interface{}{"principal": "alice", "!BADKEY": "dangling"}, entries[0].ContextMap())
+		assert.Equal(zapcore.ErrorLevel, entries[1].Level)
+		assert.Equal(map[string]interface{}{"count": int64(1)}, entries[1].ContextMap())
+	}
+
+	e := l.(LevelEnabler)
+	assert.False(e.Enabled(DebugLevel))
+	assert.True(e.Enabled(ErrorLevel))
+	assert.NotPanics(func() { NewZapAdapter(nil).Log(ErrorLevel, "nowhere") })
+}
+
+func TestLevelString(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("debug", DebugLevel.String())
+	assert.Equal("info", InfoLevel.String())
+	assert.Equal("warn", WarnLevel.String())
+	assert.Equal("error", ErrorLevel.String())
+	assert.Equal("Level(7)", Level(7).String())
+}
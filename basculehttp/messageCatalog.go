@@ -0,0 +1,125 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	acceptLanguageHeader  = "Accept-Language"
+	contentLanguageHeader = "Content-Language"
+)
+
+// MessageCatalog translates the reason a request was denied into a message
+// for a language.  Languages are BCP 47 tags such as "en" or "pt-BR".  It
+// returns false if it has no message for the language.
+type MessageCatalog interface {
+	Message(language string, reason ErrorResponseReason, status int) (string, bool)
+}
+
+// MessageCatalogFunc is a function that implements MessageCatalog.
+type MessageCatalogFunc func(string, ErrorResponseReason, int) (string, bool)
+
+// Message calls the function.
+func (f MessageCatalogFunc) Message(language string, reason ErrorResponseReason, status int) (string, bool) {
+	return f(language, reason, status)
+}
+
+// MapCatalog is a MessageCatalog holding the messages for each reason by
+// language.  Language tags are matched without regard to case, and a
+// regional tag such as "fr-CA" falls back to its base language "fr".
+type MapCatalog map[string]map[ErrorResponseReason]string
+
+// Message returns the message for the reason in the language given.
+func (m MapCatalog) Message(language string, reason ErrorResponseReason, _ int) (string, bool) {
+	for {
+		for tag, messages := range m {
+			if !strings.EqualFold(tag, language) {
+				continue
+			}
+			if msg, ok := messages[reason]; ok {
+				return msg, true
+			}
+		}
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			return "", false
+		}
+		language = language[:i]
+	}
+}
+
+// languageRange is a language range from an Accept-Language header.
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage returns the language tags of the header, most
+// preferred first.  The "*" range and ranges that can't be parsed or aren't
+// acceptable are dropped.
+func parseAcceptLanguage(header string) []string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if name != "q" {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				q = 0
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// localize returns the message for the first language the catalog has one
+// for, and that language.  It returns false if there is none.
+func localize(catalog MessageCatalog, languages []string, reason ErrorResponseReason, status int) (string, string, bool) {
+	if catalog == nil {
+		return "", "", false
+	}
+	for _, language := range languages {
+		if msg, ok := catalog.Message(language, reason, status); ok {
+			return msg, language, true
+		}
+	}
+	return "", "", false
+}
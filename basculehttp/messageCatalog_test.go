@@ -0,0 +1,164 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalog = MapCatalog{
+	"fr": {
+		MissingHeader: "Identifiants manquants",
+	},
+	"pt-BR": {
+		MissingHeader: "Credenciais ausentes",
+	},
+	"pt": {
+		MissingHeader: "Credenciais em falta",
+		ChecksFailed:  "Acesso negado",
+	},
+}
+
+func TestMapCatalog(t *testing.T) {
+	tests := []struct {
+		description     string
+		language        string
+		reason          ErrorResponseReason
+		expectedMessage string
+		expectedOK      bool
+	}{
+		{
+			description:     "Exact",
+			language:        "fr",
+			reason:          MissingHeader,
+			expectedMessage: "Identifiants manquants",
+			expectedOK:      true,
+		},
+		{
+			description:     "Case Insensitive",
+			language:        "PT-br",
+			reason:          MissingHeader,
+			expectedMessage: "Credenciais ausentes",
+			expectedOK:      true,
+		},
+		{
+			description:     "Region Fallback",
+			language:        "fr-CA",
+			reason:          MissingHeader,
+			expectedMessage: "Identifiants manquants",
+			expectedOK:      true,
+		},
+		{
+			description:     "Reason Fallback",
+			language:        "pt-BR",
+			reason:          ChecksFailed,
+			expectedMessage: "Acesso negado",
+			expectedOK:      true,
+		},
+		{
+			description: "Missing Reason",
+			language:    "fr",
+			reason:      ChecksFailed,
+		},
+		{
+			description: "Missing Language",
+			language:    "de-DE",
+			reason:      MissingHeader,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			msg, ok := testCatalog.Message(tc.language, tc.reason, http.StatusUnauthorized)
+			assert.Equal(tc.expectedOK, ok)
+			assert.Equal(tc.expectedMessage, msg)
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{header: "", expected: []string{}},
+		{header: "fr", expected: []string{"fr"}},
+		{header: "de;q=0.5, fr-CA, *;q=0.1", expected: []string{"fr-CA", "de"}},
+		{header: "en;q=0, es;q=bad, pt ; q=0.8", expected: []string{"pt"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseAcceptLanguage(tc.header))
+		})
+	}
+}
+
+func TestLocalizedErrorBody(t *testing.T) {
+	tests := []struct {
+		description      string
+		acceptLanguage   string
+		catalog          MessageCatalog
+		expectedBody     string
+		expectedLanguage string
+	}{
+		{
+			description:      "Localized",
+			acceptLanguage:   "de, pt-BR;q=0.9, fr;q=0.8",
+			catalog:          testCatalog,
+			expectedBody:     "401 Credenciais ausentes: missing_header\n",
+			expectedLanguage: "pt-BR",
+		},
+		{
+			description:    "No Translation",
+			acceptLanguage: "de",
+			catalog:        testCatalog,
+			expectedBody:   "401 Unauthorized: missing_header\n",
+		},
+		{
+			description:    "No Catalog",
+			acceptLanguage: "fr",
+			expectedBody:   "401 Unauthorized: missing_header\n",
+		},
+		{
+			description:    "Func",
+			acceptLanguage: "en",
+			catalog: MessageCatalogFunc(func(language string, _ ErrorResponseReason, status int) (string, bool) {
+				return http.StatusText(status) + " (" + language + ")", true
+			}),
+			expectedBody:     "401 Unauthorized (en): missing_header\n",
+			expectedLanguage: "en",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(acceptHeader, TextMediaType)
+			req.Header.Set(acceptLanguageHeader, tc.acceptLanguage)
+			recorder := httptest.NewRecorder()
+			eb := newErrorBodies(ErrorBodyConfig{Catalog: tc.catalog})
+			eb.writer(recorder, req, MissingHeader).WriteHeader(http.StatusUnauthorized)
+			assert.Equal(tc.expectedBody, recorder.Body.String())
+			assert.Equal(tc.expectedLanguage, recorder.Header().Get(contentLanguageHeader))
+		})
+	}
+}
@@ -22,7 +22,8 @@ import (
 
 	"github.com/justinas/alice"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/xmidt-org/bascule"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculemetrics"
 	"go.uber.org/fx"
 )
 
@@ -42,6 +43,8 @@ var (
 type MetricListener struct {
 	server   string
 	measures *AuthValidationMeasures
+	buffer   *basculemetrics.BufferedCounterVec
+	kind     bool
 }
 
 // Option is how the MetricListener is configured.
@@ -64,21 +67,36 @@ func (m *MetricListener) OnAuthenticated(auth bascule.Authentication) {
 	if auth.Token == nil {
 		outcome = EmptyOutcome
 	}
-	m.measures.ValidationOutcome.
-		With(prometheus.Labels{
-			ServerLabel:  m.server,
-			OutcomeLabel: outcome,
-		}).
-		Add(1)
+	labels := prometheus.Labels{
+		ServerLabel:  m.server,
+		OutcomeLabel: outcome,
+	}
+	if m.kind {
+		labels[TokenKindLabel] = string(bascule.KindOf(auth.Token))
+	}
+	m.record(labels)
 }
 
 // OnErrorResponse is called if the constructor or enforcer have a problem with
 // authenticating/authorizing the request.  The ErrorResponseReason is used as
 // the outcome label value in a metric.
 func (m *MetricListener) OnErrorResponse(e ErrorResponseReason, _ error) {
-	m.measures.ValidationOutcome.
-		With(prometheus.Labels{ServerLabel: m.server, OutcomeLabel: e.String()}).
-		Add(1)
+	labels := prometheus.Labels{ServerLabel: m.server, OutcomeLabel: e.String()}
+	if m.kind {
+		// there is no token to classify when a request is rejected.
+		labels[TokenKindLabel] = string(bascule.UnknownKind)
+	}
+	m.record(labels)
+}
+
+// record increments the outcome counter, going through the buffer if one is
+// configured.
+func (m *MetricListener) record(labels prometheus.Labels) {
+	if m.buffer != nil {
+		m.buffer.Inc(labels)
+		return
+	}
+	m.measures.ValidationOutcome.With(labels).Add(1)
 }
 
 // WithServer provides the server label value to be used by all MetricListener
@@ -91,6 +109,27 @@ func WithServer(s string) Option {
 	}
 }
 
+// WithBuffer sends outcome counter increments through the buffer given rather
+// than directly to the measures' counter.  The buffer should wrap the same
+// CounterVec as the measures, and its lifecycle is the caller's
+// responsibility.
+func WithBuffer(b *basculemetrics.BufferedCounterVec) Option {
+	return func(m *MetricListener) {
+		if b != nil {
+			m.buffer = b
+		}
+	}
+}
+
+// WithTokenKindLabel adds the token kind label to the outcome metric.  The
+// measures must have been created with the label, as ProvideMetricsWithTokenKind
+// does.
+func WithTokenKindLabel() Option {
+	return func(m *MetricListener) {
+		m.kind = true
+	}
+}
+
 // NewMetricListener creates a new MetricListener that uses the measures
 // provided and is configured with the given options. The measures cannot be
 // nil.
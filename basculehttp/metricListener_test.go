@@ -23,8 +23,11 @@ import (
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculemetrics"
 	"github.com/stretchr/testify/assert"
-	"github.com/xmidt-org/bascule"
+	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/touchstone/touchtest"
 )
 
@@ -205,3 +208,47 @@ func TestOnErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricListenerBuffer(t *testing.T) {
+	assert := assert.New(t)
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "testCounter",
+		Help: "testCounter",
+	}, []string{ServerLabel, OutcomeLabel})
+	b, err := basculemetrics.NewBufferedCounterVec(vec)
+	require.NoError(t, err)
+
+	m, err := NewMetricListener(&AuthValidationMeasures{ValidationOutcome: vec},
+		WithServer(testServerName), WithBuffer(b), WithBuffer(nil))
+	require.NoError(t, err)
+	m.OnAuthenticated(bascule.Authentication{Token: bascule.NewToken("", "", nil)})
+	m.OnErrorResponse(ChecksFailed, errors.New("test"))
+
+	accepted := prometheus.Labels{ServerLabel: testServerName, OutcomeLabel: AcceptedOutcome}
+	failed := prometheus.Labels{ServerLabel: testServerName, OutcomeLabel: ChecksFailed.String()}
+	assert.Equal(0, testutil.CollectAndCount(vec))
+	b.Flush()
+	assert.Equal(1.0, testutil.ToFloat64(vec.With(accepted)))
+	assert.Equal(1.0, testutil.ToFloat64(vec.With(failed)))
+}
+
+func TestMetricListenerTokenKindLabel(t *testing.T) {
+	assert := assert.New(t)
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "testCounter",
+		Help: "testCounter",
+	}, []string{ServerLabel, OutcomeLabel, TokenKindLabel})
+
+	m, err := NewMetricListener(&AuthValidationMeasures{ValidationOutcome: vec}, WithTokenKindLabel())
+	require.NoError(t, err)
+	m.OnAuthenticated(bascule.Authentication{Token: bascule.NewToken("", "", bascule.NewAttributes(
+		map[string]interface{}{bascule.TokenKindKey: bascule.ServiceKind}))})
+	m.OnErrorResponse(ChecksFailed, errors.New("test"))
+
+	assert.Equal(1.0, testutil.ToFloat64(vec.With(prometheus.Labels{
+		ServerLabel: defaultServer, OutcomeLabel: AcceptedOutcome, TokenKindLabel: string(bascule.ServiceKind),
+	})))
+	assert.Equal(1.0, testutil.ToFloat64(vec.With(prometheus.Labels{
+		ServerLabel: defaultServer, OutcomeLabel: ChecksFailed.String(), TokenKindLabel: string(bascule.UnknownKind),
+	})))
+}
@@ -26,14 +26,30 @@ import (
 // Names for our metrics
 const (
 	AuthValidationOutcome = "auth_validation"
+	AuthTokenExpiringSoon = "auth_token_expiring_soon"
+	AuthAnomalies         = "auth_anomalies"
+	AuthRevocationChecks  = "auth_revocation_checks"
+	AuthRuleFailures      = "auth_rule_failures"
 )
 
 // labels
 const (
-	OutcomeLabel = "outcome"
-	ServerLabel  = "server"
+	OutcomeLabel   = "outcome"
+	ServerLabel    = "server"
+	TokenKindLabel = "kind"
+
+	AnomalyKindLabel = "anomaly"
+
+	RevocationMethodLabel = "method"
+	RevocationStatusLabel = "status"
+
+	RuleLabel = "rule"
 )
 
+// UnnamedRule is the rule label value used when a request is denied by rules
+// that weren't given names with bascule.Named.
+const UnnamedRule = "unnamed"
+
 // outcome values other than error response reasons
 const (
 	AcceptedOutcome = "accepted"
@@ -43,6 +59,10 @@ const (
 // help messages
 const (
 	authValidationOutcomeHelpMsg = "Counter for success and failure reason results through bascule"
+	authTokenExpiringSoonHelpMsg = "Counter for authenticated requests whose token expires soon"
+	authAnomaliesHelpMsg         = "Counter for principals and client IPs whose failure rate crossed the anomaly threshold"
+	authRevocationChecksHelpMsg  = "Counter for certificate revocation check results by method and status"
+	authRuleFailuresHelpMsg      = "Counter for enforcer rule failures by rule name"
 )
 
 // ProvideMetrics provides the metrics relevant to this package as uber/fx
@@ -59,9 +79,105 @@ func ProvideMetrics() fx.Option {
 	)
 }
 
+// ProvideMetricsWithTokenKind provides the same metrics as ProvideMetrics, but
+// with an additional token kind label.  It should be used in place of
+// ProvideMetrics along with the WithTokenKindLabel MetricListener option.
+func ProvideMetricsWithTokenKind() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(
+			prometheus.CounterOpts{
+				Name:        AuthValidationOutcome,
+				Help:        authValidationOutcomeHelpMsg,
+				ConstLabels: nil,
+			}, ServerLabel, OutcomeLabel, TokenKindLabel),
+	)
+}
+
 // AuthValidationMeasures describes the defined metrics that will be used by clients
 type AuthValidationMeasures struct {
 	fx.In
 
 	ValidationOutcome *prometheus.CounterVec `name:"auth_validation"`
 }
+
+// ProvideExpiringSoonMetrics provides the metrics used by the WithLExpiringSoon
+// listener decorator option as uber/fx options.
+func ProvideExpiringSoonMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(
+			prometheus.CounterOpts{
+				Name:        AuthTokenExpiringSoon,
+				Help:        authTokenExpiringSoonHelpMsg,
+				ConstLabels: nil,
+			}, ServerLabel),
+	)
+}
+
+// ExpiringSoonMeasures describes the metrics used by the WithLExpiringSoon
+// listener decorator option.
+type ExpiringSoonMeasures struct {
+	fx.In
+
+	ExpiringSoon *prometheus.CounterVec `name:"auth_token_expiring_soon"`
+}
+
+// ProvideAnomalyMetrics provides the metrics used by the AnomalyDetector as
+// uber/fx options.
+func ProvideAnomalyMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(
+			prometheus.CounterOpts{
+				Name:        AuthAnomalies,
+				Help:        authAnomaliesHelpMsg,
+				ConstLabels: nil,
+			}, ServerLabel, AnomalyKindLabel),
+	)
+}
+
+// AnomalyMeasures describes the metrics used by the AnomalyDetector.
+type AnomalyMeasures struct {
+	fx.In
+
+	Anomalies *prometheus.CounterVec `name:"auth_anomalies"`
+}
+
+// ProvideRevocationMetrics provides the metrics used by the RevocationChecker
+// as uber/fx options.
+func ProvideRevocationMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(
+			prometheus.CounterOpts{
+				Name:        AuthRevocationChecks,
+				Help:        authRevocationChecksHelpMsg,
+				ConstLabels: nil,
+			}, ServerLabel, RevocationMethodLabel, RevocationStatusLabel),
+	)
+}
+
+// RevocationMeasures describes the metrics used by the RevocationChecker.
+type RevocationMeasures struct {
+	fx.In
+
+	RevocationChecks *prometheus.CounterVec `name:"auth_revocation_checks"`
+}
+
+// ProvideRuleMetrics provides the metrics used by the WithERuleMetrics
+// enforcer option as uber/fx options.
+func ProvideRuleMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(
+			prometheus.CounterOpts{
+				Name:        AuthRuleFailures,
+				Help:        authRuleFailuresHelpMsg,
+				ConstLabels: nil,
+			}, ServerLabel, RuleLabel),
+	)
+}
+
+// RuleMeasures describes the metrics used by the WithERuleMetrics enforcer
+// option.
+type RuleMeasures struct {
+	fx.In
+
+	RuleFailures *prometheus.CounterVec `name:"auth_rule_failures"`
+}
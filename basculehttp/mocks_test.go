@@ -20,8 +20,8 @@ package basculehttp
 import (
 	"crypto"
 
+	"github.com/s-srakshe/bascule"
 	"github.com/stretchr/testify/mock"
-	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/clortho"
 
 	"context"
@@ -10,11 +10,14 @@ func _() {
 	var x [1]struct{}
 	_ = x[Forbid-0]
 	_ = x[Allow-1]
+	_ = x[Challenge-2]
+	_ = x[Handle-3]
+	_ = x[Defer-4]
 }
 
-const _NotFoundBehavior_name = "ForbidAllow"
+const _NotFoundBehavior_name = "ForbidAllowChallengeHandleDefer"
 
-var _NotFoundBehavior_index = [...]uint8{0, 6, 11}
+var _NotFoundBehavior_index = [...]uint8{0, 6, 11, 20, 26, 31}
 
 func (i NotFoundBehavior) String() string {
 	if i < 0 || i >= NotFoundBehavior(len(_NotFoundBehavior_index)-1) {
@@ -0,0 +1,71 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import "go.uber.org/zap"
+
+// Log keys added to the decorators' log messages, so that loggers can treat
+// messages differently by outcome.
+const (
+	OutcomeLogKey = "outcome"
+	ReasonLogKey  = "reason"
+)
+
+// Outcome is what a decorator decided for a request, as logged at
+// OutcomeLogKey.
+type Outcome string
+
+const (
+	// OutcomeAuthenticated is logged by the constructor when a request's
+	// credentials were parsed and validated.
+	OutcomeAuthenticated Outcome = "authenticated"
+
+	// OutcomeUnauthenticated is logged by the constructor when a request's
+	// credentials were missing or invalid.
+	OutcomeUnauthenticated Outcome = "unauthenticated"
+
+	// OutcomeAccepted is logged by the enforcer when a request passed its
+	// rules.
+	OutcomeAccepted Outcome = "accepted"
+
+	// OutcomeDenied is logged by the enforcer when a request failed its
+	// rules.
+	OutcomeDenied Outcome = "denied"
+
+	// OutcomeNoRules is logged by the enforcer when there are no rules for a
+	// request's authorization, before its NotFoundBehavior is followed.
+	OutcomeNoRules Outcome = "no_rules"
+
+	// OutcomeExpiringSoon is logged by the listener decorator when a
+	// request's token expires within the WithLExpiringSoon window.
+	OutcomeExpiringSoon Outcome = "expiring_soon"
+
+	// OutcomeError is logged when a request couldn't be decided because of a
+	// misconfiguration or failure, rather than its credentials.
+	OutcomeError Outcome = "error"
+)
+
+// Denial reports whether the outcome is a request being turned away for its
+// credentials.  Denials are what floods of bad requests produce.
+func (o Outcome) Denial() bool {
+	return o == OutcomeUnauthenticated || o == OutcomeDenied
+}
+
+func outcomeFields(o Outcome, reason ErrorResponseReason) []zap.Field {
+	return []zap.Field{zap.String(OutcomeLogKey, string(o)), zap.String(ReasonLogKey, reason.String())}
+}
@@ -0,0 +1,34 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutcomeDenial(t *testing.T) {
+	assert := assert.New(t)
+	for _, o := range []Outcome{OutcomeUnauthenticated, OutcomeDenied} {
+		assert.True(o.Denial(), o)
+	}
+	for _, o := range []Outcome{OutcomeAuthenticated, OutcomeAccepted, OutcomeNoRules, OutcomeExpiringSoon, OutcomeError, ""} {
+		assert.False(o.Denial(), o)
+	}
+}
@@ -0,0 +1,184 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+var (
+	ErrUnsupportedHash = errors.New("unsupported password hash format")
+	ErrMalformedHash   = errors.New("malformed password hash")
+	ErrInvalidParams   = errors.New("invalid password hash parameters")
+)
+
+// Argon2idParams are the cost parameters used to create an argon2id hash.
+type Argon2idParams struct {
+	// Memory is the amount of memory used, in KiB.
+	Memory uint32
+
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+
+	// Parallelism is the number of threads used.
+	Parallelism uint8
+
+	// SaltLength is the length of the random salt, in bytes.
+	SaltLength uint32
+
+	// KeyLength is the length of the hash, in bytes.
+	KeyLength uint32
+}
+
+// DefaultArgon2idParams returns the parameters recommended by RFC 9106 for
+// environments with limited memory.
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// validate makes sure none of the cost parameters are zero, which argon2
+// can't hash with.
+func (p Argon2idParams) validate() error {
+	switch {
+	case p.Memory == 0:
+		return fmt.Errorf("%w: memory must be positive", ErrInvalidParams)
+	case p.Iterations == 0:
+		return fmt.Errorf("%w: iterations must be positive", ErrInvalidParams)
+	case p.Parallelism == 0:
+		return fmt.Errorf("%w: parallelism must be positive", ErrInvalidParams)
+	case p.KeyLength == 0:
+		return fmt.Errorf("%w: key length must be positive", ErrInvalidParams)
+	}
+	return nil
+}
+
+// HashArgon2id hashes the password with argon2id and a random salt, returning
+// the hash in the standard encoded form, such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.  It returns ErrInvalidParams
+// if the memory, iterations, parallelism, or key length is zero.
+func HashArgon2id(password string, p Argon2idParams) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPasswordHash checks the password against the hash given, which may be
+// a bcrypt hash or an encoded argon2id hash.  ErrorInvalidPassword is returned
+// if the password doesn't match.
+func VerifyPasswordHash(hash, password string) error {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrorInvalidPassword
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedHash, err)
+		}
+		return nil
+	case strings.HasPrefix(hash, argon2idPrefix):
+		return verifyArgon2id(hash, password)
+	}
+	return ErrUnsupportedHash
+}
+
+// ValidatePasswordHash checks that the hash given is in a supported format.
+func ValidatePasswordHash(hash string) error {
+	switch {
+	case isBcrypt(hash):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedHash, err)
+		}
+		return nil
+	case strings.HasPrefix(hash, argon2idPrefix):
+		_, _, _, err := parseArgon2id(hash)
+		return err
+	}
+	return ErrUnsupportedHash
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func parseArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+	parts := strings.Split(hash, "$")
+	// the hash starts with a $, so the first part is empty.
+	if len(parts) != 6 {
+		return p, nil, nil, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: unsupported version [%v]", ErrMalformedHash, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("%w: invalid key", ErrMalformedHash)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	if err := p.validate(); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+	return p, salt, key, nil
+}
+
+func verifyArgon2id(hash, password string) error {
+	p, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrorInvalidPassword
+	}
+	return nil
+}
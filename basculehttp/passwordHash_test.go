@@ -0,0 +1,177 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  8,
+	KeyLength:   16,
+}
+
+func TestVerifyPasswordHash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	argonHash, err := HashArgon2id("pass", testArgon2idParams)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(argonHash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	tests := []struct {
+		description   string
+		hash          string
+		password      string
+		expectedErr   error
+		expectedValid error
+	}{
+		{
+			description: "Bcrypt Success",
+			hash:        string(bcryptHash),
+			password:    "pass",
+		},
+		{
+			description: "Bcrypt Mismatch",
+			hash:        string(bcryptHash),
+			password:    "wrong",
+			expectedErr: ErrorInvalidPassword,
+		},
+		{
+			description:   "Bcrypt Malformed",
+			hash:          "$2a$10$short",
+			password:      "pass",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description: "Argon2id Success",
+			hash:        argonHash,
+			password:    "pass",
+		},
+		{
+			description: "Argon2id Mismatch",
+			hash:        argonHash,
+			password:    "wrong",
+			expectedErr: ErrorInvalidPassword,
+		},
+		{
+			description:   "Argon2id Wrong Parts",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$salt",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Wrong Version",
+			hash:          "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Bad Params",
+			hash:          "$argon2id$v=19$m=x$c2FsdA$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Zero Memory",
+			hash:          "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Zero Iterations",
+			hash:          "$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Zero Parallelism",
+			hash:          "$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Bad Salt",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$!!$a2V5",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Argon2id Bad Key",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+			expectedErr:   ErrMalformedHash,
+			expectedValid: ErrMalformedHash,
+		},
+		{
+			description:   "Plaintext",
+			hash:          "pass",
+			password:      "pass",
+			expectedErr:   ErrUnsupportedHash,
+			expectedValid: ErrUnsupportedHash,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			err := VerifyPasswordHash(tc.hash, tc.password)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+			} else {
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+			err = ValidatePasswordHash(tc.hash)
+			if tc.expectedValid == nil {
+				assert.NoError(err)
+			} else {
+				assert.ErrorIs(err, tc.expectedValid)
+			}
+		})
+	}
+}
+
+func TestHashArgon2idSalt(t *testing.T) {
+	a, err := HashArgon2id("pass", testArgon2idParams)
+	require.NoError(t, err)
+	b, err := HashArgon2id("pass", testArgon2idParams)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, uint32(65536), DefaultArgon2idParams().Memory)
+}
+
+func TestHashArgon2idInvalidParams(t *testing.T) {
+	for _, p := range []Argon2idParams{
+		{Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 16},
+		{Memory: 64, Parallelism: 1, SaltLength: 8, KeyLength: 16},
+		{Memory: 64, Iterations: 1, SaltLength: 8, KeyLength: 16},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 8},
+	} {
+		assert.NotPanics(t, func() {
+			hash, err := HashArgon2id("pass", p)
+			assert.Empty(t, hash)
+			assert.ErrorIs(t, err, ErrInvalidParams)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// buffers larger than this aren't pooled, so that one huge header
+	// doesn't keep a huge buffer alive.
+	maxPooledBufferSize = 4096
+
+	// defaultClaimsCapacity is the size claims maps are created with, which
+	// holds a typical JWT's claims without the map growing.
+	defaultClaimsCapacity = 16
+)
+
+// decodeBuffers holds the buffers credentials are base64 decoded into, so
+// that decoding doesn't allocate on every request.
+var decodeBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// decodedValue is a base64 decoded credential in a pooled buffer.  It must be
+// released once it is no longer used.
+type decodedValue struct {
+	buf     *[]byte
+	Decoded []byte
+}
+
+// decodeBase64 decodes the standard base64 value given into a pooled buffer.
+// Since the value is often a secret, the buffer is zeroed when it's
+// released.
+func decodeBase64(value string) (decodedValue, error) {
+	n := len(value) + base64.StdEncoding.DecodedLen(len(value))
+	bp := decodeBuffers.Get().(*[]byte)
+	if cap(*bp) < n {
+		*bp = make([]byte, n)
+	}
+	buf := (*bp)[:n]
+	// copying the value avoids the allocation of converting it to []byte.
+	src := buf[:copy(buf, value)]
+	dst := buf[len(value):]
+	d, err := base64.StdEncoding.Decode(dst, src)
+	dv := decodedValue{buf: bp, Decoded: dst[:d]}
+	if err != nil {
+		dv.release()
+		return decodedValue{}, err
+	}
+	return dv, nil
+}
+
+// release zeroes the buffer and returns it to the pool.
+func (dv decodedValue) release() {
+	if dv.buf == nil {
+		return
+	}
+	b := (*dv.buf)[:cap(*dv.buf)]
+	bascule.Zero(b)
+	if len(b) > maxPooledBufferSize {
+		return
+	}
+	*dv.buf = b[:0]
+	decodeBuffers.Put(dv.buf)
+}
+
+// splitAuthorization splits an authorization header into its scheme and
+// value at the first delimiter, returning false if there's no scheme.  The
+// usual single space delimiter takes a byte search instead of a substring
+// search.
+func splitAuthorization(header, delimiter string) (string, string, bool) {
+	var i int
+	if len(delimiter) == 1 {
+		i = strings.IndexByte(header, delimiter[0])
+	} else {
+		i = strings.Index(header, delimiter)
+	}
+	if i < 1 {
+		return "", "", false
+	}
+	return header[:i], header[i+len(delimiter):], true
+}
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBase64(t *testing.T) {
+	tests := []struct {
+		description string
+		value       string
+		expectedErr bool
+	}{
+		{
+			description: "Empty",
+		},
+		{
+			description: "Basic Auth",
+			value:       base64.StdEncoding.EncodeToString([]byte("user:pass")),
+		},
+		{
+			description: "Larger Than Pooled Buffers",
+			value:       base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 2*maxPooledBufferSize))),
+		},
+		{
+			description: "Invalid",
+			value:       "not base64!",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			expected, expectedErr := base64.StdEncoding.DecodeString(tc.value)
+			dv, err := decodeBase64(tc.value)
+			if tc.expectedErr {
+				assert.Error(err)
+				assert.Equal(expectedErr, err)
+				assert.Nil(dv.Decoded)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(expected, dv.Decoded)
+
+			buf := (*dv.buf)[:cap(*dv.buf)]
+			dv.release()
+			for _, b := range buf {
+				if b != 0 {
+					assert.Fail("buffer wasn't zeroed")
+					break
+				}
+			}
+		})
+	}
+
+	// releasing an empty value is harmless.
+	decodedValue{}.release()
+}
+
+func TestSplitAuthorization(t *testing.T) {
+	tests := []struct {
+		description    string
+		header         string
+		delimiter      string
+		expectedScheme string
+		expectedValue  string
+		expectedOK     bool
+	}{
+		{
+			description:    "Space",
+			header:         "Basic abc def",
+			delimiter:      " ",
+			expectedScheme: "Basic",
+			expectedValue:  "abc def",
+			expectedOK:     true,
+		},
+		{
+			description:    "Multiple Byte Delimiter",
+			header:         "Basic::abc",
+			delimiter:      "::",
+			expectedScheme: "Basic",
+			expectedValue:  "abc",
+			expectedOK:     true,
+		},
+		{
+			description:    "Empty Value",
+			header:         "Basic ",
+			delimiter:      " ",
+			expectedScheme: "Basic",
+			expectedOK:     true,
+		},
+		{
+			description: "No Delimiter",
+			header:      "Basic",
+			delimiter:   " ",
+		},
+		{
+			description: "No Scheme",
+			header:      " abc",
+			delimiter:   " ",
+		},
+		{
+			description: "Empty Delimiter",
+			header:      "Basic abc",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			scheme, value, ok := splitAuthorization(tc.header, tc.delimiter)
+			assert.Equal(tc.expectedScheme, scheme)
+			assert.Equal(tc.expectedValue, value)
+			assert.Equal(tc.expectedOK, ok)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+// Attributes with special meaning in a Projection.
+const (
+	// PrincipalAttribute projects the token's principal.
+	PrincipalAttribute = "$principal"
+
+	// TokenTypeAttribute projects the token's type.
+	TokenTypeAttribute = "$type"
+)
+
+var (
+	// ErrMissingProjection is returned when a required attribute isn't in
+	// the token.
+	ErrMissingProjection = errors.New("required attribute not found")
+
+	// ErrInvalidProjection is returned for a Projection that can't be used.
+	ErrInvalidProjection = errors.New("invalid projection")
+)
+
+// ProjectionTransform converts an attribute value into the header values
+// sent upstream.
+type ProjectionTransform func(interface{}) ([]string, error)
+
+// DefaultProjectionTransforms returns the transforms available to every
+// Projector, by name:
+//
+//   - "string", the default, sends the value, or each element of a list, as
+//     a string.
+//   - "join" sends a list as one comma separated value.
+//   - "lower" and "upper" change the case of the string values.
+//   - "json" sends the value as JSON.
+//   - "base64" sends the JSON of the value base64 encoded.
+//   - "sha256" sends the hex encoded SHA-256 of the string values, for
+//     upstreams that only need to correlate a value.
+func DefaultProjectionTransforms() map[string]ProjectionTransform {
+	return map[string]ProjectionTransform{
+		"string": stringsTransform,
+		"join": func(v interface{}) ([]string, error) {
+			values, err := stringsTransform(v)
+			if err != nil {
+				return nil, err
+			}
+			return []string{strings.Join(values, ",")}, nil
+		},
+		"lower": mapStrings(strings.ToLower),
+		"upper": mapStrings(strings.ToUpper),
+		"json": func(v interface{}) ([]string, error) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return []string{string(data)}, nil
+		},
+		"base64": func(v interface{}) ([]string, error) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return []string{base64.StdEncoding.EncodeToString(data)}, nil
+		},
+		"sha256": mapStrings(func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}),
+	}
+}
+
+func stringsTransform(v interface{}) ([]string, error) {
+	if s, err := cast.ToStringE(v); err == nil {
+		return []string{s}, nil
+	}
+	return cast.ToStringSliceE(v)
+}
+
+func mapStrings(f func(string) string) ProjectionTransform {
+	return func(v interface{}) ([]string, error) {
+		values, err := stringsTransform(v)
+		if err != nil {
+			return nil, err
+		}
+		for i := range values {
+			values[i] = f(values[i])
+		}
+		return values, nil
+	}
+}
+
+// Projection sends one token attribute upstream in a header.
+type Projection struct {
+	// Attribute is the path of the attribute in the token, in the syntax of
+	// bascule.ParseAttributePath, or PrincipalAttribute or
+	// TokenTypeAttribute.
+	Attribute string
+
+	// Header is the header, or gRPC metadata key, the attribute is sent in.
+	Header string
+
+	// Transform is the name of the transform applied to the attribute.
+	// Defaults to "string".
+	Transform string
+
+	// Required fails the projection when the attribute isn't in the token.
+	// Otherwise the header is left out.
+	Required bool
+}
+
+type projection struct {
+	Projection
+	path      bascule.AttributePath
+	transform ProjectionTransform
+}
+
+// Projector projects token attributes into the headers or gRPC metadata of
+// requests to upstream services, so that each service doesn't stamp its own
+// headers.  The projected headers are always removed from a request before
+// being set, so clients can't supply them.
+type Projector struct {
+	projections []projection
+}
+
+// NewProjector creates a Projector for the projections given.  The transforms
+// are added to, and can replace, the DefaultProjectionTransforms.
+func NewProjector(projections []Projection, transforms map[string]ProjectionTransform) (*Projector, error) {
+	available := DefaultProjectionTransforms()
+	for name, t := range transforms {
+		if t != nil {
+			available[name] = t
+		}
+	}
+	p := &Projector{}
+	for _, pr := range projections {
+		if len(pr.Header) == 0 || len(pr.Attribute) == 0 {
+			return nil, fmt.Errorf("%w: attribute and header are required", ErrInvalidProjection)
+		}
+		pr.Header = textproto.CanonicalMIMEHeaderKey(pr.Header)
+		if len(pr.Transform) == 0 {
+			pr.Transform = "string"
+		}
+		compiled := projection{Projection: pr, transform: available[pr.Transform]}
+		if compiled.transform == nil {
+			return nil, fmt.Errorf("%w: unknown transform [%s]", ErrInvalidProjection, pr.Transform)
+		}
+		if pr.Attribute != PrincipalAttribute && pr.Attribute != TokenTypeAttribute {
+			path, err := bascule.ParseAttributePath(pr.Attribute)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidProjection, err)
+			}
+			compiled.path = path
+		}
+		p.projections = append(p.projections, compiled)
+	}
+	return p, nil
+}
+
+// Project returns the headers projected from the token.  A nil token projects
+// nothing, but still fails if an attribute is required.
+func (p *Projector) Project(token bascule.Token) (http.Header, error) {
+	h := make(http.Header, len(p.projections))
+	for _, pr := range p.projections {
+		v, ok := pr.value(token)
+		if !ok {
+			if pr.Required {
+				return nil, fmt.Errorf("%w: [%s]", ErrMissingProjection, pr.Attribute)
+			}
+			continue
+		}
+		values, err := pr.transform(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to project [%s]: %w", pr.Attribute, err)
+		}
+		for _, value := range values {
+			h.Add(pr.Header, value)
+		}
+	}
+	return h, nil
+}
+
+// Metadata returns the projected values keyed by lowercase names, as gRPC
+// metadata expects.  The result can be converted to a metadata.MD.
+func (p *Projector) Metadata(token bascule.Token) (map[string][]string, error) {
+	h, err := p.Project(token)
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string][]string, len(h))
+	for k, v := range h {
+		md[strings.ToLower(k)] = v
+	}
+	return md, nil
+}
+
+// Apply removes the projected headers from h and then sets them from the
+// token.  If the projection fails, h is left without them.
+func (p *Projector) Apply(h http.Header, token bascule.Token) error {
+	for _, pr := range p.projections {
+		h.Del(pr.Header)
+	}
+	projected, err := p.Project(token)
+	if err != nil {
+		return err
+	}
+	for k, v := range projected {
+		h[k] = v
+	}
+	return nil
+}
+
+func (pr projection) value(token bascule.Token) (interface{}, bool) {
+	if token == nil {
+		return nil, false
+	}
+	switch pr.Attribute {
+	case PrincipalAttribute:
+		return token.Principal(), len(token.Principal()) > 0
+	case TokenTypeAttribute:
+		return token.Type(), len(token.Type()) > 0
+	}
+	if token.Attributes() == nil {
+		return nil, false
+	}
+	return pr.path.Get(token.Attributes())
+}
+
+// WithLProjection has the listener decorator apply the Projector to each
+// request before passing it on, as when this service proxies to upstreams.
+// Requests whose projection fails are rejected with a 403.
+func WithLProjection(p *Projector) LOption {
+	return func(l *listenerDecorator) {
+		if p != nil {
+			l.projector = p
+		}
+	}
+}
+
+type projectingRoundTripper struct {
+	projector *Projector
+	next      http.RoundTripper
+}
+
+// NewProjectingRoundTripper returns an http.RoundTripper that applies the
+// Projector to outbound requests, using the Authentication in the request's
+// context.  Only use it for clients of trusted upstreams.  If next is nil,
+// http.DefaultTransport is used.
+func NewProjectingRoundTripper(p *Projector, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &projectingRoundTripper{projector: p, next: next}
+}
+
+func (rt *projectingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	var token bascule.Token
+	if auth, ok := bascule.FromContext(r.Context()); ok {
+		token = auth.Token
+	}
+	// RoundTrippers shouldn't modify the request they're given.
+	r = r.Clone(r.Context())
+	if err := rt.projector.Apply(r.Header, token); err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	return rt.next.RoundTrip(r)
+}
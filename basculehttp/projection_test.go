@@ -0,0 +1,326 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProjectionToken() bascule.Token {
+	return bascule.NewToken("jwt", "alice", bascule.NewAttributes(map[string]interface{}{
+		"partnerIDs": []interface{}{"comcast", "sky"},
+		"region":     "US-East",
+		"admin":      true,
+		"profile":    map[string]interface{}{"tier": "gold"},
+	}))
+}
+
+func TestNewProjector(t *testing.T) {
+	tests := []struct {
+		description string
+		projections []Projection
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			projections: []Projection{{Attribute: "region", Header: "x-region"}},
+		},
+		{
+			description: "Missing header",
+			projections: []Projection{{Attribute: "region"}},
+			expectedErr: ErrInvalidProjection,
+		},
+		{
+			description: "Missing attribute",
+			projections: []Projection{{Header: "X-Region"}},
+			expectedErr: ErrInvalidProjection,
+		},
+		{
+			description: "Unknown transform",
+			projections: []Projection{{Attribute: "region", Header: "X-Region", Transform: "rot13"}},
+			expectedErr: ErrInvalidProjection,
+		},
+		{
+			description: "Bad path",
+			projections: []Projection{{Attribute: "region[", Header: "X-Region"}},
+			expectedErr: ErrInvalidProjection,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p, err := NewProjector(tc.projections, nil)
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				assert.Nil(p)
+				return
+			}
+			assert.NotNil(p)
+		})
+	}
+}
+
+func TestProjectorProject(t *testing.T) {
+	errTransform := errors.New("transform failed")
+	tests := []struct {
+		description string
+		projection  Projection
+		token       bascule.Token
+		expected    []string
+		expectedErr error
+	}{
+		{
+			description: "Principal",
+			projection:  Projection{Attribute: PrincipalAttribute},
+			expected:    []string{"alice"},
+		},
+		{
+			description: "Token type",
+			projection:  Projection{Attribute: TokenTypeAttribute},
+			expected:    []string{"jwt"},
+		},
+		{
+			description: "String",
+			projection:  Projection{Attribute: "region"},
+			expected:    []string{"US-East"},
+		},
+		{
+			description: "Bool",
+			projection:  Projection{Attribute: "admin"},
+			expected:    []string{"true"},
+		},
+		{
+			description: "List",
+			projection:  Projection{Attribute: "partnerIDs"},
+			expected:    []string{"comcast", "sky"},
+		},
+		{
+			description: "Nested",
+			projection:  Projection{Attribute: "profile.tier", Transform: "upper"},
+			expected:    []string{"GOLD"},
+		},
+		{
+			description: "Join",
+			projection:  Projection{Attribute: "$.partnerIDs", Transform: "join"},
+			expected:    []string{"comcast,sky"},
+		},
+		{
+			description: "Lower",
+			projection:  Projection{Attribute: "region", Transform: "lower"},
+			expected:    []string{"us-east"},
+		},
+		{
+			description: "JSON",
+			projection:  Projection{Attribute: "profile", Transform: "json"},
+			expected:    []string{`{"tier":"gold"}`},
+		},
+		{
+			description: "Base64",
+			projection:  Projection{Attribute: "profile", Transform: "base64"},
+			expected:    []string{"eyJ0aWVyIjoiZ29sZCJ9"},
+		},
+		{
+			description: "SHA-256",
+			projection:  Projection{Attribute: PrincipalAttribute, Transform: "sha256"},
+			expected:    []string{"2bd806c97f0e00af1a1fc3328fa763a9269723c8db8fac4f93af71db186d6e90"},
+		},
+		{
+			description: "Custom",
+			projection:  Projection{Attribute: "region", Transform: "fail"},
+			expectedErr: errTransform,
+		},
+		{
+			description: "Not a string",
+			projection:  Projection{Attribute: "profile"},
+			expectedErr: errors.New("unable to cast"),
+		},
+		{
+			description: "Missing",
+			projection:  Projection{Attribute: "missing"},
+		},
+		{
+			description: "Missing required",
+			projection:  Projection{Attribute: "missing", Required: true},
+			expectedErr: ErrMissingProjection,
+		},
+		{
+			description: "No token",
+			projection:  Projection{Attribute: PrincipalAttribute},
+			token:       bascule.NewToken("", "", nil),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			tc.projection.Header = "x-projected"
+			p, err := NewProjector([]Projection{tc.projection}, map[string]ProjectionTransform{
+				"fail": func(interface{}) ([]string, error) { return nil, errTransform },
+			})
+			require.NoError(err)
+			token := tc.token
+			if token == nil {
+				token = newProjectionToken()
+			}
+			h, err := p.Project(token)
+			if tc.expectedErr != nil {
+				assert.ErrorContains(err, tc.expectedErr.Error())
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.expected, h.Values("X-Projected"))
+		})
+	}
+}
+
+func TestProjectorMetadataAndApply(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	p, err := NewProjector([]Projection{
+		{Attribute: PrincipalAttribute, Header: "X-Principal"},
+		{Attribute: "partnerIDs", Header: "X-Partner-Ids"},
+		{Attribute: "missing", Header: "X-Missing"},
+	}, nil)
+	require.NoError(err)
+
+	md, err := p.Metadata(newProjectionToken())
+	require.NoError(err)
+	assert.Equal(map[string][]string{
+		"x-principal":   {"alice"},
+		"x-partner-ids": {"comcast", "sky"},
+	}, md)
+
+	h := http.Header{}
+	h.Set("X-Principal", "mallory")
+	h.Set("X-Missing", "spoofed")
+	h.Set("X-Other", "kept")
+	require.NoError(p.Apply(h, newProjectionToken()))
+	assert.Equal(http.Header{
+		"X-Principal":   {"alice"},
+		"X-Partner-Ids": {"comcast", "sky"},
+		"X-Other":       {"kept"},
+	}, h)
+
+	required, err := NewProjector([]Projection{{Attribute: "missing", Header: "X-Missing", Required: true}}, nil)
+	require.NoError(err)
+	_, err = required.Metadata(newProjectionToken())
+	assert.ErrorIs(err, ErrMissingProjection)
+	h.Set("X-Missing", "spoofed")
+	assert.ErrorIs(required.Apply(h, newProjectionToken()), ErrMissingProjection)
+	assert.Empty(h.Get("X-Missing"))
+}
+
+func TestListenerDecoratorProjection(t *testing.T) {
+	p, err := NewProjector([]Projection{
+		{Attribute: PrincipalAttribute, Header: "X-Principal"},
+		{Attribute: "region", Header: "X-Region", Required: true},
+	}, nil)
+	require.NoError(t, err)
+	tests := []struct {
+		description    string
+		token          bascule.Token
+		skipped        bool
+		expectedCode   int
+		expectedHeader http.Header
+	}{
+		{
+			description:  "Success",
+			token:        newProjectionToken(),
+			expectedCode: http.StatusOK,
+			expectedHeader: http.Header{
+				"X-Principal": {"alice"},
+				"X-Region":    {"US-East"},
+			},
+		},
+		{
+			description:  "Missing required",
+			token:        bascule.NewToken("jwt", "alice", bascule.NewAttributes(nil)),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			description:    "Skipped",
+			skipped:        true,
+			expectedCode:   http.StatusOK,
+			expectedHeader: http.Header{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var forwarded http.Header
+			handler := NewListenerDecoratorWithOptions(WithLProjection(p), WithLProjection(nil))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					forwarded = r.Header.Clone()
+					w.WriteHeader(http.StatusOK)
+				}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Principal", "mallory")
+			req = req.WithContext(bascule.WithAuthentication(req.Context(), bascule.Authentication{
+				Token: tc.token,
+			}))
+			if tc.skipped {
+				req = withSkipped(req)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(tc.expectedCode, w.Code)
+			if tc.expectedHeader != nil {
+				assert.Equal(tc.expectedHeader, forwarded)
+			}
+		})
+	}
+}
+
+func TestProjectingRoundTripper(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	p, err := NewProjector([]Projection{{Attribute: PrincipalAttribute, Header: "X-Principal", Required: true}}, nil)
+	require.NoError(err)
+
+	var sent http.Header
+	rt := NewProjectingRoundTripper(p, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r.Header.Clone()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{Token: newProjectionToken()})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://upstream.example.com", nil)
+	require.NoError(err)
+	req.Header.Set("X-Principal", "mallory")
+	resp, err := rt.RoundTrip(req)
+	require.NoError(err)
+	resp.Body.Close()
+	assert.Equal("alice", sent.Get("X-Principal"))
+	assert.Equal("mallory", req.Header.Get("X-Principal"))
+
+	req, err = http.NewRequest(http.MethodGet, "https://upstream.example.com", nil)
+	require.NoError(err)
+	_, err = rt.RoundTrip(req)
+	assert.ErrorIs(err, ErrMissingProjection)
+
+	assert.NotNil(NewProjectingRoundTripper(p, nil))
+}
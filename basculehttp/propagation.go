@@ -0,0 +1,162 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+type propagatedCredentialKey struct{}
+
+// WithCPropagation has the constructor keep the authorization value that
+// authenticated each request in the request's context, so that a
+// PropagatingRoundTripper can pass it on to upstream services.  Without it,
+// the constructor doesn't keep credentials after validating them.
+func WithCPropagation() COption {
+	return func(c *constructor) {
+		c.propagate = true
+	}
+}
+
+func withPropagatedCredential(ctx context.Context, credential string) context.Context {
+	if len(credential) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, propagatedCredentialKey{}, credential)
+}
+
+// PropagatedCredential returns the authorization value, like "Bearer <token>",
+// that authenticated the request, if the constructor was configured to keep it
+// with WithCPropagation.  It is a live credential, so it must not be logged.
+func PropagatedCredential(ctx context.Context) (string, bool) {
+	credential, ok := ctx.Value(propagatedCredentialKey{}).(string)
+	return credential, ok && credential != ""
+}
+
+// PropagationConfig configures which outbound requests a
+// PropagatingRoundTripper adds the inbound credential to.
+type PropagationConfig struct {
+	// Hosts are the upstream hosts trusted with the credential.  A host can
+	// include a port, in which case the port must match too, and a leading
+	// "*." matches any subdomain.  With no hosts, no credentials are
+	// propagated.
+	Hosts []string
+
+	// Schemes limits the credentials propagated to those whose Authentication
+	// has one of these schemes.  If empty, credentials of any scheme are
+	// propagated.
+	Schemes []bascule.Authorization
+
+	// Header is the outbound header the credential is set in.  The default
+	// is the Authorization header.
+	Header string
+
+	// AllowInsecure allows credentials to be sent over plain http.  By
+	// default, only https requests get the credential.
+	AllowInsecure bool
+}
+
+type propagatingRoundTripper struct {
+	hosts         []string
+	schemes       []bascule.Authorization
+	header        string
+	allowInsecure bool
+	next          http.RoundTripper
+}
+
+// NewPropagatingRoundTripper returns an http.RoundTripper that copies the
+// inbound request's credential, from the outbound request's context, onto
+// outbound requests to the trusted hosts.  Requests that already have the
+// header, or whose context has no credential, are passed to next as is.  If
+// next is nil, http.DefaultTransport is used.
+func NewPropagatingRoundTripper(config PropagationConfig, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	rt := &propagatingRoundTripper{
+		schemes:       config.Schemes,
+		header:        config.Header,
+		allowInsecure: config.AllowInsecure,
+		next:          next,
+	}
+	if len(rt.header) == 0 {
+		rt.header = DefaultHeaderName
+	}
+	for _, h := range config.Hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); len(h) > 0 {
+			rt.hosts = append(rt.hosts, h)
+		}
+	}
+	return rt
+}
+
+func (rt *propagatingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	credential, ok := PropagatedCredential(r.Context())
+	if !ok || len(r.Header.Get(rt.header)) > 0 || !rt.trusted(r) || !rt.schemeAllowed(r.Context()) {
+		return rt.next.RoundTrip(r)
+	}
+
+	// RoundTrippers shouldn't modify the request they're given.
+	r = r.Clone(r.Context())
+	r.Header.Set(rt.header, credential)
+	return rt.next.RoundTrip(r)
+}
+
+// trusted returns true if the request is to one of the trusted hosts, over
+// https unless insecure requests are allowed.
+func (rt *propagatingRoundTripper) trusted(r *http.Request) bool {
+	if r.URL == nil || (!rt.allowInsecure && r.URL.Scheme != "https") {
+		return false
+	}
+	hostport := strings.ToLower(r.URL.Host)
+	host := strings.ToLower(r.URL.Hostname())
+	for _, h := range rt.hosts {
+		target := host
+		if _, _, err := net.SplitHostPort(h); err == nil {
+			target = hostport
+		}
+		if h == target {
+			return true
+		}
+		if strings.HasPrefix(h, "*.") && strings.HasSuffix(target, h[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *propagatingRoundTripper) schemeAllowed(ctx context.Context) bool {
+	if len(rt.schemes) == 0 {
+		return true
+	}
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, s := range rt.schemes {
+		if s == auth.Authorization {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestConstructorPropagation(t *testing.T) {
+	tests := []struct {
+		description string
+		options     []COption
+		headers     []string
+		expected    string
+	}{
+		{
+			description: "Disabled",
+			headers:     []string{"Bearer abc"},
+		},
+		{
+			description: "Enabled",
+			options:     []COption{WithCPropagation()},
+			headers:     []string{"Bearer abc"},
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Credentials That Authenticated",
+			options:     []COption{WithCPropagation(), WithCMultipleCredentials(TryAllCredentials)},
+			headers:     []string{"Bearer bad", "Bearer abc"},
+			expected:    "Bearer abc",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				credential string
+				ok         bool
+			)
+			options := append(tc.options, WithTokenFactory(BearerAuthorization, TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+				if v == "bad" {
+					return nil, ErrInvalidToken
+				}
+				return bascule.NewToken("jwt", "alice", nil), nil
+			})))
+			handler := NewConstructor(options...)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				credential, ok = PropagatedCredential(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, h := range tc.headers {
+				req.Header.Add(DefaultHeaderName, h)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(tc.expected, credential)
+			assert.Equal(tc.expected != "", ok)
+		})
+	}
+}
+
+func TestPropagatingRoundTripper(t *testing.T) {
+	ctx := withPropagatedCredential(
+		bascule.WithAuthentication(context.Background(), bascule.Authentication{Authorization: BearerAuthorization}),
+		"Bearer abc",
+	)
+	tests := []struct {
+		description    string
+		config         PropagationConfig
+		ctx            context.Context
+		url            string
+		header         http.Header
+		expectedHeader string
+		expected       string
+	}{
+		{
+			description: "Trusted Host",
+			config:      PropagationConfig{Hosts: []string{" API.example.com ", ""}},
+			url:         "https://api.example.com/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Untrusted Host",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}},
+			url:         "https://evil.example.net/v1",
+		},
+		{
+			description: "No Hosts",
+			url:         "https://api.example.com/v1",
+		},
+		{
+			description: "Insecure",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}},
+			url:         "http://api.example.com/v1",
+		},
+		{
+			description: "Insecure Allowed",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}, AllowInsecure: true},
+			url:         "http://api.example.com/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Wildcard",
+			config:      PropagationConfig{Hosts: []string{"*.example.com"}},
+			url:         "https://a.b.example.com/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Wildcard Doesn't Match Apex",
+			config:      PropagationConfig{Hosts: []string{"*.example.com"}},
+			url:         "https://example.com/v1",
+		},
+		{
+			description: "Port",
+			config:      PropagationConfig{Hosts: []string{"api.example.com:8443"}},
+			url:         "https://api.example.com:8443/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Wrong Port",
+			config:      PropagationConfig{Hosts: []string{"api.example.com:8443"}},
+			url:         "https://api.example.com/v1",
+		},
+		{
+			description: "Any Port",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}},
+			url:         "https://api.example.com:8443/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "No Credential",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}},
+			ctx:         context.Background(),
+			url:         "https://api.example.com/v1",
+		},
+		{
+			description:    "Header Already Set",
+			config:         PropagationConfig{Hosts: []string{"api.example.com"}},
+			url:            "https://api.example.com/v1",
+			header:         http.Header{"Authorization": {"Basic xyz"}},
+			expectedHeader: DefaultHeaderName,
+			expected:       "Basic xyz",
+		},
+		{
+			description:    "Custom Header",
+			config:         PropagationConfig{Hosts: []string{"api.example.com"}, Header: "X-Upstream-Auth"},
+			url:            "https://api.example.com/v1",
+			expectedHeader: "X-Upstream-Auth",
+			expected:       "Bearer abc",
+		},
+		{
+			description: "Scheme Allowed",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}, Schemes: []bascule.Authorization{BasicAuthorization, BearerAuthorization}},
+			url:         "https://api.example.com/v1",
+			expected:    "Bearer abc",
+		},
+		{
+			description: "Scheme Not Allowed",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}, Schemes: []bascule.Authorization{BasicAuthorization}},
+			url:         "https://api.example.com/v1",
+		},
+		{
+			description: "Scheme Without Authentication",
+			config:      PropagationConfig{Hosts: []string{"api.example.com"}, Schemes: []bascule.Authorization{BearerAuthorization}},
+			ctx:         withPropagatedCredential(context.Background(), "Bearer abc"),
+			url:         "https://api.example.com/v1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			reqCtx := tc.ctx
+			if reqCtx == nil {
+				reqCtx = ctx
+			}
+			expectedHeader := tc.expectedHeader
+			if expectedHeader == "" {
+				expectedHeader = DefaultHeaderName
+			}
+			var sent *http.Request
+			rt := NewPropagatingRoundTripper(tc.config, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				sent = r
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			}))
+			req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			require.NotNil(t, sent)
+			assert.Equal(tc.expected, sent.Header.Get(expectedHeader))
+			if tc.header == nil {
+				assert.Empty(req.Header.Get(expectedHeader), "the original request was modified")
+			}
+		})
+	}
+}
+
+func TestPropagatingRoundTripperDefaultTransport(t *testing.T) {
+	rt := NewPropagatingRoundTripper(PropagationConfig{}, nil)
+	assert.Equal(t, http.DefaultTransport, rt.(*propagatingRoundTripper).next)
+	_, ok := PropagatedCredential(withPropagatedCredential(context.Background(), ""))
+	assert.False(t, ok)
+}
@@ -18,8 +18,8 @@
 package basculehttp
 
 import (
-	"github.com/xmidt-org/bascule"
-	"github.com/xmidt-org/bascule/basculechecks"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
 	"go.uber.org/fx"
 )
 
@@ -22,11 +22,11 @@ import (
 	"testing"
 
 	"github.com/justinas/alice"
+	"github.com/s-srakshe/bascule/basculechecks"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/arrange"
-	"github.com/xmidt-org/bascule/basculechecks"
 	"github.com/xmidt-org/touchstone"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxtest"
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import "net/http"
+
+const (
+	// ProxyAuthorizationHeader is the header forward proxies read the
+	// client's credentials from.
+	ProxyAuthorizationHeader = "Proxy-Authorization"
+
+	// ProxyAuthenticateHeader is the header a forward proxy's challenges are
+	// sent in, instead of WWW-Authenticate.
+	ProxyAuthenticateHeader = "Proxy-Authenticate"
+)
+
+// ProxyMode is whether the constructor authenticates requests for a forward
+// proxy rather than an origin server.
+type ProxyMode int
+
+const (
+	// NoProxy reads credentials from the constructor's header, which is
+	// Authorization by default.
+	NoProxy ProxyMode = iota
+
+	// ProxyOnly reads credentials from the Proxy-Authorization header
+	// instead.
+	ProxyOnly
+
+	// ProxyOrOrigin reads credentials from the Proxy-Authorization header if
+	// the request has one, and from the constructor's header otherwise.
+	ProxyOrOrigin
+)
+
+// WithCProxyMode sets whether requests are authenticated for a forward proxy.
+// In the proxy modes, denials that would be a 401 are a 407 and challenges are
+// sent in the Proxy-Authenticate header.
+func WithCProxyMode(m ProxyMode) COption {
+	return func(c *constructor) {
+		if m >= NoProxy && m <= ProxyOrOrigin {
+			c.proxyMode = m
+		}
+	}
+}
+
+// authorizationHeader returns the header the request's credentials are read
+// from.
+func (c *constructor) authorizationHeader(h http.Header) string {
+	switch c.proxyMode {
+	case ProxyOnly:
+		return ProxyAuthorizationHeader
+	case ProxyOrOrigin:
+		if len(h.Values(ProxyAuthorizationHeader)) > 0 {
+			return ProxyAuthorizationHeader
+		}
+	}
+	return c.headerName
+}
+
+// renewHeader returns the header that a session's replacement credentials
+// are set in.
+func (c *constructor) renewHeader() string {
+	if c.proxyMode != NoProxy {
+		return ProxyAuthorizationHeader
+	}
+	return c.headerName
+}
+
+// proxyWriter wraps the response so that denials are sent as a proxy would,
+// when the constructor is in a proxy mode.
+func (c *constructor) proxyWriter(w http.ResponseWriter) http.ResponseWriter {
+	if c.proxyMode == NoProxy {
+		return w
+	}
+	return &proxyResponseWriter{ResponseWriter: w}
+}
+
+// proxyResponseWriter turns a 401 into a 407, moving the WWW-Authenticate
+// challenges to the Proxy-Authenticate header.
+type proxyResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *proxyResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status == http.StatusUnauthorized {
+		h := w.Header()
+		if challenges := h.Values(AuthTypeHeaderKey); len(challenges) > 0 {
+			h.Del(AuthTypeHeaderKey)
+			h[ProxyAuthenticateHeader] = append(h[ProxyAuthenticateHeader], challenges...)
+		}
+		status = http.StatusProxyAuthRequired
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *proxyResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
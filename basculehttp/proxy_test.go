@@ -0,0 +1,148 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+type challengerFunc func(*http.Request, error) []string
+
+func (f challengerFunc) Challenges(r *http.Request, err error) []string {
+	return f(r, err)
+}
+
+func TestConstructorProxyMode(t *testing.T) {
+	tests := []struct {
+		description        string
+		mode               ProxyMode
+		headers            http.Header
+		expectedCode       int
+		expectedTried      string
+		expectedChallenges []string
+		expectedAuthn      []string
+	}{
+		{
+			description:   "Origin",
+			headers:       http.Header{"Authorization": {"Basic good"}, "Proxy-Authorization": {"Basic proxy"}},
+			expectedCode:  http.StatusOK,
+			expectedTried: "good",
+		},
+		{
+			description:   "Origin Denied",
+			headers:       http.Header{"Proxy-Authorization": {"Basic good"}},
+			expectedCode:  http.StatusUnauthorized,
+			expectedAuthn: []string{"Basic realm=\"test\"", "Bearer"},
+		},
+		{
+			description:   "Proxy Only",
+			mode:          ProxyOnly,
+			headers:       http.Header{"Authorization": {"Basic bad"}, "Proxy-Authorization": {"Basic good"}},
+			expectedCode:  http.StatusOK,
+			expectedTried: "good",
+		},
+		{
+			description:        "Proxy Only Denied",
+			mode:               ProxyOnly,
+			headers:            http.Header{"Authorization": {"Basic good"}},
+			expectedCode:       http.StatusProxyAuthRequired,
+			expectedChallenges: []string{"Basic realm=\"test\"", "Bearer"},
+		},
+		{
+			description:        "Proxy Only Bad Credentials",
+			mode:               ProxyOnly,
+			headers:            http.Header{"Proxy-Authorization": {"Basic bad"}},
+			expectedCode:       http.StatusProxyAuthRequired,
+			expectedTried:      "bad",
+			expectedChallenges: []string{"Basic realm=\"test\"", "Bearer"},
+		},
+		{
+			description:   "Proxy Or Origin Uses Proxy",
+			mode:          ProxyOrOrigin,
+			headers:       http.Header{"Authorization": {"Basic bad"}, "Proxy-Authorization": {"Basic good"}},
+			expectedCode:  http.StatusOK,
+			expectedTried: "good",
+		},
+		{
+			description:   "Proxy Or Origin Falls Back",
+			mode:          ProxyOrOrigin,
+			headers:       http.Header{"Authorization": {"Basic good"}},
+			expectedCode:  http.StatusOK,
+			expectedTried: "good",
+		},
+		{
+			description:        "Proxy Or Origin Denied",
+			mode:               ProxyOrOrigin,
+			headers:            http.Header{},
+			expectedCode:       http.StatusProxyAuthRequired,
+			expectedChallenges: []string{"Basic realm=\"test\"", "Bearer"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var tried string
+			handler := NewConstructor(
+				WithCProxyMode(ProxyMode(-1)),
+				WithCProxyMode(tc.mode),
+				WithChallenger(challengerFunc(func(*http.Request, error) []string {
+					return []string{"Basic realm=\"test\""}
+				})),
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+					tried = v
+					if v == "good" {
+						return bascule.NewToken("basic", "alice", nil), nil
+					}
+					return nil, errors.New("bad credentials")
+				})),
+			)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tc.headers
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(tc.expectedCode, recorder.Code)
+			assert.Equal(tc.expectedTried, tried)
+			assert.Equal(tc.expectedChallenges, recorder.Header().Values(ProxyAuthenticateHeader))
+			assert.Equal(tc.expectedAuthn, recorder.Header().Values(AuthTypeHeaderKey))
+		})
+	}
+}
+
+func TestProxyResponseWriter(t *testing.T) {
+	assert := assert.New(t)
+	recorder := httptest.NewRecorder()
+	w := (&constructor{proxyMode: ProxyOnly}).proxyWriter(recorder)
+	w.Header().Set(AuthTypeHeaderKey, "Bearer")
+	_, err := w.Write([]byte("ok"))
+	assert.NoError(err)
+	w.WriteHeader(http.StatusUnauthorized)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("Bearer", recorder.Header().Get(AuthTypeHeaderKey))
+	assert.Empty(recorder.Header().Get(ProxyAuthenticateHeader))
+
+	recorder = httptest.NewRecorder()
+	w = (&constructor{}).proxyWriter(recorder)
+	assert.Equal(recorder, w)
+}
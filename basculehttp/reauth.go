@@ -0,0 +1,165 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var (
+	ErrNoAuthentication = errors.New("no authentication found in request context")
+	ErrNilOnExpire      = errors.New("OnExpire callback cannot be nil")
+	ErrTokenExpired     = bascule.NewClassError(bascule.ExpiredClass, "token expired")
+)
+
+// ReauthConfig configures watching the authentication of a long-lived
+// connection, such as a WebSocket, which the Enforcer only checks once when
+// the connection is upgraded.
+type ReauthConfig struct {
+	// Revalidate is called when the token expires.  It can return a fresh
+	// Authentication, such as one built from a new token the client sent over
+	// the connection, which is then watched in turn.  If it's nil or returns
+	// an error, OnExpire is called.
+	Revalidate func(context.Context, bascule.Authentication) (bascule.Authentication, error)
+
+	// OnExpire is called once the authentication is no longer valid, with
+	// ErrTokenExpired or the error from Revalidate.  It usually closes the
+	// connection.  It's required.
+	OnExpire func(bascule.Authentication, error)
+
+	// Grace is added to the token's expiration before revalidating.
+	Grace time.Duration
+}
+
+// reauthWatcher schedules revalidation of an Authentication at its token's
+// expiration.
+type reauthWatcher struct {
+	ctx    context.Context
+	config ReauthConfig
+	done   chan struct{}
+
+	lock    sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+// WatchAuthentication schedules revalidation of the authentication at its
+// token's expiration and calls OnExpire if it's no longer valid.  Tokens
+// without an exp attribute are never revalidated.  Watching ends when the
+// returned function is called, the context is done, or OnExpire is called.
+func WatchAuthentication(ctx context.Context, auth bascule.Authentication, config ReauthConfig) (func(), error) {
+	if config.OnExpire == nil {
+		return nil, ErrNilOnExpire
+	}
+	w := &reauthWatcher{
+		ctx:    ctx,
+		config: config,
+		done:   make(chan struct{}),
+	}
+	w.schedule(auth)
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.finish()
+		case <-w.done:
+		}
+	}()
+	return func() { w.finish() }, nil
+}
+
+// WatchRequest watches the Authentication added to the request's context by
+// the constructor.  Call it from the handler before hijacking or upgrading the
+// connection, with a context that lives as long as the connection.  The
+// request's Authentication and request ID are copied to that context, so
+// Revalidate sees the same identity the request had.
+func WatchRequest(ctx context.Context, r *http.Request, config ReauthConfig) (func(), error) {
+	auth, ok := bascule.FromContext(r.Context())
+	if !ok {
+		return nil, ErrNoAuthentication
+	}
+	return WatchAuthentication(bascule.CopyAuth(ctx, r.Context()), auth, config)
+}
+
+func (w *reauthWatcher) schedule(auth bascule.Authentication) {
+	exp, ok := tokenExpiration(auth.Token)
+	if !ok {
+		return
+	}
+	d := time.Until(exp.Add(w.config.Grace))
+	if d < 0 {
+		d = 0
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return
+	}
+	w.timer = time.AfterFunc(d, func() { w.expire(auth) })
+}
+
+func (w *reauthWatcher) expire(auth bascule.Authentication) {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	err := ErrTokenExpired
+	if w.config.Revalidate != nil {
+		fresh, rerr := w.config.Revalidate(w.ctx, auth)
+		switch {
+		case rerr != nil:
+			err = rerr
+		case w.expired(fresh):
+			// a token that's already expired would be revalidated in a loop.
+		default:
+			w.schedule(fresh)
+			return
+		}
+	}
+	if w.finish() {
+		w.config.OnExpire(auth, err)
+	}
+}
+
+func (w *reauthWatcher) expired(auth bascule.Authentication) bool {
+	exp, ok := tokenExpiration(auth.Token)
+	return ok && !time.Now().Before(exp.Add(w.config.Grace))
+}
+
+// finish stops watching, returning true the first time it's called.
+func (w *reauthWatcher) finish() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return false
+	}
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	close(w.done)
+	return true
+}
@@ -0,0 +1,195 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authExpiringAt(exp time.Time) bascule.Authentication {
+	return bascule.Authentication{
+		Token: bascule.NewToken("test", "principal", bascule.NewAttributes(map[string]interface{}{"exp": exp.Unix()})),
+	}
+}
+
+func TestWatchAuthentication(t *testing.T) {
+	revalidateErr := errors.New("revalidate failed")
+	expired := authExpiringAt(time.Now().Add(-time.Minute))
+	tests := []struct {
+		description   string
+		auth          bascule.Authentication
+		revalidate    func(context.Context, bascule.Authentication) (bascule.Authentication, error)
+		grace         time.Duration
+		expectedCalls int32
+		expectedErr   error
+	}{
+		{
+			description:   "Expired",
+			auth:          expired,
+			expectedCalls: 1,
+			expectedErr:   ErrTokenExpired,
+		},
+		{
+			description: "Not Expired",
+			auth:        authExpiringAt(time.Now().Add(time.Hour)),
+		},
+		{
+			description: "Within Grace",
+			auth:        expired,
+			grace:       time.Hour,
+		},
+		{
+			description: "No Exp",
+			auth:        bascule.Authentication{Token: bascule.NewToken("test", "principal", bascule.NewAttributes(nil))},
+		},
+		{
+			description: "Revalidated",
+			auth:        expired,
+			revalidate: func(context.Context, bascule.Authentication) (bascule.Authentication, error) {
+				return authExpiringAt(time.Now().Add(time.Hour)), nil
+			},
+		},
+		{
+			description: "Revalidate Error",
+			auth:        expired,
+			revalidate: func(context.Context, bascule.Authentication) (bascule.Authentication, error) {
+				return bascule.Authentication{}, revalidateErr
+			},
+			expectedCalls: 1,
+			expectedErr:   revalidateErr,
+		},
+		{
+			description: "Revalidated Token Expired",
+			auth:        expired,
+			revalidate: func(context.Context, bascule.Authentication) (bascule.Authentication, error) {
+				return expired, nil
+			},
+			expectedCalls: 1,
+			expectedErr:   ErrTokenExpired,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var calls int32
+			errs := make(chan error, 1)
+			stop, err := WatchAuthentication(context.Background(), tc.auth, ReauthConfig{
+				Revalidate: tc.revalidate,
+				Grace:      tc.grace,
+				OnExpire: func(_ bascule.Authentication, err error) {
+					atomic.AddInt32(&calls, 1)
+					errs <- err
+				},
+			})
+			require.NoError(t, err)
+			defer stop()
+			if tc.expectedCalls > 0 {
+				select {
+				case err := <-errs:
+					assert.ErrorIs(err, tc.expectedErr)
+				case <-time.After(time.Second):
+					assert.Fail("OnExpire wasn't called")
+				}
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+			assert.Equal(tc.expectedCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestWatchAuthenticationStopped(t *testing.T) {
+	assert := assert.New(t)
+	_, err := WatchAuthentication(context.Background(), bascule.Authentication{}, ReauthConfig{})
+	assert.ErrorIs(err, ErrNilOnExpire)
+
+	var calls int32
+	onExpire := func(bascule.Authentication, error) { atomic.AddInt32(&calls, 1) }
+	auth := authExpiringAt(time.Now().Add(2 * time.Second))
+
+	// stopped before the timer fires.
+	stop, err := WatchAuthentication(context.Background(), auth, ReauthConfig{OnExpire: onExpire})
+	require.NoError(t, err)
+	stop()
+	stop()
+
+	// context canceled before the timer fires.
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = WatchAuthentication(ctx, auth, ReauthConfig{OnExpire: onExpire})
+	require.NoError(t, err)
+	cancel()
+
+	time.Sleep(2500 * time.Millisecond)
+	assert.Zero(atomic.LoadInt32(&calls))
+}
+
+func TestWatchRequest(t *testing.T) {
+	assert := assert.New(t)
+	config := ReauthConfig{OnExpire: func(bascule.Authentication, error) {}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	stop, err := WatchRequest(context.Background(), req, config)
+	assert.ErrorIs(err, ErrNoAuthentication)
+	assert.Nil(stop)
+
+	auth := authExpiringAt(time.Now().Add(time.Hour))
+	req = req.WithContext(bascule.WithAuthentication(req.Context(), auth))
+	stop, err = WatchRequest(context.Background(), req, config)
+	require.NoError(t, err)
+	stop()
+}
+
+func TestWatchRequestCopiesAuth(t *testing.T) {
+	assert := assert.New(t)
+	auth := authExpiringAt(time.Now().Add(-time.Minute))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(bascule.WithRequestID(bascule.WithAuthentication(req.Context(), auth), "abc-123"))
+
+	revalidated := make(chan context.Context, 1)
+	config := ReauthConfig{
+		Revalidate: func(ctx context.Context, a bascule.Authentication) (bascule.Authentication, error) {
+			revalidated <- ctx
+			return a, errors.New("done")
+		},
+		OnExpire: func(bascule.Authentication, error) {},
+	}
+	stop, err := WatchRequest(context.Background(), req, config)
+	require.NoError(t, err)
+	defer stop()
+
+	select {
+	case ctx := <-revalidated:
+		actual, ok := bascule.FromContext(ctx)
+		assert.True(ok)
+		assert.Equal(auth, actual)
+		id, ok := bascule.RequestIDFromContext(ctx)
+		assert.True(ok)
+		assert.Equal("abc-123", id)
+	case <-time.After(5 * time.Second):
+		assert.Fail("revalidate wasn't called")
+	}
+}
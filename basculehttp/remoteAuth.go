@@ -0,0 +1,342 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+)
+
+const (
+	// RemoteAuthTokenType is the type of tokens created by the
+	// RemoteAuthValidator when the authorizer doesn't give one.
+	RemoteAuthTokenType = "remote"
+
+	defaultRemoteAuthTimeout          = 5 * time.Second
+	defaultRemoteAuthCacheTTL         = time.Minute
+	defaultRemoteAuthCacheMaxEntries  = 10000
+	defaultRemoteAuthFailureThreshold = 5
+	defaultRemoteAuthCooldown         = 30 * time.Second
+	remoteAuthMaxResponseSize         = 1 << 20
+)
+
+var (
+	ErrEmptyRemoteAuthURL = errors.New("remote authorizer url cannot be empty")
+
+	// ErrRemoteAuthDenied is returned when the authorizer denies a request.
+	ErrRemoteAuthDenied = bascule.NewClassError(bascule.InvalidClass, "denied by remote authorizer")
+
+	// ErrRemoteAuthUnavailable is returned when the authorizer can't be
+	// reached, or has failed too many times in a row and isn't being tried
+	// until the cooldown passes.  Requests are denied while it's
+	// unavailable.
+	ErrRemoteAuthUnavailable = bascule.NewClassError(bascule.UnavailableClass, "remote authorizer unavailable")
+)
+
+// RemoteAuthRequest is the JSON body POSTed to the authorizer.
+type RemoteAuthRequest struct {
+	Authorization string `json:"authorization"`
+	Token         string `json:"token"`
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+}
+
+// RemoteAuthResponse is the JSON body the authorizer responds with.
+type RemoteAuthResponse struct {
+	// Allow is whether the request is allowed.
+	Allow bool `json:"allow"`
+
+	// Reason explains a denial.
+	Reason string `json:"reason,omitempty"`
+
+	// Principal, Type, and Attributes are used to build the token for an
+	// allowed request.
+	Principal  string                 `json:"principal,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// RemoteAuthConfig configures a RemoteAuthValidator.
+type RemoteAuthConfig struct {
+	// URL is the authorizer's endpoint.
+	URL string
+
+	// Client is the client used to call the authorizer.  Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Timeout bounds each call to the authorizer.  Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// CacheTTL is how long allow decisions are cached.  Defaults to one
+	// minute.  A negative TTL disables caching.
+	CacheTTL time.Duration
+
+	// DenyCacheTTL is how long deny decisions are cached.  Deny decisions
+	// aren't cached by default.
+	DenyCacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached decisions.  Defaults to
+	// 10000.
+	CacheMaxEntries int
+
+	// FailureThreshold is the number of failed calls in a row after which
+	// the authorizer isn't called until the cooldown passes.  Defaults to 5.
+	FailureThreshold int
+
+	// Cooldown is how long the authorizer isn't called after reaching the
+	// failure threshold.  Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+// RemoteAuthValidator delegates authentication and authorization to a
+// central authorizer service.  The credentials, method, and URL of each
+// request are POSTed to the authorizer, and the token is built from the
+// principal and attributes it responds with.  Decisions are cached, and the
+// authorizer isn't called for a while after it fails repeatedly so that an
+// outage doesn't slow every request down.
+type RemoteAuthValidator struct {
+	config    RemoteAuthConfig
+	now       func() time.Time
+	decisions *ttlcache.Cache
+
+	lock       sync.Mutex
+	failures   int
+	openUntil  time.Time
+	halfOpened bool
+}
+
+// NewRemoteAuthValidator creates a RemoteAuthValidator that calls the
+// authorizer at the configured URL.
+func NewRemoteAuthValidator(config RemoteAuthConfig) (*RemoteAuthValidator, error) {
+	if config.URL == "" {
+		return nil, ErrEmptyRemoteAuthURL
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultRemoteAuthTimeout
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = defaultRemoteAuthCacheTTL
+	}
+	if config.CacheMaxEntries <= 0 {
+		config.CacheMaxEntries = defaultRemoteAuthCacheMaxEntries
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultRemoteAuthFailureThreshold
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaultRemoteAuthCooldown
+	}
+	return &RemoteAuthValidator{
+		config:    config,
+		now:       time.Now,
+		decisions: ttlcache.New(config.CacheMaxEntries),
+	}, nil
+}
+
+// ParseAndValidate asks the authorizer whether the request is allowed,
+// returning a token built from its response if it is.
+func (rv *RemoteAuthValidator) ParseAndValidate(ctx context.Context, r *http.Request, a bascule.Authorization, value string) (bascule.Token, error) {
+	body := RemoteAuthRequest{
+		Authorization: string(a),
+		Token:         value,
+	}
+	if r != nil {
+		body.Method = r.Method
+		body.URL = requestURL(r)
+	}
+	key := remoteAuthKey(body)
+
+	response, ok := rv.cached(key)
+	if !ok {
+		var err error
+		response, err = rv.call(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		rv.cache(key, response)
+	}
+
+	if !response.Allow {
+		if response.Reason != "" {
+			return nil, fmt.Errorf("%w: %s", ErrRemoteAuthDenied, response.Reason)
+		}
+		return nil, ErrRemoteAuthDenied
+	}
+	tokenType := response.Type
+	if tokenType == "" {
+		tokenType = RemoteAuthTokenType
+	}
+	attributes := response.Attributes
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	return bascule.NewToken(tokenType, response.Principal, bascule.NewAttributes(attributes)), nil
+}
+
+// call POSTs the request to the authorizer, tracking failures for the
+// circuit breaker.
+func (rv *RemoteAuthValidator) call(ctx context.Context, body RemoteAuthRequest) (RemoteAuthResponse, error) {
+	if wait, ok := rv.allowCall(); !ok {
+		if wait > 0 {
+			return RemoteAuthResponse{}, NewRetryAfterError(ErrRemoteAuthUnavailable, wait)
+		}
+		return RemoteAuthResponse{}, ErrRemoteAuthUnavailable
+	}
+	response, err := rv.post(ctx, body)
+	if err != nil && ctx.Err() == nil {
+		rv.recordFailure()
+		return RemoteAuthResponse{}, fmt.Errorf("%w: %v", ErrRemoteAuthUnavailable, err)
+	}
+	if err != nil {
+		// the request ended, which isn't the authorizer's fault.
+		rv.releaseCall()
+		return RemoteAuthResponse{}, err
+	}
+	rv.recordSuccess()
+	return response, nil
+}
+
+func (rv *RemoteAuthValidator) post(ctx context.Context, body RemoteAuthRequest) (RemoteAuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, rv.config.Timeout)
+	defer cancel()
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return RemoteAuthResponse{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rv.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return RemoteAuthResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := rv.config.Client.Do(req)
+	if err != nil {
+		return RemoteAuthResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, remoteAuthMaxResponseSize))
+		return RemoteAuthResponse{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var response RemoteAuthResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, remoteAuthMaxResponseSize)).Decode(&response)
+	if err != nil {
+		return RemoteAuthResponse{}, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return response, nil
+}
+
+// allowCall returns false while the circuit is open, along with how long
+// until the cooldown ends.  Once the cooldown passes, a single call is let
+// through to see if the authorizer is back.
+func (rv *RemoteAuthValidator) allowCall() (time.Duration, bool) {
+	rv.lock.Lock()
+	defer rv.lock.Unlock()
+	if rv.failures < rv.config.FailureThreshold {
+		return 0, true
+	}
+	if now := rv.now(); rv.halfOpened || now.Before(rv.openUntil) {
+		return rv.openUntil.Sub(now), false
+	}
+	rv.halfOpened = true
+	return 0, true
+}
+
+func (rv *RemoteAuthValidator) releaseCall() {
+	rv.lock.Lock()
+	defer rv.lock.Unlock()
+	rv.halfOpened = false
+}
+
+func (rv *RemoteAuthValidator) recordFailure() {
+	rv.lock.Lock()
+	defer rv.lock.Unlock()
+	rv.failures++
+	rv.halfOpened = false
+	if rv.failures >= rv.config.FailureThreshold {
+		rv.openUntil = rv.now().Add(rv.config.Cooldown)
+	}
+}
+
+func (rv *RemoteAuthValidator) recordSuccess() {
+	rv.lock.Lock()
+	defer rv.lock.Unlock()
+	rv.failures = 0
+	rv.halfOpened = false
+}
+
+func (rv *RemoteAuthValidator) cached(key string) (RemoteAuthResponse, bool) {
+	v, ok := rv.decisions.Get(key, rv.now())
+	if !ok {
+		return RemoteAuthResponse{}, false
+	}
+	return v.(RemoteAuthResponse), true
+}
+
+func (rv *RemoteAuthValidator) cache(key string, response RemoteAuthResponse) {
+	ttl := rv.config.CacheTTL
+	if !response.Allow {
+		ttl = rv.config.DenyCacheTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	now := rv.now()
+	rv.decisions.Set(key, response, now.Add(ttl), now)
+}
+
+// requestURL returns the request's absolute URL, since the URL of a request
+// received by a server only has the path and query.
+func requestURL(r *http.Request) string {
+	u := *r.URL
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if r.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	return u.String()
+}
+
+// remoteAuthKey hashes the request so credentials aren't kept in memory as
+// cache keys.
+func remoteAuthKey(body RemoteAuthRequest) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{body.Authorization, body.Token, body.Method, body.URL}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,170 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteAuthValidator(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var body RemoteAuthRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := RemoteAuthResponse{Reason: "bad token"}
+		if body.Authorization == "Bearer" && body.Token == "good" && body.Method == http.MethodGet {
+			response = RemoteAuthResponse{
+				Allow:      true,
+				Principal:  "user",
+				Attributes: map[string]interface{}{"url": body.URL},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	rv, err := NewRemoteAuthValidator(RemoteAuthConfig{URL: server.URL, DenyCacheTTL: time.Minute})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	rv.now = func() time.Time { return now }
+	try := func(token string) (string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+		tok, err := rv.ParseAndValidate(context.Background(), req, BearerAuthorization, token)
+		if err != nil {
+			return "", err
+		}
+		assert.Equal(RemoteAuthTokenType, tok.Type())
+		u, _ := tok.Attributes().Get("url")
+		assert.Equal("http://example.com/a?b=c", u)
+		return tok.Principal(), nil
+	}
+
+	principal, err := try("good")
+	assert.NoError(err)
+	assert.Equal("user", principal)
+	_, err = try("good")
+	assert.NoError(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	_, err = try("bad")
+	assert.ErrorIs(err, ErrRemoteAuthDenied)
+	assert.Contains(err.Error(), "bad token")
+	_, err = try("bad")
+	assert.ErrorIs(err, ErrRemoteAuthDenied)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+
+	// decisions expire.
+	now = now.Add(2 * time.Minute)
+	_, err = try("good")
+	assert.NoError(err)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRemoteAuthValidatorCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var calls int32
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(RemoteAuthResponse{Allow: true, Principal: "user"})
+	}))
+	defer server.Close()
+
+	rv, err := NewRemoteAuthValidator(RemoteAuthConfig{
+		URL:              server.URL,
+		CacheTTL:         -1,
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	rv.now = func() time.Time { return now }
+	try := func() error {
+		_, err := rv.ParseAndValidate(context.Background(), nil, BearerAuthorization, "token")
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(try(), ErrRemoteAuthUnavailable)
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+
+	// an open circuit says when to try again.
+	now = now.Add(20 * time.Second)
+	var ra retryAfterer
+	require.True(errors.As(try(), &ra))
+	assert.Equal(40*time.Second, ra.RetryAfter())
+	now = now.Add(-20 * time.Second)
+
+	// after the cooldown a single call is tried, which reopens the circuit
+	// if it fails.
+	now = now.Add(time.Minute)
+	assert.ErrorIs(try(), ErrRemoteAuthUnavailable)
+	assert.ErrorIs(try(), ErrRemoteAuthUnavailable)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&healthy, 1)
+	assert.NoError(try())
+	assert.NoError(try())
+	assert.Equal(int32(5), atomic.LoadInt32(&calls))
+
+	// a request ending isn't counted against the authorizer.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = rv.ParseAndValidate(ctx, nil, BearerAuthorization, "token")
+	assert.ErrorIs(err, context.Canceled)
+	assert.Zero(rv.failures)
+}
+
+func TestNewRemoteAuthValidator(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewRemoteAuthValidator(RemoteAuthConfig{})
+	assert.ErrorIs(err, ErrEmptyRemoteAuthURL)
+
+	rv, err := NewRemoteAuthValidator(RemoteAuthConfig{URL: "http://localhost"})
+	assert.NoError(err)
+	assert.Equal(http.DefaultClient, rv.config.Client)
+	assert.Equal(defaultRemoteAuthTimeout, rv.config.Timeout)
+	assert.Equal(defaultRemoteAuthCacheTTL, rv.config.CacheTTL)
+	assert.Zero(rv.config.DenyCacheTTL)
+	assert.Equal(defaultRemoteAuthCacheMaxEntries, rv.config.CacheMaxEntries)
+	assert.Equal(defaultRemoteAuthFailureThreshold, rv.config.FailureThreshold)
+	assert.Equal(defaultRemoteAuthCooldown, rv.config.Cooldown)
+}
@@ -0,0 +1,115 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256" // register the hashes for crypto.Hash
+	_ "crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var ErrUnavailableHash = errors.New("hash function isn't available")
+
+// digestAlgorithms maps hashes to their names in the HTTP Digest Algorithm
+// Values registry.
+var digestAlgorithms = map[crypto.Hash]string{
+	crypto.SHA256: "sha-256",
+	crypto.SHA512: "sha-512",
+}
+
+// bodyDigester hashes request bodies up to a size limit.
+type bodyDigester struct {
+	hash      crypto.Hash
+	algorithm string
+	maxBytes  int64
+}
+
+func newBodyDigester(h crypto.Hash, maxBytes int64) (*bodyDigester, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailableHash, h)
+	}
+	algorithm, ok := digestAlgorithms[h]
+	if !ok {
+		algorithm = strings.ToLower(h.String())
+	}
+	return &bodyDigester{
+		hash:      h,
+		algorithm: algorithm,
+		maxBytes:  maxBytes,
+	}, nil
+}
+
+// digest reads and hashes the body, replacing it so the handler can read it
+// again.  Bodies over the size limit aren't hashed and are passed on as is.
+func (d *bodyDigester) digest(r *http.Request) (*bascule.Digest, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		h := d.hash.New()
+		return &bascule.Digest{Algorithm: d.algorithm, Sum: h.Sum(nil)}, nil
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r.Body, d.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if n > d.maxBytes {
+		r.Body = readCloser{Reader: io.MultiReader(&buf, r.Body), Closer: r.Body}
+		return nil, nil
+	}
+	r.Body.Close()
+	b := buf.Bytes()
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	h := d.hash.New()
+	h.Write(b)
+	return &bascule.Digest{Algorithm: d.algorithm, Sum: h.Sum(nil)}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureRequest adds the request's time and body digest to the
+// authentication, if the constructor captures them.
+func (c *constructor) captureRequest(r *http.Request, auth *bascule.Authentication, received time.Time) error {
+	if c.captureTime {
+		auth.Request.Time = received
+	}
+	if c.digester == nil || c.headersOnly {
+		return nil
+	}
+	d, err := c.digester.digest(r)
+	if err != nil {
+		return err
+	}
+	auth.Request.Digest = d
+	return nil
+}
@@ -0,0 +1,161 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestConstructorRequestCapture(t *testing.T) {
+	sum256 := sha256.Sum256([]byte("request body"))
+	sum512 := sha512.Sum512([]byte("request body"))
+	emptySum := sha256.Sum256(nil)
+	tests := []struct {
+		description    string
+		options        []COption
+		body           io.Reader
+		expectedTime   bool
+		expectedDigest *bascule.Digest
+		expectedStatus int
+	}{
+		{
+			description:    "Nothing Captured",
+			body:           strings.NewReader("request body"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Time",
+			options:        []COption{WithCRequestTime()},
+			body:           strings.NewReader("request body"),
+			expectedTime:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "SHA-256",
+			options:        []COption{WithCBodyDigest(crypto.SHA256, 1024)},
+			body:           strings.NewReader("request body"),
+			expectedDigest: &bascule.Digest{Algorithm: "sha-256", Sum: sum256[:]},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "SHA-512 At Limit",
+			options:        []COption{WithCBodyDigest(crypto.SHA512, int64(len("request body")))},
+			body:           strings.NewReader("request body"),
+			expectedDigest: &bascule.Digest{Algorithm: "sha-512", Sum: sum512[:]},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Empty Body",
+			options:        []COption{WithCBodyDigest(crypto.SHA256, 1024)},
+			expectedDigest: &bascule.Digest{Algorithm: "sha-256", Sum: emptySum[:]},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Too Large",
+			options:        []COption{WithCBodyDigest(crypto.SHA256, 4)},
+			body:           strings.NewReader("request body"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Unavailable Hash",
+			options:        []COption{WithCBodyDigest(crypto.MD4, 1024)},
+			body:           strings.NewReader("request body"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Headers Only",
+			options:        []COption{WithCBodyDigest(crypto.SHA256, 1024), WithCHeadersOnly()},
+			body:           strings.NewReader("request body"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "Read Error",
+			options:        []COption{WithCBodyDigest(crypto.SHA256, 1024)},
+			body:           errReader{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				auth bascule.Authentication
+				body string
+			)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ok bool
+				auth, ok = bascule.FromContext(r.Context())
+				require.True(t, ok)
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				body = string(b)
+			})
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				return bascule.NewToken("test", "principal", bascule.NewAttributes(nil)), nil
+			})
+			options := append(tc.options, WithTokenFactory(BasicAuthorization, tf))
+			req := httptest.NewRequest(http.MethodPost, "/", tc.body)
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			w := httptest.NewRecorder()
+			start := time.Now()
+			NewConstructor(options...)(handler).ServeHTTP(w, req)
+			assert.Equal(tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if tc.body != nil {
+				assert.Equal("request body", body)
+			}
+			if tc.expectedTime {
+				assert.WithinDuration(start, auth.Request.Time, time.Second)
+			} else {
+				assert.True(auth.Request.Time.IsZero())
+			}
+			assert.Equal(tc.expectedDigest, auth.Request.Digest)
+		})
+	}
+}
+
+func TestNewBodyDigester(t *testing.T) {
+	d, err := newBodyDigester(crypto.SHA384, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "sha-384", d.algorithm)
+
+	_, err = newBodyDigester(crypto.MD4, 10)
+	assert.ErrorIs(t, err, ErrUnavailableHash)
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+	"go.uber.org/zap"
+)
+
+const (
+	// RequestIDHeader is the header a request's correlation ID is read from
+	// by default, and the response header it's echoed in.
+	RequestIDHeader = "X-Request-Id"
+
+	// TraceparentHeader is the W3C trace context header.  The trace ID is
+	// used as the request ID.
+	TraceparentHeader = "traceparent"
+
+	// RequestIDLogKey is the log key of the request ID.
+	RequestIDLogKey = "requestID"
+
+	// maxRequestIDLength limits the IDs accepted from clients, since they
+	// end up in logs.
+	maxRequestIDLength = 128
+)
+
+// DefaultRequestIDHeaders returns the headers searched for a request ID when
+// none are configured: X-Request-Id, then traceparent.
+func DefaultRequestIDHeaders() []string {
+	return []string{RequestIDHeader, TraceparentHeader}
+}
+
+// WithCRequestID has the constructor extract a correlation ID from the first
+// of the headers given that the request has, defaulting to
+// DefaultRequestIDHeaders.  The ID is added to the request context with
+// bascule.WithRequestID and to the Authentication's Request, so that
+// listeners and hooks have it, and to the log messages of the constructor,
+// enforcer, and listener decorator.  It's also echoed in the X-Request-Id
+// response header and included in error bodies.  IDs that are too long or
+// have characters other than printable ASCII are ignored.
+func WithCRequestID(headers ...string) COption {
+	return func(c *constructor) {
+		if len(headers) == 0 {
+			headers = DefaultRequestIDHeaders()
+		}
+		c.requestIDHeaders = headers
+	}
+}
+
+// requestID returns the correlation ID from the first of the headers the
+// request has a valid one in.
+func requestID(r *http.Request, headers []string) (string, bool) {
+	for _, h := range headers {
+		v := strings.TrimSpace(r.Header.Get(h))
+		if v == "" {
+			continue
+		}
+		if strings.EqualFold(h, TraceparentHeader) {
+			var ok bool
+			if v, ok = traceID(v); !ok {
+				continue
+			}
+		}
+		if validRequestID(v) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// traceID returns the trace ID of a W3C traceparent header value, which is
+// formatted as version-traceid-parentid-flags.
+func traceID(traceparent string) (string, bool) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return "", false
+	}
+	id := parts[1]
+	zero := true
+	for _, c := range id {
+		switch {
+		case c == '0':
+		case (c >= '1' && c <= '9') || (c >= 'a' && c <= 'f'):
+			zero = false
+		default:
+			return "", false
+		}
+	}
+	return id, !zero
+}
+
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// withRequestIDField adds the request ID in the context, if there is one, to
+// the logger's fields.
+func withRequestIDField(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id, ok := bascule.RequestIDFromContext(ctx); ok {
+		return logger.With(zap.String(RequestIDLogKey, id))
+	}
+	return logger
+}
@@ -0,0 +1,167 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	const trace = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		description string
+		headers     []string
+		request     map[string]string
+		expectedID  string
+	}{
+		{
+			description: "Request ID",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{RequestIDHeader: "abc-123", TraceparentHeader: trace},
+			expectedID:  "abc-123",
+		},
+		{
+			description: "Traceparent",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{TraceparentHeader: trace},
+			expectedID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			description: "Custom Header",
+			headers:     []string{"X-Correlation-Id"},
+			request:     map[string]string{"X-Correlation-Id": " xyz ", RequestIDHeader: "abc-123"},
+			expectedID:  "xyz",
+		},
+		{
+			description: "Invalid Falls Through",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{RequestIDHeader: "abc\x00", TraceparentHeader: trace},
+			expectedID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			description: "Too Long",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{RequestIDHeader: strings.Repeat("a", maxRequestIDLength+1)},
+		},
+		{
+			description: "Zero Trace ID",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{TraceparentHeader: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		},
+		{
+			description: "Malformed Traceparent",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{TraceparentHeader: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		},
+		{
+			description: "Short Traceparent",
+			headers:     DefaultRequestIDHeaders(),
+			request:     map[string]string{TraceparentHeader: "00-4bf92f"},
+		},
+		{
+			description: "Disabled",
+			request:     map[string]string{RequestIDHeader: "abc-123"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.request {
+				r.Header.Set(k, v)
+			}
+			id, ok := requestID(r, tc.headers)
+			assert.Equal(tc.expectedID != "", ok)
+			assert.Equal(tc.expectedID, id)
+		})
+	}
+}
+
+func TestConstructorRequestID(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+	getLogger := func(context.Context) *zap.Logger { return logger }
+
+	var (
+		events []HookEvent
+		auth   bascule.Authentication
+	)
+	hook := func(_ http.ResponseWriter, _ *http.Request, e HookEvent) *http.Request {
+		events = append(events, e)
+		return nil
+	}
+	handler, err := NewAuthHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth, _ = bascule.FromContext(r.Context())
+		}),
+		WithAuthLogger(getLogger),
+		WithConstructorOptions(
+			WithCRequestID(),
+			WithTokenFactory(BasicAuthorization, BasicTokenFactory{"user": "pass"}),
+			WithCErrorBodies(ErrorBodyConfig{}),
+			WithCHook(AfterAllow, hook),
+			WithCHook(AfterDeny, hook),
+		),
+		WithEnforcerOptions(WithRules(BasicAuthorization, bascule.Validators{})),
+	)
+	require.NoError(err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "good-request")
+	r.SetBasicAuth("user", "pass")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("good-request", w.Header().Get(RequestIDHeader))
+	assert.Equal("good-request", auth.Request.RequestID)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "bad-request")
+	r.SetBasicAuth("user", "wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.Equal("bad-request", w.Header().Get(RequestIDHeader))
+	var body ErrorBody
+	require.NoError(json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal("bad-request", body.RequestID)
+
+	if assert.Len(events, 2) {
+		assert.Equal("good-request", events[0].RequestID)
+		assert.Equal("bad-request", events[1].RequestID)
+	}
+	entries := logs.AllUntimed()
+	require.NotEmpty(entries)
+	for _, entry := range entries {
+		assert.Contains(entry.ContextMap(), RequestIDLogKey, entry.Message)
+	}
+}
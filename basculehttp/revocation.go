@@ -0,0 +1,394 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	defaultOCSPTimeout  = 5 * time.Second
+	defaultOCSPCacheTTL = time.Hour
+	maxOCSPResponseSize = 1 << 20
+	ocspRequestType     = "application/ocsp-request"
+)
+
+// Methods of checking a certificate's revocation status.
+const (
+	StapledRevocation = "stapled"
+	CRLRevocation     = "crl"
+	OCSPRevocation    = "ocsp"
+	NoRevocation      = "none"
+)
+
+// Revocation check statuses.
+const (
+	RevocationGood     = "good"
+	RevocationRevoked  = "revoked"
+	RevocationUnknown  = "unknown"
+	RevocationSoftFail = "soft_fail"
+)
+
+var (
+	// ErrCertificateRevoked is returned when a certificate in the chain has
+	// been revoked.
+	ErrCertificateRevoked = bascule.NewClassError(bascule.InvalidClass, "certificate has been revoked")
+
+	// ErrRevocationUnknown is returned when a certificate's revocation status
+	// couldn't be determined and soft failing is off.
+	ErrRevocationUnknown = bascule.NewClassError(bascule.UnavailableClass, "certificate revocation status unknown")
+
+	// ErrNoPeerCertificates is returned when a certificate chain is required
+	// but the peer didn't send one.
+	ErrNoPeerCertificates = bascule.NewClassError(bascule.MissingCredentialsClass, "no peer certificates")
+)
+
+// RevocationConfig configures a RevocationChecker.
+type RevocationConfig struct {
+	// CRLFiles are the paths of the CRLs to check certificates against, PEM
+	// or DER encoded.  A CRL is only used for certificates whose issuer
+	// signed it, and only until its next update.
+	CRLFiles []string
+
+	// OCSP turns on querying the OCSP responders listed in certificates.
+	OCSP bool
+
+	// OCSPTimeout bounds each OCSP request.  Defaults to 5 seconds.
+	OCSPTimeout time.Duration
+
+	// OCSPCacheTTL is the longest an OCSP response is cached.  Responses are
+	// never cached past their next update.  Defaults to one hour.
+	OCSPCacheTTL time.Duration
+
+	// SoftFail accepts certificates whose status can't be determined, such as
+	// when no CRL covers the issuer and the OCSP responder can't be reached.
+	// Revoked certificates are always rejected.
+	SoftFail bool
+
+	// CheckIntermediates checks every certificate in the chain except the
+	// root, rather than only the leaf.
+	CheckIntermediates bool
+
+	// HTTPClient sends OCSP requests.  Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// crl is a parsed revocation list with its revoked serial numbers indexed.
+// The issuers that signed it are remembered, so that its signature is checked
+// once per load rather than on every handshake.
+type crl struct {
+	list    *x509.RevocationList
+	revoked map[string]bool
+
+	lock     sync.RWMutex
+	verified map[[sha256.Size]byte]bool
+}
+
+// signedBy returns true if the issuer signed the CRL.  Only issuers whose
+// signature checks out are remembered, so certificates with forged issuers
+// can't grow the CRL's memory.
+func (c *crl) signedBy(issuer *x509.Certificate) bool {
+	key := sha256.Sum256(issuer.Raw)
+	c.lock.RLock()
+	verified := c.verified[key]
+	c.lock.RUnlock()
+	if verified {
+		return true
+	}
+	if c.list.CheckSignatureFrom(issuer) != nil {
+		return false
+	}
+	c.lock.Lock()
+	c.verified[key] = true
+	c.lock.Unlock()
+	return true
+}
+
+type ocspEntry struct {
+	status  int
+	expires time.Time
+}
+
+// RevocationChecker checks client certificates for revocation using CRL
+// files, stapled OCSP responses, and OCSP responders.  Its VerifyConnection
+// method can be set on a tls.Config so that revoked certificates are rejected
+// during the mTLS handshake, before any certificate-bound token is parsed.
+type RevocationChecker struct {
+	config   RevocationConfig
+	measures *RevocationMeasures
+	server   string
+	now      func() time.Time
+
+	lock  sync.RWMutex
+	crls  []*crl
+	cache map[string]ocspEntry
+}
+
+// NewRevocationChecker creates a RevocationChecker, loading the CRL files
+// configured.  The measures are optional.
+func NewRevocationChecker(config RevocationConfig, measures *RevocationMeasures, server string) (*RevocationChecker, error) {
+	if config.OCSPTimeout <= 0 {
+		config.OCSPTimeout = defaultOCSPTimeout
+	}
+	if config.OCSPCacheTTL <= 0 {
+		config.OCSPCacheTTL = defaultOCSPCacheTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	r := &RevocationChecker{
+		config:   config,
+		measures: measures,
+		server:   server,
+		now:      time.Now,
+		cache:    make(map[string]ocspEntry),
+	}
+	if err := r.ReloadCRLs(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReloadCRLs reads the CRL files again, replacing the CRLs in use only if all
+// of them load.
+func (r *RevocationChecker) ReloadCRLs() error {
+	crls := make([]*crl, 0, len(r.config.CRLFiles))
+	for _, file := range r.config.CRLFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read CRL [%s]: %w", file, err)
+		}
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		list, err := x509.ParseRevocationList(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse CRL [%s]: %w", file, err)
+		}
+		c := &crl{
+			list:     list,
+			revoked:  make(map[string]bool, len(list.RevokedCertificates)),
+			verified: make(map[[sha256.Size]byte]bool),
+		}
+		for _, rc := range list.RevokedCertificates {
+			c.revoked[rc.SerialNumber.String()] = true
+		}
+		crls = append(crls, c)
+	}
+	r.lock.Lock()
+	r.crls = crls
+	r.lock.Unlock()
+	return nil
+}
+
+// VerifyConnection checks the verified chain of a TLS connection, using the
+// stapled OCSP response if the peer sent one.  It is meant for
+// tls.Config.VerifyConnection, with the client certificates already verified
+// by the tls package.
+func (r *RevocationChecker) VerifyConnection(cs tls.ConnectionState) error {
+	chain := cs.PeerCertificates
+	if len(cs.VerifiedChains) > 0 {
+		chain = cs.VerifiedChains[0]
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.OCSPTimeout)
+	defer cancel()
+	return r.Check(ctx, chain, cs.OCSPResponse)
+}
+
+// Check returns an error if a certificate in the chain, ordered from the leaf
+// to the root, has been revoked or, unless soft failing, if its status can't
+// be determined.  The stapled OCSP response, if any, is for the leaf.  An
+// empty chain has nothing to check, so whether a client certificate is
+// required is left to the tls.Config's ClientAuth.
+func (r *RevocationChecker) Check(ctx context.Context, chain []*x509.Certificate, stapled []byte) error {
+	if len(chain) == 0 {
+		return nil
+	}
+	if len(chain) == 1 {
+		// without the issuer, neither CRLs nor OCSP can vouch for the leaf.
+		if r.config.SoftFail {
+			r.record(NoRevocation, RevocationSoftFail)
+			return nil
+		}
+		r.record(NoRevocation, RevocationUnknown)
+		return fmt.Errorf("%w: no issuer for serial number %s", ErrRevocationUnknown, chain[0].SerialNumber)
+	}
+	last := 1
+	if r.config.CheckIntermediates {
+		last = len(chain) - 1
+	}
+	for i := 0; i < last && i+1 < len(chain); i++ {
+		var staple []byte
+		if i == 0 {
+			staple = stapled
+		}
+		method, status := r.status(ctx, chain[i], chain[i+1], staple)
+		switch status {
+		case ocsp.Good:
+			r.record(method, RevocationGood)
+		case ocsp.Revoked:
+			r.record(method, RevocationRevoked)
+			return fmt.Errorf("%w: serial number %s", ErrCertificateRevoked, chain[i].SerialNumber)
+		default:
+			if r.config.SoftFail {
+				r.record(method, RevocationSoftFail)
+				continue
+			}
+			r.record(method, RevocationUnknown)
+			return fmt.Errorf("%w: serial number %s", ErrRevocationUnknown, chain[i].SerialNumber)
+		}
+	}
+	return nil
+}
+
+// status determines the certificate's revocation status, trying the stapled
+// response, then the CRLs, then the OCSP responders.  It returns the method
+// that settled the status, or the last one tried if none did.
+func (r *RevocationChecker) status(ctx context.Context, cert, issuer *x509.Certificate, stapled []byte) (string, int) {
+	now := r.now()
+	method := NoRevocation
+	if len(stapled) > 0 {
+		method = StapledRevocation
+		if resp, err := ocsp.ParseResponseForCert(stapled, cert, issuer); err == nil && fresh(resp, now) {
+			return method, resp.Status
+		}
+	}
+	if status, ok := r.crlStatus(cert, issuer, now); ok {
+		return CRLRevocation, status
+	}
+	if r.config.OCSP && len(cert.OCSPServer) > 0 {
+		return OCSPRevocation, r.ocspStatus(ctx, cert, issuer, now)
+	}
+	if len(r.config.CRLFiles) > 0 && method == NoRevocation {
+		method = CRLRevocation
+	}
+	return method, ocsp.Unknown
+}
+
+func (r *RevocationChecker) crlStatus(cert, issuer *x509.Certificate, now time.Time) (int, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, c := range r.crls {
+		if !bytes.Equal(c.list.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+		if !c.list.NextUpdate.IsZero() && now.After(c.list.NextUpdate) {
+			continue
+		}
+		if !c.signedBy(issuer) {
+			continue
+		}
+		if c.revoked[cert.SerialNumber.String()] {
+			return ocsp.Revoked, true
+		}
+		return ocsp.Good, true
+	}
+	return ocsp.Unknown, false
+}
+
+func (r *RevocationChecker) ocspStatus(ctx context.Context, cert, issuer *x509.Certificate, now time.Time) int {
+	sum := sha256.Sum256(issuer.Raw)
+	key := hex.EncodeToString(sum[:]) + ":" + cert.SerialNumber.String()
+	r.lock.RLock()
+	cached, found := r.cache[key]
+	r.lock.RUnlock()
+	if found && now.Before(cached.expires) {
+		return cached.status
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return ocsp.Unknown
+	}
+	for _, server := range cert.OCSPServer {
+		resp, err := r.queryOCSP(ctx, server, request, cert, issuer)
+		if err != nil || !fresh(resp, now) {
+			continue
+		}
+		expires := now.Add(r.config.OCSPCacheTTL)
+		if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(expires) {
+			expires = resp.NextUpdate
+		}
+		r.lock.Lock()
+		r.cache[key] = ocspEntry{status: resp.Status, expires: expires}
+		r.lock.Unlock()
+		return resp.Status
+	}
+	return ocsp.Unknown
+}
+
+func (r *RevocationChecker) queryOCSP(ctx context.Context, server string, request []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.OCSPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ocspRequestType)
+	resp, err := r.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected OCSP response status [%d]", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, cert, issuer)
+}
+
+// fresh reports whether the OCSP response can be relied on now.
+func fresh(resp *ocsp.Response, now time.Time) bool {
+	if resp.Status == ocsp.Unknown || now.Before(resp.ThisUpdate) {
+		return false
+	}
+	return resp.NextUpdate.IsZero() || now.Before(resp.NextUpdate)
+}
+
+func (r *RevocationChecker) record(method, status string) {
+	if r.measures == nil || r.measures.RevocationChecks == nil {
+		return
+	}
+	r.measures.RevocationChecks.With(prometheus.Labels{
+		ServerLabel:           r.server,
+		RevocationMethodLabel: method,
+		RevocationStatusLabel: status,
+	}).Add(1)
+}
@@ -0,0 +1,456 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T, name string, parent *testCA) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	signer, signerKey := template, crypto.Signer(key)
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, key.Public(), signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) issue(t *testing.T, serial int64, ocspServers ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		OCSPServer:   ocspServers,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeCRL writes a CRL revoking the serials given, PEM encoded if asked.
+func (ca testCA) writeCRL(t *testing.T, nextUpdate time.Time, usePEM bool, serials ...int64) string {
+	revoked := make([]pkix.RevokedCertificate, 0, len(serials))
+	for _, s := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(s),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: revoked,
+		Number:              big.NewInt(1),
+		ThisUpdate:          time.Now().Add(-time.Hour),
+		NextUpdate:          nextUpdate,
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	if usePEM {
+		der = pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	}
+	file := filepath.Join(t.TempDir(), "ca.crl")
+	require.NoError(t, os.WriteFile(file, der, 0600))
+	return file
+}
+
+func (ca testCA) ocspResponse(t *testing.T, cert *x509.Certificate, status int) []byte {
+	resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, ca.key)
+	require.NoError(t, err)
+	return resp
+}
+
+func newRevocationMeasures() *RevocationMeasures {
+	return &RevocationMeasures{
+		RevocationChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testRevocationChecks",
+		}, []string{ServerLabel, RevocationMethodLabel, RevocationStatusLabel}),
+	}
+}
+
+func revocationCount(m *RevocationMeasures, method, status string) float64 {
+	return testutil.ToFloat64(m.RevocationChecks.With(prometheus.Labels{
+		ServerLabel:           defaultServer,
+		RevocationMethodLabel: method,
+		RevocationStatusLabel: status,
+	}))
+}
+
+func TestNewRevocationChecker(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	r, err := NewRevocationChecker(RevocationConfig{}, nil, "")
+	require.NoError(err)
+	assert.Equal(defaultOCSPTimeout, r.config.OCSPTimeout)
+	assert.Equal(defaultOCSPCacheTTL, r.config.OCSPCacheTTL)
+	assert.Equal(http.DefaultClient, r.config.HTTPClient)
+	assert.Equal(defaultServer, r.server)
+
+	_, err = NewRevocationChecker(RevocationConfig{
+		CRLFiles: []string{filepath.Join(t.TempDir(), "missing.crl")},
+	}, nil, "")
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	bad := filepath.Join(t.TempDir(), "bad.crl")
+	require.NoError(os.WriteFile(bad, []byte("not a crl"), 0600))
+	_, err = NewRevocationChecker(RevocationConfig{CRLFiles: []string{bad}}, nil, "")
+	assert.ErrorContains(err, "failed to parse CRL")
+
+	// without a client certificate, ClientAuth decides.
+	assert.NoError(r.Check(context.Background(), nil, nil))
+	assert.NoError(r.VerifyConnection(tls.ConnectionState{}))
+}
+
+func TestRevocationCheckerLeafOnly(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	root := newTestCA(t, "root", nil)
+	cert := root.issue(t, 100)
+	measures := newRevocationMeasures()
+
+	r, err := NewRevocationChecker(RevocationConfig{}, measures, "")
+	require.NoError(err)
+	err = r.Check(context.Background(), []*x509.Certificate{cert}, root.ocspResponse(t, cert, ocsp.Good))
+	assert.ErrorIs(err, ErrRevocationUnknown)
+	assert.Equal(1.0, revocationCount(measures, NoRevocation, RevocationUnknown))
+
+	r, err = NewRevocationChecker(RevocationConfig{SoftFail: true}, measures, "")
+	require.NoError(err)
+	assert.NoError(r.Check(context.Background(), []*x509.Certificate{cert}, nil))
+	assert.Equal(1.0, revocationCount(measures, NoRevocation, RevocationSoftFail))
+}
+
+func TestRevocationCheckerCRL(t *testing.T) {
+	root := newTestCA(t, "root", nil)
+	other := newTestCA(t, "other", nil)
+	good := root.issue(t, 100)
+	revoked := root.issue(t, 200)
+	later := time.Now().Add(time.Hour)
+	stale := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		description string
+		crl         string
+		softFail    bool
+		cert        *x509.Certificate
+		expectedErr error
+		status      string
+	}{
+		{
+			description: "Good",
+			crl:         root.writeCRL(t, later, false, 200),
+			cert:        good,
+			status:      RevocationGood,
+		},
+		{
+			description: "Good PEM",
+			crl:         root.writeCRL(t, later, true, 200),
+			cert:        good,
+			status:      RevocationGood,
+		},
+		{
+			description: "Revoked",
+			crl:         root.writeCRL(t, later, true, 200),
+			cert:        revoked,
+			expectedErr: ErrCertificateRevoked,
+			status:      RevocationRevoked,
+		},
+		{
+			description: "Revoked with soft fail",
+			crl:         root.writeCRL(t, later, false, 200),
+			softFail:    true,
+			cert:        revoked,
+			expectedErr: ErrCertificateRevoked,
+			status:      RevocationRevoked,
+		},
+		{
+			description: "Stale CRL",
+			crl:         root.writeCRL(t, stale, false, 200),
+			cert:        good,
+			expectedErr: ErrRevocationUnknown,
+			status:      RevocationUnknown,
+		},
+		{
+			description: "Stale CRL with soft fail",
+			crl:         root.writeCRL(t, stale, false, 200),
+			softFail:    true,
+			cert:        revoked,
+			status:      RevocationSoftFail,
+		},
+		{
+			description: "Forged CRL",
+			crl:         newTestCA(t, "root", nil).writeCRL(t, later, false),
+			cert:        revoked,
+			expectedErr: ErrRevocationUnknown,
+			status:      RevocationUnknown,
+		},
+		{
+			description: "Other issuer",
+			crl:         other.writeCRL(t, later, false, 200),
+			cert:        revoked,
+			expectedErr: ErrRevocationUnknown,
+			status:      RevocationUnknown,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			measures := newRevocationMeasures()
+			r, err := NewRevocationChecker(RevocationConfig{
+				CRLFiles: []string{tc.crl},
+				SoftFail: tc.softFail,
+			}, measures, "")
+			require.NoError(err)
+			err = r.Check(context.Background(), []*x509.Certificate{tc.cert, root.cert}, nil)
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Equal(1.0, revocationCount(measures, CRLRevocation, tc.status))
+		})
+	}
+}
+
+func TestRevocationCheckerReloadCRLs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	root := newTestCA(t, "root", nil)
+	cert := root.issue(t, 100)
+	file := root.writeCRL(t, time.Now().Add(time.Hour), false)
+	r, err := NewRevocationChecker(RevocationConfig{CRLFiles: []string{file}}, nil, "")
+	require.NoError(err)
+	chain := []*x509.Certificate{cert, root.cert}
+	assert.NoError(r.Check(context.Background(), chain, nil))
+	assert.NoError(r.Check(context.Background(), chain, nil))
+	// the CRL's signature is only checked once per load.
+	assert.Len(r.crls[0].verified, 1)
+
+	data, err := os.ReadFile(root.writeCRL(t, time.Now().Add(time.Hour), false, 100))
+	require.NoError(err)
+	require.NoError(os.WriteFile(file, data, 0600))
+	require.NoError(r.ReloadCRLs())
+	assert.ErrorIs(r.Check(context.Background(), chain, nil), ErrCertificateRevoked)
+
+	// a failed reload keeps the CRLs already loaded.
+	require.NoError(os.WriteFile(file, []byte("garbage"), 0600))
+	assert.Error(r.ReloadCRLs())
+	assert.ErrorIs(r.Check(context.Background(), chain, nil), ErrCertificateRevoked)
+}
+
+func TestRevocationCheckerIntermediates(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	root := newTestCA(t, "root", nil)
+	intermediate := newTestCA(t, "intermediate", &root)
+	cert := intermediate.issue(t, 100)
+	chain := []*x509.Certificate{cert, intermediate.cert, root.cert}
+	files := []string{
+		intermediate.writeCRL(t, time.Now().Add(time.Hour), false),
+		root.writeCRL(t, time.Now().Add(time.Hour), false, intermediate.cert.SerialNumber.Int64()),
+	}
+
+	r, err := NewRevocationChecker(RevocationConfig{CRLFiles: files}, nil, "")
+	require.NoError(err)
+	assert.NoError(r.Check(context.Background(), chain, nil))
+
+	r, err = NewRevocationChecker(RevocationConfig{CRLFiles: files, CheckIntermediates: true}, nil, "")
+	require.NoError(err)
+	assert.ErrorIs(r.Check(context.Background(), chain, nil), ErrCertificateRevoked)
+}
+
+func TestRevocationCheckerStapled(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	root := newTestCA(t, "root", nil)
+	other := newTestCA(t, "other", nil)
+	cert := root.issue(t, 100)
+	chain := []*x509.Certificate{cert, root.cert}
+	measures := newRevocationMeasures()
+	r, err := NewRevocationChecker(RevocationConfig{}, measures, "")
+	require.NoError(err)
+
+	assert.NoError(r.Check(context.Background(), chain, root.ocspResponse(t, cert, ocsp.Good)))
+	assert.Equal(1.0, revocationCount(measures, StapledRevocation, RevocationGood))
+
+	err = r.Check(context.Background(), chain, root.ocspResponse(t, cert, ocsp.Revoked))
+	assert.ErrorIs(err, ErrCertificateRevoked)
+	assert.Equal(1.0, revocationCount(measures, StapledRevocation, RevocationRevoked))
+
+	// a response from the wrong issuer is ignored.
+	err = r.Check(context.Background(), chain, other.ocspResponse(t, cert, ocsp.Good))
+	assert.ErrorIs(err, ErrRevocationUnknown)
+	assert.Equal(1.0, revocationCount(measures, StapledRevocation, RevocationUnknown))
+
+	err = r.VerifyConnection(tls.ConnectionState{
+		PeerCertificates: chain,
+		OCSPResponse:     root.ocspResponse(t, cert, ocsp.Revoked),
+	})
+	assert.ErrorIs(err, ErrCertificateRevoked)
+
+	err = r.VerifyConnection(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{chain},
+		OCSPResponse:     root.ocspResponse(t, cert, ocsp.Good),
+	})
+	assert.NoError(err)
+
+	err = r.Check(context.Background(), chain, nil)
+	assert.ErrorIs(err, ErrRevocationUnknown)
+	assert.Equal(1.0, revocationCount(measures, NoRevocation, RevocationUnknown))
+}
+
+func TestRevocationCheckerOCSP(t *testing.T) {
+	root := newTestCA(t, "root", nil)
+	tests := []struct {
+		description string
+		status      int
+		httpStatus  int
+		softFail    bool
+		expectedErr error
+		outcome     string
+		requests    int32
+	}{
+		{
+			description: "Good",
+			status:      ocsp.Good,
+			httpStatus:  http.StatusOK,
+			outcome:     RevocationGood,
+			requests:    1,
+		},
+		{
+			description: "Revoked",
+			status:      ocsp.Revoked,
+			httpStatus:  http.StatusOK,
+			expectedErr: ErrCertificateRevoked,
+			outcome:     RevocationRevoked,
+			requests:    1,
+		},
+		{
+			description: "Unknown",
+			status:      ocsp.Unknown,
+			httpStatus:  http.StatusOK,
+			expectedErr: ErrRevocationUnknown,
+			outcome:     RevocationUnknown,
+			requests:    2,
+		},
+		{
+			description: "Responder failure",
+			httpStatus:  http.StatusInternalServerError,
+			expectedErr: ErrRevocationUnknown,
+			outcome:     RevocationUnknown,
+			requests:    2,
+		},
+		{
+			description: "Responder failure with soft fail",
+			httpStatus:  http.StatusInternalServerError,
+			softFail:    true,
+			outcome:     RevocationSoftFail,
+			requests:    2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var (
+				requests int32
+				cert     *x509.Certificate
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(err)
+				_, err = ocsp.ParseRequest(body)
+				assert.NoError(err)
+				assert.Equal(ocspRequestType, r.Header.Get("Content-Type"))
+				w.WriteHeader(tc.httpStatus)
+				if tc.httpStatus == http.StatusOK {
+					_, _ = w.Write(root.ocspResponse(t, cert, tc.status))
+				}
+			}))
+			defer server.Close()
+			cert = root.issue(t, 100, server.URL)
+
+			measures := newRevocationMeasures()
+			r, err := NewRevocationChecker(RevocationConfig{
+				OCSP:       true,
+				SoftFail:   tc.softFail,
+				HTTPClient: server.Client(),
+			}, measures, "")
+			require.NoError(err)
+			chain := []*x509.Certificate{cert, root.cert}
+			for i := 0; i < 2; i++ {
+				err = r.Check(context.Background(), chain, nil)
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+			// only definite answers are cached.
+			assert.Equal(tc.requests, atomic.LoadInt32(&requests))
+			assert.Equal(2.0, revocationCount(measures, OCSPRevocation, tc.outcome))
+		})
+	}
+}
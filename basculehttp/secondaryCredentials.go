@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// SecondaryCredentialsKey is the attribute key under which secondary
+// credentials are stored on the primary Token.  The value is a
+// map[string]bascule.Token keyed by credential name.
+const SecondaryCredentialsKey = "secondaryCredentials"
+
+var ErrNoCredential = bascule.NewClassError(bascule.MissingCredentialsClass, "no credential found in request")
+
+// secondaryCredential is a TokenFactory run after the primary TokenFactory,
+// pulling its credential from somewhere other than the authorization header.
+type secondaryCredential struct {
+	name     string
+	factory  TokenFactory
+	required bool
+}
+
+// NewHeaderCredential creates a TokenFactory meant for use as a secondary
+// credential.  It ignores the value it is given and instead passes the value
+// of the header named to the TokenFactory provided.
+func NewHeaderCredential(header string, tf TokenFactory) TokenFactory {
+	return TokenFactoryFunc(func(ctx context.Context, r *http.Request, a bascule.Authorization, _ string) (bascule.Token, error) {
+		value := r.Header.Get(header)
+		if len(value) == 0 {
+			return nil, fmt.Errorf("%w: header %v", ErrNoCredential, header)
+		}
+		return tf.ParseAndValidate(ctx, r, a, value)
+	})
+}
+
+// WithSecondaryTokenFactory adds a TokenFactory that is run after the primary
+// TokenFactory succeeds.  The factory is called with the credential name as
+// the Authorization and an empty value, so it must get its credential from
+// the request itself; NewHeaderCredential helps with that.  If required is
+// true, a failure of this factory fails the request.  Otherwise the
+// credential is simply left out.
+func WithSecondaryTokenFactory(name string, tf TokenFactory, required bool) COption {
+	return func(c *constructor) {
+		if tf != nil && len(name) > 0 {
+			c.secondaries = append(c.secondaries, secondaryCredential{
+				name:     name,
+				factory:  tf,
+				required: required,
+			})
+		}
+	}
+}
+
+// parseSecondaries runs the secondary token factories and merges their tokens
+// into the primary token.
+func (c *constructor) parseSecondaries(ctx context.Context, r *http.Request, primary bascule.Token) (bascule.Token, error) {
+	if len(c.secondaries) == 0 {
+		return primary, nil
+	}
+	credentials := make(map[string]bascule.Token, len(c.secondaries))
+	for _, s := range c.secondaries {
+		t, err := s.factory.ParseAndValidate(ctx, r, bascule.Authorization(s.name), "")
+		if err != nil {
+			if s.required {
+				return nil, fmt.Errorf("failed to parse and validate secondary credential [%v]: %v", s.name, err)
+			}
+			continue
+		}
+		credentials[s.name] = t
+	}
+	if len(credentials) == 0 {
+		return primary, nil
+	}
+	return bascule.NewToken(primary.Type(), primary.Principal(), credentialAttributes{
+		credentials: credentials,
+		base:        primary.Attributes(),
+	}), nil
+}
+
+// GetSecondaryCredential returns the secondary credential token with the name
+// given, if the request included one.
+func GetSecondaryCredential(auth bascule.Authentication, name string) (bascule.Token, bool) {
+	if auth.Token == nil || auth.Token.Attributes() == nil {
+		return nil, false
+	}
+	v, ok := auth.Token.Attributes().Get(SecondaryCredentialsKey)
+	if !ok {
+		return nil, false
+	}
+	credentials, ok := v.(map[string]bascule.Token)
+	if !ok {
+		return nil, false
+	}
+	t, ok := credentials[name]
+	return t, ok
+}
+
+// credentialAttributes adds the secondary credentials to the primary token's
+// attributes.
+type credentialAttributes struct {
+	credentials map[string]bascule.Token
+	base        bascule.Attributes
+}
+
+func (c credentialAttributes) Get(key string) (interface{}, bool) {
+	if key == SecondaryCredentialsKey {
+		return c.credentials, true
+	}
+	if c.base == nil {
+		return nil, false
+	}
+	return c.base.Get(key)
+}
+
+func (c credentialAttributes) Keys() ([]string, bool) {
+	return bascule.LayeredKeys(c.base, SecondaryCredentialsKey)
+}
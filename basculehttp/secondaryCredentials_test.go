@@ -0,0 +1,132 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecondaryCredentials(t *testing.T) {
+	apiKeys := TokenFactoryFunc(func(_ context.Context, _ *http.Request, a bascule.Authorization, v string) (bascule.Token, error) {
+		if v != "good-key" {
+			return nil, errors.New("bad api key")
+		}
+		return bascule.NewToken(string(a), "key-owner", nil), nil
+	})
+	tests := []struct {
+		description        string
+		required           bool
+		apiKey             string
+		expectedStatusCode int
+		expectCredential   bool
+	}{
+		{
+			description:        "Success",
+			required:           true,
+			apiKey:             "good-key",
+			expectedStatusCode: http.StatusOK,
+			expectCredential:   true,
+		},
+		{
+			description:        "Required Missing Error",
+			required:           true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			description:        "Required Invalid Error",
+			required:           true,
+			apiKey:             "bad-key",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			description:        "Optional Missing Success",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			c := NewConstructor(
+				WithTokenFactory("Basic", BasicTokenFactory{"codex": "codex"}),
+				WithSecondaryTokenFactory("apikey", NewHeaderCredential("X-Api-Key", apiKeys), tc.required),
+				WithSecondaryTokenFactory("", apiKeys, true),
+				WithSecondaryTokenFactory("nil", nil, true),
+			)
+			var (
+				auth   bascule.Authentication
+				called bool
+			)
+			handler := c(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth, called = bascule.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest("get", "/", nil)
+			req.Header.Set(DefaultHeaderName, "Basic Y29kZXg6Y29kZXg=")
+			if tc.apiKey != "" {
+				req.Header.Set("X-Api-Key", tc.apiKey)
+			}
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatusCode, writer.Code)
+			if tc.expectedStatusCode != http.StatusOK {
+				assert.False(called)
+				return
+			}
+			assert.Equal("codex", auth.Token.Principal())
+			secondary, ok := GetSecondaryCredential(auth, "apikey")
+			assert.Equal(tc.expectCredential, ok)
+			if tc.expectCredential {
+				assert.Equal("key-owner", secondary.Principal())
+			}
+		})
+	}
+}
+
+func TestGetSecondaryCredentialMissing(t *testing.T) {
+	assert := assert.New(t)
+	_, ok := GetSecondaryCredential(bascule.Authentication{}, "a")
+	assert.False(ok)
+	_, ok = GetSecondaryCredential(bascule.Authentication{
+		Token: bascule.NewToken("", "", bascule.NewAttributes(map[string]interface{}{
+			SecondaryCredentialsKey: "wrong type",
+		})),
+	}, "a")
+	assert.False(ok)
+}
+
+func TestSecondaryCredentialsSerialization(t *testing.T) {
+	assert := assert.New(t)
+	token := bascule.NewToken("jwt", "alice", credentialAttributes{
+		credentials: map[string]bascule.Token{"apikey": bascule.NewToken("apikey", "key-owner", nil)},
+		base:        bascule.NewAttributes(map[string]interface{}{"sub": "alice"}),
+	})
+	s, err := bascule.SerializeToken(token)
+	assert.NoError(err)
+	rebuilt, err := bascule.NewTokenFromSerialized(s.MarshalProto())
+	assert.NoError(err)
+	credential, ok := GetSecondaryCredential(bascule.Authentication{Token: rebuilt}, "apikey")
+	assert.True(ok)
+	assert.Equal("key-owner", credential.Principal())
+}
@@ -0,0 +1,187 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var (
+	ErrSessionClosed            = errors.New("session is closed")
+	ErrRenewPrincipalMismatch   = bascule.NewClassError(bascule.InvalidClass, "replacement token is for a different principal")
+	ErrRenewAuthorizationFailed = bascule.NewClassError(bascule.InvalidClass, "replacement token failed authentication")
+)
+
+// Session tracks the authentication of a long-lived request, such as a
+// Server-Sent Events stream or a long poll, whose connection can outlive the
+// token it was opened with.  The client can send a replacement token
+// mid-stream, for example in a control message, which the handler passes to
+// Renew.
+type Session struct {
+	c       *constructor
+	request *http.Request
+	cancel  context.CancelFunc
+
+	lock    sync.Mutex
+	auth    bascule.Authentication
+	renewed int
+	timer   *time.Timer
+	expired bool
+	closed  bool
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the Session of the request the context belongs
+// to.  There is one when the constructor was built with WithCSessions or
+// WithCExpiryCancel.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// newSession adds a Session to the request's context, which is canceled when
+// the token expires if the constructor cancels expired requests.
+func (c *constructor) newSession(r *http.Request, auth bascule.Authentication) (*http.Request, *Session) {
+	s := &Session{
+		c:       c,
+		request: r,
+		auth:    auth,
+	}
+	ctx := r.Context()
+	if c.expiryCancel {
+		ctx, s.cancel = context.WithCancel(ctx)
+	}
+	r = r.WithContext(context.WithValue(ctx, sessionKey{}, s))
+	s.lock.Lock()
+	s.schedule()
+	s.lock.Unlock()
+	return r, s
+}
+
+// Authentication returns the current authentication, which changes when the
+// session is renewed.  The Authentication in the request's context is always
+// the one the request started with.
+func (s *Session) Authentication() bascule.Authentication {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.auth
+}
+
+// Expiry returns when the current token expires, if it has an exp attribute.
+func (s *Session) Expiry() (time.Time, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return tokenExpiration(s.auth.Token)
+}
+
+// Renew authenticates the replacement Authorization value given, like
+// "Bearer <token>", using the constructor's token factories.  The replacement
+// must be for the same principal.  On success it becomes the session's
+// Authentication and the expiry is pushed back to the new token's.  The
+// enforcer isn't run again.
+func (s *Session) Renew(authorization string) (bascule.Authentication, error) {
+	s.lock.Lock()
+	closed, principal := s.closed || s.expired, s.auth.Token.Principal()
+	s.lock.Unlock()
+	if closed {
+		return bascule.Authentication{}, ErrSessionClosed
+	}
+
+	r := headersOnly(s.request)
+	r.Header = s.request.Header.Clone()
+	r.Header.Set(s.c.renewHeader(), authorization)
+	auth, _, err := s.c.authenticationOutput(s.c.getLogger(r.Context()), r)
+	if err != nil {
+		return bascule.Authentication{}, fmt.Errorf("%w: %v", ErrRenewAuthorizationFailed, err)
+	}
+	if auth.Token.Principal() != principal {
+		return bascule.Authentication{}, ErrRenewPrincipalMismatch
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed || s.expired {
+		return bascule.Authentication{}, ErrSessionClosed
+	}
+	// the request itself hasn't changed.
+	auth.Request.Time, auth.Request.Digest = s.auth.Request.Time, s.auth.Request.Digest
+	s.auth = auth
+	s.renewed++
+	s.schedule()
+	return auth, nil
+}
+
+// schedule sets the timer that cancels the request when the current token
+// expires.  The lock must be held.
+func (s *Session) schedule() {
+	if s.cancel == nil {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	exp, ok := tokenExpiration(s.auth.Token)
+	if !ok {
+		return
+	}
+	d := time.Until(exp.Add(s.c.expiryGrace))
+	if d < 0 {
+		d = 0
+	}
+	renewed := s.renewed
+	s.timer = time.AfterFunc(d, func() { s.expire(renewed) })
+}
+
+func (s *Session) expire(renewed int) {
+	s.lock.Lock()
+	// the session may have been renewed after the timer fired.
+	if s.closed || s.renewed != renewed {
+		s.lock.Unlock()
+		return
+	}
+	s.expired = true
+	s.lock.Unlock()
+	s.cancel()
+}
+
+func (s *Session) tokenExpired() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.expired
+}
+
+// close releases the session once the handler returns.
+func (s *Session) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
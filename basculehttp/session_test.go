@@ -0,0 +1,188 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionTokenFactory takes values like "principal.seconds", making a token
+// for the principal that expires after the seconds given.
+var sessionTokenFactory = TokenFactoryFunc(func(_ context.Context, _ *http.Request, _ bascule.Authorization, v string) (bascule.Token, error) {
+	parts := strings.SplitN(v, ".", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("bad token")
+	}
+	seconds, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	attrs := bascule.NewAttributes(map[string]interface{}{"exp": time.Now().Unix() + seconds})
+	return bascule.NewToken("test", parts[0], attrs), nil
+})
+
+// serveSession runs the constructor and calls f with the request's session
+// while the handler is running.
+func serveSession(t *testing.T, f func(*http.Request, *Session), opts ...COption) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		s, ok := SessionFromContext(r.Context())
+		require.True(t, ok)
+		f(r, s)
+	})
+	opts = append(opts, WithTokenFactory(BasicAuthorization, sessionTokenFactory))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultHeaderName, "Basic alice.3600")
+	NewConstructor(opts...)(handler).ServeHTTP(httptest.NewRecorder(), req)
+	require.True(t, called)
+}
+
+func TestSessionFromContext(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		_, ok := SessionFromContext(r.Context())
+		assert.False(t, ok)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultHeaderName, "Basic alice.3600")
+	NewConstructor(WithTokenFactory(BasicAuthorization, sessionTokenFactory))(handler).ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, called)
+}
+
+func TestSessionRenew(t *testing.T) {
+	tests := []struct {
+		description       string
+		authorization     string
+		expectedPrincipal string
+		expectedErr       error
+	}{
+		{
+			description:       "Success",
+			authorization:     "Basic alice.7200",
+			expectedPrincipal: "alice",
+		},
+		{
+			description:   "Different Principal",
+			authorization: "Basic bob.7200",
+			expectedErr:   ErrRenewPrincipalMismatch,
+		},
+		{
+			description:   "Invalid Token",
+			authorization: "Basic nope",
+			expectedErr:   ErrRenewAuthorizationFailed,
+		},
+		{
+			description:   "Unsupported Key",
+			authorization: "Bearer alice.7200",
+			expectedErr:   ErrRenewAuthorizationFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			serveSession(t, func(r *http.Request, s *Session) {
+				assert := assert.New(t)
+				before, ok := s.Expiry()
+				require.True(t, ok)
+				assert.WithinDuration(time.Now().Add(time.Hour), before, 2*time.Second)
+
+				auth, err := s.Renew(tc.authorization)
+				after, _ := s.Expiry()
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+					assert.Equal(before, after)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(tc.expectedPrincipal, auth.Token.Principal())
+				assert.Equal(auth, s.Authentication())
+				assert.WithinDuration(time.Now().Add(2*time.Hour), after, 2*time.Second)
+
+				// the request's own authentication doesn't change.
+				original, ok := bascule.FromContext(r.Context())
+				require.True(t, ok)
+				assert.NotEqual(auth.Token, original.Token)
+			}, WithCSessions())
+		})
+	}
+}
+
+func TestSessionRenewClosed(t *testing.T) {
+	var session *Session
+	serveSession(t, func(_ *http.Request, s *Session) {
+		session = s
+	}, WithCSessions())
+	_, err := session.Renew("Basic alice.7200")
+	assert.ErrorIs(t, err, ErrSessionClosed)
+}
+
+func TestSessionRenewProxy(t *testing.T) {
+	serveSession(t, func(_ *http.Request, s *Session) {
+		auth, err := s.Renew("Basic alice.7200")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", auth.Token.Principal())
+		_, err = s.Renew("Basic bob.7200")
+		assert.ErrorIs(t, err, ErrRenewPrincipalMismatch)
+	}, WithCSessions(), WithCProxyMode(ProxyOrOrigin))
+}
+
+func TestSessionRenewExtendsExpiry(t *testing.T) {
+	serveSession(t, func(r *http.Request, s *Session) {
+		assert := assert.New(t)
+		_, err := s.Renew("Basic alice.1")
+		require.NoError(t, err)
+		_, err = s.Renew("Basic alice.3600")
+		require.NoError(t, err)
+
+		select {
+		case <-r.Context().Done():
+			assert.Fail("renewed request was canceled")
+		case <-time.After(1500 * time.Millisecond):
+		}
+		assert.False(TokenExpired(r.Context()))
+	}, WithCExpiryCancel(0))
+}
+
+func TestSessionExpired(t *testing.T) {
+	serveSession(t, func(r *http.Request, s *Session) {
+		assert := assert.New(t)
+		_, err := s.Renew("Basic alice.-5")
+		require.NoError(t, err)
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			assert.Fail("expired request wasn't canceled")
+		}
+		assert.True(TokenExpired(r.Context()))
+		_, err = s.Renew("Basic alice.3600")
+		assert.ErrorIs(err, ErrSessionClosed)
+	}, WithCSessions(), WithCExpiryCancel(0))
+}
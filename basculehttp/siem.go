@@ -0,0 +1,440 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/sallust"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// SIEMFormat is the format denial events are sent to a SIEM in.
+type SIEMFormat int
+
+const (
+	// CEF is ArcSight's Common Event Format.
+	CEF SIEMFormat = iota
+
+	// LEEF is QRadar's Log Event Extended Format, version 1.0.
+	LEEF
+)
+
+const (
+	defaultSIEMQueueSize    = 1000
+	defaultSIEMDialTimeout  = 5 * time.Second
+	defaultSIEMWriteTimeout = 5 * time.Second
+	defaultSIEMRetry        = 5 * time.Second
+	defaultSIEMVendor       = "xmidt-org"
+	defaultSIEMProduct      = "bascule"
+	defaultSIEMVersion      = "1.0"
+	defaultSIEMSeverity     = 5
+	defaultSIEMAppName      = "bascule"
+	// syslog facility authpriv (10) and severity warning (4).
+	defaultSIEMPriority = 10*8 + 4
+	siemEventName       = "Authorization denied"
+	leefTimeFormat      = "Jan 02 2006 15:04:05.000 MST"
+	leefTimeFormatValue = "MMM dd yyyy HH:mm:ss.SSS z"
+)
+
+var ErrEmptySIEMAddress = errors.New("siem address cannot be empty")
+
+// DenialEvent is an authorization denial, as sent to a SIEM.
+type DenialEvent struct {
+	Time      time.Time
+	RequestID string
+	Method    string
+	Path      string
+	ClientIP  string
+	Principal string
+	TokenType string
+	Reason    ErrorResponseReason
+	Err       error
+}
+
+// SIEMConfig configures a SIEMSink.
+type SIEMConfig struct {
+	// Format is the format of the events.  Defaults to CEF.
+	Format SIEMFormat
+
+	// Network is "udp" or "tcp".  Defaults to "udp".
+	Network string
+
+	// Address is the host and port of the syslog collector.  It's required.
+	Address string
+
+	// Vendor, Product, and Version identify bascule in the event headers.
+	// They default to "xmidt-org", "bascule", and "1.0".
+	Vendor  string
+	Product string
+	Version string
+
+	// Severity is the CEF severity of denials, from 0 to 10.  Defaults to 5.
+	Severity int
+
+	// AppName and Hostname are the syslog header's APP-NAME and HOSTNAME.
+	// They default to "bascule" and the host's name.
+	AppName  string
+	Hostname string
+
+	// QueueSize is the number of events buffered while waiting to be sent
+	// or for the collector to come back.  Events denied when the queue is
+	// full are dropped.  Defaults to 1000.
+	QueueSize int
+
+	// DialTimeout and WriteTimeout limit connecting to and writing to the
+	// collector.  Both default to 5 seconds.
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// RetryInterval is how long to wait before reconnecting after the
+	// collector can't be reached.  Defaults to 5 seconds.
+	RetryInterval time.Duration
+}
+
+// SIEMSink formats denial events as CEF or LEEF and ships them to a SIEM
+// over syslog, buffering them so that a slow or unreachable collector never
+// holds up requests.  Add its Hook as an AfterDeny hook of the constructor
+// and enforcer.
+type SIEMSink struct {
+	config    SIEMConfig
+	dial      func(network, address string, timeout time.Duration) (net.Conn, error)
+	getLogger func(context.Context) *zap.Logger
+	queue     chan DenialEvent
+
+	runLock sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSIEMSink creates a SIEMSink.  Events aren't sent until it is started.
+func NewSIEMSink(config SIEMConfig, getLogger func(context.Context) *zap.Logger) (*SIEMSink, error) {
+	if config.Address == "" {
+		return nil, ErrEmptySIEMAddress
+	}
+	switch config.Network {
+	case "":
+		config.Network = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported siem network %q", config.Network)
+	}
+	if config.Format != CEF && config.Format != LEEF {
+		return nil, fmt.Errorf("unsupported siem format %d", config.Format)
+	}
+	if config.Vendor == "" {
+		config.Vendor = defaultSIEMVendor
+	}
+	if config.Product == "" {
+		config.Product = defaultSIEMProduct
+	}
+	if config.Version == "" {
+		config.Version = defaultSIEMVersion
+	}
+	if config.Severity <= 0 || config.Severity > 10 {
+		config.Severity = defaultSIEMSeverity
+	}
+	if config.AppName == "" {
+		config.AppName = defaultSIEMAppName
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+		if config.Hostname == "" {
+			config.Hostname = "-"
+		}
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultSIEMQueueSize
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultSIEMDialTimeout
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaultSIEMWriteTimeout
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultSIEMRetry
+	}
+	if getLogger == nil {
+		getLogger = sallust.Get
+	}
+	return &SIEMSink{
+		config:    config,
+		dial:      net.DialTimeout,
+		getLogger: getLogger,
+		queue:     make(chan DenialEvent, config.QueueSize),
+	}, nil
+}
+
+// DenyHook returns an AfterDeny Hook that records the denials.
+func (s *SIEMSink) DenyHook() Hook {
+	return func(_ http.ResponseWriter, r *http.Request, event HookEvent) *http.Request {
+		if event.Stage != AfterDeny {
+			return nil
+		}
+		d := DenialEvent{
+			Time:      time.Now(),
+			RequestID: event.RequestID,
+			Method:    r.Method,
+			Path:      r.URL.EscapedPath(),
+			ClientIP:  denialClientIP(r, event.Auth),
+			Reason:    event.Reason,
+			Err:       event.Err,
+		}
+		if event.Auth.Token != nil {
+			d.Principal = event.Auth.Token.Principal()
+			d.TokenType = event.Auth.Token.Type()
+		}
+		s.Record(d)
+		return nil
+	}
+}
+
+// Record queues the denial to be sent, returning false if the queue is full
+// and the denial was dropped.
+func (s *SIEMSink) Record(d DenialEvent) bool {
+	select {
+	case s.queue <- d:
+		return true
+	default:
+		s.getLogger(context.Background()).Warn("siem queue full, dropping denial event",
+			zap.String(RequestIDLogKey, d.RequestID))
+		return false
+	}
+}
+
+// Format returns the denial as a CEF or LEEF message, without the syslog
+// header.
+func (s *SIEMSink) Format(d DenialEvent) string {
+	if s.config.Format == LEEF {
+		return formatLEEF(s.config, d)
+	}
+	return formatCEF(s.config, d)
+}
+
+// Start starts sending events to the collector.
+func (s *SIEMSink) Start() {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops sending events, first trying once to send the events already
+// queued.
+func (s *SIEMSink) Stop() {
+	s.runLock.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.runLock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops the sink.
+func (s *SIEMSink) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			s.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			s.Stop()
+			return nil
+		},
+	}
+}
+
+func (s *SIEMSink) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	var (
+		conn    net.Conn
+		pending *DenialEvent
+		retry   <-chan time.Time
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		if pending == nil && retry == nil {
+			select {
+			case <-stop:
+				s.drain(conn)
+				return
+			case d := <-s.queue:
+				pending = &d
+			}
+		}
+		if retry != nil {
+			select {
+			case <-stop:
+				s.drain(conn)
+				return
+			case <-retry:
+				retry = nil
+			}
+		}
+		var err error
+		if conn == nil {
+			conn, err = s.dial(s.config.Network, s.config.Address, s.config.DialTimeout)
+		}
+		if err == nil {
+			err = s.write(conn, *pending)
+		}
+		if err != nil {
+			s.getLogger(context.Background()).Error("failed to send denial event to siem",
+				zap.String("address", s.config.Address), zap.Error(err))
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+			retry = time.After(s.config.RetryInterval)
+			continue
+		}
+		pending = nil
+	}
+}
+
+// drain makes one attempt to send the queued events when stopping.
+func (s *SIEMSink) drain(conn net.Conn) {
+	if len(s.queue) == 0 {
+		return
+	}
+	if conn == nil {
+		c, err := s.dial(s.config.Network, s.config.Address, s.config.DialTimeout)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		conn = c
+	}
+	for {
+		select {
+		case d := <-s.queue:
+			if s.write(conn, d) != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// write sends the event with an RFC 5424 syslog header.  Over TCP, each
+// message ends with a newline, as in RFC 6587's non-transparent framing.
+func (s *SIEMSink) write(conn net.Conn, d DenialEvent) error {
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - - %s", defaultSIEMPriority,
+		d.Time.UTC().Format(time.RFC3339Nano), s.config.Hostname, s.config.AppName, s.Format(d))
+	if s.config.Network == "tcp" {
+		msg += "\n"
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout)); err != nil {
+		return err
+	}
+	_, err := conn.Write([]byte(msg))
+	return err
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefHeaderEscaper   = strings.NewReplacer(`|`, `\|`, "\n", " ", "\r", " ")
+	leefValueEscaper    = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+// denialFields returns the event's fields in order, with their CEF and
+// LEEF keys.
+func denialFields(d DenialEvent) [][3]string {
+	var msg string
+	if d.Err != nil {
+		msg = d.Err.Error()
+	}
+	return [][3]string{
+		{"act", "action", "deny"},
+		{"requestMethod", "method", d.Method},
+		{"request", "url", d.Path},
+		{"src", "src", d.ClientIP},
+		{"suser", "usrName", d.Principal},
+		{"cs1", "tokenType", d.TokenType},
+		{"cs2", "requestID", d.RequestID},
+		{"reason", "reason", d.Reason.String()},
+		{"msg", "msg", msg},
+	}
+}
+
+func formatCEF(config SIEMConfig, d DenialEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|rt=%d",
+		cefHeaderEscaper.Replace(config.Vendor),
+		cefHeaderEscaper.Replace(config.Product),
+		cefHeaderEscaper.Replace(config.Version),
+		cefHeaderEscaper.Replace(d.Reason.String()),
+		siemEventName,
+		config.Severity,
+		d.Time.UnixMilli())
+	for _, f := range denialFields(d) {
+		if f[2] == "" {
+			continue
+		}
+		b.WriteString(" " + f[0] + "=" + cefExtensionEscaper.Replace(f[2]))
+		switch f[0] {
+		case "cs1":
+			b.WriteString(" cs1Label=tokenType")
+		case "cs2":
+			b.WriteString(" cs2Label=requestID")
+		}
+	}
+	return b.String()
+}
+
+func formatLEEF(config SIEMConfig, d DenialEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:1.0|%s|%s|%s|%s|devTime=%s\tdevTimeFormat=%s\tsev=%s",
+		leefHeaderEscaper.Replace(config.Vendor),
+		leefHeaderEscaper.Replace(config.Product),
+		leefHeaderEscaper.Replace(config.Version),
+		leefHeaderEscaper.Replace(d.Reason.String()),
+		d.Time.UTC().Format(leefTimeFormat),
+		leefTimeFormatValue,
+		strconv.Itoa(config.Severity))
+	for _, f := range denialFields(d) {
+		if f[2] == "" {
+			continue
+		}
+		b.WriteString("\t" + f[1] + "=" + leefValueEscaper.Replace(f[2]))
+	}
+	return b.String()
+}
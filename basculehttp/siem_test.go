@@ -0,0 +1,342 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDenial() DenialEvent {
+	return DenialEvent{
+		Time:      time.Date(2023, time.March, 4, 5, 6, 7, 8000000, time.UTC),
+		RequestID: "abc123",
+		Method:    http.MethodGet,
+		Path:      "/api/v1/a=b",
+		ClientIP:  "10.0.0.1",
+		Principal: "ali|ce",
+		TokenType: "jwt",
+		Reason:    ChecksFailed,
+		Err:       errors.New("no capabilities\nmatch"),
+	}
+}
+
+func TestNewSIEMSink(t *testing.T) {
+	tests := []struct {
+		description    string
+		config         SIEMConfig
+		expectedConfig SIEMConfig
+		expectedErr    error
+	}{
+		{
+			description: "Defaults",
+			config:      SIEMConfig{Address: "localhost:514", Hostname: "host"},
+			expectedConfig: SIEMConfig{
+				Format:        CEF,
+				Network:       "udp",
+				Address:       "localhost:514",
+				Vendor:        defaultSIEMVendor,
+				Product:       defaultSIEMProduct,
+				Version:       defaultSIEMVersion,
+				Severity:      defaultSIEMSeverity,
+				AppName:       defaultSIEMAppName,
+				Hostname:      "host",
+				QueueSize:     defaultSIEMQueueSize,
+				DialTimeout:   defaultSIEMDialTimeout,
+				WriteTimeout:  defaultSIEMWriteTimeout,
+				RetryInterval: defaultSIEMRetry,
+			},
+		},
+		{
+			description: "Custom",
+			config: SIEMConfig{
+				Format:        LEEF,
+				Network:       "tcp",
+				Address:       "siem:514",
+				Vendor:        "v",
+				Product:       "p",
+				Version:       "2",
+				Severity:      8,
+				AppName:       "a",
+				Hostname:      "h",
+				QueueSize:     10,
+				DialTimeout:   time.Second,
+				WriteTimeout:  2 * time.Second,
+				RetryInterval: 3 * time.Second,
+			},
+			expectedConfig: SIEMConfig{
+				Format:        LEEF,
+				Network:       "tcp",
+				Address:       "siem:514",
+				Vendor:        "v",
+				Product:       "p",
+				Version:       "2",
+				Severity:      8,
+				AppName:       "a",
+				Hostname:      "h",
+				QueueSize:     10,
+				DialTimeout:   time.Second,
+				WriteTimeout:  2 * time.Second,
+				RetryInterval: 3 * time.Second,
+			},
+		},
+		{
+			description: "Empty address",
+			expectedErr: ErrEmptySIEMAddress,
+		},
+		{
+			description: "Bad network",
+			config:      SIEMConfig{Address: "localhost:514", Network: "unix"},
+		},
+		{
+			description: "Bad format",
+			config:      SIEMConfig{Address: "localhost:514", Format: SIEMFormat(7)},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, err := NewSIEMSink(tc.config, nil)
+			if tc.expectedConfig.Address == "" {
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				assert.Nil(s)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(tc.expectedConfig, s.config)
+			assert.Equal(tc.expectedConfig.QueueSize, cap(s.queue))
+		})
+	}
+}
+
+func TestSIEMSinkFormat(t *testing.T) {
+	tests := []struct {
+		description string
+		format      SIEMFormat
+		event       DenialEvent
+		expected    string
+	}{
+		{
+			description: "CEF",
+			format:      CEF,
+			event:       testDenial(),
+			expected: `CEF:0|ven\|dor|bascule|1.0|checks_failed|Authorization denied|5|rt=1677906367008` +
+				` act=deny requestMethod=GET request=/api/v1/a\=b src=10.0.0.1 suser=ali|ce` +
+				` cs1=jwt cs1Label=tokenType cs2=abc123 cs2Label=requestID reason=checks_failed msg=no capabilities\nmatch`,
+		},
+		{
+			description: "CEF minimal",
+			format:      CEF,
+			event:       DenialEvent{Time: time.Unix(1, 0), Reason: MissingHeader},
+			expected:    `CEF:0|ven\|dor|bascule|1.0|missing_header|Authorization denied|5|rt=1000 act=deny reason=missing_header`,
+		},
+		{
+			description: "LEEF",
+			format:      LEEF,
+			event:       testDenial(),
+			expected: "LEEF:1.0|ven\\|dor|bascule|1.0|checks_failed|devTime=Mar 04 2023 05:06:07.008 UTC" +
+				"\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsev=5\taction=deny\tmethod=GET\turl=/api/v1/a=b" +
+				"\tsrc=10.0.0.1\tusrName=ali|ce\ttokenType=jwt\trequestID=abc123\treason=checks_failed\tmsg=no capabilities match",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			s, err := NewSIEMSink(SIEMConfig{Address: "localhost:514", Format: tc.format, Vendor: "ven|dor"}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Format(tc.event))
+		})
+	}
+}
+
+func TestSIEMSinkDenyHook(t *testing.T) {
+	assert := assert.New(t)
+	s, err := NewSIEMSink(SIEMConfig{Address: "localhost:514"}, nil)
+	require.NoError(t, err)
+	hook := s.DenyHook()
+
+	r := httptest.NewRequest(http.MethodPost, "/a/b?secret=1", nil)
+	r.RemoteAddr = "192.168.1.2:1234"
+	assert.Nil(hook(nil, r, HookEvent{Stage: AfterAllow}))
+	assert.Empty(s.queue)
+
+	assert.Nil(hook(nil, r, HookEvent{Stage: AfterDeny, Reason: MissingHeader, RequestID: "id"}))
+	assert.Nil(hook(nil, r, HookEvent{
+		Stage: AfterDeny,
+		Auth: bascule.Authentication{
+			Token:   bascule.NewToken("basic", "bob", nil),
+			Request: bascule.Request{ClientIP: "10.1.1.1"},
+		},
+		Reason: ChecksFailed,
+	}))
+	require.Len(t, s.queue, 2)
+
+	d := <-s.queue
+	assert.Equal("192.168.1.2", d.ClientIP)
+	assert.Equal("/a/b", d.Path)
+	assert.Equal(http.MethodPost, d.Method)
+	assert.Equal("id", d.RequestID)
+	assert.Equal(MissingHeader, d.Reason)
+	assert.Empty(d.Principal)
+
+	d = <-s.queue
+	assert.Equal("10.1.1.1", d.ClientIP)
+	assert.Equal("bob", d.Principal)
+	assert.Equal("basic", d.TokenType)
+}
+
+func TestSIEMSinkDropsWhenFull(t *testing.T) {
+	s, err := NewSIEMSink(SIEMConfig{Address: "localhost:514", QueueSize: 1}, nil)
+	require.NoError(t, err)
+	assert.True(t, s.Record(testDenial()))
+	assert.False(t, s.Record(testDenial()))
+}
+
+func TestSIEMSinkUDP(t *testing.T) {
+	assert := assert.New(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewSIEMSink(SIEMConfig{Address: conn.LocalAddr().String(), Hostname: "host"}, nil)
+	require.NoError(t, err)
+	hook := s.Hook()
+	require.NoError(t, hook.OnStart(context.Background()))
+	defer func() { assert.NoError(hook.OnStop(context.Background())) }()
+
+	d := testDenial()
+	s.Record(d)
+	buf := make([]byte, 2048)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal("<84>1 2023-03-04T05:06:07.008Z host bascule - - - "+s.Format(d), string(buf[:n]))
+}
+
+func TestSIEMSinkTCP(t *testing.T) {
+	assert := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	var (
+		lines []string
+		wg    sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}()
+
+	s, err := NewSIEMSink(SIEMConfig{
+		Format:   LEEF,
+		Network:  "tcp",
+		Address:  listener.Addr().String(),
+		Hostname: "host",
+	}, nil)
+	require.NoError(t, err)
+
+	// events queued before starting are sent, and the rest are sent when
+	// stopping.
+	s.Record(testDenial())
+	s.Start()
+	s.Start()
+	s.Record(testDenial())
+	s.Stop()
+	s.Stop()
+	wg.Wait()
+
+	require.Len(t, lines, 2)
+	for _, l := range lines {
+		assert.True(strings.HasPrefix(l, "<84>1 2023-03-04T05:06:07.008Z host bascule - - - LEEF:1.0|"))
+	}
+}
+
+func TestSIEMSinkRetry(t *testing.T) {
+	assert := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		received <- line
+	}()
+
+	s, err := NewSIEMSink(SIEMConfig{
+		Network:       "tcp",
+		Address:       listener.Addr().String(),
+		RetryInterval: time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+	var (
+		lock  sync.Mutex
+		dials int
+	)
+	s.dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		lock.Lock()
+		dials++
+		first := dials == 1
+		lock.Unlock()
+		if first {
+			return nil, errors.New("collector unreachable")
+		}
+		return net.DialTimeout(network, address, timeout)
+	}
+
+	s.Start()
+	defer s.Stop()
+	s.Record(testDenial())
+	select {
+	case line := <-received:
+		assert.Contains(line, "CEF:0|")
+	case <-time.After(5 * time.Second):
+		assert.Fail("event wasn't sent after retrying")
+	}
+	lock.Lock()
+	assert.Equal(2, dials)
+	lock.Unlock()
+}
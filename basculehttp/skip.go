@@ -0,0 +1,108 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// SkipFunc decides whether a request bypasses authentication, such as health
+// checks, metrics, and static assets.
+type SkipFunc func(*http.Request) bool
+
+type skippedKey struct{}
+
+// Skipped returns true if authentication was bypassed for the request the
+// context belongs to.  The enforcer and listeners let skipped requests
+// through, so bypassing the constructor is enough to bypass the whole chain.
+func Skipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skippedKey{}).(bool)
+	return skipped
+}
+
+func withSkipped(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), skippedKey{}, true))
+}
+
+// skipFuncs is a list of SkipFuncs, any of which can skip a request.
+type skipFuncs []SkipFunc
+
+func (s skipFuncs) add(f SkipFunc) skipFuncs {
+	if f == nil {
+		return s
+	}
+	return append(s, f)
+}
+
+func (s skipFuncs) skip(r *http.Request) bool {
+	if Skipped(r.Context()) {
+		return true
+	}
+	for _, f := range s {
+		if f(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipPaths skips requests for the paths given.  A path ending in "*" skips
+// every path starting with the rest of it, so "/static/*" skips everything
+// under "/static/".
+func SkipPaths(paths ...string) SkipFunc {
+	exact := make(map[string]bool)
+	var prefixes []string
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			prefixes = append(prefixes, strings.TrimSuffix(p, "*"))
+			continue
+		}
+		exact[p] = true
+	}
+	return func(r *http.Request) bool {
+		if exact[r.URL.Path] {
+			return true
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SkipMethods skips requests with any of the methods given.
+func SkipMethods(methods ...string) SkipFunc {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if strings.EqualFold(r.Method, m) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SkipPreflight skips CORS preflight requests, which browsers send without
+// credentials.
+func SkipPreflight() SkipFunc {
+	return isPreflight
+}
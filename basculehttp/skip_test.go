@@ -0,0 +1,143 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipFuncs(t *testing.T) {
+	paths := SkipPaths("/health", "/static/*")
+	methods := SkipMethods("head")
+	preflight := SkipPreflight()
+	tests := []struct {
+		description       string
+		method            string
+		path              string
+		preflight         bool
+		expectedPaths     bool
+		expectedMethods   bool
+		expectedPreflight bool
+	}{
+		{
+			description:   "Exact Path",
+			method:        http.MethodGet,
+			path:          "/health",
+			expectedPaths: true,
+		},
+		{
+			description: "Exact Path Prefix",
+			method:      http.MethodGet,
+			path:        "/health/deep",
+		},
+		{
+			description:   "Prefix Path",
+			method:        http.MethodGet,
+			path:          "/static/app.js",
+			expectedPaths: true,
+		},
+		{
+			description:     "Method",
+			method:          http.MethodHead,
+			path:            "/api",
+			expectedMethods: true,
+		},
+		{
+			description:       "Preflight",
+			method:            http.MethodOptions,
+			path:              "/api",
+			preflight:         true,
+			expectedPreflight: true,
+		},
+		{
+			description: "Options Without Preflight",
+			method:      http.MethodOptions,
+			path:        "/api",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			assert.Equal(tc.expectedPaths, paths(req))
+			assert.Equal(tc.expectedMethods, methods(req))
+			assert.Equal(tc.expectedPreflight, preflight(req))
+		})
+	}
+}
+
+func TestAuthChainSkip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	listener := new(mockListener)
+	var skipped bool
+	h, err := NewAuthHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			skipped = Skipped(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+		WithConstructorOptions(WithTokenFactory(BasicAuthorization, BasicTokenFactory{})),
+		WithEnforcerOptions(WithRules(BasicAuthorization, basculechecks.AllowAll())),
+		WithAuthSkip(SkipPaths("/health")),
+		WithAuthSkip(nil),
+		WithListeners(listener),
+	)
+	require.NoError(err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.True(skipped)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	listener.AssertNotCalled(t, "OnAuthenticated")
+}
+
+func TestEnforcerSkip(t *testing.T) {
+	assert := assert.New(t)
+	e := NewEnforcer(
+		WithRules("jwt", basculechecks.NonEmptyPrincipal()),
+		WithESkip(SkipMethods(http.MethodHead)),
+		WithESkip(nil),
+	)
+	serve := func(method string) int {
+		req := httptest.NewRequest(method, "/", nil)
+		req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+			Authorization: "jwt",
+			Token:         bascule.NewToken("jwt", "", nil),
+		}))
+		w := httptest.NewRecorder()
+		e(next).ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(http.StatusOK, serve(http.MethodHead))
+	assert.Equal(http.StatusForbidden, serve(http.MethodGet))
+}
@@ -0,0 +1,176 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const (
+	// NegotiateAuthorization is the authorization scheme for SPNEGO, as
+	// described in RFC 4559.
+	NegotiateAuthorization bascule.Authorization = "Negotiate"
+
+	// KerberosTokenType is the type of tokens created by the
+	// SPNEGOTokenFactory.
+	KerberosTokenType = "kerberos"
+
+	// KerberosNameKey is the attribute key the principal's name, without the
+	// realm, is stored under.
+	KerberosNameKey = "kerberosName"
+
+	// KerberosRealmKey is the attribute key the principal's realm is stored
+	// under.
+	KerberosRealmKey = "kerberosRealm"
+)
+
+var (
+	ErrNilKerberosAcceptor     = errors.New("kerberos acceptor cannot be nil")
+	ErrEmptyNegotiateToken     = bascule.NewClassError(bascule.MissingCredentialsClass, "empty negotiate token")
+	ErrNTLMNotSupported        = bascule.NewClassError(bascule.MalformedClass, "ntlm is not supported, only kerberos")
+	ErrKerberosRealmNotAllowed = bascule.NewClassError(bascule.InvalidClass, "kerberos realm not allowed")
+)
+
+// ntlmSignature starts every NTLM message, which some clients send in place
+// of a Kerberos ticket when they can't get one.
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// KerberosIdentity is the client principal from a validated Kerberos ticket.
+type KerberosIdentity struct {
+	// Name is the principal's name, without the realm.
+	Name string
+
+	// Realm is the principal's realm.
+	Realm string
+
+	// Groups are the group memberships from the ticket's authorization data,
+	// such as an Active Directory PAC, if there were any.
+	Groups []string
+}
+
+// KerberosAcceptor validates the SPNEGO token sent by a client, decrypting the
+// Kerberos ticket in it with the service's keytab.  It is small so that any
+// Kerberos library can be adapted to it; for example, gokrb5's
+// spnego.SPNEGOService can be wrapped given a keytab loaded with
+// keytab.Load.
+type KerberosAcceptor interface {
+	Accept(ctx context.Context, token []byte) (KerberosIdentity, error)
+}
+
+// KerberosAcceptorFunc is a function that implements KerberosAcceptor.
+type KerberosAcceptorFunc func(context.Context, []byte) (KerberosIdentity, error)
+
+func (kaf KerberosAcceptorFunc) Accept(ctx context.Context, token []byte) (KerberosIdentity, error) {
+	return kaf(ctx, token)
+}
+
+// SPNEGOConfig configures an SPNEGOTokenFactory.
+type SPNEGOConfig struct {
+	// Realms are the realms principals are allowed from.  If empty, any
+	// realm the keytab can validate tickets for is allowed.
+	Realms []string
+
+	// StripRealm uses the principal's name without the realm as the token's
+	// principal.  Only use this when there's a single realm, otherwise
+	// principals from different realms can't be told apart.
+	StripRealm bool
+}
+
+// SPNEGOTokenFactory validates Kerberos tickets sent with the Negotiate
+// scheme, so intranet clients can use single sign on.  The principal's name
+// and realm are added to the token's attributes, and any groups are added
+// under LDAPGroupsKey so the same checks work with the LDAPTokenFactory.  Mutual
+// authentication isn't supported, so no token is sent back to the client.
+type SPNEGOTokenFactory struct {
+	acceptor KerberosAcceptor
+	config   SPNEGOConfig
+}
+
+// NewSPNEGOTokenFactory creates an SPNEGOTokenFactory that validates tickets
+// with the acceptor given.
+func NewSPNEGOTokenFactory(acceptor KerberosAcceptor, config SPNEGOConfig) (*SPNEGOTokenFactory, error) {
+	if acceptor == nil {
+		return nil, ErrNilKerberosAcceptor
+	}
+	return &SPNEGOTokenFactory{
+		acceptor: acceptor,
+		config:   config,
+	}, nil
+}
+
+// ParseAndValidate expects the given value to be a base64 encoded SPNEGO
+// token.
+func (s *SPNEGOTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode string: %v", err)
+	}
+	if len(decoded) == 0 {
+		return nil, ErrEmptyNegotiateToken
+	}
+	if bytes.HasPrefix(decoded, ntlmSignature) {
+		return nil, ErrNTLMNotSupported
+	}
+
+	id, err := s.acceptor.Accept(ctx, decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept kerberos ticket: %w", err)
+	}
+	if !s.realmAllowed(id.Realm) {
+		return nil, fmt.Errorf("%w: %s", ErrKerberosRealmNotAllowed, id.Realm)
+	}
+
+	principal := id.Name
+	if !s.config.StripRealm && id.Realm != "" {
+		principal = id.Name + "@" + id.Realm
+	}
+	attributes := map[string]interface{}{
+		KerberosNameKey:  id.Name,
+		KerberosRealmKey: id.Realm,
+	}
+	if len(id.Groups) > 0 {
+		attributes[LDAPGroupsKey] = id.Groups
+	}
+	return bascule.NewToken(KerberosTokenType, principal, bascule.NewAttributes(attributes)), nil
+}
+
+// Challenges asks the client to use the Negotiate scheme.
+func (s *SPNEGOTokenFactory) Challenges(*http.Request, error) []string {
+	return []string{string(NegotiateAuthorization)}
+}
+
+func (s *SPNEGOTokenFactory) realmAllowed(realm string) bool {
+	if len(s.config.Realms) == 0 {
+		return true
+	}
+	for _, r := range s.config.Realms {
+		// realms are case sensitive, but are upper case by convention.
+		if r == realm {
+			return true
+		}
+	}
+	return false
+}
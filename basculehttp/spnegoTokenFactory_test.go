@@ -0,0 +1,121 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSPNEGOTokenFactory(t *testing.T) {
+	errTest := errors.New("test")
+	acceptor := KerberosAcceptorFunc(func(_ context.Context, token []byte) (KerberosIdentity, error) {
+		switch string(token) {
+		case "good":
+			return KerberosIdentity{Name: "alice", Realm: "EXAMPLE.COM", Groups: []string{"admins"}}, nil
+		case "other":
+			return KerberosIdentity{Name: "bob", Realm: "OTHER.COM"}, nil
+		}
+		return KerberosIdentity{}, errTest
+	})
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		description       string
+		config            SPNEGOConfig
+		value             string
+		expectedPrincipal string
+		expectedErr       error
+	}{
+		{
+			description:       "Success",
+			value:             encode("good"),
+			expectedPrincipal: "alice@EXAMPLE.COM",
+		},
+		{
+			description:       "Strip Realm Success",
+			config:            SPNEGOConfig{Realms: []string{"EXAMPLE.COM"}, StripRealm: true},
+			value:             encode("good"),
+			expectedPrincipal: "alice",
+		},
+		{
+			description: "Realm Not Allowed Error",
+			config:      SPNEGOConfig{Realms: []string{"EXAMPLE.COM"}},
+			value:       encode("other"),
+			expectedErr: ErrKerberosRealmNotAllowed,
+		},
+		{
+			description: "Accept Error",
+			value:       encode("bad"),
+			expectedErr: errTest,
+		},
+		{
+			description: "NTLM Error",
+			value:       encode("NTLMSSP\x00\x01\x00\x00\x00"),
+			expectedErr: ErrNTLMNotSupported,
+		},
+		{
+			description: "Empty Error",
+			value:       "",
+			expectedErr: ErrEmptyNegotiateToken,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			f, err := NewSPNEGOTokenFactory(acceptor, tc.config)
+			require.NoError(err)
+			token, err := f.ParseAndValidate(context.Background(), nil, NegotiateAuthorization, tc.value)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(token)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(KerberosTokenType, token.Type())
+			assert.Equal(tc.expectedPrincipal, token.Principal())
+			name, _ := token.Attributes().Get(KerberosNameKey)
+			assert.Equal("alice", name)
+			realm, _ := token.Attributes().Get(KerberosRealmKey)
+			assert.Equal("EXAMPLE.COM", realm)
+			groups, _ := token.Attributes().Get(LDAPGroupsKey)
+			assert.Equal([]string{"admins"}, groups)
+		})
+	}
+}
+
+func TestNewSPNEGOTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewSPNEGOTokenFactory(nil, SPNEGOConfig{})
+	assert.ErrorIs(err, ErrNilKerberosAcceptor)
+
+	f, err := NewSPNEGOTokenFactory(KerberosAcceptorFunc(func(context.Context, []byte) (KerberosIdentity, error) {
+		return KerberosIdentity{}, nil
+	}), SPNEGOConfig{})
+	assert.NoError(err)
+	assert.Equal([]string{"Negotiate"}, f.Challenges(&http.Request{}, nil))
+}
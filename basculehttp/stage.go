@@ -0,0 +1,104 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// FailureStage is the stage of handling a request that a failure happened in.
+type FailureStage int
+
+const (
+	// UnknownStage is used for failures that aren't about the client's
+	// credentials or permissions, like a misconfigured URL parser.
+	UnknownStage FailureStage = iota
+
+	// AuthenticationStage failures are about the credential: it is missing,
+	// malformed, unsupported, expired, or doesn't verify.
+	AuthenticationStage
+
+	// AuthorizationStage failures happen with a valid credential that isn't
+	// allowed to make the request, like one missing a capability.
+	AuthorizationStage
+)
+
+var stageNames = []string{
+	UnknownStage:        "unknown",
+	AuthenticationStage: "authentication",
+	AuthorizationStage:  "authorization",
+}
+
+// String returns the metric label safe name of the stage.
+func (s FailureStage) String() string {
+	if s < 0 || int(s) >= len(stageNames) {
+		return stageNames[UnknownStage]
+	}
+	return stageNames[s]
+}
+
+// StageOf returns the stage a failure happened in, from the class of the
+// error if it has one, and otherwise from the reason.
+func StageOf(reason ErrorResponseReason, err error) FailureStage {
+	switch bascule.ClassOf(err) {
+	case bascule.MissingCredentialsClass, bascule.MalformedClass, bascule.KeyClass,
+		bascule.InvalidClass, bascule.ExpiredClass:
+		return AuthenticationStage
+	case bascule.CapabilityClass, bascule.PartnerClass:
+		return AuthorizationStage
+	}
+	switch reason {
+	case MissingHeader, InvalidHeader, KeyNotSupported, ParseFailed, MissingAuthentication,
+		InvalidTokenType, UnsupportedCritical, MissingKeyID:
+		return AuthenticationStage
+	case ChecksNotFound, ChecksFailed, ImpersonationDenied, EvaluationBudgetExceeded, IPBlocked:
+		return AuthorizationStage
+	}
+	return UnknownStage
+}
+
+// StageStatus returns the status WithCStageStatuses and WithEStageStatuses
+// write for a failure, so that middleware outside of net/http can answer the
+// same way.  It returns false for failures in the UnknownStage.
+func StageStatus(reason ErrorResponseReason, err error) (int, bool) {
+	return stageStatus(reason, err)
+}
+
+// stageStatus returns the status for a failure by its stage: a 401 for
+// authentication failures and a 403 for authorization failures.  Throttling
+// and outages keep their 429 and 503.  It returns false for the UnknownStage.
+func stageStatus(reason ErrorResponseReason, err error) (int, bool) {
+	switch bascule.ClassOf(err) {
+	case bascule.RateLimitedClass:
+		return http.StatusTooManyRequests, true
+	case bascule.UnavailableClass:
+		return http.StatusServiceUnavailable, true
+	}
+	if reason == LockedOut {
+		return http.StatusTooManyRequests, true
+	}
+	switch StageOf(reason, err) {
+	case AuthenticationStage:
+		return http.StatusUnauthorized, true
+	case AuthorizationStage:
+		return http.StatusForbidden, true
+	}
+	return 0, false
+}
@@ -0,0 +1,289 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageOf(t *testing.T) {
+	tests := []struct {
+		description   string
+		reason        ErrorResponseReason
+		err           error
+		expectedStage FailureStage
+		expectedName  string
+	}{
+		{
+			description:   "Expired class",
+			reason:        ChecksFailed,
+			err:           bascule.NewClassError(bascule.ExpiredClass, "expired"),
+			expectedStage: AuthenticationStage,
+			expectedName:  "authentication",
+		},
+		{
+			description:   "Capability class",
+			reason:        ParseFailed,
+			err:           bascule.NewClassError(bascule.CapabilityClass, "no capability"),
+			expectedStage: AuthorizationStage,
+			expectedName:  "authorization",
+		},
+		{
+			description:   "Authentication reason",
+			reason:        MissingHeader,
+			err:           errors.New("no header"),
+			expectedStage: AuthenticationStage,
+			expectedName:  "authentication",
+		},
+		{
+			description:   "Authorization reason",
+			reason:        ChecksFailed,
+			err:           errors.New("rule failed"),
+			expectedStage: AuthorizationStage,
+			expectedName:  "authorization",
+		},
+		{
+			description:   "Unknown",
+			reason:        GetURLFailed,
+			err:           errors.New("bad url"),
+			expectedStage: UnknownStage,
+			expectedName:  "unknown",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			stage := StageOf(tc.reason, tc.err)
+			assert.Equal(tc.expectedStage, stage)
+			assert.Equal(tc.expectedName, stage.String())
+		})
+	}
+	assert.Equal(t, "unknown", FailureStage(-1).String())
+	assert.Equal(t, "unknown", FailureStage(10).String())
+}
+
+func TestStageStatus(t *testing.T) {
+	tests := []struct {
+		description    string
+		reason         ErrorResponseReason
+		err            error
+		expectedStatus int
+		expectedOK     bool
+	}{
+		{
+			description:    "Authentication",
+			reason:         ParseFailed,
+			err:            bascule.NewClassError(bascule.InvalidClass, "bad signature"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedOK:     true,
+		},
+		{
+			description:    "Authorization",
+			reason:         ChecksFailed,
+			err:            bascule.NewClassError(bascule.PartnerClass, "wrong partner"),
+			expectedStatus: http.StatusForbidden,
+			expectedOK:     true,
+		},
+		{
+			description:    "Rate limited",
+			reason:         ChecksFailed,
+			err:            bascule.NewClassError(bascule.RateLimitedClass, "slow down"),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedOK:     true,
+		},
+		{
+			description:    "Unavailable",
+			reason:         ParseFailed,
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedOK:     true,
+		},
+		{
+			description:    "Locked out",
+			reason:         LockedOut,
+			err:            errors.New("locked out"),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedOK:     true,
+		},
+		{
+			description: "Unknown",
+			reason:      GetURLFailed,
+			err:         errors.New("bad url"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			status, ok := stageStatus(tc.reason, tc.err)
+			assert.Equal(tc.expectedStatus, status)
+			assert.Equal(tc.expectedOK, ok)
+		})
+	}
+}
+
+func TestConstructorStageStatuses(t *testing.T) {
+	tests := []struct {
+		description        string
+		auth               string
+		err                error
+		options            []COption
+		expectedStatus     int
+		expectedChallenges []string
+	}{
+		{
+			description:        "Invalid token",
+			auth:               "Basic abc",
+			err:                bascule.NewClassError(bascule.InvalidClass, "bad token"),
+			expectedStatus:     http.StatusUnauthorized,
+			expectedChallenges: []string{string(BearerAuthorization)},
+		},
+		{
+			description:    "Capability failure",
+			auth:           "Basic abc",
+			err:            bascule.NewClassError(bascule.CapabilityClass, "no capability"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:        "Unsupported scheme",
+			auth:               "Unknown abc",
+			expectedStatus:     http.StatusUnauthorized,
+			expectedChallenges: []string{string(BearerAuthorization)},
+		},
+		{
+			description:    "Unavailable",
+			auth:           "Basic abc",
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			description:    "Reason status first",
+			auth:           "Basic abc",
+			err:            bascule.NewClassError(bascule.InvalidClass, "bad token"),
+			options:        []COption{WithCReasonStatusMap(ReasonStatusMap{ParseFailed: http.StatusBadRequest})},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "Challenger",
+			auth:        "Basic abc",
+			err:         bascule.NewClassError(bascule.ExpiredClass, "expired"),
+			options: []COption{WithChallenger(challengerFunc(func(*http.Request, error) []string {
+				return []string{`Basic realm="test"`}
+			}))},
+			expectedStatus:     http.StatusUnauthorized,
+			expectedChallenges: []string{`Basic realm="test"`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			options := append([]COption{
+				WithCStageStatuses(),
+				WithTokenFactory(BasicAuthorization, TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+					return nil, tc.err
+				})),
+			}, tc.options...)
+			handler := NewConstructor(options...)(next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.auth)
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatus, writer.Code)
+			assert.Equal(tc.expectedChallenges, writer.Header().Values(AuthTypeHeaderKey))
+		})
+	}
+}
+
+func TestEnforcerStageStatuses(t *testing.T) {
+	tests := []struct {
+		description        string
+		err                error
+		noAuth             bool
+		options            []EOption
+		expectedStatus     int
+		expectedChallenges []string
+	}{
+		{
+			description:        "Expired",
+			err:                bascule.NewClassError(bascule.ExpiredClass, "expired"),
+			options:            []EOption{WithEStageStatuses(), WithNotFoundChallenges(`Bearer realm="test"`)},
+			expectedStatus:     http.StatusUnauthorized,
+			expectedChallenges: []string{`Bearer realm="test"`},
+		},
+		{
+			description:    "Capability failure",
+			err:            bascule.NewClassError(bascule.CapabilityClass, "no capability"),
+			options:        []EOption{WithEStageStatuses()},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Unclassified failure",
+			err:            errors.New("rule failed"),
+			options:        []EOption{WithEStageStatuses()},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Rate limited",
+			err:            bascule.NewClassError(bascule.RateLimitedClass, "slow down"),
+			options:        []EOption{WithEStageStatuses()},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			description:        "Missing authentication",
+			noAuth:             true,
+			options:            []EOption{WithEStageStatuses()},
+			expectedStatus:     http.StatusUnauthorized,
+			expectedChallenges: []string{string(BearerAuthorization)},
+		},
+		{
+			description:    "Missing authentication without stages",
+			noAuth:         true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Expired without stages",
+			err:            bascule.NewClassError(bascule.ExpiredClass, "expired"),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			rule := bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return tc.err })
+			options := append([]EOption{WithRules("jwt", rule)}, tc.options...)
+			handler := NewEnforcer(options...)(next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tc.noAuth {
+				req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+					Authorization: "jwt",
+					Token:         bascule.NewToken("jwt", "alice", nil),
+				}))
+			}
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, req)
+			assert.Equal(tc.expectedStatus, writer.Code)
+			assert.Equal(tc.expectedChallenges, writer.Header().Values(AuthTypeHeaderKey))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+)
+
+// StatusMap maps error classes to the HTTP status written for them.
+type StatusMap map[bascule.ErrorClass]int
+
+// DefaultStatusMap is the StatusMap used by the enforcer unless another is
+// configured.  Credentials that can't be used get a 401, checks on valid
+// credentials that fail get a 403, and throttling and outages get a 429 and
+// 503.
+var DefaultStatusMap = StatusMap{
+	bascule.MissingCredentialsClass: http.StatusUnauthorized,
+	bascule.MalformedClass:          http.StatusUnauthorized,
+	bascule.KeyClass:                http.StatusUnauthorized,
+	bascule.InvalidClass:            http.StatusUnauthorized,
+	bascule.ExpiredClass:            http.StatusUnauthorized,
+	bascule.CapabilityClass:         http.StatusForbidden,
+	bascule.PartnerClass:            http.StatusForbidden,
+	bascule.RateLimitedClass:        http.StatusTooManyRequests,
+	bascule.UnavailableClass:        http.StatusServiceUnavailable,
+}
+
+// Status returns the status for the class of the error, if there is one.
+func (m StatusMap) Status(err error) (int, bool) {
+	if len(m) == 0 || err == nil {
+		return 0, false
+	}
+	status, ok := m[bascule.ClassOf(err)]
+	return status, ok
+}
+
+// StatusOr returns the status for the class of the error, or the status given
+// if the class isn't mapped.
+func (m StatusMap) StatusOr(err error, defaultStatus int) int {
+	if status, ok := m.Status(err); ok {
+		return status
+	}
+	return defaultStatus
+}
+
+// ReasonStatusMap maps the reasons requests are denied to the HTTP status
+// written for them, for API contracts that require specific codes.
+type ReasonStatusMap map[ErrorResponseReason]int
+
+// Status returns the status for the reason, if there is one.
+func (m ReasonStatusMap) Status(reason ErrorResponseReason) (int, bool) {
+	status, ok := m[reason]
+	return status, ok
+}
+
+// ParseReasonStatusMap builds a ReasonStatusMap from a map of reason names,
+// such as "key_not_supported", to statuses, so that the mapping can be
+// configured.  Each status must be a 4xx or 5xx status.
+func ParseReasonStatusMap(statuses map[string]int) (ReasonStatusMap, error) {
+	m := make(ReasonStatusMap, len(statuses))
+	for name, status := range statuses {
+		reason, ok := parseErrorResponseReason(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown error response reason [%s]", name)
+		}
+		if status < http.StatusBadRequest || status > 599 {
+			return nil, fmt.Errorf("status %d for reason [%s] isn't an error status", status, name)
+		}
+		m[reason] = status
+	}
+	return m, nil
+}
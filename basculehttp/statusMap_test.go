@@ -0,0 +1,280 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusMap(t *testing.T) {
+	tests := []struct {
+		description    string
+		m              StatusMap
+		err            error
+		expectedStatus int
+		expectedOK     bool
+	}{
+		{
+			description:    "Rate Limited",
+			m:              DefaultStatusMap,
+			err:            fmt.Errorf("wrapped: %w", bascule.ErrRateLimited),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedOK:     true,
+		},
+		{
+			description:    "Unavailable",
+			m:              DefaultStatusMap,
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedOK:     true,
+		},
+		{
+			description:    "Expired",
+			m:              DefaultStatusMap,
+			err:            ErrStaleDigestNonce,
+			expectedStatus: http.StatusUnauthorized,
+			expectedOK:     true,
+		},
+		{
+			description: "Unknown Class",
+			m:           DefaultStatusMap,
+			err:         errors.New("test"),
+		},
+		{
+			description: "Nil Error",
+			m:           DefaultStatusMap,
+		},
+		{
+			description: "Empty Map",
+			err:         bascule.ErrRateLimited,
+		},
+		{
+			description:    "Custom Map",
+			m:              StatusMap{bascule.RateLimitedClass: http.StatusServiceUnavailable},
+			err:            bascule.ErrRateLimited,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedOK:     true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			status, ok := tc.m.Status(tc.err)
+			assert.Equal(tc.expectedOK, ok)
+			assert.Equal(tc.expectedStatus, status)
+			if tc.expectedOK {
+				assert.Equal(tc.expectedStatus, tc.m.StatusOr(tc.err, http.StatusTeapot))
+			} else {
+				assert.Equal(http.StatusTeapot, tc.m.StatusOr(tc.err, http.StatusTeapot))
+			}
+		})
+	}
+}
+
+func TestConstructorStatusMap(t *testing.T) {
+	tests := []struct {
+		description    string
+		options        []COption
+		err            error
+		expectedStatus int
+	}{
+		{
+			description:    "Default",
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "Mapped",
+			options:        []COption{WithCStatusMap(DefaultStatusMap)},
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			description:    "Not Mapped",
+			options:        []COption{WithCStatusMap(StatusMap{bascule.RateLimitedClass: http.StatusTooManyRequests})},
+			err:            ErrRemoteAuthUnavailable,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				return nil, tc.err
+			})
+			options := append(tc.options, WithTokenFactory(BasicAuthorization, tf))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			w := httptest.NewRecorder()
+			NewConstructor(options...)(next).ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestEnforcerStatusMap(t *testing.T) {
+	tests := []struct {
+		description    string
+		options        []EOption
+		err            error
+		expectedStatus int
+	}{
+		{
+			description:    "Unclassified",
+			err:            errors.New("test"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "No Map",
+			err:            bascule.ErrExpired,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Default Map",
+			options:        []EOption{WithEStatusMap(DefaultStatusMap)},
+			err:            bascule.ErrRateLimited,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			description:    "Custom Map",
+			options:        []EOption{WithEStatusMap(StatusMap{bascule.CapabilityClass: http.StatusNotFound}), WithEStatusMap(nil)},
+			err:            bascule.Errors{bascule.ErrCapability},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			options := append(tc.options, WithRules("jwt", bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+				return tc.err
+			})))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Authorization: "jwt",
+				Token:         bascule.NewToken("jwt", "alice", nil),
+			}))
+			w := httptest.NewRecorder()
+			NewEnforcer(options...)(next).ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestParseReasonStatusMap(t *testing.T) {
+	tests := []struct {
+		description string
+		statuses    map[string]int
+		expected    ReasonStatusMap
+		expectedErr bool
+	}{
+		{
+			description: "Empty",
+			expected:    ReasonStatusMap{},
+		},
+		{
+			description: "Success",
+			statuses:    map[string]int{"key_not_supported": 401, "invalid_header": 400},
+			expected:    ReasonStatusMap{KeyNotSupported: http.StatusUnauthorized, InvalidHeader: http.StatusBadRequest},
+		},
+		{
+			description: "Unknown Reason",
+			statuses:    map[string]int{"nope": 401},
+			expectedErr: true,
+		},
+		{
+			description: "Not An Error Status",
+			statuses:    map[string]int{"parse_failed": 200},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := ParseReasonStatusMap(tc.statuses)
+			assert.Equal(tc.expected, m)
+			assert.Equal(tc.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestConstructorReasonStatusMap(t *testing.T) {
+	tests := []struct {
+		description    string
+		options        []COption
+		header         string
+		expectedStatus int
+	}{
+		{
+			description:    "Default",
+			header:         "Basic abc",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "Legacy",
+			options:        []COption{WithCErrorHTTPResponseFunc(LegacyOnErrorHTTPResponse)},
+			header:         "Unknown abc",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description: "Mapped Reason",
+			options: []COption{
+				WithCErrorHTTPResponseFunc(LegacyOnErrorHTTPResponse),
+				WithCReasonStatusMap(ReasonStatusMap{KeyNotSupported: http.StatusUnauthorized}),
+			},
+			header:         "Unknown abc",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description: "Before Status Map",
+			options: []COption{
+				WithCStatusMap(DefaultStatusMap),
+				WithCReasonStatusMap(ReasonStatusMap{ParseFailed: http.StatusBadRequest}),
+			},
+			header:         "Basic abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "Not Mapped",
+			options: []COption{
+				WithCStatusMap(DefaultStatusMap),
+				WithCReasonStatusMap(ReasonStatusMap{InvalidHeader: http.StatusBadRequest}),
+			},
+			header:         "Basic abc",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				return nil, ErrRemoteAuthUnavailable
+			})
+			options := append(tc.options, WithTokenFactory(BasicAuthorization, tf))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, tc.header)
+			w := httptest.NewRecorder()
+			NewConstructor(options...)(next).ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
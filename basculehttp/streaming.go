@@ -0,0 +1,77 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/spf13/cast"
+)
+
+const expClaimKey = "exp"
+
+// ErrBodyReadDuringAuth is returned when a token factory reads the request
+// body while the constructor only allows authentication from headers.
+var ErrBodyReadDuringAuth = errors.New("request body can't be read during authentication")
+
+// headersOnlyBody replaces the body of the request given to token factories,
+// so a streaming body is never partly consumed before the handler sees it.
+type headersOnlyBody struct{}
+
+func (headersOnlyBody) Read([]byte) (int, error) {
+	return 0, ErrBodyReadDuringAuth
+}
+
+func (headersOnlyBody) Close() error {
+	return nil
+}
+
+// headersOnly returns a shallow copy of the request without its body.
+func headersOnly(r *http.Request) *http.Request {
+	hr := *r
+	hr.Body = headersOnlyBody{}
+	hr.GetBody = nil
+	return &hr
+}
+
+// TokenExpired returns true if the context was canceled because the token
+// used to authenticate the request expired.  See WithCExpiryCancel.
+func TokenExpired(ctx context.Context) bool {
+	s, ok := SessionFromContext(ctx)
+	return ok && s.tokenExpired()
+}
+
+// tokenExpiration gets the exp claim from the token, if there is one.
+func tokenExpiration(t bascule.Token) (time.Time, bool) {
+	if t == nil || t.Attributes() == nil {
+		return time.Time{}, false
+	}
+	v, ok := t.Attributes().Get(expClaimKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	exp, err := cast.ToInt64E(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(exp, 0), true
+}
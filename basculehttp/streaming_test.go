@@ -0,0 +1,155 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstructorHeadersOnly(t *testing.T) {
+	tests := []struct {
+		description     string
+		headersOnly     bool
+		expectedFactory string
+		expectedHandler string
+		expectedErr     error
+	}{
+		{
+			description:     "Headers Only",
+			headersOnly:     true,
+			expectedHandler: "streamed body",
+			expectedErr:     ErrBodyReadDuringAuth,
+		},
+		{
+			description:     "Body Readable",
+			expectedFactory: "streamed body",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var factoryBody string
+			var factoryErr error
+			tf := TokenFactoryFunc(func(_ context.Context, r *http.Request, _ bascule.Authorization, _ string) (bascule.Token, error) {
+				b, err := io.ReadAll(r.Body)
+				factoryBody, factoryErr = string(b), err
+				return bascule.NewToken("test", "principal", bascule.NewAttributes(nil)), nil
+			})
+			opts := []COption{WithTokenFactory(BasicAuthorization, tf)}
+			if tc.headersOnly {
+				opts = append(opts, WithCHeadersOnly())
+			}
+			var handlerBody string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				handlerBody = string(b)
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("streamed body"))
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			w := httptest.NewRecorder()
+			NewConstructor(opts...)(handler).ServeHTTP(w, req)
+			assert.Equal(http.StatusOK, w.Code)
+			assert.Equal(tc.expectedFactory, factoryBody)
+			assert.ErrorIs(factoryErr, tc.expectedErr)
+			assert.Equal(tc.expectedHandler, handlerBody)
+		})
+	}
+}
+
+func TestConstructorExpiryCancel(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		description     string
+		attributes      map[string]interface{}
+		grace           time.Duration
+		expectedExpired bool
+	}{
+		{
+			description:     "Expired",
+			attributes:      map[string]interface{}{"exp": float64(now.Unix() - 1)},
+			expectedExpired: true,
+		},
+		{
+			description: "Within Grace",
+			attributes:  map[string]interface{}{"exp": now.Unix() - 1},
+			grace:       time.Hour,
+		},
+		{
+			description: "Not Expired",
+			attributes:  map[string]interface{}{"exp": now.Add(time.Hour).Unix()},
+		},
+		{
+			description: "No Exp",
+			attributes:  map[string]interface{}{},
+		},
+		{
+			description: "Bad Exp",
+			attributes:  map[string]interface{}{"exp": "soon"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				return bascule.NewToken("test", "principal", bascule.NewAttributes(tc.attributes)), nil
+			})
+			var (
+				called  bool
+				expired bool
+				ctx     context.Context
+			)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				ctx = r.Context()
+				select {
+				case <-ctx.Done():
+				case <-time.After(100 * time.Millisecond):
+				}
+				expired = TokenExpired(ctx)
+			})
+			c := NewConstructor(
+				WithTokenFactory(BasicAuthorization, tf),
+				WithCExpiryCancel(tc.grace),
+			)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(DefaultHeaderName, "Basic abc")
+			c(handler).ServeHTTP(httptest.NewRecorder(), req)
+			require.True(t, called)
+			assert.Equal(tc.expectedExpired, expired)
+
+			// the context is released once the handler returns.
+			assert.Error(ctx.Err())
+			assert.Equal(tc.expectedExpired, TokenExpired(ctx))
+		})
+	}
+}
+
+func TestTokenExpiredNoSession(t *testing.T) {
+	assert.False(t, TokenExpired(context.Background()))
+}
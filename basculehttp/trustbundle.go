@@ -0,0 +1,429 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultTrustBundleRefresh = 5 * time.Minute
+	defaultTrustBundleTimeout = 30 * time.Second
+	maxTrustBundleSize        = 1 << 20
+
+	// x509SVIDUse is the use of the JWKS keys in a SPIFFE bundle that hold
+	// X.509 CA certificates.
+	x509SVIDUse = "x509-svid"
+)
+
+var (
+	// ErrEmptyTrustBundle is returned when a TrustBundleManager has no CA
+	// certificates to trust.
+	ErrEmptyTrustBundle = errors.New("trust bundle has no certificates")
+
+	// ErrNoVerifiedChains is returned when a peer certificate doesn't chain to
+	// the trust bundle.
+	ErrNoVerifiedChains = errors.New("peer certificate isn't trusted")
+)
+
+// FederationEndpoint is a SPIFFE bundle endpoint serving the trust bundle of
+// a federated trust domain.
+type FederationEndpoint struct {
+	// TrustDomain is the SPIFFE trust domain the bundle is for.
+	TrustDomain string
+
+	// URL is the https URL of the bundle endpoint.
+	URL string
+}
+
+// TrustBundleConfig configures a TrustBundleManager.
+type TrustBundleConfig struct {
+	// Files are paths of PEM encoded CA certificates.
+	Files []string
+
+	// PEM holds PEM encoded CA certificates given directly in configuration.
+	PEM []string
+
+	// Federation lists the SPIFFE bundle endpoints to fetch CA certificates
+	// from.
+	Federation []FederationEndpoint
+
+	// RefreshInterval is how often the files and federation endpoints are
+	// read again.  A shorter spiffe_refresh_hint from an endpoint takes
+	// precedence.  Defaults to 5 minutes.
+	RefreshInterval time.Duration
+
+	// Timeout bounds each refresh.  Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// HTTPClient fetches the federation endpoints.  Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// OnError, if set, is called when a refresh fails for a source.  The
+	// source's last certificates stay trusted.
+	OnError func(source string, err error)
+}
+
+// spiffeBundle is the JWKS document served by SPIFFE bundle endpoints.
+type spiffeBundle struct {
+	Keys        []spiffeKey `json:"keys"`
+	RefreshHint int64       `json:"spiffe_refresh_hint"`
+}
+
+type spiffeKey struct {
+	Use string   `json:"use"`
+	X5C []string `json:"x5c"`
+}
+
+// TrustBundleManager merges CA certificates from files, configuration, and
+// SPIFFE federation endpoints into one trust bundle, refreshing it in the
+// background.  Servers and clients using its TLS configs pick up rotated CAs
+// on their next handshake, so established connections aren't dropped.
+type TrustBundleManager struct {
+	config TrustBundleConfig
+
+	lock    sync.RWMutex
+	sources map[string][]*x509.Certificate
+	pool    *x509.CertPool
+	hint    time.Duration
+
+	runLock sync.Mutex
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+}
+
+// NewTrustBundleManager creates a TrustBundleManager, loading the trust bundle
+// for the first time.  It fails if no source could be loaded.
+func NewTrustBundleManager(ctx context.Context, config TrustBundleConfig) (*TrustBundleManager, error) {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultTrustBundleRefresh
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTrustBundleTimeout
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	m := &TrustBundleManager{
+		config:  config,
+		sources: make(map[string][]*x509.Certificate),
+		pool:    x509.NewCertPool(),
+	}
+	err := m.Refresh(ctx)
+	if len(m.Certificates()) == 0 {
+		return nil, multierr.Append(ErrEmptyTrustBundle, err)
+	}
+	return m, nil
+}
+
+// Refresh reloads every source, rebuilding the trust bundle.  Sources that
+// fail keep their last certificates, and their errors are returned combined.
+func (m *TrustBundleManager) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	loaded := make(map[string][]*x509.Certificate)
+	var (
+		errs error
+		hint time.Duration
+	)
+	fail := func(source string, err error) {
+		err = fmt.Errorf("failed to load trust bundle from [%s]: %w", source, err)
+		errs = multierr.Append(errs, err)
+		if m.config.OnError != nil {
+			m.config.OnError(source, err)
+		}
+	}
+	for i, p := range m.config.PEM {
+		source := fmt.Sprintf("config[%d]", i)
+		certs, err := parsePEMCertificates([]byte(p))
+		if err != nil {
+			fail(source, err)
+			continue
+		}
+		loaded[source] = certs
+	}
+	for _, file := range m.config.Files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			loaded[file], err = parsePEMCertificates(data)
+		}
+		if err != nil {
+			delete(loaded, file)
+			fail(file, err)
+		}
+	}
+	for _, endpoint := range m.config.Federation {
+		certs, refreshHint, err := m.fetchSPIFFEBundle(ctx, endpoint)
+		if err != nil {
+			fail(endpoint.URL, err)
+			continue
+		}
+		loaded[endpoint.URL] = certs
+		if refreshHint > 0 && (hint == 0 || refreshHint < hint) {
+			hint = refreshHint
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for source, certs := range loaded {
+		m.sources[source] = certs
+	}
+	pool := x509.NewCertPool()
+	for _, certs := range m.sources {
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+	m.pool = pool
+	m.hint = hint
+	return errs
+}
+
+func (m *TrustBundleManager) fetchSPIFFEBundle(ctx context.Context, endpoint FederationEndpoint) ([]*x509.Certificate, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := m.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected response status [%d]", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTrustBundleSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	var bundle spiffeBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode SPIFFE bundle for [%s]: %w", endpoint.TrustDomain, err)
+	}
+	var certs []*x509.Certificate
+	for _, key := range bundle.Keys {
+		if key.Use != x509SVIDUse {
+			continue
+		}
+		if len(key.X5C) != 1 {
+			return nil, 0, fmt.Errorf("x509-svid key for [%s] must have exactly one certificate", endpoint.TrustDomain)
+		}
+		der, err := base64.StdEncoding.DecodeString(key.X5C[0])
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode x509-svid key for [%s]: %w", endpoint.TrustDomain, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse x509-svid key for [%s]: %w", endpoint.TrustDomain, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, 0, fmt.Errorf("%w: [%s]", ErrEmptyTrustBundle, endpoint.TrustDomain)
+	}
+	return certs, time.Duration(bundle.RefreshHint) * time.Second, nil
+}
+
+// parsePEMCertificates parses every CERTIFICATE block in the data.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, ErrEmptyTrustBundle
+	}
+	return certs, nil
+}
+
+// Pool returns the current trust bundle.  The pool isn't changed by later
+// refreshes, so it can be kept for as long as needed.
+func (m *TrustBundleManager) Pool() *x509.CertPool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.pool
+}
+
+// Certificates returns the CA certificates in the current trust bundle.
+func (m *TrustBundleManager) Certificates() []*x509.Certificate {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var certs []*x509.Certificate
+	for _, c := range m.sources {
+		certs = append(certs, c...)
+	}
+	return certs
+}
+
+// ServerTLSConfig returns a copy of the base config that verifies client
+// certificates against the current trust bundle on each handshake.  Client
+// certificates are required unless the base config asks for them some other
+// way.  The base config may be nil, and its VerifyConnection, such as a
+// RevocationChecker's, still runs.
+func (m *TrustBundleManager) ServerTLSConfig(base *tls.Config) *tls.Config {
+	config := cloneTLSConfig(base)
+	if config.ClientAuth == tls.NoClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	config.ClientCAs = m.Pool()
+	template := config.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := template.Clone()
+		c.ClientCAs = m.Pool()
+		return c, nil
+	}
+	return config
+}
+
+// ClientTLSConfig returns a copy of the base config for outbound connections
+// that verifies servers against the current trust bundle on each handshake.
+// The base config may be nil.
+func (m *TrustBundleManager) ClientTLSConfig(base *tls.Config) *tls.Config {
+	config := cloneTLSConfig(base)
+	// the tls package only verifies against a fixed RootCAs, so verification
+	// is done in VerifyConnection with the trust bundle current at the time.
+	config.InsecureSkipVerify = true //nolint:gosec
+	config.RootCAs = nil
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return ErrNoPeerCertificates
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range cs.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       cs.ServerName,
+			Roots:         m.Pool(),
+			Intermediates: intermediates,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNoVerifiedChains, err)
+		}
+		if verify != nil {
+			return verify(cs)
+		}
+		return nil
+	}
+	return config
+}
+
+func cloneTLSConfig(base *tls.Config) *tls.Config {
+	if base == nil {
+		return &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return base.Clone()
+}
+
+// next returns how long to wait before the next refresh.
+func (m *TrustBundleManager) next() time.Duration {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.hint > 0 && m.hint < m.config.RefreshInterval {
+		return m.hint
+	}
+	return m.config.RefreshInterval
+}
+
+// Start refreshes the trust bundle in the background until Stop is called.
+// Calling Start again while running does nothing.
+func (m *TrustBundleManager) Start() {
+	m.runLock.Lock()
+	defer m.runLock.Unlock()
+	if m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done.Add(1)
+	go m.run(ctx)
+}
+
+func (m *TrustBundleManager) run(ctx context.Context) {
+	defer m.done.Done()
+	timer := time.NewTimer(m.next())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			_ = m.Refresh(ctx)
+			timer.Reset(m.next())
+		}
+	}
+}
+
+// Stop stops refreshing the trust bundle.  The current bundle stays in use.
+func (m *TrustBundleManager) Stop() {
+	m.runLock.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.runLock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	m.done.Wait()
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops refreshing the
+// trust bundle.
+func (m *TrustBundleManager) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			m.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			m.Stop()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,343 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func certPEM(certs ...*x509.Certificate) string {
+	var b strings.Builder
+	for _, c := range certs {
+		_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	return b.String()
+}
+
+// tlsCertificate issues a certificate for 127.0.0.1 usable by both clients
+// and servers.
+func (ca testCA) tlsCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// bundleServer serves a SPIFFE bundle that can be changed.
+type bundleServer struct {
+	lock   sync.Mutex
+	status int
+	body   string
+}
+
+func (b *bundleServer) set(status int, body string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.status, b.body = status, body
+}
+
+func (b *bundleServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	w.WriteHeader(b.status)
+	_, _ = w.Write([]byte(b.body))
+}
+
+func spiffeBundleJSON(t *testing.T, hint int64, certs ...*x509.Certificate) string {
+	bundle := spiffeBundle{RefreshHint: hint}
+	for _, c := range certs {
+		bundle.Keys = append(bundle.Keys, spiffeKey{
+			Use: x509SVIDUse,
+			X5C: []string{base64.StdEncoding.EncodeToString(c.Raw)},
+		})
+	}
+	// keys for JWT-SVIDs are skipped.
+	bundle.Keys = append(bundle.Keys, spiffeKey{Use: "jwt-svid"})
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestNewTrustBundleManager(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m, err := NewTrustBundleManager(context.Background(), TrustBundleConfig{})
+	assert.ErrorIs(err, ErrEmptyTrustBundle)
+	assert.Nil(m)
+
+	m, err = NewTrustBundleManager(context.Background(), TrustBundleConfig{
+		Files: []string{filepath.Join(t.TempDir(), "missing.pem")},
+	})
+	assert.ErrorIs(err, ErrEmptyTrustBundle)
+	assert.ErrorIs(err, os.ErrNotExist)
+	assert.Nil(m)
+
+	ca := newTestCA(t, "config", nil)
+	m, err = NewTrustBundleManager(context.Background(), TrustBundleConfig{
+		PEM: []string{certPEM(ca.cert)},
+	})
+	require.NoError(err)
+	assert.Equal(defaultTrustBundleRefresh, m.config.RefreshInterval)
+	assert.Equal(defaultTrustBundleTimeout, m.config.Timeout)
+	assert.Equal(http.DefaultClient, m.config.HTTPClient)
+	assert.Len(m.Certificates(), 1)
+}
+
+func TestTrustBundleManagerRefresh(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	configCA := newTestCA(t, "config", nil)
+	fileCA := newTestCA(t, "file", nil)
+	rotatedCA := newTestCA(t, "rotated", nil)
+	federatedCA := newTestCA(t, "federated", nil)
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(os.WriteFile(file, []byte(certPEM(fileCA.cert)), 0600))
+	endpoint := &bundleServer{}
+	endpoint.set(http.StatusOK, spiffeBundleJSON(t, 60, federatedCA.cert))
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	var failed []string
+	m, err := NewTrustBundleManager(context.Background(), TrustBundleConfig{
+		Files: []string{file},
+		PEM:   []string{certPEM(configCA.cert)},
+		Federation: []FederationEndpoint{
+			{TrustDomain: "example.org", URL: server.URL},
+		},
+		RefreshInterval: time.Hour,
+		OnError: func(source string, _ error) {
+			failed = append(failed, source)
+		},
+	})
+	require.NoError(err)
+	assert.Len(m.Certificates(), 3)
+	assert.Equal(time.Minute, m.next())
+
+	verify := func(ca testCA) error {
+		cert := ca.issue(t, 1)
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:     m.Pool(),
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		return err
+	}
+	assert.NoError(verify(configCA))
+	assert.NoError(verify(fileCA))
+	assert.NoError(verify(federatedCA))
+	assert.Error(verify(rotatedCA))
+
+	// rotation: the old and new CAs are both served during the overlap.
+	require.NoError(os.WriteFile(file, []byte(certPEM(fileCA.cert, rotatedCA.cert)), 0600))
+	require.NoError(m.Refresh(context.Background()))
+	assert.NoError(verify(fileCA))
+	assert.NoError(verify(rotatedCA))
+	require.NoError(os.WriteFile(file, []byte(certPEM(rotatedCA.cert)), 0600))
+	require.NoError(m.Refresh(context.Background()))
+	assert.Error(verify(fileCA))
+	assert.NoError(verify(rotatedCA))
+	assert.Empty(failed)
+
+	// failing sources keep their last certificates.
+	require.NoError(os.WriteFile(file, []byte("garbage"), 0600))
+	endpoint.set(http.StatusServiceUnavailable, "")
+	err = m.Refresh(context.Background())
+	assert.ErrorIs(err, ErrEmptyTrustBundle)
+	assert.ErrorContains(err, "unexpected response status [503]")
+	assert.Equal([]string{file, server.URL}, failed)
+	assert.NoError(verify(rotatedCA))
+	assert.NoError(verify(federatedCA))
+	assert.Equal(time.Hour, m.next())
+}
+
+func TestTrustBundleManagerBadFederation(t *testing.T) {
+	ca := newTestCA(t, "config", nil)
+	tests := []struct {
+		description string
+		body        string
+		expectedErr string
+	}{
+		{
+			description: "Not JSON",
+			body:        "{",
+			expectedErr: "failed to decode SPIFFE bundle",
+		},
+		{
+			description: "No keys",
+			body:        `{"keys": []}`,
+			expectedErr: ErrEmptyTrustBundle.Error(),
+		},
+		{
+			description: "Certificate chain",
+			body:        `{"keys": [{"use": "x509-svid", "x5c": ["a", "b"]}]}`,
+			expectedErr: "exactly one certificate",
+		},
+		{
+			description: "Bad base64",
+			body:        `{"keys": [{"use": "x509-svid", "x5c": ["!"]}]}`,
+			expectedErr: "failed to decode x509-svid key",
+		},
+		{
+			description: "Bad certificate",
+			body:        `{"keys": [{"use": "x509-svid", "x5c": ["YWJj"]}]}`,
+			expectedErr: "failed to parse x509-svid key",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			endpoint := &bundleServer{}
+			endpoint.set(http.StatusOK, tc.body)
+			server := httptest.NewServer(endpoint)
+			defer server.Close()
+
+			m, err := NewTrustBundleManager(context.Background(), TrustBundleConfig{
+				PEM:        []string{certPEM(ca.cert)},
+				Federation: []FederationEndpoint{{TrustDomain: "example.org", URL: server.URL}},
+			})
+			require.NoError(err)
+			assert.ErrorContains(m.Refresh(context.Background()), tc.expectedErr)
+			assert.Len(m.Certificates(), 1)
+		})
+	}
+}
+
+func TestTrustBundleManagerTLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	serverCA := newTestCA(t, "server", nil)
+	oldCA := newTestCA(t, "old", nil)
+	newCA := newTestCA(t, "new", nil)
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(os.WriteFile(file, []byte(certPEM(serverCA.cert, oldCA.cert)), 0600))
+	m, err := NewTrustBundleManager(context.Background(), TrustBundleConfig{Files: []string{file}})
+	require.NoError(err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = m.ServerTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{serverCA.tlsCertificate(t)},
+		MinVersion:   tls.VersionTLS12,
+	})
+	server.StartTLS()
+	defer server.Close()
+	assert.Equal(tls.RequireAndVerifyClientCert, server.TLS.ClientAuth)
+
+	get := func(ca testCA) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: m.ClientTLSConfig(&tls.Config{
+				Certificates: []tls.Certificate{ca.tlsCertificate(t)},
+				MinVersion:   tls.VersionTLS12,
+			}),
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.NoError(get(oldCA))
+	assert.Error(get(newCA))
+
+	// rotating to the new CA takes effect on the next handshake.
+	require.NoError(os.WriteFile(file, []byte(certPEM(serverCA.cert, newCA.cert)), 0600))
+	require.NoError(m.Refresh(context.Background()))
+	assert.Error(get(oldCA))
+	assert.NoError(get(newCA))
+
+	// the client stops trusting the server once its CA is removed.
+	require.NoError(os.WriteFile(file, []byte(certPEM(newCA.cert)), 0600))
+	require.NoError(m.Refresh(context.Background()))
+	assert.ErrorIs(get(newCA), ErrNoVerifiedChains)
+
+	assert.ErrorIs(m.ClientTLSConfig(nil).VerifyConnection(tls.ConnectionState{}), ErrNoPeerCertificates)
+}
+
+func TestTrustBundleManagerStartStop(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ca := newTestCA(t, "config", nil)
+	endpoint := &bundleServer{}
+	endpoint.set(http.StatusOK, spiffeBundleJSON(t, 0, ca.cert))
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	refreshed := make(chan struct{}, 1)
+	m, err := NewTrustBundleManager(context.Background(), TrustBundleConfig{
+		Federation:      []FederationEndpoint{{TrustDomain: "example.org", URL: server.URL}},
+		RefreshInterval: time.Millisecond,
+		OnError: func(string, error) {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		},
+	})
+	require.NoError(err)
+	endpoint.set(http.StatusInternalServerError, "")
+
+	hook := m.Hook()
+	require.NoError(hook.OnStart(context.Background()))
+	m.Start()
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		assert.Fail("trust bundle wasn't refreshed")
+	}
+	require.NoError(hook.OnStop(context.Background()))
+	m.Stop()
+	assert.Len(m.Certificates(), 1)
+}
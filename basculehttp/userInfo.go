@@ -0,0 +1,235 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+)
+
+const (
+	defaultUserInfoTimeout         = 5 * time.Second
+	defaultUserInfoCacheTTL        = 5 * time.Minute
+	defaultUserInfoCacheMaxEntries = 10000
+	userInfoMaxResponseSize        = 1 << 20
+	userInfoSubjectClaim           = "sub"
+)
+
+var (
+	ErrEmptyUserInfoURL = errors.New("userinfo url cannot be empty")
+
+	// ErrUserInfoUnavailable is returned when the userinfo endpoint can't be
+	// called and the enrichment is required.
+	ErrUserInfoUnavailable = bascule.NewClassError(bascule.UnavailableClass, "userinfo endpoint unavailable")
+
+	// ErrUserInfoSubjectMismatch is returned when the userinfo response is
+	// for a different subject than the token, which OpenID Connect requires
+	// to be rejected.
+	ErrUserInfoSubjectMismatch = bascule.NewClassError(bascule.InvalidClass, "userinfo subject doesn't match the token")
+)
+
+// UserInfoConfig configures a UserInfoTokenFactory.
+type UserInfoConfig struct {
+	// URL is the OpenID Connect userinfo endpoint.
+	URL string
+
+	// Claims are the claims validators rely on.  The userinfo endpoint is
+	// only called when one of them is missing from the token.  Defaults to
+	// "email" and "name".
+	Claims []string
+
+	// Client is the client used to call the endpoint.  Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Timeout bounds each call to the endpoint.  Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// CacheTTL is how long responses are cached by subject.  Defaults to five
+	// minutes.  A negative TTL disables caching.
+	CacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached responses.  Defaults to
+	// 10000.
+	CacheMaxEntries int
+
+	// Required fails the request with ErrUserInfoUnavailable when the
+	// endpoint can't be called.  Otherwise, the token is used as it is.
+	Required bool
+}
+
+// UserInfoTokenFactory fills in claims missing from sparse access tokens by
+// calling the OpenID Connect userinfo endpoint with the access token.  Claims
+// from the response are added to the token's attributes, though claims that
+// are in the token itself take precedence.  Responses are cached by subject.
+type UserInfoTokenFactory struct {
+	factory TokenFactory
+	config  UserInfoConfig
+	now     func() time.Time
+	cache   *ttlcache.Cache
+}
+
+// NewUserInfoTokenFactory wraps the TokenFactory given, usually a
+// BearerTokenFactory.
+func NewUserInfoTokenFactory(tf TokenFactory, config UserInfoConfig) (*UserInfoTokenFactory, error) {
+	if tf == nil {
+		return nil, ErrNilTokenFactory
+	}
+	if config.URL == "" {
+		return nil, ErrEmptyUserInfoURL
+	}
+	if len(config.Claims) == 0 {
+		config.Claims = []string{"email", "name"}
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultUserInfoTimeout
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = defaultUserInfoCacheTTL
+	}
+	if config.CacheMaxEntries <= 0 {
+		config.CacheMaxEntries = defaultUserInfoCacheMaxEntries
+	}
+	return &UserInfoTokenFactory{
+		factory: tf,
+		config:  config,
+		now:     time.Now,
+		cache:   ttlcache.New(config.CacheMaxEntries),
+	}, nil
+}
+
+// ParseAndValidate calls the wrapped TokenFactory, then adds the userinfo
+// claims to the token if any of the configured claims are missing.
+func (u *UserInfoTokenFactory) ParseAndValidate(ctx context.Context, r *http.Request, a bascule.Authorization, value string) (bascule.Token, error) {
+	token, err := u.factory.ParseAndValidate(ctx, r, a, value)
+	if err != nil || !u.sparse(token) {
+		return token, err
+	}
+
+	subject := token.Principal()
+	claims, ok := u.cached(subject)
+	if !ok {
+		claims, err = u.fetch(ctx, value)
+		if err != nil {
+			if u.config.Required {
+				return nil, fmt.Errorf("%w: %v", ErrUserInfoUnavailable, err)
+			}
+			return token, nil
+		}
+		if sub, _ := claims[userInfoSubjectClaim].(string); sub != subject {
+			return nil, fmt.Errorf("%w: [%v]", ErrUserInfoSubjectMismatch, sub)
+		}
+		u.store(subject, claims)
+	}
+	return bascule.NewToken(token.Type(), token.Principal(), userInfoAttributes{
+		base: token.Attributes(),
+		info: claims,
+	}), nil
+}
+
+// sparse returns true if the token is missing one of the configured claims.
+func (u *UserInfoTokenFactory) sparse(token bascule.Token) bool {
+	attributes := token.Attributes()
+	if attributes == nil {
+		return true
+	}
+	for _, claim := range u.config.Claims {
+		if _, ok := attributes.Get(claim); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch calls the userinfo endpoint with the access token.
+func (u *UserInfoTokenFactory) fetch(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(DefaultHeaderName, string(BearerAuthorization)+" "+accessToken)
+	req.Header.Set(acceptHeader, JSONMediaType)
+	resp, err := u.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, userInfoMaxResponseSize))
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, userInfoMaxResponseSize)).Decode(&claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return claims, nil
+}
+
+func (u *UserInfoTokenFactory) cached(subject string) (map[string]interface{}, bool) {
+	v, ok := u.cache.Get(subject, u.now())
+	if !ok {
+		return nil, false
+	}
+	return v.(map[string]interface{}), true
+}
+
+func (u *UserInfoTokenFactory) store(subject string, claims map[string]interface{}) {
+	if u.config.CacheTTL < 0 {
+		return
+	}
+	now := u.now()
+	u.cache.Set(subject, claims, now.Add(u.config.CacheTTL), now)
+}
+
+// userInfoAttributes layers the userinfo claims under the token's own
+// attributes.
+type userInfoAttributes struct {
+	base bascule.Attributes
+	info map[string]interface{}
+}
+
+func (a userInfoAttributes) Get(key string) (interface{}, bool) {
+	if a.base != nil {
+		if v, ok := a.base.Get(key); ok {
+			return v, ok
+		}
+	}
+	v, ok := a.info[key]
+	return v, ok
+}
+
+func (a userInfoAttributes) Keys() ([]string, bool) {
+	keys, _ := bascule.BasicAttributes(a.info).Keys()
+	return bascule.LayeredKeys(a.base, keys...)
+}
@@ -0,0 +1,209 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUserInfoTokenFactory(t *testing.T) {
+	assert := assert.New(t)
+	tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+		return nil, nil
+	})
+	u, err := NewUserInfoTokenFactory(nil, UserInfoConfig{URL: "http://localhost"})
+	assert.ErrorIs(err, ErrNilTokenFactory)
+	assert.Nil(u)
+
+	u, err = NewUserInfoTokenFactory(tf, UserInfoConfig{})
+	assert.ErrorIs(err, ErrEmptyUserInfoURL)
+	assert.Nil(u)
+
+	u, err = NewUserInfoTokenFactory(tf, UserInfoConfig{URL: "http://localhost"})
+	assert.NoError(err)
+	assert.Equal([]string{"email", "name"}, u.config.Claims)
+	assert.Equal(http.DefaultClient, u.config.Client)
+	assert.Equal(defaultUserInfoTimeout, u.config.Timeout)
+	assert.Equal(defaultUserInfoCacheTTL, u.config.CacheTTL)
+	assert.Equal(defaultUserInfoCacheMaxEntries, u.config.CacheMaxEntries)
+}
+
+func TestUserInfoTokenFactory(t *testing.T) {
+	testErr := errors.New("test error")
+	tests := []struct {
+		description   string
+		claims        map[string]interface{}
+		factoryErr    error
+		status        int
+		response      map[string]interface{}
+		required      bool
+		expectedCalls int32
+		expectedErr   error
+		expectedEmail interface{}
+		expectedName  interface{}
+	}{
+		{
+			description:   "Enriched",
+			claims:        map[string]interface{}{"sub": "alice", "name": "Alice"},
+			status:        http.StatusOK,
+			response:      map[string]interface{}{"sub": "alice", "email": "alice@example.com", "name": "Other"},
+			expectedCalls: 1,
+			expectedEmail: "alice@example.com",
+			expectedName:  "Alice",
+		},
+		{
+			description:   "Not Sparse",
+			claims:        map[string]interface{}{"sub": "alice", "name": "Alice", "email": "a@example.com"},
+			expectedEmail: "a@example.com",
+			expectedName:  "Alice",
+		},
+		{
+			description: "Factory Error",
+			factoryErr:  testErr,
+			expectedErr: testErr,
+		},
+		{
+			description:   "Subject Mismatch",
+			claims:        map[string]interface{}{"sub": "alice"},
+			status:        http.StatusOK,
+			response:      map[string]interface{}{"sub": "mallory", "email": "m@example.com"},
+			expectedCalls: 1,
+			expectedErr:   ErrUserInfoSubjectMismatch,
+		},
+		{
+			description:   "Endpoint Error",
+			claims:        map[string]interface{}{"sub": "alice", "name": "Alice"},
+			status:        http.StatusUnauthorized,
+			expectedCalls: 1,
+			expectedName:  "Alice",
+		},
+		{
+			description:   "Endpoint Error Required",
+			claims:        map[string]interface{}{"sub": "alice"},
+			status:        http.StatusInternalServerError,
+			required:      true,
+			expectedCalls: 1,
+			expectedErr:   ErrUserInfoUnavailable,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				assert.Equal("Bearer access-token", r.Header.Get(DefaultHeaderName))
+				w.WriteHeader(tc.status)
+				if tc.response != nil {
+					_ = json.NewEncoder(w).Encode(tc.response)
+				}
+			}))
+			defer server.Close()
+
+			tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+				if tc.factoryErr != nil {
+					return nil, tc.factoryErr
+				}
+				return bascule.NewToken("jwt", "alice", bascule.NewAttributes(tc.claims)), nil
+			})
+			u, err := NewUserInfoTokenFactory(tf, UserInfoConfig{URL: server.URL, Required: tc.required})
+			require.NoError(err)
+			token, err := u.ParseAndValidate(context.Background(), nil, BearerAuthorization, "access-token")
+			assert.Equal(tc.expectedCalls, atomic.LoadInt32(&calls))
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(token)
+				return
+			}
+			require.NoError(err)
+			email, _ := token.Attributes().Get("email")
+			assert.Equal(tc.expectedEmail, email)
+			name, _ := token.Attributes().Get("name")
+			assert.Equal(tc.expectedName, name)
+			assert.Equal("alice", token.Principal())
+		})
+	}
+}
+
+func TestUserInfoTokenFactoryCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"sub": "alice", "email": "alice@example.com"})
+	}))
+	defer server.Close()
+
+	tf := TokenFactoryFunc(func(context.Context, *http.Request, bascule.Authorization, string) (bascule.Token, error) {
+		return bascule.NewToken("jwt", "alice", nil), nil
+	})
+	u, err := NewUserInfoTokenFactory(tf, UserInfoConfig{
+		URL:             server.URL,
+		Claims:          []string{"email"},
+		CacheTTL:        time.Minute,
+		CacheMaxEntries: 1,
+	})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	u.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		token, err := u.ParseAndValidate(context.Background(), nil, BearerAuthorization, "access-token")
+		require.NoError(err)
+		email, ok := token.Attributes().Get("email")
+		assert.True(ok)
+		assert.Equal("alice@example.com", email)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	now = now.Add(time.Minute)
+	_, err = u.ParseAndValidate(context.Background(), nil, BearerAuthorization, "access-token")
+	require.NoError(err)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(1, u.cache.Len())
+
+	u.config.CacheTTL = -1
+	u.cache = ttlcache.New(1)
+	_, err = u.ParseAndValidate(context.Background(), nil, BearerAuthorization, "access-token")
+	require.NoError(err)
+	assert.Zero(u.cache.Len())
+}
+
+func TestUserInfoAttributesKeys(t *testing.T) {
+	assert := assert.New(t)
+	keys, ok := bascule.AttributeKeys(userInfoAttributes{
+		base: bascule.NewAttributes(map[string]interface{}{"sub": "alice"}),
+		info: map[string]interface{}{"email": "alice@example.com", "sub": "alice"},
+	})
+	assert.True(ok)
+	assert.Equal([]string{"email", "sub"}, keys)
+}
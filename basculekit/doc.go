@@ -0,0 +1,33 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+/*
+Package basculekit provides go-kit endpoint middleware that authorizes
+requests, for services where authorization must run per endpoint rather than
+per transport.  The middleware validates the Authentication in the context,
+so a transport still has to build it: with go-kit's HTTP transport, wrap the
+server in the basculehttp constructor, whose Authentication is then in the
+context go-kit passes to the endpoint.
+
+	authorize := basculekit.New(basculekit.WithRules("Bearer", rules))
+	e := authorize(makeEndpoint(svc))
+	handler := basculehttp.NewConstructor(...)(httptransport.NewServer(e, decode, encode))
+
+The errors the middleware returns implement go-kit's StatusCoder, so the
+default error encoder writes the same status the basculehttp enforcer would.
+*/
+
+package basculekit
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+)
+
+var (
+	ErrNoAuthentication = bascule.NewClassError(bascule.MissingCredentialsClass, "no authentication found in context")
+	ErrNoRules          = bascule.NewClassError(bascule.CapabilityClass, "no rules found for authorization")
+)
+
+// Error is returned by the middleware for a request that isn't allowed.
+type Error struct {
+	Reason basculehttp.ErrorResponseReason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("endpoint authorization failed [%s]: %v", e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the status the basculehttp enforcer writes for the
+// failure when it picks statuses by stage: a 401 for a missing or invalid
+// credential and a 403 for a credential that isn't allowed.
+func (e *Error) StatusCode() int {
+	if status, ok := basculehttp.StageStatus(e.Reason, e.Err); ok {
+		return status
+	}
+	return http.StatusForbidden
+}
+
+// Option is how the middleware is configured.
+type Option func(*middleware)
+
+type middleware struct {
+	rules        map[bascule.Authorization]bascule.Validator
+	defaultRules bascule.Validator
+	kindRules    map[bascule.TokenKind]bascule.Validator
+}
+
+// WithRules sets the validator run for tokens of the given Authorization
+// value, after the default rules.
+func WithRules(key bascule.Authorization, v bascule.Validator) Option {
+	return func(m *middleware) {
+		if v != nil {
+			m.rules[key] = v
+		}
+	}
+}
+
+// WithDefaultRules sets the validator run for every Authorization value.
+// Authorization values without their own rules only run the default rules;
+// without default rules, they are rejected.
+func WithDefaultRules(v bascule.Validator) Option {
+	return func(m *middleware) {
+		if v != nil {
+			m.defaultRules = v
+		}
+	}
+}
+
+// WithKindRules sets a validator to be run against tokens of the given kind,
+// after the rules for the token's Authorization value pass.
+func WithKindRules(kind bascule.TokenKind, v bascule.Validator) Option {
+	return func(m *middleware) {
+		if v != nil {
+			m.kindRules[kind] = v
+		}
+	}
+}
+
+// New creates go-kit endpoint middleware that validates the Authentication in
+// the context before calling the endpoint.  Requests without an
+// Authentication, or whose Authorization value has no rules, are rejected.
+func New(options ...Option) endpoint.Middleware {
+	m := &middleware{
+		rules:     make(map[bascule.Authorization]bascule.Validator),
+		kindRules: make(map[bascule.TokenKind]bascule.Validator),
+	}
+	for _, o := range options {
+		if o != nil {
+			o(m)
+		}
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := m.check(ctx); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+func (m *middleware) check(ctx context.Context) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil {
+		return &Error{Reason: basculehttp.MissingAuthentication, Err: ErrNoAuthentication}
+	}
+	rules, ok := m.rulesFor(auth.Authorization)
+	if !ok {
+		return &Error{Reason: basculehttp.ChecksNotFound, Err: ErrNoRules}
+	}
+	if err := rules.Check(ctx, auth.Token); err != nil {
+		return &Error{Reason: basculehttp.ChecksFailed, Err: err}
+	}
+	if kindRules, ok := m.kindRules[bascule.KindOf(auth.Token)]; ok {
+		if err := kindRules.Check(ctx, auth.Token); err != nil {
+			return &Error{Reason: basculehttp.ChecksFailed, Err: err}
+		}
+	}
+	return nil
+}
+
+// rulesFor returns the default rules followed by the rules for the
+// Authorization value, or false if there are neither.
+func (m *middleware) rulesFor(key bascule.Authorization) (bascule.Validator, bool) {
+	rules, ok := m.rules[key]
+	switch {
+	case ok && m.defaultRules != nil:
+		return bascule.Validators{m.defaultRules, rules}, true
+	case ok:
+		return rules, true
+	case m.defaultRules != nil:
+		return m.defaultRules, true
+	}
+	return nil, false
+}
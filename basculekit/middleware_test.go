@@ -0,0 +1,143 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	denied := bascule.NewClassError(bascule.CapabilityClass, "denied")
+	deny := bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+		return denied
+	})
+	userToken := bascule.NewToken("jwt", "alice", bascule.NewAttributes(map[string]interface{}{
+		bascule.TokenKindKey: bascule.UserKind,
+	}))
+	tests := []struct {
+		description    string
+		options        []Option
+		auth           *bascule.Authentication
+		expectedReason basculehttp.ErrorResponseReason
+		expectedErr    error
+		expectedClass  bascule.ErrorClass
+		expectedStatus int
+	}{
+		{
+			description: "Success",
+			options:     []Option{WithRules("Bearer", basculechecks.AllowAll())},
+			auth:        &bascule.Authentication{Authorization: "Bearer", Token: userToken},
+		},
+		{
+			description: "Default Rules Success",
+			options:     []Option{nil, WithDefaultRules(basculechecks.AllowAll())},
+			auth:        &bascule.Authentication{Authorization: "Basic", Token: userToken},
+		},
+		{
+			description:    "No Authentication",
+			options:        []Option{WithRules("Bearer", basculechecks.AllowAll())},
+			expectedReason: basculehttp.MissingAuthentication,
+			expectedErr:    ErrNoAuthentication,
+			expectedClass:  bascule.MissingCredentialsClass,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "No Rules",
+			options:        []Option{WithRules("Bearer", basculechecks.AllowAll())},
+			auth:           &bascule.Authentication{Authorization: "Basic", Token: userToken},
+			expectedReason: basculehttp.ChecksNotFound,
+			expectedErr:    ErrNoRules,
+			expectedClass:  bascule.CapabilityClass,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description: "Default Rules Fail",
+			options: []Option{
+				WithDefaultRules(deny),
+				WithRules("Bearer", basculechecks.AllowAll()),
+			},
+			auth:           &bascule.Authentication{Authorization: "Bearer", Token: userToken},
+			expectedReason: basculehttp.ChecksFailed,
+			expectedClass:  bascule.CapabilityClass,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description: "Kind Rules Fail",
+			options: []Option{
+				WithRules("Bearer", basculechecks.AllowAll()),
+				WithKindRules(bascule.UserKind, deny),
+				WithKindRules(bascule.ServiceKind, basculechecks.AllowAll()),
+			},
+			auth:           &bascule.Authentication{Authorization: "Bearer", Token: userToken},
+			expectedReason: basculehttp.ChecksFailed,
+			expectedClass:  bascule.CapabilityClass,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			called := false
+			e := New(tc.options...)(func(_ context.Context, request interface{}) (interface{}, error) {
+				called = true
+				return request, nil
+			})
+			ctx := context.Background()
+			if tc.auth != nil {
+				ctx = bascule.WithAuthentication(ctx, *tc.auth)
+			}
+
+			response, err := e(ctx, "request")
+			if tc.expectedClass == bascule.UnknownClass {
+				assert.NoError(err)
+				assert.True(called)
+				assert.Equal("request", response)
+				return
+			}
+			assert.False(called)
+			assert.Nil(response)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+			assert.Equal(tc.expectedClass, bascule.ClassOf(err))
+			var authErr *Error
+			if assert.True(errors.As(err, &authErr)) {
+				assert.Equal(tc.expectedReason, authErr.Reason)
+				assert.Equal(tc.expectedStatus, authErr.StatusCode())
+			}
+		})
+	}
+}
+
+func TestErrorStatusCode(t *testing.T) {
+	assert := assert.New(t)
+	err := &Error{Reason: basculehttp.Unknown, Err: errors.New("unclassified")}
+	assert.Equal(http.StatusForbidden, err.StatusCode())
+	assert.Contains(err.Error(), "unclassified")
+
+	err = &Error{Reason: basculehttp.ChecksFailed, Err: bascule.NewClassError(bascule.UnavailableClass, "down")}
+	assert.Equal(http.StatusServiceUnavailable, err.StatusCode())
+}
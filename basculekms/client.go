@@ -0,0 +1,123 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrNilClient            = errors.New("kms client cannot be nil")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnsupportedKey       = errors.New("unsupported key type")
+	ErrMalformedSignature   = errors.New("malformed ecdsa signature")
+)
+
+// Client is the part of a KMS the package needs.  It is small so that any
+// KMS SDK can be adapted to it:
+//
+//   - AWS KMS: PublicKey calls GetPublicKey and parses the result with
+//     ParsePublicKey.  Sign calls Sign with a MessageType of DIGEST and the
+//     SigningAlgorithm from AWSSigningAlgorithm.
+//   - GCP Cloud KMS: PublicKey calls GetPublicKey on the key version and
+//     parses the PEM with ParsePublicKey.  Sign calls AsymmetricSign with the
+//     digest, since the algorithm is part of the key version.
+type Client interface {
+	// PublicKey returns the public half of the KMS key.
+	PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+
+	// Sign signs the digest with the KMS key using the JWS algorithm given.
+	// The signature is returned as the KMS produces it: the raw signature
+	// for RSA keys, or ASN.1 DER for ECDSA keys.
+	Sign(ctx context.Context, keyID, alg string, digest []byte) ([]byte, error)
+}
+
+// algorithm describes a JWS signing algorithm.
+type algorithm struct {
+	hash crypto.Hash
+	aws  string
+
+	// ecSize is the byte size of r and s for ECDSA algorithms, and zero
+	// otherwise.
+	ecSize int
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {hash: crypto.SHA256, aws: "RSASSA_PKCS1_V1_5_SHA_256"},
+	"RS384": {hash: crypto.SHA384, aws: "RSASSA_PKCS1_V1_5_SHA_384"},
+	"RS512": {hash: crypto.SHA512, aws: "RSASSA_PKCS1_V1_5_SHA_512"},
+	"PS256": {hash: crypto.SHA256, aws: "RSASSA_PSS_SHA_256"},
+	"PS384": {hash: crypto.SHA384, aws: "RSASSA_PSS_SHA_384"},
+	"PS512": {hash: crypto.SHA512, aws: "RSASSA_PSS_SHA_512"},
+	"ES256": {hash: crypto.SHA256, aws: "ECDSA_SHA_256", ecSize: 32},
+	"ES384": {hash: crypto.SHA384, aws: "ECDSA_SHA_384", ecSize: 48},
+	"ES512": {hash: crypto.SHA512, aws: "ECDSA_SHA_512", ecSize: 66},
+}
+
+func lookupAlgorithm(alg string) (algorithm, error) {
+	a, ok := algorithms[alg]
+	if !ok {
+		return algorithm{}, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	return a, nil
+}
+
+// AWSSigningAlgorithm returns the AWS KMS SigningAlgorithm for the JWS
+// algorithm given.
+func AWSSigningAlgorithm(alg string) (string, error) {
+	a, err := lookupAlgorithm(alg)
+	if err != nil {
+		return "", err
+	}
+	return a.aws, nil
+}
+
+// ParsePublicKey parses a public key returned by a KMS, either as a PEM block
+// or as DER encoded SubjectPublicKeyInfo.
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	return x509.ParsePKIXPublicKey(b)
+}
+
+// derToJOSE converts an ASN.1 DER ECDSA signature into the fixed size r||s
+// form JWS uses.
+func derToJOSE(der []byte, size int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil || len(rest) > 0 || sig.R == nil || sig.S == nil {
+		return nil, ErrMalformedSignature
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || len(sig.R.Bytes()) > size || len(sig.S.Bytes()) > size {
+		return nil, ErrMalformedSignature
+	}
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
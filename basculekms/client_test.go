@@ -0,0 +1,99 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSSigningAlgorithm(t *testing.T) {
+	assert := assert.New(t)
+	a, err := AWSSigningAlgorithm("ES256")
+	assert.NoError(err)
+	assert.Equal("ECDSA_SHA_256", a)
+	a, err = AWSSigningAlgorithm("PS512")
+	assert.NoError(err)
+	assert.Equal("RSASSA_PSS_SHA_512", a)
+	_, err = AWSSigningAlgorithm("HS256")
+	assert.ErrorIs(err, ErrUnsupportedAlgorithm)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(ek.Public())
+	require.NoError(err)
+
+	k, err := ParsePublicKey(der)
+	assert.NoError(err)
+	assert.Equal(ek.Public(), k)
+
+	k, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(err)
+	assert.Equal(ek.Public(), k)
+
+	_, err = ParsePublicKey([]byte("nope"))
+	assert.Error(err)
+}
+
+func TestDERToJOSE(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ek, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(err)
+	digest := make([]byte, 48)
+	der, err := ecdsa.SignASN1(rand.Reader, ek, digest)
+	require.NoError(err)
+
+	sig, err := derToJOSE(der, 48)
+	assert.NoError(err)
+	assert.Len(sig, 96)
+
+	_, err = derToJOSE(der, 16)
+	assert.ErrorIs(err, ErrMalformedSignature)
+	_, err = derToJOSE(append(der, 0), 48)
+	assert.ErrorIs(err, ErrMalformedSignature)
+	_, err = derToJOSE([]byte("nope"), 48)
+	assert.ErrorIs(err, ErrMalformedSignature)
+}
+
+func TestNewKey(t *testing.T) {
+	assert := assert.New(t)
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	k, err := newKey("kid", rk.Public())
+	assert.NoError(err)
+	assert.Equal("kid", k.KeyID())
+	assert.Equal("RSA", k.KeyType())
+	assert.Equal("sig", k.KeyUsage())
+	assert.Equal(rk.Public(), k.Raw())
+
+	_, err = newKey("kid", "not a key")
+	assert.ErrorIs(err, ErrUnsupportedKey)
+}
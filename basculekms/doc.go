@@ -0,0 +1,25 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+Package basculekms verifies and signs JWTs with keys held in a cloud key
+management service, such as AWS KMS or GCP Cloud KMS, so that private keys
+never leave the service.  Public keys are fetched once and cached, so
+verifying tokens doesn't call the KMS for every request.
+*/
+
+package basculekms
@@ -0,0 +1,80 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// key is a clortho.Key holding the public half of a KMS key.
+type key struct {
+	keyID  string
+	public crypto.PublicKey
+}
+
+func newKey(keyID string, public crypto.PublicKey) (*key, error) {
+	switch public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return &key{keyID: keyID, public: public}, nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+}
+
+func (k *key) KeyID() string            { return k.keyID }
+func (k *key) KeyUsage() string         { return "sig" }
+func (k *key) Raw() interface{}         { return k.public }
+func (k *key) Public() crypto.PublicKey { return k.public }
+
+func (k *key) KeyType() string {
+	if _, ok := k.public.(*rsa.PublicKey); ok {
+		return "RSA"
+	}
+	return "EC"
+}
+
+// Thumbprint produces the RFC 7638 thumbprint of the key.
+func (k *key) Thumbprint(h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("hash %v is unavailable", h)
+	}
+	var members string
+	switch pk := k.public.(type) {
+	case *rsa.PublicKey:
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			b64(big.NewInt(int64(pk.E)).Bytes()), b64(pk.N.Bytes()))
+	case *ecdsa.PublicKey:
+		size := (pk.Curve.Params().BitSize + 7) / 8
+		x, y := make([]byte, size), make([]byte, size)
+		pk.X.FillBytes(x)
+		pk.Y.FillBytes(y)
+		members = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+			pk.Curve.Params().Name, b64(x), b64(y))
+	}
+	hh := h.New()
+	hh.Write([]byte(members))
+	return hh.Sum(nil), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
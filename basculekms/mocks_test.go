@@ -0,0 +1,80 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"sync"
+)
+
+var errTest = errors.New("test")
+
+// fakeKMS is a Client holding local keys.
+type fakeKMS struct {
+	lock         sync.Mutex
+	keys         map[string]crypto.Signer
+	publicCalls  int
+	signCalls    int
+	failPublic   bool
+	corruptSigns bool
+}
+
+func newFakeKMS(keys map[string]crypto.Signer) *fakeKMS {
+	return &fakeKMS{keys: keys}
+}
+
+func (f *fakeKMS) PublicKey(_ context.Context, keyID string) (crypto.PublicKey, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.publicCalls++
+	k, ok := f.keys[keyID]
+	if !ok || f.failPublic {
+		return nil, errTest
+	}
+	return k.Public(), nil
+}
+
+func (f *fakeKMS) Sign(_ context.Context, keyID, alg string, digest []byte) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.signCalls++
+	k, ok := f.keys[keyID]
+	if !ok {
+		return nil, errTest
+	}
+	if f.corruptSigns {
+		return []byte("garbage"), nil
+	}
+	a, err := lookupAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+	var opts crypto.SignerOpts = a.hash
+	if alg[0] == 'P' {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: a.hash}
+	}
+	if ek, ok := k.(*ecdsa.PrivateKey); ok {
+		return ecdsa.SignASN1(rand.Reader, ek, digest)
+	}
+	return k.Sign(rand.Reader, digest, opts)
+}
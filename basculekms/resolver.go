@@ -0,0 +1,151 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/clortho"
+)
+
+const defaultKeyTTL = time.Hour
+
+var ErrUnknownKeyID = errors.New("unknown key id")
+
+// ResolverConfig configures a Resolver.
+type ResolverConfig struct {
+	// Keys maps the kids found in JWT headers to KMS key ids, such as an
+	// AWS key ARN or a GCP key version name.  If it's empty, kids are used
+	// as KMS key ids, which lets a token pick any key the client can read,
+	// so it should only be left empty when the client's access is limited
+	// to keys for verifying these tokens.
+	Keys map[string]string
+
+	// TTL is how long public keys are cached.  Defaults to one hour.
+	TTL time.Duration
+}
+
+type cachedKey struct {
+	key     *key
+	expires time.Time
+}
+
+// Resolver is a clortho.Resolver that gets public keys from a KMS, so that
+// tokens signed with KMS keys can be verified by the BearerTokenFactory.
+type Resolver struct {
+	client Client
+	config ResolverConfig
+	now    func() time.Time
+
+	lock      sync.Mutex
+	keys      map[string]cachedKey
+	listeners map[int]clortho.ResolveListener
+	nextID    int
+}
+
+// NewResolver creates a Resolver that gets public keys with the client given.
+func NewResolver(client Client, config ResolverConfig) (*Resolver, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultKeyTTL
+	}
+	return &Resolver{
+		client:    client,
+		config:    config,
+		now:       time.Now,
+		keys:      make(map[string]cachedKey),
+		listeners: make(map[int]clortho.ResolveListener),
+	}, nil
+}
+
+// Resolve returns the public key for the kid, from the cache if possible.
+func (r *Resolver) Resolve(ctx context.Context, keyID string) (clortho.Key, error) {
+	kmsKeyID := keyID
+	if len(r.config.Keys) > 0 {
+		var ok bool
+		if kmsKeyID, ok = r.config.Keys[keyID]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+		}
+	}
+
+	r.lock.Lock()
+	cached, ok := r.keys[keyID]
+	r.lock.Unlock()
+	if ok && r.now().Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	k, err := r.fetch(ctx, keyID, kmsKeyID)
+	r.dispatch(clortho.ResolveEvent{URI: kmsKeyID, KeyID: keyID, Key: keyOrNil(k), Err: err})
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	r.keys[keyID] = cachedKey{key: k, expires: r.now().Add(r.config.TTL)}
+	r.lock.Unlock()
+	return k, nil
+}
+
+func (r *Resolver) fetch(ctx context.Context, keyID, kmsKeyID string) (*key, error) {
+	public, err := r.client.PublicKey(ctx, kmsKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key %q from kms: %w", kmsKeyID, err)
+	}
+	return newKey(keyID, public)
+}
+
+// AddListener adds a listener that is told about each key fetched from the
+// KMS.
+func (r *Resolver) AddListener(l clortho.ResolveListener) clortho.CancelListenerFunc {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	id := r.nextID
+	r.nextID++
+	r.listeners[id] = l
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.listeners, id)
+	}
+}
+
+func (r *Resolver) dispatch(event clortho.ResolveEvent) {
+	r.lock.Lock()
+	listeners := make([]clortho.ResolveListener, 0, len(r.listeners))
+	for _, l := range r.listeners {
+		listeners = append(listeners, l)
+	}
+	r.lock.Unlock()
+	for _, l := range listeners {
+		l.OnResolveEvent(event)
+	}
+}
+
+// keyOrNil keeps a nil *key from becoming a non-nil clortho.Key.
+func keyOrNil(k *key) clortho.Key {
+	if k == nil {
+		return nil
+	}
+	return k
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/clortho"
+)
+
+type listenerFunc func(clortho.ResolveEvent)
+
+func (f listenerFunc) OnResolveEvent(e clortho.ResolveEvent) {
+	f(e)
+}
+
+func TestResolver(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	kms := newFakeKMS(map[string]crypto.Signer{"arn:key/1": ek})
+
+	_, err = NewResolver(nil, ResolverConfig{})
+	assert.ErrorIs(err, ErrNilClient)
+
+	r, err := NewResolver(kms, ResolverConfig{
+		Keys: map[string]string{"kid1": "arn:key/1", "kid2": "arn:key/2"},
+		TTL:  time.Minute,
+	})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	var events []clortho.ResolveEvent
+	cancel := r.AddListener(listenerFunc(func(e clortho.ResolveEvent) {
+		events = append(events, e)
+	}))
+
+	k, err := r.Resolve(context.Background(), "kid1")
+	require.NoError(err)
+	assert.Equal("kid1", k.KeyID())
+	assert.Equal(ek.Public(), k.Public())
+	_, err = r.Resolve(context.Background(), "kid1")
+	assert.NoError(err)
+	assert.Equal(1, kms.publicCalls)
+
+	// keys are fetched again once the ttl passes.
+	now = now.Add(time.Minute)
+	_, err = r.Resolve(context.Background(), "kid1")
+	assert.NoError(err)
+	assert.Equal(2, kms.publicCalls)
+
+	_, err = r.Resolve(context.Background(), "kid3")
+	assert.ErrorIs(err, ErrUnknownKeyID)
+	_, err = r.Resolve(context.Background(), "kid2")
+	assert.ErrorIs(err, errTest)
+
+	require.Len(events, 3)
+	assert.Equal("arn:key/1", events[0].URI)
+	assert.NotNil(events[0].Key)
+	assert.Nil(events[2].Key)
+	assert.ErrorIs(events[2].Err, errTest)
+
+	cancel()
+	_, err = r.Resolve(context.Background(), "kid2")
+	assert.Error(err)
+	assert.Len(events, 3)
+
+	// without a key map, kids are kms key ids.
+	r, err = NewResolver(kms, ResolverConfig{})
+	require.NoError(err)
+	k, err = r.Resolve(context.Background(), "arn:key/1")
+	assert.NoError(err)
+	assert.Equal("arn:key/1", k.KeyID())
+	assert.Equal(defaultKeyTTL, r.config.TTL)
+}
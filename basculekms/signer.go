@@ -0,0 +1,123 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrEmptyKeyID = errors.New("kms key id cannot be empty")
+
+// Signer signs JWTs with a KMS key.
+type Signer struct {
+	client   Client
+	kmsKeyID string
+	kid      string
+	alg      string
+	algo     algorithm
+
+	lock   sync.Mutex
+	public *key
+}
+
+// NewSigner creates a Signer that signs with the KMS key and JWS algorithm
+// given.  The kid is put in the header of signed tokens; if empty, the KMS
+// key id is used.
+func NewSigner(client Client, kmsKeyID, kid, alg string) (*Signer, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+	if kmsKeyID == "" {
+		return nil, ErrEmptyKeyID
+	}
+	a, err := lookupAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		kid = kmsKeyID
+	}
+	return &Signer{
+		client:   client,
+		kmsKeyID: kmsKeyID,
+		kid:      kid,
+		alg:      alg,
+		algo:     a,
+	}, nil
+}
+
+// Alg returns the JWS algorithm the Signer uses.
+func (s *Signer) Alg() string {
+	return s.alg
+}
+
+// KeyID returns the kid of the Signer's key.
+func (s *Signer) KeyID() string {
+	return s.kid
+}
+
+// Sign hashes the JWS signing input and has the KMS sign it, returning the
+// signature in the form JWS uses.
+func (s *Signer) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	h := s.algo.hash.New()
+	h.Write(signingInput)
+	sig, err := s.client.Sign(ctx, s.kmsKeyID, s.alg, h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with kms key %q: %w", s.kmsKeyID, err)
+	}
+	if s.algo.ecSize > 0 {
+		return derToJOSE(sig, s.algo.ecSize)
+	}
+	return sig, nil
+}
+
+// PublicKey returns the public half of the Signer's key, which is fetched
+// from the KMS the first time and cached after that.
+func (s *Signer) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.public != nil {
+		return s.public.Public(), nil
+	}
+	public, err := s.client.PublicKey(ctx, s.kmsKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key %q from kms: %w", s.kmsKeyID, err)
+	}
+	k, err := newKey(s.kid, public)
+	if err != nil {
+		return nil, err
+	}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if s.algo.ecSize > 0 {
+			return nil, fmt.Errorf("%w: %s with an rsa key", ErrUnsupportedAlgorithm, s.alg)
+		}
+	case *ecdsa.PublicKey:
+		if s.algo.ecSize == 0 {
+			return nil, fmt.Errorf("%w: %s with an ecdsa key", ErrUnsupportedAlgorithm, s.alg)
+		}
+	}
+	s.public = k
+	return public, nil
+}
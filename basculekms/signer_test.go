@@ -0,0 +1,115 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculekms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ek256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ek521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	kms := newFakeKMS(map[string]crypto.Signer{"rsa": rk, "ec256": ek256, "ec521": ek521})
+
+	tests := []struct {
+		keyID string
+		alg   string
+	}{
+		{keyID: "rsa", alg: "RS256"},
+		{keyID: "rsa", alg: "RS512"},
+		{keyID: "rsa", alg: "PS256"},
+		{keyID: "ec256", alg: "ES256"},
+		{keyID: "ec521", alg: "ES512"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.alg, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			s, err := NewSigner(kms, tc.keyID, "", tc.alg)
+			require.NoError(err)
+			assert.Equal(tc.alg, s.Alg())
+			assert.Equal(tc.keyID, s.KeyID())
+
+			input := "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ0ZXN0In0"
+			sig, err := s.Sign(context.Background(), []byte(input))
+			require.NoError(err)
+			public, err := s.PublicKey(context.Background())
+			require.NoError(err)
+			err = jwt.GetSigningMethod(tc.alg).Verify(input, base64.RawURLEncoding.EncodeToString(sig), public)
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestSignerErrors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	kms := newFakeKMS(map[string]crypto.Signer{"ec": ek})
+
+	_, err = NewSigner(nil, "ec", "", "ES256")
+	assert.ErrorIs(err, ErrNilClient)
+	_, err = NewSigner(kms, "", "", "ES256")
+	assert.ErrorIs(err, ErrEmptyKeyID)
+	_, err = NewSigner(kms, "ec", "", "HS256")
+	assert.ErrorIs(err, ErrUnsupportedAlgorithm)
+
+	// the algorithm has to match the key.
+	s, err := NewSigner(kms, "ec", "kid", "RS256")
+	require.NoError(err)
+	assert.Equal("kid", s.KeyID())
+	_, err = s.PublicKey(context.Background())
+	assert.ErrorIs(err, ErrUnsupportedAlgorithm)
+
+	s, err = NewSigner(kms, "missing", "", "ES256")
+	require.NoError(err)
+	_, err = s.Sign(context.Background(), []byte("input"))
+	assert.ErrorIs(err, errTest)
+	_, err = s.PublicKey(context.Background())
+	assert.ErrorIs(err, errTest)
+
+	// public keys are only fetched once.
+	s, err = NewSigner(kms, "ec", "", "ES256")
+	require.NoError(err)
+	kms.publicCalls = 0
+	for i := 0; i < 3; i++ {
+		_, err = s.PublicKey(context.Background())
+		assert.NoError(err)
+	}
+	assert.Equal(1, kms.publicCalls)
+
+	kms.corruptSigns = true
+	_, err = s.Sign(context.Background(), []byte("input"))
+	assert.ErrorIs(err, ErrMalformedSignature)
+}
@@ -0,0 +1,219 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculemetrics
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/fx"
+)
+
+const (
+	defaultShards        = 16
+	defaultFlushInterval = time.Second
+)
+
+var ErrNilCounterVec = errors.New("counter vec cannot be nil")
+
+// BufferOption is how a BufferedCounterVec is configured.
+type BufferOption func(*BufferedCounterVec)
+
+// WithShards sets the number of shards increments are spread across.  More
+// shards mean less lock contention between concurrent requests.
+func WithShards(n int) BufferOption {
+	return func(b *BufferedCounterVec) {
+		if n > 0 {
+			b.shards = make([]shard, n)
+		}
+	}
+}
+
+// WithFlushInterval sets how often the buffered increments are written to the
+// underlying counter vec.
+func WithFlushInterval(d time.Duration) BufferOption {
+	return func(b *BufferedCounterVec) {
+		if d > 0 {
+			b.interval = d
+		}
+	}
+}
+
+type entry struct {
+	labels prometheus.Labels
+	value  float64
+}
+
+type shard struct {
+	lock    sync.Mutex
+	entries map[string]*entry
+}
+
+// BufferedCounterVec aggregates counter increments in memory and periodically
+// adds them to a prometheus CounterVec, avoiding the CounterVec's label lookup
+// on every request.  Counts are only visible to prometheus after a flush.
+type BufferedCounterVec struct {
+	vec      *prometheus.CounterVec
+	shards   []shard
+	interval time.Duration
+
+	lock sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBufferedCounterVec creates a BufferedCounterVec that flushes into the
+// CounterVec given.  Start must be called for periodic flushing to happen.
+func NewBufferedCounterVec(vec *prometheus.CounterVec, options ...BufferOption) (*BufferedCounterVec, error) {
+	if vec == nil {
+		return nil, ErrNilCounterVec
+	}
+	b := BufferedCounterVec{
+		vec:      vec,
+		shards:   make([]shard, defaultShards),
+		interval: defaultFlushInterval,
+	}
+	for _, o := range options {
+		if o != nil {
+			o(&b)
+		}
+	}
+	return &b, nil
+}
+
+// Add buffers an increment of v for the labels given.
+func (b *BufferedCounterVec) Add(labels prometheus.Labels, v float64) {
+	key := labelsKey(labels)
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	s := &b.shards[h.Sum32()%uint32(len(b.shards))]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]*entry)
+	}
+	e, ok := s.entries[key]
+	if !ok {
+		l := make(prometheus.Labels, len(labels))
+		for k, v := range labels {
+			l[k] = v
+		}
+		e = &entry{labels: l}
+		s.entries[key] = e
+	}
+	e.value += v
+}
+
+// Inc buffers an increment of one for the labels given.
+func (b *BufferedCounterVec) Inc(labels prometheus.Labels) {
+	b.Add(labels, 1)
+}
+
+// Flush writes all buffered increments to the underlying CounterVec.
+func (b *BufferedCounterVec) Flush() {
+	for i := range b.shards {
+		s := &b.shards[i]
+		s.lock.Lock()
+		entries := s.entries
+		s.entries = nil
+		s.lock.Unlock()
+
+		for _, e := range entries {
+			b.vec.With(e.labels).Add(e.value)
+		}
+	}
+}
+
+// Start begins flushing periodically in the background.  Calling Start more
+// than once has no effect.
+func (b *BufferedCounterVec) Start() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.stop != nil {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.run(b.stop, b.done)
+}
+
+func (b *BufferedCounterVec) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Stop ends background flushing and flushes anything still buffered, so no
+// counts are lost on shutdown.
+func (b *BufferedCounterVec) Stop() {
+	b.lock.Lock()
+	stop, done := b.stop, b.done
+	b.stop, b.done = nil, nil
+	b.lock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	b.Flush()
+}
+
+// Hook returns an uber fx lifecycle hook that starts the BufferedCounterVec
+// with the application and flushes it on shutdown.
+func (b *BufferedCounterVec) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			b.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			b.Stop()
+			return nil
+		},
+	}
+}
+
+func labelsKey(labels prometheus.Labels) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(n)
+		b.WriteByte(0)
+		b.WriteString(labels[n])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
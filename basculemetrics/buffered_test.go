@@ -0,0 +1,106 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculemetrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVec() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "testCounter",
+		Help: "testCounter",
+	}, []string{"server", "outcome"})
+}
+
+func TestNewBufferedCounterVec(t *testing.T) {
+	assert := assert.New(t)
+	b, err := NewBufferedCounterVec(nil)
+	assert.Nil(b)
+	assert.ErrorIs(err, ErrNilCounterVec)
+
+	b, err = NewBufferedCounterVec(newTestVec(), WithShards(3), WithShards(0),
+		WithFlushInterval(time.Minute), WithFlushInterval(0), nil)
+	assert.NoError(err)
+	assert.Len(b.shards, 3)
+	assert.Equal(time.Minute, b.interval)
+}
+
+func TestBufferedCounterVecFlush(t *testing.T) {
+	assert := assert.New(t)
+	vec := newTestVec()
+	b, err := NewBufferedCounterVec(vec, WithShards(4))
+	require.NoError(t, err)
+
+	accepted := prometheus.Labels{"server": "primary", "outcome": "accepted"}
+	rejected := prometheus.Labels{"outcome": "rejected", "server": "primary"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Inc(accepted)
+			}
+			b.Add(rejected, 2)
+		}()
+	}
+	wg.Wait()
+
+	// nothing is visible until a flush.
+	assert.Equal(0, testutil.CollectAndCount(vec))
+	b.Flush()
+	assert.Equal(1000.0, testutil.ToFloat64(vec.With(accepted)))
+	assert.Equal(20.0, testutil.ToFloat64(vec.With(rejected)))
+
+	// flushing again doesn't double count.
+	b.Flush()
+	assert.Equal(1000.0, testutil.ToFloat64(vec.With(accepted)))
+}
+
+func TestBufferedCounterVecLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	vec := newTestVec()
+	b, err := NewBufferedCounterVec(vec, WithFlushInterval(time.Millisecond))
+	require.NoError(t, err)
+	labels := prometheus.Labels{"server": "primary", "outcome": "accepted"}
+
+	hook := b.Hook()
+	assert.NoError(hook.OnStart(context.Background()))
+	b.Start()
+	b.Inc(labels)
+	assert.Eventually(func() bool {
+		return testutil.ToFloat64(vec.With(labels)) == 1.0
+	}, time.Second, time.Millisecond)
+
+	b.Inc(labels)
+	assert.NoError(hook.OnStop(context.Background()))
+	assert.Equal(2.0, testutil.ToFloat64(vec.With(labels)))
+
+	// stopping twice is safe.
+	b.Stop()
+}
@@ -0,0 +1,28 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+Package basculessf emits Shared Signals Framework (SSF) security events, such
+as the CAEP session revoked and token claims change events and the RISC
+credential compromise event, so that bascule can feed enterprise security
+event pipelines.  Events are encoded as signed Security Event Tokens (RFC
+8417) and sent by a Transmitter, such as the PushTransmitter, which uses push
+delivery (RFC 8935).  An Emitter sends events in the background, and an
+EventValidator emits events when the validators it wraps detect anomalies.
+*/
+
+package basculessf
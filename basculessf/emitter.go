@@ -0,0 +1,238 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/touchstone"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Names for our metrics
+const (
+	AuthSecurityEvents = "auth_security_events"
+)
+
+// labels
+const (
+	EventTypeLabel = "type"
+	OutcomeLabel   = "outcome"
+)
+
+// label values
+const (
+	SentOutcome    = "sent"
+	FailedOutcome  = "failed"
+	DroppedOutcome = "dropped"
+)
+
+const (
+	securityEventsHelpMsg = "Counter for security events emitted, by event type and outcome"
+
+	defaultQueueSize     = 100
+	defaultWorkers       = 1
+	defaultEmitTimeout   = 30 * time.Second
+	eventTypeLogKey      = "eventType"
+	eventTransmitFailMsg = "failed to transmit security event"
+)
+
+var (
+	ErrNilTransmitter = errors.New("transmitter cannot be nil")
+	ErrNilValidator   = errors.New("validator cannot be nil")
+	ErrNilEmitter     = errors.New("emitter cannot be nil")
+)
+
+// ProvideMetrics provides the metrics used by the Emitter as uber/fx options.
+func ProvideMetrics() fx.Option {
+	return fx.Options(
+		touchstone.CounterVec(prometheus.CounterOpts{
+			Name:        AuthSecurityEvents,
+			Help:        securityEventsHelpMsg,
+			ConstLabels: nil,
+		}, EventTypeLabel, OutcomeLabel),
+	)
+}
+
+// EventMeasures describes the metrics used by the Emitter.
+type EventMeasures struct {
+	fx.In
+
+	Events *prometheus.CounterVec `name:"auth_security_events"`
+}
+
+// EmitterConfig configures an Emitter.
+type EmitterConfig struct {
+	// QueueSize is the number of events that can wait to be transmitted.
+	// Events emitted when the queue is full are dropped.  Defaults to 100.
+	QueueSize int
+
+	// Workers is the number of events transmitted at once.  Defaults to 1.
+	Workers int
+
+	// Timeout limits how long transmitting a single event can take.
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Emitter transmits security events in the background, so that emitting an
+// event never blocks the request that caused it.
+type Emitter struct {
+	transmitter Transmitter
+	measures    *EventMeasures
+	getLogger   func(context.Context) *zap.Logger
+	workers     int
+	timeout     time.Duration
+	queue       chan Event
+
+	runLock sync.Mutex
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// NewEmitter creates an Emitter.  The measures and logger are optional.
+// Events aren't transmitted until the Emitter is started.
+func NewEmitter(t Transmitter, config EmitterConfig, measures *EventMeasures, getLogger func(context.Context) *zap.Logger) (*Emitter, error) {
+	if t == nil {
+		return nil, ErrNilTransmitter
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultEmitTimeout
+	}
+	if getLogger == nil {
+		getLogger = sallust.Get
+	}
+	return &Emitter{
+		transmitter: t,
+		measures:    measures,
+		getLogger:   getLogger,
+		workers:     config.Workers,
+		timeout:     config.Timeout,
+		queue:       make(chan Event, config.QueueSize),
+	}, nil
+}
+
+// Emit queues the event to be transmitted, returning false if the queue is
+// full and the event was dropped.
+func (e *Emitter) Emit(event Event) bool {
+	select {
+	case e.queue <- event:
+		return true
+	default:
+		e.observe(event.Type, DroppedOutcome)
+		e.getLogger(context.Background()).Warn("security event queue full, dropping event",
+			zap.String(eventTypeLogKey, event.Type))
+		return false
+	}
+}
+
+// Start starts transmitting events.
+func (e *Emitter) Start() {
+	e.runLock.Lock()
+	defer e.runLock.Unlock()
+	if e.stop != nil {
+		return
+	}
+	e.stop = make(chan struct{})
+	for i := 0; i < e.workers; i++ {
+		e.done.Add(1)
+		go e.run(e.stop)
+	}
+}
+
+func (e *Emitter) run(stop <-chan struct{}) {
+	defer e.done.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-e.queue:
+			e.transmit(event)
+		}
+	}
+}
+
+func (e *Emitter) transmit(event Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	if err := e.transmitter.Transmit(ctx, event); err != nil {
+		e.observe(event.Type, FailedOutcome)
+		e.getLogger(ctx).Error(eventTransmitFailMsg,
+			zap.String(eventTypeLogKey, event.Type), zap.Error(err))
+		return
+	}
+	e.observe(event.Type, SentOutcome)
+}
+
+// Stop stops transmitting events, first transmitting the events already
+// queued.
+func (e *Emitter) Stop() {
+	e.runLock.Lock()
+	stop := e.stop
+	e.stop = nil
+	e.runLock.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	e.done.Wait()
+	for {
+		select {
+		case event := <-e.queue:
+			e.transmit(event)
+		default:
+			return
+		}
+	}
+}
+
+// Hook returns an uber fx lifecycle hook that starts and stops the Emitter.
+func (e *Emitter) Hook() fx.Hook {
+	return fx.Hook{
+		OnStart: func(context.Context) error {
+			e.Start()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			e.Stop()
+			return nil
+		},
+	}
+}
+
+func (e *Emitter) observe(eventType, outcome string) {
+	if e.measures == nil || e.measures.Events == nil {
+		return
+	}
+	e.measures.Events.With(prometheus.Labels{
+		EventTypeLabel: eventType,
+		OutcomeLabel:   outcome,
+	}).Inc()
+}
@@ -0,0 +1,146 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTransmitter struct {
+	lock   sync.Mutex
+	events []Event
+	err    error
+}
+
+func (r *recordingTransmitter) Transmit(_ context.Context, e Event) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func (r *recordingTransmitter) Events() []Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func newTestMeasures() *EventMeasures {
+	return &EventMeasures{
+		Events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "testEvents",
+			Help: "testEvents",
+		}, []string{EventTypeLabel, OutcomeLabel}),
+	}
+}
+
+func TestNewEmitter(t *testing.T) {
+	assert := assert.New(t)
+	e, err := NewEmitter(nil, EmitterConfig{}, nil, nil)
+	assert.ErrorIs(err, ErrNilTransmitter)
+	assert.Nil(e)
+
+	e, err = NewEmitter(&recordingTransmitter{}, EmitterConfig{}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(defaultQueueSize, cap(e.queue))
+	assert.Equal(defaultWorkers, e.workers)
+	assert.Equal(defaultEmitTimeout, e.timeout)
+	assert.NotNil(e.getLogger)
+
+	e, err = NewEmitter(&recordingTransmitter{}, EmitterConfig{QueueSize: 5, Workers: 3, Timeout: time.Second}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(5, cap(e.queue))
+	assert.Equal(3, e.workers)
+	assert.Equal(time.Second, e.timeout)
+}
+
+func TestEmitter(t *testing.T) {
+	tests := []struct {
+		description     string
+		err             error
+		expectedOutcome string
+	}{
+		{
+			description:     "Sent",
+			expectedOutcome: SentOutcome,
+		},
+		{
+			description:     "Failed",
+			err:             errors.New("receiver down"),
+			expectedOutcome: FailedOutcome,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			transmitter := &recordingTransmitter{err: tc.err}
+			measures := newTestMeasures()
+			e, err := NewEmitter(transmitter, EmitterConfig{Workers: 2}, measures, nil)
+			require.NoError(t, err)
+
+			hook := e.Hook()
+			require.NoError(t, hook.OnStart(context.Background()))
+			// starting twice is harmless.
+			e.Start()
+			event := Event{Type: SessionRevoked, Subject: OpaqueSubject("alice")}
+			assert.True(e.Emit(event))
+			assert.Eventually(func() bool {
+				return len(transmitter.Events()) == 1
+			}, time.Second, time.Millisecond)
+			require.NoError(t, hook.OnStop(context.Background()))
+			// stopping twice is harmless.
+			e.Stop()
+
+			assert.Equal([]Event{event}, transmitter.Events())
+			assert.Equal(1.0, testutil.ToFloat64(measures.Events.With(prometheus.Labels{
+				EventTypeLabel: SessionRevoked,
+				OutcomeLabel:   tc.expectedOutcome,
+			})))
+		})
+	}
+}
+
+func TestEmitterDropsWhenFull(t *testing.T) {
+	assert := assert.New(t)
+	transmitter := &recordingTransmitter{}
+	measures := newTestMeasures()
+	e, err := NewEmitter(transmitter, EmitterConfig{QueueSize: 1}, measures, nil)
+	require.NoError(t, err)
+
+	first := Event{Type: SessionRevoked, Subject: OpaqueSubject("alice")}
+	assert.True(e.Emit(first))
+	assert.False(e.Emit(Event{Type: CredentialCompromise, Subject: OpaqueSubject("bob")}))
+	assert.Equal(1.0, testutil.ToFloat64(measures.Events.With(prometheus.Labels{
+		EventTypeLabel: CredentialCompromise,
+		OutcomeLabel:   DroppedOutcome,
+	})))
+
+	// queued events are transmitted when stopping.
+	e.Start()
+	e.Stop()
+	assert.Equal([]Event{first}, transmitter.Events())
+}
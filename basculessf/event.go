@@ -0,0 +1,182 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/s-srakshe/bascule/tokenmint"
+)
+
+// Event types.
+const (
+	SessionRevoked        = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+	TokenClaimsChange     = "https://schemas.openid.net/secevent/caep/event-type/token-claims-change"
+	CredentialChange      = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+	CredentialCompromise  = "https://schemas.openid.net/secevent/risc/event-type/credential-compromise"
+	secEventJWTType       = "secevent+jwt"
+	defaultInitiator      = "system"
+	defaultReasonLanguage = "en"
+)
+
+var (
+	ErrMissingEventType = errors.New("event type is required")
+	ErrMissingSubject   = errors.New("event subject is required")
+)
+
+// Subject identifies who or what an event is about, as an SSF subject
+// identifier.
+type Subject map[string]interface{}
+
+// IssSubSubject identifies a subject by the issuer and subject of its
+// tokens.
+func IssSubSubject(iss, sub string) Subject {
+	return Subject{"format": "iss_sub", "iss": iss, "sub": sub}
+}
+
+// OpaqueSubject identifies a subject by an identifier only the transmitter
+// and receiver understand.
+func OpaqueSubject(id string) Subject {
+	return Subject{"format": "opaque", "id": id}
+}
+
+// EmailSubject identifies a subject by email address.
+func EmailSubject(email string) Subject {
+	return Subject{"format": "email", "email": email}
+}
+
+// Event is a security event.
+type Event struct {
+	// Type is the event type URI, such as SessionRevoked.
+	Type string
+
+	// Subject is who or what the event is about.
+	Subject Subject
+
+	// Time is when the event happened.  Defaults to when it's encoded.
+	Time time.Time
+
+	// InitiatingEntity is what caused the event: "admin", "user", "policy",
+	// or "system".  Defaults to "system".
+	InitiatingEntity string
+
+	// Reason is a description of the event for administrators.
+	Reason string
+
+	// Fields are the event type specific fields, such as claims for a
+	// TokenClaimsChange event or credential_type for a CredentialCompromise
+	// event.
+	Fields map[string]interface{}
+}
+
+// payload returns the event's entry in the events claim.
+func (e Event) payload(now time.Time) map[string]interface{} {
+	p := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		p[k] = v
+	}
+	t := e.Time
+	if t.IsZero() {
+		t = now
+	}
+	p["event_timestamp"] = t.Unix()
+	p["initiating_entity"] = e.InitiatingEntity
+	if e.InitiatingEntity == "" {
+		p["initiating_entity"] = defaultInitiator
+	}
+	if e.Reason != "" {
+		p["reason_admin"] = map[string]string{defaultReasonLanguage: e.Reason}
+	}
+	return p
+}
+
+// Encoder encodes events as signed Security Event Tokens.
+type Encoder struct {
+	// Signer signs the tokens.  It's required.
+	Signer tokenmint.Signer
+
+	// Issuer and Audience are the iss and aud claims.
+	Issuer   string
+	Audience []string
+
+	now func() time.Time
+}
+
+// Encode returns the event as a signed Security Event Token.
+func (enc Encoder) Encode(ctx context.Context, e Event) (string, error) {
+	if enc.Signer == nil {
+		return "", tokenmint.ErrNilSigner
+	}
+	if e.Type == "" {
+		return "", ErrMissingEventType
+	}
+	if len(e.Subject) == 0 {
+		return "", ErrMissingSubject
+	}
+	now := time.Now()
+	if enc.now != nil {
+		now = enc.now()
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate jti: %w", err)
+	}
+	claims := map[string]interface{}{
+		"iat":    now.Unix(),
+		"jti":    hex.EncodeToString(jti),
+		"sub_id": e.Subject,
+		"events": map[string]interface{}{e.Type: e.payload(now)},
+	}
+	if enc.Issuer != "" {
+		claims["iss"] = enc.Issuer
+	}
+	if len(enc.Audience) == 1 {
+		claims["aud"] = enc.Audience[0]
+	} else if len(enc.Audience) > 1 {
+		claims["aud"] = enc.Audience
+	}
+
+	header := map[string]interface{}{
+		"alg": enc.Signer.Alg(),
+		"typ": secEventJWTType,
+	}
+	if kid := enc.Signer.KeyID(); kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := enc.Signer.Sign(ctx, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign event: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
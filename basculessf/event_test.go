@@ -0,0 +1,178 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule/tokenmint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errSigner struct {
+	tokenmint.Signer
+	err error
+}
+
+func (e errSigner) Sign(context.Context, []byte) ([]byte, error) {
+	return nil, e.err
+}
+
+func newTestEncoder(t *testing.T) (Encoder, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	s, err := tokenmint.NewLocalSigner(key, "kid-1", "ES256")
+	require.NoError(t, err)
+	return Encoder{
+		Signer:   s,
+		Issuer:   "https://bascule.example.com",
+		Audience: []string{"https://receiver.example.com"},
+		now:      func() time.Time { return time.Unix(1700000000, 0) },
+	}, key
+}
+
+func decodeSET(t *testing.T, set string, key *ecdsa.PrivateKey) (map[string]interface{}, jwt.MapClaims) {
+	token, err := jwt.Parse(set, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+	return token.Header, token.Claims.(jwt.MapClaims)
+}
+
+func TestSubjects(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(Subject{"format": "iss_sub", "iss": "a", "sub": "b"}, IssSubSubject("a", "b"))
+	assert.Equal(Subject{"format": "opaque", "id": "c"}, OpaqueSubject("c"))
+	assert.Equal(Subject{"format": "email", "email": "d@example.com"}, EmailSubject("d@example.com"))
+}
+
+func TestEncoderEncode(t *testing.T) {
+	assert := assert.New(t)
+	enc, key := newTestEncoder(t)
+	set, err := enc.Encode(context.Background(), Event{
+		Type:    SessionRevoked,
+		Subject: IssSubSubject("https://idp.example.com", "alice"),
+		Time:    time.Unix(1690000000, 0),
+		Reason:  "token replayed",
+		Fields:  map[string]interface{}{"custom": "value"},
+	})
+	require.NoError(t, err)
+
+	header, claims := decodeSET(t, set, key)
+	assert.Equal(secEventJWTType, header["typ"])
+	assert.Equal("kid-1", header["kid"])
+	assert.Equal("https://bascule.example.com", claims["iss"])
+	assert.Equal("https://receiver.example.com", claims["aud"])
+	assert.Equal(float64(1700000000), claims["iat"])
+	assert.Len(claims["jti"], 32)
+	assert.Equal(map[string]interface{}{
+		"format": "iss_sub",
+		"iss":    "https://idp.example.com",
+		"sub":    "alice",
+	}, claims["sub_id"])
+	assert.Equal(map[string]interface{}{
+		SessionRevoked: map[string]interface{}{
+			"event_timestamp":   float64(1690000000),
+			"initiating_entity": "system",
+			"reason_admin":      map[string]interface{}{"en": "token replayed"},
+			"custom":            "value",
+		},
+	}, claims["events"])
+}
+
+func TestEncoderEncodeDefaults(t *testing.T) {
+	assert := assert.New(t)
+	enc, _ := newTestEncoder(t)
+	enc.Issuer = ""
+	enc.Audience = []string{"a", "b"}
+	set, err := enc.Encode(context.Background(), Event{
+		Type:             TokenClaimsChange,
+		Subject:          OpaqueSubject("bob"),
+		InitiatingEntity: "admin",
+	})
+	require.NoError(t, err)
+
+	parts := strings.Split(set, ".")
+	require.Len(t, parts, 3)
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &claims))
+	assert.NotContains(claims, "iss")
+	assert.Equal([]interface{}{"a", "b"}, claims["aud"])
+	assert.Equal(map[string]interface{}{
+		TokenClaimsChange: map[string]interface{}{
+			"event_timestamp":   float64(1700000000),
+			"initiating_entity": "admin",
+		},
+	}, claims["events"])
+}
+
+func TestEncoderEncodeErrors(t *testing.T) {
+	enc, _ := newTestEncoder(t)
+	signErr := errors.New("sign failed")
+	tests := []struct {
+		description string
+		encoder     Encoder
+		event       Event
+		expectedErr error
+	}{
+		{
+			description: "Nil signer",
+			event:       Event{Type: SessionRevoked, Subject: OpaqueSubject("a")},
+			expectedErr: tokenmint.ErrNilSigner,
+		},
+		{
+			description: "Missing type",
+			encoder:     enc,
+			event:       Event{Subject: OpaqueSubject("a")},
+			expectedErr: ErrMissingEventType,
+		},
+		{
+			description: "Missing subject",
+			encoder:     enc,
+			event:       Event{Type: SessionRevoked},
+			expectedErr: ErrMissingSubject,
+		},
+		{
+			description: "Sign error",
+			encoder:     Encoder{Signer: errSigner{Signer: enc.Signer, err: signErr}},
+			event:       Event{Type: SessionRevoked, Subject: OpaqueSubject("a")},
+			expectedErr: signErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			set, err := tc.encoder.Encode(context.Background(), tc.event)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Empty(t, set)
+		})
+	}
+}
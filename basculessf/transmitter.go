@@ -0,0 +1,161 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/s-srakshe/bascule/acquire"
+	"github.com/s-srakshe/bascule/tokenmint"
+)
+
+const (
+	secEventContentType   = "application/secevent+jwt"
+	defaultPushTimeout    = 10 * time.Second
+	maxErrorResponseBytes = 4096
+)
+
+var (
+	ErrEmptyPushURL = errors.New("push url cannot be empty")
+
+	// ErrEventRejected is wrapped by the error a PushTransmitter returns
+	// when the receiver rejects an event as invalid.
+	ErrEventRejected = errors.New("security event rejected by receiver")
+)
+
+// Transmitter sends security events to a receiver.
+type Transmitter interface {
+	Transmit(context.Context, Event) error
+}
+
+// TransmitterFunc is a function that is a Transmitter.
+type TransmitterFunc func(context.Context, Event) error
+
+// Transmit runs the TransmitterFunc.
+func (tf TransmitterFunc) Transmit(ctx context.Context, e Event) error {
+	return tf(ctx, e)
+}
+
+// PushConfig configures a PushTransmitter.
+type PushConfig struct {
+	// URL is the receiver's push endpoint.  It's required.
+	URL string
+
+	// Encoder encodes and signs the events.  Its Signer is required.
+	Encoder Encoder
+
+	// Authorization, if set, provides the Authorization header sent with
+	// each event.
+	Authorization acquire.Acquirer
+
+	// Client sends the events.  Defaults to an http.Client with the
+	// Timeout.
+	Client *http.Client
+
+	// Timeout is the timeout of the default Client.  Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// PushTransmitter sends security events to a receiver using push delivery,
+// as described in RFC 8935.
+type PushTransmitter struct {
+	url           string
+	encoder       Encoder
+	authorization acquire.Acquirer
+	client        *http.Client
+}
+
+// NewPushTransmitter creates a PushTransmitter.
+func NewPushTransmitter(config PushConfig) (*PushTransmitter, error) {
+	if config.URL == "" {
+		return nil, ErrEmptyPushURL
+	}
+	if config.Encoder.Signer == nil {
+		return nil, fmt.Errorf("invalid encoder: %w", tokenmint.ErrNilSigner)
+	}
+	client := config.Client
+	if client == nil {
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = defaultPushTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	return &PushTransmitter{
+		url:           config.URL,
+		encoder:       config.Encoder,
+		authorization: config.Authorization,
+		client:        client,
+	}, nil
+}
+
+// pushError is the body a receiver returns when it rejects an event.
+type pushError struct {
+	Err         string `json:"err"`
+	Description string `json:"description"`
+}
+
+// Transmit encodes the event and sends it to the receiver.
+func (p *PushTransmitter) Transmit(ctx context.Context, e Event) error {
+	set, err := p.encoder.Encode(ctx, e)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, strings.NewReader(set))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", secEventContentType)
+	request.Header.Set("Accept", "application/json")
+	if p.authorization != nil {
+		if err := acquire.AddAuth(request, p.authorization); err != nil {
+			return err
+		}
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorResponseBytes))
+
+	switch {
+	case response.StatusCode == http.StatusAccepted || response.StatusCode == http.StatusOK:
+		return nil
+	case response.StatusCode == http.StatusBadRequest:
+		var pe pushError
+		if json.Unmarshal(bytes.TrimSpace(body), &pe) == nil && pe.Err != "" {
+			if pe.Description != "" {
+				return fmt.Errorf("%w: %s: %s", ErrEventRejected, pe.Err, pe.Description)
+			}
+			return fmt.Errorf("%w: %s", ErrEventRejected, pe.Err)
+		}
+		return ErrEventRejected
+	default:
+		return fmt.Errorf("unexpected response status %d from receiver", response.StatusCode)
+	}
+}
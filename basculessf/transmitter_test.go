@@ -0,0 +1,202 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule/acquire"
+	"github.com/s-srakshe/bascule/tokenmint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPushTransmitter(t *testing.T) {
+	enc, _ := newTestEncoder(t)
+	client := &http.Client{}
+	tests := []struct {
+		description     string
+		config          PushConfig
+		expectedTimeout bool
+		expectedErr     error
+	}{
+		{
+			description:     "Defaults",
+			config:          PushConfig{URL: "http://localhost", Encoder: enc},
+			expectedTimeout: true,
+		},
+		{
+			description: "Custom client",
+			config:      PushConfig{URL: "http://localhost", Encoder: enc, Client: client},
+		},
+		{
+			description: "Empty URL",
+			config:      PushConfig{Encoder: enc},
+			expectedErr: ErrEmptyPushURL,
+		},
+		{
+			description: "Nil signer",
+			config:      PushConfig{URL: "http://localhost"},
+			expectedErr: tokenmint.ErrNilSigner,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p, err := NewPushTransmitter(tc.config)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(p)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectedTimeout {
+				assert.Equal(defaultPushTimeout, p.client.Timeout)
+			} else {
+				assert.Same(client, p.client)
+			}
+		})
+	}
+}
+
+func TestPushTransmitterTransmit(t *testing.T) {
+	event := Event{Type: SessionRevoked, Subject: OpaqueSubject("alice")}
+	acquirer, err := acquire.NewFixedAuthAcquirer("Bearer abc")
+	require.NoError(t, err)
+	tests := []struct {
+		description   string
+		status        int
+		body          string
+		authorization acquire.Acquirer
+		event         Event
+		expectedAuth  string
+		expectedErr   error
+		expectedMsg   string
+		skipRequest   bool
+	}{
+		{
+			description: "Accepted",
+			status:      http.StatusAccepted,
+		},
+		{
+			description:   "With authorization",
+			status:        http.StatusAccepted,
+			authorization: acquirer,
+			expectedAuth:  "Bearer abc",
+		},
+		{
+			description: "Rejected with description",
+			status:      http.StatusBadRequest,
+			body:        `{"err":"invalid_audience","description":"wrong aud"}`,
+			expectedErr: ErrEventRejected,
+			expectedMsg: "invalid_audience: wrong aud",
+		},
+		{
+			description: "Rejected without description",
+			status:      http.StatusBadRequest,
+			body:        `{"err":"jwtParse"}`,
+			expectedErr: ErrEventRejected,
+			expectedMsg: "jwtParse",
+		},
+		{
+			description: "Rejected without body",
+			status:      http.StatusBadRequest,
+			expectedErr: ErrEventRejected,
+		},
+		{
+			description: "Unexpected status",
+			status:      http.StatusInternalServerError,
+			expectedMsg: "unexpected response status 500",
+		},
+		{
+			description: "Invalid event",
+			event:       Event{Type: SessionRevoked},
+			expectedErr: ErrMissingSubject,
+			skipRequest: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			enc, key := newTestEncoder(t)
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				assert.Equal(http.MethodPost, r.Method)
+				assert.Equal(secEventContentType, r.Header.Get("Content-Type"))
+				assert.Equal(tc.expectedAuth, r.Header.Get("Authorization"))
+				b, err := io.ReadAll(r.Body)
+				assert.NoError(err)
+				_, claims := decodeSET(t, string(b), key)
+				assert.Contains(claims["events"], SessionRevoked)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			p, err := NewPushTransmitter(PushConfig{
+				URL:           server.URL,
+				Encoder:       enc,
+				Authorization: tc.authorization,
+			})
+			require.NoError(t, err)
+			e := tc.event
+			if e.Type == "" {
+				e = event
+			}
+			err = p.Transmit(context.Background(), e)
+			if tc.skipRequest {
+				assert.Zero(requests)
+			} else {
+				assert.Equal(1, requests)
+			}
+			if tc.expectedErr == nil && tc.expectedMsg == "" {
+				assert.NoError(err)
+				return
+			}
+			require.Error(t, err)
+			if tc.expectedErr != nil {
+				assert.True(errors.Is(err, tc.expectedErr))
+			}
+			assert.Contains(err.Error(), tc.expectedMsg)
+		})
+	}
+}
+
+func TestPushTransmitterTransmitSendError(t *testing.T) {
+	enc, _ := newTestEncoder(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	p, err := NewPushTransmitter(PushConfig{URL: server.URL, Encoder: enc})
+	require.NoError(t, err)
+	err = p.Transmit(context.Background(), Event{Type: SessionRevoked, Subject: OpaqueSubject("a")})
+	assert.ErrorContains(t, err, "failed to send event")
+}
+
+func TestTransmitterFunc(t *testing.T) {
+	expectedErr := errors.New("test error")
+	var tf Transmitter = TransmitterFunc(func(context.Context, Event) error {
+		return expectedErr
+	})
+	assert.Equal(t, expectedErr, tf.Transmit(context.Background(), Event{}))
+}
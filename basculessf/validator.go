@@ -0,0 +1,125 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"errors"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+)
+
+const issuerClaim = "iss"
+
+// EventRule describes the security event to emit when a validator fails
+// with a certain error.
+type EventRule struct {
+	// Err is the error, checked with errors.Is, that triggers the event.
+	Err error
+
+	// Type is the event type to emit.
+	Type string
+
+	// Reason is the event's reason.  Defaults to the validator's error.
+	Reason string
+
+	// Fields are the event type specific fields of the event.
+	Fields map[string]interface{}
+}
+
+// DefaultEventRules returns the rules for the anomalies bascule's validators
+// detect: a token used from a different client than it was issued to is
+// reported as a compromised credential.
+func DefaultEventRules() []EventRule {
+	return []EventRule{
+		{
+			Err:    basculechecks.ErrBindingMismatch,
+			Type:   CredentialCompromise,
+			Fields: map[string]interface{}{"credential_type": "bearer_token"},
+		},
+	}
+}
+
+// EventValidator wraps a Validator, emitting a security event whenever it
+// fails with an error matching one of the rules.  The error is returned
+// unchanged.
+type EventValidator struct {
+	validator bascule.Validator
+	emitter   *Emitter
+	rules     []EventRule
+}
+
+// NewEventValidator creates an EventValidator.  If no rules are given, the
+// DefaultEventRules are used.
+func NewEventValidator(v bascule.Validator, emitter *Emitter, rules ...EventRule) (*EventValidator, error) {
+	if v == nil {
+		return nil, ErrNilValidator
+	}
+	if emitter == nil {
+		return nil, ErrNilEmitter
+	}
+	if len(rules) == 0 {
+		rules = DefaultEventRules()
+	}
+	return &EventValidator{
+		validator: v,
+		emitter:   emitter,
+		rules:     rules,
+	}, nil
+}
+
+// Check runs the wrapped Validator and emits the event for the first rule
+// matching its error.
+func (ev *EventValidator) Check(ctx context.Context, token bascule.Token) error {
+	err := ev.validator.Check(ctx, token)
+	if err == nil || token == nil {
+		return err
+	}
+	for _, r := range ev.rules {
+		if r.Err == nil || !errors.Is(err, r.Err) {
+			continue
+		}
+		reason := r.Reason
+		if reason == "" {
+			reason = err.Error()
+		}
+		ev.emitter.Emit(Event{
+			Type:             r.Type,
+			Subject:          TokenSubject(token),
+			InitiatingEntity: "policy",
+			Reason:           reason,
+			Fields:           r.Fields,
+		})
+		break
+	}
+	return err
+}
+
+// TokenSubject returns the subject a token is for: its issuer and principal
+// when the token has an iss claim, or just its principal otherwise.
+func TokenSubject(token bascule.Token) Subject {
+	if attrs := token.Attributes(); attrs != nil {
+		if iss, ok := attrs.Get(issuerClaim); ok {
+			if s, ok := iss.(string); ok && s != "" {
+				return IssSubSubject(s, token.Principal())
+			}
+		}
+	}
+	return OpaqueSubject(token.Principal())
+}
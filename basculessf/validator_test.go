@@ -0,0 +1,134 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculessf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEventValidator(t *testing.T) {
+	assert := assert.New(t)
+	v := bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return nil })
+	e, err := NewEmitter(&recordingTransmitter{}, EmitterConfig{}, nil, nil)
+	require.NoError(t, err)
+
+	ev, err := NewEventValidator(nil, e)
+	assert.ErrorIs(err, ErrNilValidator)
+	assert.Nil(ev)
+
+	ev, err = NewEventValidator(v, nil)
+	assert.ErrorIs(err, ErrNilEmitter)
+	assert.Nil(ev)
+
+	ev, err = NewEventValidator(v, e)
+	require.NoError(t, err)
+	assert.Equal(DefaultEventRules(), ev.rules)
+}
+
+func TestEventValidatorCheck(t *testing.T) {
+	revoked := errors.New("session revoked")
+	rules := append(DefaultEventRules(), EventRule{
+		Err:    revoked,
+		Type:   SessionRevoked,
+		Reason: "revoked by the identity provider",
+	})
+	tests := []struct {
+		description   string
+		token         bascule.Token
+		err           error
+		expectedEvent *Event
+	}{
+		{
+			description: "Success",
+			token:       bascule.NewToken("jwt", "alice", bascule.NewAttributes(nil)),
+		},
+		{
+			description: "Unmatched error",
+			token:       bascule.NewToken("jwt", "alice", bascule.NewAttributes(nil)),
+			err:         errors.New("some other failure"),
+		},
+		{
+			description: "Nil token",
+			err:         revoked,
+		},
+		{
+			description: "Binding mismatch",
+			token: bascule.NewToken("jwt", "alice", bascule.NewAttributes(map[string]interface{}{
+				"iss": "https://idp.example.com",
+			})),
+			err: fmt.Errorf("%w: ip", basculechecks.ErrBindingMismatch),
+			expectedEvent: &Event{
+				Type:             CredentialCompromise,
+				Subject:          IssSubSubject("https://idp.example.com", "alice"),
+				InitiatingEntity: "policy",
+				Reason:           "token used from a different client than it was issued to: ip",
+				Fields:           map[string]interface{}{"credential_type": "bearer_token"},
+			},
+		},
+		{
+			description: "Custom rule",
+			token:       bascule.NewToken("basic", "bob", bascule.NewAttributes(nil)),
+			err:         revoked,
+			expectedEvent: &Event{
+				Type:             SessionRevoked,
+				Subject:          OpaqueSubject("bob"),
+				InitiatingEntity: "policy",
+				Reason:           "revoked by the identity provider",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			transmitter := &recordingTransmitter{}
+			e, err := NewEmitter(transmitter, EmitterConfig{}, nil, nil)
+			require.NoError(t, err)
+			v := bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return tc.err })
+			ev, err := NewEventValidator(v, e, rules...)
+			require.NoError(t, err)
+
+			err = ev.Check(context.Background(), tc.token)
+			assert.Equal(tc.err, err)
+
+			e.Start()
+			e.Stop()
+			if tc.expectedEvent == nil {
+				assert.Empty(transmitter.Events())
+				return
+			}
+			assert.Equal([]Event{*tc.expectedEvent}, transmitter.Events())
+		})
+	}
+}
+
+func TestTokenSubject(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(OpaqueSubject("a"), TokenSubject(bascule.NewToken("jwt", "a", nil)))
+	assert.Equal(OpaqueSubject("a"), TokenSubject(bascule.NewToken("jwt", "a",
+		bascule.NewAttributes(map[string]interface{}{"iss": 5}))))
+	assert.Equal(IssSubSubject("i", "a"), TokenSubject(bascule.NewToken("jwt", "a",
+		bascule.NewAttributes(map[string]interface{}{"iss": "i"}))))
+}
@@ -0,0 +1,55 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"time"
+
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+)
+
+// ttlCache is a bounded cache whose entries expire after a fixed TTL.  A cache
+// with a TTL that isn't positive caches nothing.
+type ttlCache struct {
+	ttl   time.Duration
+	now   func() time.Time
+	cache *ttlcache.Cache
+}
+
+func newTTLCache(ttl time.Duration, maxEntries int) *ttlCache {
+	return &ttlCache{
+		ttl:   ttl,
+		now:   time.Now,
+		cache: ttlcache.New(maxEntries),
+	}
+}
+
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	return c.cache.Get(key, c.now())
+}
+
+func (c *ttlCache) set(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+	c.cache.Set(key, value, now.Add(c.ttl), now)
+}
@@ -0,0 +1,232 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/spf13/cast"
+)
+
+var (
+	// ErrClientNotRegistered is returned when the token's client isn't in the
+	// registry and registration is required.
+	ErrClientNotRegistered = bascule.NewClassError(bascule.CapabilityClass, "client not registered")
+
+	// ErrClientEndpointNotAllowed is returned when the client isn't allowed to
+	// call the endpoint requested.
+	ErrClientEndpointNotAllowed = bascule.NewClassError(bascule.CapabilityClass, "endpoint not allowed for client")
+
+	// ErrClientPartnerNotAllowed is returned when the token has a partner ID
+	// the client isn't allowed.
+	ErrClientPartnerNotAllowed = bascule.NewClassError(bascule.PartnerClass, "partner not allowed for client")
+
+	// ErrClientRegistryUnavailable is returned when the registry can't be
+	// reached.
+	ErrClientRegistryUnavailable = bascule.NewClassError(bascule.UnavailableClass, "client registry unavailable")
+)
+
+// ClientPolicyConfig configures a ClientPolicyValidator.
+type ClientPolicyConfig struct {
+	// ClientIDClaims are the claims that can hold the token's client ID.  The
+	// first one in the token is used, and the token's principal is used if
+	// there are none.  Defaults to "client_id" and "azp".
+	ClientIDClaims []string
+
+	// Required rejects tokens whose client isn't registered.  Otherwise,
+	// unregistered clients aren't restricted.
+	Required bool
+}
+
+// ClientPolicyValidator enforces the policies kept for each client in a
+// ClientRegistry: the endpoints it may call, the partners its tokens may have,
+// and how long its tokens may live.  Rate limits are enforced by a
+// QuotaValidator using ClientQuotaLimits.
+type ClientPolicyValidator struct {
+	registry ClientRegistry
+	config   ClientPolicyConfig
+	now      func() time.Time
+
+	// endpoints caches the compiled endpoint expressions, which a nil
+	// *regexp.Regexp marks as invalid.
+	endpoints sync.Map
+}
+
+// NewClientPolicyValidator creates a ClientPolicyValidator.  The registry
+// cannot be nil.
+func NewClientPolicyValidator(registry ClientRegistry, config ClientPolicyConfig) (*ClientPolicyValidator, error) {
+	if registry == nil {
+		return nil, ErrNilStore
+	}
+	if len(config.ClientIDClaims) == 0 {
+		config.ClientIDClaims = []string{"client_id", "azp"}
+	}
+	return &ClientPolicyValidator{
+		registry: registry,
+		config:   config,
+		now:      time.Now,
+	}, nil
+}
+
+// Check looks up the token's client and checks the request against its
+// policies.
+func (v *ClientPolicyValidator) Check(ctx context.Context, token bascule.Token) error {
+	if token == nil {
+		return basculechecks.ErrNoToken
+	}
+	id := v.clientID(token)
+	c, err := v.registry.Client(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		if v.config.Required {
+			return fmt.Errorf("%w: [%v]", ErrClientNotRegistered, id)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClientRegistryUnavailable, err)
+	}
+
+	if len(c.Endpoints) > 0 {
+		auth, ok := bascule.FromContext(ctx)
+		if !ok {
+			return basculechecks.ErrNoAuth
+		}
+		if auth.Request.URL == nil {
+			return basculechecks.ErrNoURL
+		}
+		path := auth.Request.URL.EscapedPath()
+		if !v.endpointAllowed(c.Endpoints, path) {
+			return fmt.Errorf("%w: client [%v] on [%v]", ErrClientEndpointNotAllowed, id, path)
+		}
+	}
+	if len(c.Partners) > 0 {
+		if err := partnersAllowed(c.Partners, token.Attributes()); err != nil {
+			return fmt.Errorf("%w: client [%v]: %v", ErrClientPartnerNotAllowed, id, err)
+		}
+	}
+	if c.MaxTokenTTL > 0 {
+		if err := v.checkTTL(c.MaxTokenTTL, token.Attributes()); err != nil {
+			return fmt.Errorf("%w: client [%v]", err, id)
+		}
+	}
+	return nil
+}
+
+// clientID returns the first client ID claim in the token, or its principal.
+func (v *ClientPolicyValidator) clientID(token bascule.Token) string {
+	if attributes := token.Attributes(); attributes != nil {
+		for _, claim := range v.config.ClientIDClaims {
+			if id, ok := attributes.Get(claim); ok {
+				if s := cast.ToString(id); s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return token.Principal()
+}
+
+// endpointAllowed returns true if one of the expressions matches the path
+// from its start.  Invalid expressions don't match anything.
+func (v *ClientPolicyValidator) endpointAllowed(endpoints []string, path string) bool {
+	for _, e := range endpoints {
+		r, ok := v.endpoints.Load(e)
+		if !ok {
+			compiled, _ := regexp.Compile(e)
+			r, _ = v.endpoints.LoadOrStore(e, compiled)
+		}
+		re := r.(*regexp.Regexp)
+		if re == nil {
+			continue
+		}
+		if idxs := re.FindStringIndex(path); len(idxs) > 0 && idxs[0] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// partnersAllowed checks that every partner ID in the token is allowed.
+func partnersAllowed(allowed []string, attributes bascule.Attributes) error {
+	v, ok := bascule.GetNestedAttribute(attributes, basculechecks.PartnerKeys()...)
+	if !ok {
+		return nil
+	}
+	partners, err := cast.ToStringSliceE(v)
+	if err != nil {
+		return fmt.Errorf("unexpected partner IDs [%v]", v)
+	}
+	set := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		set[a] = true
+	}
+	if set["*"] {
+		return nil
+	}
+	for _, p := range partners {
+		if !set[p] {
+			return fmt.Errorf("partner [%v]", p)
+		}
+	}
+	return nil
+}
+
+// checkTTL checks the token's lifetime, from iat, or now if there's no iat,
+// to exp.
+func (v *ClientPolicyValidator) checkTTL(maxTTL time.Duration, attributes bascule.Attributes) error {
+	if attributes == nil {
+		return fmt.Errorf("%w: exp", bascule.ErrMissingClaim)
+	}
+	value, ok := attributes.Get("exp")
+	exp, err := cast.ToInt64E(value)
+	if !ok || err != nil {
+		return fmt.Errorf("%w: exp", bascule.ErrMissingClaim)
+	}
+	start := v.now().Unix()
+	if value, ok := attributes.Get("iat"); ok {
+		if iat, err := cast.ToInt64E(value); err == nil {
+			start = iat
+		}
+	}
+	if ttl := time.Duration(exp-start) * time.Second; ttl > maxTTL {
+		return fmt.Errorf("%w: %v is over %v", bascule.ErrTTLTooLong, ttl, maxTTL)
+	}
+	return nil
+}
+
+// ClientQuotaLimits returns a QuotaLimitSource for a QuotaValidator that uses
+// the RateLimits of the clients in the registry, looking up the token's
+// principal as the client ID.  If the client can't be found, the
+// QuotaValidator's own limits are used.
+func ClientQuotaLimits(registry ClientRegistry) basculechecks.QuotaLimitSource {
+	return basculechecks.QuotaLimitSourceFunc(func(ctx context.Context, principal string, period basculechecks.QuotaPeriod) (int64, bool) {
+		c, err := registry.Client(ctx, principal)
+		if err != nil {
+			return 0, false
+		}
+		limit, ok := c.RateLimits[period]
+		return limit, ok
+	})
+}
@@ -0,0 +1,228 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ bascule.Validator = (*ClientPolicyValidator)(nil)
+
+type clientRegistryFunc func(context.Context, string) (Client, error)
+
+func (f clientRegistryFunc) Client(ctx context.Context, id string) (Client, error) {
+	return f(ctx, id)
+}
+
+func TestNewClientPolicyValidator(t *testing.T) {
+	assert := assert.New(t)
+	v, err := NewClientPolicyValidator(nil, ClientPolicyConfig{})
+	assert.ErrorIs(err, ErrNilStore)
+	assert.Nil(v)
+
+	v, err = NewClientPolicyValidator(ClientMap{}, ClientPolicyConfig{})
+	assert.NoError(err)
+	assert.Equal([]string{"client_id", "azp"}, v.config.ClientIDClaims)
+}
+
+func TestClientPolicyValidator(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	registry := NewClientMap([]Client{
+		{
+			ID:        "reporting",
+			Endpoints: []string{"(", "/reports"},
+			Partners:  []string{"comcast"},
+		},
+		{
+			ID:          "short-lived",
+			MaxTokenTTL: time.Hour,
+		},
+		{
+			ID:       "any-partner",
+			Partners: []string{"*"},
+		},
+	})
+	tests := []struct {
+		description   string
+		principal     string
+		attributes    map[string]interface{}
+		path          string
+		noAuth        bool
+		required      bool
+		registry      ClientRegistry
+		expectedErr   error
+		expectedClass bascule.ErrorClass
+	}{
+		{
+			description: "Allowed",
+			attributes: map[string]interface{}{
+				"client_id":        "reporting",
+				"allowedResources": map[string]interface{}{"allowedPartners": []interface{}{"comcast"}},
+			},
+			path: "/reports/daily",
+		},
+		{
+			description: "Client ID From AZP",
+			attributes:  map[string]interface{}{"azp": "reporting"},
+			path:        "/things",
+			expectedErr: ErrClientEndpointNotAllowed,
+		},
+		{
+			description:   "Endpoint Not Allowed",
+			principal:     "reporting",
+			path:          "/v1/reports",
+			expectedErr:   ErrClientEndpointNotAllowed,
+			expectedClass: bascule.CapabilityClass,
+		},
+		{
+			description: "Endpoint Without Auth",
+			principal:   "reporting",
+			noAuth:      true,
+			expectedErr: basculechecks.ErrNoAuth,
+		},
+		{
+			description: "Partner Not Allowed",
+			attributes: map[string]interface{}{
+				"client_id":        "reporting",
+				"allowedResources": map[string]interface{}{"allowedPartners": []interface{}{"comcast", "other"}},
+			},
+			path:          "/reports",
+			expectedErr:   ErrClientPartnerNotAllowed,
+			expectedClass: bascule.PartnerClass,
+		},
+		{
+			description: "Any Partner",
+			attributes: map[string]interface{}{
+				"client_id":        "any-partner",
+				"allowedResources": map[string]interface{}{"allowedPartners": []interface{}{"other"}},
+			},
+		},
+		{
+			description: "TTL Within Cap",
+			principal:   "short-lived",
+			attributes: map[string]interface{}{
+				"iat": float64(now.Unix()),
+				"exp": float64(now.Add(time.Hour).Unix()),
+			},
+		},
+		{
+			description: "TTL From Now",
+			principal:   "short-lived",
+			attributes:  map[string]interface{}{"exp": float64(now.Add(30 * time.Minute).Unix())},
+		},
+		{
+			description: "TTL Over Cap",
+			principal:   "short-lived",
+			attributes: map[string]interface{}{
+				"iat": float64(now.Unix()),
+				"exp": float64(now.Add(2 * time.Hour).Unix()),
+			},
+			expectedErr:   bascule.ErrTTLTooLong,
+			expectedClass: bascule.InvalidClass,
+		},
+		{
+			description: "TTL Without Exp",
+			principal:   "short-lived",
+			expectedErr: bascule.ErrMissingClaim,
+		},
+		{
+			description: "Unregistered",
+			principal:   "unknown",
+		},
+		{
+			description:   "Unregistered Required",
+			principal:     "unknown",
+			required:      true,
+			expectedErr:   ErrClientNotRegistered,
+			expectedClass: bascule.CapabilityClass,
+		},
+		{
+			description: "Registry Error",
+			principal:   "reporting",
+			registry: clientRegistryFunc(func(context.Context, string) (Client, error) {
+				return Client{}, errors.New("registry down")
+			}),
+			expectedErr:   ErrClientRegistryUnavailable,
+			expectedClass: bascule.UnavailableClass,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			r := tc.registry
+			if r == nil {
+				r = registry
+			}
+			v, err := NewClientPolicyValidator(r, ClientPolicyConfig{Required: tc.required})
+			require.NoError(err)
+			v.now = func() time.Time { return now }
+
+			token := bascule.NewToken("test", tc.principal, bascule.NewAttributes(tc.attributes))
+			ctx := context.Background()
+			if !tc.noAuth {
+				path := tc.path
+				if path == "" {
+					path = "/things"
+				}
+				u, err := url.Parse(path)
+				require.NoError(err)
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token:   token,
+					Request: bascule.Request{URL: u, Method: "GET"},
+				})
+			}
+			err = v.Check(ctx, token)
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorIs(err, tc.expectedErr)
+			if tc.expectedClass != bascule.UnknownClass {
+				assert.Equal(tc.expectedClass, bascule.ClassOf(err))
+			}
+		})
+	}
+
+	v, err := NewClientPolicyValidator(registry, ClientPolicyConfig{})
+	require.NoError(t, err)
+	assert.ErrorIs(t, v.Check(context.Background(), nil), basculechecks.ErrNoToken)
+}
+
+func TestClientQuotaLimits(t *testing.T) {
+	assert := assert.New(t)
+	source := ClientQuotaLimits(NewClientMap([]Client{
+		{ID: "reporting", RateLimits: map[basculechecks.QuotaPeriod]int64{basculechecks.Daily: 100}},
+	}))
+	limit, ok := source.QuotaLimit(context.Background(), "reporting", basculechecks.Daily)
+	assert.True(ok)
+	assert.Equal(int64(100), limit)
+	_, ok = source.QuotaLimit(context.Background(), "reporting", basculechecks.Monthly)
+	assert.False(ok)
+	_, ok = source.QuotaLimit(context.Background(), "unknown", basculechecks.Daily)
+	assert.False(ok)
+}
@@ -0,0 +1,143 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/s-srakshe/bascule/basculechecks"
+)
+
+// Client is the metadata kept for a client, gathering the exceptions a
+// client needs in one place rather than scattered across configuration.
+// Empty fields don't restrict the client.
+type Client struct {
+	// ID is the client's ID, as found in the client_id claim.
+	ID string `json:"id"`
+
+	// Endpoints are regular expressions, matched from the start of the
+	// request path, of the endpoints the client may call.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Partners are the partner IDs the client's tokens may have.  "*" allows
+	// any partner.
+	Partners []string `json:"partners,omitempty"`
+
+	// RateLimits are the client's request quotas by period, used in place of
+	// the QuotaValidator's limits.
+	RateLimits map[basculechecks.QuotaPeriod]int64 `json:"rateLimits,omitempty"`
+
+	// MaxTokenTTL is the longest lifetime, from iat to exp, the client's
+	// tokens may have.  In JSON, it's a duration string like "1h".
+	MaxTokenTTL time.Duration `json:"-"`
+
+	// Metadata is anything else kept about the client, such as its owner.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// UnmarshalJSON decodes a client, parsing the MaxTokenTTL duration string.
+func (c *Client) UnmarshalJSON(b []byte) error {
+	type client Client
+	v := struct {
+		*client
+		MaxTokenTTL string `json:"maxTokenTTL"`
+	}{client: (*client)(c)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	c.MaxTokenTTL = 0
+	if v.MaxTokenTTL != "" {
+		d, err := time.ParseDuration(v.MaxTokenTTL)
+		if err != nil {
+			return fmt.Errorf("invalid maxTokenTTL for client [%v]: %v", c.ID, err)
+		}
+		c.MaxTokenTTL = d
+	}
+	return nil
+}
+
+// ClientMap is a ClientRegistry kept in memory, by client ID.
+type ClientMap map[string]Client
+
+// Client returns the client in the map.
+func (m ClientMap) Client(_ context.Context, id string) (Client, error) {
+	c, ok := m[id]
+	if !ok {
+		return Client{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// NewClientMap builds a ClientMap from a list of clients.
+func NewClientMap(clients []Client) ClientMap {
+	m := make(ClientMap, len(clients))
+	for _, c := range clients {
+		m[c.ID] = c
+	}
+	return m
+}
+
+// FileClientRegistry is a ClientRegistry read from a JSON file holding a list
+// of clients.  Reload rereads the file, so that it can be updated without a
+// restart.
+type FileClientRegistry struct {
+	path string
+
+	lock    sync.RWMutex
+	clients ClientMap
+}
+
+// NewFileClientRegistry reads the clients from the JSON file at the path
+// given.
+func NewFileClientRegistry(path string) (*FileClientRegistry, error) {
+	r := &FileClientRegistry{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload rereads the file.  If it can't be read, the clients already loaded
+// are kept.
+func (r *FileClientRegistry) Reload() error {
+	b, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read clients file: %v", err)
+	}
+	var clients []Client
+	if err := json.Unmarshal(b, &clients); err != nil {
+		return fmt.Errorf("failed to decode clients file: %v", err)
+	}
+	m := NewClientMap(clients)
+	r.lock.Lock()
+	r.clients = m
+	r.lock.Unlock()
+	return nil
+}
+
+// Client returns the client from the last successful read of the file.
+func (r *FileClientRegistry) Client(ctx context.Context, id string) (Client, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.clients.Client(ctx, id)
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ ClientRegistry = ClientMap(nil)
+	_ ClientRegistry = (*FileClientRegistry)(nil)
+)
+
+const testClients = `[
+	{
+		"id": "reporting",
+		"endpoints": ["/reports"],
+		"partners": ["comcast"],
+		"rateLimits": {"daily": 100},
+		"maxTokenTTL": "1h",
+		"metadata": {"owner": "analytics"}
+	},
+	{"id": "admin-ui"}
+]`
+
+func TestClientUnmarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	var clients []Client
+	require.NoError(t, json.Unmarshal([]byte(testClients), &clients))
+	assert.Equal([]Client{
+		{
+			ID:          "reporting",
+			Endpoints:   []string{"/reports"},
+			Partners:    []string{"comcast"},
+			RateLimits:  map[basculechecks.QuotaPeriod]int64{basculechecks.Daily: 100},
+			MaxTokenTTL: time.Hour,
+			Metadata:    map[string]string{"owner": "analytics"},
+		},
+		{ID: "admin-ui"},
+	}, clients)
+
+	var c Client
+	assert.Error(json.Unmarshal([]byte(`{"id": "bad", "maxTokenTTL": "forever"}`), &c))
+	assert.Error(json.Unmarshal([]byte(`{"id": 5}`), &c))
+}
+
+func TestClientMap(t *testing.T) {
+	assert := assert.New(t)
+	m := NewClientMap([]Client{{ID: "a"}, {ID: "b"}})
+	c, err := m.Client(context.Background(), "b")
+	assert.NoError(err)
+	assert.Equal("b", c.ID)
+	_, err = m.Client(context.Background(), "c")
+	assert.ErrorIs(err, ErrNotFound)
+}
+
+func TestFileClientRegistry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "clients.json")
+
+	r, err := NewFileClientRegistry(path)
+	assert.Error(err)
+	assert.Nil(r)
+
+	require.NoError(os.WriteFile(path, []byte(testClients), 0600))
+	r, err = NewFileClientRegistry(path)
+	require.NoError(err)
+	c, err := r.Client(context.Background(), "reporting")
+	assert.NoError(err)
+	assert.Equal(time.Hour, c.MaxTokenTTL)
+	_, err = r.Client(context.Background(), "new")
+	assert.ErrorIs(err, ErrNotFound)
+
+	// a bad file keeps the clients already loaded.
+	require.NoError(os.WriteFile(path, []byte("not json"), 0600))
+	assert.Error(r.Reload())
+	_, err = r.Client(context.Background(), "reporting")
+	assert.NoError(err)
+
+	require.NoError(os.WriteFile(path, []byte(`[{"id": "new"}]`), 0600))
+	assert.NoError(r.Reload())
+	_, err = r.Client(context.Background(), "new")
+	assert.NoError(err)
+	_, err = r.Client(context.Background(), "reporting")
+	assert.ErrorIs(err, ErrNotFound)
+}
@@ -0,0 +1,30 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+Package basculestore provides principal, credential, and capability stores
+for teams that authenticate with API keys or basic auth rather than a JWT
+identity provider.  A database/sql backed implementation is included, along
+with token factories that consult the stores.
+
+It also provides a ClientRegistry, backed by a file, a web service, or a
+database, keeping per-client policies such as allowed endpoints, partners,
+rate limits, and token lifetime caps.  The ClientPolicyValidator and
+ClientQuotaLimits enforce them.
+*/
+
+package basculestore
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+)
+
+// APIKeyTokenType is the type of the Tokens created by the
+// APIKeyTokenFactory.
+const APIKeyTokenType = "apikey"
+
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// BasicTokenFactory verifies basic auth credentials against a
+// CredentialStore, using the username as the credential id.  If a
+// CapabilityStore is set, the principal's capabilities are added to the
+// token so capability validators can use them.
+type BasicTokenFactory struct {
+	Credentials  CredentialStore
+	Capabilities CapabilityStore
+}
+
+// ParseAndValidate expects the given value to be a base64 encoded string with
+// the username followed by a colon and then the password.  The password is
+// verified against the password hash stored for the username.
+func (btf BasicTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	if btf.Credentials == nil {
+		return nil, ErrNilStore
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode string: %v", err)
+	}
+	defer bascule.Zero(decoded)
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return nil, basculehttp.ErrorMalformedValue
+	}
+	username := string(decoded[:i])
+	c, err := btf.Credentials.Credential(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return nil, basculehttp.ErrorPrincipalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyPassword(c, decoded[i+1:]); err != nil {
+		return nil, err
+	}
+	principal := c.Principal
+	if principal == "" {
+		principal = username
+	}
+	return newToken(ctx, "basic", principal, btf.Capabilities)
+}
+
+// APIKeyTokenFactory verifies API keys against a CredentialStore.  Keys are
+// looked up by HashAPIKey, so the store never needs to hold the keys
+// themselves.  If the credential found has a secret, it must also equal the
+// hash, which guards against stores that match ids loosely, such as case
+// insensitive database collations.  If a CapabilityStore is set, the
+// principal's capabilities are added to the token.
+type APIKeyTokenFactory struct {
+	Credentials  CredentialStore
+	Capabilities CapabilityStore
+}
+
+// ParseAndValidate looks up the API key given and returns a Token for its
+// owner.
+func (atf APIKeyTokenFactory) ParseAndValidate(ctx context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	if atf.Credentials == nil {
+		return nil, ErrNilStore
+	}
+	if len(value) == 0 {
+		return nil, basculehttp.ErrEmptyValue
+	}
+	hash := HashAPIKey(value)
+	c, err := atf.Credentials.Credential(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidCredential
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.hasSecret() && !c.secretEqual([]byte(hash)) {
+		return nil, ErrInvalidCredential
+	}
+	if c.Principal == "" {
+		return nil, basculehttp.ErrInvalidPrincipal
+	}
+	return newToken(ctx, APIKeyTokenType, c.Principal, atf.Capabilities)
+}
+
+// verifyPassword checks the password against the credential's password hash
+// with basculehttp.VerifyPasswordHash.  A credential without a secret matches
+// no password.
+func verifyPassword(c Credential, password []byte) error {
+	hash := c.Secret
+	if c.ZeroizingSecret != nil {
+		c.ZeroizingSecret.Reveal(func(b []byte) {
+			hash = string(b)
+		})
+	}
+	if hash == "" {
+		return basculehttp.ErrorInvalidPassword
+	}
+	return basculehttp.VerifyPasswordHash(hash, string(password))
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of the API key given, which
+// is the credential id the APIKeyTokenFactory looks keys up by.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken(ctx context.Context, tokenType, principal string, store CapabilityStore) (bascule.Token, error) {
+	attributes := map[string]interface{}{}
+	if store != nil {
+		capabilities, err := store.Capabilities(ctx, principal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get capabilities: %v", err)
+		}
+		attributes[bascule.CapabilitiesKey] = capabilities
+	}
+	return bascule.NewToken(tokenType, principal, bascule.NewAttributes(attributes)), nil
+}
@@ -0,0 +1,257 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicTokenFactory(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	store := testStore{
+		credentials: map[string]Credential{
+			"user":      {Principal: "user-principal", Secret: string(hash)},
+			"other":     {Secret: string(hash)},
+			"zeroizing": {Principal: "user-principal", ZeroizingSecret: bascule.NewZeroizingBytes(hash)},
+			"none":      {Principal: "none"},
+			"plaintext": {Principal: "plaintext", Secret: "pass"},
+		},
+		capabilities: map[string][]string{
+			"user-principal": {"a"},
+		},
+	}
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		description          string
+		factory              BasicTokenFactory
+		value                string
+		expectedPrincipal    string
+		expectedCapabilities interface{}
+		expectedErr          error
+		expectErr            bool
+	}{
+		{
+			description:          "Success",
+			factory:              BasicTokenFactory{Credentials: store, Capabilities: store},
+			value:                encode("user:pass"),
+			expectedPrincipal:    "user-principal",
+			expectedCapabilities: []string{"a"},
+		},
+		{
+			description:       "No Capability Store Success",
+			factory:           BasicTokenFactory{Credentials: store},
+			value:             encode("other:pass"),
+			expectedPrincipal: "other",
+		},
+		{
+			description: "Nil Store",
+			expectedErr: ErrNilStore,
+		},
+		{
+			description: "Decode Error",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       "!!!",
+			expectErr:   true,
+		},
+		{
+			description: "Malformed",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("userpass"),
+			expectedErr: basculehttp.ErrorMalformedValue,
+		},
+		{
+			description: "Not Found",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("nobody:pass"),
+			expectedErr: basculehttp.ErrorPrincipalNotFound,
+		},
+		{
+			description: "Wrong Password",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("user:wrong"),
+			expectedErr: basculehttp.ErrorInvalidPassword,
+		},
+		{
+			description:       "Zeroizing Secret Success",
+			factory:           BasicTokenFactory{Credentials: store},
+			value:             encode("zeroizing:pass"),
+			expectedPrincipal: "user-principal",
+		},
+		{
+			description: "Zeroizing Secret Mismatch",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("zeroizing:pas"),
+			expectedErr: basculehttp.ErrorInvalidPassword,
+		},
+		{
+			description: "No Secret",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("none:"),
+			expectedErr: basculehttp.ErrorInvalidPassword,
+		},
+		{
+			description: "Plaintext Secret",
+			factory:     BasicTokenFactory{Credentials: store},
+			value:       encode("plaintext:pass"),
+			expectedErr: basculehttp.ErrUnsupportedHash,
+		},
+		{
+			description: "Store Error",
+			factory:     BasicTokenFactory{Credentials: testStore{err: errors.New("test")}},
+			value:       encode("user:pass"),
+			expectErr:   true,
+		},
+		{
+			description: "Capability Store Error",
+			factory:     BasicTokenFactory{Credentials: store, Capabilities: testStore{err: errors.New("test")}},
+			value:       encode("user:pass"),
+			expectErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			token, err := tc.factory.ParseAndValidate(context.Background(), httptest.NewRequest("GET", "/", nil), "Basic", tc.value)
+			if tc.expectErr || tc.expectedErr != nil {
+				assert.Nil(token)
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("basic", token.Type())
+			assert.Equal(tc.expectedPrincipal, token.Principal())
+			capabilities, _ := token.Attributes().Get(bascule.CapabilitiesKey)
+			assert.Equal(tc.expectedCapabilities, capabilities)
+		})
+	}
+}
+
+func TestAPIKeyTokenFactory(t *testing.T) {
+	store := testStore{
+		credentials: map[string]Credential{
+			HashAPIKey("good-key"):     {Principal: "service"},
+			HashAPIKey("hashed-key"):   {Principal: "service", Secret: HashAPIKey("hashed-key")},
+			HashAPIKey("zeroized-key"): {Principal: "service", ZeroizingSecret: bascule.NewZeroizingString(HashAPIKey("zeroized-key"))},
+			HashAPIKey("mismatch-key"): {Principal: "service", Secret: HashAPIKey("other-key")},
+			HashAPIKey("no-principal"): {},
+		},
+		capabilities: map[string][]string{
+			"service": {"a", "b"},
+		},
+	}
+	tests := []struct {
+		description          string
+		factory              APIKeyTokenFactory
+		value                string
+		expectedCapabilities interface{}
+		expectedErr          error
+		expectErr            bool
+	}{
+		{
+			description:          "Success",
+			factory:              APIKeyTokenFactory{Credentials: store, Capabilities: store},
+			value:                "good-key",
+			expectedCapabilities: []string{"a", "b"},
+		},
+		{
+			description:          "Matching Secret Success",
+			factory:              APIKeyTokenFactory{Credentials: store, Capabilities: store},
+			value:                "hashed-key",
+			expectedCapabilities: []string{"a", "b"},
+		},
+		{
+			description:          "Matching Zeroizing Secret Success",
+			factory:              APIKeyTokenFactory{Credentials: store, Capabilities: store},
+			value:                "zeroized-key",
+			expectedCapabilities: []string{"a", "b"},
+		},
+		{
+			description: "Mismatched Secret",
+			factory:     APIKeyTokenFactory{Credentials: store},
+			value:       "mismatch-key",
+			expectedErr: ErrInvalidCredential,
+		},
+		{
+			description: "Nil Store",
+			value:       "good-key",
+			expectedErr: ErrNilStore,
+		},
+		{
+			description: "Empty Value",
+			factory:     APIKeyTokenFactory{Credentials: store},
+			expectedErr: basculehttp.ErrEmptyValue,
+		},
+		{
+			description: "Unknown Key",
+			factory:     APIKeyTokenFactory{Credentials: store},
+			value:       "bad-key",
+			expectedErr: ErrInvalidCredential,
+		},
+		{
+			description: "No Principal",
+			factory:     APIKeyTokenFactory{Credentials: store},
+			value:       "no-principal",
+			expectedErr: basculehttp.ErrInvalidPrincipal,
+		},
+		{
+			description: "Store Error",
+			factory:     APIKeyTokenFactory{Credentials: testStore{err: errors.New("test")}},
+			value:       "good-key",
+			expectErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			token, err := tc.factory.ParseAndValidate(context.Background(), httptest.NewRequest("GET", "/", nil), "ApiKey", tc.value)
+			if tc.expectErr || tc.expectedErr != nil {
+				assert.Nil(token)
+				assert.Error(err)
+				if tc.expectedErr != nil {
+					assert.ErrorIs(err, tc.expectedErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(APIKeyTokenType, token.Type())
+			assert.Equal("service", token.Principal())
+			capabilities, _ := token.Attributes().Get(bascule.CapabilitiesKey)
+			assert.Equal(tc.expectedCapabilities, capabilities)
+		})
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", HashAPIKey("test"))
+}
@@ -0,0 +1,158 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPClientTimeout  = 5 * time.Second
+	defaultHTTPClientCacheTTL = 5 * time.Minute
+	httpClientMaxResponseSize = 1 << 20
+)
+
+var ErrEmptyClientURL = errors.New("client registry url cannot be empty")
+
+// HTTPClientRegistryConfig configures an HTTPClientRegistry.
+type HTTPClientRegistryConfig struct {
+	// URL is the base URL of the registry.  Clients are fetched from the URL
+	// with the escaped client ID added as the last path segment.
+	URL string
+
+	// Header is added to each request, such as to authenticate with the
+	// registry.
+	Header http.Header
+
+	// Client is the client used to call the registry.  Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+
+	// Timeout bounds each call to the registry.  Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// CacheTTL is how long clients, and clients not found, are cached.
+	// Defaults to five minutes.  A negative TTL disables caching.
+	CacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached clients.  Defaults to
+	// 10000.
+	CacheMaxEntries int
+}
+
+// HTTPClientRegistry is a ClientRegistry backed by a web service that returns
+// a client as JSON, or a 404 if the client isn't registered.
+type HTTPClientRegistry struct {
+	config HTTPClientRegistryConfig
+	cache  *ttlCache
+}
+
+type clientResult struct {
+	client Client
+	found  bool
+}
+
+// NewHTTPClientRegistry creates an HTTPClientRegistry.
+func NewHTTPClientRegistry(config HTTPClientRegistryConfig) (*HTTPClientRegistry, error) {
+	if config.URL == "" {
+		return nil, ErrEmptyClientURL
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHTTPClientTimeout
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = defaultHTTPClientCacheTTL
+	}
+	if config.CacheMaxEntries <= 0 {
+		config.CacheMaxEntries = defaultCacheMaxEntries
+	}
+	return &HTTPClientRegistry{
+		config: config,
+		cache:  newTTLCache(config.CacheTTL, config.CacheMaxEntries),
+	}, nil
+}
+
+// Client fetches the client from the registry, or the cache.
+func (r *HTTPClientRegistry) Client(ctx context.Context, id string) (Client, error) {
+	if v, ok := r.cache.get(id); ok {
+		return clientOf(v.(clientResult))
+	}
+	result, err := r.fetch(ctx, id)
+	if err != nil {
+		return Client{}, err
+	}
+	r.cache.set(id, result)
+	return clientOf(result)
+}
+
+func clientOf(r clientResult) (Client, error) {
+	if !r.found {
+		return Client{}, ErrNotFound
+	}
+	return r.client, nil
+}
+
+func (r *HTTPClientRegistry) fetch(ctx context.Context, id string) (clientResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.config.URL+"/"+url.PathEscape(id), nil)
+	if err != nil {
+		return clientResult{}, err
+	}
+	for k, v := range r.config.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.config.Client.Do(req)
+	if err != nil {
+		return clientResult{}, fmt.Errorf("failed to call client registry: %v", err)
+	}
+	defer resp.Body.Close()
+	body := io.LimitReader(resp.Body, httpClientMaxResponseSize)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		_, _ = io.Copy(io.Discard, body)
+		return clientResult{}, nil
+	default:
+		_, _ = io.Copy(io.Discard, body)
+		return clientResult{}, fmt.Errorf("unexpected client registry status code %d", resp.StatusCode)
+	}
+
+	var c Client
+	if err := json.NewDecoder(body).Decode(&c); err != nil {
+		return clientResult{}, fmt.Errorf("failed to decode client: %v", err)
+	}
+	if c.ID == "" {
+		c.ID = id
+	}
+	return clientResult{client: c, found: true}, nil
+}
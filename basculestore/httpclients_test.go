@@ -0,0 +1,106 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ ClientRegistry = (*HTTPClientRegistry)(nil)
+
+func TestNewHTTPClientRegistry(t *testing.T) {
+	assert := assert.New(t)
+	r, err := NewHTTPClientRegistry(HTTPClientRegistryConfig{})
+	assert.ErrorIs(err, ErrEmptyClientURL)
+	assert.Nil(r)
+
+	r, err = NewHTTPClientRegistry(HTTPClientRegistryConfig{URL: "http://registry/clients/"})
+	require.NoError(t, err)
+	assert.Equal("http://registry/clients", r.config.URL)
+	assert.Equal(http.DefaultClient, r.config.Client)
+	assert.Equal(defaultHTTPClientTimeout, r.config.Timeout)
+	assert.Equal(defaultHTTPClientCacheTTL, r.config.CacheTTL)
+}
+
+func TestHTTPClientRegistry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/clients/reporting":
+			_, _ = w.Write([]byte(`{"endpoints": ["/reports"], "maxTokenTTL": "1h"}`))
+		case "/clients/a%2Fb":
+			_, _ = w.Write([]byte(`{"id": "a/b"}`))
+		case "/clients/garbled":
+			_, _ = w.Write([]byte(`{`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPClientRegistry(HTTPClientRegistryConfig{
+		URL:    server.URL + "/clients",
+		Header: http.Header{"X-Api-Key": {"secret"}},
+	})
+	require.NoError(err)
+	ctx := context.Background()
+
+	c, err := r.Client(ctx, "reporting")
+	assert.NoError(err)
+	assert.Equal("reporting", c.ID)
+	assert.Equal([]string{"/reports"}, c.Endpoints)
+	c, err = r.Client(ctx, "a/b")
+	assert.NoError(err)
+	assert.Equal("a/b", c.ID)
+
+	_, err = r.Client(ctx, "missing")
+	assert.ErrorIs(err, ErrNotFound)
+	_, err = r.Client(ctx, "garbled")
+	assert.Error(err)
+	assert.NotErrorIs(err, ErrNotFound)
+
+	// found and not found clients are cached, errors aren't.
+	before := atomic.LoadInt32(&calls)
+	_, err = r.Client(ctx, "reporting")
+	assert.NoError(err)
+	_, err = r.Client(ctx, "missing")
+	assert.ErrorIs(err, ErrNotFound)
+	_, err = r.Client(ctx, "garbled")
+	assert.Error(err)
+	assert.Equal(before+1, atomic.LoadInt32(&calls))
+
+	r, err = NewHTTPClientRegistry(HTTPClientRegistryConfig{URL: server.URL + "/clients"})
+	require.NoError(err)
+	_, err = r.Client(ctx, "reporting")
+	assert.Error(err)
+	assert.NotErrorIs(err, ErrNotFound)
+}
@@ -0,0 +1,168 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// testDriver is a minimal database/sql driver whose queries are answered by
+// functions registered by the tests.
+type testDriver struct {
+	lock     sync.Mutex
+	queries  map[string]func(args []driver.Value) ([][]driver.Value, error)
+	prepared map[string]int
+	executed map[string]int
+}
+
+var (
+	driverOnce sync.Once
+	theDriver  = &testDriver{}
+)
+
+func newTestDB(queries map[string]func([]driver.Value) ([][]driver.Value, error)) (*sql.DB, *testDriver) {
+	driverOnce.Do(func() {
+		sql.Register("basculestoretest", theDriver)
+	})
+	theDriver.lock.Lock()
+	theDriver.queries = queries
+	theDriver.prepared = make(map[string]int)
+	theDriver.executed = make(map[string]int)
+	theDriver.lock.Unlock()
+	db, _ := sql.Open("basculestoretest", "")
+	return db, theDriver
+}
+
+func (d *testDriver) counts(query string) (prepared int, executed int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.prepared[query], d.executed[query]
+}
+
+func (d *testDriver) Open(string) (driver.Conn, error) {
+	return testConn{d: d}, nil
+}
+
+type testConn struct {
+	d *testDriver
+}
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.lock.Lock()
+	defer c.d.lock.Unlock()
+	f, ok := c.d.queries[query]
+	if !ok {
+		return nil, errors.New("unknown query")
+	}
+	c.d.prepared[query]++
+	return testStmt{d: c.d, query: query, f: f}, nil
+}
+
+func (c testConn) Close() error {
+	return nil
+}
+
+func (c testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+type testStmt struct {
+	d     *testDriver
+	query string
+	f     func([]driver.Value) ([][]driver.Value, error)
+}
+
+func (s testStmt) Close() error {
+	return nil
+}
+
+func (s testStmt) NumInput() int {
+	return 1
+}
+
+func (s testStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec not supported")
+}
+
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.lock.Lock()
+	s.d.executed[s.query]++
+	s.d.lock.Unlock()
+	rows, err := s.f(args)
+	if err != nil {
+		return nil, err
+	}
+	return &testRows{rows: rows}, nil
+}
+
+type testRows struct {
+	rows [][]driver.Value
+}
+
+func (r *testRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"a", "b"}
+	}
+	cols := make([]string, len(r.rows[0]))
+	for i := range cols {
+		cols[i] = string(rune('a' + i))
+	}
+	return cols
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type testStore struct {
+	credentials  map[string]Credential
+	capabilities map[string][]string
+	err          error
+}
+
+func (s testStore) Credential(_ context.Context, id string) (Credential, error) {
+	if s.err != nil {
+		return Credential{}, s.err
+	}
+	c, ok := s.credentials[id]
+	if !ok {
+		return Credential{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s testStore) Capabilities(_ context.Context, principal string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.capabilities[principal], nil
+}
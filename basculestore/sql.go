@@ -0,0 +1,210 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/s-srakshe/bascule"
+)
+
+const defaultCacheMaxEntries = 10000
+
+var ErrNilDB = errors.New("database cannot be nil")
+
+// SQLConfig configures a SQLStore.
+type SQLConfig struct {
+	// CredentialQuery selects the principal and secret, in that order, for
+	// the credential id given as its only argument.  For example:
+	//   SELECT principal, secret FROM credentials WHERE id = $1
+	// The secret may be NULL, which leaves the Credential without one.
+	// If it is empty, the store can't be used as a CredentialStore.
+	CredentialQuery string
+
+	// CapabilitiesQuery selects one capability per row for the principal
+	// given as its only argument.  For example:
+	//   SELECT capability FROM capabilities WHERE principal = $1
+	// If it is empty, the store can't be used as a CapabilityStore.
+	CapabilitiesQuery string
+
+	// ClientQuery selects a client, as a JSON document, for the client ID
+	// given as its only argument.  For example:
+	//   SELECT document FROM clients WHERE id = $1
+	// If it is empty, the store can't be used as a ClientRegistry.
+	ClientQuery string
+
+	// CacheTTL is how long results are cached.  If it isn't positive,
+	// results aren't cached.
+	CacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached results of each kind.
+	// Defaults to 10000.
+	CacheMaxEntries int
+}
+
+// SQLStore is a CredentialStore, CapabilityStore, and ClientRegistry backed
+// by a database/sql database.  Its queries are prepared once, when the store is created.
+type SQLStore struct {
+	credentialStmt   *sql.Stmt
+	capabilitiesStmt *sql.Stmt
+	clientStmt       *sql.Stmt
+	credentials      *ttlCache
+	capabilities     *ttlCache
+	clients          *ttlCache
+}
+
+// NewSQLStore prepares the configured queries against the database given.
+// Close should be called when the store is no longer needed.
+func NewSQLStore(ctx context.Context, db *sql.DB, config SQLConfig) (*SQLStore, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	if config.CacheMaxEntries <= 0 {
+		config.CacheMaxEntries = defaultCacheMaxEntries
+	}
+	s := &SQLStore{
+		credentials:  newTTLCache(config.CacheTTL, config.CacheMaxEntries),
+		capabilities: newTTLCache(config.CacheTTL, config.CacheMaxEntries),
+		clients:      newTTLCache(config.CacheTTL, config.CacheMaxEntries),
+	}
+	var err error
+	if config.CredentialQuery != "" {
+		s.credentialStmt, err = db.PrepareContext(ctx, config.CredentialQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare credential query: %v", err)
+		}
+	}
+	if config.CapabilitiesQuery != "" {
+		s.capabilitiesStmt, err = db.PrepareContext(ctx, config.CapabilitiesQuery)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to prepare capabilities query: %v", err)
+		}
+	}
+	if config.ClientQuery != "" {
+		s.clientStmt, err = db.PrepareContext(ctx, config.ClientQuery)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to prepare client query: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Credential looks up the credential with the id given.
+func (s *SQLStore) Credential(ctx context.Context, id string) (Credential, error) {
+	if s.credentialStmt == nil {
+		return Credential{}, errors.New("no credential query configured")
+	}
+	if v, ok := s.credentials.get(id); ok {
+		return v.(Credential), nil
+	}
+	var (
+		c      Credential
+		secret []byte
+	)
+	err := s.credentialStmt.QueryRowContext(ctx, id).Scan(&c.Principal, &secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Credential{}, ErrNotFound
+	}
+	if err != nil {
+		return Credential{}, fmt.Errorf("failed to query credential: %v", err)
+	}
+	if secret != nil {
+		c.ZeroizingSecret = bascule.NewZeroizingBytes(secret)
+	}
+	s.credentials.set(id, c)
+	return c, nil
+}
+
+// Capabilities looks up the capabilities granted to the principal given.
+func (s *SQLStore) Capabilities(ctx context.Context, principal string) ([]string, error) {
+	if s.capabilitiesStmt == nil {
+		return nil, errors.New("no capabilities query configured")
+	}
+	if v, ok := s.capabilities.get(principal); ok {
+		return v.([]string), nil
+	}
+	rows, err := s.capabilitiesStmt.QueryContext(ctx, principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capabilities: %v", err)
+	}
+	defer rows.Close()
+	capabilities := []string{}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("failed to scan capability: %v", err)
+		}
+		capabilities = append(capabilities, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capabilities: %v", err)
+	}
+	s.capabilities.set(principal, capabilities)
+	return capabilities, nil
+}
+
+// Client looks up the client with the id given.
+func (s *SQLStore) Client(ctx context.Context, id string) (Client, error) {
+	if s.clientStmt == nil {
+		return Client{}, errors.New("no client query configured")
+	}
+	if v, ok := s.clients.get(id); ok {
+		return v.(Client), nil
+	}
+	var document []byte
+	err := s.clientStmt.QueryRowContext(ctx, id).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Client{}, ErrNotFound
+	}
+	if err != nil {
+		return Client{}, fmt.Errorf("failed to query client: %v", err)
+	}
+	var c Client
+	if err := json.Unmarshal(document, &c); err != nil {
+		return Client{}, fmt.Errorf("failed to decode client: %v", err)
+	}
+	if c.ID == "" {
+		c.ID = id
+	}
+	s.clients.set(id, c)
+	return c, nil
+}
+
+// Close closes the prepared statements.
+func (s *SQLStore) Close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{s.credentialStmt, s.capabilitiesStmt, s.clientStmt} {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close statements: %v", errs)
+	}
+	return nil
+}
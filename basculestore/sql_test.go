@@ -0,0 +1,204 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	credentialQuery   = "SELECT principal, secret FROM credentials WHERE id = ?"
+	capabilitiesQuery = "SELECT capability FROM capabilities WHERE principal = ?"
+	clientQuery       = "SELECT document FROM clients WHERE id = ?"
+)
+
+func testQueries() map[string]func([]driver.Value) ([][]driver.Value, error) {
+	return map[string]func([]driver.Value) ([][]driver.Value, error){
+		credentialQuery: func(args []driver.Value) ([][]driver.Value, error) {
+			switch args[0] {
+			case "user":
+				return [][]driver.Value{{"user-principal", "pass"}}, nil
+			case "no-secret":
+				return [][]driver.Value{{"service", nil}}, nil
+			case "broken":
+				return nil, errors.New("db error")
+			}
+			return nil, nil
+		},
+		capabilitiesQuery: func(args []driver.Value) ([][]driver.Value, error) {
+			switch args[0] {
+			case "user-principal":
+				return [][]driver.Value{{"a"}, {"b"}}, nil
+			case "broken":
+				return nil, errors.New("db error")
+			}
+			return nil, nil
+		},
+		clientQuery: func(args []driver.Value) ([][]driver.Value, error) {
+			switch args[0] {
+			case "reporting":
+				return [][]driver.Value{{`{"endpoints": ["/reports"]}`}}, nil
+			case "garbled":
+				return [][]driver.Value{{`{`}}, nil
+			case "broken":
+				return nil, errors.New("db error")
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestNewSQLStore(t *testing.T) {
+	assert := assert.New(t)
+	s, err := NewSQLStore(context.Background(), nil, SQLConfig{})
+	assert.Nil(s)
+	assert.ErrorIs(err, ErrNilDB)
+
+	db, _ := newTestDB(testQueries())
+	s, err = NewSQLStore(context.Background(), db, SQLConfig{CredentialQuery: "bad"})
+	assert.Nil(s)
+	assert.Error(err)
+
+	s, err = NewSQLStore(context.Background(), db, SQLConfig{
+		CredentialQuery:   credentialQuery,
+		CapabilitiesQuery: "bad",
+	})
+	assert.Nil(s)
+	assert.Error(err)
+
+	s, err = NewSQLStore(context.Background(), db, SQLConfig{
+		CredentialQuery: credentialQuery,
+		ClientQuery:     "bad",
+	})
+	assert.Nil(s)
+	assert.Error(err)
+
+	s, err = NewSQLStore(context.Background(), db, SQLConfig{})
+	require.NoError(t, err)
+	_, err = s.Credential(context.Background(), "user")
+	assert.Error(err)
+	_, err = s.Capabilities(context.Background(), "user-principal")
+	assert.Error(err)
+	_, err = s.Client(context.Background(), "reporting")
+	assert.Error(err)
+	assert.NoError(s.Close())
+}
+
+func TestSQLStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	db, d := newTestDB(testQueries())
+	s, err := NewSQLStore(ctx, db, SQLConfig{
+		CredentialQuery:   credentialQuery,
+		CapabilitiesQuery: capabilitiesQuery,
+		ClientQuery:       clientQuery,
+		CacheTTL:          time.Minute,
+	})
+	require.NoError(err)
+	defer s.Close()
+
+	c, err := s.Credential(ctx, "user")
+	assert.NoError(err)
+	assert.Equal("user-principal", c.Principal)
+	assert.True(c.ZeroizingSecret.EqualString("pass"))
+	c, err = s.Credential(ctx, "user")
+	assert.NoError(err)
+	assert.Equal("user-principal", c.Principal)
+
+	c, err = s.Credential(ctx, "no-secret")
+	assert.NoError(err)
+	assert.Equal(Credential{Principal: "service"}, c)
+
+	_, err = s.Credential(ctx, "missing")
+	assert.ErrorIs(err, ErrNotFound)
+	_, err = s.Credential(ctx, "broken")
+	assert.Error(err)
+
+	capabilities, err := s.Capabilities(ctx, "user-principal")
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b"}, capabilities)
+	capabilities, err = s.Capabilities(ctx, "user-principal")
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b"}, capabilities)
+
+	capabilities, err = s.Capabilities(ctx, "nobody")
+	assert.NoError(err)
+	assert.Empty(capabilities)
+	_, err = s.Capabilities(ctx, "broken")
+	assert.Error(err)
+
+	client, err := s.Client(ctx, "reporting")
+	assert.NoError(err)
+	assert.Equal(Client{ID: "reporting", Endpoints: []string{"/reports"}}, client)
+	client, err = s.Client(ctx, "reporting")
+	assert.NoError(err)
+	assert.Equal("reporting", client.ID)
+	_, err = s.Client(ctx, "missing")
+	assert.ErrorIs(err, ErrNotFound)
+	_, err = s.Client(ctx, "garbled")
+	assert.Error(err)
+	_, err = s.Client(ctx, "broken")
+	assert.Error(err)
+
+	// statements are prepared once and cached results skip the database.
+	prepared, executed := d.counts(credentialQuery)
+	assert.Equal(1, prepared)
+	assert.Equal(4, executed)
+	prepared, executed = d.counts(capabilitiesQuery)
+	assert.Equal(1, prepared)
+	assert.Equal(3, executed)
+	prepared, executed = d.counts(clientQuery)
+	assert.Equal(1, prepared)
+	assert.Equal(4, executed)
+}
+
+func TestTTLCache(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+	c := newTTLCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	c.set("a", 1)
+	v, ok := c.get("a")
+	assert.True(ok)
+	assert.Equal(1, v)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get("a")
+	assert.False(ok)
+
+	c.set("b", 2)
+	c.set("c", 3)
+	c.set("d", 4)
+	assert.LessOrEqual(c.cache.Len(), 2)
+	_, ok = c.get("d")
+	assert.True(ok)
+
+	disabled := newTTLCache(0, 2)
+	disabled.set("a", 1)
+	_, ok = disabled.get("a")
+	assert.False(ok)
+}
@@ -0,0 +1,76 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package basculestore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/s-srakshe/bascule"
+)
+
+var (
+	ErrNotFound = errors.New("not found in store")
+	ErrNilStore = errors.New("store cannot be nil")
+)
+
+// Credential is a stored credential and the principal it belongs to.  For
+// basic auth, the secret is a bcrypt or argon2id hash of the password, such as
+// one made by basculehttp.HashArgon2id.  For API keys, it's optional, and is
+// the key's HashAPIKey if set.  Stores that want the secret zeroed when it's
+// no longer needed can set ZeroizingSecret instead of Secret; it's used
+// whenever it's set.
+type Credential struct {
+	Principal       string
+	Secret          string
+	ZeroizingSecret *bascule.ZeroizingString
+}
+
+// hasSecret reports whether the credential has a secret.
+func (c Credential) hasSecret() bool {
+	return c.ZeroizingSecret != nil || c.Secret != ""
+}
+
+// secretEqual reports whether the credential's secret matches the value
+// given, in constant time.  A credential without a secret matches nothing.
+func (c Credential) secretEqual(value []byte) bool {
+	if c.ZeroizingSecret != nil {
+		return c.ZeroizingSecret.Equal(value)
+	}
+	return c.Secret != "" && bascule.SecretEqual([]byte(c.Secret), value)
+}
+
+// CredentialStore looks up credentials, such as basic auth passwords or API
+// keys, by their id.  ErrNotFound is returned when there is no credential
+// with the id given.
+type CredentialStore interface {
+	Credential(ctx context.Context, id string) (Credential, error)
+}
+
+// CapabilityStore looks up the capabilities granted to a principal.  A
+// principal with no capabilities gets an empty list rather than an error.
+type CapabilityStore interface {
+	Capabilities(ctx context.Context, principal string) ([]string, error)
+}
+
+// ClientRegistry looks up the policies of a client, such as an application
+// calling with OAuth client credentials, by its client ID.  ErrNotFound is
+// returned when the client isn't registered.
+type ClientRegistry interface {
+	Client(ctx context.Context, id string) (Client, error)
+}
@@ -0,0 +1,113 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var ErrBudgetExceeded = errors.New("evaluation budget exceeded")
+
+// Budget tracks the evaluation cost spent validating a single request.  It is
+// safe for concurrent use.
+type Budget struct {
+	limit int64
+	spent int64
+}
+
+// NewBudget creates a Budget allowing the total cost given.  A limit that
+// isn't positive allows any cost, while still tracking what is spent.
+func NewBudget(limit int) *Budget {
+	return &Budget{limit: int64(limit)}
+}
+
+// Spend records the cost given, returning ErrBudgetExceeded if the total goes
+// over the limit.
+func (b *Budget) Spend(cost int) error {
+	spent := atomic.AddInt64(&b.spent, int64(cost))
+	if b.limit > 0 && spent > b.limit {
+		return fmt.Errorf("%w: spent %d of %d", ErrBudgetExceeded, spent, b.limit)
+	}
+	return nil
+}
+
+// Spent returns the total cost spent so far.
+func (b *Budget) Spent() int {
+	return int(atomic.LoadInt64(&b.spent))
+}
+
+// Limit returns the total cost allowed.
+func (b *Budget) Limit() int {
+	return int(b.limit)
+}
+
+// Exceeded returns true if more than the limit has been spent.
+func (b *Budget) Exceeded() bool {
+	return b.limit > 0 && atomic.LoadInt64(&b.spent) > b.limit
+}
+
+type budgetKey struct{}
+
+// WithBudget adds the Budget given to the context.
+func WithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// BudgetFromContext gets the Budget from the context provided.
+func BudgetFromContext(ctx context.Context) (*Budget, bool) {
+	b, ok := ctx.Value(budgetKey{}).(*Budget)
+	return b, ok && b != nil
+}
+
+// CostedValidator is a Validator that declares how expensive it is to run.
+type CostedValidator interface {
+	Validator
+	Cost() int
+}
+
+type costedValidator struct {
+	v    Validator
+	cost int
+}
+
+// WithCost wraps a Validator with the cost given.  Before the Validator runs,
+// its cost is spent from the Budget in the context, if there is one, and the
+// Validator is skipped with an error if the budget is exceeded or the context
+// is done.
+func WithCost(v Validator, cost int) CostedValidator {
+	return costedValidator{v: v, cost: cost}
+}
+
+func (c costedValidator) Cost() int {
+	return c.cost
+}
+
+func (c costedValidator) Check(ctx context.Context, t Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b, ok := BudgetFromContext(ctx); ok {
+		if err := b.Spend(c.cost); err != nil {
+			return err
+		}
+	}
+	return c.v.Check(ctx, t)
+}
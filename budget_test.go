@@ -0,0 +1,83 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBudget(5)
+	assert.Equal(5, b.Limit())
+	assert.NoError(b.Spend(3))
+	assert.NoError(b.Spend(2))
+	assert.False(b.Exceeded())
+	assert.ErrorIs(b.Spend(1), ErrBudgetExceeded)
+	assert.True(b.Exceeded())
+	assert.Equal(6, b.Spent())
+
+	unlimited := NewBudget(0)
+	assert.NoError(unlimited.Spend(100))
+	assert.False(unlimited.Exceeded())
+	assert.Equal(100, unlimited.Spent())
+}
+
+func TestBudgetContext(t *testing.T) {
+	assert := assert.New(t)
+	_, ok := BudgetFromContext(context.Background())
+	assert.False(ok)
+
+	b := NewBudget(1)
+	got, ok := BudgetFromContext(WithBudget(context.Background(), b))
+	assert.True(ok)
+	assert.Equal(b, got)
+
+	_, ok = BudgetFromContext(WithBudget(context.Background(), nil))
+	assert.False(ok)
+}
+
+func TestWithCost(t *testing.T) {
+	assert := assert.New(t)
+	testErr := errors.New("test")
+	calls := 0
+	v := WithCost(ValidatorFunc(func(context.Context, Token) error {
+		calls++
+		return testErr
+	}), 3)
+	assert.Equal(3, v.Cost())
+
+	// no budget means the validator always runs.
+	assert.ErrorIs(v.Check(context.Background(), nil), testErr)
+
+	b := NewBudget(5)
+	ctx := WithBudget(context.Background(), b)
+	assert.ErrorIs(v.Check(ctx, nil), testErr)
+	assert.ErrorIs(v.Check(ctx, nil), ErrBudgetExceeded)
+	assert.Equal(2, calls)
+	assert.Equal(6, b.Spent())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(v.Check(canceled, nil), context.Canceled)
+	assert.Equal(2, calls)
+}
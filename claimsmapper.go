@@ -0,0 +1,132 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Canonical attribute keys that a ClaimsMapper normalizes issuer specific
+// claims into.
+const (
+	PartnerIDsKey   = "partner-ids"
+	RolesKey        = "roles"
+	CapabilitiesKey = "capabilities"
+)
+
+var ErrEmptyCanonicalKey = errors.New("canonical key cannot be empty")
+
+// ClaimsMapper translates the attributes of a freshly parsed token into a
+// canonical form, so that validators don't need to know where a particular
+// issuer puts groups, roles, or partners.
+type ClaimsMapper interface {
+	MapClaims(Attributes) (Attributes, error)
+}
+
+// ClaimsMapperFunc makes it so any function that has the same signature as
+// ClaimsMapper's MapClaims function implements ClaimsMapper.
+type ClaimsMapperFunc func(Attributes) (Attributes, error)
+
+// MapClaims runs the ClaimsMapperFunc, making a ClaimsMapperFunc also a
+// ClaimsMapper.
+func (cmf ClaimsMapperFunc) MapClaims(a Attributes) (Attributes, error) {
+	return cmf(a)
+}
+
+// ClaimsMappingConfig is the declarative configuration for a ClaimsMapper.
+// Mappings is keyed by the canonical attribute key and each value is a list of
+// claim paths that are searched in order; the first path found wins.  A claim
+// path is written JSONPath style, e.g. "$.allowedResources.allowedPartners" or
+// "groups[*].name"; see ParseAttributePath for the supported syntax.
+type ClaimsMappingConfig struct {
+	Mappings map[string][]string
+
+	// Required lists the canonical keys that must be resolved.  If a required
+	// key can't be found through any of its paths, mapping fails.
+	Required []string
+}
+
+type claimsMapper struct {
+	mappings map[string][]AttributePath
+	required []string
+}
+
+// MapClaims resolves each canonical key using its claim paths and returns
+// Attributes containing the canonical keys layered on top of the original
+// attributes.  Original claims remain reachable by their own keys.
+func (c claimsMapper) MapClaims(a Attributes) (Attributes, error) {
+	if a == nil {
+		return nil, errors.New("nil attributes")
+	}
+	canonical := BasicAttributes{}
+	for key, paths := range c.mappings {
+		for _, p := range paths {
+			if v, ok := p.Get(a); ok {
+				canonical[key] = v
+				break
+			}
+		}
+	}
+	for _, key := range c.required {
+		if _, ok := canonical[key]; !ok {
+			return nil, fmt.Errorf("required canonical claim [%v] not found", key)
+		}
+	}
+	return mappedAttributes{canonical: canonical, base: a}, nil
+}
+
+// NewClaimsMapper validates and compiles the configuration given into a
+// ClaimsMapper.
+func NewClaimsMapper(config ClaimsMappingConfig) (ClaimsMapper, error) {
+	c := claimsMapper{
+		mappings: make(map[string][]AttributePath, len(config.Mappings)),
+		required: config.Required,
+	}
+	for key, paths := range config.Mappings {
+		if key == "" {
+			return nil, ErrEmptyCanonicalKey
+		}
+		for _, p := range paths {
+			path, err := ParseAttributePath(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path for canonical key [%v]: %w", key, err)
+			}
+			c.mappings[key] = append(c.mappings[key], path)
+		}
+	}
+	return c, nil
+}
+
+// mappedAttributes layers canonical attributes over the original ones.
+type mappedAttributes struct {
+	canonical BasicAttributes
+	base      Attributes
+}
+
+func (m mappedAttributes) Get(key string) (interface{}, bool) {
+	if v, ok := m.canonical[key]; ok {
+		return v, ok
+	}
+	return m.base.Get(key)
+}
+
+func (m mappedAttributes) Keys() ([]string, bool) {
+	keys, _ := m.canonical.Keys()
+	return LayeredKeys(m.base, keys...)
+}
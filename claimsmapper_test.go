@@ -0,0 +1,150 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClaimsMapper(t *testing.T) {
+	tests := []struct {
+		description string
+		config      ClaimsMappingConfig
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{
+					PartnerIDsKey: {"$.allowedResources.allowedPartners", "partners"},
+				},
+			},
+		},
+		{
+			description: "Empty Config Success",
+		},
+		{
+			description: "Empty Canonical Key Error",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{"": {"a"}},
+			},
+			expectedErr: ErrEmptyCanonicalKey,
+		},
+		{
+			description: "Empty Path Error",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{RolesKey: {"$."}},
+			},
+			expectedErr: ErrInvalidAttributePath,
+		},
+		{
+			description: "Empty Segment Error",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{RolesKey: {"a..b"}},
+			},
+			expectedErr: ErrInvalidAttributePath,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := NewClaimsMapper(tc.config)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(m)
+				return
+			}
+			assert.NoError(err)
+			assert.NotNil(m)
+		})
+	}
+}
+
+func TestClaimsMapperMapClaims(t *testing.T) {
+	original := NewAttributes(map[string]interface{}{
+		"sub": "test",
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []string{"comcast"},
+		},
+		"groups": []string{"admin"},
+	})
+	tests := []struct {
+		description  string
+		config       ClaimsMappingConfig
+		attributes   Attributes
+		expectedVals map[string]interface{}
+		missingKeys  []string
+		expectedErr  bool
+	}{
+		{
+			description: "Success",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{
+					PartnerIDsKey: {"$.allowedResources.allowedPartners"},
+					RolesKey:      {"roles", "groups"},
+				},
+			},
+			attributes: original,
+			expectedVals: map[string]interface{}{
+				PartnerIDsKey: []string{"comcast"},
+				RolesKey:      []string{"admin"},
+				"sub":         "test",
+			},
+			missingKeys: []string{CapabilitiesKey},
+		},
+		{
+			description: "Required Key Error",
+			config: ClaimsMappingConfig{
+				Mappings: map[string][]string{CapabilitiesKey: {"scope"}},
+				Required: []string{CapabilitiesKey},
+			},
+			attributes:  original,
+			expectedErr: true,
+		},
+		{
+			description: "Nil Attributes Error",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := NewClaimsMapper(tc.config)
+			require.NoError(t, err)
+			result, err := m.MapClaims(tc.attributes)
+			if tc.expectedErr {
+				assert.Error(err)
+				assert.Nil(result)
+				return
+			}
+			assert.NoError(err)
+			for k, v := range tc.expectedVals {
+				val, ok := result.Get(k)
+				assert.True(ok)
+				assert.Equal(v, val)
+			}
+			for _, k := range tc.missingKeys {
+				_, ok := result.Get(k)
+				assert.False(ok)
+			}
+		})
+	}
+}
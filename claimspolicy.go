@@ -0,0 +1,162 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrAlgorithmNotAllowed = NewClassError(InvalidClass, "signing algorithm not allowed")
+	ErrAudienceNotAllowed  = NewClassError(InvalidClass, "audience not allowed")
+	ErrIssuerNotAllowed    = NewClassError(InvalidClass, "issuer not allowed")
+	ErrMissingClaim        = NewClassError(InvalidClass, "required claim missing")
+	ErrTTLTooLong          = NewClassError(InvalidClass, "token lifetime too long")
+)
+
+// ClaimsPolicy is the set of rules a JWT's claims must follow beyond having a
+// valid signature.  Keeping them in one struct lets the whole policy be
+// configured and reviewed as a single block.  Empty fields aren't checked.
+type ClaimsPolicy struct {
+	// Audiences lists the accepted aud values.  The token must have at least
+	// one of them.
+	Audiences []string
+
+	// Issuers lists the accepted iss values.
+	Issuers []string
+
+	// Algorithms lists the accepted alg header values.
+	Algorithms []string
+
+	// RequiredClaims lists claims that must be present.
+	RequiredClaims []string
+
+	// MaxTTL is the longest lifetime a token may have, measured from iat, or
+	// from now if there's no iat.  Tokens without exp are rejected when it's
+	// set.
+	MaxTTL time.Duration
+
+	// ClockSkew is the leeway allowed when checking exp, nbf, and iat.
+	ClockSkew time.Duration
+}
+
+// Leeway returns the ClockSkew as a Leeway for ClaimsWithLeeway.
+func (p ClaimsPolicy) Leeway() Leeway {
+	s := int64(math.Ceil(p.ClockSkew.Seconds()))
+	return Leeway{EXP: s, NBF: s, IAT: s}
+}
+
+// ValidateAlgorithm checks the alg header against the allowed Algorithms.
+func (p ClaimsPolicy) ValidateAlgorithm(alg string) error {
+	if len(p.Algorithms) == 0 {
+		return nil
+	}
+	for _, a := range p.Algorithms {
+		if a == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, alg)
+}
+
+// ValidateClaims checks the claims against the policy.  The time based claims
+// are checked by ClaimsWithLeeway, except for the MaxTTL.
+func (p ClaimsPolicy) ValidateClaims(claims map[string]interface{}, now time.Time) error {
+	for _, name := range p.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	if len(p.Issuers) > 0 {
+		iss, _ := claims["iss"].(string)
+		if !policyContains(p.Issuers, iss) {
+			return fmt.Errorf("%w: %q", ErrIssuerNotAllowed, iss)
+		}
+	}
+	if len(p.Audiences) > 0 && !p.audienceAllowed(claims["aud"]) {
+		return fmt.Errorf("%w: %v", ErrAudienceNotAllowed, claims["aud"])
+	}
+	if p.MaxTTL > 0 {
+		exp, ok := numericDate(claims["exp"])
+		if !ok {
+			return fmt.Errorf("%w: exp", ErrMissingClaim)
+		}
+		start := now.Unix()
+		if iat, ok := numericDate(claims["iat"]); ok {
+			start = iat
+		}
+		maxTTL := p.MaxTTL + p.ClockSkew
+		if ttl := time.Duration(exp-start) * time.Second; ttl > maxTTL {
+			return fmt.Errorf("%w: %v is over %v", ErrTTLTooLong, ttl, p.MaxTTL)
+		}
+	}
+	return nil
+}
+
+// audienceAllowed handles aud being either a string or a list, as allowed by
+// RFC 7519.
+func (p ClaimsPolicy) audienceAllowed(aud interface{}) bool {
+	switch a := aud.(type) {
+	case string:
+		return policyContains(p.Audiences, a)
+	case []string:
+		for _, s := range a {
+			if policyContains(p.Audiences, s) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok && policyContains(p.Audiences, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func numericDate(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			f, err := n.Float64()
+			return int64(f), err == nil
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+func policyContains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimsPolicyLeeway(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(Leeway{}, ClaimsPolicy{}.Leeway())
+	assert.Equal(Leeway{EXP: 2, NBF: 2, IAT: 2}, ClaimsPolicy{ClockSkew: 1500 * time.Millisecond}.Leeway())
+}
+
+func TestClaimsPolicyValidateAlgorithm(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(ClaimsPolicy{}.ValidateAlgorithm("none"))
+	p := ClaimsPolicy{Algorithms: []string{"RS256", "ES256"}}
+	assert.NoError(p.ValidateAlgorithm("ES256"))
+	assert.ErrorIs(p.ValidateAlgorithm("HS256"), ErrAlgorithmNotAllowed)
+	assert.ErrorIs(p.ValidateAlgorithm(""), ErrAlgorithmNotAllowed)
+}
+
+func TestClaimsPolicyValidateClaims(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		description string
+		policy      ClaimsPolicy
+		claims      map[string]interface{}
+		expectedErr error
+	}{
+		{
+			description: "Empty Policy",
+			claims:      map[string]interface{}{},
+		},
+		{
+			description: "Required Claims Success",
+			policy:      ClaimsPolicy{RequiredClaims: []string{"sub", "jti"}},
+			claims:      map[string]interface{}{"sub": "a", "jti": "b"},
+		},
+		{
+			description: "Missing Required Claim",
+			policy:      ClaimsPolicy{RequiredClaims: []string{"sub", "jti"}},
+			claims:      map[string]interface{}{"sub": "a"},
+			expectedErr: ErrMissingClaim,
+		},
+		{
+			description: "Issuer Success",
+			policy:      ClaimsPolicy{Issuers: []string{"a", "b"}},
+			claims:      map[string]interface{}{"iss": "b"},
+		},
+		{
+			description: "Issuer Not Allowed",
+			policy:      ClaimsPolicy{Issuers: []string{"a", "b"}},
+			claims:      map[string]interface{}{"iss": "c"},
+			expectedErr: ErrIssuerNotAllowed,
+		},
+		{
+			description: "Missing Issuer",
+			policy:      ClaimsPolicy{Issuers: []string{"a"}},
+			claims:      map[string]interface{}{},
+			expectedErr: ErrIssuerNotAllowed,
+		},
+		{
+			description: "String Audience Success",
+			policy:      ClaimsPolicy{Audiences: []string{"svc"}},
+			claims:      map[string]interface{}{"aud": "svc"},
+		},
+		{
+			description: "List Audience Success",
+			policy:      ClaimsPolicy{Audiences: []string{"svc"}},
+			claims:      map[string]interface{}{"aud": []interface{}{"other", "svc"}},
+		},
+		{
+			description: "String List Audience Success",
+			policy:      ClaimsPolicy{Audiences: []string{"svc"}},
+			claims:      map[string]interface{}{"aud": []string{"svc"}},
+		},
+		{
+			description: "Audience Not Allowed",
+			policy:      ClaimsPolicy{Audiences: []string{"svc"}},
+			claims:      map[string]interface{}{"aud": []interface{}{"other", 5}},
+			expectedErr: ErrAudienceNotAllowed,
+		},
+		{
+			description: "Missing Audience",
+			policy:      ClaimsPolicy{Audiences: []string{"svc"}},
+			claims:      map[string]interface{}{},
+			expectedErr: ErrAudienceNotAllowed,
+		},
+		{
+			description: "TTL From IAT Success",
+			policy:      ClaimsPolicy{MaxTTL: time.Hour},
+			claims:      map[string]interface{}{"iat": 0.0, "exp": 3600.0},
+		},
+		{
+			description: "TTL From Now Success",
+			policy:      ClaimsPolicy{MaxTTL: time.Minute},
+			claims:      map[string]interface{}{"exp": json.Number("1060")},
+		},
+		{
+			description: "TTL Within Skew",
+			policy:      ClaimsPolicy{MaxTTL: time.Minute, ClockSkew: 5 * time.Second},
+			claims:      map[string]interface{}{"iat": int64(0), "exp": 65},
+		},
+		{
+			description: "TTL Too Long",
+			policy:      ClaimsPolicy{MaxTTL: time.Minute},
+			claims:      map[string]interface{}{"iat": 0.0, "exp": 61.0},
+			expectedErr: ErrTTLTooLong,
+		},
+		{
+			description: "TTL Missing Exp",
+			policy:      ClaimsPolicy{MaxTTL: time.Minute},
+			claims:      map[string]interface{}{"iat": 0.0},
+			expectedErr: ErrMissingClaim,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.policy.ValidateClaims(tc.claims, now)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+)
+
+// claimsConfig is the JSON form of a bascule.ClaimsPolicy, with durations
+// as strings like "1h".
+type claimsConfig struct {
+	Audiences      []string `json:"audiences"`
+	Issuers        []string `json:"issuers"`
+	Algorithms     []string `json:"algorithms"`
+	RequiredClaims []string `json:"requiredClaims"`
+	MaxTTL         string   `json:"maxTTL"`
+	ClockSkew      string   `json:"clockSkew"`
+}
+
+// checkConfig is the policy the check command tests tokens against.  At most
+// one of Capabilities and CapabilitiesMap should be set.
+type checkConfig struct {
+	Claims          claimsConfig                               `json:"claims"`
+	Capabilities    *basculechecks.CapabilitiesValidatorConfig `json:"capabilities"`
+	CapabilitiesMap *basculechecks.CapabilitiesMapConfig       `json:"capabilitiesMap"`
+}
+
+func loadCheckConfig(path string) (checkConfig, bascule.ClaimsPolicy, error) {
+	var (
+		config checkConfig
+		policy bascule.ClaimsPolicy
+	)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return config, policy, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return config, policy, fmt.Errorf("failed to decode config: %v", err)
+	}
+	if config.Capabilities != nil && config.CapabilitiesMap != nil {
+		return config, policy, errors.New("config can't have both capabilities and capabilitiesMap")
+	}
+	c := config.Claims
+	policy = bascule.ClaimsPolicy{
+		Audiences:      c.Audiences,
+		Issuers:        c.Issuers,
+		Algorithms:     c.Algorithms,
+		RequiredClaims: c.RequiredClaims,
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{name: "maxTTL", value: c.MaxTTL, dest: &policy.MaxTTL},
+		{name: "clockSkew", value: c.ClockSkew, dest: &policy.ClockSkew},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.dest, err = time.ParseDuration(d.value); err != nil {
+			return config, policy, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+	}
+	return config, policy, nil
+}
+
+// loadKey reads a PEM encoded RSA, ECDSA, or Ed25519 public key.
+func loadKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	if k, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseEdPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("key isn't a PEM encoded RSA, ECDSA, or Ed25519 public key")
+}
+
+// result is the outcome of one step of the check.  A step with neither an
+// error nor a skip reason passed.
+type result struct {
+	name   string
+	err    error
+	skip   string
+	detail string
+}
+
+func (r result) String() string {
+	switch {
+	case r.err != nil:
+		return fmt.Sprintf("  fail  %s: %v", r.name, r.err)
+	case r.skip != "":
+		return fmt.Sprintf("  skip  %s: %s", r.name, r.skip)
+	case r.detail != "":
+		return fmt.Sprintf("  pass  %s: %s", r.name, r.detail)
+	}
+	return fmt.Sprintf("  pass  %s", r.name)
+}
+
+func runCheck(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var (
+		configPath = fs.String("config", "", "the JSON policy config file")
+		keyPath    = fs.String("key", "", "a PEM public key to verify the signature with; without it, the signature isn't checked")
+		method     = fs.String("method", "GET", "the request method")
+		rawURL     = fs.String("url", "/", "the request URL or path")
+		at         = fs.String("at", "", "the RFC 3339 time to check the token at; defaults to now")
+	)
+	if err := fs.Parse(args); err != nil {
+		return exitError, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if *configPath == "" {
+		return exitError, fmt.Errorf("%w: -config is required", errUsage)
+	}
+	now, err := parseTime(*at)
+	if err != nil {
+		return exitError, err
+	}
+	u, err := url.Parse(*rawURL)
+	if err != nil {
+		return exitError, fmt.Errorf("%w: invalid -url: %v", errUsage, err)
+	}
+	config, policy, err := loadCheckConfig(*configPath)
+	if err != nil {
+		return exitError, err
+	}
+	var key crypto.PublicKey
+	if *keyPath != "" {
+		if key, err = loadKey(*keyPath); err != nil {
+			return exitError, err
+		}
+	}
+	raw, err := readToken(fs.Args(), stdin)
+	if err != nil {
+		return exitError, err
+	}
+	token, claims, err := parseUnverified(raw)
+	if err != nil {
+		return exitError, err
+	}
+
+	results := []result{
+		checkSignature(raw, key),
+		{name: "algorithm", err: policy.ValidateAlgorithm(token.Method.Alg()), detail: token.Method.Alg()},
+		checkTimes(claims, policy, now),
+		{name: "claims", err: policy.ValidateClaims(claims, now)},
+	}
+	capability, d := checkCapabilities(config, claims, strings.ToUpper(*method), u)
+	results = append(results, capability)
+
+	code := exitAllowed
+	decision := "allow"
+	for _, r := range results {
+		if r.err != nil {
+			code = exitDenied
+			decision = "deny"
+		}
+	}
+	fmt.Fprintf(stdout, "decision: %s\n", decision)
+	for _, r := range results {
+		fmt.Fprintln(stdout, r)
+	}
+	if d != nil {
+		for _, field := range []struct{ name, value string }{
+			{name: "capability", value: d.Capability()},
+			{name: "partner", value: d.Partner()},
+			{name: "endpoint", value: d.Endpoint()},
+		} {
+			if field.value != "" {
+				fmt.Fprintf(stdout, "%s: %s\n", field.name, field.value)
+			}
+		}
+	}
+	return code, nil
+}
+
+func checkSignature(raw string, key crypto.PublicKey) result {
+	r := result{name: "signature"}
+	if key == nil {
+		r.skip = "no key given"
+		return r
+	}
+	p := jwt.Parser{SkipClaimsValidation: true}
+	_, err := p.Parse(raw, func(*jwt.Token) (interface{}, error) {
+		return key, nil
+	})
+	r.err = err
+	return r
+}
+
+// checkTimes checks exp, nbf, and iat at the time given, allowing for the
+// policy's clock skew.
+func checkTimes(claims jwt.MapClaims, policy bascule.ClaimsPolicy, now time.Time) result {
+	r := result{name: "time claims"}
+	leeway := policy.Leeway()
+	n := now.Unix()
+	var problems []string
+	if !claims.VerifyExpiresAt(n-leeway.EXP, false) {
+		problems = append(problems, "token is expired")
+	}
+	if !claims.VerifyNotBefore(n+leeway.NBF, false) {
+		problems = append(problems, "token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(n+leeway.IAT, false) {
+		problems = append(problems, "token used before issued")
+	}
+	if len(problems) > 0 {
+		r.err = errors.New(strings.Join(problems, ", "))
+	}
+	return r
+}
+
+// checkCapabilities runs the token through a MetricValidator built from the
+// capability config, as a service using the config would, returning the
+// bascule.Decision it made.
+func checkCapabilities(config checkConfig, claims jwt.MapClaims, method string, u *url.URL) (result, *bascule.Decision) {
+	r := result{name: "capabilities"}
+	var (
+		out basculechecks.CapabilitiesCheckerOut
+		err error
+	)
+	switch {
+	case config.Capabilities != nil:
+		c := *config.Capabilities
+		if c.Type == "monitor" {
+			r.detail = "monitor only in the config, checked as enforced"
+		}
+		c.Type = "enforce"
+		out, err = basculechecks.NewCapabilitiesValidator(c)
+	case config.CapabilitiesMap != nil:
+		out, err = basculechecks.NewCapabilitiesMap(*config.CapabilitiesMap)
+	default:
+		r.skip = "no capability config"
+		return r, nil
+	}
+	if err != nil {
+		r.err = err
+		return r, nil
+	}
+	measures := &basculechecks.AuthCapabilityCheckMeasures{
+		CapabilityCheckOutcome: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: basculechecks.AuthCapabilityCheckOutcome,
+		}, []string{basculechecks.ServerLabel, basculechecks.OutcomeLabel, basculechecks.ReasonLabel,
+			basculechecks.ClientIDLabel, basculechecks.PartnerIDLabel, basculechecks.EndpointLabel,
+			basculechecks.MethodLabel}),
+	}
+	v, err := basculechecks.NewMetricValidator(out.Checker, measures, out.Options...)
+	if err != nil {
+		r.err = err
+		return r, nil
+	}
+
+	principal, _ := claims["sub"].(string)
+	token := bascule.NewToken("jwt", principal, bascule.NewAttributes(claims))
+	d := bascule.NewDecision()
+	ctx := bascule.WithDecision(context.Background(), d)
+	ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+		Authorization: bascule.Authorization("Bearer"),
+		Token:         token,
+		Request:       bascule.Request{URL: u, Method: method},
+	})
+	r.err = v.Check(ctx, token)
+	return r, d
+}
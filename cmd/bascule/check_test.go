@@ -0,0 +1,201 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCapabilitiesConfig = `{
+		"claims": {"issuers": ["issuer"], "algorithms": ["RS256"], "clockSkew": "30s"},
+		"capabilities": {
+			"type": "monitor",
+			"prefix": "x1:webpa:api:",
+			"acceptAllMethod": "all",
+			"endpointBuckets": ["/things", "/other"]
+		}
+	}`
+	testCapabilitiesMapConfig = `{
+		"capabilitiesMap": {"endpoints": {"/things": "read-things"}, "default": "admin"}
+	}`
+)
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyPath := writeFile(t, dir, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	configPath := writeFile(t, dir, "config.json", testCapabilitiesConfig)
+	mapConfigPath := writeFile(t, dir, "map.json", testCapabilitiesMapConfig)
+
+	claims := jwt.MapClaims{
+		"sub":          "alice",
+		"iss":          "issuer",
+		"iat":          float64(1700000000),
+		"exp":          float64(1700000300),
+		"capabilities": []interface{}{"x1:webpa:api:/things:get", "read-things"},
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []interface{}{"comcast"},
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	require.NoError(t, err)
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(otherKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		description  string
+		args         []string
+		token        string
+		expectedCode int
+		expectedOut  []string
+		expectedErr  string
+	}{
+		{
+			description:  "Allowed",
+			args:         []string{"-config", configPath, "-key", keyPath, "-url", "/things/1", "-at", "2023-11-14T22:15:00Z"},
+			token:        token,
+			expectedCode: exitAllowed,
+			expectedOut: []string{
+				"decision: allow",
+				"  pass  signature\n",
+				"  pass  algorithm: RS256",
+				"  pass  capabilities: monitor only in the config, checked as enforced",
+				"capability: x1:webpa:api:/things:get",
+				"partner: comcast",
+				"endpoint: /things",
+			},
+		},
+		{
+			description:  "Wrong Method",
+			args:         []string{"-config", configPath, "-key", keyPath, "-method", "delete", "-url", "/things/1", "-at", "2023-11-14T22:15:00Z"},
+			token:        token,
+			expectedCode: exitDenied,
+			expectedOut:  []string{"decision: deny", "  fail  capabilities:"},
+		},
+		{
+			description:  "Expired Within Skew",
+			args:         []string{"-config", configPath, "-url", "/things", "-at", "2023-11-14T22:18:40Z"},
+			token:        token,
+			expectedCode: exitAllowed,
+			expectedOut:  []string{"decision: allow", "  skip  signature: no key given"},
+		},
+		{
+			description:  "Expired",
+			args:         []string{"-config", configPath, "-url", "/things", "-at", "2023-11-14T22:20:00Z"},
+			token:        token,
+			expectedCode: exitDenied,
+			expectedOut:  []string{"decision: deny", "  fail  time claims: token is expired"},
+		},
+		{
+			description:  "Forged",
+			args:         []string{"-config", configPath, "-key", keyPath, "-url", "/things", "-at", "2023-11-14T22:15:00Z"},
+			token:        forged,
+			expectedCode: exitDenied,
+			expectedOut:  []string{"decision: deny", "  fail  signature:"},
+		},
+		{
+			description:  "Capabilities Map",
+			args:         []string{"-config", mapConfigPath, "-url", "/things", "-at", "2023-11-14T22:15:00Z"},
+			token:        "Bearer " + token,
+			expectedCode: exitAllowed,
+			expectedOut:  []string{"decision: allow", "capability: read-things"},
+		},
+		{
+			description:  "Capabilities Map Default",
+			args:         []string{"-config", mapConfigPath, "-url", "/admin", "-at", "2023-11-14T22:15:00Z"},
+			token:        token,
+			expectedCode: exitDenied,
+			expectedOut:  []string{"decision: deny"},
+		},
+		{
+			description:  "Missing Config",
+			token:        token,
+			expectedCode: exitError,
+			expectedErr:  "-config is required",
+		},
+		{
+			description:  "Unreadable Config",
+			args:         []string{"-config", filepath.Join(dir, "missing.json")},
+			token:        token,
+			expectedCode: exitError,
+			expectedErr:  "failed to read config",
+		},
+		{
+			description:  "Bad Key",
+			args:         []string{"-config", configPath, "-key", configPath},
+			token:        token,
+			expectedCode: exitError,
+			expectedErr:  "key isn't a PEM encoded",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"check"}, tc.args...)
+			code := run(args, strings.NewReader(tc.token), &stdout, &stderr)
+			assert.Equal(tc.expectedCode, code, stdout.String()+stderr.String())
+			for _, o := range tc.expectedOut {
+				assert.Contains(stdout.String(), o)
+			}
+			assert.Contains(stderr.String(), tc.expectedErr)
+		})
+	}
+}
+
+func TestLoadCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		description string
+		contents    string
+	}{
+		{description: "Not JSON", contents: "{"},
+		{description: "Both Capability Configs", contents: `{"capabilities": {}, "capabilitiesMap": {}}`},
+		{description: "Bad Duration", contents: `{"claims": {"maxTTL": "forever"}}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			_, _, err := loadCheckConfig(writeFile(t, dir, "config.json", tc.contents))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+func runDecode(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	at := fs.String("at", "", "the RFC 3339 time to describe the time claims relative to; defaults to now")
+	if err := fs.Parse(args); err != nil {
+		return exitError, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	now, err := parseTime(*at)
+	if err != nil {
+		return exitError, err
+	}
+	raw, err := readToken(fs.Args(), stdin)
+	if err != nil {
+		return exitError, err
+	}
+	token, claims, err := parseUnverified(raw)
+	if err != nil {
+		return exitError, err
+	}
+
+	if err := printJSON(stdout, "header", token.Header); err != nil {
+		return exitError, err
+	}
+	if err := printJSON(stdout, "claims", claims); err != nil {
+		return exitError, err
+	}
+	for _, name := range timeClaims {
+		if t, ok := claimTime(claims, name); ok {
+			fmt.Fprintf(stdout, "%s: %s (%s)\n", name, t.UTC().Format(time.RFC3339), relative(t, now))
+		}
+	}
+	return exitAllowed, nil
+}
+
+// parseUnverified decodes the token without checking its signature.
+func parseUnverified(raw string) (*jwt.Token, jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, _, err := new(jwt.Parser).ParseUnverified(raw, claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode token: %v", err)
+	}
+	return token, claims, nil
+}
+
+func printJSON(w io.Writer, name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", name, err)
+	}
+	fmt.Fprintf(w, "%s: %s\n", name, b)
+	return nil
+}
+
+// claimTime returns the time in a NumericDate claim.
+func claimTime(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+// relative describes when t is compared to now.
+func relative(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	if d < 0 {
+		return fmt.Sprintf("%v ago", -d)
+	}
+	return fmt.Sprintf("in %v", d)
+}
@@ -0,0 +1,53 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice",
+		"iat": float64(1700000000),
+		"exp": float64(1700000300),
+	}).SignedString([]byte("secret"))
+	require.NoError(err)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"decode", "-at", "2023-11-14T22:15:00Z", raw}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(exitAllowed, code, stderr.String())
+	out := stdout.String()
+	assert.Contains(out, `"alg": "HS256"`)
+	assert.Contains(out, `"sub": "alice"`)
+	assert.Contains(out, "iat: 2023-11-14T22:13:20Z (1m40s ago)")
+	assert.Contains(out, "exp: 2023-11-14T22:18:20Z (in 3m20s)")
+
+	stdout.Reset()
+	code = run([]string{"decode"}, strings.NewReader("not a token"), &stdout, &stderr)
+	assert.Equal(exitError, code)
+	assert.Contains(stderr.String(), "failed to decode token")
+}
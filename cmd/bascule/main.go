@@ -0,0 +1,133 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+The bascule command inspects JWTs and tests them against bascule policies
+offline, for debugging policies and for testing them in CI.
+
+Usage:
+
+	bascule decode [-at time] [token]
+	bascule check -config file [-key file] [-method method] [-url url] [-at time] [token]
+
+The token is read from standard input if it isn't given or is "-".  A
+"Bearer " prefix is ignored.
+
+decode prints the token's header and claims without verifying it.
+
+check runs the token through the claims policy and capability check in the
+JSON config file and prints whether the request would be allowed, and why.  The
+exit code is 0 if the request is allowed, 1 if it's denied, and 2 if the
+command couldn't run.
+*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exit codes.
+const (
+	exitAllowed = 0
+	exitDenied  = 1
+	exitError   = 2
+)
+
+const usage = `usage:
+  bascule decode [-at time] [token]
+  bascule check -config file [-key file] [-method method] [-url url] [-at time] [token]
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand in args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	var (
+		code int
+		err  error
+	)
+	switch args[0] {
+	case "decode":
+		code, err = runDecode(args[1:], stdin, stdout)
+	case "check":
+		code, err = runCheck(args[1:], stdin, stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitAllowed
+	default:
+		err = fmt.Errorf("%w: unknown command [%v]", errUsage, args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "bascule %s\n", err)
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(stderr, usage)
+		}
+		return exitError
+	}
+	return code
+}
+
+// readToken returns the token given as an argument, or read from stdin.
+func readToken(args []string, stdin io.Reader) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("%w: too many arguments", errUsage)
+	}
+	var raw string
+	if len(args) == 1 && args[0] != "-" {
+		raw = args[0]
+	} else {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read token: %v", err)
+		}
+		raw = line
+	}
+	raw = strings.TrimSpace(raw)
+	if len(raw) > 7 && strings.EqualFold(raw[:7], "bearer ") {
+		raw = strings.TrimSpace(raw[7:])
+	}
+	if raw == "" {
+		return "", fmt.Errorf("%w: no token given", errUsage)
+	}
+	return raw, nil
+}
+
+// parseTime parses the -at flag, defaulting to now.
+func parseTime(at string) (time.Time, error) {
+	if at == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: -at must be an RFC 3339 time: %v", errUsage, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		description  string
+		args         []string
+		expectedCode int
+		expectedOut  string
+		expectedErr  string
+	}{
+		{
+			description:  "No Command",
+			expectedCode: exitError,
+			expectedErr:  "usage:",
+		},
+		{
+			description:  "Unknown Command",
+			args:         []string{"sign"},
+			expectedCode: exitError,
+			expectedErr:  "unknown command [sign]",
+		},
+		{
+			description:  "Help",
+			args:         []string{"help"},
+			expectedCode: exitAllowed,
+			expectedOut:  "usage:",
+		},
+		{
+			description:  "Bad Flag",
+			args:         []string{"decode", "-nope"},
+			expectedCode: exitError,
+			expectedErr:  "usage:",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var stdout, stderr bytes.Buffer
+			code := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			assert.Equal(tc.expectedCode, code)
+			assert.Contains(stdout.String(), tc.expectedOut)
+			assert.Contains(stderr.String(), tc.expectedErr)
+		})
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	tests := []struct {
+		description   string
+		args          []string
+		stdin         string
+		expectedToken string
+		expectedErr   error
+	}{
+		{
+			description:   "Argument",
+			args:          []string{"a.b.c"},
+			expectedToken: "a.b.c",
+		},
+		{
+			description:   "Stdin",
+			stdin:         "  Bearer a.b.c\nignored",
+			expectedToken: "a.b.c",
+		},
+		{
+			description:   "Dash",
+			args:          []string{"-"},
+			stdin:         "bearer a.b.c",
+			expectedToken: "a.b.c",
+		},
+		{
+			description: "Empty",
+			expectedErr: errUsage,
+		},
+		{
+			description: "Too Many",
+			args:        []string{"a", "b"},
+			expectedErr: errUsage,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			token, err := readToken(tc.args, strings.NewReader(tc.stdin))
+			assert.Equal(tc.expectedToken, token)
+			assert.ErrorIs(err, tc.expectedErr)
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	assert := assert.New(t)
+	at, err := parseTime("2023-11-14T22:13:20Z")
+	assert.NoError(err)
+	assert.Equal(int64(1700000000), at.Unix())
+	_, err = parseTime("yesterday")
+	assert.ErrorIs(err, errUsage)
+	_, err = parseTime("")
+	assert.NoError(err)
+}
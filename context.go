@@ -20,6 +20,7 @@ package bascule
 import (
 	"context"
 	"net/url"
+	"time"
 )
 
 // Authorization represents the authorization mechanism performed on the token,
@@ -38,6 +39,32 @@ type Authentication struct {
 type Request struct {
 	URL    *url.URL
 	Method string
+
+	// ClientIP is the IP address the request came from, if it was captured.
+	ClientIP string
+
+	// UserAgent is the request's User-Agent header.
+	UserAgent string
+
+	// Time is when the request was received, if it was captured.
+	Time time.Time
+
+	// Digest is the digest of the request body, if it was captured.
+	Digest *Digest
+
+	// RequestID is the request's correlation ID, if one was extracted.
+	RequestID string
+}
+
+// Digest is the digest of a request body, so that validators checking
+// signatures don't have to read the body again.
+type Digest struct {
+	// Algorithm is the hash used, named as in the HTTP Digest Algorithm
+	// Values registry, e.g. "sha-256".
+	Algorithm string
+
+	// Sum is the hash of the body.
+	Sum []byte
 }
 
 type authenticationKey struct{}
@@ -53,3 +80,77 @@ func FromContext(ctx context.Context) (Authentication, bool) {
 	auth, ok := ctx.Value(authenticationKey{}).(Authentication)
 	return auth, ok
 }
+
+type requestIDKey struct{}
+
+// WithRequestID adds the request's correlation ID to the context given, so
+// that logs and audit records of the auth decision can be traced back to the
+// request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext gets the request's correlation ID from the context
+// provided.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// detachedContext keeps the values of its parent but not its deadline or
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// Detach returns a context with the values of the context given, including
+// its Authentication, but which is never canceled and has no deadline.  Use
+// it for work that outlives the request, like background goroutines, so
+// that the work keeps the request's identity without being canceled when the
+// request finishes.
+func Detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return detachedContext{parent: ctx}
+}
+
+// CopyAuth returns the dst context with the Authentication, request ID, and
+// Decision of the src context, if it has them.  The rest of src, including
+// its cancellation, isn't carried over.  Use it to hand the request's
+// identity to work that runs under a context of its own, like a worker
+// pool's.
+func CopyAuth(dst, src context.Context) context.Context {
+	if dst == nil {
+		dst = context.Background()
+	}
+	if src == nil {
+		return dst
+	}
+	if auth, ok := FromContext(src); ok {
+		dst = WithAuthentication(dst, auth)
+	}
+	if id, ok := RequestIDFromContext(src); ok {
+		dst = WithRequestID(dst, id)
+	}
+	if d, ok := DecisionFromContext(src); ok {
+		dst = WithDecision(dst, d)
+	}
+	return dst
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -48,3 +49,104 @@ func TestContext(t *testing.T) {
 	assert.True(ok)
 	assert.Equal(expectedAuth, auth)
 }
+
+func TestRequestIDContext(t *testing.T) {
+	assert := assert.New(t)
+	_, ok := RequestIDFromContext(context.Background())
+	assert.False(ok)
+
+	_, ok = RequestIDFromContext(WithRequestID(context.Background(), ""))
+	assert.False(ok)
+
+	id, ok := RequestIDFromContext(WithRequestID(context.Background(), "abc-123"))
+	assert.True(ok)
+	assert.Equal("abc-123", id)
+}
+
+func TestDetach(t *testing.T) {
+	assert := assert.New(t)
+	auth := Authentication{Authorization: "Bearer", Token: NewToken("jwt", "alice", nil)}
+	parent, cancel := context.WithTimeout(WithAuthentication(context.Background(), auth), time.Hour)
+	parent = WithRequestID(parent, "abc-123")
+	cancel()
+
+	ctx := Detach(parent)
+	assert.NoError(ctx.Err())
+	assert.Nil(ctx.Done())
+	_, ok := ctx.Deadline()
+	assert.False(ok)
+
+	actual, ok := FromContext(ctx)
+	assert.True(ok)
+	assert.Equal(auth, actual)
+	id, ok := RequestIDFromContext(ctx)
+	assert.True(ok)
+	assert.Equal("abc-123", id)
+
+	assert.Equal(context.Background(), Detach(nil)) //nolint:staticcheck // nil contexts are handled
+}
+
+func TestCopyAuth(t *testing.T) {
+	auth := Authentication{Authorization: "Bearer", Token: NewToken("jwt", "alice", nil)}
+	decision := new(Decision)
+	src, cancel := context.WithCancel(context.Background())
+	src = WithDecision(WithRequestID(WithAuthentication(src, auth), "abc-123"), decision)
+	cancel()
+
+	tests := []struct {
+		description      string
+		dst              context.Context
+		src              context.Context
+		expectedAuth     bool
+		expectedID       bool
+		expectedDecision bool
+	}{
+		{
+			description:      "Success",
+			dst:              context.Background(),
+			src:              src,
+			expectedAuth:     true,
+			expectedID:       true,
+			expectedDecision: true,
+		},
+		{
+			description:      "Nil Destination",
+			src:              src,
+			expectedAuth:     true,
+			expectedID:       true,
+			expectedDecision: true,
+		},
+		{
+			description: "Empty Source",
+			dst:         context.Background(),
+			src:         context.Background(),
+		},
+		{
+			description: "Nil Source",
+			dst:         context.Background(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := CopyAuth(tc.dst, tc.src)
+			assert.NoError(ctx.Err())
+
+			actual, ok := FromContext(ctx)
+			assert.Equal(tc.expectedAuth, ok)
+			if tc.expectedAuth {
+				assert.Equal(auth, actual)
+			}
+			id, ok := RequestIDFromContext(ctx)
+			assert.Equal(tc.expectedID, ok)
+			if tc.expectedID {
+				assert.Equal("abc-123", id)
+			}
+			d, ok := DecisionFromContext(ctx)
+			assert.Equal(tc.expectedDecision, ok)
+			if tc.expectedDecision {
+				assert.Same(decision, d)
+			}
+		})
+	}
+}
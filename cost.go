@@ -0,0 +1,92 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"sort"
+)
+
+// CostOf returns how expensive the Validator is to run: its Cost if it is a
+// CostedValidator, the total cost of its members if it is a Validators, and
+// zero otherwise.
+func CostOf(v Validator) int {
+	switch t := v.(type) {
+	case CostedValidator:
+		return t.Cost()
+	case Validators:
+		total := 0
+		for _, m := range t {
+			total += CostOf(m)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// flatten appends the validators given to the list, replacing nested
+// Validators with their members and dropping nils.
+func flatten(list Validators, vs ...Validator) Validators {
+	for _, v := range vs {
+		switch t := v.(type) {
+		case nil:
+		case Validators:
+			list = flatten(list, t...)
+		default:
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// ByCost returns the validators sorted by increasing cost, so that cheap
+// checks run before expensive ones such as remote introspection.  Nested
+// Validators are flattened so that their members are sorted too.
+// Validators with the same cost keep their order.
+func ByCost(vs ...Validator) Validators {
+	sorted := flatten(nil, vs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return CostOf(sorted[i]) < CostOf(sorted[j])
+	})
+	return sorted
+}
+
+// ShortCircuit returns a Validator that runs the validators in order of
+// increasing cost.  Like Validators, it runs all of them to give a complete
+// picture of a failure, except that once one has failed, the validators
+// costing more than maxCost are skipped: a request already failing a cheap
+// check isn't worth the latency and dependency load of an expensive one.
+func ShortCircuit(maxCost int, vs ...Validator) Validator {
+	sorted := ByCost(vs...)
+	return ValidatorFunc(func(ctx context.Context, t Token) error {
+		var all Errors
+		for _, v := range sorted {
+			if len(all) > 0 && CostOf(v) > maxCost {
+				break
+			}
+			if err := v.Check(ctx, t); err != nil {
+				all = append(all, err)
+			}
+		}
+		if len(all) > 0 {
+			return all
+		}
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingValidator struct {
+	name string
+	err  error
+	ran  *[]string
+}
+
+func (r recordingValidator) Check(context.Context, Token) error {
+	*r.ran = append(*r.ran, r.name)
+	return r.err
+}
+
+func TestCostOf(t *testing.T) {
+	assert := assert.New(t)
+	free := ValidatorFunc(func(context.Context, Token) error { return nil })
+	assert.Zero(CostOf(free))
+	assert.Equal(5, CostOf(WithCost(free, 5)))
+	assert.Equal(8, CostOf(Validators{WithCost(free, 5), free, Validators{WithCost(free, 3)}}))
+}
+
+func TestByCost(t *testing.T) {
+	var ran []string
+	v := func(name string, cost int) Validator {
+		r := recordingValidator{name: name, ran: &ran}
+		if cost == 0 {
+			return r
+		}
+		return WithCost(r, cost)
+	}
+	sorted := ByCost(v("opa", 50), nil, Validators{v("introspect", 100), v("issuer", 0)}, v("type", 0), v("scope", 1))
+	assert.NoError(t, sorted.Check(context.Background(), nil))
+	assert.Equal(t, []string{"issuer", "type", "scope", "opa", "introspect"}, ran)
+}
+
+func TestShortCircuit(t *testing.T) {
+	badIssuer := errors.New("bad issuer")
+	denied := errors.New("denied")
+	tests := []struct {
+		description string
+		issuerErr   error
+		typeErr     error
+		opaErr      error
+		maxCost     int
+		expectedRan []string
+		expectedErr []error
+	}{
+		{
+			description: "Success",
+			maxCost:     10,
+			expectedRan: []string{"issuer", "type", "opa", "introspect"},
+		},
+		{
+			description: "Cheap Failure Skips Expensive",
+			issuerErr:   badIssuer,
+			maxCost:     10,
+			expectedRan: []string{"issuer", "type"},
+			expectedErr: []error{badIssuer},
+		},
+		{
+			description: "All Cheap Failures Reported",
+			issuerErr:   badIssuer,
+			typeErr:     denied,
+			maxCost:     10,
+			expectedRan: []string{"issuer", "type"},
+			expectedErr: []error{badIssuer, denied},
+		},
+		{
+			description: "Expensive Failure Skips More Expensive",
+			opaErr:      denied,
+			maxCost:     10,
+			expectedRan: []string{"issuer", "type", "opa"},
+			expectedErr: []error{denied},
+		},
+		{
+			description: "High Max Cost Runs Everything",
+			issuerErr:   badIssuer,
+			maxCost:     1000,
+			expectedRan: []string{"issuer", "type", "opa", "introspect"},
+			expectedErr: []error{badIssuer},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var ran []string
+			v := ShortCircuit(tc.maxCost,
+				WithCost(recordingValidator{name: "introspect", ran: &ran}, 100),
+				WithCost(recordingValidator{name: "opa", err: tc.opaErr, ran: &ran}, 50),
+				recordingValidator{name: "issuer", err: tc.issuerErr, ran: &ran},
+				WithCost(recordingValidator{name: "type", err: tc.typeErr, ran: &ran}, 1),
+			)
+			err := v.Check(context.Background(), nil)
+			assert.Equal(tc.expectedRan, ran)
+			if len(tc.expectedErr) == 0 {
+				assert.NoError(err)
+				return
+			}
+			var errs Errors
+			assert.True(errors.As(err, &errs))
+			assert.Equal(Errors(tc.expectedErr), errs)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"sync"
+)
+
+// Decision records how a request was authorized, so that handlers can branch
+// on the details: which named rules passed, which capability matched, and the
+// partner and endpoint buckets the request fell into.  Rules add to the
+// Decision in the context as they pass or fail.  It is safe for concurrent
+// use.
+type Decision struct {
+	lock       sync.Mutex
+	rules      []string
+	failed     []string
+	capability string
+	partner    string
+	endpoint   string
+}
+
+// NewDecision creates an empty Decision.
+func NewDecision() *Decision {
+	return &Decision{}
+}
+
+// AddRule records that the named rule passed.
+func (d *Decision) AddRule(name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.rules = append(d.rules, name)
+}
+
+// Rules returns the names of the rules that passed, in the order they passed.
+func (d *Decision) Rules() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]string(nil), d.rules...)
+}
+
+// Passed returns true if the named rule passed.
+func (d *Decision) Passed(name string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, r := range d.rules {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFailure records that the named rule failed.
+func (d *Decision) AddFailure(name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.failed = append(d.failed, name)
+}
+
+// Failed returns the names of the rules that failed, in the order they failed.
+func (d *Decision) Failed() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]string(nil), d.failed...)
+}
+
+// SetCapability records the capability that authorized the request.
+func (d *Decision) SetCapability(capability string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.capability = capability
+}
+
+// Capability returns the capability that authorized the request, or an empty
+// string if no capability was checked.
+func (d *Decision) Capability() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.capability
+}
+
+// SetBuckets records the partner and endpoint buckets of the request.
+func (d *Decision) SetBuckets(partner, endpoint string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.partner = partner
+	d.endpoint = endpoint
+}
+
+// Partner returns the partner bucket of the request, or an empty string if it
+// wasn't determined.
+func (d *Decision) Partner() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.partner
+}
+
+// Endpoint returns the endpoint bucket of the request, or an empty string if
+// it wasn't determined.
+func (d *Decision) Endpoint() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.endpoint
+}
+
+type decisionKey struct{}
+
+// WithDecision adds the Decision given to the context.
+func WithDecision(ctx context.Context, d *Decision) context.Context {
+	return context.WithValue(ctx, decisionKey{}, d)
+}
+
+// DecisionFromContext gets the Decision from the context provided.  Handlers
+// behind the enforcer can use it to see how their request was authorized.
+func DecisionFromContext(ctx context.Context) (*Decision, bool) {
+	d, ok := ctx.Value(decisionKey{}).(*Decision)
+	return d, ok && d != nil
+}
+
+// NamedValidator is a Validator with a rule name, used to report which rules
+// passed or rejected a request.
+type NamedValidator interface {
+	Validator
+	Name() string
+}
+
+type namedValidator struct {
+	name string
+	v    Validator
+}
+
+// Named wraps a Validator so that its name is added to the Decision in the
+// context, if there is one, as a passed or failed rule.
+func Named(name string, v Validator) NamedValidator {
+	return namedValidator{name: name, v: v}
+}
+
+func (n namedValidator) Name() string {
+	return n.name
+}
+
+func (n namedValidator) Check(ctx context.Context, t Token) error {
+	err := n.v.Check(ctx, t)
+	if d, ok := DecisionFromContext(ctx); ok {
+		if err != nil {
+			d.AddFailure(n.name)
+		} else {
+			d.AddRule(n.name)
+		}
+	}
+	return err
+}
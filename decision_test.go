@@ -0,0 +1,90 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecision(t *testing.T) {
+	assert := assert.New(t)
+	d := NewDecision()
+	assert.Empty(d.Rules())
+	assert.Empty(d.Failed())
+	assert.False(d.Passed("a"))
+	assert.Empty(d.Capability())
+	assert.Empty(d.Partner())
+	assert.Empty(d.Endpoint())
+
+	d.AddRule("a")
+	d.AddRule("b")
+	d.SetCapability("x:y:all")
+	d.SetBuckets("comcast", "devices")
+	rules := d.Rules()
+	assert.Equal([]string{"a", "b"}, rules)
+	rules[0] = "changed"
+	assert.Equal([]string{"a", "b"}, d.Rules())
+	assert.True(d.Passed("b"))
+	assert.Empty(d.Failed())
+
+	d.AddFailure("c")
+	failed := d.Failed()
+	assert.Equal([]string{"c"}, failed)
+	failed[0] = "changed"
+	assert.Equal([]string{"c"}, d.Failed())
+	assert.False(d.Passed("c"))
+	assert.Equal("x:y:all", d.Capability())
+	assert.Equal("comcast", d.Partner())
+	assert.Equal("devices", d.Endpoint())
+}
+
+func TestDecisionContext(t *testing.T) {
+	assert := assert.New(t)
+	_, ok := DecisionFromContext(context.Background())
+	assert.False(ok)
+	_, ok = DecisionFromContext(WithDecision(context.Background(), nil))
+	assert.False(ok)
+
+	d := NewDecision()
+	actual, ok := DecisionFromContext(WithDecision(context.Background(), d))
+	assert.True(ok)
+	assert.Equal(d, actual)
+}
+
+func TestNamed(t *testing.T) {
+	assert := assert.New(t)
+	testErr := errors.New("test")
+	pass := Named("pass", ValidatorFunc(func(context.Context, Token) error { return nil }))
+	fail := Named("fail", ValidatorFunc(func(context.Context, Token) error { return testErr }))
+
+	d := NewDecision()
+	ctx := WithDecision(context.Background(), d)
+	assert.NoError(pass.Check(ctx, nil))
+	assert.ErrorIs(fail.Check(ctx, nil), testErr)
+	assert.Equal([]string{"pass"}, d.Rules())
+	assert.Equal([]string{"fail"}, d.Failed())
+	assert.Equal("pass", pass.Name())
+	assert.Equal("fail", fail.Name())
+
+	assert.NoError(pass.Check(context.Background(), nil))
+	assert.ErrorIs(fail.Check(context.Background(), nil), testErr)
+}
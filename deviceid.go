@@ -0,0 +1,83 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Device ID schemes understood by NormalizeDeviceID.
+const (
+	MacScheme    = "mac"
+	UUIDScheme   = "uuid"
+	SerialScheme = "serial"
+	DNSScheme    = "dns"
+)
+
+const macLength = 12
+
+var ErrInvalidDeviceID = errors.New("invalid device id")
+
+// NormalizeDeviceID converts a device id of the form <scheme>:<value> into its
+// canonical form.  The scheme is lowercased, and mac addresses are stripped of
+// separators and lowercased, so "MAC:11-22-33-AA-BB-CC" becomes
+// "mac:112233aabbcc".
+func NormalizeDeviceID(id string) (string, error) {
+	i := strings.IndexByte(id, ':')
+	if i <= 0 || i == len(id)-1 {
+		return "", fmt.Errorf("%w: [%v]", ErrInvalidDeviceID, id)
+	}
+	scheme := strings.ToLower(id[:i])
+	value := id[i+1:]
+	switch scheme {
+	case MacScheme:
+		mac, err := normalizeMac(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: [%v]: %v", ErrInvalidDeviceID, id, err)
+		}
+		value = mac
+	case UUIDScheme, DNSScheme:
+		value = strings.ToLower(value)
+	case SerialScheme:
+	default:
+		return "", fmt.Errorf("%w: unknown scheme [%v]", ErrInvalidDeviceID, scheme)
+	}
+	return scheme + ":" + value, nil
+}
+
+func normalizeMac(value string) (string, error) {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'F':
+			b.WriteRune(r + ('a' - 'A'))
+		case r == ':', r == '-', r == '.', r == ',':
+			// separators are dropped.
+		default:
+			return "", fmt.Errorf("invalid character %q", r)
+		}
+	}
+	if b.Len() != macLength {
+		return "", fmt.Errorf("expected %d hex digits", macLength)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,52 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeDeviceID(t *testing.T) {
+	tests := []struct {
+		id          string
+		expectedID  string
+		expectedErr error
+	}{
+		{id: "mac:112233445566", expectedID: "mac:112233445566"},
+		{id: "MAC:11-22-33-AA-BB-CC", expectedID: "mac:112233aabbcc"},
+		{id: "mac:11:22:33:aa:bb:cc", expectedID: "mac:112233aabbcc"},
+		{id: "uuid:ABC-123", expectedID: "uuid:abc-123"},
+		{id: "Serial:ABC123", expectedID: "serial:ABC123"},
+		{id: "dns:Device.Example.com", expectedID: "dns:device.example.com"},
+		{id: "mac:1122334455", expectedErr: ErrInvalidDeviceID},
+		{id: "mac:11223344556z", expectedErr: ErrInvalidDeviceID},
+		{id: "imei:123", expectedErr: ErrInvalidDeviceID},
+		{id: "mac:", expectedErr: ErrInvalidDeviceID},
+		{id: "112233445566", expectedErr: ErrInvalidDeviceID},
+	}
+	for _, tc := range tests {
+		t.Run(tc.id, func(t *testing.T) {
+			assert := assert.New(t)
+			id, err := NormalizeDeviceID(tc.id)
+			assert.Equal(tc.expectedID, id)
+			assert.ErrorIs(err, tc.expectedErr)
+		})
+	}
+}
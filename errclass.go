@@ -0,0 +1,147 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import "errors"
+
+// ErrorClass is the kind of failure an error represents, independent of the
+// package it came from.  It decides things like the HTTP status written.
+type ErrorClass int
+
+const (
+	UnknownClass ErrorClass = iota
+	MissingCredentialsClass
+	MalformedClass
+	KeyClass
+	InvalidClass
+	ExpiredClass
+	CapabilityClass
+	PartnerClass
+	RateLimitedClass
+	UnavailableClass
+)
+
+// Sentinel errors for each class.  errors.Is matches an error to the sentinel
+// of its class.
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrMalformed          = errors.New("malformed credentials")
+	ErrKeyResolution      = errors.New("key resolution failed")
+	ErrInvalid            = errors.New("invalid credentials")
+	ErrExpired            = errors.New("credentials expired")
+	ErrCapability         = errors.New("capability check failed")
+	ErrPartner            = errors.New("partner check failed")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrUnavailable        = errors.New("dependency unavailable")
+)
+
+var classSentinels = []error{
+	MissingCredentialsClass: ErrMissingCredentials,
+	MalformedClass:          ErrMalformed,
+	KeyClass:                ErrKeyResolution,
+	InvalidClass:            ErrInvalid,
+	ExpiredClass:            ErrExpired,
+	CapabilityClass:         ErrCapability,
+	PartnerClass:            ErrPartner,
+	RateLimitedClass:        ErrRateLimited,
+	UnavailableClass:        ErrUnavailable,
+}
+
+var classCodes = []string{
+	UnknownClass:            "unknown",
+	MissingCredentialsClass: "missing_credentials",
+	MalformedClass:          "malformed",
+	KeyClass:                "key",
+	InvalidClass:            "invalid",
+	ExpiredClass:            "expired",
+	CapabilityClass:         "capability",
+	PartnerClass:            "partner",
+	RateLimitedClass:        "rate_limited",
+	UnavailableClass:        "unavailable",
+}
+
+// String returns the metric label safe code of the class.
+func (c ErrorClass) String() string {
+	if c < 0 || int(c) >= len(classCodes) {
+		return classCodes[UnknownClass]
+	}
+	return classCodes[c]
+}
+
+// Err returns the sentinel error of the class, or nil for UnknownClass.
+func (c ErrorClass) Err() error {
+	if c <= UnknownClass || int(c) >= len(classSentinels) {
+		return nil
+	}
+	return classSentinels[c]
+}
+
+// Classifier is implemented by errors that know their class.
+type Classifier interface {
+	ErrorClass() ErrorClass
+}
+
+type classError struct {
+	class ErrorClass
+	text  string
+}
+
+// NewClassError creates an error with the text given that belongs to the
+// class given, so errors.Is matches it to the class's sentinel.
+func NewClassError(class ErrorClass, text string) error {
+	return &classError{class: class, text: text}
+}
+
+func (e *classError) Error() string {
+	return e.text
+}
+
+func (e *classError) ErrorClass() ErrorClass {
+	return e.class
+}
+
+func (e *classError) Is(target error) bool {
+	return target != nil && target == e.class.Err()
+}
+
+// ClassOf returns the class of the error.  A wrapped Classifier or class
+// sentinel is used if there is one, and for a MultiError the first of its
+// errors with a class is used.
+func ClassOf(err error) ErrorClass {
+	if err == nil {
+		return UnknownClass
+	}
+	var c Classifier
+	if errors.As(err, &c) {
+		return c.ErrorClass()
+	}
+	for class, sentinel := range classSentinels {
+		if sentinel != nil && errors.Is(err, sentinel) {
+			return ErrorClass(class)
+		}
+	}
+	var me MultiError
+	if errors.As(err, &me) {
+		for _, e := range me.Errors() {
+			if class := ClassOf(e); class != UnknownClass {
+				return class
+			}
+		}
+	}
+	return UnknownClass
+}
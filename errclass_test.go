@@ -0,0 +1,116 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type classifiedErr struct{}
+
+func (classifiedErr) Error() string {
+	return "classified"
+}
+
+func (classifiedErr) ErrorClass() ErrorClass {
+	return PartnerClass
+}
+
+func TestErrorClass(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("unknown", UnknownClass.String())
+	assert.Equal("rate_limited", RateLimitedClass.String())
+	assert.Equal("unknown", ErrorClass(-1).String())
+	assert.Equal("unknown", ErrorClass(100).String())
+
+	assert.Nil(UnknownClass.Err())
+	assert.Nil(ErrorClass(100).Err())
+	assert.Equal(ErrExpired, ExpiredClass.Err())
+}
+
+func TestNewClassError(t *testing.T) {
+	assert := assert.New(t)
+	err := NewClassError(KeyClass, "no key")
+	assert.Equal("no key", err.Error())
+	assert.ErrorIs(err, ErrKeyResolution)
+	assert.ErrorIs(fmt.Errorf("wrapped: %w", err), ErrKeyResolution)
+	assert.False(errors.Is(err, ErrInvalid))
+	assert.False(errors.Is(err, nil))
+}
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		description   string
+		err           error
+		expectedClass ErrorClass
+	}{
+		{
+			description:   "Nil",
+			expectedClass: UnknownClass,
+		},
+		{
+			description:   "Unclassified",
+			err:           errors.New("test"),
+			expectedClass: UnknownClass,
+		},
+		{
+			description:   "Sentinel",
+			err:           ErrUnavailable,
+			expectedClass: UnavailableClass,
+		},
+		{
+			description:   "Wrapped Sentinel",
+			err:           fmt.Errorf("failed: %w", ErrMalformed),
+			expectedClass: MalformedClass,
+		},
+		{
+			description:   "Class Error",
+			err:           NewClassError(ExpiredClass, "expired"),
+			expectedClass: ExpiredClass,
+		},
+		{
+			description:   "Classifier",
+			err:           fmt.Errorf("failed: %w", classifiedErr{}),
+			expectedClass: PartnerClass,
+		},
+		{
+			description:   "Errors",
+			err:           Errors{errors.New("test"), NewClassError(CapabilityClass, "no capability"), ErrRateLimited},
+			expectedClass: CapabilityClass,
+		},
+		{
+			description:   "Errors Without Class",
+			err:           Errors{errors.New("test")},
+			expectedClass: UnknownClass,
+		},
+		{
+			description:   "Claims Policy",
+			err:           fmt.Errorf("%w: iss", ErrIssuerNotAllowed),
+			expectedClass: InvalidClass,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expectedClass, ClassOf(tc.err))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func FuzzParseAttributePath(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"$",
+		"a",
+		"$.a.b",
+		"a[0].b",
+		"groups[*].name",
+		"['key.with.dots']",
+		`["quoted"]`,
+		"a..b",
+		"a[",
+		"a[-1]",
+		"a]b",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, path string) {
+		p, err := ParseAttributePath(path)
+		if err != nil {
+			return
+		}
+		again, err := ParseAttributePath(p.String())
+		if err != nil {
+			t.Fatalf("path [%q] formatted as [%q] which doesn't parse: %v", path, p.String(), err)
+		}
+		if !reflect.DeepEqual(p, again) {
+			t.Errorf("path [%q] formatted as [%q] which parses differently", path, p.String())
+		}
+	})
+}
+
+func FuzzClaimsMapper(f *testing.F) {
+	f.Add("$.allowedResources.allowedPartners", `{"allowedResources":{"allowedPartners":["comcast"]}}`)
+	f.Add("groups[*].name", `{"groups":[{"name":"a"},{"id":1},"b",null]}`)
+	f.Add("a[1]", `{"a":[1]}`)
+	f.Add("['a.b']", `{"a.b":true}`)
+	f.Add("a.b.c", `{"a":{"b":null}}`)
+	f.Fuzz(func(t *testing.T, path string, claims string) {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(claims), &m); err != nil {
+			return
+		}
+		mapper, err := NewClaimsMapper(ClaimsMappingConfig{
+			Mappings: map[string][]string{PartnerIDsKey: {path}},
+		})
+		if err != nil {
+			return
+		}
+		attrs, err := mapper.MapClaims(NewAttributes(m))
+		if err != nil {
+			t.Fatalf("mapping claims %s failed: %v", claims, err)
+		}
+		got, ok := attrs.Get(PartnerIDsKey)
+		p, _ := ParseAttributePath(path)
+		expected, found := p.Get(NewAttributes(m))
+		if found && (!ok || !reflect.DeepEqual(expected, got)) {
+			t.Errorf("path [%q] found [%v] in %s, but the mapped claim was [%v]", path, expected, claims, got)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+	"github.com/spf13/cast"
+)
+
+// GroupNamesKey is the attribute key a GroupExpander puts the names of the
+// token's groups under by default.
+const GroupNamesKey = "group-names"
+
+const (
+	defaultGroupCacheTTL        = 5 * time.Minute
+	defaultGroupCacheMaxEntries = 10000
+)
+
+var (
+	ErrNilDirectory = errors.New("directory cannot be nil")
+
+	// ErrDirectoryUnavailable is returned when the directory can't resolve
+	// the token's groups and expansion is required.
+	ErrDirectoryUnavailable = NewClassError(UnavailableClass, "group directory unavailable")
+)
+
+// TokenEnricher adds information to a token after it has been parsed and
+// validated, such as attributes looked up elsewhere, so that validators can
+// use it.
+type TokenEnricher interface {
+	Enrich(context.Context, Token) (Token, error)
+}
+
+// TokenEnricherFunc makes it so any function with the same signature as
+// Enrich implements TokenEnricher.
+type TokenEnricherFunc func(context.Context, Token) (Token, error)
+
+// Enrich runs the function.
+func (f TokenEnricherFunc) Enrich(ctx context.Context, t Token) (Token, error) {
+	return f(ctx, t)
+}
+
+// Group is a group or team resolved by a Directory.
+type Group struct {
+	ID    string
+	Name  string
+	Roles []string
+}
+
+// Directory resolves group IDs into groups, such as by querying LDAP, a SCIM
+// service, or an HTTP API.  IDs the directory doesn't know are left out of
+// the groups returned.
+type Directory interface {
+	Groups(ctx context.Context, ids []string) ([]Group, error)
+}
+
+// DirectoryFunc makes it so any function with the same signature as Groups
+// implements Directory.
+type DirectoryFunc func(context.Context, []string) ([]Group, error)
+
+// Groups runs the function.
+func (f DirectoryFunc) Groups(ctx context.Context, ids []string) ([]Group, error) {
+	return f(ctx, ids)
+}
+
+// GroupExpansionConfig configures a GroupExpander.
+type GroupExpansionConfig struct {
+	// GroupsKeyPath is where the group IDs are in the token's attributes.
+	// Defaults to "groups".
+	GroupsKeyPath []string
+
+	// NamesKey is the attribute key the group names are put under.  Defaults
+	// to GroupNamesKey.
+	NamesKey string
+
+	// RolesKey is the attribute key the groups' roles are put under, along
+	// with any roles the token already had.  Defaults to RolesKey.
+	RolesKey string
+
+	// CacheTTL is how long resolved groups are cached.  Defaults to five
+	// minutes.  A negative TTL disables caching.
+	CacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached groups.  Defaults to 10000.
+	CacheMaxEntries int
+
+	// Required fails the request with ErrDirectoryUnavailable when the
+	// directory returns an error.  Otherwise, the token is left as it was.
+	Required bool
+}
+
+// GroupExpander is a TokenEnricher that resolves the group IDs in a token
+// into group names and roles using a Directory.  It is safe for concurrent
+// use.
+type GroupExpander struct {
+	directory Directory
+	config    GroupExpansionConfig
+	now       func() time.Time
+	groups    *ttlcache.Cache
+}
+
+type cachedGroup struct {
+	group Group
+	found bool
+}
+
+// NewGroupExpander creates a GroupExpander using the directory given.
+func NewGroupExpander(d Directory, config GroupExpansionConfig) (*GroupExpander, error) {
+	if d == nil {
+		return nil, ErrNilDirectory
+	}
+	if len(config.GroupsKeyPath) == 0 {
+		config.GroupsKeyPath = []string{"groups"}
+	}
+	if config.NamesKey == "" {
+		config.NamesKey = GroupNamesKey
+	}
+	if config.RolesKey == "" {
+		config.RolesKey = RolesKey
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = defaultGroupCacheTTL
+	}
+	if config.CacheMaxEntries <= 0 {
+		config.CacheMaxEntries = defaultGroupCacheMaxEntries
+	}
+	return &GroupExpander{
+		directory: d,
+		config:    config,
+		now:       time.Now,
+		groups:    ttlcache.New(config.CacheMaxEntries),
+	}, nil
+}
+
+// Enrich returns a token whose attributes include the names and roles of the
+// token's groups.  Tokens without groups are returned as they are.
+func (g *GroupExpander) Enrich(ctx context.Context, t Token) (Token, error) {
+	if t == nil || t.Attributes() == nil {
+		return t, nil
+	}
+	val, ok := GetNestedAttribute(t.Attributes(), g.config.GroupsKeyPath...)
+	if !ok {
+		return t, nil
+	}
+	ids, err := cast.ToStringSliceE(val)
+	if err != nil || len(ids) == 0 {
+		return t, nil
+	}
+
+	groups, err := g.resolve(ctx, ids)
+	if err != nil {
+		if g.config.Required {
+			return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+		}
+		return t, nil
+	}
+
+	names := make([]string, 0, len(groups))
+	var roles []string
+	if existing, ok := t.Attributes().Get(g.config.RolesKey); ok {
+		roles, _ = cast.ToStringSliceE(existing)
+	}
+	for _, group := range groups {
+		names = append(names, group.Name)
+		roles = append(roles, group.Roles...)
+	}
+	return NewToken(t.Type(), t.Principal(), mappedAttributes{
+		canonical: BasicAttributes{
+			g.config.NamesKey: names,
+			g.config.RolesKey: dedupe(roles),
+		},
+		base: t.Attributes(),
+	}), nil
+}
+
+// resolve returns the groups with the IDs given, asking the directory for the
+// ones that aren't cached.
+func (g *GroupExpander) resolve(ctx context.Context, ids []string) ([]Group, error) {
+	resolved := make(map[string]cachedGroup, len(ids))
+	var missing []string
+	now := g.now()
+	for _, id := range dedupe(ids) {
+		if c, ok := g.groups.Get(id, now); ok {
+			resolved[id] = c.(cachedGroup)
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		groups, err := g.directory.Groups(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range missing {
+			resolved[id] = cachedGroup{}
+		}
+		for _, group := range groups {
+			resolved[group.ID] = cachedGroup{group: group, found: true}
+		}
+		g.cache(missing, resolved)
+	}
+
+	groups := make([]Group, 0, len(resolved))
+	for _, c := range resolved {
+		if c.found {
+			groups = append(groups, c.group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups, nil
+}
+
+// cache stores the groups just resolved, including IDs the directory didn't
+// know, so that unknown IDs don't cause a lookup on every request.
+func (g *GroupExpander) cache(ids []string, resolved map[string]cachedGroup) {
+	if g.config.CacheTTL < 0 {
+		return
+	}
+	now := g.now()
+	for _, id := range ids {
+		g.groups.Set(id, resolved[id], now.Add(g.config.CacheTTL), now)
+	}
+}
+
+// dedupe removes repeated and empty strings, keeping the first of each.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDirectory = map[string]Group{
+	"g1": {ID: "g1", Name: "admins", Roles: []string{"admin", "user"}},
+	"g2": {ID: "g2", Name: "devs", Roles: []string{"user", "deployer"}},
+}
+
+func newTestDirectory(calls *int32, err error) Directory {
+	return DirectoryFunc(func(_ context.Context, ids []string) ([]Group, error) {
+		atomic.AddInt32(calls, 1)
+		if err != nil {
+			return nil, err
+		}
+		var groups []Group
+		for _, id := range ids {
+			if g, ok := testDirectory[id]; ok {
+				groups = append(groups, g)
+			}
+		}
+		return groups, nil
+	})
+}
+
+func TestNewGroupExpander(t *testing.T) {
+	assert := assert.New(t)
+	g, err := NewGroupExpander(nil, GroupExpansionConfig{})
+	assert.ErrorIs(err, ErrNilDirectory)
+	assert.Nil(g)
+
+	var calls int32
+	g, err = NewGroupExpander(newTestDirectory(&calls, nil), GroupExpansionConfig{})
+	assert.NoError(err)
+	assert.Equal([]string{"groups"}, g.config.GroupsKeyPath)
+	assert.Equal(GroupNamesKey, g.config.NamesKey)
+	assert.Equal(RolesKey, g.config.RolesKey)
+	assert.Equal(defaultGroupCacheTTL, g.config.CacheTTL)
+	assert.Equal(defaultGroupCacheMaxEntries, g.config.CacheMaxEntries)
+}
+
+func TestGroupExpanderEnrich(t *testing.T) {
+	testErr := errors.New("directory down")
+	tests := []struct {
+		description   string
+		config        GroupExpansionConfig
+		directoryErr  error
+		attributes    Attributes
+		expectedNames []string
+		expectedRoles []string
+		expectedErr   error
+		unchanged     bool
+	}{
+		{
+			description:   "Success",
+			attributes:    NewAttributes(map[string]interface{}{"groups": []interface{}{"g2", "g1", "g3", "g1"}}),
+			expectedNames: []string{"admins", "devs"},
+			expectedRoles: []string{"admin", "user", "deployer"},
+		},
+		{
+			description: "Custom Keys",
+			config: GroupExpansionConfig{
+				GroupsKeyPath: []string{"resources", "teams"},
+				NamesKey:      "teams",
+				RolesKey:      "team-roles",
+			},
+			attributes: NewAttributes(map[string]interface{}{
+				"resources":  map[string]interface{}{"teams": []string{"g2"}},
+				"team-roles": []string{"viewer"},
+			}),
+			expectedNames: []string{"devs"},
+			expectedRoles: []string{"viewer", "user", "deployer"},
+		},
+		{
+			description: "No Groups",
+			attributes:  NewAttributes(map[string]interface{}{"other": "value"}),
+			unchanged:   true,
+		},
+		{
+			description: "Groups Not Strings",
+			attributes:  NewAttributes(map[string]interface{}{"groups": map[string]interface{}{"a": "b"}}),
+			unchanged:   true,
+		},
+		{
+			description:  "Directory Error",
+			directoryErr: testErr,
+			attributes:   NewAttributes(map[string]interface{}{"groups": []string{"g1"}}),
+			unchanged:    true,
+		},
+		{
+			description:  "Directory Error Required",
+			config:       GroupExpansionConfig{Required: true},
+			directoryErr: testErr,
+			attributes:   NewAttributes(map[string]interface{}{"groups": []string{"g1"}}),
+			expectedErr:  ErrDirectoryUnavailable,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var calls int32
+			g, err := NewGroupExpander(newTestDirectory(&calls, tc.directoryErr), tc.config)
+			require.NoError(err)
+			token := NewToken("test", "alice", tc.attributes)
+			enriched, err := g.Enrich(context.Background(), token)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Equal(UnavailableClass, ClassOf(err))
+				assert.Nil(enriched)
+				return
+			}
+			require.NoError(err)
+			if tc.unchanged {
+				assert.Equal(token, enriched)
+				return
+			}
+			assert.Equal("test", enriched.Type())
+			assert.Equal("alice", enriched.Principal())
+			names, ok := enriched.Attributes().Get(g.config.NamesKey)
+			assert.True(ok)
+			assert.Equal(tc.expectedNames, names)
+			roles, ok := enriched.Attributes().Get(g.config.RolesKey)
+			assert.True(ok)
+			assert.Equal(tc.expectedRoles, roles)
+		})
+	}
+
+	var calls int32
+	g, err := NewGroupExpander(newTestDirectory(&calls, nil), GroupExpansionConfig{})
+	require.NoError(t, err)
+	enriched, err := g.Enrich(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, enriched)
+}
+
+func TestGroupExpanderCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	var requested [][]string
+	d := DirectoryFunc(func(_ context.Context, ids []string) ([]Group, error) {
+		sorted := append([]string(nil), ids...)
+		sort.Strings(sorted)
+		requested = append(requested, sorted)
+		var groups []Group
+		for _, id := range ids {
+			if g, ok := testDirectory[id]; ok {
+				groups = append(groups, g)
+			}
+		}
+		return groups, nil
+	})
+	g, err := NewGroupExpander(d, GroupExpansionConfig{CacheTTL: time.Minute, CacheMaxEntries: 3})
+	require.NoError(err)
+	now := time.Unix(1000, 0)
+	g.now = func() time.Time { return now }
+	enrich := func(groups ...string) {
+		_, err := g.Enrich(context.Background(), NewToken("test", "alice", NewAttributes(map[string]interface{}{"groups": groups})))
+		require.NoError(err)
+	}
+
+	enrich("g1", "g3")
+	enrich("g1", "g2", "g3")
+	assert.Equal([][]string{{"g1", "g3"}, {"g2"}}, requested)
+
+	// expired groups are looked up again.
+	now = now.Add(time.Minute)
+	enrich("g1")
+	assert.Equal([][]string{{"g1", "g3"}, {"g2"}, {"g1"}}, requested)
+
+	// going over the max entries clears out expired groups first.
+	enrich("g4")
+	assert.Equal(2, g.groups.Len())
+
+	// caching can be disabled.
+	g, err = NewGroupExpander(d, GroupExpansionConfig{CacheTTL: -1})
+	require.NoError(err)
+	requested = nil
+	enrich("g1")
+	enrich("g1")
+	assert.Len(requested, 2)
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package ttlcache provides the bounded cache with expiring entries shared by
+// bascule's verification, decision, reputation, and store caches.
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Cache is a bounded, concurrency safe cache whose entries each expire at
+// their own time.  Callers pass in the current time, so that they can control
+// the clock in tests.
+type Cache struct {
+	maxEntries int
+
+	lock    sync.Mutex
+	entries map[string]entry
+}
+
+// New creates a Cache holding at most maxEntries entries.
+func New(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]entry),
+	}
+}
+
+// Get returns the value stored for the key, if there is one that hasn't
+// expired by now.
+func (c *Cache) Get(key string, now time.Time) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set stores the value for the key until it expires.  If the cache is full,
+// expired entries are removed first, falling back to clearing the cache if
+// everything is still fresh.
+func (c *Cache) Set(key string, value interface{}, expires, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry{value: value, expires: expires}
+}
+
+// Len returns the number of entries, including expired ones that haven't
+// been removed yet.
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.entries)
+}
+
+// evict must be called with the lock held.
+func (c *Cache) evict(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]entry)
+	}
+}
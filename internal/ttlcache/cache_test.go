@@ -0,0 +1,72 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+	c := New(2)
+
+	c.Set("a", 1, now.Add(time.Minute), now)
+	v, ok := c.Get("a", now)
+	assert.True(ok)
+	assert.Equal(1, v)
+
+	// nil values are still found.
+	c.Set("nil", nil, now.Add(time.Minute), now)
+	v, ok = c.Get("nil", now)
+	assert.True(ok)
+	assert.Nil(v)
+
+	// expired entries aren't returned, and are removed.
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a", now)
+	assert.False(ok)
+	assert.Equal(1, c.Len())
+}
+
+func TestCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+	c := New(2)
+
+	c.Set("a", 1, now.Add(time.Minute), now)
+	c.Set("b", 2, now.Add(2*time.Minute), now)
+	assert.Equal(2, c.Len())
+
+	// everything is fresh, so the cache is cleared.
+	c.Set("c", 3, now.Add(time.Minute), now)
+	assert.Equal(1, c.Len())
+
+	// expired entries are dropped first.
+	c.Set("d", 4, now.Add(2*time.Minute), now)
+	now = now.Add(time.Minute)
+	c.Set("e", 5, now.Add(time.Minute), now)
+	assert.Equal(2, c.Len())
+	_, ok := c.Get("d", now)
+	assert.True(ok)
+	_, ok = c.Get("e", now)
+	assert.True(ok)
+}
@@ -58,21 +58,22 @@ type Leeway struct {
 
 // Valid implements the jwt.Claims interface, ensuring that the token claism
 // are valid.  This implementation checks the time based claims: exp, iat, nbf.
+// Each Leeway widens the window the token is accepted in.
 func (c *ClaimsWithLeeway) Valid() error {
 	vErr := new(jwt.ValidationError)
 	now := jwt.TimeFunc().Unix()
 
-	if !c.VerifyExpiresAt(now+c.Leeway.EXP, false) {
+	if !c.VerifyExpiresAt(now-c.Leeway.EXP, false) {
 		vErr.Inner = errors.New("Token is expired")
 		vErr.Errors |= jwt.ValidationErrorExpired
 	}
 
-	if !c.VerifyIssuedAt(now-c.Leeway.IAT, false) {
+	if !c.VerifyIssuedAt(now+c.Leeway.IAT, false) {
 		vErr.Inner = errors.New("Token used before issued")
 		vErr.Errors |= jwt.ValidationErrorIssuedAt
 	}
 
-	if !c.VerifyNotBefore(now-c.Leeway.NBF, false) {
+	if !c.VerifyNotBefore(now+c.Leeway.NBF, false) {
 		vErr.Inner = errors.New("Token is not valid yet")
 		vErr.Errors |= jwt.ValidationErrorNotValidYet
 	}
@@ -37,3 +37,67 @@ func TestValid(t *testing.T) {
 	err := claims.Valid()
 	assert.NoError(err)
 }
+
+func TestValidLeeway(t *testing.T) {
+	now := float64(jwt.TimeFunc().Unix())
+	leeway := Leeway{EXP: 5, NBF: 5, IAT: 5}
+	tests := []struct {
+		description string
+		claims      jwt.MapClaims
+		leeway      Leeway
+		expectedErr uint32
+	}{
+		{
+			description: "Expired Within Leeway",
+			claims:      jwt.MapClaims{"exp": now - 3},
+			leeway:      leeway,
+		},
+		{
+			description: "Expired",
+			claims:      jwt.MapClaims{"exp": now - 3},
+			expectedErr: jwt.ValidationErrorExpired,
+		},
+		{
+			description: "Expired Past Leeway",
+			claims:      jwt.MapClaims{"exp": now - 10},
+			leeway:      leeway,
+			expectedErr: jwt.ValidationErrorExpired,
+		},
+		{
+			description: "Issued In Future Within Leeway",
+			claims:      jwt.MapClaims{"iat": now + 3},
+			leeway:      leeway,
+		},
+		{
+			description: "Issued In Future",
+			claims:      jwt.MapClaims{"iat": now + 10},
+			leeway:      leeway,
+			expectedErr: jwt.ValidationErrorIssuedAt,
+		},
+		{
+			description: "Not Yet Valid Within Leeway",
+			claims:      jwt.MapClaims{"nbf": now + 3},
+			leeway:      leeway,
+		},
+		{
+			description: "Not Yet Valid",
+			claims:      jwt.MapClaims{"nbf": now + 3},
+			expectedErr: jwt.ValidationErrorNotValidYet,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			claims := ClaimsWithLeeway{MapClaims: tc.claims, Leeway: tc.leeway}
+			err := claims.Valid()
+			if tc.expectedErr == 0 {
+				assert.NoError(err)
+				return
+			}
+			var vErr *jwt.ValidationError
+			if assert.ErrorAs(err, &vErr) {
+				assert.Equal(tc.expectedErr, vErr.Errors&tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+Package policytest runs table driven policy tests described in YAML fixtures,
+so that teams can gate changes to their Validators and CapabilitiesCheckers in
+CI.  A fixture lists cases, each with a token, a request, and the expected
+outcome:
+
+	cases:
+	  - name: readers can get things
+	    token:
+	      principal: alice
+	      claims:
+	        capabilities: ["x1:webpa:api:/things:get"]
+	    request:
+	      method: GET
+	      url: /things/1
+	    expect:
+	      outcome: allow
+	      capability: x1:webpa:api:/things:get
+	  - name: readers can't delete things
+	    token:
+	      principal: alice
+	      claims:
+	        capabilities: ["x1:webpa:api:/things:get"]
+	    request:
+	      method: DELETE
+	      url: /things/1
+	    expect:
+	      outcome: deny
+	      class: capability
+
+A test then runs the fixture against the policy:
+
+	func TestPolicy(t *testing.T) {
+		policytest.Run(t, policytest.MustLoad("testdata/policy.yaml"), validator)
+	}
+*/
+package policytest
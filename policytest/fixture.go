@@ -0,0 +1,211 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policytest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/s-srakshe/bascule"
+	"gopkg.in/yaml.v3"
+)
+
+// Outcomes a case can expect.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+var ErrInvalidFixture = errors.New("invalid fixture")
+
+// Fixture is a set of policy test cases.
+type Fixture struct {
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is a single policy test: a token making a request, and what the
+// policy should decide.
+type Case struct {
+	Name    string         `yaml:"name"`
+	Token   TokenFixture   `yaml:"token"`
+	Request RequestFixture `yaml:"request"`
+	Expect  Expectation    `yaml:"expect"`
+}
+
+// TokenFixture describes the token a case uses.  A case without a token
+// makes an unauthenticated request.
+type TokenFixture struct {
+	// Type is the token type.  Defaults to "jwt".
+	Type string `yaml:"type"`
+
+	Principal string `yaml:"principal"`
+
+	// Claims are the token's attributes.  Numbers are converted to float64,
+	// as they would be when decoded from a JWT.
+	Claims map[string]interface{} `yaml:"claims"`
+}
+
+// RequestFixture describes the request a case makes.
+type RequestFixture struct {
+	// Method defaults to GET.
+	Method string `yaml:"method"`
+
+	// URL defaults to "/".
+	URL string `yaml:"url"`
+
+	ClientIP  string `yaml:"clientIP"`
+	UserAgent string `yaml:"userAgent"`
+
+	// Endpoint is the endpoint bucket passed to CapabilitiesCheckers.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// Expectation is what the policy should decide.
+type Expectation struct {
+	// Outcome is Allow or Deny.
+	Outcome string `yaml:"outcome"`
+
+	// Class is the bascule.ErrorClass a denial should have, such as
+	// "capability" or "partner".
+	Class string `yaml:"class"`
+
+	// Error is text a denial's error message should contain.
+	Error string `yaml:"error"`
+
+	// Capability is the capability an allowed request should be authorized
+	// by, as recorded in the bascule.Decision.
+	Capability string `yaml:"capability"`
+}
+
+// Parse reads a YAML fixture and checks that every case has a name and a
+// valid outcome.
+func Parse(r io.Reader) (Fixture, error) {
+	var f Fixture
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	if err := d.Decode(&f); err != nil {
+		return Fixture{}, fmt.Errorf("%w: %v", ErrInvalidFixture, err)
+	}
+	names := make(map[string]bool, len(f.Cases))
+	for i, c := range f.Cases {
+		if c.Name == "" {
+			return Fixture{}, fmt.Errorf("%w: case %d has no name", ErrInvalidFixture, i)
+		}
+		if names[c.Name] {
+			return Fixture{}, fmt.Errorf("%w: duplicate case [%v]", ErrInvalidFixture, c.Name)
+		}
+		names[c.Name] = true
+		if c.Expect.Outcome != Allow && c.Expect.Outcome != Deny {
+			return Fixture{}, fmt.Errorf("%w: case [%v] has outcome [%v], expected %s or %s",
+				ErrInvalidFixture, c.Name, c.Expect.Outcome, Allow, Deny)
+		}
+		if _, err := url.Parse(c.Request.URL); err != nil {
+			return Fixture{}, fmt.Errorf("%w: case [%v] has an invalid url: %v", ErrInvalidFixture, c.Name, err)
+		}
+	}
+	return f, nil
+}
+
+// Load reads the YAML fixture file at the path given.
+func Load(path string) (Fixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+	defer file.Close()
+	f, err := Parse(file)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("%v: %w", path, err)
+	}
+	return f, nil
+}
+
+// MustLoad is Load, panicking on errors, for use in tests.
+func MustLoad(path string) Fixture {
+	f, err := Load(path)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Authentication builds the bascule.Authentication for the case.
+func (c Case) Authentication() bascule.Authentication {
+	r := c.Request
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+	if r.URL == "" {
+		r.URL = "/"
+	}
+	// the url was checked by Parse.
+	u, _ := url.Parse(r.URL)
+	auth := bascule.Authentication{
+		Authorization: "Bearer",
+		Request: bascule.Request{
+			URL:       u,
+			Method:    r.Method,
+			ClientIP:  r.ClientIP,
+			UserAgent: r.UserAgent,
+		},
+	}
+	if c.Token.Principal != "" || len(c.Token.Claims) > 0 {
+		t := c.Token.Type
+		if t == "" {
+			t = "jwt"
+		}
+		claims, _ := normalize(c.Token.Claims).(map[string]interface{})
+		auth.Token = bascule.NewToken(t, c.Token.Principal, bascule.NewAttributes(claims))
+	}
+	return auth
+}
+
+// normalize converts YAML numbers to float64 and maps to
+// map[string]interface{}, as decoding a JWT would give.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = normalize(v)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, v := range t {
+			l[i] = normalize(v)
+		}
+		return l
+	}
+	return v
+}
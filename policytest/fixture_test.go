@@ -0,0 +1,140 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policytest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		description string
+		fixture     string
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			fixture:     "cases:\n  - name: a\n    expect:\n      outcome: allow\n",
+		},
+		{
+			description: "Invalid YAML",
+			fixture:     "cases: [",
+			expectedErr: ErrInvalidFixture,
+		},
+		{
+			description: "Unknown Field",
+			fixture:     "cases:\n  - name: a\n    expect:\n      result: allow\n",
+			expectedErr: ErrInvalidFixture,
+		},
+		{
+			description: "Missing Name",
+			fixture:     "cases:\n  - expect:\n      outcome: allow\n",
+			expectedErr: ErrInvalidFixture,
+		},
+		{
+			description: "Duplicate Name",
+			fixture:     "cases:\n  - name: a\n    expect:\n      outcome: allow\n  - name: a\n    expect:\n      outcome: deny\n",
+			expectedErr: ErrInvalidFixture,
+		},
+		{
+			description: "Invalid Outcome",
+			fixture:     "cases:\n  - name: a\n    expect:\n      outcome: maybe\n",
+			expectedErr: ErrInvalidFixture,
+		},
+		{
+			description: "Invalid URL",
+			fixture:     "cases:\n  - name: a\n    request:\n      url: \"%zz\"\n    expect:\n      outcome: allow\n",
+			expectedErr: ErrInvalidFixture,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			f, err := Parse(strings.NewReader(tc.fixture))
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Empty(f.Cases)
+				return
+			}
+			assert.NoError(err)
+			assert.Len(f.Cases, 1)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	f, err := Load("testdata/capabilities.yaml")
+	assert.NoError(err)
+	assert.Len(f.Cases, 4)
+
+	_, err = Load("testdata/missing.yaml")
+	assert.Error(err)
+
+	assert.Panics(func() { MustLoad("testdata/missing.yaml") })
+}
+
+func TestCaseAuthentication(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	f := MustLoad("testdata/capabilities.yaml")
+
+	auth := f.Cases[2].Authentication()
+	require.NotNil(auth.Token)
+	assert.Equal("jwt", auth.Token.Type())
+	assert.Equal("bob", auth.Token.Principal())
+	exp, ok := auth.Token.Attributes().Get("exp")
+	assert.True(ok)
+	assert.Equal(float64(1700000000), exp)
+	caps, ok := auth.Token.Attributes().Get("capabilities")
+	assert.True(ok)
+	assert.Equal([]interface{}{"x1:webpa:api:.*:all"}, caps)
+	assert.Equal("DELETE", auth.Request.Method)
+	assert.Equal("/things/1", auth.Request.URL.Path)
+
+	auth = f.Cases[3].Authentication()
+	assert.Nil(auth.Token)
+	assert.Equal("GET", auth.Request.Method)
+
+	auth = Case{Token: TokenFixture{Type: "basic", Principal: "carol"}}.Authentication()
+	require.NotNil(auth.Token)
+	assert.Equal("basic", auth.Token.Type())
+	assert.Equal("/", auth.Request.URL.Path)
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+	v := normalize(map[string]interface{}{
+		"int":    1,
+		"int64":  int64(2),
+		"uint64": uint64(3),
+		"nested": map[interface{}]interface{}{4: []interface{}{5, "six"}},
+	})
+	assert.Equal(map[string]interface{}{
+		"int":    float64(1),
+		"int64":  float64(2),
+		"uint64": float64(3),
+		"nested": map[string]interface{}{"4": []interface{}{float64(5), "six"}},
+	}, v)
+	assert.Equal(bascule.ErrMissingCredentials, normalize(bascule.ErrMissingCredentials))
+}
@@ -0,0 +1,123 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policytest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+)
+
+// Result is what a policy decided for a case.
+type Result struct {
+	Case     Case
+	Err      error
+	Decision *bascule.Decision
+}
+
+// Allowed reports whether the policy allowed the request.
+func (r Result) Allowed() bool {
+	return r.Err == nil
+}
+
+// Failures lists the ways the result differs from the case's expectation.
+// An empty list means the case passed.
+func (r Result) Failures() []string {
+	var failures []string
+	e := r.Case.Expect
+	switch {
+	case e.Outcome == Allow && !r.Allowed():
+		failures = append(failures, fmt.Sprintf("expected allow, got deny: %v", r.Err))
+	case e.Outcome == Deny && r.Allowed():
+		failures = append(failures, "expected deny, got allow")
+	}
+	if e.Outcome == Deny && !r.Allowed() {
+		if e.Class != "" {
+			if class := bascule.ClassOf(r.Err).String(); class != e.Class {
+				failures = append(failures, fmt.Sprintf("expected error class [%v], got [%v]: %v", e.Class, class, r.Err))
+			}
+		}
+		if e.Error != "" && !strings.Contains(r.Err.Error(), e.Error) {
+			failures = append(failures, fmt.Sprintf("expected error containing [%v], got [%v]", e.Error, r.Err))
+		}
+	}
+	if e.Outcome == Allow && r.Allowed() && e.Capability != "" {
+		if c := r.Decision.Capability(); c != e.Capability {
+			failures = append(failures, fmt.Sprintf("expected capability [%v], got [%v]", e.Capability, c))
+		}
+	}
+	return failures
+}
+
+// Evaluate runs the case against the validator.  The case's Authentication,
+// endpoint, and a new bascule.Decision are put in the context the validator is
+// given.  A case without a token is denied with
+// bascule.ErrMissingCredentials.
+func Evaluate(ctx context.Context, v bascule.Validator, c Case) Result {
+	auth := c.Authentication()
+	d := bascule.NewDecision()
+	ctx = bascule.WithDecision(bascule.WithAuthentication(ctx, auth), d)
+	ctx = context.WithValue(ctx, endpointKey{}, c.Request.Endpoint)
+	var err error
+	if auth.Token == nil {
+		err = bascule.ErrMissingCredentials
+	} else {
+		err = v.Check(ctx, auth.Token)
+	}
+	return Result{Case: c, Err: err, Decision: d}
+}
+
+// Checker adapts a CapabilitiesChecker to a bascule.Validator, so fixtures
+// can be run against one directly.  The case's endpoint is passed as the
+// ParsedValues' Endpoint.
+func Checker(c basculechecks.CapabilitiesChecker) bascule.Validator {
+	return bascule.ValidatorFunc(func(ctx context.Context, _ bascule.Token) error {
+		auth, ok := bascule.FromContext(ctx)
+		if !ok {
+			return bascule.ErrMissingCredentials
+		}
+		return basculechecks.CheckWithContext(ctx, c, auth, basculechecks.ParsedValues{
+			Endpoint: endpointFromContext(ctx),
+		})
+	})
+}
+
+type endpointKey struct{}
+
+func endpointFromContext(ctx context.Context) string {
+	e, _ := ctx.Value(endpointKey{}).(string)
+	return e
+}
+
+// Run runs every case in the fixture against the validator as a subtest,
+// failing each case whose result doesn't match its expectation.
+func Run(t *testing.T, f Fixture, v bascule.Validator) {
+	t.Helper()
+	for _, c := range f.Cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			for _, failure := range Evaluate(context.Background(), v, c).Failures() {
+				t.Error(failure)
+			}
+		})
+	}
+}
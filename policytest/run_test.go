@@ -0,0 +1,126 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/s-srakshe/bascule"
+	"github.com/s-srakshe/bascule/basculechecks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCapabilitiesChecker(t *testing.T) basculechecks.CapabilitiesChecker {
+	out, err := basculechecks.NewCapabilitiesValidator(basculechecks.CapabilitiesValidatorConfig{
+		Type:            "enforce",
+		Prefix:          "x1:webpa:api:",
+		AcceptAllMethod: "all",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, out.Checker)
+	return out.Checker
+}
+
+func TestRun(t *testing.T) {
+	Run(t, MustLoad("testdata/capabilities.yaml"), Checker(newCapabilitiesChecker(t)))
+}
+
+func TestEvaluate(t *testing.T) {
+	f := MustLoad("testdata/capabilities.yaml")
+	allow := f.Cases[0]
+	deny := f.Cases[1]
+	testErr := bascule.NewClassError(bascule.PartnerClass, "wrong partner")
+	tests := []struct {
+		description      string
+		c                Case
+		validator        bascule.Validator
+		expectedFailures int
+	}{
+		{
+			description: "Allowed",
+			c:           allow,
+			validator:   Checker(newCapabilitiesChecker(t)),
+		},
+		{
+			description: "Denied",
+			c:           deny,
+			validator:   Checker(newCapabilitiesChecker(t)),
+		},
+		{
+			description:      "Unexpected Allow",
+			c:                deny,
+			validator:        bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return nil }),
+			expectedFailures: 1,
+		},
+		{
+			description:      "Unexpected Deny",
+			c:                allow,
+			validator:        bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return testErr }),
+			expectedFailures: 1,
+		},
+		{
+			description:      "Wrong Class And Message",
+			c:                deny,
+			validator:        bascule.ValidatorFunc(func(context.Context, bascule.Token) error { return testErr }),
+			expectedFailures: 2,
+		},
+		{
+			description: "Wrong Capability",
+			c:           allow,
+			validator: bascule.ValidatorFunc(func(ctx context.Context, _ bascule.Token) error {
+				d, _ := bascule.DecisionFromContext(ctx)
+				d.SetCapability("x1:webpa:api:.*:all")
+				return nil
+			}),
+			expectedFailures: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r := Evaluate(context.Background(), tc.validator, tc.c)
+			assert.Equal(tc.c, r.Case)
+			assert.NotNil(r.Decision)
+			assert.Len(r.Failures(), tc.expectedFailures, r.Failures())
+		})
+	}
+}
+
+func TestChecker(t *testing.T) {
+	assert := assert.New(t)
+	var endpoint string
+	c := Checker(basculechecks.CapabilitiesCheckerCtxFunc(
+		func(_ context.Context, _ bascule.Authentication, vals basculechecks.ParsedValues) error {
+			endpoint = vals.Endpoint
+			return nil
+		}))
+
+	r := Evaluate(context.Background(), c, Case{
+		Token:   TokenFixture{Principal: "alice"},
+		Request: RequestFixture{Endpoint: "/things"},
+		Expect:  Expectation{Outcome: Allow},
+	})
+	assert.Empty(r.Failures())
+	assert.Equal("/things", endpoint)
+
+	err := c.Check(context.Background(), nil)
+	assert.True(errors.Is(err, bascule.ErrMissingCredentials))
+}
@@ -0,0 +1,191 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// DefaultRedactionMask is the value sensitive data is replaced with.
+const DefaultRedactionMask = "[REDACTED]"
+
+// RedactorOption is how a Redactor is configured.
+type RedactorOption func(*Redactor)
+
+// Redactor masks the values of sensitive attributes so they don't end up in
+// logs, listeners, or error messages.  A nil Redactor redacts nothing.
+type Redactor struct {
+	mask      string
+	keys      map[string]struct{}
+	principal bool
+}
+
+// WithSensitiveKeys classifies the attribute keys given as sensitive.
+func WithSensitiveKeys(keys ...string) RedactorOption {
+	return func(r *Redactor) {
+		for _, k := range keys {
+			r.keys[k] = struct{}{}
+		}
+	}
+}
+
+// WithSensitivePrincipal classifies the token's principal as sensitive.
+func WithSensitivePrincipal() RedactorOption {
+	return func(r *Redactor) {
+		r.principal = true
+	}
+}
+
+// WithMask sets the value that sensitive data is replaced with.
+func WithMask(mask string) RedactorOption {
+	return func(r *Redactor) {
+		if len(mask) > 0 {
+			r.mask = mask
+		}
+	}
+}
+
+// NewRedactor creates a Redactor configured with the options given.
+func NewRedactor(options ...RedactorOption) *Redactor {
+	r := Redactor{
+		mask: DefaultRedactionMask,
+		keys: make(map[string]struct{}),
+	}
+	for _, o := range options {
+		if o != nil {
+			o(&r)
+		}
+	}
+	return &r
+}
+
+// Mask returns the value used to replace sensitive data.
+func (r *Redactor) Mask() string {
+	if r == nil {
+		return DefaultRedactionMask
+	}
+	return r.mask
+}
+
+// IsSensitive returns true if the attribute key given has been classified as
+// sensitive.
+func (r *Redactor) IsSensitive(key string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.keys[key]
+	return ok
+}
+
+// Attributes wraps the attributes given so that sensitive keys return the
+// mask rather than their value.
+func (r *Redactor) Attributes(a Attributes) Attributes {
+	if r == nil || a == nil || len(r.keys) == 0 {
+		return a
+	}
+	return redactedAttributes{r: r, a: a}
+}
+
+// Token returns a copy of the token given with sensitive data masked.
+func (r *Redactor) Token(t Token) Token {
+	if r == nil || t == nil {
+		return t
+	}
+	principal := t.Principal()
+	if r.principal {
+		principal = r.mask
+	}
+	return NewToken(t.Type(), principal, r.Attributes(t.Attributes()))
+}
+
+// Error returns an error whose message has every sensitive value found in the
+// token replaced by the mask.  The original error is still available through
+// errors.Unwrap, errors.Is, and errors.As.
+func (r *Redactor) Error(err error, t Token) error {
+	if r == nil || err == nil || t == nil {
+		return err
+	}
+	var values []string
+	if r.principal && t.Principal() != "" {
+		values = append(values, t.Principal())
+	}
+	if a := t.Attributes(); a != nil {
+		for k := range r.keys {
+			if v, ok := a.Get(k); ok {
+				values = append(values, sensitiveStrings(v)...)
+			}
+		}
+	}
+	msg := err.Error()
+	for _, v := range values {
+		msg = strings.ReplaceAll(msg, v, r.mask)
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return redactedError{err: err, msg: msg}
+}
+
+func sensitiveStrings(v interface{}) []string {
+	if s, err := cast.ToStringSliceE(v); err == nil {
+		result := make([]string, 0, len(s))
+		for _, str := range s {
+			if str != "" {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	if s := fmt.Sprint(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+type redactedAttributes struct {
+	r *Redactor
+	a Attributes
+}
+
+func (ra redactedAttributes) Get(key string) (interface{}, bool) {
+	v, ok := ra.a.Get(key)
+	if ok && ra.r.IsSensitive(key) {
+		return ra.r.mask, true
+	}
+	return v, ok
+}
+
+func (ra redactedAttributes) Keys() ([]string, bool) {
+	return AttributeKeys(ra.a)
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e redactedError) Error() string {
+	return e.msg
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
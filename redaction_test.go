@@ -0,0 +1,116 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactorToken(t *testing.T) {
+	assert := assert.New(t)
+	token := NewToken("jwt", "user@example.com", NewAttributes(map[string]interface{}{
+		"email":    "user@example.com",
+		"deviceID": "mac:112233445566",
+		"other":    "visible",
+	}))
+
+	r := NewRedactor(
+		WithSensitiveKeys("email", "deviceID"),
+		WithSensitivePrincipal(),
+		WithMask("***"),
+		nil,
+	)
+	redacted := r.Token(token)
+	assert.Equal("jwt", redacted.Type())
+	assert.Equal("***", redacted.Principal())
+	v, ok := redacted.Attributes().Get("email")
+	assert.True(ok)
+	assert.Equal("***", v)
+	v, ok = redacted.Attributes().Get("other")
+	assert.True(ok)
+	assert.Equal("visible", v)
+	_, ok = redacted.Attributes().Get("missing")
+	assert.False(ok)
+
+	var nilRedactor *Redactor
+	assert.Equal(token, nilRedactor.Token(token))
+	assert.False(nilRedactor.IsSensitive("email"))
+	assert.Equal(DefaultRedactionMask, nilRedactor.Mask())
+}
+
+func TestRedactorError(t *testing.T) {
+	token := NewToken("jwt", "princ", NewAttributes(map[string]interface{}{
+		"emails": []string{"a@example.com", "b@example.com"},
+		"serial": 12345,
+	}))
+	baseErr := errors.New("base error")
+	tests := []struct {
+		description string
+		redactor    *Redactor
+		err         error
+		token       Token
+		expectedMsg string
+	}{
+		{
+			description: "Success",
+			redactor:    NewRedactor(WithSensitiveKeys("emails", "serial")),
+			err:         fmt.Errorf("%w: a@example.com, b@example.com, 12345", baseErr),
+			token:       token,
+			expectedMsg: "base error: [REDACTED], [REDACTED], [REDACTED]",
+		},
+		{
+			description: "Principal Success",
+			redactor:    NewRedactor(WithSensitivePrincipal()),
+			err:         fmt.Errorf("%w for princ", baseErr),
+			token:       token,
+			expectedMsg: "base error for [REDACTED]",
+		},
+		{
+			description: "Nothing Sensitive Success",
+			redactor:    NewRedactor(WithSensitiveKeys("emails")),
+			err:         baseErr,
+			token:       token,
+			expectedMsg: "base error",
+		},
+		{
+			description: "Nil Redactor Success",
+			err:         fmt.Errorf("%w: a@example.com", baseErr),
+			token:       token,
+			expectedMsg: "base error: a@example.com",
+		},
+		{
+			description: "Nil Token Success",
+			redactor:    NewRedactor(WithSensitiveKeys("emails")),
+			err:         fmt.Errorf("%w: a@example.com", baseErr),
+			expectedMsg: "base error: a@example.com",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			err := tc.redactor.Error(tc.err, tc.token)
+			assert.Equal(tc.expectedMsg, err.Error())
+			assert.ErrorIs(err, baseErr)
+		})
+	}
+	assert.Nil(t, NewRedactor().Error(nil, token))
+}
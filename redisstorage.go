@@ -0,0 +1,138 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// redisIncrScript increments the key and sets its expiration in one step, so
+// a key is never left without one.
+const redisIncrScript = `local n = redis.call('INCR', KEYS[1])
+if tonumber(ARGV[1]) > 0 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return n`
+
+var ErrNilRedisDoer = errors.New("redis doer cannot be nil")
+
+// RedisDoer sends a command to Redis and returns its reply.  It is small so
+// that any Redis client can be adapted to it; with go-redis, for example:
+//
+//	func(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		v, err := client.Do(ctx, args...).Result()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, nil
+//		}
+//		return v, err
+//	}
+//
+// A nil reply must be returned as nil without an error.
+type RedisDoer interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// RedisDoerFunc is a function that implements RedisDoer.
+type RedisDoerFunc func(context.Context, ...interface{}) (interface{}, error)
+
+func (f RedisDoerFunc) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return f(ctx, args...)
+}
+
+// RedisStorage is a Storage backed by Redis, for sharing state between
+// instances.
+type RedisStorage struct {
+	doer   RedisDoer
+	prefix string
+}
+
+// NewRedisStorage creates a RedisStorage that sends commands with the doer
+// given.  The prefix is added to every key, so that one Redis can be shared
+// by several services.
+func NewRedisStorage(doer RedisDoer, prefix string) (*RedisStorage, error) {
+	if doer == nil {
+		return nil, ErrNilRedisDoer
+	}
+	return &RedisStorage{
+		doer:   doer,
+		prefix: prefix,
+	}, nil
+}
+
+// Get returns the value stored at the key.
+func (r *RedisStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.doer.Do(ctx, "GET", r.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, ErrKeyNotFound
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply type %T", v)
+}
+
+// Set stores the value at the key.
+func (r *RedisStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []interface{}{"SET", r.prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", redisMillis(ttl))
+	}
+	_, err := r.doer.Do(ctx, args...)
+	return err
+}
+
+// Incr adds one to the integer stored at the key.
+func (r *RedisStorage) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	v, err := r.doer.Do(ctx, "EVAL", redisIncrScript, 1, r.prefix+key, redisMillis(ttl))
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected redis reply type %T", v)
+}
+
+// Delete removes the key.
+func (r *RedisStorage) Delete(ctx context.Context, key string) error {
+	_, err := r.doer.Do(ctx, "DEL", r.prefix+key)
+	return err
+}
+
+// redisMillis converts the TTL to milliseconds, rounding positive TTLs up to
+// at least one since Redis rejects an expiration of zero.
+func redisMillis(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	ms := ttl.Milliseconds()
+	if ms == 0 {
+		ms = 1
+	}
+	return ms
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStorage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	_, err := NewRedisStorage(nil, "")
+	assert.ErrorIs(err, ErrNilRedisDoer)
+
+	var commands [][]interface{}
+	replies := map[string]interface{}{}
+	r, err := NewRedisStorage(RedisDoerFunc(func(_ context.Context, args ...interface{}) (interface{}, error) {
+		commands = append(commands, args)
+		cmd := args[0].(string)
+		if cmd == "FAIL" {
+			return nil, errors.New("test")
+		}
+		return replies[cmd], nil
+	}), "svc:")
+	require.NoError(err)
+
+	_, err = r.Get(ctx, "a")
+	assert.ErrorIs(err, ErrKeyNotFound)
+	replies["GET"] = "value"
+	v, err := r.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal([]byte("value"), v)
+	replies["GET"] = []byte("bytes")
+	v, err = r.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal([]byte("bytes"), v)
+	replies["GET"] = 1
+	_, err = r.Get(ctx, "a")
+	assert.Error(err)
+
+	assert.NoError(r.Set(ctx, "a", []byte("v"), time.Second))
+	assert.NoError(r.Set(ctx, "b", []byte("v"), 0))
+	assert.NoError(r.Set(ctx, "c", []byte("v"), time.Microsecond))
+
+	replies["EVAL"] = int64(3)
+	n, err := r.Incr(ctx, "n", time.Minute)
+	assert.NoError(err)
+	assert.Equal(int64(3), n)
+	replies["EVAL"] = "4"
+	n, err = r.Incr(ctx, "n", 0)
+	assert.NoError(err)
+	assert.Equal(int64(4), n)
+	replies["EVAL"] = 1.5
+	_, err = r.Incr(ctx, "n", 0)
+	assert.Error(err)
+
+	assert.NoError(r.Delete(ctx, "a"))
+
+	assert.Equal([][]interface{}{
+		{"GET", "svc:a"},
+		{"GET", "svc:a"},
+		{"GET", "svc:a"},
+		{"GET", "svc:a"},
+		{"SET", "svc:a", []byte("v"), "PX", int64(1000)},
+		{"SET", "svc:b", []byte("v")},
+		{"SET", "svc:c", []byte("v"), "PX", int64(1)},
+		{"EVAL", redisIncrScript, 1, "svc:n", int64(60000)},
+		{"EVAL", redisIncrScript, 1, "svc:n", int64(0)},
+		{"EVAL", redisIncrScript, 1, "svc:n", int64(0)},
+		{"DEL", "svc:a"},
+	}, commands)
+
+	f, err := NewRedisStorage(RedisDoerFunc(func(context.Context, ...interface{}) (interface{}, error) {
+		return nil, errors.New("test")
+	}), "")
+	require.NoError(err)
+	_, err = f.Get(ctx, "a")
+	assert.Error(err)
+	_, err = f.Incr(ctx, "a", 0)
+	assert.Error(err)
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"runtime"
+)
+
+// ZeroizingString holds a secret, such as a password or API key.  Comparisons
+// against it take constant time, including for inputs of different lengths,
+// and its bytes are overwritten by Zero or, failing that, when it's garbage
+// collected.  It never formats as its value, so it's safe to log.
+//
+// Zeroization is best effort: Go may have copied the secret elsewhere before
+// it was wrapped, and strings the secret came from can't be overwritten.
+type ZeroizingString struct {
+	b []byte
+}
+
+// NewZeroizingString copies the secret given into a ZeroizingString.
+func NewZeroizingString(secret string) *ZeroizingString {
+	return newZeroizing([]byte(secret))
+}
+
+// NewZeroizingBytes copies the secret given into a ZeroizingString, then
+// zeroes the slice given.
+func NewZeroizingBytes(secret []byte) *ZeroizingString {
+	b := make([]byte, len(secret))
+	copy(b, secret)
+	Zero(secret)
+	return newZeroizing(b)
+}
+
+func newZeroizing(b []byte) *ZeroizingString {
+	z := &ZeroizingString{b: b}
+	runtime.SetFinalizer(z, (*ZeroizingString).Zero)
+	return z
+}
+
+// Equal reports whether the secret matches the value given, in time that
+// doesn't depend on either's contents or length.  A nil or zeroed
+// ZeroizingString matches nothing.
+func (z *ZeroizingString) Equal(value []byte) bool {
+	if z == nil || z.b == nil {
+		return false
+	}
+	return SecretEqual(z.b, value)
+}
+
+// EqualString is Equal for a string value.
+func (z *ZeroizingString) EqualString(value string) bool {
+	return z.Equal([]byte(value))
+}
+
+// Len returns the length of the secret.
+func (z *ZeroizingString) Len() int {
+	if z == nil {
+		return 0
+	}
+	return len(z.b)
+}
+
+// Zeroed reports whether the secret has been zeroed and can no longer be
+// used.
+func (z *ZeroizingString) Zeroed() bool {
+	return z == nil || z.b == nil
+}
+
+// Zero overwrites the secret.  Afterwards, the ZeroizingString matches
+// nothing.
+func (z *ZeroizingString) Zero() {
+	if z == nil {
+		return
+	}
+	Zero(z.b)
+	z.b = nil
+}
+
+// Reveal calls the function given with the secret's bytes, for the rare
+// cases where the plaintext is needed, such as signing a request.  The bytes
+// must not be retained after the function returns.
+func (z *ZeroizingString) Reveal(f func([]byte)) {
+	if z == nil || z.b == nil {
+		f(nil)
+		return
+	}
+	f(z.b)
+	runtime.KeepAlive(z)
+}
+
+// String returns DefaultRedactionMask, never the secret.
+func (z *ZeroizingString) String() string {
+	return DefaultRedactionMask
+}
+
+// GoString returns DefaultRedactionMask, so %#v doesn't reveal the secret.
+func (z *ZeroizingString) GoString() string {
+	return DefaultRedactionMask
+}
+
+// MarshalText returns DefaultRedactionMask, so encoding the secret as JSON
+// or YAML doesn't reveal it.
+func (z *ZeroizingString) MarshalText() ([]byte, error) {
+	return []byte(DefaultRedactionMask), nil
+}
+
+// SecretEqual reports whether two secrets are equal, in time that doesn't
+// depend on either's contents or length.  The secrets are hashed before
+// they're compared, since subtle.ConstantTimeCompare returns early when the
+// lengths differ.
+func SecretEqual(expected, actual []byte) bool {
+	e := sha256.Sum256(expected)
+	a := sha256.Sum256(actual)
+	return subtle.ConstantTimeCompare(e[:], a[:]) == 1
+}
+
+// Zero overwrites the bytes given.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZeroizingString(t *testing.T) {
+	tests := []struct {
+		description string
+		secret      *ZeroizingString
+		value       string
+		expected    bool
+	}{
+		{
+			description: "Match",
+			secret:      NewZeroizingString("pass"),
+			value:       "pass",
+			expected:    true,
+		},
+		{
+			description: "Empty Match",
+			secret:      NewZeroizingString(""),
+			value:       "",
+			expected:    true,
+		},
+		{
+			description: "Mismatch",
+			secret:      NewZeroizingString("pass"),
+			value:       "pasS",
+		},
+		{
+			description: "Prefix Mismatch",
+			secret:      NewZeroizingString("pass"),
+			value:       "pas",
+		},
+		{
+			description: "Nil",
+			value:       "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expected, tc.secret.EqualString(tc.value))
+			assert.Equal(tc.expected, tc.secret.Equal([]byte(tc.value)))
+		})
+	}
+}
+
+func TestSecretEqual(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(SecretEqual([]byte("pass"), []byte("pass")))
+	assert.True(SecretEqual(nil, []byte{}))
+	assert.False(SecretEqual([]byte("pass"), []byte("pas")))
+	assert.False(SecretEqual([]byte("pass"), []byte("pasS")))
+	assert.False(SecretEqual(nil, []byte("pass")))
+}
+
+func TestZeroizingStringZero(t *testing.T) {
+	assert := assert.New(t)
+	source := []byte("pass")
+	z := NewZeroizingBytes(source)
+	assert.Equal([]byte{0, 0, 0, 0}, source)
+	assert.Equal(4, z.Len())
+	assert.False(z.Zeroed())
+	assert.True(z.EqualString("pass"))
+
+	var revealed []byte
+	z.Reveal(func(b []byte) { revealed = b })
+	assert.Equal([]byte("pass"), revealed)
+
+	z.Zero()
+	assert.Equal([]byte{0, 0, 0, 0}, revealed)
+	assert.True(z.Zeroed())
+	assert.Equal(0, z.Len())
+	assert.False(z.EqualString("pass"))
+	assert.False(z.EqualString(""))
+	z.Reveal(func(b []byte) { assert.Nil(b) })
+
+	var nilZ *ZeroizingString
+	nilZ.Zero()
+	assert.True(nilZ.Zeroed())
+	assert.Equal(0, nilZ.Len())
+}
+
+func TestZeroizingStringFormatting(t *testing.T) {
+	assert := assert.New(t)
+	z := NewZeroizingString("pass")
+	assert.Equal(DefaultRedactionMask, z.String())
+	assert.Equal(DefaultRedactionMask, fmt.Sprintf("%v", z))
+	assert.Equal(DefaultRedactionMask, fmt.Sprintf("%#v", z))
+	assert.NotContains(fmt.Sprintf("%+v", map[string]*ZeroizingString{"user": z}), "pass")
+
+	b, err := json.Marshal(struct{ Secret *ZeroizingString }{z})
+	assert.NoError(err)
+	assert.JSONEq(`{"Secret":"[REDACTED]"}`, string(b))
+}
@@ -0,0 +1,585 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnserializableValue  = errors.New("value cannot be serialized")
+	ErrInvalidSerialization = errors.New("invalid serialized authentication")
+)
+
+// The types of a SerializedValue that aren't basic Go types.  Basic Go types
+// are named as they are in Go, as are slices of them and maps of strings to
+// them, e.g. "int64", "[]string", and "map[string]float64".
+const (
+	nilValue        = "nil"
+	listValue       = "list"
+	mapValue        = "map"
+	attributesValue = "attributes"
+	tokenValue      = "token"
+	tokenMapValue   = "map[string]token"
+	kindValue       = "kind"
+	timeValue       = "time"
+	durationValue   = "duration"
+	bytesValue      = "bytes"
+	numberValue     = "number"
+)
+
+// basicTypes are the Go types a SerializedValue holds as is.
+var basicTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+	} {
+		t := reflect.TypeOf(v)
+		basicTypes[t.String()] = t
+	}
+}
+
+// SerializedAuthentication is the canonical serialized form of an
+// Authentication, for passing it between processes like ext_authz servers,
+// audit pipelines, and caches.  It follows the Authentication message in
+// authentication.proto, and its JSON form is that message's protobuf JSON
+// mapping.
+type SerializedAuthentication struct {
+	Authorization string            `json:"authorization,omitempty"`
+	Token         *SerializedToken  `json:"token,omitempty"`
+	Request       SerializedRequest `json:"request"`
+}
+
+// SerializedToken is the serialized form of a Token.  Its attributes must be
+// able to list their keys; see KeyLister.
+type SerializedToken struct {
+	Type       string           `json:"type"`
+	Principal  string           `json:"principal"`
+	Attributes *SerializedValue `json:"attributes,omitempty"`
+}
+
+// SerializedRequest is the serialized form of a Request.
+type SerializedRequest struct {
+	URL             string `json:"url,omitempty"`
+	Method          string `json:"method,omitempty"`
+	ClientIP        string `json:"clientIp,omitempty"`
+	UserAgent       string `json:"userAgent,omitempty"`
+	Time            string `json:"time,omitempty"`
+	DigestAlgorithm string `json:"digestAlgorithm,omitempty"`
+	DigestSum       []byte `json:"digestSum,omitempty"`
+	RequestID       string `json:"requestId,omitempty"`
+}
+
+// SerializedValue is a serialized attribute value.  Its type is kept along
+// with it so that the value is rebuilt with the same Go type, e.g. an int64
+// instead of the float64 plain JSON would give back.  Only the field for the
+// type is set.
+type SerializedValue struct {
+	Type   string                     `json:"type"`
+	Bool   bool                       `json:"boolValue,omitempty"`
+	Int    int64                      `json:"intValue,omitempty,string"`
+	Uint   uint64                     `json:"uintValue,omitempty,string"`
+	Float  float64                    `json:"floatValue,omitempty"`
+	String string                     `json:"stringValue,omitempty"`
+	Bytes  []byte                     `json:"bytesValue,omitempty"`
+	List   []SerializedValue          `json:"listValue,omitempty"`
+	Map    map[string]SerializedValue `json:"mapValue,omitempty"`
+	Token  *SerializedToken           `json:"tokenValue,omitempty"`
+}
+
+// SerializeAuthentication returns the serialized form of the Authentication.
+// It fails if the token's attributes can't be listed or hold a value that
+// can't be serialized.
+func SerializeAuthentication(a Authentication) (SerializedAuthentication, error) {
+	token, err := SerializeToken(a.Token)
+	if err != nil {
+		return SerializedAuthentication{}, err
+	}
+	return SerializedAuthentication{
+		Authorization: string(a.Authorization),
+		Token:         token,
+		Request:       serializeRequest(a.Request),
+	}, nil
+}
+
+// Authentication rebuilds the Authentication that was serialized.  Its token
+// is a basic Token with BasicAttributes, whatever the original token was.
+func (s SerializedAuthentication) Authentication() (Authentication, error) {
+	a := Authentication{Authorization: Authorization(s.Authorization)}
+	if s.Token != nil {
+		token, err := s.Token.Token()
+		if err != nil {
+			return Authentication{}, err
+		}
+		a.Token = token
+	}
+	request, err := s.Request.request()
+	if err != nil {
+		return Authentication{}, err
+	}
+	a.Request = request
+	return a, nil
+}
+
+// MarshalJSON writes the Authentication's canonical JSON form.
+func (a Authentication) MarshalJSON() ([]byte, error) {
+	s, err := SerializeAuthentication(a)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON reads the Authentication's canonical JSON form.
+func (a *Authentication) UnmarshalJSON(data []byte) error {
+	var s SerializedAuthentication
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	auth, err := s.Authentication()
+	if err != nil {
+		return err
+	}
+	*a = auth
+	return nil
+}
+
+// MarshalProto writes the Authentication's canonical protobuf form.
+func (a Authentication) MarshalProto() ([]byte, error) {
+	s, err := SerializeAuthentication(a)
+	if err != nil {
+		return nil, err
+	}
+	return s.MarshalProto(), nil
+}
+
+// NewFromSerialized rebuilds an Authentication from its canonical JSON or
+// protobuf form, telling them apart by the JSON object's opening brace.
+func NewFromSerialized(data []byte) (Authentication, error) {
+	var s SerializedAuthentication
+	if err := unmarshalSerialized(data, &s); err != nil {
+		return Authentication{}, err
+	}
+	return s.Authentication()
+}
+
+// SerializeToken returns the serialized form of the Token, or nil if there is
+// no token.
+func SerializeToken(t Token) (*SerializedToken, error) {
+	if t == nil {
+		return nil, nil
+	}
+	s := &SerializedToken{
+		Type:      t.Type(),
+		Principal: t.Principal(),
+	}
+	if a := t.Attributes(); a != nil {
+		attributes, err := serializeAttributes("", a)
+		if err != nil {
+			return nil, err
+		}
+		s.Attributes = &attributes
+	}
+	return s, nil
+}
+
+// Token rebuilds the Token that was serialized.
+func (s SerializedToken) Token() (Token, error) {
+	if s.Attributes == nil {
+		return NewToken(s.Type, s.Principal, nil), nil
+	}
+	if s.Attributes.Type != attributesValue {
+		return nil, fmt.Errorf("%w: token attributes have type %q", ErrInvalidSerialization, s.Attributes.Type)
+	}
+	v, err := s.Attributes.value("")
+	if err != nil {
+		return nil, err
+	}
+	return NewToken(s.Type, s.Principal, v.(Attributes)), nil
+}
+
+// MarshalJSON writes the token's canonical JSON form.
+func (st simpleToken) MarshalJSON() ([]byte, error) {
+	s, err := SerializeToken(st)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// NewTokenFromSerialized rebuilds a Token from its canonical JSON or protobuf
+// form.
+func NewTokenFromSerialized(data []byte) (Token, error) {
+	var s SerializedToken
+	if err := unmarshalSerialized(data, &s); err != nil {
+		return nil, err
+	}
+	return s.Token()
+}
+
+// protoUnmarshaler is implemented by the serialized types.
+type protoUnmarshaler interface {
+	UnmarshalProto([]byte) error
+}
+
+func unmarshalSerialized(data []byte, s protoUnmarshaler) error {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return fmt.Errorf("%w: no data", ErrInvalidSerialization)
+	}
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, s); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSerialization, err)
+		}
+		return nil
+	}
+	return s.UnmarshalProto(data)
+}
+
+func serializeRequest(r Request) SerializedRequest {
+	s := SerializedRequest{
+		Method:    r.Method,
+		ClientIP:  r.ClientIP,
+		UserAgent: r.UserAgent,
+		RequestID: r.RequestID,
+	}
+	if r.URL != nil {
+		s.URL = r.URL.String()
+	}
+	if !r.Time.IsZero() {
+		s.Time = r.Time.Format(time.RFC3339Nano)
+	}
+	if r.Digest != nil {
+		s.DigestAlgorithm = r.Digest.Algorithm
+		s.DigestSum = r.Digest.Sum
+	}
+	return s
+}
+
+func (s SerializedRequest) request() (Request, error) {
+	r := Request{
+		Method:    s.Method,
+		ClientIP:  s.ClientIP,
+		UserAgent: s.UserAgent,
+		RequestID: s.RequestID,
+	}
+	if s.URL != "" {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return Request{}, fmt.Errorf("%w: %v", ErrInvalidSerialization, err)
+		}
+		r.URL = u
+	}
+	if s.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, s.Time)
+		if err != nil {
+			return Request{}, fmt.Errorf("%w: %v", ErrInvalidSerialization, err)
+		}
+		r.Time = t
+	}
+	if s.DigestAlgorithm != "" || len(s.DigestSum) > 0 {
+		r.Digest = &Digest{Algorithm: s.DigestAlgorithm, Sum: s.DigestSum}
+	}
+	return r, nil
+}
+
+func serializeAttributes(path string, a Attributes) (SerializedValue, error) {
+	keys, ok := AttributeKeys(a)
+	if !ok {
+		return SerializedValue{}, fmt.Errorf("%w: attributes %s of type %T can't list their keys",
+			ErrUnserializableValue, pathName(path), a)
+	}
+	s := SerializedValue{Type: attributesValue, Map: make(map[string]SerializedValue, len(keys))}
+	for _, k := range keys {
+		v, ok := a.Get(k)
+		if !ok {
+			continue
+		}
+		sv, err := serializeValue(joinPath(path, k), v)
+		if err != nil {
+			return SerializedValue{}, err
+		}
+		s.Map[k] = sv
+	}
+	return s, nil
+}
+
+func serializeValue(path string, v interface{}) (SerializedValue, error) {
+	switch t := v.(type) {
+	case nil:
+		return SerializedValue{Type: nilValue}, nil
+	case TokenKind:
+		return SerializedValue{Type: kindValue, String: string(t)}, nil
+	case time.Time:
+		return SerializedValue{Type: timeValue, String: t.Format(time.RFC3339Nano)}, nil
+	case time.Duration:
+		return SerializedValue{Type: durationValue, Int: int64(t)}, nil
+	case []byte:
+		return SerializedValue{Type: bytesValue, Bytes: t}, nil
+	case json.Number:
+		return SerializedValue{Type: numberValue, String: string(t)}, nil
+	case Token:
+		token, err := SerializeToken(t)
+		if err != nil {
+			return SerializedValue{}, err
+		}
+		return SerializedValue{Type: tokenValue, Token: token}, nil
+	case map[string]Token:
+		s := SerializedValue{Type: tokenMapValue, Map: make(map[string]SerializedValue, len(t))}
+		for k, token := range t {
+			sv, err := serializeValue(joinPath(path, k), token)
+			if err != nil {
+				return SerializedValue{}, err
+			}
+			s.Map[k] = sv
+		}
+		return s, nil
+	case []interface{}:
+		s := SerializedValue{Type: listValue, List: make([]SerializedValue, len(t))}
+		for i, e := range t {
+			sv, err := serializeValue(path+"["+strconv.Itoa(i)+"]", e)
+			if err != nil {
+				return SerializedValue{}, err
+			}
+			s.List[i] = sv
+		}
+		return s, nil
+	case map[string]interface{}:
+		s := SerializedValue{Type: mapValue, Map: make(map[string]SerializedValue, len(t))}
+		for k, e := range t {
+			sv, err := serializeValue(joinPath(path, k), e)
+			if err != nil {
+				return SerializedValue{}, err
+			}
+			s.Map[k] = sv
+		}
+		return s, nil
+	case Attributes:
+		return serializeAttributes(path, t)
+	}
+
+	rv := reflect.ValueOf(v)
+	if name, ok := basicName(rv.Type()); ok {
+		return serializeBasic(name, rv), nil
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		if name, ok := basicName(rv.Type().Elem()); ok {
+			s := SerializedValue{Type: "[]" + name, List: make([]SerializedValue, rv.Len())}
+			for i := range s.List {
+				s.List[i] = serializeBasic(name, rv.Index(i))
+			}
+			return s, nil
+		}
+	case reflect.Map:
+		if name, ok := basicName(rv.Type().Elem()); ok && rv.Type().Key() == basicTypes["string"] {
+			s := SerializedValue{Type: "map[string]" + name, Map: make(map[string]SerializedValue, rv.Len())}
+			iter := rv.MapRange()
+			for iter.Next() {
+				s.Map[iter.Key().String()] = serializeBasic(name, iter.Value())
+			}
+			return s, nil
+		}
+	}
+	return SerializedValue{}, fmt.Errorf("%w: attribute %s has type %T", ErrUnserializableValue, pathName(path), v)
+}
+
+// basicName returns the name of the type if it is a basic Go type and not a
+// type defined from one.
+func basicName(t reflect.Type) (string, bool) {
+	name := t.String()
+	return name, basicTypes[name] == t
+}
+
+func serializeBasic(name string, v reflect.Value) SerializedValue {
+	s := SerializedValue{Type: name}
+	switch v.Kind() {
+	case reflect.Bool:
+		s.Bool = v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.Int = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Uint = v.Uint()
+	case reflect.Float32, reflect.Float64:
+		s.Float = v.Float()
+	case reflect.String:
+		s.String = v.String()
+	}
+	return s
+}
+
+// value rebuilds the value that was serialized.
+func (s SerializedValue) value(path string) (interface{}, error) {
+	switch s.Type {
+	case nilValue:
+		return nil, nil
+	case kindValue:
+		return TokenKind(s.String), nil
+	case timeValue:
+		t, err := time.Parse(time.RFC3339Nano, s.String)
+		if err != nil {
+			return nil, fmt.Errorf("%w: attribute %s: %v", ErrInvalidSerialization, pathName(path), err)
+		}
+		return t, nil
+	case durationValue:
+		return time.Duration(s.Int), nil
+	case bytesValue:
+		return append([]byte{}, s.Bytes...), nil
+	case numberValue:
+		return json.Number(s.String), nil
+	case tokenValue:
+		if s.Token == nil {
+			return nil, fmt.Errorf("%w: attribute %s has no token", ErrInvalidSerialization, pathName(path))
+		}
+		return s.Token.Token()
+	case tokenMapValue:
+		m := make(map[string]Token, len(s.Map))
+		for k, e := range s.Map {
+			v, err := e.value(joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			token, ok := v.(Token)
+			if !ok {
+				return nil, fmt.Errorf("%w: attribute %s isn't a token", ErrInvalidSerialization, pathName(joinPath(path, k)))
+			}
+			m[k] = token
+		}
+		return m, nil
+	case listValue:
+		l := make([]interface{}, len(s.List))
+		for i, e := range s.List {
+			v, err := e.value(path + "[" + strconv.Itoa(i) + "]")
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+	case mapValue, attributesValue:
+		m := make(map[string]interface{}, len(s.Map))
+		for k, e := range s.Map {
+			v, err := e.value(joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		if s.Type == attributesValue {
+			return BasicAttributes(m), nil
+		}
+		return m, nil
+	}
+
+	if t, ok := basicTypes[s.Type]; ok {
+		v, err := s.basic(path, t)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+	if t, ok := basicTypes[strings.TrimPrefix(s.Type, "[]")]; ok && strings.HasPrefix(s.Type, "[]") {
+		l := reflect.MakeSlice(reflect.SliceOf(t), len(s.List), len(s.List))
+		for i, e := range s.List {
+			v, err := e.basic(path+"["+strconv.Itoa(i)+"]", t)
+			if err != nil {
+				return nil, err
+			}
+			l.Index(i).Set(v)
+		}
+		return l.Interface(), nil
+	}
+	if t, ok := basicTypes[strings.TrimPrefix(s.Type, "map[string]")]; ok && strings.HasPrefix(s.Type, "map[string]") {
+		m := reflect.MakeMapWithSize(reflect.MapOf(basicTypes["string"], t), len(s.Map))
+		for k, e := range s.Map {
+			v, err := e.basic(joinPath(path, k), t)
+			if err != nil {
+				return nil, err
+			}
+			m.SetMapIndex(reflect.ValueOf(k), v)
+		}
+		return m.Interface(), nil
+	}
+	return nil, fmt.Errorf("%w: attribute %s has unknown type %q", ErrInvalidSerialization, pathName(path), s.Type)
+}
+
+// basic rebuilds a basic Go value of the type given.
+func (s SerializedValue) basic(path string, t reflect.Type) (reflect.Value, error) {
+	if s.Type != t.String() {
+		return reflect.Value{}, fmt.Errorf("%w: attribute %s has type %q instead of %q",
+			ErrInvalidSerialization, pathName(path), s.Type, t.String())
+	}
+	v := reflect.New(t).Elem()
+	overflow := false
+	switch t.Kind() {
+	case reflect.Bool:
+		v.SetBool(s.Bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		overflow = v.OverflowInt(s.Int)
+		v.SetInt(s.Int)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		overflow = v.OverflowUint(s.Uint)
+		v.SetUint(s.Uint)
+	case reflect.Float32, reflect.Float64:
+		overflow = v.OverflowFloat(s.Float)
+		v.SetFloat(s.Float)
+	case reflect.String:
+		v.SetString(s.String)
+	}
+	if overflow {
+		return reflect.Value{}, fmt.Errorf("%w: attribute %s overflows %s", ErrInvalidSerialization, pathName(path), s.Type)
+	}
+	return v, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return strconv.Quote(path)
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys(m map[string]SerializedValue) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,297 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSerializationAuth() Authentication {
+	u, _ := url.Parse("https://example.com/api/v1/devices?q=1")
+	return Authentication{
+		Authorization: "Bearer",
+		Token: NewToken("jwt", "alice", BasicAttributes{
+			"int":      int64(1) << 60,
+			"uint":     uint32(7),
+			"float":    1.5,
+			"float32":  float32(0.25),
+			"bool":     true,
+			"string":   "value",
+			"nil":      nil,
+			"kind":     UserKind,
+			"time":     time.Date(2023, time.March, 4, 5, 6, 7, 8, time.FixedZone("EST", -5*3600)),
+			"duration": time.Minute,
+			"bytes":    []byte{0, 1, 2},
+			"number":   json.Number("12345678901234567890"),
+			"strings":  []string{"a", "b"},
+			"ints":     []int{1, 2, 3},
+			"labels":   map[string]string{"a": "b"},
+			"list":     []interface{}{"a", 1, []interface{}{false}},
+			"map": map[string]interface{}{
+				"nested": map[string]interface{}{"capabilities": []interface{}{"x:y"}},
+			},
+			"attributes": BasicAttributes{"a": "b"},
+			"token":      NewToken("basic", "bob", nil),
+			"credentials": map[string]Token{
+				"device": NewToken("device", "mac:112233445566", BasicAttributes{TokenKindKey: DeviceKind}),
+			},
+		}),
+		Request: Request{
+			URL:       u,
+			Method:    "GET",
+			ClientIP:  "10.0.0.1",
+			UserAgent: "agent",
+			Time:      time.Date(2023, time.March, 4, 5, 6, 7, 8, time.UTC),
+			Digest:    &Digest{Algorithm: "sha-256", Sum: []byte{9, 8, 7}},
+			RequestID: "abc123",
+		},
+	}
+}
+
+func TestSerializationRoundTrip(t *testing.T) {
+	tests := []struct {
+		description string
+		auth        Authentication
+	}{
+		{
+			description: "Full",
+			auth:        testSerializationAuth(),
+		},
+		{
+			description: "No Token",
+			auth:        Authentication{Authorization: "Basic"},
+		},
+		{
+			description: "Token Without Attributes",
+			auth:        Authentication{Token: NewToken("basic", "bob", nil)},
+		},
+		{
+			description: "Layered Attributes",
+			auth: Authentication{
+				Token: NewToken("jwt", "alice", mappedAttributes{
+					canonical: BasicAttributes{"partner": "comcast"},
+					base:      BasicAttributes{"sub": "alice"},
+				}),
+			},
+		},
+	}
+	marshalers := []struct {
+		description string
+		marshal     func(Authentication) ([]byte, error)
+	}{
+		{
+			description: "JSON",
+			marshal: func(a Authentication) ([]byte, error) {
+				return json.Marshal(a)
+			},
+		},
+		{
+			description: "Proto",
+			marshal: func(a Authentication) ([]byte, error) {
+				return a.MarshalProto()
+			},
+		},
+	}
+	for _, m := range marshalers {
+		for _, tc := range tests {
+			t.Run(m.description+" "+tc.description, func(t *testing.T) {
+				assert := assert.New(t)
+				require := require.New(t)
+				data, err := m.marshal(tc.auth)
+				require.NoError(err)
+				auth, err := NewFromSerialized(data)
+				require.NoError(err)
+
+				// layered attributes come back as BasicAttributes.
+				expected, err := SerializeAuthentication(tc.auth)
+				require.NoError(err)
+				actual, err := SerializeAuthentication(auth)
+				require.NoError(err)
+				assert.Equal(expected, actual)
+
+				assert.Equal(tc.auth.Authorization, auth.Authorization)
+				if tc.auth.Token == nil {
+					assert.Nil(auth.Token)
+					return
+				}
+				assert.Equal(tc.auth.Token.Type(), auth.Token.Type())
+				assert.Equal(tc.auth.Token.Principal(), auth.Token.Principal())
+			})
+		}
+	}
+}
+
+func TestSerializationTypeFidelity(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	expected := testSerializationAuth()
+	data, err := json.Marshal(expected)
+	require.NoError(err)
+
+	var auth Authentication
+	require.NoError(json.Unmarshal(data, &auth))
+	assert.Equal(expected.Request, auth.Request)
+	attributes := auth.Token.Attributes()
+	for _, k := range []string{
+		"int", "uint", "float", "float32", "bool", "string", "nil", "kind",
+		"duration", "bytes", "number", "strings", "ints", "labels", "list", "map",
+		"attributes", "token",
+	} {
+		v, ok := attributes.Get(k)
+		assert.True(ok, k)
+		e, _ := expected.Token.Attributes().Get(k)
+		assert.Equal(e, v, k)
+	}
+	v, _ := attributes.Get("time")
+	e, _ := expected.Token.Attributes().Get("time")
+	assert.True(e.(time.Time).Equal(v.(time.Time)))
+
+	v, _ = attributes.Get("credentials")
+	credentials, ok := v.(map[string]Token)
+	require.True(ok)
+	assert.Equal("mac:112233445566", credentials["device"].Principal())
+	assert.Equal(DeviceKind, kindFromAttributes(credentials["device"].Attributes()))
+}
+
+func TestSerializeErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		token       Token
+		expectedErr string
+	}{
+		{
+			description: "Unlisted Attributes",
+			token:       NewToken("jwt", "alice", unlistedAttributes{}),
+			expectedErr: `attributes <root> of type bascule.unlistedAttributes can't list their keys`,
+		},
+		{
+			description: "Unsupported Type",
+			token: NewToken("jwt", "alice", BasicAttributes{
+				"a": map[string]interface{}{"b": []interface{}{struct{}{}}},
+			}),
+			expectedErr: `attribute "a.b[0]" has type struct {}`,
+		},
+		{
+			description: "Defined Type",
+			token:       NewToken("jwt", "alice", BasicAttributes{"a": definedInt(1)}),
+			expectedErr: `attribute "a" has type bascule.definedInt`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := json.Marshal(Authentication{Token: tc.token})
+			assert.True(errors.Is(err, ErrUnserializableValue))
+			assert.Contains(err.Error(), tc.expectedErr)
+			_, err = SerializeToken(tc.token)
+			assert.ErrorIs(err, ErrUnserializableValue)
+		})
+	}
+}
+
+type definedInt int
+
+func TestNewFromSerializedErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+		expectedErr string
+	}{
+		{
+			description: "Empty",
+			data:        " \n",
+			expectedErr: "no data",
+		},
+		{
+			description: "Bad JSON",
+			data:        `{"token": 5}`,
+		},
+		{
+			description: "Bad Proto",
+			data:        "\x0a\x05ab",
+		},
+		{
+			description: "Unknown Value Type",
+			data:        `{"token":{"type":"jwt","principal":"a","attributes":{"type":"attributes","mapValue":{"a":{"type":"complex128"}}}}}`,
+			expectedErr: `attribute "a" has unknown type "complex128"`,
+		},
+		{
+			description: "Attributes Not A Map",
+			data:        `{"token":{"type":"jwt","principal":"a","attributes":{"type":"string"}}}`,
+			expectedErr: `token attributes have type "string"`,
+		},
+		{
+			description: "Overflow",
+			data:        `{"token":{"type":"jwt","principal":"a","attributes":{"type":"attributes","mapValue":{"a":{"type":"int8","intValue":"300"}}}}}`,
+			expectedErr: `attribute "a" overflows int8`,
+		},
+		{
+			description: "Mismatched Element Type",
+			data:        `{"token":{"type":"jwt","principal":"a","attributes":{"type":"attributes","mapValue":{"a":{"type":"[]int","listValue":[{"type":"string"}]}}}}}`,
+			expectedErr: `attribute "a[0]" has type "string" instead of "int"`,
+		},
+		{
+			description: "Bad Time",
+			data:        `{"request":{"time":"yesterday"}}`,
+		},
+		{
+			description: "Bad URL",
+			data:        `{"request":{"url":"%zz"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := NewFromSerialized([]byte(tc.data))
+			assert.ErrorIs(err, ErrInvalidSerialization)
+			assert.Contains(err.Error(), tc.expectedErr)
+		})
+	}
+}
+
+func TestTokenSerialization(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	token := NewToken("jwt", "alice", BasicAttributes{"count": 3, "kind": ServiceKind})
+
+	data, err := json.Marshal(token)
+	require.NoError(err)
+	assert.JSONEq(`{"type":"jwt","principal":"alice","attributes":{"type":"attributes","mapValue":{
+		"count":{"type":"int","intValue":"3"},
+		"kind":{"type":"kind","stringValue":"service"}
+	}}}`, string(data))
+
+	s, err := SerializeToken(token)
+	require.NoError(err)
+	for _, data := range [][]byte{data, s.MarshalProto()} {
+		rebuilt, err := NewTokenFromSerialized(data)
+		require.NoError(err)
+		assert.Equal(token, rebuilt)
+	}
+
+	s, err = SerializeToken(nil)
+	assert.NoError(err)
+	assert.Nil(s)
+}
@@ -0,0 +1,281 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MarshalProto writes the Authentication message in authentication.proto.
+func (s SerializedAuthentication) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, s.Authorization)
+	if s.Token != nil {
+		b = appendProtoMessage(b, 2, s.Token.MarshalProto())
+	}
+	if request := s.Request.MarshalProto(); len(request) > 0 {
+		b = appendProtoMessage(b, 3, request)
+	}
+	return b
+}
+
+// UnmarshalProto reads the Authentication message in authentication.proto.
+func (s *SerializedAuthentication) UnmarshalProto(b []byte) error {
+	*s = SerializedAuthentication{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			s.Authorization = string(f.bytes)
+		case 2:
+			s.Token = new(SerializedToken)
+			return s.Token.UnmarshalProto(f.bytes)
+		case 3:
+			return s.Request.UnmarshalProto(f.bytes)
+		}
+		return nil
+	})
+}
+
+// MarshalProto writes the Token message in authentication.proto.
+func (s SerializedToken) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, s.Type)
+	b = appendProtoString(b, 2, s.Principal)
+	if s.Attributes != nil {
+		b = appendProtoMessage(b, 3, s.Attributes.MarshalProto())
+	}
+	return b
+}
+
+// UnmarshalProto reads the Token message in authentication.proto.
+func (s *SerializedToken) UnmarshalProto(b []byte) error {
+	*s = SerializedToken{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			s.Type = string(f.bytes)
+		case 2:
+			s.Principal = string(f.bytes)
+		case 3:
+			s.Attributes = new(SerializedValue)
+			return s.Attributes.UnmarshalProto(f.bytes)
+		}
+		return nil
+	})
+}
+
+// MarshalProto writes the Request message in authentication.proto.
+func (s SerializedRequest) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, s.URL)
+	b = appendProtoString(b, 2, s.Method)
+	b = appendProtoString(b, 3, s.ClientIP)
+	b = appendProtoString(b, 4, s.UserAgent)
+	b = appendProtoString(b, 5, s.Time)
+	b = appendProtoString(b, 6, s.DigestAlgorithm)
+	b = appendProtoBytes(b, 7, s.DigestSum)
+	b = appendProtoString(b, 8, s.RequestID)
+	return b
+}
+
+// UnmarshalProto reads the Request message in authentication.proto.
+func (s *SerializedRequest) UnmarshalProto(b []byte) error {
+	*s = SerializedRequest{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			s.URL = string(f.bytes)
+		case 2:
+			s.Method = string(f.bytes)
+		case 3:
+			s.ClientIP = string(f.bytes)
+		case 4:
+			s.UserAgent = string(f.bytes)
+		case 5:
+			s.Time = string(f.bytes)
+		case 6:
+			s.DigestAlgorithm = string(f.bytes)
+		case 7:
+			s.DigestSum = append([]byte(nil), f.bytes...)
+		case 8:
+			s.RequestID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// MarshalProto writes the Value message in authentication.proto.
+func (s SerializedValue) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, s.Type)
+	if s.Bool {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if s.Int != 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(s.Int))
+	}
+	if s.Uint != 0 {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, s.Uint)
+	}
+	if bits := math.Float64bits(s.Float); bits != 0 {
+		b = protowire.AppendTag(b, 5, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, bits)
+	}
+	b = appendProtoString(b, 6, s.String)
+	b = appendProtoBytes(b, 7, s.Bytes)
+	for _, v := range s.List {
+		b = appendProtoMessage(b, 8, v.MarshalProto())
+	}
+	for _, k := range sortedKeys(s.Map) {
+		entry := appendProtoString(nil, 1, k)
+		entry = appendProtoMessage(entry, 2, s.Map[k].MarshalProto())
+		b = appendProtoMessage(b, 9, entry)
+	}
+	if s.Token != nil {
+		b = appendProtoMessage(b, 10, s.Token.MarshalProto())
+	}
+	return b
+}
+
+// UnmarshalProto reads the Value message in authentication.proto.
+func (s *SerializedValue) UnmarshalProto(b []byte) error {
+	*s = SerializedValue{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			s.Type = string(f.bytes)
+		case 2:
+			s.Bool = f.varint != 0
+		case 3:
+			s.Int = protowire.DecodeZigZag(f.varint)
+		case 4:
+			s.Uint = f.varint
+		case 5:
+			s.Float = math.Float64frombits(f.varint)
+		case 6:
+			s.String = string(f.bytes)
+		case 7:
+			s.Bytes = append([]byte(nil), f.bytes...)
+		case 8:
+			var v SerializedValue
+			if err := v.UnmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			s.List = append(s.List, v)
+		case 9:
+			var (
+				k string
+				v SerializedValue
+			)
+			err := parseProto(f.bytes, func(f protoField) error {
+				switch f.num {
+				case 1:
+					k = string(f.bytes)
+				case 2:
+					return v.UnmarshalProto(f.bytes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if s.Map == nil {
+				s.Map = make(map[string]SerializedValue)
+			}
+			s.Map[k] = v
+		case 10:
+			s.Token = new(SerializedToken)
+			return s.Token.UnmarshalProto(f.bytes)
+		}
+		return nil
+	})
+}
+
+// protoField is a field read from a protobuf message.  Varint and fixed
+// values are in varint, and length delimited values in bytes.
+type protoField struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// parseProto calls f with each field of the protobuf message, skipping the
+// fields of types the serialized messages don't use.
+func parseProto(b []byte, f func(protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protoError(n)
+		}
+		b = b[n:]
+		field := protoField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			field.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			field.varint, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			field.num = 0
+		}
+		if n < 0 {
+			return protoError(n)
+		}
+		b = b[n:]
+		if field.num == 0 {
+			continue
+		}
+		if err := f(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func protoError(n int) error {
+	return fmt.Errorf("%w: %v", ErrInvalidSerialization, protowire.ParseError(n))
+}
+
+func appendProtoString(b []byte, num protowire.Number, v string) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
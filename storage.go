@@ -0,0 +1,151 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const memoryStorageSweep = 1000
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	ErrNilStorage  = errors.New("storage cannot be nil")
+)
+
+// Storage is shared state with expiring keys, used for things like replay
+// protection, rate limiting, caching, and lockouts.  Clustered deployments
+// should use a shared implementation, such as RedisStorage, so that every
+// instance sees the same state.
+type Storage interface {
+	// Get returns the value stored at the key, or ErrKeyNotFound if there
+	// isn't one or it has expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores the value at the key.  It expires after the TTL, unless the
+	// TTL isn't positive.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Incr atomically adds one to the integer stored at the key, starting
+	// from zero if there isn't one, and returns the new value.  The key
+	// expires after the TTL from the last increment, unless the TTL isn't
+	// positive.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Delete removes the key.  Deleting a key that doesn't exist isn't an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+type storageEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e storageEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// MemoryStorage is a Storage for a single instance.  Expired keys are removed
+// as they're found, and swept periodically.
+type MemoryStorage struct {
+	now func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]storageEntry
+	writes  int
+}
+
+// NewMemoryStorage creates an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		now:     time.Now,
+		entries: make(map[string]storageEntry),
+	}
+}
+
+// Get returns the value stored at the key.
+func (m *MemoryStorage) Get(_ context.Context, key string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if e.expired(m.now()) {
+		delete(m.entries, key)
+		return nil, ErrKeyNotFound
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set stores the value at the key.
+func (m *MemoryStorage) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.set(key, append([]byte(nil), value...), ttl)
+	return nil
+}
+
+// Incr adds one to the integer stored at the key.
+func (m *MemoryStorage) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var n int64
+	if e, ok := m.entries[key]; ok && !e.expired(m.now()) {
+		var err error
+		n, err = strconv.ParseInt(string(e.value), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n++
+	m.set(key, []byte(strconv.FormatInt(n, 10)), ttl)
+	return n, nil
+}
+
+// Delete removes the key.
+func (m *MemoryStorage) Delete(_ context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// set stores the entry, sweeping expired entries every so often.  It must be
+// called with the lock held.
+func (m *MemoryStorage) set(key string, value []byte, ttl time.Duration) {
+	now := m.now()
+	m.writes++
+	if m.writes%memoryStorageSweep == 0 {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	e := storageEntry{value: value}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	m.entries[key] = e
+}
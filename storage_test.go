@@ -0,0 +1,84 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+
+	_, err := m.Get(ctx, "a")
+	assert.ErrorIs(err, ErrKeyNotFound)
+
+	value := []byte("value")
+	assert.NoError(m.Set(ctx, "a", value, time.Minute))
+	value[0] = 'V'
+	v, err := m.Get(ctx, "a")
+	assert.NoError(err)
+	assert.Equal([]byte("value"), v)
+
+	assert.NoError(m.Set(ctx, "forever", []byte("x"), 0))
+
+	n, err := m.Incr(ctx, "n", time.Minute)
+	assert.NoError(err)
+	assert.Equal(int64(1), n)
+	now = now.Add(30 * time.Second)
+	n, err = m.Incr(ctx, "n", time.Minute)
+	assert.NoError(err)
+	assert.Equal(int64(2), n)
+	_, err = m.Incr(ctx, "a", time.Minute)
+	assert.Error(err)
+
+	// the counter's ttl restarts with each increment.
+	now = now.Add(45 * time.Second)
+	_, err = m.Get(ctx, "a")
+	assert.ErrorIs(err, ErrKeyNotFound)
+	v, err = m.Get(ctx, "n")
+	assert.NoError(err)
+	assert.Equal([]byte("2"), v)
+	now = now.Add(time.Minute)
+	n, err = m.Incr(ctx, "n", time.Minute)
+	assert.NoError(err)
+	assert.Equal(int64(1), n)
+
+	assert.NoError(m.Delete(ctx, "n"))
+	assert.NoError(m.Delete(ctx, "missing"))
+	_, err = m.Get(ctx, "n")
+	assert.ErrorIs(err, ErrKeyNotFound)
+
+	// expired keys are swept.
+	assert.NoError(m.Set(ctx, "old", nil, time.Second))
+	now = now.Add(time.Minute)
+	for i := 0; i < memoryStorageSweep; i++ {
+		assert.NoError(m.Set(ctx, "new", nil, time.Second))
+	}
+	_, ok := m.entries["old"]
+	assert.False(ok)
+	_, err = m.Get(ctx, "forever")
+	assert.NoError(err)
+}
@@ -59,6 +59,11 @@ func (st simpleToken) Attributes() Attributes {
 	return st.attributes
 }
 
+// Kind returns the TokenKind stored in the token's attributes, if any.
+func (st simpleToken) Kind() TokenKind {
+	return kindFromAttributes(st.attributes)
+}
+
 // NewToken creates a Token from basic information.  Many secure pipelines can use the returned value as
 // their token.  Specialized pipelines can create additional interfaces and augment the returned Token
 // as desired.  Alternatively, some pipelines can simply create their own Tokens out of whole cloth.
@@ -0,0 +1,184 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cast"
+)
+
+// TokenKind describes what type of caller a token represents, since policies
+// frequently differ between services, users, and devices.
+type TokenKind string
+
+const (
+	UnknownKind TokenKind = "unknown"
+	ServiceKind TokenKind = "service"
+	UserKind    TokenKind = "user"
+	DeviceKind  TokenKind = "device"
+)
+
+// TokenKindKey is the attribute key the token kind is stored under.
+const TokenKindKey = "tokenKind"
+
+var ErrEmptyTokenKind = errors.New("token kind cannot be empty")
+
+// KindedToken is an optional interface implemented by Tokens that know their
+// TokenKind.  Tokens created with NewToken implement it.
+type KindedToken interface {
+	Token
+	Kind() TokenKind
+}
+
+// KindOf returns the TokenKind of the token given.  Tokens that don't
+// implement KindedToken are checked for the TokenKindKey attribute.
+func KindOf(t Token) TokenKind {
+	if t == nil {
+		return UnknownKind
+	}
+	if kt, ok := t.(KindedToken); ok {
+		return kt.Kind()
+	}
+	return kindFromAttributes(t.Attributes())
+}
+
+func kindFromAttributes(a Attributes) TokenKind {
+	if a == nil {
+		return UnknownKind
+	}
+	v, ok := a.Get(TokenKindKey)
+	if !ok {
+		return UnknownKind
+	}
+	switch k := v.(type) {
+	case TokenKind:
+		if k != "" {
+			return k
+		}
+	case string:
+		if k != "" {
+			return TokenKind(k)
+		}
+	}
+	return UnknownKind
+}
+
+// TokenKindRule derives a TokenKind from a claim.  If Values is empty, the
+// claim only needs to be present.  Otherwise the claim (or any element of it,
+// if it is a list) must equal one of the Values.
+type TokenKindRule struct {
+	Kind   TokenKind
+	Claim  string
+	Values []string
+}
+
+// TokenKindConfig is the configuration for a token kind ClaimsMapper.  Rules
+// are evaluated in order and the first match wins.  If no rule matches, the
+// Default is used, which itself defaults to UnknownKind.
+type TokenKindConfig struct {
+	Rules   []TokenKindRule
+	Default TokenKind
+}
+
+type kindRule struct {
+	kind   TokenKind
+	path   AttributePath
+	values map[string]struct{}
+}
+
+func (r kindRule) matches(a Attributes) bool {
+	v, ok := r.path.Get(a)
+	if !ok {
+		return false
+	}
+	if len(r.values) == 0 {
+		return true
+	}
+	vals, err := cast.ToStringSliceE(v)
+	if err != nil {
+		return false
+	}
+	for _, val := range vals {
+		if _, ok := r.values[val]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTokenKindMapper creates a ClaimsMapper that classifies a token using the
+// rules given and stores the result under TokenKindKey.
+func NewTokenKindMapper(config TokenKindConfig) (ClaimsMapper, error) {
+	rules := make([]kindRule, 0, len(config.Rules))
+	for _, r := range config.Rules {
+		if r.Kind == "" {
+			return nil, ErrEmptyTokenKind
+		}
+		path, err := ParseAttributePath(r.Claim)
+		if err != nil {
+			return nil, fmt.Errorf("invalid claim for token kind [%v]: %w", r.Kind, err)
+		}
+		values := make(map[string]struct{}, len(r.Values))
+		for _, v := range r.Values {
+			values[v] = struct{}{}
+		}
+		rules = append(rules, kindRule{kind: r.Kind, path: path, values: values})
+	}
+	def := config.Default
+	if def == "" {
+		def = UnknownKind
+	}
+
+	return ClaimsMapperFunc(func(a Attributes) (Attributes, error) {
+		if a == nil {
+			return nil, errors.New("nil attributes")
+		}
+		kind := def
+		for _, r := range rules {
+			if r.matches(a) {
+				kind = r.kind
+				break
+			}
+		}
+		return mappedAttributes{
+			canonical: BasicAttributes{TokenKindKey: kind},
+			base:      a,
+		}, nil
+	}), nil
+}
+
+// ClaimsMappers runs a list of ClaimsMappers in order, each one receiving the
+// output of the last.
+type ClaimsMappers []ClaimsMapper
+
+// MapClaims runs each ClaimsMapper in order, stopping at the first error.
+func (cms ClaimsMappers) MapClaims(a Attributes) (Attributes, error) {
+	var err error
+	for _, m := range cms {
+		if m == nil {
+			continue
+		}
+		a, err = m.MapClaims(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
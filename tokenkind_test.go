@@ -0,0 +1,182 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKindedToken struct {
+	Token
+}
+
+func (testKindedToken) Kind() TokenKind {
+	return DeviceKind
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		description  string
+		token        Token
+		expectedKind TokenKind
+	}{
+		{
+			description:  "Attribute",
+			token:        NewToken("jwt", "p", NewAttributes(map[string]interface{}{TokenKindKey: "service"})),
+			expectedKind: ServiceKind,
+		},
+		{
+			description:  "Typed Attribute",
+			token:        NewToken("jwt", "p", NewAttributes(map[string]interface{}{TokenKindKey: UserKind})),
+			expectedKind: UserKind,
+		},
+		{
+			description:  "KindedToken",
+			token:        testKindedToken{Token: NewToken("jwt", "p", nil)},
+			expectedKind: DeviceKind,
+		},
+		{
+			description:  "Non KindedToken",
+			token:        struct{ Token }{NewToken("jwt", "p", NewAttributes(map[string]interface{}{TokenKindKey: "device"}))},
+			expectedKind: DeviceKind,
+		},
+		{
+			description:  "Missing",
+			token:        NewToken("jwt", "p", NewAttributes(map[string]interface{}{})),
+			expectedKind: UnknownKind,
+		},
+		{
+			description:  "Wrong Type",
+			token:        NewToken("jwt", "p", NewAttributes(map[string]interface{}{TokenKindKey: 5})),
+			expectedKind: UnknownKind,
+		},
+		{
+			description:  "Empty",
+			token:        NewToken("jwt", "p", NewAttributes(map[string]interface{}{TokenKindKey: ""})),
+			expectedKind: UnknownKind,
+		},
+		{
+			description:  "Nil Attributes",
+			token:        NewToken("jwt", "p", nil),
+			expectedKind: UnknownKind,
+		},
+		{
+			description:  "Nil Token",
+			expectedKind: UnknownKind,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expectedKind, KindOf(tc.token))
+		})
+	}
+}
+
+func TestTokenKindMapper(t *testing.T) {
+	config := TokenKindConfig{
+		Rules: []TokenKindRule{
+			{Kind: DeviceKind, Claim: "$.deviceID"},
+			{Kind: ServiceKind, Claim: "grant_type", Values: []string{"client_credentials"}},
+			{Kind: UserKind, Claim: "amr", Values: []string{"pwd", "mfa"}},
+		},
+		Default: ServiceKind,
+	}
+	m, err := NewTokenKindMapper(config)
+	require.NoError(t, err)
+
+	tests := []struct {
+		description  string
+		claims       map[string]interface{}
+		expectedKind TokenKind
+	}{
+		{
+			description:  "Device",
+			claims:       map[string]interface{}{"deviceID": "mac:112233445566"},
+			expectedKind: DeviceKind,
+		},
+		{
+			description:  "Service",
+			claims:       map[string]interface{}{"grant_type": "client_credentials"},
+			expectedKind: ServiceKind,
+		},
+		{
+			description:  "User List",
+			claims:       map[string]interface{}{"amr": []interface{}{"mfa"}},
+			expectedKind: UserKind,
+		},
+		{
+			description:  "Default",
+			claims:       map[string]interface{}{"amr": map[string]interface{}{}},
+			expectedKind: ServiceKind,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			a, err := m.MapClaims(NewAttributes(tc.claims))
+			assert.NoError(err)
+			assert.Equal(tc.expectedKind, KindOf(NewToken("jwt", "p", a)))
+		})
+	}
+
+	_, err = m.MapClaims(nil)
+	assert.Error(t, err)
+}
+
+func TestNewTokenKindMapperErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewTokenKindMapper(TokenKindConfig{Rules: []TokenKindRule{{Claim: "a"}}})
+	assert.ErrorIs(err, ErrEmptyTokenKind)
+	_, err = NewTokenKindMapper(TokenKindConfig{Rules: []TokenKindRule{{Kind: UserKind, Claim: "a["}}})
+	assert.ErrorIs(err, ErrInvalidAttributePath)
+
+	m, err := NewTokenKindMapper(TokenKindConfig{})
+	assert.NoError(err)
+	a, err := m.MapClaims(NewAttributes(map[string]interface{}{}))
+	assert.NoError(err)
+	assert.Equal(UnknownKind, KindOf(NewToken("", "", a)))
+}
+
+func TestClaimsMappers(t *testing.T) {
+	assert := assert.New(t)
+	kind, err := NewTokenKindMapper(TokenKindConfig{Default: DeviceKind})
+	assert.NoError(err)
+	canonical, err := NewClaimsMapper(ClaimsMappingConfig{
+		Mappings: map[string][]string{RolesKey: {"groups"}},
+	})
+	assert.NoError(err)
+
+	a, err := ClaimsMappers{canonical, nil, kind}.MapClaims(NewAttributes(map[string]interface{}{
+		"groups": []string{"admin"},
+	}))
+	assert.NoError(err)
+	v, ok := a.Get(RolesKey)
+	assert.True(ok)
+	assert.Equal([]string{"admin"}, v)
+	assert.Equal(DeviceKind, KindOf(NewToken("", "", a)))
+
+	testErr := errors.New("test")
+	_, err = ClaimsMappers{ClaimsMapperFunc(func(Attributes) (Attributes, error) {
+		return nil, testErr
+	}), kind}.MapClaims(NewAttributes(map[string]interface{}{}))
+	assert.ErrorIs(err, testErr)
+}
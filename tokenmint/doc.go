@@ -0,0 +1,25 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+Package tokenmint creates signed JWTs for service to service calls, for
+deployments without an external identity provider.  Tokens can be signed with
+a local key or with a KMS key through basculekms, and a Minter can be used as
+an acquire.Acquirer to add tokens to outbound requests.
+*/
+
+package tokenmint
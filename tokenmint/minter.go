@@ -0,0 +1,202 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package tokenmint
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTTL         = 5 * time.Minute
+	defaultSignTimeout = 5 * time.Second
+)
+
+var (
+	ErrNilSigner       = errors.New("signer cannot be nil")
+	ErrReservedClaim   = errors.New("claim is set by the minter")
+	ErrBufferTooLarge  = errors.New("refresh buffer must be shorter than the ttl")
+	reservedClaimNames = []string{"iss", "sub", "aud", "iat", "nbf", "exp", "jti"}
+)
+
+// Config configures a Minter.
+type Config struct {
+	// Issuer, Subject, and Audience are the iss, sub, and aud claims.  They
+	// are left out if empty.
+	Issuer   string
+	Subject  string
+	Audience []string
+
+	// TTL is how long minted tokens are valid for.  Defaults to 5 minutes.
+	TTL time.Duration
+
+	// Buffer is how long before a token expires that Acquire mints a new
+	// one.  Defaults to a tenth of the TTL.
+	Buffer time.Duration
+
+	// Claims are added to every token.  They can't include the registered
+	// claims set by the minter.
+	Claims map[string]interface{}
+
+	// SignTimeout bounds signing when a token is minted by Acquire, which
+	// has no context.  Defaults to 5 seconds.
+	SignTimeout time.Duration
+}
+
+// Minter creates signed JWTs.  It implements acquire.Acquirer, reusing a
+// token until it is close to expiring so that remote signers such as a KMS
+// aren't called for every request.
+type Minter struct {
+	signer Signer
+	config Config
+	now    func() time.Time
+
+	lock    sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// NewMinter creates a Minter that signs tokens with the signer given.
+func NewMinter(signer Signer, config Config) (*Minter, error) {
+	if signer == nil {
+		return nil, ErrNilSigner
+	}
+	for _, name := range reservedClaimNames {
+		if _, ok := config.Claims[name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrReservedClaim, name)
+		}
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = config.TTL / 10
+	}
+	if config.Buffer >= config.TTL {
+		return nil, ErrBufferTooLarge
+	}
+	if config.SignTimeout <= 0 {
+		config.SignTimeout = defaultSignTimeout
+	}
+	return &Minter{
+		signer: signer,
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Mint creates a new signed token, returning it with its expiration.  The
+// claims given are added to the configured ones for this token only.
+func (m *Minter) Mint(ctx context.Context, claims map[string]interface{}) (string, time.Time, error) {
+	for _, name := range reservedClaimNames {
+		if _, ok := claims[name]; ok {
+			return "", time.Time{}, fmt.Errorf("%w: %s", ErrReservedClaim, name)
+		}
+	}
+	now := m.now()
+	expires := now.Add(m.config.TTL)
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate jti: %w", err)
+	}
+	all := make(map[string]interface{}, len(m.config.Claims)+len(claims)+7)
+	for k, v := range m.config.Claims {
+		all[k] = v
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+	if m.config.Issuer != "" {
+		all["iss"] = m.config.Issuer
+	}
+	if m.config.Subject != "" {
+		all["sub"] = m.config.Subject
+	}
+	if len(m.config.Audience) == 1 {
+		all["aud"] = m.config.Audience[0]
+	} else if len(m.config.Audience) > 1 {
+		all["aud"] = m.config.Audience
+	}
+	all["iat"] = now.Unix()
+	all["nbf"] = now.Unix()
+	all["exp"] = expires.Unix()
+	all["jti"] = hex.EncodeToString(jti)
+
+	header := map[string]interface{}{
+		"alg": m.signer.Alg(),
+		"typ": "JWT",
+	}
+	if kid := m.signer.KeyID(); kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	c, err := json.Marshal(all)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := m.signer.Sign(ctx, []byte(signingInput))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), expires, nil
+}
+
+// Acquire returns an Authorization value with a bearer token, minting a new
+// token when the last one is close to expiring.
+func (m *Minter) Acquire(ctx context.Context) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.value != "" && m.now().Add(m.config.Buffer).Before(m.expires) {
+		return m.value, nil
+	}
+	return m.mintValue(ctx)
+}
+
+// InvalidateAndRefresh discards the current token, such as one the upstream
+// service rejected, and mints a new one.
+func (m *Minter) InvalidateAndRefresh(ctx context.Context) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.value, m.expires = "", time.Time{}
+	return m.mintValue(ctx)
+}
+
+// mintValue mints a token and caches its Authorization value.  The lock must
+// be held.
+func (m *Minter) mintValue(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.SignTimeout)
+	defer cancel()
+	token, expires, err := m.Mint(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	m.value, m.expires = "Bearer "+token, expires
+	return m.value, nil
+}
@@ -0,0 +1,278 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package tokenmint
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule/acquire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ acquire.Acquirer = (*Minter)(nil)
+
+type countingSigner struct {
+	Signer
+	calls int
+	err   error
+}
+
+func (c *countingSigner) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.Signer.Sign(ctx, signingInput)
+}
+
+func newTestSigner(t *testing.T) (*countingSigner, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	s, err := NewLocalSigner(key, "kid-1", "ES256")
+	require.NoError(t, err)
+	return &countingSigner{Signer: s}, key
+}
+
+func TestNewMinter(t *testing.T) {
+	s, _ := newTestSigner(t)
+	tests := []struct {
+		description    string
+		signer         Signer
+		config         Config
+		expectedConfig Config
+		expectedErr    error
+	}{
+		{
+			description: "Defaults",
+			signer:      s,
+			expectedConfig: Config{
+				TTL:         defaultTTL,
+				Buffer:      defaultTTL / 10,
+				SignTimeout: defaultSignTimeout,
+			},
+		},
+		{
+			description: "Custom",
+			signer:      s,
+			config: Config{
+				Issuer:      "svc",
+				TTL:         time.Hour,
+				Buffer:      time.Minute,
+				SignTimeout: time.Second,
+			},
+			expectedConfig: Config{
+				Issuer:      "svc",
+				TTL:         time.Hour,
+				Buffer:      time.Minute,
+				SignTimeout: time.Second,
+			},
+		},
+		{
+			description: "Nil Signer",
+			expectedErr: ErrNilSigner,
+		},
+		{
+			description: "Reserved Claim",
+			signer:      s,
+			config:      Config{Claims: map[string]interface{}{"exp": 5}},
+			expectedErr: ErrReservedClaim,
+		},
+		{
+			description: "Buffer Too Large",
+			signer:      s,
+			config:      Config{TTL: time.Minute, Buffer: time.Minute},
+			expectedErr: ErrBufferTooLarge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			m, err := NewMinter(tc.signer, tc.config)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(m)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(tc.expectedConfig, m.config)
+		})
+	}
+}
+
+func TestMint(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s, key := newTestSigner(t)
+	m, err := NewMinter(s, Config{
+		Issuer:   "issuer",
+		Subject:  "service",
+		Audience: []string{"a", "b"},
+		TTL:      time.Minute,
+		Claims:   map[string]interface{}{"capabilities": []string{"x"}},
+	})
+	require.NoError(err)
+
+	token, expires, err := m.Mint(context.Background(), map[string]interface{}{"extra": "yes"})
+	require.NoError(err)
+	assert.WithinDuration(time.Now().Add(time.Minute), expires, 2*time.Second)
+
+	parsed, err := jwt.Parse(token, func(tok *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(err)
+	assert.True(parsed.Valid)
+	assert.Equal("ES256", parsed.Header["alg"])
+	assert.Equal("JWT", parsed.Header["typ"])
+	assert.Equal("kid-1", parsed.Header["kid"])
+
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal("issuer", claims["iss"])
+	assert.Equal("service", claims["sub"])
+	assert.Equal([]interface{}{"a", "b"}, claims["aud"])
+	assert.Equal([]interface{}{"x"}, claims["capabilities"])
+	assert.Equal("yes", claims["extra"])
+	assert.Equal(float64(expires.Unix()), claims["exp"])
+	assert.NotEmpty(claims["jti"])
+	assert.NotEmpty(claims["iat"])
+	assert.NotEmpty(claims["nbf"])
+
+	// the extra claims are only for that token.
+	token, _, err = m.Mint(context.Background(), nil)
+	require.NoError(err)
+	parsed, err = jwt.Parse(token, func(tok *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(err)
+	assert.NotContains(parsed.Claims.(jwt.MapClaims), "extra")
+}
+
+func TestMintSingleAudience(t *testing.T) {
+	s, key := newTestSigner(t)
+	m, err := NewMinter(s, Config{Audience: []string{"a"}})
+	require.NoError(t, err)
+	token, _, err := m.Mint(context.Background(), nil)
+	require.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(tok *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a", claims["aud"])
+	assert.NotContains(t, claims, "iss")
+	assert.NotContains(t, claims, "sub")
+}
+
+func TestMintErrors(t *testing.T) {
+	signErr := errors.New("sign failed")
+	tests := []struct {
+		description string
+		claims      map[string]interface{}
+		signErr     error
+		expectedErr error
+	}{
+		{
+			description: "Reserved Claim",
+			claims:      map[string]interface{}{"iss": "me"},
+			expectedErr: ErrReservedClaim,
+		},
+		{
+			description: "Unmarshalable Claim",
+			claims:      map[string]interface{}{"bad": make(chan int)},
+		},
+		{
+			description: "Sign Error",
+			signErr:     signErr,
+			expectedErr: signErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, _ := newTestSigner(t)
+			s.err = tc.signErr
+			m, err := NewMinter(s, Config{})
+			require.NoError(t, err)
+			token, expires, err := m.Mint(context.Background(), tc.claims)
+			assert.Error(err)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+			assert.Empty(token)
+			assert.True(expires.IsZero())
+		})
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s, _ := newTestSigner(t)
+	m, err := NewMinter(s, Config{TTL: time.Minute, Buffer: 10 * time.Second})
+	require.NoError(err)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	first, err := m.Acquire(context.Background())
+	require.NoError(err)
+	assert.True(strings.HasPrefix(first, "Bearer "))
+	assert.Equal(1, s.calls)
+
+	// reused until the buffer before expiration.
+	now = now.Add(49 * time.Second)
+	second, err := m.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal(first, second)
+	assert.Equal(1, s.calls)
+
+	now = now.Add(time.Second)
+	third, err := m.Acquire(context.Background())
+	require.NoError(err)
+	assert.NotEqual(first, third)
+	assert.Equal(2, s.calls)
+
+	// failures aren't cached.
+	s.err = errors.New("sign failed")
+	now = now.Add(time.Minute)
+	value, err := m.Acquire(context.Background())
+	assert.Error(err)
+	assert.Empty(value)
+	s.err = nil
+	value, err = m.Acquire(context.Background())
+	assert.NoError(err)
+	assert.NotEmpty(value)
+	assert.Equal(4, s.calls)
+
+	// refreshing mints a new token before the buffer.
+	refreshed, err := m.InvalidateAndRefresh(context.Background())
+	require.NoError(err)
+	assert.NotEqual(value, refreshed)
+	assert.Equal(5, s.calls)
+	cached, err := m.Acquire(context.Background())
+	require.NoError(err)
+	assert.Equal(refreshed, cached)
+	assert.Equal(5, s.calls)
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package tokenmint
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNilKey               = errors.New("key cannot be nil")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// Signer signs the JWS signing input of a token.  basculekms.Signer is a
+// Signer backed by a KMS key.
+type Signer interface {
+	// Alg returns the JWS algorithm used, which goes in the token's header.
+	Alg() string
+
+	// KeyID returns the kid that goes in the token's header.  It can be
+	// empty.
+	KeyID() string
+
+	// Sign returns the signature of the signing input.
+	Sign(ctx context.Context, signingInput []byte) ([]byte, error)
+}
+
+// localSigner is a Signer holding a private key in memory.
+type localSigner struct {
+	key    crypto.Signer
+	kid    string
+	method jwt.SigningMethod
+}
+
+// NewLocalSigner creates a Signer using the private key given, which must be
+// an *rsa.PrivateKey, *ecdsa.PrivateKey, or ed25519.PrivateKey matching the
+// algorithm.
+func NewLocalSigner(key crypto.Signer, kid, alg string) (Signer, error) {
+	if key == nil {
+		return nil, ErrNilKey
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || alg == jwt.SigningMethodNone.Alg() || alg[0] == 'H' {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	return &localSigner{
+		key:    key,
+		kid:    kid,
+		method: method,
+	}, nil
+}
+
+func (l *localSigner) Alg() string {
+	return l.method.Alg()
+}
+
+func (l *localSigner) KeyID() string {
+	return l.kid
+}
+
+func (l *localSigner) Sign(_ context.Context, signingInput []byte) ([]byte, error) {
+	sig, err := l.method.Sign(string(signingInput), l.key)
+	if err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(sig)
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package tokenmint
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule/basculekms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Signer = (*basculekms.Signer)(nil)
+
+func TestNewLocalSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		description string
+		key         crypto.Signer
+		public      crypto.PublicKey
+		alg         string
+		expectedErr error
+	}{
+		{
+			description: "RS256",
+			key:         rsaKey,
+			public:      rsaKey.Public(),
+			alg:         "RS256",
+		},
+		{
+			description: "PS384",
+			key:         rsaKey,
+			public:      rsaKey.Public(),
+			alg:         "PS384",
+		},
+		{
+			description: "ES256",
+			key:         ecKey,
+			public:      ecKey.Public(),
+			alg:         "ES256",
+		},
+		{
+			description: "EdDSA",
+			key:         edKey,
+			public:      edKey.Public(),
+			alg:         "EdDSA",
+		},
+		{
+			description: "Nil Key",
+			alg:         "RS256",
+			expectedErr: ErrNilKey,
+		},
+		{
+			description: "HMAC",
+			key:         rsaKey,
+			alg:         "HS256",
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			description: "None",
+			key:         rsaKey,
+			alg:         "none",
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			description: "Unknown",
+			key:         rsaKey,
+			alg:         "XX999",
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			description: "Empty",
+			key:         rsaKey,
+			expectedErr: ErrUnsupportedAlgorithm,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			s, err := NewLocalSigner(tc.key, "kid-1", tc.alg)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.Nil(s)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.alg, s.Alg())
+			assert.Equal("kid-1", s.KeyID())
+
+			input := "header.claims"
+			sig, err := s.Sign(context.Background(), []byte(input))
+			require.NoError(err)
+			encoded := jwt.EncodeSegment(sig)
+			assert.False(strings.Contains(encoded, "="))
+			assert.NoError(jwt.GetSigningMethod(tc.alg).Verify(input, encoded, tc.public))
+		})
+	}
+}
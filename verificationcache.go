@@ -0,0 +1,119 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/s-srakshe/bascule/internal/ttlcache"
+)
+
+const (
+	defaultVerificationTTL        = time.Minute
+	defaultVerificationMaxEntries = 10000
+)
+
+// ErrNilJWTParser is returned when a JWTParser is required but not given.
+var ErrNilJWTParser = errors.New("jwt parser cannot be nil")
+
+// VerificationCacheConfig configures a VerificationCache.
+type VerificationCacheConfig struct {
+	// TTL is the longest a verified signature is remembered.  A key that is
+	// revoked can still be accepted for this long.  Defaults to one minute.
+	TTL time.Duration
+
+	// MaxEntries bounds the number of remembered signatures.  Defaults to
+	// 10000.
+	MaxEntries int
+}
+
+// VerificationCache is a JWTParser that remembers the JWTs whose signatures
+// were recently verified, keyed by a hash of the signing input and signature.
+// When the same JWT is seen again, signature verification and key resolution
+// are skipped, but the claims are still decoded and checked, so exp and nbf
+// are enforced on every request.
+type VerificationCache struct {
+	parser   JWTParser
+	config   VerificationCacheConfig
+	now      func() time.Time
+	verified *ttlcache.Cache
+}
+
+// NewVerificationCache creates a VerificationCache around the parser given,
+// which does the verification when the JWT isn't cached.
+func NewVerificationCache(parser JWTParser, config VerificationCacheConfig) (*VerificationCache, error) {
+	if parser == nil {
+		return nil, ErrNilJWTParser
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultVerificationTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultVerificationMaxEntries
+	}
+	return &VerificationCache{
+		parser:   parser,
+		config:   config,
+		now:      time.Now,
+		verified: ttlcache.New(config.MaxEntries),
+	}, nil
+}
+
+// ParseJWT implements JWTParser.  Only JWTs that the wrapped parser found
+// valid are cached.
+func (v *VerificationCache) ParseJWT(token string, claims jwt.Claims, keyfunc jwt.Keyfunc) (*jwt.Token, error) {
+	// the compact JWS is the signing input and signature, and the signing
+	// input includes the kid header.
+	sum := sha256.Sum256([]byte(token))
+	key := string(sum[:])
+	now := v.now()
+
+	if _, found := v.verified.Get(key, now); found {
+		return parseVerified(token, claims)
+	}
+
+	jwtToken, err := v.parser.ParseJWT(token, claims, keyfunc)
+	if err != nil || jwtToken == nil || !jwtToken.Valid {
+		return jwtToken, err
+	}
+
+	v.verified.Set(key, struct{}{}, now.Add(v.config.TTL), now)
+	return jwtToken, nil
+}
+
+// parseVerified decodes a JWT whose signature is known to be good, validating
+// its claims the same way jwt.ParseWithClaims does.
+func parseVerified(token string, claims jwt.Claims) (*jwt.Token, error) {
+	jwtToken, parts, err := new(jwt.Parser).ParseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err = jwtToken.Claims.Valid(); err != nil {
+		var vErr *jwt.ValidationError
+		if !errors.As(err, &vErr) {
+			vErr = &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorClaimsInvalid}
+		}
+		return nil, vErr
+	}
+	jwtToken.Signature = parts[2]
+	jwtToken.Valid = true
+	return jwtToken, nil
+}
@@ -0,0 +1,203 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bascule
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHMACKey = []byte("verification cache test key")
+
+// countingParser counts the JWTs it verifies.
+type countingParser struct {
+	parses int
+}
+
+func (c *countingParser) ParseJWT(token string, claims jwt.Claims, keyfunc jwt.Keyfunc) (*jwt.Token, error) {
+	c.parses++
+	return DefaultJWTParser.ParseJWT(token, claims, keyfunc)
+}
+
+func hmacKeyfunc(*jwt.Token) (interface{}, error) {
+	return testHMACKey, nil
+}
+
+func signTestJWT(t testing.TB, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(testHMACKey)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestNewVerificationCache(t *testing.T) {
+	assert := assert.New(t)
+	v, err := NewVerificationCache(nil, VerificationCacheConfig{})
+	assert.ErrorIs(err, ErrNilJWTParser)
+	assert.Nil(v)
+
+	v, err = NewVerificationCache(DefaultJWTParser, VerificationCacheConfig{})
+	assert.NoError(err)
+	assert.Equal(VerificationCacheConfig{
+		TTL:        defaultVerificationTTL,
+		MaxEntries: defaultVerificationMaxEntries,
+	}, v.config)
+}
+
+func TestVerificationCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	parser := &countingParser{}
+	v, err := NewVerificationCache(parser, VerificationCacheConfig{TTL: time.Minute})
+	require.NoError(err)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	token := signTestJWT(t, jwt.MapClaims{
+		"sub": "alice",
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	for i := 0; i < 3; i++ {
+		claims := ClaimsWithLeeway{}
+		jwtToken, err := v.ParseJWT(token, &claims, hmacKeyfunc)
+		require.NoError(err)
+		assert.True(jwtToken.Valid)
+		assert.NotEmpty(jwtToken.Signature)
+		assert.Equal("alice", claims.MapClaims["sub"])
+	}
+	assert.Equal(1, parser.parses)
+
+	// the signature is verified again once the entry expires.
+	v.now = func() time.Time { return now.Add(time.Minute) }
+	_, err = v.ParseJWT(token, &ClaimsWithLeeway{}, hmacKeyfunc)
+	assert.NoError(err)
+	assert.Equal(2, parser.parses)
+
+	// a tampered signature isn't a cache hit.
+	_, err = v.ParseJWT(token[:len(token)-2]+"AA", &ClaimsWithLeeway{}, hmacKeyfunc)
+	assert.Error(err)
+	assert.Equal(3, parser.parses)
+
+	// failures aren't cached.
+	errKey := errors.New("no key")
+	for i := 0; i < 2; i++ {
+		_, err = v.ParseJWT(signTestJWT(t, jwt.MapClaims{"sub": "bob"}), &ClaimsWithLeeway{},
+			func(*jwt.Token) (interface{}, error) { return nil, errKey })
+		assert.ErrorContains(err, errKey.Error())
+	}
+	assert.Equal(5, parser.parses)
+}
+
+func TestVerificationCacheChecksClaims(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	parser := &countingParser{}
+	v, err := NewVerificationCache(parser, VerificationCacheConfig{TTL: time.Hour})
+	require.NoError(err)
+
+	expires := time.Now().Add(time.Minute)
+	token := signTestJWT(t, jwt.MapClaims{
+		"sub": "alice",
+		"exp": expires.Unix(),
+		"nbf": time.Now().Add(-time.Minute).Unix(),
+	})
+	_, err = v.ParseJWT(token, &ClaimsWithLeeway{}, hmacKeyfunc)
+	require.NoError(err)
+
+	defer func(f func() time.Time) { jwt.TimeFunc = f }(jwt.TimeFunc)
+	jwt.TimeFunc = func() time.Time { return expires.Add(time.Second) }
+	jwtToken, err := v.ParseJWT(token, &ClaimsWithLeeway{}, hmacKeyfunc)
+	assert.Nil(jwtToken)
+	var vErr *jwt.ValidationError
+	require.ErrorAs(err, &vErr)
+	assert.NotZero(vErr.Errors & jwt.ValidationErrorExpired)
+
+	jwtToken, err = v.ParseJWT(token, &ClaimsWithLeeway{Leeway: Leeway{EXP: 5}}, hmacKeyfunc)
+	assert.NoError(err)
+	assert.True(jwtToken.Valid)
+
+	jwt.TimeFunc = func() time.Time { return expires.Add(-time.Hour) }
+	_, err = v.ParseJWT(token, &ClaimsWithLeeway{}, hmacKeyfunc)
+	require.ErrorAs(err, &vErr)
+	assert.NotZero(vErr.Errors & jwt.ValidationErrorNotValidYet)
+	assert.Equal(1, parser.parses)
+}
+
+func TestVerificationCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	v, err := NewVerificationCache(DefaultJWTParser, VerificationCacheConfig{TTL: time.Minute, MaxEntries: 2})
+	require.NoError(err)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	parse := func(sub string) {
+		_, err := v.ParseJWT(signTestJWT(t, jwt.MapClaims{"sub": sub}), &ClaimsWithLeeway{}, hmacKeyfunc)
+		require.NoError(err)
+	}
+	parse("a")
+	parse("b")
+	assert.Equal(2, v.verified.Len())
+
+	// everything is fresh, so the cache is cleared.
+	parse("c")
+	assert.Equal(1, v.verified.Len())
+
+	// expired entries are dropped first.
+	v.now = func() time.Time { return now.Add(30 * time.Second) }
+	parse("d")
+	v.now = func() time.Time { return now.Add(time.Minute) }
+	parse("e")
+	assert.Equal(2, v.verified.Len())
+}
+
+func BenchmarkVerificationCache(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(b, err)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(key)
+	require.NoError(b, err)
+	keyfunc := func(*jwt.Token) (interface{}, error) { return key.Public(), nil }
+	cache, err := NewVerificationCache(DefaultJWTParser, VerificationCacheConfig{})
+	require.NoError(b, err)
+
+	parsers := map[string]JWTParser{
+		"Uncached": DefaultJWTParser,
+		"Cached":   cache,
+	}
+	for name, parser := range parsers {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parser.ParseJWT(token, &ClaimsWithLeeway{}, keyfunc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}